@@ -47,8 +47,7 @@ export function useImageActions(): ImageActionsReturn {
       if (res.ok) {
         article.is_read = true;
         // Update unread counts after marking as read
-        await store.fetchUnreadCounts();
-        await store.fetchFilterCounts();
+        await store.fetchCounts();
       }
     } catch (e) {
       console.error('Failed to mark as read:', e);
@@ -67,8 +66,7 @@ export function useImageActions(): ImageActionsReturn {
         method: 'POST',
       });
       // Update unread counts after toggling read status
-      await store.fetchUnreadCounts();
-      await store.fetchFilterCounts();
+      await store.fetchCounts();
     } catch (e) {
       console.error('Error toggling read status:', e);
       // Revert the state change on error
@@ -13,6 +13,7 @@ export function useImageGalleryData(): ImageGalleryDataReturn {
   const isLoading = ref(false);
   const page = ref(1);
   const hasMore = ref(true);
+  const nextCursor = ref('');
   const imageCountCache = ref<Map<number, number>>(new Map());
 
   // Load showOnlyUnread preference from localStorage
@@ -35,7 +36,9 @@ export function useImageGalleryData(): ImageGalleryDataReturn {
     isLoading.value = true;
     try {
       // Build URL with query parameters
-      let url = `/api/articles/images?page=${page.value}&limit=${ITEMS_PER_PAGE}`;
+      // Pages after the first continue from the previous page's cursor
+      const cursor = loadMore ? nextCursor.value : '';
+      let url = `/api/articles/images?cursor=${encodeURIComponent(cursor)}&limit=${ITEMS_PER_PAGE}`;
 
       // Add only_unread filter if enabled
       if (showOnlyUnread.value) {
@@ -58,13 +61,13 @@ export function useImageGalleryData(): ImageGalleryDataReturn {
       if (res.ok) {
         const data = await res.json();
 
-        // Validate that data is an array
-        if (!Array.isArray(data)) {
-          console.error('API response is not an array:', data);
+        // Validate that the page holds an array
+        if (!Array.isArray(data?.articles)) {
+          console.error('API response has no articles array:', data);
           return;
         }
 
-        const newArticles = data;
+        const newArticles = data.articles;
 
         if (loadMore) {
           articles.value = [...articles.value, ...newArticles];
@@ -72,7 +75,8 @@ export function useImageGalleryData(): ImageGalleryDataReturn {
           articles.value = newArticles;
         }
 
-        hasMore.value = newArticles.length >= ITEMS_PER_PAGE;
+        nextCursor.value = data.next_cursor || '';
+        hasMore.value = nextCursor.value !== '';
 
         // Preload image counts for new articles
         newArticles.forEach((article: Article) => {
@@ -120,6 +124,7 @@ export function useImageGalleryData(): ImageGalleryDataReturn {
    */
   async function refresh(): Promise<void> {
     page.value = 1;
+    nextCursor.value = '';
     articles.value = [];
     hasMore.value = true;
     await fetchImages();
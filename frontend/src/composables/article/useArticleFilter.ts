@@ -21,6 +21,7 @@ export function useArticleFilter() {
   const filterPage = ref(1);
   const filterHasMore = ref(true);
   const filterTotal = ref(0);
+  const filterNextCursor = ref('');
 
   // Reset filter state
   function resetFilterState(): void {
@@ -28,6 +29,7 @@ export function useArticleFilter() {
     filterPage.value = 1;
     filterHasMore.value = true;
     filterTotal.value = 0;
+    filterNextCursor.value = '';
   }
 
   // Fetch filtered articles from server with pagination
@@ -39,14 +41,14 @@ export function useArticleFilter() {
 
     store.setIsFilterLoading(true);
     try {
-      const page = append ? filterPage.value : 1;
+      const cursor = append ? filterNextCursor.value : '';
 
       const res = await fetch('/api/articles/filter', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({
           conditions: filters,
-          page: page,
+          cursor: cursor,
           limit: 50,
         }),
       });
@@ -74,6 +76,7 @@ export function useArticleFilter() {
           }
         });
 
+        filterNextCursor.value = data.next_cursor || '';
         filterHasMore.value = data.has_more;
         filterTotal.value = data.total;
       } else {
@@ -107,6 +110,7 @@ export function useArticleFilter() {
     filterPage.value = 1;
     filterHasMore.value = true;
     filterTotal.value = 0;
+    filterNextCursor.value = '';
   }
 
   return {
@@ -27,11 +27,19 @@ export function generateInitialSettings(): SettingsData {
     ai_translation_prompt: settingsDefaults.ai_translation_prompt,
     ai_usage_limit: settingsDefaults.ai_usage_limit,
     ai_usage_tokens: settingsDefaults.ai_usage_tokens,
+    archive_favorites: settingsDefaults.archive_favorites,
     auto_cleanup_enabled: settingsDefaults.auto_cleanup_enabled,
     auto_show_all_content: settingsDefaults.auto_show_all_content,
+    backup_compress: settingsDefaults.backup_compress,
+    backup_enabled: settingsDefaults.backup_enabled,
+    backup_interval_hours: settingsDefaults.backup_interval_hours,
+    backup_keep_daily: settingsDefaults.backup_keep_daily,
+    backup_keep_weekly: settingsDefaults.backup_keep_weekly,
     baidu_app_id: settingsDefaults.baidu_app_id,
     baidu_secret_key: settingsDefaults.baidu_secret_key,
     close_to_tray: settingsDefaults.close_to_tray,
+    cold_storage_enabled: settingsDefaults.cold_storage_enabled,
+    cold_storage_keep_content: settingsDefaults.cold_storage_keep_content,
     content_font_family: settingsDefaults.content_font_family,
     content_font_size: settingsDefaults.content_font_size,
     content_line_height: settingsDefaults.content_line_height,
@@ -84,6 +92,11 @@ export function generateInitialSettings(): SettingsData {
     obsidian_enabled: settingsDefaults.obsidian_enabled,
     obsidian_vault: settingsDefaults.obsidian_vault,
     obsidian_vault_path: settingsDefaults.obsidian_vault_path,
+    offline_mode: settingsDefaults.offline_mode,
+    offline_prefetch_categories: settingsDefaults.offline_prefetch_categories,
+    offline_prefetch_enabled: settingsDefaults.offline_prefetch_enabled,
+    offline_prefetch_filters: settingsDefaults.offline_prefetch_filters,
+    offline_prefetch_max_mb: settingsDefaults.offline_prefetch_max_mb,
     proxy_enabled: settingsDefaults.proxy_enabled,
     proxy_host: settingsDefaults.proxy_host,
     proxy_password: settingsDefaults.proxy_password,
@@ -95,6 +108,8 @@ export function generateInitialSettings(): SettingsData {
     rsshub_api_key: settingsDefaults.rsshub_api_key,
     rsshub_enabled: settingsDefaults.rsshub_enabled,
     rsshub_endpoint: settingsDefaults.rsshub_endpoint,
+    rsshub_health_check_interval: settingsDefaults.rsshub_health_check_interval,
+    rsshub_instances: settingsDefaults.rsshub_instances,
     rules: settingsDefaults.rules,
     shortcuts: settingsDefaults.shortcuts,
     shortcuts_enabled: settingsDefaults.shortcuts_enabled,
@@ -116,6 +131,7 @@ export function generateInitialSettings(): SettingsData {
     translation_provider: settingsDefaults.translation_provider,
     update_check_enabled: settingsDefaults.update_check_enabled,
     update_interval: settingsDefaults.update_interval,
+    webpage_reader_safe: settingsDefaults.webpage_reader_safe,
     window_height: settingsDefaults.window_height,
     window_maximized: settingsDefaults.window_maximized,
     window_width: settingsDefaults.window_width,
@@ -148,11 +164,20 @@ export function parseSettingsData(data: Record<string, string>): SettingsData {
     ai_translation_prompt: data.ai_translation_prompt || settingsDefaults.ai_translation_prompt,
     ai_usage_limit: data.ai_usage_limit || settingsDefaults.ai_usage_limit,
     ai_usage_tokens: data.ai_usage_tokens || settingsDefaults.ai_usage_tokens,
+    archive_favorites: data.archive_favorites === 'true',
     auto_cleanup_enabled: data.auto_cleanup_enabled === 'true',
     auto_show_all_content: data.auto_show_all_content === 'true',
+    backup_compress: data.backup_compress === 'true',
+    backup_enabled: data.backup_enabled === 'true',
+    backup_interval_hours:
+      parseInt(data.backup_interval_hours) || settingsDefaults.backup_interval_hours,
+    backup_keep_daily: parseInt(data.backup_keep_daily) || settingsDefaults.backup_keep_daily,
+    backup_keep_weekly: parseInt(data.backup_keep_weekly) || settingsDefaults.backup_keep_weekly,
     baidu_app_id: data.baidu_app_id || settingsDefaults.baidu_app_id,
     baidu_secret_key: data.baidu_secret_key || settingsDefaults.baidu_secret_key,
     close_to_tray: data.close_to_tray === 'true',
+    cold_storage_enabled: data.cold_storage_enabled === 'true',
+    cold_storage_keep_content: data.cold_storage_keep_content === 'true',
     content_font_family: data.content_font_family || settingsDefaults.content_font_family,
     content_font_size: parseInt(data.content_font_size) || settingsDefaults.content_font_size,
     content_line_height: data.content_line_height || settingsDefaults.content_line_height,
@@ -220,6 +245,14 @@ export function parseSettingsData(data: Record<string, string>): SettingsData {
     obsidian_enabled: data.obsidian_enabled === 'true',
     obsidian_vault: data.obsidian_vault || settingsDefaults.obsidian_vault,
     obsidian_vault_path: data.obsidian_vault_path || settingsDefaults.obsidian_vault_path,
+    offline_mode: data.offline_mode === 'true',
+    offline_prefetch_categories:
+      data.offline_prefetch_categories || settingsDefaults.offline_prefetch_categories,
+    offline_prefetch_enabled: data.offline_prefetch_enabled === 'true',
+    offline_prefetch_filters:
+      data.offline_prefetch_filters || settingsDefaults.offline_prefetch_filters,
+    offline_prefetch_max_mb:
+      parseInt(data.offline_prefetch_max_mb) || settingsDefaults.offline_prefetch_max_mb,
     proxy_enabled: data.proxy_enabled === 'true',
     proxy_host: data.proxy_host || settingsDefaults.proxy_host,
     proxy_password: data.proxy_password || settingsDefaults.proxy_password,
@@ -232,6 +265,9 @@ export function parseSettingsData(data: Record<string, string>): SettingsData {
     rsshub_api_key: data.rsshub_api_key || settingsDefaults.rsshub_api_key,
     rsshub_enabled: data.rsshub_enabled === 'true',
     rsshub_endpoint: data.rsshub_endpoint || settingsDefaults.rsshub_endpoint,
+    rsshub_health_check_interval:
+      parseInt(data.rsshub_health_check_interval) || settingsDefaults.rsshub_health_check_interval,
+    rsshub_instances: data.rsshub_instances || settingsDefaults.rsshub_instances,
     rules: data.rules || settingsDefaults.rules,
     shortcuts: data.shortcuts || settingsDefaults.shortcuts,
     shortcuts_enabled: data.shortcuts_enabled === 'true',
@@ -253,6 +289,7 @@ export function parseSettingsData(data: Record<string, string>): SettingsData {
     translation_provider: data.translation_provider || settingsDefaults.translation_provider,
     update_check_enabled: data.update_check_enabled === 'true',
     update_interval: parseInt(data.update_interval) || settingsDefaults.update_interval,
+    webpage_reader_safe: data.webpage_reader_safe === 'true',
     window_height: data.window_height || settingsDefaults.window_height,
     window_maximized: data.window_maximized || settingsDefaults.window_maximized,
     window_width: data.window_width || settingsDefaults.window_width,
@@ -292,15 +329,39 @@ export function buildAutoSavePayload(settingsRef: Ref<SettingsData>): Record<str
       settingsRef.value.ai_translation_prompt ?? settingsDefaults.ai_translation_prompt,
     ai_usage_limit: settingsRef.value.ai_usage_limit ?? settingsDefaults.ai_usage_limit,
     ai_usage_tokens: settingsRef.value.ai_usage_tokens ?? settingsDefaults.ai_usage_tokens,
+    archive_favorites: (
+      settingsRef.value.archive_favorites ?? settingsDefaults.archive_favorites
+    ).toString(),
     auto_cleanup_enabled: (
       settingsRef.value.auto_cleanup_enabled ?? settingsDefaults.auto_cleanup_enabled
     ).toString(),
     auto_show_all_content: (
       settingsRef.value.auto_show_all_content ?? settingsDefaults.auto_show_all_content
     ).toString(),
+    backup_compress: (
+      settingsRef.value.backup_compress ?? settingsDefaults.backup_compress
+    ).toString(),
+    backup_enabled: (
+      settingsRef.value.backup_enabled ?? settingsDefaults.backup_enabled
+    ).toString(),
+    backup_interval_hours: (
+      settingsRef.value.backup_interval_hours ?? settingsDefaults.backup_interval_hours
+    ).toString(),
+    backup_keep_daily: (
+      settingsRef.value.backup_keep_daily ?? settingsDefaults.backup_keep_daily
+    ).toString(),
+    backup_keep_weekly: (
+      settingsRef.value.backup_keep_weekly ?? settingsDefaults.backup_keep_weekly
+    ).toString(),
     baidu_app_id: settingsRef.value.baidu_app_id ?? settingsDefaults.baidu_app_id,
     baidu_secret_key: settingsRef.value.baidu_secret_key ?? settingsDefaults.baidu_secret_key,
     close_to_tray: (settingsRef.value.close_to_tray ?? settingsDefaults.close_to_tray).toString(),
+    cold_storage_enabled: (
+      settingsRef.value.cold_storage_enabled ?? settingsDefaults.cold_storage_enabled
+    ).toString(),
+    cold_storage_keep_content: (
+      settingsRef.value.cold_storage_keep_content ?? settingsDefaults.cold_storage_keep_content
+    ).toString(),
     content_font_family:
       settingsRef.value.content_font_family ?? settingsDefaults.content_font_family,
     content_font_size: (
@@ -403,6 +464,17 @@ export function buildAutoSavePayload(settingsRef: Ref<SettingsData>): Record<str
     obsidian_vault: settingsRef.value.obsidian_vault ?? settingsDefaults.obsidian_vault,
     obsidian_vault_path:
       settingsRef.value.obsidian_vault_path ?? settingsDefaults.obsidian_vault_path,
+    offline_mode: (settingsRef.value.offline_mode ?? settingsDefaults.offline_mode).toString(),
+    offline_prefetch_categories:
+      settingsRef.value.offline_prefetch_categories ?? settingsDefaults.offline_prefetch_categories,
+    offline_prefetch_enabled: (
+      settingsRef.value.offline_prefetch_enabled ?? settingsDefaults.offline_prefetch_enabled
+    ).toString(),
+    offline_prefetch_filters:
+      settingsRef.value.offline_prefetch_filters ?? settingsDefaults.offline_prefetch_filters,
+    offline_prefetch_max_mb: (
+      settingsRef.value.offline_prefetch_max_mb ?? settingsDefaults.offline_prefetch_max_mb
+    ).toString(),
     proxy_enabled: (settingsRef.value.proxy_enabled ?? settingsDefaults.proxy_enabled).toString(),
     proxy_host: settingsRef.value.proxy_host ?? settingsDefaults.proxy_host,
     proxy_password: settingsRef.value.proxy_password ?? settingsDefaults.proxy_password,
@@ -418,6 +490,10 @@ export function buildAutoSavePayload(settingsRef: Ref<SettingsData>): Record<str
       settingsRef.value.rsshub_enabled ?? settingsDefaults.rsshub_enabled
     ).toString(),
     rsshub_endpoint: settingsRef.value.rsshub_endpoint ?? settingsDefaults.rsshub_endpoint,
+    rsshub_health_check_interval: (
+      settingsRef.value.rsshub_health_check_interval ?? settingsDefaults.rsshub_health_check_interval
+    ).toString(),
+    rsshub_instances: settingsRef.value.rsshub_instances ?? settingsDefaults.rsshub_instances,
     rules: settingsRef.value.rules ?? settingsDefaults.rules,
     shortcuts: settingsRef.value.shortcuts ?? settingsDefaults.shortcuts,
     shortcuts_enabled: (
@@ -461,6 +537,9 @@ export function buildAutoSavePayload(settingsRef: Ref<SettingsData>): Record<str
     update_interval: (
       settingsRef.value.update_interval ?? settingsDefaults.update_interval
     ).toString(),
+    webpage_reader_safe: (
+      settingsRef.value.webpage_reader_safe ?? settingsDefaults.webpage_reader_safe
+    ).toString(),
     zotero_api_key: settingsRef.value.zotero_api_key ?? settingsDefaults.zotero_api_key,
     zotero_enabled: (
       settingsRef.value.zotero_enabled ?? settingsDefaults.zotero_enabled
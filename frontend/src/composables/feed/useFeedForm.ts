@@ -22,6 +22,7 @@ export function useFeedForm(feed?: Feed) {
   const showCustomCategory = ref(false);
   const scriptPath = ref('');
   const hideFromTimeline = ref(false);
+  const allowPrivateNetwork = ref(false);
   const isImageMode = ref(false);
 
   // XPath fields
@@ -213,6 +214,7 @@ export function useFeedForm(feed?: Feed) {
     category.value = feed.category;
     scriptPath.value = feed.script_path || '';
     hideFromTimeline.value = feed.hide_from_timeline || false;
+    allowPrivateNetwork.value = feed.allow_private_network || false;
     isImageMode.value = feed.is_image_mode || false;
 
     // Initialize XPath fields
@@ -312,6 +314,7 @@ export function useFeedForm(feed?: Feed) {
     category.value = '';
     scriptPath.value = '';
     hideFromTimeline.value = false;
+    allowPrivateNetwork.value = false;
     isImageMode.value = false;
     xpathType.value = 'HTML+XPath';
     xpathItem.value = '';
@@ -378,6 +381,7 @@ export function useFeedForm(feed?: Feed) {
     showCustomCategory,
     scriptPath,
     hideFromTimeline,
+    allowPrivateNetwork,
     isImageMode,
     xpathType,
     xpathItem,
@@ -234,6 +234,7 @@ export function useFeedManagement() {
           image_url: feed.image_url,
           script_path: feed.script_path,
           hide_from_timeline: feed.hide_from_timeline,
+          allow_private_network: feed.allow_private_network,
           proxy_url: feed.proxy_url,
           proxy_enabled: feed.proxy_enabled,
           refresh_interval: feed.refresh_interval,
@@ -288,6 +289,7 @@ export function useFeedManagement() {
           image_url: feed.image_url,
           script_path: feed.script_path,
           hide_from_timeline: feed.hide_from_timeline,
+          allow_private_network: feed.allow_private_network,
           proxy_url: feed.proxy_url,
           proxy_enabled: feed.proxy_enabled,
           refresh_interval: feed.refresh_interval,
@@ -360,6 +362,7 @@ export function useFeedManagement() {
           image_url: feed.image_url,
           script_path: feed.script_path,
           hide_from_timeline: feed.hide_from_timeline,
+          allow_private_network: feed.allow_private_network,
           proxy_url: feed.proxy_url,
           proxy_enabled: feed.proxy_enabled,
           refresh_interval: feed.refresh_interval,
@@ -417,6 +420,7 @@ export function useFeedManagement() {
           image_url: feed.image_url,
           script_path: feed.script_path,
           hide_from_timeline: feed.hide_from_timeline,
+          allow_private_network: feed.allow_private_network,
           proxy_url: feed.proxy_url,
           proxy_enabled: feed.proxy_enabled,
           refresh_interval: feed.refresh_interval,
@@ -146,6 +146,7 @@ export function useRuleOptions() {
     { value: 'mark_unread', labelKey: 'setting.rule.actionMarkUnread' },
     { value: 'read_later', labelKey: 'setting.rule.actionReadLater' },
     { value: 'remove_read_later', labelKey: 'setting.rule.actionRemoveReadLater' },
+    { value: 'archive', labelKey: 'setting.rule.actionArchive' },
   ];
 
   // Feed names for multi-select
@@ -278,6 +278,7 @@ const en: TranslationMessages = {
       days: 'days',
       daysAgo: '{count} days ago',
       hoursAgo: '{count} hours ago',
+      hours: 'hours',
       justNow: 'Just now',
       minutes: 'minutes',
       minutesAgo: '{count} minutes ago',
@@ -491,6 +492,12 @@ const en: TranslationMessages = {
       feedLastUpdateStatus: 'Feed Update Status',
       updateSuccess: 'Success',
       updateFailed: 'Failed',
+      searchQuery: 'Search Query',
+      queryPlaceholder: 'e.g. feed:"Hacker News" is:unread after:-7d title:/^rust/ -word',
+      useQuery: 'Use Query',
+      queryHelp:
+        'Fields: feed: cat: tag: type: title: author: url: content: is:unread/read/starred/hidden/later has:image/audio/video/summary/translation after: before: (2025-01-31 or -7d, -12h). Prefix - to negate, separate with OR for alternatives, /pattern/ for regular expressions, = for exact matches.',
+      queryError: 'Query error: {message}',
       logicPrecedence:
         'Conditions are evaluated with the following precedence: NOT > AND > OR. This means NOT is evaluated first, then AND, and finally OR.',
     },
@@ -790,6 +797,9 @@ const en: TranslationMessages = {
     database: {
       articleContentCacheCleanup: 'Article Content Cache',
       articleContentCacheCleanupDesc: 'Clear all cached article content',
+      archiveFavorites: 'Archive Favorites Offline',
+      archiveFavoritesDesc:
+        'Save a self-contained copy of each favorite with its images, kept even when the site goes away',
       autoCleanup: 'Auto Cleanup',
       autoCleanupDesc: 'Automatically remove old articles to save space',
       clean: 'Clean',
@@ -806,6 +816,27 @@ const en: TranslationMessages = {
       days: 'days',
       maxArticleAge: 'Max Article Age',
       maxArticleAgeDesc: 'Delete articles older than this many days (except favorites)',
+      coldStorage: 'Cold Storage Archive',
+      coldStorageDesc:
+        'Move cleaned-up articles to a separate archive file instead of deleting them, so they stay searchable',
+      coldStorageKeepContent: 'Keep Content in Archive',
+      coldStorageKeepContentDesc: 'Also archive the cached content of articles, compressed',
+      backupEnabled: 'Scheduled Backups',
+      backupEnabledDesc:
+        'Regularly take verified snapshots of the database, which can be downloaded or restored on the next start',
+      backupInterval: 'Backup Interval',
+      backupIntervalDesc: 'How often a snapshot is taken',
+      backupKeepDaily: 'Daily Copies',
+      backupKeepDailyDesc: 'Keep the newest snapshot of this many days',
+      backupKeepWeekly: 'Weekly Copies',
+      backupKeepWeeklyDesc: 'Keep the newest snapshot of this many weeks',
+      backupCompress: 'Compress Backups',
+      backupCompressDesc: 'Store snapshots gzip-compressed',
+      backupNow: 'Back Up Now',
+      backupNowDesc: 'Take a manual snapshot, which is never rotated away',
+      backupNowStart: 'Back Up',
+      backupNowDone: 'Database snapshot taken',
+      backupNowFailed: 'Failed to take a database snapshot',
       maxCacheSize: 'Max Cache Size',
       maxCacheSizeDesc: 'Maximum database size before cleanup',
       mediaCacheCleanup: 'Clean Media Cache',
@@ -817,6 +848,19 @@ const en: TranslationMessages = {
       mediaCacheMaxAgeDesc: 'Delete cached media older than this many days',
       mediaCacheMaxSize: 'Max Cache Size',
       mediaCacheMaxSizeDesc: 'Maximum media cache size',
+      offlineMode: 'Offline Mode',
+      offlineModeDesc:
+        'Serve articles, content and images only from caches; read and favorite changes are synced once back online',
+      offlinePrefetch: 'Prefetch for Offline Reading',
+      offlinePrefetchDesc:
+        'After each refresh, download full text and images of unread articles (needs media cache for images)',
+      offlinePrefetchMaxSize: 'Prefetch Budget',
+      offlinePrefetchMaxSizeDesc: 'Maximum data downloaded by one prefetch run',
+      offlinePrefetchNow: 'Prefetch Now',
+      offlinePrefetchNowDesc: 'Start a prefetch run without waiting for the next refresh',
+      offlinePrefetchStart: 'Start Prefetch',
+      offlinePrefetchStarted: 'Offline prefetch started',
+      offlinePrefetchBusy: 'A prefetch is already running',
       clearArticleContentCacheConfirm:
         'Are you sure you want to clear all article content cache? This action cannot be undone.',
       clearMediaCacheConfirm:
@@ -835,6 +879,9 @@ const en: TranslationMessages = {
       fixedInterval: 'Fixed Interval',
       imageMode: 'Multimedia Mode',
       imageModeDesc: 'Display this feed in multimedia gallery view instead of article list',
+      allowPrivateNetwork: 'Allow Private Network',
+      allowPrivateNetworkDesc:
+        'Let images, pages and full text for this feed be fetched from local network addresses. Only enable for trusted intranet feeds',
       intelligentInterval: 'Intelligent Interval',
       neverRefresh: 'Never Refresh',
       refreshMode: 'Refresh Mode',
@@ -1002,6 +1049,9 @@ const en: TranslationMessages = {
         'Automatically mark articles as read when hovering over them (does not apply to Read Later articles)',
       imageGalleryEnabled: 'Enable Multimedia Gallery',
       imageGalleryEnabledDesc: 'Enable multimedia waterfall mode for multimedia-focused feeds',
+      webpageReaderSafe: 'Reader-Safe Webpage View',
+      webpageReaderSafeDesc:
+        'Remove scripts from original webpages shown in the reader. Pages load faster and cannot track you, but interactive content may not work',
       showAdvancedSettings: 'Show Advanced Settings',
       showArticlePreviewImages: 'Show Preview Images',
       showArticlePreviewImagesDesc: 'Display preview images in the article list',
@@ -1042,6 +1092,7 @@ const en: TranslationMessages = {
       actionMarkUnread: 'Mark as Unread',
       actionReadLater: 'Add to Read Later',
       actionRemoveReadLater: 'Remove from Read Later',
+      actionArchive: 'Save Offline Archive',
       actionUnfavorite: 'Remove from Favorites',
       actionUnhide: 'Unhide Article',
       addRule: 'Add Rule',
@@ -272,6 +272,7 @@ const zh: TranslationMessages = {
       days: '天',
       daysAgo: '{count} 天前',
       hoursAgo: '{count} 小时前',
+      hours: '小时',
       justNow: '刚刚',
       minutes: '分钟',
       minutesAgo: '{count} 分钟前',
@@ -483,6 +484,12 @@ const zh: TranslationMessages = {
       feedLastUpdateStatus: '订阅源更新状态',
       updateSuccess: '成功',
       updateFailed: '失败',
+      searchQuery: '搜索查询',
+      queryPlaceholder: '例如 feed:"Hacker News" is:unread after:-7d title:/^rust/ -word',
+      useQuery: '使用查询',
+      queryHelp:
+        '字段：feed: cat: tag: type: title: author: url: content: is:unread/read/starred/hidden/later has:image/audio/video/summary/translation after: before:（2025-01-31 或 -7d、-12h）。前缀 - 表示取反，用 OR 分隔表示“或”，/pattern/ 表示正则表达式，= 表示精确匹配。',
+      queryError: '查询错误：{message}',
       logicPrecedence:
         '条件按以下优先级进行计算：NOT > AND > OR。这意味着 NOT 最先计算，然后是 AND，最后是 OR。',
     },
@@ -777,6 +784,8 @@ const zh: TranslationMessages = {
     database: {
       articleContentCacheCleanup: '文章内容缓存',
       articleContentCacheCleanupDesc: '清除所有缓存的文章内容',
+      archiveFavorites: '离线存档收藏',
+      archiveFavoritesDesc: '为每篇收藏保存包含图片的独立副本，原网站失效后仍可阅读',
       autoCleanup: '自动清理',
       autoCleanupDesc: '自动删除旧文章以节省空间',
       clean: '清理',
@@ -792,6 +801,25 @@ const zh: TranslationMessages = {
       days: '天',
       maxArticleAge: '文章最大保留天数',
       maxArticleAgeDesc: '删除超过此天数的文章（收藏除外）',
+      coldStorage: '冷存储归档',
+      coldStorageDesc: '将清理的文章移入单独的归档文件而不是删除，以便仍可搜索',
+      coldStorageKeepContent: '归档文章内容',
+      coldStorageKeepContentDesc: '同时压缩归档文章的缓存内容',
+      backupEnabled: '定时备份',
+      backupEnabledDesc: '定期为数据库创建经过校验的快照，可下载或在下次启动时恢复',
+      backupInterval: '备份间隔',
+      backupIntervalDesc: '创建快照的频率',
+      backupKeepDaily: '每日副本',
+      backupKeepDailyDesc: '保留最近若干天中每天最新的快照',
+      backupKeepWeekly: '每周副本',
+      backupKeepWeeklyDesc: '保留最近若干周中每周最新的快照',
+      backupCompress: '压缩备份',
+      backupCompressDesc: '使用 gzip 压缩存储快照',
+      backupNow: '立即备份',
+      backupNowDesc: '创建一个手动快照，轮换时不会被删除',
+      backupNowStart: '备份',
+      backupNowDone: '已创建数据库快照',
+      backupNowFailed: '创建数据库快照失败',
       maxCacheSize: '最大缓存大小',
       maxCacheSizeDesc: '清理前的最大数据库大小',
       mediaCacheCleanup: '清理媒体缓存',
@@ -803,6 +831,17 @@ const zh: TranslationMessages = {
       mediaCacheMaxAgeDesc: '删除超过此天数的缓存媒体',
       mediaCacheMaxSize: '最大缓存大小',
       mediaCacheMaxSizeDesc: '媒体缓存最大大小',
+      offlineMode: '离线模式',
+      offlineModeDesc: '仅从缓存提供文章、内容和图片；已读和收藏的更改将在恢复联网后同步',
+      offlinePrefetch: '离线阅读预取',
+      offlinePrefetchDesc: '每次刷新后下载未读文章的全文和图片（图片需要启用媒体缓存）',
+      offlinePrefetchMaxSize: '预取额度',
+      offlinePrefetchMaxSizeDesc: '单次预取下载的最大数据量',
+      offlinePrefetchNow: '立即预取',
+      offlinePrefetchNowDesc: '不等待下次刷新，立即开始预取',
+      offlinePrefetchStart: '开始预取',
+      offlinePrefetchStarted: '已开始离线预取',
+      offlinePrefetchBusy: '预取已在进行中',
       clearArticleContentCacheConfirm: '确定要清空所有文章内容缓存吗？此操作不可撤销。',
       clearMediaCacheConfirm: '确定要清空所有媒体缓存吗？此操作不可撤销。',
     },
@@ -817,6 +856,8 @@ const zh: TranslationMessages = {
       fixedInterval: '固定间隔',
       imageMode: '多媒体模式',
       imageModeDesc: '以多媒体库视图而非文章列表展示此订阅源',
+      allowPrivateNetwork: '允许访问内网',
+      allowPrivateNetworkDesc: '允许从局域网地址获取此订阅源的图片、网页和全文。仅对可信的内网订阅源启用',
       intelligentInterval: '智能间隔',
       neverRefresh: '不刷新',
       refreshMode: '刷新模式',
@@ -981,6 +1022,8 @@ const zh: TranslationMessages = {
       hoverMarkAsReadDesc: '鼠标悬停在文章上时自动标记为已读（不适用于稍后阅读的文章）',
       imageGalleryEnabled: '启用多媒体库',
       imageGalleryEnabledDesc: '为图片、视频等媒体类订阅源启用多媒体瀑布流模式',
+      webpageReaderSafe: '安全网页视图',
+      webpageReaderSafeDesc: '在阅读器中显示原网页时移除脚本。页面加载更快且无法跟踪你，但交互内容可能无法使用',
       showAdvancedSettings: '显示高级设置',
       showArticlePreviewImages: '显示预览图片',
       showArticlePreviewImagesDesc: '在文章列表中显示预览图片',
@@ -1021,6 +1064,7 @@ const zh: TranslationMessages = {
       actionMarkUnread: '标记为未读',
       actionReadLater: '添加到稍后阅读',
       actionRemoveReadLater: '从稍后阅读中移除',
+      actionArchive: '保存离线存档',
       actionUnfavorite: '取消收藏',
       actionUnhide: '取消隐藏',
       addRule: '添加规则',
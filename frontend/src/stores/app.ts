@@ -45,6 +45,7 @@ export interface AppActions {
   loadMore: () => Promise<void>;
   fetchFeeds: () => Promise<void>;
   fetchUnreadCounts: () => Promise<void>;
+  fetchCounts: () => Promise<void>;
   markAllAsRead: (feedId?: number, category?: string) => Promise<void>;
   updateArticleSummary: (articleId: number, summary: string) => void;
   toggleTheme: () => void;
@@ -91,6 +92,9 @@ export const useAppStore = defineStore('app', () => {
   const isLoading = ref<boolean>(false);
   const page = ref<number>(1);
   const hasMore = ref<boolean>(true);
+  // Cursor of the next page; pages are read by cursor so that new articles
+  // arriving while scrolling do not shift them
+  const nextCursor = ref<string>('');
   const searchQuery = ref<string>('');
   const themePreference = ref<ThemePreference>(
     (localStorage.getItem('themePreference') as ThemePreference) || 'auto'
@@ -190,7 +194,8 @@ export const useAppStore = defineStore('app', () => {
     isLoading.value = true;
     const limit = 50;
 
-    let url = `/api/articles?page=${page.value}&limit=${limit}`;
+    const cursor = append ? nextCursor.value : '';
+    let url = `/api/articles?cursor=${encodeURIComponent(cursor)}&limit=${limit}`;
     if (currentFilter.value) url += `&filter=${currentFilter.value}`;
     if (showOnlyUnread.value && currentFilter.value !== 'unread') url += '&only_unread=true';
     if (currentFeedId.value) url += `&feed_id=${currentFeedId.value}`;
@@ -199,9 +204,11 @@ export const useAppStore = defineStore('app', () => {
 
     try {
       const res = await fetch(url);
-      const data: Article[] = (await res.json()) || [];
+      const result: { articles: Article[]; next_cursor: string } = await res.json();
+      const data: Article[] = result?.articles || [];
 
-      if (data.length < limit) {
+      nextCursor.value = result?.next_cursor || '';
+      if (!nextCursor.value) {
         hasMore.value = false;
       }
 
@@ -242,8 +249,7 @@ export const useAppStore = defineStore('app', () => {
       feeds.value = data;
 
       // Fetch unread counts and filter counts after fetching feeds
-      await fetchUnreadCounts();
-      await fetchFilterCounts();
+      await fetchCounts();
       // Fetch tags after fetching feeds
       await fetchTags();
     } catch (e) {
@@ -263,34 +269,43 @@ export const useAppStore = defineStore('app', () => {
     }
   }
 
-  async function fetchUnreadCounts(): Promise<void> {
-    try {
-      const res = await fetch('/api/articles/unread-counts');
-      const data = await res.json();
-      unreadCounts.value = {
-        total: data.total || 0,
-        feedCounts: data.feed_counts || {},
-      };
-    } catch {
-      unreadCounts.value = { total: 0, feedCounts: {} };
-    }
+  function emptyFilterCounts(): Record<string, Record<number | string, number>> {
+    return {
+      unread: {},
+      favorites: {},
+      favorites_unread: {},
+      read_later: {},
+      read_later_unread: {},
+      images: {},
+      images_unread: {},
+      saved_filters: {},
+    };
   }
 
   // Filter-specific counts for sidebar filtering
-  const filterCounts = ref<Record<string, Record<number | string, number>>>({
-    unread: {},
-    favorites: {},
-    favorites_unread: {},
-    read_later: {},
-    read_later_unread: {},
-    images: {},
-    images_unread: {},
-  });
+  const filterCounts = ref<Record<string, Record<number | string, number>>>(emptyFilterCounts());
 
-  async function fetchFilterCounts(): Promise<void> {
+  // Unread and filter counts come from one request; calls made while it is in
+  // flight share it
+  let countsRequest: Promise<void> | null = null;
+
+  async function fetchCounts(): Promise<void> {
+    if (!countsRequest) {
+      countsRequest = loadCounts().finally(() => {
+        countsRequest = null;
+      });
+    }
+    return countsRequest;
+  }
+
+  async function loadCounts(): Promise<void> {
     try {
-      const res = await fetch('/api/articles/filter-counts');
+      const res = await fetch('/api/articles/counts');
       const data = await res.json();
+      unreadCounts.value = {
+        total: data.total || 0,
+        feedCounts: data.unread || {},
+      };
       filterCounts.value = {
         unread: data.unread || {},
         favorites: data.favorites || {},
@@ -299,21 +314,19 @@ export const useAppStore = defineStore('app', () => {
         read_later_unread: data.read_later_unread || {},
         images: data.images || {},
         images_unread: data.images_unread || {},
+        saved_filters: data.saved_filters || {},
       };
     } catch (e) {
-      console.error('[App Store] Fetch filter counts error:', e);
-      filterCounts.value = {
-        unread: {},
-        favorites: {},
-        favorites_unread: {},
-        read_later: {},
-        read_later_unread: {},
-        images: {},
-        images_unread: {},
-      };
+      console.error('[App Store] Fetch counts error:', e);
+      unreadCounts.value = { total: 0, feedCounts: {} };
+      filterCounts.value = emptyFilterCounts();
     }
   }
 
+  // Both kinds of counts are refreshed together
+  const fetchUnreadCounts = fetchCounts;
+  const fetchFilterCounts = fetchCounts;
+
   async function markAllAsRead(feedId?: number, category?: string): Promise<void> {
     try {
       const params = new URLSearchParams();
@@ -332,8 +345,7 @@ export const useAppStore = defineStore('app', () => {
         }
         return { ...article, is_read: true };
       });
-      await fetchUnreadCounts();
-      await fetchFilterCounts();
+      await fetchCounts();
     } catch {
       // Error handled silently
     }
@@ -838,6 +850,7 @@ export const useAppStore = defineStore('app', () => {
     loadMore,
     fetchFeeds,
     fetchTags,
+    fetchCounts,
     fetchUnreadCounts,
     fetchFilterCounts,
     markAllAsRead,
@@ -47,6 +47,7 @@ export interface Feed {
   proxy_enabled?: boolean;
   refresh_interval?: number;
   is_image_mode?: boolean;
+  allow_private_network?: boolean;
   // XPath support
   type?: string;
   xpath_item?: string;
@@ -175,7 +176,8 @@ export type RuleAction =
   | { type: 'mark_read' }
   | { type: 'mark_unread' }
   | { type: 'read_later' }
-  | { type: 'remove_read_later' };
+  | { type: 'remove_read_later' }
+  | { type: 'archive' };
 
 export interface KeyboardShortcut {
   action: string;
@@ -19,11 +19,19 @@ export interface SettingsData {
   ai_translation_prompt: string;
   ai_usage_limit: string;
   ai_usage_tokens: string;
+  archive_favorites: boolean;
   auto_cleanup_enabled: boolean;
   auto_show_all_content: boolean;
+  backup_compress: boolean;
+  backup_enabled: boolean;
+  backup_interval_hours: number;
+  backup_keep_daily: number;
+  backup_keep_weekly: number;
   baidu_app_id: string;
   baidu_secret_key: string;
   close_to_tray: boolean;
+  cold_storage_enabled: boolean;
+  cold_storage_keep_content: boolean;
   content_font_family: string;
   content_font_size: number;
   content_line_height: string;
@@ -76,6 +84,11 @@ export interface SettingsData {
   obsidian_enabled: boolean;
   obsidian_vault: string;
   obsidian_vault_path: string;
+  offline_mode: boolean;
+  offline_prefetch_categories: string;
+  offline_prefetch_enabled: boolean;
+  offline_prefetch_filters: string;
+  offline_prefetch_max_mb: number;
   proxy_enabled: boolean;
   proxy_host: string;
   proxy_password: string;
@@ -87,6 +100,8 @@ export interface SettingsData {
   rsshub_api_key: string;
   rsshub_enabled: boolean;
   rsshub_endpoint: string;
+  rsshub_health_check_interval: number;
+  rsshub_instances: string;
   rules: string;
   shortcuts: string;
   shortcuts_enabled: boolean;
@@ -108,6 +123,7 @@ export interface SettingsData {
   translation_provider: string;
   update_check_enabled: boolean;
   update_interval: number;
+  webpage_reader_safe: boolean;
   window_height: string;
   window_maximized: string;
   window_width: string;
@@ -0,0 +1,391 @@
+// Package archive builds self-contained offline copies of articles.
+//
+// An archive is a single HTML file: the article content with every image,
+// stylesheet and font it references downloaded and embedded as a data URI,
+// so the copy keeps working after the original site or its images are gone.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// maxResourceSize limits the size of a single embedded resource
+	maxResourceSize = 20 << 20
+	// maxTotalSize limits the size of all embedded resources of an archive
+	maxTotalSize = 200 << 20
+	// maxCSSDepth limits how deeply stylesheets importing stylesheets are followed
+	maxCSSDepth = 3
+)
+
+// ErrEmpty is returned when there is no content to archive
+var ErrEmpty = errors.New("nothing to archive")
+
+// Document is the article to archive
+type Document struct {
+	Title       string
+	Author      string
+	SourceURL   string // Original article URL, also the base for relative links
+	PublishedAt time.Time
+	Content     string // Article HTML
+}
+
+var pageTemplate = template.Must(template.New("archive").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="generator" content="MrRSS">
+<title>{{.Title}}</title>
+<style>
+body{max-width:48rem;margin:2rem auto;padding:0 1rem;font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",sans-serif;line-height:1.6;color:#222}
+img,video{max-width:100%;height:auto}
+pre{overflow:auto}
+header{border-bottom:1px solid #ddd;margin-bottom:1.5rem;color:#555;font-size:.9rem}
+header h1{color:#222;font-size:1.8rem}
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<p>{{if .Author}}{{.Author}} · {{end}}{{if not .PublishedAt.IsZero}}{{.PublishedAt.Format "2006-01-02"}} · {{end}}<a href="{{.SourceURL}}">{{.SourceURL}}</a></p>
+<p>Archived {{.ArchivedAt.Format "2006-01-02 15:04 MST"}}</p>
+</header>
+<article>
+{{.Content}}
+</article>
+</body>
+</html>
+`))
+
+// Build renders doc as a single HTML file and embeds the images,
+// stylesheets and fonts it references. Resources that cannot be downloaded
+// keep their original URL.
+func Build(ctx context.Context, client *http.Client, doc Document) ([]byte, error) {
+	if strings.TrimSpace(doc.Content) == "" {
+		return nil, ErrEmpty
+	}
+	base, err := url.Parse(doc.SourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse source URL: %w", err)
+	}
+
+	var page bytes.Buffer
+	err = pageTemplate.Execute(&page, struct {
+		Document
+		Content    template.HTML
+		ArchivedAt time.Time
+	}{doc, template.HTML(doc.Content), time.Now()})
+	if err != nil {
+		return nil, fmt.Errorf("render archive: %w", err)
+	}
+
+	root, err := html.Parse(&page)
+	if err != nil {
+		return nil, fmt.Errorf("parse content: %w", err)
+	}
+
+	e := &embedder{ctx: ctx, client: client, cache: make(map[string]string)}
+	e.embedNode(root, base)
+
+	var out bytes.Buffer
+	if err := html.Render(&out, root); err != nil {
+		return nil, fmt.Errorf("render archive: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// embedder downloads resources and turns them into data URIs
+type embedder struct {
+	ctx    context.Context
+	client *http.Client
+	cache  map[string]string // Resource URL to data URI, "" for failures
+	total  int64
+}
+
+// embedNode walks the document, embedding resources and removing what an
+// offline copy cannot use
+func (e *embedder) embedNode(n *html.Node, base *url.URL) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode {
+			switch c.DataAtom {
+			case atom.Script, atom.Noscript, atom.Base:
+				n.RemoveChild(c)
+				c = next
+				continue
+			case atom.Link:
+				if style := e.embedStylesheet(c, base); style != nil {
+					n.InsertBefore(style, c)
+					n.RemoveChild(c)
+				}
+				c = next
+				continue
+			case atom.Style:
+				if text := c.FirstChild; text != nil && text.Type == html.TextNode {
+					text.Data = e.embedCSS(text.Data, base, 0)
+				}
+			}
+			e.embedAttributes(c, base)
+		}
+		e.embedNode(c, base)
+		c = next
+	}
+}
+
+// embedAttributes embeds the resources referenced by an element's attributes
+func (e *embedder) embedAttributes(n *html.Node, base *url.URL) {
+	// Lazy-loaded images keep the real URL in a data attribute
+	if n.DataAtom == atom.Img && getAttr(n, "src") == "" {
+		for _, key := range []string{"data-src", "data-original", "data-lazy-src"} {
+			if v := getAttr(n, key); v != "" {
+				setAttr(n, "src", v)
+				break
+			}
+		}
+	}
+
+	for i := 0; i < len(n.Attr); i++ {
+		attr := &n.Attr[i]
+		switch {
+		case attr.Key == "src" && (n.DataAtom == atom.Img || n.DataAtom == atom.Input),
+			attr.Key == "poster" && n.DataAtom == atom.Video:
+			if data := e.fetch(attr.Val, base); data != "" {
+				attr.Val = data
+			} else if ref := resolve(attr.Val, base); ref != "" {
+				attr.Val = ref
+			}
+		case attr.Key == "srcset" && (n.DataAtom == atom.Img || n.DataAtom == atom.Source):
+			attr.Val = e.embedSrcset(attr.Val, base)
+		case attr.Key == "href" && n.DataAtom == atom.A, attr.Key == "src":
+			// Links and other media stay online, with absolute URLs
+			if ref := resolve(attr.Val, base); ref != "" {
+				attr.Val = ref
+			}
+		case attr.Key == "style":
+			attr.Val = e.embedCSS(attr.Val, base, 0)
+		}
+	}
+}
+
+// embedSrcset embeds every candidate of a srcset attribute
+func (e *embedder) embedSrcset(srcset string, base *url.URL) string {
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		if data := e.fetch(fields[0], base); data != "" {
+			fields[0] = data
+		} else if ref := resolve(fields[0], base); ref != "" {
+			fields[0] = ref
+		}
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// embedStylesheet returns a style element with the contents of a
+// <link rel="stylesheet">, or nil to keep the link element
+func (e *embedder) embedStylesheet(n *html.Node, base *url.URL) *html.Node {
+	rel := strings.ToLower(getAttr(n, "rel"))
+	if !strings.Contains(rel, "stylesheet") {
+		if strings.Contains(rel, "icon") {
+			if data := e.fetch(getAttr(n, "href"), base); data != "" {
+				setAttr(n, "href", data)
+			}
+		}
+		return nil
+	}
+
+	ref := resolve(getAttr(n, "href"), base)
+	if ref == "" {
+		return nil
+	}
+	body, _, err := e.download(ref)
+	if err != nil {
+		return nil
+	}
+	sheetURL, _ := url.Parse(ref)
+
+	style := &html.Node{Type: html.ElementNode, Data: "style", DataAtom: atom.Style}
+	if media := getAttr(n, "media"); media != "" {
+		style.Attr = append(style.Attr, html.Attribute{Key: "media", Val: media})
+	}
+	style.AppendChild(&html.Node{Type: html.TextNode, Data: e.embedCSS(string(body), sheetURL, 1)})
+	return style
+}
+
+var (
+	cssURLPattern    = regexp.MustCompile(`url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)`)
+	cssImportPattern = regexp.MustCompile(`@import\s+(['"])([^'"]+)(['"])`)
+)
+
+// embedCSS embeds the images and fonts referenced by a stylesheet. Imported
+// stylesheets are embedded with their own references resolved.
+func (e *embedder) embedCSS(css string, base *url.URL, depth int) string {
+	embed := func(ref string) string {
+		abs := resolve(ref, base)
+		if abs == "" {
+			return ""
+		}
+		if depth < maxCSSDepth && isStylesheet(abs) {
+			body, _, err := e.download(abs)
+			if err != nil {
+				return abs
+			}
+			sheetURL, _ := url.Parse(abs)
+			nested := e.embedCSS(string(body), sheetURL, depth+1)
+			return "data:text/css;base64," + base64.StdEncoding.EncodeToString([]byte(nested))
+		}
+		if data := e.fetch(abs, base); data != "" {
+			return data
+		}
+		return abs
+	}
+
+	css = cssURLPattern.ReplaceAllStringFunc(css, func(match string) string {
+		m := cssURLPattern.FindStringSubmatch(match)
+		if v := embed(m[2]); v != "" {
+			return `url("` + v + `")`
+		}
+		return match
+	})
+	return cssImportPattern.ReplaceAllStringFunc(css, func(match string) string {
+		m := cssImportPattern.FindStringSubmatch(match)
+		if v := embed(m[2]); v != "" {
+			return `@import url("` + v + `")`
+		}
+		return match
+	})
+}
+
+// fetch returns the resource at ref as a data URI, or "" when it cannot be
+// downloaded
+func (e *embedder) fetch(ref string, base *url.URL) string {
+	abs := resolve(ref, base)
+	if abs == "" {
+		return ""
+	}
+	if strings.HasPrefix(abs, "data:") {
+		return abs
+	}
+	if data, ok := e.cache[abs]; ok {
+		return data
+	}
+
+	body, contentType, err := e.download(abs)
+	if err != nil {
+		e.cache[abs] = ""
+		return ""
+	}
+	data := "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(body)
+	e.cache[abs] = data
+	return data
+}
+
+// download fetches a resource within the size limits and returns its body
+// and content type
+func (e *embedder) download(abs string) ([]byte, string, error) {
+	if e.total >= maxTotalSize {
+		return nil, "", errors.New("archive size limit reached")
+	}
+
+	req, err := http.NewRequestWithContext(e.ctx, http.MethodGet, abs, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResourceSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(body) > maxResourceSize {
+		return nil, "", errors.New("resource too large")
+	}
+	e.total += int64(len(body))
+
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType != "application/octet-stream" {
+		contentType = mediaType
+	} else if byExt := mime.TypeByExtension(path.Ext(req.URL.Path)); byExt != "" {
+		contentType, _, _ = mime.ParseMediaType(byExt)
+	} else {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+	return body, contentType, nil
+}
+
+// resolve returns ref as an absolute http(s) or data URL, or "" when it
+// cannot be archived
+func resolve(ref string, base *url.URL) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return ""
+	}
+	if strings.HasPrefix(ref, "data:") {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	u = base.ResolveReference(u)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+// isStylesheet reports whether a URL names a stylesheet
+func isStylesheet(abs string) bool {
+	u, err := url.Parse(abs)
+	return err == nil && strings.EqualFold(path.Ext(u.Path), ".css")
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
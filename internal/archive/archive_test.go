@@ -0,0 +1,123 @@
+package archive
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildEmbedsResources(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nfake image")
+	font := []byte("wOFF fake font")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/img/photo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+	})
+	mux.HandleFunc("/css/site.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte(`@import "extra.css"; body { background: url(../img/photo.png) }`))
+	})
+	mux.HandleFunc("/css/extra.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`@font-face { font-family: X; src: url('fonts/x.woff') }`))
+	})
+	mux.HandleFunc("/css/fonts/x.woff", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(font)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	doc := Document{
+		Title:     "Story <1>",
+		SourceURL: srv.URL + "/posts/story.html",
+		Content: `<link rel="stylesheet" href="/css/site.css">
+<p>Text</p>
+<img src="../img/photo.png" srcset="/img/photo.png 2x">
+<img data-src="/img/photo.png">
+<img src="/img/missing.png">
+<script>alert(1)</script>
+<a href="other.html">Other</a>`,
+	}
+	data, err := Build(context.Background(), srv.Client(), doc)
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	out := string(data)
+
+	pngURI := "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	if strings.Count(out, pngURI) < 4 {
+		t.Errorf("expected the image embedded for src, srcset, data-src and the stylesheet:\n%s", out)
+	}
+	if !strings.Contains(out, "<style>") || strings.Contains(out, "<link") {
+		t.Errorf("expected the stylesheet inlined:\n%s", out)
+	}
+	if !strings.Contains(out, `@import url("data:text/css;base64,`) {
+		t.Errorf("expected the imported stylesheet embedded:\n%s", out)
+	}
+	for _, want := range []string{
+		"Story &lt;1&gt;",
+		`src="` + srv.URL + `/img/missing.png"`,
+		`href="` + srv.URL + `/posts/other.html"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("archive missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<script") {
+		t.Errorf("scripts should be removed:\n%s", out)
+	}
+
+	// The font is embedded inside the imported stylesheet
+	e := &embedder{ctx: context.Background(), client: srv.Client(), cache: make(map[string]string)}
+	base, _ := url.Parse(srv.URL + "/css/site.css")
+	css := e.embedCSS(`@import "extra.css";`, base, 0)
+	encoded := strings.TrimSuffix(strings.TrimPrefix(css, `@import url("data:text/css;base64,`), `");`)
+	nested, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || !strings.Contains(string(nested), base64.StdEncoding.EncodeToString(font)) {
+		t.Errorf("expected the font embedded in the imported stylesheet, got %q (%v)", nested, err)
+	}
+
+	if _, err := Build(context.Background(), srv.Client(), Document{SourceURL: srv.URL, Content: "  "}); err != ErrEmpty {
+		t.Errorf("expected ErrEmpty, got %v", err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archives")
+	store := NewStore(dir)
+
+	if err := store.Save(1, []byte("<html>one</html>")); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	if err := store.Save(2, []byte("<html>two</html>")); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	f, err := store.Open(1)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	f.Close()
+
+	removed, err := store.Prune(map[int64]bool{1: true})
+	if err != nil || removed != 1 {
+		t.Fatalf("Prune = %d, %v; want 1", removed, err)
+	}
+	if _, err := store.Open(2); !os.IsNotExist(err) {
+		t.Errorf("expected archive 2 to be pruned, got %v", err)
+	}
+
+	if err := store.Remove(1); err != nil {
+		t.Fatalf("Remove error: %v", err)
+	}
+	if err := store.Remove(1); err != nil {
+		t.Errorf("removing a missing archive should succeed, got %v", err)
+	}
+}
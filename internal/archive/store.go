@@ -0,0 +1,120 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"MrRSS/internal/utils/fileutil"
+)
+
+// Store keeps archive files in a directory of the data dir, one file per
+// article. The files are not part of the database, so the size-based
+// cleanup never removes them.
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store in dir. An empty dir means the archives
+// directory in the data dir.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory of the archive files
+func (s *Store) Dir() (string, error) {
+	if s.dir != "" {
+		return s.dir, nil
+	}
+	dataDir, err := fileutil.GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "archives"), nil
+}
+
+// Path returns the file of an article's archive
+func (s *Store) Path(articleID int64) (string, error) {
+	dir, err := s.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strconv.FormatInt(articleID, 10)+".html"), nil
+}
+
+// Save writes the archive of an article, replacing an earlier one
+func (s *Store) Save(articleID int64, data []byte) error {
+	path, err := s.Path(articleID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create archive directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial archive
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write archive: %w", err)
+	}
+	return nil
+}
+
+// Open opens the archive of an article
+func (s *Store) Open(articleID int64) (*os.File, error) {
+	path, err := s.Path(articleID)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Remove deletes the archive of an article. A missing archive is not an error.
+func (s *Store) Remove(articleID int64) error {
+	path, err := s.Path(articleID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Prune deletes the archives of articles not in keep, such as those left
+// behind by deleted feeds, and returns how many were deleted
+func (s *Store) Prune(keep map[int64]bool) (int, error) {
+	dir, err := s.Dir()
+	if err != nil {
+		return 0, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".html") {
+			continue
+		}
+		id, err := strconv.ParseInt(strings.TrimSuffix(name, ".html"), 10, 64)
+		if err != nil || keep[id] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
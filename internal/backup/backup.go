@@ -0,0 +1,423 @@
+// Package backup writes and restores versioned archives of all MrRSS user data.
+//
+// An archive is a zip file with a manifest and one JSON file per entity.
+// IDs in the archive are the IDs of the exporting database; a restore maps
+// them to the IDs of the importing database. Secrets (API keys, passwords,
+// proxy credentials) are only included when a passphrase is given, and are
+// then stored in a single file encrypted with that passphrase, because the
+// machine-bound encryption of the settings table cannot be carried over.
+package backup
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"MrRSS/internal/config"
+	"MrRSS/internal/crypto"
+	"MrRSS/internal/database"
+	"MrRSS/internal/models"
+	"MrRSS/internal/utils/urlutil"
+	"MrRSS/internal/version"
+)
+
+// FormatName identifies MrRSS backup archives
+const FormatName = "mrrss-backup"
+
+// FormatVersion is the archive format version written by Export.
+// Import accepts archives up to this version.
+const FormatVersion = 1
+
+// Archive entries
+const (
+	manifestFile          = "manifest.json"
+	settingsFile          = "settings.json"
+	secretsFile           = "secrets.enc"
+	feedsFile             = "feeds.json"
+	tagsFile              = "tags.json"
+	feedTagsFile          = "feed_tags.json"
+	savedFiltersFile      = "saved_filters.json"
+	aiProfilesFile        = "ai_profiles.json"
+	chatSessionsFile      = "chat_sessions.json"
+	statisticsFile        = "statistics.json"
+	articlesFile          = "articles.json"
+	articleContentsFile   = "article_contents.json"
+	subscriptionListsFile = "subscription_lists.json"
+	extractionRulesFile   = "feed_extraction_rules.json"
+	retentionPoliciesFile = "retention_policies.json"
+)
+
+// localSettings are tied to this machine and never leave it
+var localSettings = map[string]bool{
+	"window_x":                true,
+	"window_y":                true,
+	"window_width":            true,
+	"window_height":           true,
+	"window_maximized":        true,
+	"last_global_refresh":     true,
+	"last_network_test":       true,
+	"network_speed":           true,
+	"network_bandwidth_mbps":  true,
+	"network_latency_ms":      true,
+	"freshrss_last_sync_time": true,
+	"custom_css_file":         true,
+}
+
+// Manifest describes an archive
+type Manifest struct {
+	Format          string         `json:"format"`
+	Version         int            `json:"version"`
+	AppVersion      string         `json:"app_version"`
+	CreatedAt       time.Time      `json:"created_at"`
+	IncludesContent bool           `json:"includes_content"`
+	IncludesSecrets bool           `json:"includes_secrets"`
+	Entities        map[string]int `json:"entities"` // Number of records per entity
+}
+
+// ExportOptions controls what goes into an archive
+type ExportOptions struct {
+	// IncludeContent adds the article content cache
+	IncludeContent bool
+	// Passphrase encrypts secrets into the archive. Without it secrets are left out.
+	Passphrase string
+	// EncryptedSettings lists the setting keys stored encrypted
+	EncryptedSettings []string
+}
+
+// feedTag links a feed to a tag by their archive IDs
+type feedTag struct {
+	FeedID int64 `json:"feed_id"`
+	TagID  int64 `json:"tag_id"`
+}
+
+// chatSession is a chat session with its messages
+type chatSession struct {
+	database.ChatSession
+	Messages []database.ChatMessage `json:"messages"`
+}
+
+// statRecord is one statistics counter
+type statRecord struct {
+	EventDate string `json:"event_date"`
+	EventType string `json:"event_type"`
+	Count     int    `json:"count"`
+}
+
+// subscriptionList is a linked OPML list with the archive IDs of the feeds it added
+type subscriptionList struct {
+	models.SubscriptionList
+	FeedIDs []int64 `json:"feed_ids"`
+}
+
+// secrets holds everything that is only written encrypted with the passphrase
+type secrets struct {
+	Settings       map[string]string `json:"settings"`
+	AIProfileKeys  map[int64]string  `json:"ai_profile_keys"`
+	EmailPasswords map[int64]string  `json:"email_passwords"`
+	ProxyURLs      map[int64]string  `json:"proxy_urls"` // Feed proxy URLs that carry credentials
+}
+
+// Export writes an archive of all user data to w and returns its manifest.
+// FreshRSS feeds and their articles are left out; they come back with the next sync.
+func Export(db *database.DB, w io.Writer, opts ExportOptions) (*Manifest, error) {
+	manifest := &Manifest{
+		Format:          FormatName,
+		Version:         FormatVersion,
+		AppVersion:      version.Version,
+		CreatedAt:       time.Now().UTC(),
+		IncludesContent: opts.IncludeContent,
+		IncludesSecrets: opts.Passphrase != "",
+		Entities:        make(map[string]int),
+	}
+	sec := secrets{
+		Settings:       make(map[string]string),
+		AIProfileKeys:  make(map[int64]string),
+		EmailPasswords: make(map[int64]string),
+		ProxyURLs:      make(map[int64]string),
+	}
+
+	zw := zip.NewWriter(w)
+	write := func(name string, count int, v interface{}) error {
+		manifest.Entities[name] = count
+		return writeJSON(zw, name, v)
+	}
+
+	// Settings
+	stored, err := db.GetAllSettingValues()
+	if err != nil {
+		return nil, fmt.Errorf("read settings: %w", err)
+	}
+	encrypted := make(map[string]bool, len(opts.EncryptedSettings))
+	for _, key := range opts.EncryptedSettings {
+		encrypted[key] = true
+	}
+	settings := make(map[string]string)
+	for _, key := range config.SettingsKeys() {
+		value, ok := stored[key]
+		if !ok || localSettings[key] {
+			continue
+		}
+		if encrypted[key] {
+			if value, err = db.GetEncryptedSetting(key); err == nil && value != "" {
+				sec.Settings[key] = value
+			}
+			continue
+		}
+		settings[key] = value
+	}
+	if err := write(settingsFile, len(settings), settings); err != nil {
+		return nil, err
+	}
+
+	// Feeds
+	allFeeds, err := db.GetFeeds()
+	if err != nil {
+		return nil, fmt.Errorf("read feeds: %w", err)
+	}
+	feeds := make([]models.Feed, 0, len(allFeeds))
+	for _, f := range allFeeds {
+		if f.IsFreshRSSSource {
+			continue
+		}
+		if f.EmailPassword != "" {
+			sec.EmailPasswords[f.ID] = f.EmailPassword
+		}
+		if stripped := urlutil.StripCredentials(f.ProxyURL); stripped != f.ProxyURL {
+			sec.ProxyURLs[f.ID] = f.ProxyURL
+		}
+		f.EmailPassword = ""
+		f.ProxyURL = urlutil.StripCredentials(f.ProxyURL)
+		feeds = append(feeds, f)
+	}
+	if err := write(feedsFile, len(feeds), feeds); err != nil {
+		return nil, err
+	}
+
+	// Full-text extraction rules chosen per feed
+	allRules, err := db.GetFeedExtractionRules()
+	if err != nil {
+		return nil, fmt.Errorf("read extraction rules: %w", err)
+	}
+	extractionRules := make(map[int64]string, len(allRules))
+	for _, f := range feeds {
+		if rule, ok := allRules[f.ID]; ok {
+			extractionRules[f.ID] = rule
+		}
+	}
+	if err := write(extractionRulesFile, len(extractionRules), extractionRules); err != nil {
+		return nil, err
+	}
+
+	// Tags and feed tags
+	tags, err := db.GetTags()
+	if err != nil {
+		return nil, fmt.Errorf("read tags: %w", err)
+	}
+	if err := write(tagsFile, len(tags), tags); err != nil {
+		return nil, err
+	}
+	feedIDs := make([]int64, 0, len(feeds))
+	for _, f := range feeds {
+		feedIDs = append(feedIDs, f.ID)
+	}
+	tagsByFeed, err := db.GetTagsForFeeds(feedIDs)
+	if err != nil {
+		return nil, fmt.Errorf("read feed tags: %w", err)
+	}
+	feedTags := make([]feedTag, 0)
+	for _, f := range feeds {
+		for _, t := range tagsByFeed[f.ID] {
+			feedTags = append(feedTags, feedTag{FeedID: f.ID, TagID: t.ID})
+		}
+	}
+	if err := write(feedTagsFile, len(feedTags), feedTags); err != nil {
+		return nil, err
+	}
+
+	// Retention policies, without those of feeds that are not exported
+	allPolicies, err := db.GetRetentionPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("read retention policies: %w", err)
+	}
+	exported := make(map[int64]bool, len(feeds))
+	for _, f := range feeds {
+		exported[f.ID] = true
+	}
+	policies := make([]models.RetentionPolicy, 0, len(allPolicies))
+	for _, p := range allPolicies {
+		if p.Scope == models.RetentionScopeFeed && !exported[p.FeedID] {
+			continue
+		}
+		policies = append(policies, p)
+	}
+	if err := write(retentionPoliciesFile, len(policies), policies); err != nil {
+		return nil, err
+	}
+
+	// Saved filters
+	filters, err := db.GetSavedFilters()
+	if err != nil {
+		return nil, fmt.Errorf("read saved filters: %w", err)
+	}
+	if err := write(savedFiltersFile, len(filters), filters); err != nil {
+		return nil, err
+	}
+
+	// AI profiles
+	profiles, err := db.GetAllAIProfiles()
+	if err != nil {
+		return nil, fmt.Errorf("read AI profiles: %w", err)
+	}
+	for i := range profiles {
+		if profiles[i].APIKey != "" {
+			sec.AIProfileKeys[profiles[i].ID] = profiles[i].APIKey
+		}
+		profiles[i].APIKey = ""
+	}
+	if err := write(aiProfilesFile, len(profiles), profiles); err != nil {
+		return nil, err
+	}
+
+	// Articles with user state
+	articles, err := db.GetArticlesForBackup(opts.IncludeContent)
+	if err != nil {
+		return nil, fmt.Errorf("read articles: %w", err)
+	}
+	if err := write(articlesFile, len(articles), articles); err != nil {
+		return nil, err
+	}
+	backedUp := make(map[int64]bool, len(articles))
+	for _, a := range articles {
+		backedUp[a.ID] = true
+	}
+
+	if opts.IncludeContent {
+		all, err := db.GetAllArticleContents()
+		if err != nil {
+			return nil, fmt.Errorf("read article contents: %w", err)
+		}
+		contents := make(map[int64]string, len(all))
+		for id, content := range all {
+			if backedUp[id] {
+				contents[id] = content
+			}
+		}
+		if err := write(articleContentsFile, len(contents), contents); err != nil {
+			return nil, err
+		}
+	}
+
+	// Chat sessions
+	sessions, err := db.GetAllChatSessions()
+	if err != nil {
+		return nil, err
+	}
+	chats := make([]chatSession, 0, len(sessions))
+	for _, s := range sessions {
+		if !backedUp[s.ArticleID] {
+			continue
+		}
+		messages, err := db.GetChatMessages(s.ID)
+		if err != nil {
+			return nil, err
+		}
+		chats = append(chats, chatSession{ChatSession: s, Messages: messages})
+	}
+	if err := write(chatSessionsFile, len(chats), chats); err != nil {
+		return nil, err
+	}
+
+	// Statistics
+	stats, err := db.GetStatsByDateRange("0000-01-01", "9999-12-31")
+	if err != nil {
+		return nil, fmt.Errorf("read statistics: %w", err)
+	}
+	records := make([]statRecord, 0, len(stats))
+	for _, s := range stats {
+		records = append(records, statRecord{EventDate: s.EventDate, EventType: s.EventType, Count: s.Count})
+	}
+	if err := write(statisticsFile, len(records), records); err != nil {
+		return nil, err
+	}
+
+	// Subscription lists
+	lists, err := db.GetSubscriptionLists()
+	if err != nil {
+		return nil, fmt.Errorf("read subscription lists: %w", err)
+	}
+	subscriptionLists := make([]subscriptionList, 0, len(lists))
+	for _, l := range lists {
+		owned, err := db.GetSubscriptionListFeeds(l.ID)
+		if err != nil {
+			return nil, err
+		}
+		entry := subscriptionList{SubscriptionList: l, FeedIDs: make([]int64, 0, len(owned))}
+		for _, f := range owned {
+			entry.FeedIDs = append(entry.FeedIDs, f.ID)
+		}
+		subscriptionLists = append(subscriptionLists, entry)
+	}
+	if err := write(subscriptionListsFile, len(subscriptionLists), subscriptionLists); err != nil {
+		return nil, err
+	}
+
+	// Secrets, encrypted as a whole so the passphrase is only stretched once
+	if opts.Passphrase != "" {
+		data, err := json.Marshal(sec)
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := crypto.EncryptWithPassphrase(string(data), opts.Passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt secrets: %w", err)
+		}
+		fw, err := zw.Create(secretsFile)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(fw, sealed); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeJSON(zw, manifestFile, manifest); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// writeJSON adds a JSON entry to the archive
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(fw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// readJSON decodes an archive entry. Missing entries leave v untouched.
+func readJSON(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
+	}
+	return nil
+}
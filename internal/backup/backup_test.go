@@ -0,0 +1,235 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"MrRSS/internal/database"
+	"MrRSS/internal/models"
+)
+
+func newTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create db: %v", err)
+	}
+	if err := db.Init(); err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// seedSource fills a database with one of everything
+func seedSource(t *testing.T, db *database.DB) {
+	t.Helper()
+
+	// Shift IDs so they differ from those in a fresh database
+	if _, err := db.AddFeed(&models.Feed{Title: "Placeholder", URL: "https://placeholder.example.com/feed"}); err != nil {
+		t.Fatal(err)
+	}
+	feedID, err := db.AddFeed(&models.Feed{
+		Title:         "Blog",
+		URL:           "https://blog.example.com/feed",
+		Category:      "Tech",
+		Type:          "email",
+		EmailPassword: "imap-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagID, err := db.AddTag(&models.Tag{Name: "Go", Color: "#00ADD8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetFeedTags(feedID, []int64{tagID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.AddRetentionPolicy(&models.RetentionPolicy{Scope: models.RetentionScopeFeed, FeedID: feedID, KeepNewest: 50, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AddRetentionPolicy(&models.RetentionPolicy{Scope: models.RetentionScopeTag, TagID: tagID, MaxAgeDays: 7, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.AddSavedFilter(&models.SavedFilter{Name: "Unread Go", Conditions: "[]"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.CreateAIProfile(&models.AIProfile{Name: "Local", APIKey: "placeholder", Endpoint: "http://localhost", Model: "m"}); err != nil {
+		t.Fatal(err)
+	}
+	profileID, err := db.CreateAIProfile(&models.AIProfile{Name: "Main", APIKey: "sk-profile", Endpoint: "https://api.example.com", Model: "gpt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetSetting("ai_chat_profile_id", strconv.FormatInt(profileID, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetSetting("theme", "dark"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetSetting("window_width", "1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetEncryptedSetting("deepl_api_key", "deepl-secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	article := models.Article{
+		FeedID:                feedID,
+		Title:                 "Hello",
+		URL:                   "https://blog.example.com/hello",
+		PublishedAt:           time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		HasValidPublishedTime: true,
+		IsFavorite:            true,
+	}
+	if err := db.SaveArticle(&article); err != nil {
+		t.Fatal(err)
+	}
+	articles, err := db.GetArticlesForBackup(false)
+	if err != nil || len(articles) != 1 {
+		t.Fatalf("expected 1 article with state, got %d (%v)", len(articles), err)
+	}
+	article = articles[0]
+	if err := db.SetArticleContent(article.ID, "<p>cached</p>"); err != nil {
+		t.Fatal(err)
+	}
+
+	sessionID, err := db.CreateChatSession(article.ID, "Questions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CreateChatMessage(sessionID, "user", "What is this?", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.IncrementStat("article_read"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := newTestDB(t)
+	seedSource(t, src)
+
+	var buf bytes.Buffer
+	manifest, err := Export(src, &buf, ExportOptions{
+		IncludeContent:    true,
+		Passphrase:        "open sesame",
+		EncryptedSettings: []string{"deepl_api_key"},
+	})
+	if err != nil {
+		t.Fatalf("Export error: %v", err)
+	}
+	if !manifest.IncludesSecrets || manifest.Entities[articlesFile] != 1 || manifest.Entities[articleContentsFile] != 1 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("imap-secret")) {
+		t.Fatal("archive must not contain secrets in plain text")
+	}
+
+	archive := bytes.NewReader(buf.Bytes())
+
+	dst := newTestDB(t)
+	if _, err := Import(dst, archive, archive.Size(), ImportOptions{Passphrase: "wrong"}); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+
+	result, err := Import(dst, archive, archive.Size(), ImportOptions{
+		Mode:              ModeReplace,
+		Passphrase:        "open sesame",
+		EncryptedSettings: []string{"deepl_api_key"},
+	})
+	if err != nil {
+		t.Fatalf("Import error: %v", err)
+	}
+	if result.Feeds != 2 || result.Articles != 1 || result.ChatSessions != 1 || !result.SecretsRestored {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	feeds, _ := dst.GetFeeds()
+	var blog models.Feed
+	for _, f := range feeds {
+		if f.URL == "https://blog.example.com/feed" {
+			blog = f
+		}
+	}
+	if blog.EmailPassword != "imap-secret" || blog.Category != "Tech" {
+		t.Errorf("feed not restored with its secret: %+v", blog)
+	}
+	if tags, _ := dst.GetFeedTags(blog.ID); len(tags) != 1 || tags[0].Name != "Go" {
+		t.Errorf("feed tags not restored: %+v", tags)
+	}
+
+	tags, _ := dst.GetFeedTags(blog.ID)
+	policies, _ := dst.GetRetentionPolicies()
+	if len(policies) != 2 || policies[0].FeedID != blog.ID || policies[0].KeepNewest != 50 ||
+		len(tags) != 1 || policies[1].TagID != tags[0].ID || policies[1].MaxAgeDays != 7 {
+		t.Errorf("retention policies not restored: %+v", policies)
+	}
+
+	if v, _ := dst.GetEncryptedSetting("deepl_api_key"); v != "deepl-secret" {
+		t.Errorf("encrypted setting = %q", v)
+	}
+	if v, _ := dst.GetSetting("theme"); v != "dark" {
+		t.Errorf("theme = %q", v)
+	}
+	if v, _ := dst.GetSetting("window_width"); v == "1234" {
+		t.Error("machine-local settings must not be restored")
+	}
+
+	// The chat profile setting must point to the restored profile
+	profileSetting, _ := dst.GetSetting("ai_chat_profile_id")
+	profiles, _ := dst.GetAllAIProfiles()
+	found := false
+	for _, p := range profiles {
+		if strconv.FormatInt(p.ID, 10) == profileSetting {
+			found = p.Name == "Main" && p.APIKey == "sk-profile"
+		}
+	}
+	if !found {
+		t.Errorf("ai_chat_profile_id %q does not point to the restored profile: %+v", profileSetting, profiles)
+	}
+
+	articles, _ := dst.GetArticlesForBackup(true)
+	if len(articles) != 1 || !articles[0].IsFavorite || articles[0].FeedID != blog.ID {
+		t.Fatalf("article state not restored: %+v", articles)
+	}
+	if content, ok, _ := dst.GetArticleContent(articles[0].ID); !ok || content != "<p>cached</p>" {
+		t.Errorf("article content = %q", content)
+	}
+	sessions, _ := dst.GetChatSessionsByArticle(articles[0].ID)
+	if len(sessions) != 1 || sessions[0].MessageCount != 1 {
+		t.Errorf("chat sessions not restored: %+v", sessions)
+	}
+
+	// Merging the same archive again changes nothing
+	again, err := Import(dst, archive, archive.Size(), ImportOptions{Mode: ModeMerge})
+	if err != nil {
+		t.Fatalf("merge Import error: %v", err)
+	}
+	if again.Feeds != 0 || again.Tags != 0 || again.ChatSessions != 0 || again.AIProfiles != 0 || again.RetentionPolicies != 0 || len(again.Warnings) != 1 {
+		t.Errorf("unexpected merge result: %+v", again)
+	}
+	if stats, _ := dst.GetTotalStats(); stats["article_read"] != 1 {
+		t.Errorf("statistics double counted: %+v", stats)
+	}
+}
+
+func TestImportRejectsForeignArchives(t *testing.T) {
+	db := newTestDB(t)
+	data := []byte("not a zip")
+	if _, err := Import(db, bytes.NewReader(data), int64(len(data)), ImportOptions{}); err == nil {
+		t.Error("expected an error for a non-zip upload")
+	}
+	if _, err := Import(db, bytes.NewReader(nil), 0, ImportOptions{Mode: "overwrite"}); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
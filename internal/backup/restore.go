@@ -0,0 +1,642 @@
+package backup
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"MrRSS/internal/config"
+	"MrRSS/internal/crypto"
+	"MrRSS/internal/database"
+	"MrRSS/internal/models"
+	"MrRSS/internal/utils/urlutil"
+)
+
+// Mode selects how a restore treats existing data
+type Mode string
+
+const (
+	// ModeMerge keeps existing data and adds what the archive has on top.
+	// Existing feeds, tags, filters and profiles win over archived ones,
+	// and only settings that are unset here are taken from the archive.
+	ModeMerge Mode = "merge"
+	// ModeReplace deletes existing user data first and restores all archived settings
+	ModeReplace Mode = "replace"
+)
+
+// ErrWrongPassphrase is returned when the secrets of an archive cannot be decrypted
+var ErrWrongPassphrase = errors.New("wrong backup passphrase")
+
+// maxEntrySize limits how much of a single archive entry is read
+const maxEntrySize = 1 << 30
+
+// aiProfileSettings hold AI profile IDs and are remapped on restore
+var aiProfileSettings = []string{
+	"ai_chat_profile_id",
+	"ai_search_profile_id",
+	"ai_summary_profile_id",
+	"ai_translation_profile_id",
+}
+
+// ImportOptions controls a restore
+type ImportOptions struct {
+	Mode Mode
+	// Passphrase decrypts the archived secrets. Without it secrets are skipped.
+	Passphrase string
+	// EncryptedSettings lists the setting keys stored encrypted
+	EncryptedSettings []string
+}
+
+// ImportResult summarizes a restore
+type ImportResult struct {
+	Manifest          Manifest `json:"manifest"`
+	Mode              Mode     `json:"mode"`
+	Settings          int      `json:"settings"`
+	Feeds             int      `json:"feeds"`
+	Tags              int      `json:"tags"`
+	SavedFilters      int      `json:"saved_filters"`
+	AIProfiles        int      `json:"ai_profiles"`
+	Articles          int      `json:"articles"`
+	ArticleContents   int      `json:"article_contents"`
+	ChatSessions      int      `json:"chat_sessions"`
+	Statistics        int      `json:"statistics"`
+	SubscriptionLists int      `json:"subscription_lists"`
+	RetentionPolicies int      `json:"retention_policies"`
+	SecretsRestored   bool     `json:"secrets_restored"`
+	Warnings          []string `json:"warnings,omitempty"`
+	FeedIDs           []int64  `json:"-"` // Feeds added by the restore, to be fetched
+}
+
+// archive holds the decoded entries of a backup
+type archive struct {
+	settings          map[string]string
+	secrets           secrets
+	feeds             []models.Feed
+	tags              []models.Tag
+	feedTags          []feedTag
+	extractionRules   map[int64]string
+	savedFilters      []models.SavedFilter
+	aiProfiles        []models.AIProfile
+	articles          []models.Article
+	articleContents   map[int64]string
+	chatSessions      []chatSession
+	statistics        []statRecord
+	subscriptionLists []subscriptionList
+	retentionPolicies []models.RetentionPolicy
+}
+
+// Import restores an archive into db. The whole archive is read and
+// validated, including the passphrase, before anything is changed. When a
+// replace fails halfway the previous data is put back.
+func Import(db *database.DB, r io.ReaderAt, size int64, opts ImportOptions) (*ImportResult, error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeMerge
+	}
+	if opts.Mode != ModeMerge && opts.Mode != ModeReplace {
+		return nil, fmt.Errorf("unknown restore mode %q", opts.Mode)
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	result := &ImportResult{Mode: opts.Mode}
+	if _, ok := files[manifestFile]; !ok {
+		return nil, errors.New("not a backup archive: missing manifest")
+	}
+	if err := readJSON(files, manifestFile, &result.Manifest); err != nil {
+		return nil, err
+	}
+	if result.Manifest.Format != FormatName {
+		return nil, fmt.Errorf("not a backup archive: unknown format %q", result.Manifest.Format)
+	}
+	if result.Manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("backup format version %d is newer than supported version %d", result.Manifest.Version, FormatVersion)
+	}
+
+	a, err := readArchive(files, opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if result.Manifest.IncludesSecrets {
+		if opts.Passphrase == "" {
+			result.Warnings = append(result.Warnings, "the backup contains secrets but no passphrase was given; API keys and passwords were not restored")
+		} else {
+			result.SecretsRestored = true
+		}
+	}
+
+	if opts.Mode != ModeReplace {
+		return result, restoreArchive(db, a, opts, result)
+	}
+
+	// Replacing clears the data first, so keep a copy to put back when the
+	// restore fails halfway
+	dir, err := os.MkdirTemp("", "mrrss-restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	undoPath := filepath.Join(dir, "before-restore.db")
+	if err := db.VacuumInto(undoPath); err != nil {
+		return nil, fmt.Errorf("snapshot existing data: %w", err)
+	}
+	if err := db.ClearUserData(); err != nil {
+		return nil, fmt.Errorf("clear existing data: %w", err)
+	}
+	if err := restoreArchive(db, a, opts, result); err != nil {
+		if undoErr := db.RestoreUserData(undoPath); undoErr != nil {
+			return result, fmt.Errorf("%w (putting back the previous data failed too: %v)", err, undoErr)
+		}
+		return result, fmt.Errorf("%w (the previous data was put back)", err)
+	}
+	return result, nil
+}
+
+// restoreArchive adds the archived data to db
+func restoreArchive(db *database.DB, a *archive, opts ImportOptions, result *ImportResult) error {
+	feedIDs, err := restoreFeeds(db, a, result)
+	if err != nil {
+		return err
+	}
+	tagIDs, err := restoreTags(db, a, feedIDs, result)
+	if err != nil {
+		return err
+	}
+	if err := restoreRetentionPolicies(db, a, feedIDs, tagIDs, result); err != nil {
+		return err
+	}
+	if err := restoreSavedFilters(db, a, result); err != nil {
+		return err
+	}
+	profileIDs, err := restoreAIProfiles(db, a, result)
+	if err != nil {
+		return err
+	}
+	if err := restoreSettings(db, a, profileIDs, opts, result); err != nil {
+		return err
+	}
+	articleIDs := restoreArticles(db, a, feedIDs, result)
+	restoreChatSessions(db, a, articleIDs, result)
+	for _, s := range a.statistics {
+		if err := db.RestoreStat(database.StatRecord{EventDate: s.EventDate, EventType: s.EventType, Count: s.Count}); err != nil {
+			result.warn("statistics %s/%s: %v", s.EventDate, s.EventType, err)
+			continue
+		}
+		result.Statistics++
+	}
+	return restoreSubscriptionLists(db, a, feedIDs, result)
+}
+
+// warn records a non-fatal restore problem
+func (r *ImportResult) warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Printf("Backup restore: %s", msg)
+	r.Warnings = append(r.Warnings, msg)
+}
+
+// readArchive decodes all entries and decrypts the secrets
+func readArchive(files map[string]*zip.File, passphrase string) (*archive, error) {
+	a := &archive{}
+	entries := []struct {
+		name string
+		v    interface{}
+	}{
+		{settingsFile, &a.settings},
+		{feedsFile, &a.feeds},
+		{tagsFile, &a.tags},
+		{feedTagsFile, &a.feedTags},
+		{extractionRulesFile, &a.extractionRules},
+		{savedFiltersFile, &a.savedFilters},
+		{aiProfilesFile, &a.aiProfiles},
+		{articlesFile, &a.articles},
+		{articleContentsFile, &a.articleContents},
+		{chatSessionsFile, &a.chatSessions},
+		{statisticsFile, &a.statistics},
+		{subscriptionListsFile, &a.subscriptionLists},
+		{retentionPoliciesFile, &a.retentionPolicies},
+	}
+	for _, e := range entries {
+		if err := readJSON(files, e.name, e.v); err != nil {
+			return nil, err
+		}
+	}
+
+	if f, ok := files[secretsFile]; ok && passphrase != "" {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := io.ReadAll(io.LimitReader(rc, maxEntrySize))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		data, err := crypto.DecryptWithPassphrase(strings.TrimSpace(string(sealed)), passphrase)
+		if err != nil {
+			return nil, ErrWrongPassphrase
+		}
+		if err := json.Unmarshal([]byte(data), &a.secrets); err != nil {
+			return nil, fmt.Errorf("read %s: %w", secretsFile, err)
+		}
+	}
+	return a, nil
+}
+
+// restoreFeeds adds archived feeds and maps archive feed IDs to local IDs.
+// Feeds that already exist locally keep their settings.
+func restoreFeeds(db *database.DB, a *archive, result *ImportResult) (map[int64]int64, error) {
+	existing, err := db.GetFeeds()
+	if err != nil {
+		return nil, err
+	}
+	byURL := make(map[string]int64, len(existing))
+	for _, f := range existing {
+		byURL[f.URL] = f.ID
+	}
+
+	ids := make(map[int64]int64, len(a.feeds))
+	for _, f := range a.feeds {
+		oldID := f.ID
+		if id, ok := byURL[f.URL]; ok {
+			ids[oldID] = id
+			continue
+		}
+
+		f.ID = 0
+		f.IsFreshRSSSource = false
+		f.FreshRSSStreamID = ""
+		f.EmailPassword = a.secrets.EmailPasswords[oldID]
+		if proxyURL, ok := a.secrets.ProxyURLs[oldID]; ok {
+			f.ProxyURL = proxyURL
+		}
+		id, err := db.AddFeed(&f)
+		if err != nil {
+			result.warn("feed %s: %v", f.URL, err)
+			continue
+		}
+		ids[oldID] = id
+		byURL[f.URL] = id
+		result.Feeds++
+		result.FeedIDs = append(result.FeedIDs, id)
+
+		if rule := a.extractionRules[oldID]; rule != "" {
+			if err := db.SetFeedExtractionRule(id, rule); err != nil {
+				result.warn("extraction rule for %s: %v", f.URL, err)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// restoreTags adds missing tags, matched by name, restores feed tags and
+// maps archive tag IDs to local IDs
+func restoreTags(db *database.DB, a *archive, feedIDs map[int64]int64, result *ImportResult) (map[int64]int64, error) {
+	existing, err := db.GetTags()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]int64, len(existing))
+	for _, t := range existing {
+		byName[strings.ToLower(t.Name)] = t.ID
+	}
+
+	tagIDs := make(map[int64]int64, len(a.tags))
+	for _, t := range a.tags {
+		if id, ok := byName[strings.ToLower(t.Name)]; ok {
+			tagIDs[t.ID] = id
+			continue
+		}
+		tag := models.Tag{Name: t.Name, Color: t.Color, Position: t.Position}
+		id, err := db.AddTag(&tag)
+		if err != nil {
+			result.warn("tag %s: %v", t.Name, err)
+			continue
+		}
+		tagIDs[t.ID] = id
+		byName[strings.ToLower(t.Name)] = id
+		result.Tags++
+	}
+
+	// Group by local feed and merge with the tags the feed already has
+	wanted := make(map[int64][]int64)
+	for _, ft := range a.feedTags {
+		feedID, ok1 := feedIDs[ft.FeedID]
+		tagID, ok2 := tagIDs[ft.TagID]
+		if ok1 && ok2 {
+			wanted[feedID] = append(wanted[feedID], tagID)
+		}
+	}
+	for feedID, ids := range wanted {
+		current, err := db.GetFeedTags(feedID)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[int64]bool, len(current)+len(ids))
+		merged := make([]int64, 0, len(current)+len(ids))
+		for _, t := range current {
+			seen[t.ID] = true
+			merged = append(merged, t.ID)
+		}
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				merged = append(merged, id)
+			}
+		}
+		if err := db.SetFeedTags(feedID, merged); err != nil {
+			result.warn("tags of feed %d: %v", feedID, err)
+		}
+	}
+	return tagIDs, nil
+}
+
+// restoreRetentionPolicies adds retention policies for scopes that have
+// none yet; existing policies win when merging
+func restoreRetentionPolicies(db *database.DB, a *archive, feedIDs, tagIDs map[int64]int64, result *ImportResult) error {
+	existing, err := db.GetRetentionPolicies()
+	if err != nil {
+		return err
+	}
+	scopeKey := func(p models.RetentionPolicy) string {
+		return fmt.Sprintf("%s\x00%d\x00%s\x00%d", p.Scope, p.FeedID, p.Category, p.TagID)
+	}
+	scopes := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		scopes[scopeKey(p)] = true
+	}
+
+	for _, p := range a.retentionPolicies {
+		policy := p
+		switch p.Scope {
+		case models.RetentionScopeFeed:
+			id, ok := feedIDs[p.FeedID]
+			if !ok {
+				continue
+			}
+			policy.FeedID = id
+		case models.RetentionScopeTag:
+			id, ok := tagIDs[p.TagID]
+			if !ok {
+				continue
+			}
+			policy.TagID = id
+		}
+		if scopes[scopeKey(policy)] {
+			continue
+		}
+		if _, err := db.AddRetentionPolicy(&policy); err != nil {
+			result.warn("retention policy %d: %v", p.ID, err)
+			continue
+		}
+		scopes[scopeKey(policy)] = true
+		result.RetentionPolicies++
+	}
+	return nil
+}
+
+// restoreSavedFilters adds filters whose name is not taken yet
+func restoreSavedFilters(db *database.DB, a *archive, result *ImportResult) error {
+	existing, err := db.GetSavedFilters()
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool, len(existing))
+	for _, f := range existing {
+		names[f.Name] = true
+	}
+
+	for _, f := range a.savedFilters {
+		if names[f.Name] {
+			continue
+		}
+		filter := models.SavedFilter{Name: f.Name, Conditions: f.Conditions, Position: f.Position}
+		if _, err := db.AddSavedFilter(&filter); err != nil {
+			result.warn("saved filter %s: %v", f.Name, err)
+			continue
+		}
+		names[f.Name] = true
+		result.SavedFilters++
+	}
+	return nil
+}
+
+// restoreAIProfiles adds missing AI profiles, matched by name, endpoint and
+// model, and maps archive profile IDs to local IDs. API keys are encrypted
+// with this machine's key when stored.
+func restoreAIProfiles(db *database.DB, a *archive, result *ImportResult) (map[int64]int64, error) {
+	existing, err := db.GetAllAIProfilesWithoutKeys()
+	if err != nil {
+		return nil, err
+	}
+	profileKey := func(p models.AIProfile) string {
+		return p.Name + "\x00" + p.Endpoint + "\x00" + p.Model
+	}
+	byKey := make(map[string]int64, len(existing))
+	hasDefault := false
+	for _, p := range existing {
+		byKey[profileKey(p)] = p.ID
+		hasDefault = hasDefault || p.IsDefault
+	}
+
+	ids := make(map[int64]int64, len(a.aiProfiles))
+	for _, p := range a.aiProfiles {
+		if id, ok := byKey[profileKey(p)]; ok {
+			ids[p.ID] = id
+			continue
+		}
+		profile := p
+		profile.APIKey = a.secrets.AIProfileKeys[p.ID]
+		// Keep the local default when merging
+		profile.IsDefault = p.IsDefault && !hasDefault
+		id, err := db.CreateAIProfile(&profile)
+		if err != nil {
+			result.warn("AI profile %s: %v", p.Name, err)
+			continue
+		}
+		hasDefault = hasDefault || profile.IsDefault
+		ids[p.ID] = id
+		result.AIProfiles++
+	}
+	return ids, nil
+}
+
+// restoreSettings applies archived settings. Secrets are re-encrypted with
+// this machine's key. When merging only unset settings are restored.
+func restoreSettings(db *database.DB, a *archive, profileIDs map[int64]int64, opts ImportOptions, result *ImportResult) error {
+	known := make(map[string]bool)
+	for _, key := range config.SettingsKeys() {
+		known[key] = !localSettings[key]
+	}
+	encrypted := make(map[string]bool, len(opts.EncryptedSettings))
+	for _, key := range opts.EncryptedSettings {
+		encrypted[key] = true
+	}
+
+	// Profile IDs differ between databases
+	for _, key := range aiProfileSettings {
+		value, ok := a.settings[key]
+		if !ok || value == "" {
+			continue
+		}
+		oldID, err := strconv.ParseInt(value, 10, 64)
+		if newID, mapped := profileIDs[oldID]; err == nil && mapped {
+			a.settings[key] = strconv.FormatInt(newID, 10)
+		} else {
+			delete(a.settings, key)
+		}
+	}
+
+	current, err := db.GetAllSettingValues()
+	if err != nil {
+		return err
+	}
+	shouldSet := func(key string) bool {
+		return known[key] && (opts.Mode == ModeReplace || current[key] == "")
+	}
+
+	for key, value := range a.settings {
+		if encrypted[key] || !shouldSet(key) {
+			continue
+		}
+		if err := db.SetSetting(key, value); err != nil {
+			return fmt.Errorf("restore setting %s: %w", key, err)
+		}
+		result.Settings++
+	}
+	for key, value := range a.secrets.Settings {
+		if !encrypted[key] || !shouldSet(key) {
+			continue
+		}
+		if err := db.SetEncryptedSetting(key, value); err != nil {
+			return fmt.Errorf("restore setting %s: %w", key, err)
+		}
+		result.Settings++
+	}
+	return nil
+}
+
+// restoreArticles restores articles with user state and their cached content,
+// and maps archive article IDs to local IDs. Unique IDs include the feed ID,
+// so they are recomputed for the local feed.
+func restoreArticles(db *database.DB, a *archive, feedIDs map[int64]int64, result *ImportResult) map[int64]int64 {
+	ids := make(map[int64]int64, len(a.articles))
+	for _, article := range a.articles {
+		feedID, ok := feedIDs[article.FeedID]
+		if !ok {
+			continue
+		}
+		hasValidTime := urlutil.GenerateArticleUniqueID(article.Title, article.FeedID, article.PublishedAt, true) == article.UniqueID
+		oldID := article.ID
+		article.ID = 0
+		article.FeedID = feedID
+		article.UniqueID = urlutil.GenerateArticleUniqueID(article.Title, feedID, article.PublishedAt, hasValidTime)
+
+		id, err := db.RestoreArticle(&article)
+		if err != nil {
+			result.warn("article %s: %v", article.URL, err)
+			continue
+		}
+		ids[oldID] = id
+		result.Articles++
+	}
+
+	for oldID, content := range a.articleContents {
+		id, ok := ids[oldID]
+		if !ok {
+			continue
+		}
+		if err := db.SetArticleContent(id, content); err != nil {
+			result.warn("content of article %d: %v", id, err)
+			continue
+		}
+		result.ArticleContents++
+	}
+	return ids
+}
+
+// restoreChatSessions restores chat sessions of restored articles.
+// Sessions that already exist (same article, title and creation time) are skipped.
+func restoreChatSessions(db *database.DB, a *archive, articleIDs map[int64]int64, result *ImportResult) {
+	for _, s := range a.chatSessions {
+		articleID, ok := articleIDs[s.ArticleID]
+		if !ok {
+			continue
+		}
+
+		existing, err := db.GetChatSessionsByArticle(articleID)
+		if err != nil {
+			result.warn("chat sessions of article %d: %v", articleID, err)
+			continue
+		}
+		duplicate := false
+		for _, e := range existing {
+			if e.Title == s.Title && e.CreatedAt.Equal(s.CreatedAt) {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+
+		session := s.ChatSession
+		session.ArticleID = articleID
+		sessionID, err := db.RestoreChatSession(&session)
+		if err != nil {
+			result.warn("chat session %s: %v", s.Title, err)
+			continue
+		}
+		for _, m := range s.Messages {
+			m.SessionID = sessionID
+			if err := db.RestoreChatMessage(&m); err != nil {
+				result.warn("chat session %s: %v", s.Title, err)
+			}
+		}
+		result.ChatSessions++
+	}
+}
+
+// restoreSubscriptionLists restores linked OPML lists and their provenance
+func restoreSubscriptionLists(db *database.DB, a *archive, feedIDs map[int64]int64, result *ImportResult) error {
+	existing, err := db.GetSubscriptionLists()
+	if err != nil {
+		return err
+	}
+	urls := make(map[string]bool, len(existing))
+	for _, l := range existing {
+		urls[l.URL] = true
+	}
+
+	for _, l := range a.subscriptionLists {
+		if urls[l.URL] {
+			continue
+		}
+		list := l.SubscriptionList
+		listID, err := db.AddSubscriptionList(&list)
+		if err != nil {
+			result.warn("subscription list %s: %v", l.URL, err)
+			continue
+		}
+		for _, oldID := range l.FeedIDs {
+			if feedID, ok := feedIDs[oldID]; ok {
+				_ = db.AddSubscriptionListFeed(listID, feedID)
+			}
+		}
+		urls[l.URL] = true
+		result.SubscriptionLists++
+	}
+	return nil
+}
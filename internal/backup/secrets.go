@@ -0,0 +1,191 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"MrRSS/internal/crypto"
+	"MrRSS/internal/database"
+)
+
+// SecretsFormatName identifies a secrets export
+const SecretsFormatName = "mrrss-secrets"
+
+// SecretsFormatVersion is the version of the secrets export format
+const SecretsFormatVersion = 1
+
+// SecretsFile moves the secrets of one installation to another. Unlike the
+// secrets of a backup archive they are keyed by setting key, AI profile name
+// and feed URL, so they can be imported into a database that already has
+// the feeds and profiles, e.g. a copy of the database on a new machine.
+type SecretsFile struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Secrets   string    `json:"secrets"` // portableSecrets as JSON, encrypted with the passphrase
+}
+
+// portableSecrets are the secrets of a SecretsFile
+type portableSecrets struct {
+	Settings       map[string]string `json:"settings"`
+	AIProfileKeys  map[string]string `json:"ai_profile_keys"` // By profile name
+	EmailPasswords map[string]string `json:"email_passwords"` // By feed URL
+}
+
+// SecretsImportResult summarizes an import of a SecretsFile
+type SecretsImportResult struct {
+	Settings       int `json:"settings"`
+	AIProfileKeys  int `json:"ai_profile_keys"`
+	EmailPasswords int `json:"email_passwords"`
+	Skipped        int `json:"skipped"` // Secrets of profiles or feeds that do not exist here
+}
+
+// ExportSecrets decrypts the encrypted settings, AI profile API keys and
+// feed email passwords and seals them with passphrase. It fails when a
+// secret cannot be decrypted, e.g. while the master key is locked.
+func ExportSecrets(db *database.DB, passphrase string, encryptedSettings []string) (*SecretsFile, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase is required")
+	}
+	sec := portableSecrets{
+		Settings:       make(map[string]string),
+		AIProfileKeys:  make(map[string]string),
+		EmailPasswords: make(map[string]string),
+	}
+
+	for _, key := range encryptedSettings {
+		value, err := db.GetEncryptedSetting(key)
+		if err != nil {
+			return nil, fmt.Errorf("read setting %s: %w", key, err)
+		}
+		if value != "" {
+			sec.Settings[key] = value
+		}
+	}
+
+	profiles, err := db.GetAllAIProfiles()
+	if err != nil {
+		return nil, fmt.Errorf("read AI profiles: %w", err)
+	}
+	for _, p := range profiles {
+		if p.APIKey == "" {
+			if has, _ := db.HasAPIKeySet(p.ID); has {
+				return nil, fmt.Errorf("decrypt API key of AI profile %s: %w", p.Name, crypto.ErrLocked)
+			}
+			continue
+		}
+		sec.AIProfileKeys[p.Name] = p.APIKey
+	}
+
+	feeds, err := db.GetFeeds()
+	if err != nil {
+		return nil, fmt.Errorf("read feeds: %w", err)
+	}
+	for _, f := range feeds {
+		if f.EmailPassword == "" || f.IsFreshRSSSource {
+			continue
+		}
+		// Feed secrets that cannot be decrypted are read as stored
+		if crypto.IsEncrypted(f.EmailPassword) {
+			return nil, fmt.Errorf("decrypt email password of feed %s: %w", f.URL, crypto.ErrLocked)
+		}
+		sec.EmailPasswords[f.URL] = f.EmailPassword
+	}
+
+	data, err := json.Marshal(sec)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := crypto.EncryptWithPassphrase(string(data), passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt secrets: %w", err)
+	}
+	return &SecretsFile{
+		Format:    SecretsFormatName,
+		Version:   SecretsFormatVersion,
+		CreatedAt: time.Now().UTC(),
+		Secrets:   sealed,
+	}, nil
+}
+
+// ImportSecrets decrypts a SecretsFile and stores its secrets with the key of
+// this installation. Existing secrets are overwritten.
+func ImportSecrets(db *database.DB, file *SecretsFile, passphrase string, encryptedSettings []string) (*SecretsImportResult, error) {
+	if file.Format != SecretsFormatName {
+		return nil, fmt.Errorf("not a secrets export (format %q)", file.Format)
+	}
+	if file.Version > SecretsFormatVersion {
+		return nil, fmt.Errorf("secrets export version %d is newer than supported version %d", file.Version, SecretsFormatVersion)
+	}
+	data, err := crypto.DecryptWithPassphrase(file.Secrets, passphrase)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	var sec portableSecrets
+	if err := json.Unmarshal([]byte(data), &sec); err != nil {
+		return nil, fmt.Errorf("read secrets: %w", err)
+	}
+
+	result := &SecretsImportResult{}
+	encrypted := make(map[string]bool, len(encryptedSettings))
+	for _, key := range encryptedSettings {
+		encrypted[key] = true
+	}
+	for key, value := range sec.Settings {
+		if !encrypted[key] {
+			result.Skipped++
+			continue
+		}
+		if err := db.SetEncryptedSetting(key, value); err != nil {
+			return result, fmt.Errorf("store setting %s: %w", key, err)
+		}
+		result.Settings++
+	}
+
+	profiles, err := db.GetAllAIProfiles()
+	if err != nil {
+		return result, fmt.Errorf("read AI profiles: %w", err)
+	}
+	byName := make(map[string]int, len(profiles))
+	for i := range profiles {
+		byName[profiles[i].Name] = i
+	}
+	for name, key := range sec.AIProfileKeys {
+		i, ok := byName[name]
+		if !ok {
+			result.Skipped++
+			continue
+		}
+		profiles[i].APIKey = key
+		if err := db.UpdateAIProfile(&profiles[i]); err != nil {
+			return result, fmt.Errorf("store API key of AI profile %s: %w", name, err)
+		}
+		result.AIProfileKeys++
+	}
+
+	feeds, err := db.GetFeeds()
+	if err != nil {
+		return result, fmt.Errorf("read feeds: %w", err)
+	}
+	byURL := make(map[string]int64, len(feeds))
+	for _, f := range feeds {
+		if !f.IsFreshRSSSource {
+			byURL[f.URL] = f.ID
+		}
+	}
+	for url, password := range sec.EmailPasswords {
+		id, ok := byURL[url]
+		if !ok {
+			result.Skipped++
+			continue
+		}
+		password := password
+		if err := db.UpdateFeedWithOptions(id, database.FeedUpdateOptions{EmailPassword: &password}); err != nil {
+			return result, fmt.Errorf("store email password of feed %s: %w", url, err)
+		}
+		result.EmailPasswords++
+	}
+	return result, nil
+}
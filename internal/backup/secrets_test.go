@@ -0,0 +1,56 @@
+package backup
+
+import (
+	"errors"
+	"testing"
+
+	"MrRSS/internal/models"
+)
+
+func TestExportImportSecrets(t *testing.T) {
+	source := newTestDB(t)
+	if _, err := source.AddFeed(&models.Feed{Title: "Mail", URL: "email://me@example.com", Type: "email", EmailPassword: "imap-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.CreateAIProfile(&models.AIProfile{Name: "Main", APIKey: "sk-secret", Endpoint: "http://localhost", Model: "m"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.SetEncryptedSetting("deepl_api_key", "deepl-secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := ExportSecrets(source, "correct horse", []string{"deepl_api_key"})
+	if err != nil {
+		t.Fatalf("ExportSecrets: %v", err)
+	}
+
+	target := newTestDB(t)
+	feedID, err := target.AddFeed(&models.Feed{Title: "Mail", URL: "email://me@example.com", Type: "email"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := target.CreateAIProfile(&models.AIProfile{Name: "Main", Endpoint: "http://localhost", Model: "m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ImportSecrets(target, file, "wrong", []string{"deepl_api_key"}); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("ImportSecrets(wrong) error = %v, want ErrWrongPassphrase", err)
+	}
+	result, err := ImportSecrets(target, file, "correct horse", []string{"deepl_api_key"})
+	if err != nil {
+		t.Fatalf("ImportSecrets: %v", err)
+	}
+	if result.Settings != 1 || result.AIProfileKeys != 1 || result.EmailPasswords != 1 || result.Skipped != 0 {
+		t.Errorf("ImportSecrets = %+v", result)
+	}
+
+	if value, _ := target.GetEncryptedSetting("deepl_api_key"); value != "deepl-secret" {
+		t.Errorf("deepl_api_key = %q", value)
+	}
+	if feed, _ := target.GetFeedByID(feedID); feed.EmailPassword != "imap-secret" {
+		t.Errorf("email password = %q", feed.EmailPassword)
+	}
+	if profiles, _ := target.GetAllAIProfiles(); len(profiles) != 1 || profiles[0].APIKey != "sk-secret" {
+		t.Errorf("AI profiles = %+v", profiles)
+	}
+}
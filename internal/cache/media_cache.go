@@ -13,23 +13,55 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"MrRSS/internal/utils/httputil"
 )
 
+// MediaContentTypes are the response types accepted when downloading media
+var MediaContentTypes = []string{
+	"image/", "video/", "audio/",
+	"application/octet-stream", "binary/octet-stream",
+	"application/ogg", "application/vnd.apple.mpegurl", "application/x-mpegurl", "application/dash+xml",
+}
+
 // MediaCache handles caching of images and videos to work around anti-hotlinking
 type MediaCache struct {
 	cacheDir string
+	// client downloads media loaded into memory
+	client *http.Client
+	// streamClient fetches streamed media. It has no overall timeout because a
+	// response body is read for as long as the media plays.
+	streamClient *http.Client
 }
 
-// NewMediaCache creates a new media cache instance
+// NewMediaCache creates a new media cache instance. It refuses to download
+// from private network addresses until SetOutboundPolicy says otherwise.
 func NewMediaCache(cacheDir string) (*MediaCache, error) {
 	// Create cache directory if it doesn't exist
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create cache directory: %w", err)
 	}
 
-	return &MediaCache{
+	mc := &MediaCache{
 		cacheDir: cacheDir,
-	}, nil
+	}
+	mc.SetOutboundPolicy(httputil.OutboundPolicy{})
+	return mc, nil
+}
+
+// SetOutboundPolicy sets which addresses media may be downloaded from.
+// Only media content types are accepted regardless of the policy.
+func (mc *MediaCache) SetOutboundPolicy(policy httputil.OutboundPolicy) {
+	policy.ContentTypes = MediaContentTypes
+
+	// Creating a client only fails for an invalid proxy URL, and none is used
+	mc.client, _ = httputil.NewOutboundClient("", 30*time.Second, policy)
+	mc.client.Transport.(*httputil.OutboundTransport).Base.Proxy = http.ProxyFromEnvironment
+
+	mc.streamClient, _ = httputil.NewOutboundClient("", 0, policy)
+	streamTransport := mc.streamClient.Transport.(*httputil.OutboundTransport).Base
+	streamTransport.Proxy = http.ProxyFromEnvironment
+	streamTransport.ResponseHeaderTimeout = 30 * time.Second
 }
 
 // GetCachedPath returns the cached file path for a given URL (using extension from URL)
@@ -71,11 +103,15 @@ func (mc *MediaCache) Exists(url string) bool {
 	return found
 }
 
-// Get retrieves cached media or downloads it if not cached
+// Get retrieves cached media or downloads it if not cached. Media larger
+// than MaxMemoryBuffer is rejected with ErrTooLarge; use Open to stream it.
 func (mc *MediaCache) Get(url, referer string) ([]byte, string, error) {
 	// Check if already cached
 	cachedPath, found := mc.findCachedFile(url)
 	if found {
+		if info, err := os.Stat(cachedPath); err == nil && info.Size() > MaxMemoryBuffer {
+			return nil, "", ErrTooLarge
+		}
 		data, err := os.ReadFile(cachedPath)
 		if err != nil {
 			return nil, "", fmt.Errorf("failed to read cached file: %w", err)
@@ -109,10 +145,6 @@ func (mc *MediaCache) Get(url, referer string) ([]byte, string, error) {
 
 // download fetches media from the given URL with proper headers
 func (mc *MediaCache) download(url, referer string) ([]byte, string, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create request: %w", err)
@@ -141,7 +173,7 @@ func (mc *MediaCache) download(url, referer string) ([]byte, string, error) {
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("Upgrade-Insecure-Requests", "1")
 
-	resp, err := client.Do(req)
+	resp, err := mc.client.Do(req)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to fetch media: %w", err)
 	}
@@ -151,10 +183,16 @@ func (mc *MediaCache) download(url, referer string) ([]byte, string, error) {
 		return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	if resp.ContentLength > MaxMemoryBuffer {
+		return nil, "", ErrTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxMemoryBuffer+1))
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to read response body: %w", err)
 	}
+	if len(data) > MaxMemoryBuffer {
+		return nil, "", ErrTooLarge
+	}
 
 	contentType := resp.Header.Get("Content-Type")
 	if contentType == "" {
@@ -198,6 +236,70 @@ func getSmartReferer(imageURL, originalReferer string) string {
 	return fmt.Sprintf("%s://%s", imgURL.Scheme, imgURL.Host)
 }
 
+// cacheEntry is a cached media file or the chunk directory of partially
+// cached media, which is cleaned up as a unit
+type cacheEntry struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// entries lists the cached media files and chunk directories
+func (mc *MediaCache) entries() ([]cacheEntry, error) {
+	dirEntries, err := os.ReadDir(mc.cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var entries []cacheEntry
+	for _, entry := range dirEntries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, cacheEntry{
+			path:    filepath.Join(mc.cacheDir, entry.Name()),
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		})
+	}
+
+	partialDir := filepath.Join(mc.cacheDir, partialDirName)
+	partEntries, err := os.ReadDir(partialDir)
+	if err != nil {
+		return entries, nil
+	}
+	for _, entry := range partEntries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		e := cacheEntry{
+			path:    filepath.Join(partialDir, entry.Name()),
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		}
+		if entry.IsDir() {
+			// A chunk directory is as old as its newest chunk
+			e.size = 0
+			files, _ := os.ReadDir(e.path)
+			for _, f := range files {
+				if fi, err := f.Info(); err == nil {
+					e.size += fi.Size()
+					if fi.ModTime().After(e.modTime) {
+						e.modTime = fi.ModTime()
+					}
+				}
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
 // CleanupOldFiles removes cached files older than the specified age
 func (mc *MediaCache) CleanupOldFiles(maxAgeDays int) (int, error) {
 	var cutoffTime time.Time
@@ -210,24 +312,14 @@ func (mc *MediaCache) CleanupOldFiles(maxAgeDays int) (int, error) {
 		cutoffTime = time.Now().AddDate(0, 0, -maxAgeDays)
 	}
 
-	entries, err := os.ReadDir(mc.cacheDir)
+	entries, err := mc.entries()
 	if err != nil {
-		return 0, fmt.Errorf("failed to read cache directory: %w", err)
+		return 0, err
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		filePath := filepath.Join(mc.cacheDir, entry.Name())
-		info, err := entry.Info()
-		if err != nil {
-			continue
-		}
-
-		if info.ModTime().Before(cutoffTime) {
-			if err := os.Remove(filePath); err == nil {
+		if entry.modTime.Before(cutoffTime) {
+			if err := os.RemoveAll(entry.path); err == nil {
 				count++
 			}
 		}
@@ -240,22 +332,13 @@ func (mc *MediaCache) CleanupOldFiles(maxAgeDays int) (int, error) {
 func (mc *MediaCache) GetCacheSize() (int64, error) {
 	var totalSize int64
 
-	entries, err := os.ReadDir(mc.cacheDir)
+	entries, err := mc.entries()
 	if err != nil {
-		return 0, fmt.Errorf("failed to read cache directory: %w", err)
+		return 0, err
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		info, err := entry.Info()
-		if err != nil {
-			continue
-		}
-
-		totalSize += info.Size()
+		totalSize += entry.size
 	}
 
 	return totalSize, nil
@@ -264,45 +347,19 @@ func (mc *MediaCache) GetCacheSize() (int64, error) {
 // CleanupBySize removes oldest files until cache is under the size limit
 func (mc *MediaCache) CleanupBySize(maxSizeMB int) (int, error) {
 	maxSize := int64(maxSizeMB) * 1024 * 1024
-	currentSize, err := mc.GetCacheSize()
+	files, err := mc.entries()
 	if err != nil {
 		return 0, err
 	}
 
+	var currentSize int64
+	for _, f := range files {
+		currentSize += f.size
+	}
 	if currentSize <= maxSize {
 		return 0, nil
 	}
 
-	// Get all files with their modification times
-	type fileInfo struct {
-		path    string
-		modTime time.Time
-		size    int64
-	}
-
-	var files []fileInfo
-	entries, err := os.ReadDir(mc.cacheDir)
-	if err != nil {
-		return 0, fmt.Errorf("failed to read cache directory: %w", err)
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		info, err := entry.Info()
-		if err != nil {
-			continue
-		}
-
-		files = append(files, fileInfo{
-			path:    filepath.Join(mc.cacheDir, entry.Name()),
-			modTime: info.ModTime(),
-			size:    info.Size(),
-		})
-	}
-
 	// Sort by modification time (oldest first) using built-in sort for better performance
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].modTime.Before(files[j].modTime)
@@ -315,7 +372,7 @@ func (mc *MediaCache) CleanupBySize(maxSizeMB int) (int, error) {
 			break
 		}
 
-		if err := os.Remove(f.path); err == nil {
+		if err := os.RemoveAll(f.path); err == nil {
 			currentSize -= f.size
 			count++
 		}
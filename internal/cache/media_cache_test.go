@@ -1,10 +1,17 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"MrRSS/internal/utils/httputil"
 )
 
 func TestMediaCache_BasicOperations(t *testing.T) {
@@ -59,6 +66,35 @@ func TestMediaCache_BasicOperations(t *testing.T) {
 	}
 }
 
+func TestMediaCache_RefusesPrivateAddresses(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png"))
+	}))
+	defer srv.Close()
+
+	mc, err := NewMediaCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewMediaCache failed: %v", err)
+	}
+	if _, _, err := mc.Get(srv.URL+"/a.png", ""); !errors.Is(err, httputil.ErrBlockedAddress) {
+		t.Fatalf("expected ErrBlockedAddress, got %v", err)
+	}
+	if _, err := mc.Open(context.Background(), srv.URL+"/a.png", ""); !errors.Is(err, httputil.ErrBlockedAddress) {
+		t.Fatalf("expected ErrBlockedAddress from Open, got %v", err)
+	}
+	if hits.Load() != 0 {
+		t.Fatalf("expected no requests to reach the server, got %d", hits.Load())
+	}
+
+	mc.SetOutboundPolicy(httputil.OutboundPolicy{AllowHost: func(host string) bool { return host == "127.0.0.1" }})
+	if data, _, err := mc.Get(srv.URL+"/a.png", ""); err != nil || string(data) != "png" {
+		t.Fatalf("expected allowlisted host to be fetched, got %q (%v)", data, err)
+	}
+}
+
 func TestGetExtensionAndContentTypeHelpers(t *testing.T) {
 	if ext := getExtensionFromURL("https://x/y.png?v=1"); ext != ".png" {
 		t.Fatalf("expected .png got %s", ext)
@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"MrRSS/internal/utils/httputil"
+)
+
+// rangeServer serves data with Range support and counts the bytes it sends
+func rangeServer(t *testing.T, data []byte) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var sent atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(&countingWriter{ResponseWriter: w, n: &sent}, r, "", time.Unix(0, 0), bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &sent
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+func TestMediaStream_SeekFetchesOnlyReadChunks(t *testing.T) {
+	data := make([]byte, 16*StreamChunkSize+123)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	srv, sent := rangeServer(t, data)
+	mc, err := NewMediaCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewMediaCache failed: %v", err)
+	}
+	// Test servers listen on loopback
+	mc.SetOutboundPolicy(httputil.OutboundPolicy{AllowPrivate: true})
+	url := srv.URL + "/episode.mp3"
+
+	stream, err := mc.Open(context.Background(), url, "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if stream.Size() != int64(len(data)) {
+		t.Fatalf("expected size %d, got %d", len(data), stream.Size())
+	}
+	if stream.ContentType() != "audio/mpeg" || stream.ETag() != `"v1"` {
+		t.Fatalf("unexpected content type %q or etag %q", stream.ContentType(), stream.ETag())
+	}
+
+	// Seek into the middle of a chunk and read across a chunk boundary
+	offset := int64(5*StreamChunkSize + 1000)
+	if _, err := stream.Seek(offset, io.SeekStart); err != nil {
+		t.Fatalf("Seek failed: %v", err)
+	}
+	buf := make([]byte, StreamChunkSize)
+	if _, err := io.ReadFull(stream, buf); err != nil {
+		t.Fatalf("ReadFull failed: %v", err)
+	}
+	if !bytes.Equal(buf, data[offset:offset+StreamChunkSize]) {
+		t.Fatal("read data does not match")
+	}
+	stream.Close()
+
+	// Only the probed first chunk and one read-ahead window may have been sent
+	if got := sent.Load(); got > (1+streamWindowChunks)*StreamChunkSize {
+		t.Fatalf("expected only the read chunks to be downloaded, server sent %d of %d bytes", got, len(data))
+	}
+	if mc.Exists(url) {
+		t.Fatal("partially read media should not be a complete cached file")
+	}
+
+	// Cached chunks are read from disk without the server
+	before := sent.Load()
+	stream, err = mc.OpenCached(url)
+	if err != nil {
+		t.Fatalf("OpenCached failed: %v", err)
+	}
+	if _, err := stream.Seek(offset, io.SeekStart); err != nil {
+		t.Fatalf("Seek failed: %v", err)
+	}
+	if _, err := io.ReadFull(stream, buf); err != nil {
+		t.Fatalf("cached ReadFull failed: %v", err)
+	}
+	if _, err := stream.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Seek failed: %v", err)
+	}
+	if _, err := stream.Read(buf); err != ErrNotCached {
+		t.Fatalf("expected ErrNotCached for an uncached chunk, got %v", err)
+	}
+	stream.Close()
+	if sent.Load() != before {
+		t.Fatal("OpenCached must not download")
+	}
+
+	// Reading everything merges the chunks into a single cached file
+	stream, err = mc.Open(context.Background(), url, "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	all, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	stream.Close()
+	if !bytes.Equal(all, data) {
+		t.Fatal("full read does not match")
+	}
+	if !mc.Exists(url) {
+		t.Fatal("expected complete media to be cached as a single file")
+	}
+	if size, err := mc.GetCacheSize(); err != nil || size != int64(len(data)) {
+		t.Fatalf("expected cache size %d, got %d (%v)", len(data), size, err)
+	}
+}
+
+func TestMediaStream_ServerWithoutRanges(t *testing.T) {
+	data := []byte(strings.Repeat("x", 5000))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	mc, err := NewMediaCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewMediaCache failed: %v", err)
+	}
+	// Test servers listen on loopback
+	mc.SetOutboundPolicy(httputil.OutboundPolicy{AllowPrivate: true})
+	stream, err := mc.Open(context.Background(), srv.URL+"/image", "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer stream.Close()
+	got, err := io.ReadAll(stream)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("unexpected read result (%v)", err)
+	}
+	if !mc.Exists(srv.URL + "/image") {
+		t.Fatal("expected media to be cached")
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header       string
+		start, total int64
+		ok           bool
+	}{
+		{"bytes 0-99/1000", 0, 1000, true},
+		{"bytes 500-999/1000", 500, 1000, true},
+		{"bytes 0-99/*", 0, 0, false},
+		{"items 0-99/1000", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, total, ok := parseContentRange(tt.header)
+		if start != tt.start || total != tt.total || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v", tt.header, start, total, ok)
+		}
+	}
+}
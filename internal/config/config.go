@@ -32,11 +32,19 @@ type Defaults struct {
 	AITranslationPrompt           string `json:"ai_translation_prompt"`
 	AIUsageLimit                  string `json:"ai_usage_limit"`
 	AIUsageTokens                 string `json:"ai_usage_tokens"`
+	ArchiveFavorites              bool   `json:"archive_favorites"`
 	AutoCleanupEnabled            bool   `json:"auto_cleanup_enabled"`
 	AutoShowAllContent            bool   `json:"auto_show_all_content"`
+	BackupCompress                bool   `json:"backup_compress"`
+	BackupEnabled                 bool   `json:"backup_enabled"`
+	BackupIntervalHours           int    `json:"backup_interval_hours"`
+	BackupKeepDaily               int    `json:"backup_keep_daily"`
+	BackupKeepWeekly              int    `json:"backup_keep_weekly"`
 	BaiduAppId                    string `json:"baidu_app_id"`
 	BaiduSecretKey                string `json:"baidu_secret_key"`
 	CloseToTray                   bool   `json:"close_to_tray"`
+	ColdStorageEnabled            bool   `json:"cold_storage_enabled"`
+	ColdStorageKeepContent        bool   `json:"cold_storage_keep_content"`
 	ContentFontFamily             string `json:"content_font_family"`
 	ContentFontSize               int    `json:"content_font_size"`
 	ContentLineHeight             string `json:"content_line_height"`
@@ -89,6 +97,11 @@ type Defaults struct {
 	ObsidianEnabled               bool   `json:"obsidian_enabled"`
 	ObsidianVault                 string `json:"obsidian_vault"`
 	ObsidianVaultPath             string `json:"obsidian_vault_path"`
+	OfflineMode                   bool   `json:"offline_mode"`
+	OfflinePrefetchCategories     string `json:"offline_prefetch_categories"`
+	OfflinePrefetchEnabled        bool   `json:"offline_prefetch_enabled"`
+	OfflinePrefetchFilters        string `json:"offline_prefetch_filters"`
+	OfflinePrefetchMaxMb          int    `json:"offline_prefetch_max_mb"`
 	ProxyEnabled                  bool   `json:"proxy_enabled"`
 	ProxyHost                     string `json:"proxy_host"`
 	ProxyPassword                 string `json:"proxy_password"`
@@ -100,6 +113,8 @@ type Defaults struct {
 	RsshubAPIKey                  string `json:"rsshub_api_key"`
 	RsshubEnabled                 bool   `json:"rsshub_enabled"`
 	RsshubEndpoint                string `json:"rsshub_endpoint"`
+	RsshubHealthCheckInterval     int    `json:"rsshub_health_check_interval"`
+	RsshubInstances               string `json:"rsshub_instances"`
 	Rules                         string `json:"rules"`
 	Shortcuts                     string `json:"shortcuts"`
 	ShortcutsEnabled              bool   `json:"shortcuts_enabled"`
@@ -121,6 +136,7 @@ type Defaults struct {
 	TranslationProvider           string `json:"translation_provider"`
 	UpdateCheckEnabled            bool   `json:"update_check_enabled"`
 	UpdateInterval                int    `json:"update_interval"`
+	WebpageReaderSafe             bool   `json:"webpage_reader_safe"`
 	WindowHeight                  string `json:"window_height"`
 	WindowMaximized               string `json:"window_maximized"`
 	WindowWidth                   string `json:"window_width"`
@@ -175,16 +191,32 @@ func GetString(key string) string {
 		return defaults.AIUsageLimit
 	case "ai_usage_tokens":
 		return defaults.AIUsageTokens
+	case "archive_favorites":
+		return strconv.FormatBool(defaults.ArchiveFavorites)
 	case "auto_cleanup_enabled":
 		return strconv.FormatBool(defaults.AutoCleanupEnabled)
 	case "auto_show_all_content":
 		return strconv.FormatBool(defaults.AutoShowAllContent)
+	case "backup_compress":
+		return strconv.FormatBool(defaults.BackupCompress)
+	case "backup_enabled":
+		return strconv.FormatBool(defaults.BackupEnabled)
+	case "backup_interval_hours":
+		return strconv.Itoa(defaults.BackupIntervalHours)
+	case "backup_keep_daily":
+		return strconv.Itoa(defaults.BackupKeepDaily)
+	case "backup_keep_weekly":
+		return strconv.Itoa(defaults.BackupKeepWeekly)
 	case "baidu_app_id":
 		return defaults.BaiduAppId
 	case "baidu_secret_key":
 		return defaults.BaiduSecretKey
 	case "close_to_tray":
 		return strconv.FormatBool(defaults.CloseToTray)
+	case "cold_storage_enabled":
+		return strconv.FormatBool(defaults.ColdStorageEnabled)
+	case "cold_storage_keep_content":
+		return strconv.FormatBool(defaults.ColdStorageKeepContent)
 	case "content_font_family":
 		return defaults.ContentFontFamily
 	case "content_font_size":
@@ -289,6 +321,16 @@ func GetString(key string) string {
 		return defaults.ObsidianVault
 	case "obsidian_vault_path":
 		return defaults.ObsidianVaultPath
+	case "offline_mode":
+		return strconv.FormatBool(defaults.OfflineMode)
+	case "offline_prefetch_categories":
+		return defaults.OfflinePrefetchCategories
+	case "offline_prefetch_enabled":
+		return strconv.FormatBool(defaults.OfflinePrefetchEnabled)
+	case "offline_prefetch_filters":
+		return defaults.OfflinePrefetchFilters
+	case "offline_prefetch_max_mb":
+		return strconv.Itoa(defaults.OfflinePrefetchMaxMb)
 	case "proxy_enabled":
 		return strconv.FormatBool(defaults.ProxyEnabled)
 	case "proxy_host":
@@ -311,6 +353,10 @@ func GetString(key string) string {
 		return strconv.FormatBool(defaults.RsshubEnabled)
 	case "rsshub_endpoint":
 		return defaults.RsshubEndpoint
+	case "rsshub_health_check_interval":
+		return strconv.Itoa(defaults.RsshubHealthCheckInterval)
+	case "rsshub_instances":
+		return defaults.RsshubInstances
 	case "rules":
 		return defaults.Rules
 	case "shortcuts":
@@ -353,6 +399,8 @@ func GetString(key string) string {
 		return strconv.FormatBool(defaults.UpdateCheckEnabled)
 	case "update_interval":
 		return strconv.Itoa(defaults.UpdateInterval)
+	case "webpage_reader_safe":
+		return strconv.FormatBool(defaults.WebpageReaderSafe)
 	case "window_height":
 		return defaults.WindowHeight
 	case "window_maximized":
@@ -70,20 +70,29 @@ func DeriveKey(machineID string, salt []byte) []byte {
 	return pbkdf2.Key([]byte(machineID), salt, pbkdf2Iterations, keySize, sha256.New)
 }
 
-// Encrypt encrypts plaintext using AES-256-GCM with a machine-specific key.
+// Encrypt encrypts plaintext using AES-256-GCM with a machine-specific key,
+// or with the master key when one is loaded (see SetMasterKey).
 // The output format is: [salt(16 bytes)][nonce(12 bytes)][ciphertext+tag]
 // Returns base64-encoded result for safe storage in database.
 func Encrypt(plaintext string) (string, error) {
+	return EncryptWithKey(plaintext, CurrentMasterKey())
+}
+
+// EncryptWithPassphrase encrypts plaintext like Encrypt, but derives the key
+// from a passphrase instead of the machine ID, so the value can be decrypted
+// on another machine (used for backups).
+func EncryptWithPassphrase(plaintext, passphrase string) (string, error) {
 	if plaintext == "" {
 		return "", nil
 	}
-
-	// Get machine ID for key derivation
-	machineID, err := GetMachineID()
-	if err != nil {
-		return "", fmt.Errorf("failed to get machine ID: %w", err)
+	if passphrase == "" {
+		return "", errors.New("passphrase is required")
 	}
+	return encryptWithSecret(plaintext, passphrase)
+}
 
+// encryptWithSecret encrypts plaintext with a key derived from secret
+func encryptWithSecret(plaintext, secret string) (string, error) {
 	// Generate random salt
 	salt := make([]byte, saltSize)
 	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
@@ -91,7 +100,7 @@ func Encrypt(plaintext string) (string, error) {
 	}
 
 	// Derive encryption key
-	key := DeriveKey(machineID, salt)
+	key := DeriveKey(secret, salt)
 
 	// Create AES cipher
 	block, err := aes.NewCipher(key)
@@ -127,11 +136,24 @@ func Encrypt(plaintext string) (string, error) {
 
 // Decrypt decrypts ciphertext that was encrypted with Encrypt.
 // The input must be version-prefixed base64-encoded and contain: [salt][nonce][ciphertext+tag]
+// Values of a master key that is not loaded fail with ErrLocked.
 func Decrypt(ciphertextBase64 string) (string, error) {
+	return DecryptWithKey(ciphertextBase64, CurrentMasterKey())
+}
+
+// DecryptWithPassphrase decrypts ciphertext that was encrypted with EncryptWithPassphrase
+func DecryptWithPassphrase(ciphertextBase64, passphrase string) (string, error) {
 	if ciphertextBase64 == "" {
 		return "", nil
 	}
+	if passphrase == "" {
+		return "", errors.New("passphrase is required")
+	}
+	return decryptWithSecret(ciphertextBase64, passphrase)
+}
 
+// decryptWithSecret decrypts ciphertext with a key derived from secret
+func decryptWithSecret(ciphertextBase64, secret string) (string, error) {
 	// Check and strip version marker
 	if !strings.HasPrefix(ciphertextBase64, versionMarker) {
 		return "", fmt.Errorf("missing or invalid version marker")
@@ -152,14 +174,8 @@ func Decrypt(ciphertextBase64 string) (string, error) {
 	// Extract salt
 	salt := data[:saltSize]
 
-	// Get machine ID for key derivation
-	machineID, err := GetMachineID()
-	if err != nil {
-		return "", fmt.Errorf("failed to get machine ID: %w", err)
-	}
-
 	// Derive decryption key
-	key := DeriveKey(machineID, salt)
+	key := DeriveKey(secret, salt)
 
 	// Create AES cipher
 	block, err := aes.NewCipher(key)
@@ -199,5 +215,5 @@ func IsEncrypted(value string) bool {
 	}
 
 	// Check for version marker - this is definitive, not a heuristic
-	return strings.HasPrefix(value, versionMarker)
+	return strings.HasPrefix(value, versionMarker) || strings.HasPrefix(value, masterKeyMarker)
 }
@@ -3,6 +3,7 @@ package crypto
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestEncryptDecrypt(t *testing.T) {
@@ -241,3 +242,116 @@ func BenchmarkIsEncrypted(b *testing.B) {
 		_ = IsEncrypted(encrypted)
 	}
 }
+
+func TestEncryptWithPassphrase(t *testing.T) {
+	encrypted, err := EncryptWithPassphrase("sk-secret", "correct horse")
+	if err != nil {
+		t.Fatalf("EncryptWithPassphrase() error = %v", err)
+	}
+	if !IsEncrypted(encrypted) {
+		t.Errorf("Expected version marker on %q", encrypted)
+	}
+
+	decrypted, err := DecryptWithPassphrase(encrypted, "correct horse")
+	if err != nil {
+		t.Fatalf("DecryptWithPassphrase() error = %v", err)
+	}
+	if decrypted != "sk-secret" {
+		t.Errorf("DecryptWithPassphrase() = %q, want %q", decrypted, "sk-secret")
+	}
+
+	if _, err := DecryptWithPassphrase(encrypted, "wrong"); err == nil {
+		t.Error("Expected an error for a wrong passphrase")
+	}
+	if _, err := EncryptWithPassphrase("sk-secret", ""); err == nil {
+		t.Error("Expected an error for an empty passphrase")
+	}
+}
+
+func TestMasterKey(t *testing.T) {
+	t.Cleanup(func() { SetMasterKey(nil) })
+
+	salt, err := NewSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DeriveMasterKey("correct horse", salt)
+	if err != nil {
+		t.Fatalf("DeriveMasterKey() error = %v", err)
+	}
+	if other, _ := DeriveMasterKey("wrong", salt); other.ID == key.ID {
+		t.Error("Expected different key IDs for different passphrases")
+	}
+
+	machineValue, err := Encrypt("machine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetMasterKey(key)
+	encrypted, err := Encrypt("sk-secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !IsEncrypted(encrypted) || KeyID(encrypted) != key.ID {
+		t.Errorf("Expected a value tagged with key %s, got %q", key.ID, encrypted)
+	}
+	if decrypted, err := Decrypt(encrypted); err != nil || decrypted != "sk-secret" {
+		t.Errorf("Decrypt() = %q, %v", decrypted, err)
+	}
+	if decrypted, err := Decrypt(machineValue); err != nil || decrypted != "machine" {
+		t.Errorf("Decrypt() of a machine key value = %q, %v", decrypted, err)
+	}
+
+	// Tampering with the key tag fails authentication
+	retagged := strings.Replace(encrypted, key.ID, strings.Repeat("0", keyIDLength), 1)
+	zero := &MasterKey{ID: strings.Repeat("0", keyIDLength), key: key.key}
+	if _, err := DecryptWithKey(retagged, zero); err == nil {
+		t.Error("Expected an error for a retagged value")
+	}
+
+	wrapped, err := WrapMasterKey(key)
+	if err != nil {
+		t.Fatalf("WrapMasterKey() error = %v", err)
+	}
+	SetMasterKey(nil)
+	if _, err := Decrypt(encrypted); err != ErrLocked {
+		t.Errorf("Decrypt() without the master key error = %v, want ErrLocked", err)
+	}
+	unwrapped, err := UnwrapMasterKey(wrapped)
+	if err != nil || unwrapped.ID != key.ID {
+		t.Fatalf("UnwrapMasterKey() = %+v, %v", unwrapped, err)
+	}
+}
+
+func TestKeyChangeHoldsEncrypt(t *testing.T) {
+	t.Cleanup(func() { SetMasterKey(nil) })
+
+	key := newMasterKey(make([]byte, keySize))
+	change := BeginKeyChange()
+	if change.Current() != nil {
+		t.Fatalf("Current() = %+v, want the machine key", change.Current())
+	}
+
+	// Encrypt waits for the change, then uses the new key
+	done := make(chan string)
+	go func() {
+		encrypted, _ := Encrypt("sk-secret")
+		done <- encrypted
+	}()
+	select {
+	case <-done:
+		t.Fatal("Encrypt() did not wait for the key change")
+	case <-time.After(50 * time.Millisecond):
+	}
+	change.Commit(key)
+	change.Abort()
+	if encrypted := <-done; KeyID(encrypted) != key.ID {
+		t.Errorf("Encrypt() after the change = %q, want a value of key %s", encrypted, key.ID)
+	}
+
+	// An aborted change keeps the key
+	BeginKeyChange().Abort()
+	if CurrentMasterKey() != key {
+		t.Error("Abort() changed the master key")
+	}
+}
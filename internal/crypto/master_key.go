@@ -0,0 +1,231 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// Argon2id parameters for deriving the master key (RFC 9106 second recommended option)
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	// Version marker of values encrypted with a master key. It is followed by
+	// the key ID and a colon, so values tell which key they need.
+	masterKeyMarker = "MrRSS-v2:"
+	// Length of the key ID in hex characters
+	keyIDLength = 16
+)
+
+var (
+	// ErrLocked is returned when a value needs a master key that is not loaded
+	ErrLocked = errors.New("encrypted with a master passphrase that is not unlocked")
+	// ErrWrongPassphrase is returned when a passphrase does not derive the expected key
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+)
+
+// MasterKey is a key derived from the user's master passphrase. Unlike the
+// machine key it survives moving the database to another machine.
+type MasterKey struct {
+	ID  string // Fingerprint of the key, stored with every value it encrypts
+	key []byte
+}
+
+var (
+	masterMu sync.RWMutex
+	master   *MasterKey
+)
+
+// NewSalt returns a random salt for DeriveMasterKey
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return salt, nil
+}
+
+// DeriveMasterKey derives a master key from a passphrase using Argon2id
+func DeriveMasterKey(passphrase string, salt []byte) (*MasterKey, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase is required")
+	}
+	return newMasterKey(argon2.IDKey([]byte(passphrase), salt, argon2Time, argon2Memory, argon2Threads, keySize)), nil
+}
+
+func newMasterKey(key []byte) *MasterKey {
+	sum := sha256.Sum256(append([]byte("MrRSS master key id:"), key...))
+	return &MasterKey{ID: hex.EncodeToString(sum[:])[:keyIDLength], key: key}
+}
+
+// SetMasterKey makes Encrypt use key; nil switches back to the machine key.
+// Values encrypted with the machine key can always still be decrypted.
+func SetMasterKey(key *MasterKey) {
+	masterMu.Lock()
+	defer masterMu.Unlock()
+	master = key
+}
+
+// KeyChange holds the master key while values are re-encrypted with another
+// key. Encrypt and Decrypt wait until the change ends, so no value is
+// encrypted with a key that is being replaced.
+type KeyChange struct {
+	done bool
+}
+
+// BeginKeyChange starts a key change, which must end with Commit or Abort
+func BeginKeyChange() *KeyChange {
+	masterMu.Lock()
+	return &KeyChange{}
+}
+
+// Current returns the master key being replaced, or nil for the machine key
+func (c *KeyChange) Current() *MasterKey {
+	return master
+}
+
+// Commit makes Encrypt use key and ends the change
+func (c *KeyChange) Commit(key *MasterKey) {
+	if c.done {
+		return
+	}
+	master = key
+	c.done = true
+	masterMu.Unlock()
+}
+
+// Abort ends the change and keeps the current key. It does nothing after Commit.
+func (c *KeyChange) Abort() {
+	if c.done {
+		return
+	}
+	c.done = true
+	masterMu.Unlock()
+}
+
+// CurrentMasterKey returns the loaded master key, or nil when the machine key is used
+func CurrentMasterKey() *MasterKey {
+	masterMu.RLock()
+	defer masterMu.RUnlock()
+	return master
+}
+
+// WrapMasterKey encrypts a master key with the machine key, so it can be
+// loaded on this machine without asking for the passphrase
+func WrapMasterKey(key *MasterKey) (string, error) {
+	return EncryptWithKey(base64.StdEncoding.EncodeToString(key.key), nil)
+}
+
+// UnwrapMasterKey decrypts a master key wrapped by WrapMasterKey. It fails on
+// any other machine.
+func UnwrapMasterKey(wrapped string) (*MasterKey, error) {
+	encoded, err := DecryptWithKey(wrapped, nil)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != keySize {
+		return nil, ErrInvalidCiphertext
+	}
+	return newMasterKey(key), nil
+}
+
+// KeyID returns the ID of the master key a value is encrypted with, or ""
+// for values encrypted with the machine key or not encrypted at all
+func KeyID(value string) string {
+	if !strings.HasPrefix(value, masterKeyMarker) {
+		return ""
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, masterKeyMarker), ":")
+	return id
+}
+
+// EncryptWithKey encrypts plaintext with a master key, or with the machine
+// key when key is nil
+func EncryptWithKey(plaintext string, key *MasterKey) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	if key == nil {
+		machineID, err := GetMachineID()
+		if err != nil {
+			return "", fmt.Errorf("failed to get machine ID: %w", err)
+		}
+		return encryptWithSecret(plaintext, machineID)
+	}
+
+	gcm, err := newGCM(key.key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	// The key ID is authenticated, so a value cannot be moved to another key tag
+	prefix := masterKeyMarker + key.ID + ":"
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(prefix))
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptWithKey decrypts a value encrypted with the machine key, or with
+// key when the value carries its ID. It returns ErrLocked for a value of
+// another master key.
+func DecryptWithKey(value string, key *MasterKey) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(value, masterKeyMarker) {
+		machineID, err := GetMachineID()
+		if err != nil {
+			return "", fmt.Errorf("failed to get machine ID: %w", err)
+		}
+		return decryptWithSecret(value, machineID)
+	}
+
+	id := KeyID(value)
+	if key == nil || key.ID != id {
+		return "", ErrLocked
+	}
+	prefix := masterKeyMarker + id + ":"
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64: %w", err)
+	}
+	gcm, err := newGCM(key.key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize()+gcm.Overhead() {
+		return "", ErrInvalidCiphertext
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(prefix))
+	if err != nil {
+		return "", ErrDecryptionFailed
+	}
+	return string(plaintext), nil
+}
+
+// newGCM creates an AES-256-GCM cipher for key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
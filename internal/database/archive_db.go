@@ -0,0 +1,138 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"MrRSS/internal/models"
+)
+
+// maxArchiveAttempts is how often archiving an article is tried before it is
+// marked as failed
+const maxArchiveAttempts = 3
+
+// QueueArticleArchive queues an article for archiving. Failed archives are
+// queued again; finished ones only when refresh is set.
+func (db *DB) QueueArticleArchive(articleID int64, refresh bool) error {
+	db.WaitForReady()
+	_, err := db.Exec(`
+		INSERT INTO article_archives (article_id, status, queued_at) VALUES (?, 'pending', ?)
+		ON CONFLICT(article_id) DO UPDATE SET status = 'pending', error = '', attempts = 0, queued_at = excluded.queued_at
+		WHERE article_archives.status = 'failed' OR ?
+	`, articleID, time.Now(), refresh)
+	return err
+}
+
+// QueueFavoriteArchives queues every favorite that has no archive yet and
+// returns how many were queued
+func (db *DB) QueueFavoriteArchives() (int64, error) {
+	db.WaitForReady()
+	result, err := db.Exec(`
+		INSERT OR IGNORE INTO article_archives (article_id, status, queued_at)
+		SELECT id, 'pending', ? FROM articles WHERE is_favorite = 1
+	`, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetPendingArticleArchives returns the IDs of articles waiting to be
+// archived, oldest first
+func (db *DB) GetPendingArticleArchives(limit int) ([]int64, error) {
+	db.WaitForReady()
+	rows, err := db.Query(`
+		SELECT article_id FROM article_archives
+		WHERE status = 'pending'
+		ORDER BY queued_at ASC, article_id ASC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SetArticleArchived records a finished archive of size bytes
+func (db *DB) SetArticleArchived(articleID, size int64) error {
+	db.WaitForReady()
+	_, err := db.Exec(`
+		UPDATE article_archives
+		SET status = 'done', size = ?, error = '', attempts = attempts + 1, archived_at = ?
+		WHERE article_id = ?
+	`, size, time.Now(), articleID)
+	return err
+}
+
+// SetArticleArchiveFailed records a failed archiving attempt. The article
+// stays queued until it has failed maxArchiveAttempts times.
+func (db *DB) SetArticleArchiveFailed(articleID int64, message string) error {
+	db.WaitForReady()
+	_, err := db.Exec(`
+		UPDATE article_archives
+		SET attempts = attempts + 1, error = ?,
+			status = CASE WHEN attempts + 1 >= ? THEN 'failed' ELSE 'pending' END
+		WHERE article_id = ?
+	`, message, maxArchiveAttempts, articleID)
+	return err
+}
+
+// GetArticleArchive returns the archive record of an article, or nil when
+// the article was never archived
+func (db *DB) GetArticleArchive(articleID int64) (*models.ArticleArchive, error) {
+	db.WaitForReady()
+
+	var a models.ArticleArchive
+	var archivedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT article_id, status, size, error, attempts, queued_at, archived_at
+		FROM article_archives WHERE article_id = ?
+	`, articleID).Scan(&a.ArticleID, &a.Status, &a.Size, &a.Error, &a.Attempts, &a.QueuedAt, &archivedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if archivedAt.Valid {
+		a.ArchivedAt = &archivedAt.Time
+	}
+	return &a, nil
+}
+
+// DeleteArticleArchive forgets the archive of an article
+func (db *DB) DeleteArticleArchive(articleID int64) error {
+	db.WaitForReady()
+	_, err := db.Exec(`DELETE FROM article_archives WHERE article_id = ?`, articleID)
+	return err
+}
+
+// GetArchivedArticleIDs returns the IDs of all articles with an archive record
+func (db *DB) GetArchivedArticleIDs() (map[int64]bool, error) {
+	db.WaitForReady()
+	rows, err := db.Query(`SELECT article_id FROM article_archives`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
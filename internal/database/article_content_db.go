@@ -52,6 +52,35 @@ func (db *DB) DeleteArticleContent(articleID int64) error {
 	return err
 }
 
+// GetArticleFullText retrieves the cached full-text extraction of an article
+func (db *DB) GetArticleFullText(articleID int64) (string, bool, error) {
+	db.WaitForReady()
+	var content string
+	err := db.QueryRow(
+		`SELECT content FROM article_fulltexts WHERE article_id = ?`,
+		articleID,
+	).Scan(&content)
+
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return content, true, nil
+}
+
+// SetArticleFullText stores or updates the full-text extraction of an article
+func (db *DB) SetArticleFullText(articleID int64, content string) error {
+	db.WaitForReady()
+	_, err := db.Exec(
+		`INSERT OR REPLACE INTO article_fulltexts (article_id, content, fetched_at)
+		 VALUES (?, ?, CURRENT_TIMESTAMP)`,
+		articleID, content,
+	)
+	return err
+}
+
 // CleanupOldArticleContents removes article content cache entries older than maxAgeDays
 func (db *DB) CleanupOldArticleContents(maxAgeDays int) (int64, error) {
 	db.WaitForReady()
@@ -1,12 +1,13 @@
 package database
 
-import "log"
+// Article counts are read from feed_counters, which triggers keep in step
+// with the articles table (see feed_counters_db.go).
 
 // GetTotalUnreadCount returns the total number of unread articles.
 func (db *DB) GetTotalUnreadCount() (int, error) {
 	db.WaitForReady()
 	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM articles WHERE is_read = 0 AND is_hidden = 0").Scan(&count)
+	err := db.QueryRow("SELECT COALESCE(SUM(unread), 0) FROM feed_counters").Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -17,7 +18,7 @@ func (db *DB) GetTotalUnreadCount() (int, error) {
 func (db *DB) GetUnreadCountByFeed(feedID int64) (int, error) {
 	db.WaitForReady()
 	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM articles WHERE feed_id = ? AND is_read = 0 AND is_hidden = 0", feedID).Scan(&count)
+	err := db.QueryRow("SELECT COALESCE(SUM(unread), 0) FROM feed_counters WHERE feed_id = ?", feedID).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -26,189 +27,35 @@ func (db *DB) GetUnreadCountByFeed(feedID int64) (int, error) {
 
 // GetUnreadCountsForAllFeeds returns a map of feed_id to unread count.
 func (db *DB) GetUnreadCountsForAllFeeds() (map[int64]int, error) {
-	db.WaitForReady()
-	rows, err := db.Query(`
-		SELECT feed_id, COUNT(*)
-		FROM articles
-		WHERE is_read = 0 AND is_hidden = 0
-		GROUP BY feed_id
-	`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	counts := make(map[int64]int)
-	for rows.Next() {
-		var feedID int64
-		var count int
-		if err := rows.Scan(&feedID, &count); err != nil {
-			log.Println("Error scanning unread count:", err)
-			continue
-		}
-		counts[feedID] = count
-	}
-	return counts, rows.Err()
+	return db.getFeedCounter("unread")
 }
 
 // GetFavoriteCountsForAllFeeds returns a map of feed_id to favorite article count.
 func (db *DB) GetFavoriteCountsForAllFeeds() (map[int64]int, error) {
-	db.WaitForReady()
-	rows, err := db.Query(`
-		SELECT feed_id, COUNT(*)
-		FROM articles
-		WHERE is_favorite = 1 AND is_hidden = 0
-		GROUP BY feed_id
-	`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	counts := make(map[int64]int)
-	for rows.Next() {
-		var feedID int64
-		var count int
-		if err := rows.Scan(&feedID, &count); err != nil {
-			log.Println("Error scanning favorite count:", err)
-			continue
-		}
-		counts[feedID] = count
-	}
-	return counts, rows.Err()
+	return db.getFeedCounter("favorites")
 }
 
 // GetReadLaterCountsForAllFeeds returns a map of feed_id to read_later article count.
 func (db *DB) GetReadLaterCountsForAllFeeds() (map[int64]int, error) {
-	db.WaitForReady()
-	rows, err := db.Query(`
-		SELECT feed_id, COUNT(*)
-		FROM articles
-		WHERE is_read_later = 1 AND is_hidden = 0
-		GROUP BY feed_id
-	`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	counts := make(map[int64]int)
-	for rows.Next() {
-		var feedID int64
-		var count int
-		if err := rows.Scan(&feedID, &count); err != nil {
-			log.Println("Error scanning read_later count:", err)
-			continue
-		}
-		counts[feedID] = count
-	}
-	return counts, rows.Err()
+	return db.getFeedCounter("read_later")
 }
 
 // GetImageModeCountsForAllFeeds returns a map of feed_id to image article count.
 func (db *DB) GetImageModeCountsForAllFeeds() (map[int64]int, error) {
-	db.WaitForReady()
-	rows, err := db.Query(`
-		SELECT feed_id, COUNT(*)
-		FROM articles
-		WHERE (image_url IS NOT NULL AND image_url != '') AND is_hidden = 0
-		GROUP BY feed_id
-	`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	counts := make(map[int64]int)
-	for rows.Next() {
-		var feedID int64
-		var count int
-		if err := rows.Scan(&feedID, &count); err != nil {
-			log.Println("Error scanning image mode count:", err)
-			continue
-		}
-		counts[feedID] = count
-	}
-	return counts, rows.Err()
+	return db.getFeedCounter("images")
 }
 
 // GetImageUnreadCountsForAllFeeds returns a map of feed_id to unread image article count.
 func (db *DB) GetImageUnreadCountsForAllFeeds() (map[int64]int, error) {
-	db.WaitForReady()
-	rows, err := db.Query(`
-		SELECT feed_id, COUNT(*)
-		FROM articles
-		WHERE (image_url IS NOT NULL AND image_url != '') AND is_read = 0 AND is_hidden = 0
-		GROUP BY feed_id
-	`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	counts := make(map[int64]int)
-	for rows.Next() {
-		var feedID int64
-		var count int
-		if err := rows.Scan(&feedID, &count); err != nil {
-			log.Println("Error scanning image unread count:", err)
-			continue
-		}
-		counts[feedID] = count
-	}
-	return counts, rows.Err()
+	return db.getFeedCounter("images_unread")
 }
 
 // GetFavoriteUnreadCountsForAllFeeds returns a map of feed_id to favorite AND unread article count.
 func (db *DB) GetFavoriteUnreadCountsForAllFeeds() (map[int64]int, error) {
-	db.WaitForReady()
-	rows, err := db.Query(`
-		SELECT feed_id, COUNT(*)
-		FROM articles
-		WHERE is_favorite = 1 AND is_read = 0 AND is_hidden = 0
-		GROUP BY feed_id
-	`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	counts := make(map[int64]int)
-	for rows.Next() {
-		var feedID int64
-		var count int
-		if err := rows.Scan(&feedID, &count); err != nil {
-			log.Println("Error scanning favorite unread count:", err)
-			continue
-		}
-		counts[feedID] = count
-	}
-	return counts, rows.Err()
+	return db.getFeedCounter("favorites_unread")
 }
 
 // GetReadLaterUnreadCountsForAllFeeds returns a map of feed_id to read_later AND unread article count.
 func (db *DB) GetReadLaterUnreadCountsForAllFeeds() (map[int64]int, error) {
-	db.WaitForReady()
-	rows, err := db.Query(`
-		SELECT feed_id, COUNT(*)
-		FROM articles
-		WHERE is_read_later = 1 AND is_read = 0 AND is_hidden = 0
-		GROUP BY feed_id
-	`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	counts := make(map[int64]int)
-	for rows.Next() {
-		var feedID int64
-		var count int
-		if err := rows.Scan(&feedID, &count); err != nil {
-			log.Println("Error scanning read_later unread count:", err)
-			continue
-		}
-		counts[feedID] = count
-	}
-	return counts, rows.Err()
+	return db.getFeedCounter("read_later_unread")
 }
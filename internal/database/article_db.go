@@ -128,6 +128,56 @@ func (db *DB) GetArticles(filter string, feedID int64, category string, showHidd
 func (db *DB) GetArticlesWithUnreadFilter(filter string, feedID int64, category string, showHidden bool, onlyUnread bool, limit, offset int) ([]models.Article, error) {
 	db.WaitForReady()
 
+	where, args, empty, err := db.articleListWhere(filter, feedID, category, showHidden, onlyUnread)
+	if err != nil || empty {
+		return []models.Article{}, err
+	}
+
+	query := "SELECT " + articleListColumns + articleListFrom + joinWhere(where) + " ORDER BY a.published_at DESC, a.id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var articles []models.Article
+	for rows.Next() {
+		a, err := scanArticleListRow(rows)
+		if err != nil {
+			log.Println("Error scanning article:", err)
+			continue
+		}
+		articles = append(articles, a)
+	}
+	return articles, nil
+}
+
+// GetArticlesPage returns the articles of a list view like
+// GetArticlesWithUnreadFilter, one page at a time from an opaque cursor
+// instead of an offset. An empty cursor starts at the newest article.
+func (db *DB) GetArticlesPage(filter string, feedID int64, category string, showHidden bool, onlyUnread bool, cursor string, limit int) (*ArticlePage, error) {
+	db.WaitForReady()
+
+	c, err := decodeArticleCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	where, args, empty, err := db.articleListWhere(filter, feedID, category, showHidden, onlyUnread)
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		return newArticlePage(c, nil, nil, false), nil
+	}
+	return db.queryArticlePage(articleListFrom, where, args, c, limit)
+}
+
+// articleListWhere returns the WHERE clauses and arguments selecting the
+// articles of a list view. empty reports a category without feeds, which
+// cannot have articles.
+func (db *DB) articleListWhere(filter string, feedID int64, category string, showHidden bool, onlyUnread bool) ([]string, []interface{}, bool, error) {
 	// Optimization: For category queries, first get the feed IDs, then query articles
 	// This avoids JOINing all articles and then filtering by category
 	var feedIDFilter []int64
@@ -148,7 +198,7 @@ func (db *DB) GetArticlesWithUnreadFilter(filter string, feedID int64, category
 
 		rows, err := db.Query(categoryQuery, categoryArgs...)
 		if err != nil {
-			return nil, fmt.Errorf("failed to query feeds by category: %w", err)
+			return nil, nil, false, fmt.Errorf("failed to query feeds by category: %w", err)
 		}
 		defer rows.Close()
 
@@ -163,18 +213,12 @@ func (db *DB) GetArticlesWithUnreadFilter(filter string, feedID int64, category
 
 		// If no feeds found in this category, return empty result early
 		if len(feedIDFilter) == 0 {
-			return []models.Article{}, nil
+			return nil, nil, true, nil
 		}
 
 		useFeedIDFilter = true
 	}
 
-	// Build the main query
-	baseQuery := `
-		SELECT a.id, a.feed_id, a.title, a.url, a.image_url, a.audio_url, a.video_url, a.published_at, a.is_read, a.is_favorite, a.is_hidden, a.is_read_later, a.translated_title, a.summary, a.freshrss_item_id, f.title, a.author
-		FROM articles a
-		JOIN feeds f ON a.feed_id = f.id
-	`
 	var args []interface{}
 	whereClauses := []string{}
 
@@ -219,46 +263,7 @@ func (db *DB) GetArticlesWithUnreadFilter(filter string, feedID int64, category
 		args = append(args, feedID)
 	}
 
-	query := baseQuery
-	if len(whereClauses) > 0 {
-		query += " WHERE " + whereClauses[0]
-		for i := 1; i < len(whereClauses); i++ {
-			query += " AND " + whereClauses[i]
-		}
-	}
-	query += " ORDER BY a.published_at DESC LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
-
-	rows, err := db.Query(query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var articles []models.Article
-	for rows.Next() {
-		var a models.Article
-		var imageURL, audioURL, videoURL, translatedTitle, summary, freshrssItemID, author sql.NullString
-		var publishedAt sql.NullTime
-		if err := rows.Scan(&a.ID, &a.FeedID, &a.Title, &a.URL, &imageURL, &audioURL, &videoURL, &publishedAt, &a.IsRead, &a.IsFavorite, &a.IsHidden, &a.IsReadLater, &translatedTitle, &summary, &freshrssItemID, &a.FeedTitle, &author); err != nil {
-			log.Println("Error scanning article:", err)
-			continue
-		}
-		a.ImageURL = imageURL.String
-		a.AudioURL = audioURL.String
-		a.VideoURL = videoURL.String
-		if publishedAt.Valid {
-			a.PublishedAt = publishedAt.Time
-		} else {
-			a.PublishedAt = time.Time{}
-		}
-		a.TranslatedTitle = translatedTitle.String
-		a.Summary = summary.String
-		a.FreshRSSItemID = freshrssItemID.String
-		a.Author = author.String
-		articles = append(articles, a)
-	}
-	return articles, nil
+	return whereClauses, args, false, nil
 }
 
 // GetArticleByID retrieves a single article by its ID.
@@ -217,6 +217,113 @@ func TestGetArticlesWithUnreadFilterCombinesWithFavorites(t *testing.T) {
 	}
 }
 
+func TestGetArticlesPageWalksListByCursor(t *testing.T) {
+	db := setupDBWithFeed(t)
+
+	var feedID int64
+	if err := db.QueryRow(`SELECT id FROM feeds WHERE url = ?`, "https://example.com/feed").Scan(&feedID); err != nil {
+		t.Fatalf("scan feed id: %v", err)
+	}
+	insert := func(title string, publishedAt time.Time) {
+		t.Helper()
+		if _, err := db.Exec(
+			`INSERT INTO articles (feed_id, title, url, published_at, unique_id) VALUES (?, ?, ?, ?, ?)`,
+			feedID, title, "https://example.com/"+title, publishedAt, title,
+		); err != nil {
+			t.Fatalf("insert article %q: %v", title, err)
+		}
+	}
+
+	// Several articles share a publish time, so pages must break ties by id
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		insert(fmt.Sprintf("a%02d", i), base.Add(-time.Duration(i/3)*time.Hour))
+	}
+	want, err := db.GetArticlesWithUnreadFilter("", 0, "", false, false, 100, 0)
+	if err != nil {
+		t.Fatalf("GetArticlesWithUnreadFilter: %v", err)
+	}
+
+	titles := func(articles []models.Article) []string {
+		var out []string
+		for _, a := range articles {
+			out = append(out, a.Title)
+		}
+		return out
+	}
+
+	var walked []models.Article
+	var pages []*dbpkg.ArticlePage
+	cursor := ""
+	for {
+		page, err := db.GetArticlesPage("", 0, "", false, false, cursor, 3)
+		if err != nil {
+			t.Fatalf("GetArticlesPage(%q): %v", cursor, err)
+		}
+		pages = append(pages, page)
+		walked = append(walked, page.Articles...)
+		if len(pages) == 1 {
+			if page.PrevCursor != "" {
+				t.Errorf("first page has a prev cursor")
+			}
+			// A new article arriving while scrolling does not shift later pages
+			insert("new", base.Add(time.Hour))
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if got, exp := strings.Join(titles(walked), ","), strings.Join(titles(want), ","); got != exp {
+		t.Fatalf("walked %s, want %s", got, exp)
+	}
+	if len(pages) != 4 || len(pages[3].Articles) != 1 {
+		t.Fatalf("expected pages of 3, 3, 3 and 1 articles, got %d pages", len(pages))
+	}
+
+	// Walking back from the last page returns the same pages, and the
+	// first page now leads on to the new article
+	for i := len(pages) - 1; i > 0; i-- {
+		prev, err := db.GetArticlesPage("", 0, "", false, false, pages[i].PrevCursor, 3)
+		if err != nil {
+			t.Fatalf("GetArticlesPage(prev of page %d): %v", i, err)
+		}
+		if got, exp := strings.Join(titles(prev.Articles), ","), strings.Join(titles(pages[i-1].Articles), ","); got != exp {
+			t.Errorf("page before page %d = %s, want %s", i, got, exp)
+		}
+		if (i > 1 || prev.PrevCursor != "") && prev.NextCursor == "" {
+			t.Errorf("page before page %d has no next cursor", i)
+		}
+		if i == 1 {
+			newer, err := db.GetArticlesPage("", 0, "", false, false, prev.PrevCursor, 3)
+			if err != nil || len(newer.Articles) != 1 || newer.Articles[0].Title != "new" || newer.PrevCursor != "" {
+				t.Errorf("expected only the new article before the first page, got %+v, %v", newer, err)
+			}
+		}
+	}
+
+	if _, err := db.GetArticlesPage("", 0, "", false, false, "not-a-cursor", 3); err != dbpkg.ErrInvalidCursor {
+		t.Errorf("invalid cursor error = %v, want ErrInvalidCursor", err)
+	}
+
+	// Filtered lists page the same way
+	odd := []dbpkg.FilterCondition{{Field: "article_title", Operator: "regex", Value: `^a0[13579]$`}}
+	filtered, err := db.GetFilteredArticlesPage(odd, false, "", 4)
+	if err != nil {
+		t.Fatalf("GetFilteredArticlesPage: %v", err)
+	}
+	if len(filtered.Articles) != 4 || filtered.NextCursor == "" {
+		t.Fatalf("expected a full filtered page with more to come, got %d articles", len(filtered.Articles))
+	}
+	rest, err := db.GetFilteredArticlesPage(odd, false, filtered.NextCursor, 4)
+	if err != nil {
+		t.Fatalf("GetFilteredArticlesPage(next): %v", err)
+	}
+	if len(rest.Articles) != 1 || rest.Articles[0].Title != "a09" || rest.NextCursor != "" || rest.PrevCursor == "" {
+		t.Fatalf("expected the last odd article, got %d (next %q)", len(rest.Articles), rest.NextCursor)
+	}
+}
+
 func TestSaveAndGetArticle(t *testing.T) {
 	db := setupDBWithFeed(t)
 
@@ -586,3 +693,90 @@ func TestArticleDifferentTitlesNotDeduplicated(t *testing.T) {
 		t.Fatalf("expected 2 articles with different titles, got %d", len(articles))
 	}
 }
+
+func TestArticleArchiveQueueAndCleanupProtection(t *testing.T) {
+	db := setupDBWithFeed(t)
+
+	var feedID int64
+	if err := db.QueryRow(`SELECT id FROM feeds WHERE url = ?`, "https://example.com/feed").Scan(&feedID); err != nil {
+		t.Fatalf("scan feed id: %v", err)
+	}
+
+	insert := func(title string, favorite bool) int64 {
+		res, err := db.Exec(
+			`INSERT INTO articles (feed_id, title, url, published_at, is_read, is_favorite, is_read_later, unique_id) VALUES (?, ?, ?, ?, 1, ?, 0, ?)`,
+			feedID, title, "https://example.com/"+title, time.Now().AddDate(0, 0, -90), favorite, "archive-"+title,
+		)
+		if err != nil {
+			t.Fatalf("insert article: %v", err)
+		}
+		id, _ := res.LastInsertId()
+		return id
+	}
+	favorite := insert("favorite", true)
+	ruled := insert("ruled", false)
+	plain := insert("plain", false)
+
+	if n, err := db.QueueFavoriteArchives(); err != nil || n != 1 {
+		t.Fatalf("QueueFavoriteArchives = %d, %v; want 1", n, err)
+	}
+	if err := db.QueueArticleArchive(ruled, false); err != nil {
+		t.Fatalf("QueueArticleArchive error: %v", err)
+	}
+	pending, err := db.GetPendingArticleArchives(10)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("pending = %v, %v; want 2 articles", pending, err)
+	}
+
+	// Failures keep the article queued until the last attempt
+	for i := 0; i < 3; i++ {
+		if err := db.SetArticleArchiveFailed(ruled, "unreachable"); err != nil {
+			t.Fatalf("SetArticleArchiveFailed error: %v", err)
+		}
+	}
+	if a, _ := db.GetArticleArchive(ruled); a == nil || a.Status != models.ArchiveStatusFailed || a.Attempts != 3 || a.Error != "unreachable" {
+		t.Fatalf("expected a failed archive, got %+v", a)
+	}
+	_ = db.QueueArticleArchive(ruled, false)
+	if a, _ := db.GetArticleArchive(ruled); a.Status != models.ArchiveStatusPending || a.Attempts != 0 {
+		t.Fatalf("a failed archive should be queued again, got %+v", a)
+	}
+
+	if err := db.SetArticleArchived(ruled, 1234); err != nil {
+		t.Fatalf("SetArticleArchived error: %v", err)
+	}
+	_ = db.QueueArticleArchive(ruled, false)
+	if a, _ := db.GetArticleArchive(ruled); a.Status != models.ArchiveStatusDone || a.Size != 1234 || a.ArchivedAt == nil {
+		t.Fatalf("a finished archive should stay done, got %+v", a)
+	}
+	_ = db.QueueArticleArchive(ruled, true)
+	if a, _ := db.GetArticleArchive(ruled); a.Status != models.ArchiveStatusPending {
+		t.Fatalf("refresh should queue the archive again, got %+v", a)
+	}
+	if a, _ := db.GetArticleArchive(plain); a != nil {
+		t.Fatalf("expected no archive, got %+v", a)
+	}
+
+	// Archived articles survive cleanup even when they are not favorites
+	deleted, err := db.CleanupOldReadArticles(30)
+	if err != nil || deleted != 1 {
+		t.Fatalf("CleanupOldReadArticles = %d, %v; want 1", deleted, err)
+	}
+	if _, err := db.GetArticleByID(ruled); err != nil {
+		t.Errorf("archived article was deleted: %v", err)
+	}
+	if _, err := db.GetArticleByID(plain); err == nil {
+		t.Error("expected the plain article to be deleted")
+	}
+
+	ids, err := db.GetArchivedArticleIDs()
+	if err != nil || !ids[favorite] || !ids[ruled] || len(ids) != 2 {
+		t.Errorf("archived IDs = %v, %v", ids, err)
+	}
+	if err := db.DeleteArticleArchive(ruled); err != nil {
+		t.Fatalf("DeleteArticleArchive error: %v", err)
+	}
+	if a, _ := db.GetArticleArchive(ruled); a != nil {
+		t.Errorf("expected the archive to be deleted, got %+v", a)
+	}
+}
@@ -0,0 +1,371 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"MrRSS/internal/models"
+)
+
+// FilterCondition is one condition of an advanced filter, a saved filter or a
+// rule. Conditions are joined by their Logic, with AND binding tighter than
+// OR, and Negate inverts a single condition.
+type FilterCondition struct {
+	ID       int64    `json:"id"`
+	Logic    string   `json:"logic"`    // "and", "or" (null for first condition)
+	Negate   bool     `json:"negate"`   // NOT modifier for this condition
+	Field    string   `json:"field"`    // "feed_name", "feed_category", "article_title", "published_after", "published_before", etc.
+	Operator string   `json:"operator"` // "contains", "exact", "regex" (null for date fields and multi-select)
+	Value    string   `json:"value"`    // Single value for text/date fields
+	Values   []string `json:"values"`   // Multiple values for feed_name, feed_category, feed_type and feed_tags
+}
+
+// feedTypeExpr is the type code of feed f: "regular", "freshrss", "rsshub",
+// "script", "xpath" or "email"
+const feedTypeExpr = `CASE
+		WHEN COALESCE(f.is_freshrss_source, 0) = 1 THEN 'freshrss'
+		WHEN f.url LIKE 'rsshub://%' THEN 'rsshub'
+		WHEN COALESCE(f.script_path, '') != '' THEN 'script'
+		WHEN f.type = 'email' THEN 'email'
+		WHEN f.type IN ('HTML+XPath', 'XML+XPath') THEN 'xpath'
+		ELSE 'regular'
+	END`
+
+// publishedUnixExpr is the publish time of article a in Unix seconds, with
+// articles without a date at the zero time
+var publishedUnixExpr = fmt.Sprintf("COALESCE(parse_time(a.published_at), %d)", time.Time{}.Unix())
+
+// compileFilter compiles conditions to a parameterized SQL expression over
+// articles a joined with feeds f. Without conditions every article matches.
+func (db *DB) compileFilter(conditions []FilterCondition) (string, []interface{}, error) {
+	if len(conditions) == 0 {
+		return "1", nil, nil
+	}
+
+	// OR of groups of AND-ed conditions
+	var groups []string
+	var group []string
+	var args []interface{}
+	for i, condition := range conditions {
+		expr, condArgs, err := db.compileCondition(condition)
+		if err != nil {
+			return "", nil, err
+		}
+		if condition.Negate {
+			expr = "NOT (" + expr + ")"
+		} else {
+			expr = "(" + expr + ")"
+		}
+		if i > 0 && condition.Logic != "and" {
+			groups = append(groups, "("+strings.Join(group, " AND ")+")")
+			group = nil
+		}
+		group = append(group, expr)
+		args = append(args, condArgs...)
+	}
+	groups = append(groups, "("+strings.Join(group, " AND ")+")")
+	return strings.Join(groups, " OR "), args, nil
+}
+
+// compileCondition compiles a single condition, ignoring Negate. Conditions
+// with an unknown field or without a value match every article.
+func (db *DB) compileCondition(c FilterCondition) (string, []interface{}, error) {
+	switch c.Field {
+	case "feed_name":
+		return matchAnyExpr("f.title", c.Values, c.Value)
+	case "feed_category":
+		return matchAnyExpr("COALESCE(f.category, '')", c.Values, c.Value)
+	case "feed_type":
+		return matchAnyExpr(feedTypeExpr, c.Values, c.Value)
+	case "feed_tags":
+		expr, args, _ := matchAnyExpr("t.name", c.Values, c.Value)
+		if expr == "1" {
+			return expr, nil, nil
+		}
+		return `EXISTS (SELECT 1 FROM feed_tags ft JOIN tags t ON t.id = ft.tag_id
+			WHERE ft.feed_id = a.feed_id AND (` + expr + `))`, args, nil
+
+	case "article_title":
+		return textMatchExpr("COALESCE(a.title, '')", c)
+	case "author":
+		return textMatchExpr("COALESCE(a.author, '')", c)
+	case "url":
+		return textMatchExpr("COALESCE(a.url, '')", c)
+	case "article_content":
+		// Only articles with cached content can match
+		expr, args, _ := textMatchExpr("c.content", c)
+		if expr == "1" {
+			return expr, nil, nil
+		}
+		return "EXISTS (SELECT 1 FROM article_contents c WHERE c.article_id = a.id AND " + expr + ")", args, nil
+
+	case "is_freshrss_feed":
+		return flagExpr("COALESCE(f.is_freshrss_source, 0) = 1", c.Value)
+	case "is_image_mode_feed":
+		return flagExpr("COALESCE(f.is_image_mode, 0) = 1", c.Value)
+	case "is_read":
+		return flagExpr("COALESCE(a.is_read, 0) = 1", c.Value)
+	case "is_favorite":
+		return flagExpr("COALESCE(a.is_favorite, 0) = 1", c.Value)
+	case "is_hidden":
+		return flagExpr("COALESCE(a.is_hidden, 0) = 1", c.Value)
+	case "is_read_later":
+		return flagExpr("COALESCE(a.is_read_later, 0) = 1", c.Value)
+	case "has_summary":
+		return flagExpr("COALESCE(a.summary, '') != ''", c.Value)
+	case "has_translation":
+		return flagExpr("COALESCE(a.translated_title, '') != ''", c.Value)
+	case "has_image":
+		return flagExpr("COALESCE(a.image_url, '') != ''", c.Value)
+	case "has_audio":
+		return flagExpr("COALESCE(a.audio_url, '') != ''", c.Value)
+	case "has_video":
+		return flagExpr("COALESCE(a.video_url, '') != ''", c.Value)
+
+	case "published_after", "published_before":
+		if c.Value == "" {
+			return "1", nil, nil
+		}
+		date, err := time.Parse("2006-01-02", c.Value)
+		if err != nil {
+			log.Printf("Invalid date format for %s filter: %s", c.Field, c.Value)
+			return "1", nil, nil
+		}
+		if c.Field == "published_after" {
+			return publishedUnixExpr + " >= ?", []interface{}{date.Unix()}, nil
+		}
+		// Inclusive of the whole selected day (UTC)
+		return publishedUnixExpr + " < ?", []interface{}{date.AddDate(0, 0, 1).Unix()}, nil
+
+	case "published_after_hours", "published_after_days":
+		if c.Value == "" {
+			return "1", nil, nil
+		}
+		n, err := strconv.Atoi(c.Value)
+		if err != nil || n < 0 {
+			log.Printf("Invalid value for %s filter: %s", c.Field, c.Value)
+			return "1", nil, nil
+		}
+		cutoff := time.Now().Add(-time.Duration(n) * time.Hour)
+		if c.Field == "published_after_days" {
+			cutoff = time.Now().AddDate(0, 0, -n)
+		}
+		return publishedUnixExpr + " >= ?", []interface{}{cutoff.Unix()}, nil
+
+	case "feed_articles_per_month":
+		if c.Value == "" {
+			return "1", nil, nil
+		}
+		threshold, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			log.Printf("Invalid threshold value for feed_articles_per_month filter: %s", c.Value)
+			return "1", nil, nil
+		}
+		// The rate is computed per feed as shown in the feed list
+		feeds, err := db.GetFeeds()
+		if err != nil {
+			return "", nil, err
+		}
+		var ids []string
+		for _, feed := range feeds {
+			if feed.ArticlesPerMonth >= threshold {
+				ids = append(ids, strconv.FormatInt(feed.ID, 10))
+			}
+		}
+		if len(ids) == 0 {
+			return "0", nil, nil
+		}
+		return "a.feed_id IN (" + strings.Join(ids, ",") + ")", nil, nil
+
+	case "feed_last_update_status":
+		if c.Value == "" {
+			return "1", nil, nil
+		}
+		return "(CASE WHEN COALESCE(f.last_error, '') != '' THEN 'failed' ELSE 'success' END) = ?",
+			[]interface{}{strings.ToLower(c.Value)}, nil
+	}
+	return "1", nil, nil
+}
+
+// textMatchExpr matches column against the condition's value: containing it,
+// equal to it ("exact"), both ignoring case, or matching it as a regular
+// expression ("regex")
+func textMatchExpr(column string, c FilterCondition) (string, []interface{}, error) {
+	if c.Value == "" {
+		return "1", nil, nil
+	}
+	switch c.Operator {
+	case "exact":
+		return "casefold(" + column + ") = ?", []interface{}{strings.ToLower(c.Value)}, nil
+	case "regex":
+		if _, err := regexp.Compile(c.Value); err != nil {
+			log.Printf("Invalid regex pattern: %v", err)
+			return "0", nil, nil
+		}
+		return column + " REGEXP ?", []interface{}{c.Value}, nil
+	}
+	return "instr(casefold(" + column + "), ?) > 0", []interface{}{strings.ToLower(c.Value)}, nil
+}
+
+// matchAnyExpr matches column containing any of values, ignoring case, or
+// value when no values are selected
+func matchAnyExpr(column string, values []string, value string) (string, []interface{}, error) {
+	if len(values) == 0 {
+		if value == "" {
+			return "1", nil, nil
+		}
+		values = []string{value}
+	}
+	clauses := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		clauses[i] = "instr(casefold(" + column + "), ?) > 0"
+		args[i] = strings.ToLower(v)
+	}
+	return strings.Join(clauses, " OR "), args, nil
+}
+
+// flagExpr matches articles for which expr is true when value is "true",
+// false otherwise
+func flagExpr(expr, value string) (string, []interface{}, error) {
+	if value == "" {
+		return "1", nil, nil
+	}
+	if value == "true" {
+		return expr, nil, nil
+	}
+	return "NOT (" + expr + ")", nil, nil
+}
+
+// filteredArticlesWhere returns the WHERE clauses selecting the articles
+// matching conditions
+func (db *DB) filteredArticlesWhere(conditions []FilterCondition, showHidden bool) ([]string, []interface{}, error) {
+	expr, args, err := db.compileFilter(conditions)
+	if err != nil {
+		return nil, nil, err
+	}
+	where := []string{expr}
+	if !showHidden {
+		where = append(where, "a.is_hidden = 0")
+	}
+	return where, args, nil
+}
+
+// GetFilteredArticles returns the articles of all feeds matching conditions,
+// newest first.
+func (db *DB) GetFilteredArticles(conditions []FilterCondition, showHidden bool, limit, offset int) ([]models.Article, error) {
+	db.WaitForReady()
+
+	where, args, err := db.filteredArticlesWhere(conditions, showHidden)
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT " + articleListColumns + articleListFrom + joinWhere(where) + " ORDER BY a.published_at DESC, a.id DESC LIMIT ? OFFSET ?"
+	rows, err := db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	articles := []models.Article{}
+	for rows.Next() {
+		a, err := scanArticleListRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+	}
+	return articles, rows.Err()
+}
+
+// CountFilteredArticles returns the number of articles matching conditions,
+// optionally only the unread ones.
+func (db *DB) CountFilteredArticles(conditions []FilterCondition, showHidden, onlyUnread bool) (int, error) {
+	db.WaitForReady()
+
+	where, args, err := db.filteredArticlesWhere(conditions, showHidden)
+	if err != nil {
+		return 0, err
+	}
+	if onlyUnread {
+		where = append(where, "a.is_read = 0")
+	}
+	var count int
+	err = db.QueryRow("SELECT COUNT(*)"+articleListFrom+joinWhere(where), args...).Scan(&count)
+	return count, err
+}
+
+// GetFilteredArticlesPage returns a page of the articles of all feeds
+// matching conditions, reading from cursor like GetArticlesPage.
+func (db *DB) GetFilteredArticlesPage(conditions []FilterCondition, showHidden bool, cursor string, limit int) (*ArticlePage, error) {
+	db.WaitForReady()
+
+	c, err := decodeArticleCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	where, args, err := db.filteredArticlesWhere(conditions, showHidden)
+	if err != nil {
+		return nil, err
+	}
+	return db.queryArticlePage(articleListFrom, where, args, c, limit)
+}
+
+// FilterArticleIDs returns the IDs among ids of the articles matching
+// conditions, in ascending order. With nil ids all articles are considered,
+// hidden ones included.
+func (db *DB) FilterArticleIDs(conditions []FilterCondition, ids []int64) ([]int64, error) {
+	db.WaitForReady()
+
+	expr, args, err := db.compileFilter(conditions)
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT a.id" + articleListFrom + " WHERE " + expr
+
+	if ids == nil {
+		return db.queryIDs(query+" ORDER BY a.id", args)
+	}
+
+	// Keep the number of bound variables well below SQLite's limit
+	const batchSize = 500
+	matched := []int64{}
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:min(start+batchSize, len(ids))]
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")
+		batchArgs := append([]interface{}{}, args...)
+		for _, id := range batch {
+			batchArgs = append(batchArgs, id)
+		}
+		got, err := db.queryIDs(query+" AND a.id IN ("+placeholders+") ORDER BY a.id", batchArgs)
+		if err != nil {
+			return nil, err
+		}
+		matched = append(matched, got...)
+	}
+	slices.Sort(matched)
+	return matched, nil
+}
+
+func (db *DB) queryIDs(query string, args []interface{}) ([]int64, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
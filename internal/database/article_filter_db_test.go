@@ -0,0 +1,128 @@
+package database_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	dbpkg "MrRSS/internal/database"
+	"MrRSS/internal/models"
+)
+
+func TestFilterArticleIDsCompilesConditions(t *testing.T) {
+	db := setupDBWithFeed(t)
+
+	var newsID int64
+	if err := db.QueryRow(`SELECT id FROM feeds WHERE url = ?`, "https://example.com/feed").Scan(&newsID); err != nil {
+		t.Fatalf("scan feed id: %v", err)
+	}
+	res, err := db.Exec(`INSERT INTO feeds (title, url, category) VALUES ('Hub', 'rsshub://github/trending', 'dev')`)
+	if err != nil {
+		t.Fatalf("insert feed: %v", err)
+	}
+	hubID, _ := res.LastInsertId()
+	tagID, err := db.AddTag(&models.Tag{Name: "Tech", Color: "#000000"})
+	if err != nil {
+		t.Fatalf("AddTag: %v", err)
+	}
+	if err := db.SetFeedTags(hubID, []int64{tagID}); err != nil {
+		t.Fatalf("SetFeedTags: %v", err)
+	}
+
+	// Publish times are stored in different zones
+	cst := time.FixedZone("CST", 8*3600)
+	ids := map[string]int64{}
+	insert := func(key string, feedID int64, title, author string, publishedAt time.Time, isRead bool) {
+		t.Helper()
+		res, err := db.Exec(
+			`INSERT INTO articles (feed_id, title, url, author, published_at, is_read, unique_id) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			feedID, title, "https://example.com/"+key, author, publishedAt, isRead, key,
+		)
+		if err != nil {
+			t.Fatalf("insert article %s: %v", key, err)
+		}
+		ids[key], _ = res.LastInsertId()
+	}
+	insert("go", newsID, "Go 1.30 released", "Alice", time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), false)
+	insert("rust", newsID, "Rust ÜBER alles", "Bob", time.Date(2026, 3, 2, 1, 0, 0, 0, cst), true)
+	insert("trend", hubID, "Trending repos", "", time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC), false)
+	if err := db.SetArticleContent(ids["trend"], "<p>Weekly golang digest</p>"); err != nil {
+		t.Fatalf("SetArticleContent: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		conditions []dbpkg.FilterCondition
+		want       []string
+	}{
+		{"no conditions", nil, []string{"go", "rust", "trend"}},
+		{"contains ignores case beyond ASCII", []dbpkg.FilterCondition{
+			{Field: "article_title", Value: "über"},
+		}, []string{"rust"}},
+		{"exact", []dbpkg.FilterCondition{
+			{Field: "author", Operator: "exact", Value: "alice"},
+		}, []string{"go"}},
+		{"regex", []dbpkg.FilterCondition{
+			{Field: "article_title", Operator: "regex", Value: `^(Go|Rust)\b`},
+		}, []string{"go", "rust"}},
+		{"invalid regex matches nothing", []dbpkg.FilterCondition{
+			{Field: "article_title", Operator: "regex", Value: `(`},
+		}, nil},
+		{"AND binds tighter than OR", []dbpkg.FilterCondition{
+			{Field: "article_title", Value: "trending"},
+			{Logic: "or", Field: "feed_name", Values: []string{"test"}},
+			{Logic: "and", Field: "is_read", Value: "true"},
+		}, []string{"rust", "trend"}},
+		{"NOT applies to one condition", []dbpkg.FilterCondition{
+			{Field: "feed_category", Values: []string{"news"}},
+			{Logic: "and", Negate: true, Field: "is_read", Value: "true"},
+		}, []string{"go"}},
+		{"feed type and tags", []dbpkg.FilterCondition{
+			{Field: "feed_type", Values: []string{"rsshub"}},
+			{Logic: "and", Field: "feed_tags", Values: []string{"tech"}},
+		}, []string{"trend"}},
+		{"cached content only", []dbpkg.FilterCondition{
+			{Field: "article_content", Value: "GOLANG"},
+		}, []string{"trend"}},
+		{"negated content includes uncached articles", []dbpkg.FilterCondition{
+			{Negate: true, Field: "article_content", Value: "golang"},
+		}, []string{"go", "rust"}},
+		{"published before is inclusive in UTC", []dbpkg.FilterCondition{
+			{Field: "published_before", Value: "2026-03-01"},
+		}, []string{"go", "rust"}},
+		{"published after", []dbpkg.FilterCondition{
+			{Field: "published_after", Value: "2026-03-02"},
+		}, []string{"trend"}},
+		{"unknown fields match", []dbpkg.FilterCondition{
+			{Field: "no_such_field", Value: "x"},
+		}, []string{"go", "rust", "trend"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.FilterArticleIDs(tt.conditions, nil)
+			if err != nil {
+				t.Fatalf("FilterArticleIDs: %v", err)
+			}
+			var want []int64
+			for _, key := range tt.want {
+				want = append(want, ids[key])
+			}
+			slices.Sort(want)
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("got %v, want %v (%v)", got, want, tt.want)
+			}
+		})
+	}
+
+	// Limited to the given articles
+	got, err := db.FilterArticleIDs([]dbpkg.FilterCondition{{Field: "feed_name", Value: "test"}}, []int64{ids["rust"], ids["trend"]})
+	if err != nil || fmt.Sprint(got) != fmt.Sprint([]int64{ids["rust"]}) {
+		t.Errorf("FilterArticleIDs with ids = %v, %v", got, err)
+	}
+
+	unread, err := db.CountFilteredArticles([]dbpkg.FilterCondition{{Field: "feed_category", Value: "news"}}, false, true)
+	if err != nil || unread != 1 {
+		t.Errorf("CountFilteredArticles unread = %d, %v, want 1", unread, err)
+	}
+}
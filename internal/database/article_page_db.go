@@ -0,0 +1,187 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"MrRSS/internal/models"
+)
+
+// articleListColumns are the article columns shown in article lists, read by
+// scanArticleListRow
+const articleListColumns = `a.id, a.feed_id, a.title, a.url, a.image_url, a.audio_url, a.video_url, a.published_at, a.is_read, a.is_favorite, a.is_hidden, a.is_read_later, a.translated_title, a.summary, a.freshrss_item_id, f.title, a.author`
+
+const articleListFrom = `
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id`
+
+// ErrInvalidCursor is returned for a pagination cursor that was not issued
+// by an article page
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ArticlePage is one page of an article list paged by cursor. NextCursor
+// continues with older articles and PrevCursor with newer ones; each is empty
+// when there is nothing more in that direction.
+type ArticlePage struct {
+	Articles   []models.Article `json:"articles"`
+	NextCursor string           `json:"next_cursor"`
+	PrevCursor string           `json:"prev_cursor"`
+}
+
+// articleCursor is a position in an article list, which is ordered newest
+// first by (published_at, id). published_at is kept as stored so that it
+// compares exactly like the ORDER BY, whatever time zone it was written in.
+type articleCursor struct {
+	PublishedAt string `json:"p"`
+	ID          int64  `json:"i"`
+	// Before selects the articles before the position (newer) instead of
+	// after it
+	Before bool `json:"b,omitempty"`
+}
+
+func (c articleCursor) isSet() bool {
+	return c.ID != 0
+}
+
+func (c articleCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeArticleCursor parses a cursor from ArticlePage. The empty cursor is
+// the start of the list.
+func decodeArticleCursor(s string) (articleCursor, error) {
+	var c articleCursor
+	if s == "" {
+		return c, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &c) != nil || c.ID <= 0 {
+		return articleCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// queryArticlePage returns the page of up to limit articles next to cursor c
+// among those selected by where
+func (db *DB) queryArticlePage(from string, where []string, args []interface{}, c articleCursor, limit int) (*ArticlePage, error) {
+	articles, keys, err := db.queryArticleKeys(from, where, args, c, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	more := len(articles) > limit
+	if more {
+		articles, keys = articles[:limit], keys[:limit]
+	}
+	return newArticlePage(c, articles, keys, more), nil
+}
+
+// queryArticleKeys returns up to limit articles after c in the direction of
+// c, with the stored published_at of each. Articles without a publish date
+// sort last and are only on the first page; the schema baseline fills it in
+// and articles are always saved with one.
+func (db *DB) queryArticleKeys(from string, where []string, args []interface{}, c articleCursor, limit int) ([]models.Article, []string, error) {
+	order := "DESC"
+	if c.isSet() {
+		op := "<"
+		if c.Before {
+			op, order = ">", "ASC"
+		}
+		where = append(slices.Clip(where), "(a.published_at, a.id) "+op+" (?, ?)")
+		args = append(slices.Clip(args), c.PublishedAt, c.ID)
+	}
+	query := "SELECT " + articleListColumns + ", COALESCE(CAST(a.published_at AS TEXT), '')" + from + joinWhere(where) +
+		" ORDER BY a.published_at " + order + ", a.id " + order + " LIMIT ?"
+	args = append(slices.Clip(args), limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var articles []models.Article
+	var keys []string
+	for rows.Next() {
+		var key string
+		a, err := scanArticleListRow(rows, &key)
+		if err != nil {
+			return nil, nil, err
+		}
+		articles = append(articles, a)
+		keys = append(keys, key)
+	}
+	return articles, keys, rows.Err()
+}
+
+// newArticlePage builds the page for articles read from cursor c, in the
+// order they were read. more reports that further articles follow in the
+// direction of c.
+func newArticlePage(c articleCursor, articles []models.Article, keys []string, more bool) *ArticlePage {
+	if c.Before {
+		slices.Reverse(articles)
+		slices.Reverse(keys)
+	}
+	page := &ArticlePage{Articles: articles}
+	if page.Articles == nil {
+		page.Articles = []models.Article{}
+	}
+
+	// Reading backwards, the cursor's own article is always older than the page
+	hasNext := more || c.Before
+	hasPrev := (c.isSet() && !c.Before) || (c.Before && more)
+
+	if hasNext {
+		next := articleCursor{PublishedAt: c.PublishedAt, ID: c.ID}
+		if n := len(articles); n > 0 {
+			next = articleCursor{PublishedAt: keys[n-1], ID: articles[n-1].ID}
+		}
+		page.NextCursor = next.encode()
+	}
+	if hasPrev {
+		prev := articleCursor{PublishedAt: c.PublishedAt, ID: c.ID, Before: true}
+		if len(articles) > 0 {
+			prev = articleCursor{PublishedAt: keys[0], ID: articles[0].ID, Before: true}
+		}
+		page.PrevCursor = prev.encode()
+	}
+	return page
+}
+
+// scanArticleListRow scans a row selecting articleListColumns, followed by
+// extra destinations
+func scanArticleListRow(rows *sql.Rows, extra ...interface{}) (models.Article, error) {
+	var a models.Article
+	var imageURL, audioURL, videoURL, translatedTitle, summary, freshrssItemID, author sql.NullString
+	var publishedAt sql.NullTime
+	dest := []interface{}{&a.ID, &a.FeedID, &a.Title, &a.URL, &imageURL, &audioURL, &videoURL, &publishedAt, &a.IsRead, &a.IsFavorite, &a.IsHidden, &a.IsReadLater, &translatedTitle, &summary, &freshrssItemID, &a.FeedTitle, &author}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return a, err
+	}
+	a.ImageURL = imageURL.String
+	a.AudioURL = audioURL.String
+	a.VideoURL = videoURL.String
+	if publishedAt.Valid {
+		a.PublishedAt = publishedAt.Time
+	} else {
+		a.PublishedAt = time.Time{}
+	}
+	a.TranslatedTitle = translatedTitle.String
+	a.Summary = summary.String
+	a.FreshRSSItemID = freshrssItemID.String
+	a.Author = author.String
+	return a, nil
+}
+
+// joinWhere returns a WHERE clause joining clauses with AND
+func joinWhere(clauses []string) string {
+	if len(clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(clauses, " AND ")
+}
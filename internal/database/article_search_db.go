@@ -16,73 +16,77 @@ import (
 // If onlyUnread is true, only returns unread articles.
 func (db *DB) GetImageGalleryArticles(feedID int64, category string, showHidden bool, onlyUnread bool, limit, offset int) ([]models.Article, error) {
 	db.WaitForReady()
-	baseQuery := `
-		SELECT a.id, a.feed_id, a.title, a.url, a.image_url, a.audio_url, a.video_url, a.published_at, a.is_read, a.is_favorite, a.is_hidden, a.is_read_later, a.translated_title, a.summary, f.title, a.author
-		FROM articles a
-		JOIN feeds f ON a.feed_id = f.id
-		WHERE COALESCE(f.is_image_mode, 0) = 1
-	`
+
+	where, args := imageGalleryWhere(feedID, category, showHidden, onlyUnread)
+	query := "SELECT " + articleListColumns + articleListFrom + joinWhere(where) + " ORDER BY a.published_at DESC, a.id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	articles := make([]models.Article, 0)
+	for rows.Next() {
+		a, err := scanArticleListRow(rows)
+		if err != nil {
+			log.Println("Error scanning article:", err)
+			continue
+		}
+		articles = append(articles, a)
+	}
+	return articles, nil
+}
+
+// GetImageGalleryArticlesPage returns the articles of GetImageGalleryArticles
+// one page at a time from an opaque cursor. An empty cursor starts at the
+// newest article.
+func (db *DB) GetImageGalleryArticlesPage(feedID int64, category string, showHidden bool, onlyUnread bool, cursor string, limit int) (*ArticlePage, error) {
+	db.WaitForReady()
+
+	c, err := decodeArticleCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	where, args := imageGalleryWhere(feedID, category, showHidden, onlyUnread)
+	return db.queryArticlePage(articleListFrom, where, args, c, limit)
+}
+
+// imageGalleryWhere returns the WHERE clauses and arguments selecting the
+// articles with images of image mode feeds
+func imageGalleryWhere(feedID int64, category string, showHidden bool, onlyUnread bool) ([]string, []interface{}) {
+	where := []string{"COALESCE(f.is_image_mode, 0) = 1"}
 	var args []interface{}
 
 	// Always filter hidden articles unless showHidden is true
 	if !showHidden {
-		baseQuery += " AND a.is_hidden = 0"
+		where = append(where, "a.is_hidden = 0")
 	}
 
 	// Only get articles with image_url
-	baseQuery += " AND a.image_url IS NOT NULL AND a.image_url != ''"
+	where = append(where, "a.image_url IS NOT NULL AND a.image_url != ''")
 
 	// Filter for only unread articles if requested
 	if onlyUnread {
-		baseQuery += " AND a.is_read = 0"
+		where = append(where, "a.is_read = 0")
 	}
 
 	if feedID > 0 {
-		baseQuery += " AND a.feed_id = ?"
+		where = append(where, "a.feed_id = ?")
 		args = append(args, feedID)
 	} else if category == "\x00" {
 		// Special value "\x00" means explicit uncategorized filtering
-		baseQuery += " AND (f.category IS NULL OR f.category = '')"
+		where = append(where, "(f.category IS NULL OR f.category = '')")
 	} else if category != "" {
 		// For categories, use prefix match to support nested categories
-		baseQuery += " AND (f.category = ? OR f.category LIKE ?)"
+		where = append(where, "(f.category = ? OR f.category LIKE ?)")
 		args = append(args, category, category+"/%")
 	}
 	// Note: When category is empty string, it means no category filter was provided,
 	// so we should not filter by category at all (show all image mode articles from all categories).
 
-	baseQuery += " ORDER BY a.published_at DESC LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
-
-	rows, err := db.Query(baseQuery, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	articles := make([]models.Article, 0)
-	for rows.Next() {
-		var a models.Article
-		var imageURL, audioURL, videoURL, translatedTitle, summary, author sql.NullString
-		var publishedAt sql.NullTime
-		if err := rows.Scan(&a.ID, &a.FeedID, &a.Title, &a.URL, &imageURL, &audioURL, &videoURL, &publishedAt, &a.IsRead, &a.IsFavorite, &a.IsHidden, &a.IsReadLater, &translatedTitle, &summary, &a.FeedTitle, &author); err != nil {
-			log.Println("Error scanning article:", err)
-			continue
-		}
-		a.ImageURL = imageURL.String
-		a.AudioURL = audioURL.String
-		a.VideoURL = videoURL.String
-		if publishedAt.Valid {
-			a.PublishedAt = publishedAt.Time
-		} else {
-			a.PublishedAt = time.Time{}
-		}
-		a.TranslatedTitle = translatedTitle.String
-		a.Summary = summary.String
-		a.Author = author.String
-		articles = append(articles, a)
-	}
-	return articles, nil
+	return where, args
 }
 
 // SearchArticlesWithAI executes a search query with an AI-generated WHERE clause.
@@ -0,0 +1,325 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"MrRSS/internal/models"
+)
+
+// GetAllSettingValues returns every stored setting as stored, so encrypted
+// settings are returned in their encrypted form.
+func (db *DB) GetAllSettingValues() (map[string]string, error) {
+	db.WaitForReady()
+
+	rows, err := db.Query(`SELECT key, value FROM settings`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var key string
+		var value sql.NullString
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		settings[key] = value.String
+	}
+	return settings, rows.Err()
+}
+
+// GetArticlesForBackup returns the articles of local feeds that carry user
+// state: read, favorite, hidden or read-later flags, or chat sessions.
+// With includeCached, articles with cached content are returned as well.
+func (db *DB) GetArticlesForBackup(includeCached bool) ([]models.Article, error) {
+	db.WaitForReady()
+
+	cached := "0"
+	if includeCached {
+		cached = "EXISTS (SELECT 1 FROM article_contents ac WHERE ac.article_id = a.id)"
+	}
+
+	rows, err := db.Query(`
+		SELECT a.id, a.feed_id, COALESCE(a.title, ''), COALESCE(a.url, ''), COALESCE(a.image_url, ''),
+			COALESCE(a.audio_url, ''), COALESCE(a.video_url, ''), a.published_at,
+			COALESCE(a.is_read, 0), COALESCE(a.is_favorite, 0), COALESCE(a.is_hidden, 0), COALESCE(a.is_read_later, 0),
+			COALESCE(a.author, ''), COALESCE(a.translated_title, ''), COALESCE(a.summary, ''),
+			COALESCE(a.original_summary, ''), COALESCE(a.unique_id, '')
+		FROM articles a
+		JOIN feeds f ON f.id = a.feed_id
+		WHERE COALESCE(f.is_freshrss_source, 0) = 0 AND (
+			a.is_read = 1 OR a.is_favorite = 1 OR a.is_hidden = 1 OR a.is_read_later = 1
+			OR EXISTS (SELECT 1 FROM chat_sessions cs WHERE cs.article_id = a.id)
+			OR ` + cached + `
+		)
+		ORDER BY a.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	articles := make([]models.Article, 0)
+	for rows.Next() {
+		var a models.Article
+		var publishedAt sql.NullTime
+		if err := rows.Scan(&a.ID, &a.FeedID, &a.Title, &a.URL, &a.ImageURL,
+			&a.AudioURL, &a.VideoURL, &publishedAt,
+			&a.IsRead, &a.IsFavorite, &a.IsHidden, &a.IsReadLater,
+			&a.Author, &a.TranslatedTitle, &a.Summary,
+			&a.OriginalSummary, &a.UniqueID); err != nil {
+			return nil, err
+		}
+		if publishedAt.Valid {
+			a.PublishedAt = publishedAt.Time
+		}
+		articles = append(articles, a)
+	}
+	return articles, rows.Err()
+}
+
+// GetAllArticleContents returns the cached content of all articles, keyed by article ID
+func (db *DB) GetAllArticleContents() (map[int64]string, error) {
+	db.WaitForReady()
+
+	rows, err := db.Query(`SELECT article_id, content FROM article_contents`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contents := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var content string
+		if err := rows.Scan(&id, &content); err != nil {
+			return nil, err
+		}
+		contents[id] = content
+	}
+	return contents, rows.Err()
+}
+
+// GetAllChatSessions returns all chat sessions, oldest first
+func (db *DB) GetAllChatSessions() ([]ChatSession, error) {
+	db.WaitForReady()
+
+	rows, err := db.Query(`
+		SELECT id, article_id, title, created_at, updated_at,
+		       (SELECT COUNT(*) FROM chat_messages WHERE session_id = chat_sessions.id) as message_count
+		FROM chat_sessions
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]ChatSession, 0)
+	for rows.Next() {
+		var session ChatSession
+		if err := rows.Scan(
+			&session.ID, &session.ArticleID, &session.Title,
+			&session.CreatedAt, &session.UpdatedAt, &session.MessageCount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan chat session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// RestoreArticle stores a backed-up article and returns its ID. An existing
+// article is matched by unique ID, or by feed and URL; its read, favorite,
+// hidden and read-later flags are combined with the backed-up ones.
+// article.UniqueID must already be computed for the article's feed ID.
+func (db *DB) RestoreArticle(article *models.Article) (int64, error) {
+	db.WaitForReady()
+
+	var id int64
+	err := db.QueryRow(`
+		SELECT id FROM articles
+		WHERE unique_id = ? OR (feed_id = ? AND url = ? AND url != '')
+		LIMIT 1
+	`, article.UniqueID, article.FeedID, article.URL).Scan(&id)
+
+	switch {
+	case err == sql.ErrNoRows:
+		result, err := db.Exec(`
+			INSERT INTO articles (feed_id, title, url, image_url, audio_url, video_url, published_at,
+				translated_title, is_read, is_favorite, is_hidden, is_read_later,
+				summary, original_summary, unique_id, author)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, article.FeedID, article.Title, article.URL, article.ImageURL, article.AudioURL, article.VideoURL,
+			article.PublishedAt, article.TranslatedTitle, article.IsRead, article.IsFavorite, article.IsHidden,
+			article.IsReadLater, article.Summary, article.OriginalSummary, article.UniqueID, article.Author)
+		if err != nil {
+			return 0, err
+		}
+		return result.LastInsertId()
+	case err != nil:
+		return 0, err
+	}
+
+	_, err = db.Exec(`
+		UPDATE articles SET
+			is_read = (is_read OR ?),
+			is_favorite = (is_favorite OR ?),
+			is_hidden = (is_hidden OR ?),
+			is_read_later = (is_read_later OR ?),
+			summary = CASE WHEN COALESCE(summary, '') = '' THEN ? ELSE summary END,
+			translated_title = CASE WHEN COALESCE(translated_title, '') = '' THEN ? ELSE translated_title END
+		WHERE id = ?
+	`, article.IsRead, article.IsFavorite, article.IsHidden, article.IsReadLater,
+		article.Summary, article.TranslatedTitle, id)
+	return id, err
+}
+
+// RestoreChatSession inserts a backed-up chat session, keeping its timestamps
+func (db *DB) RestoreChatSession(session *ChatSession) (int64, error) {
+	db.WaitForReady()
+
+	result, err := db.Exec(
+		`INSERT INTO chat_sessions (article_id, title, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+		session.ArticleID, session.Title, session.CreatedAt, session.UpdatedAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore chat session: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// RestoreChatMessage inserts a backed-up chat message, keeping its timestamp
+func (db *DB) RestoreChatMessage(message *ChatMessage) error {
+	db.WaitForReady()
+
+	_, err := db.Exec(
+		`INSERT INTO chat_messages (session_id, role, content, thinking, created_at) VALUES (?, ?, ?, ?, ?)`,
+		message.SessionID, message.Role, message.Content, message.Thinking, message.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore chat message: %w", err)
+	}
+	return nil
+}
+
+// RestoreStat stores a backed-up statistics counter. When the day already
+// has a counter the higher count is kept, so restoring twice never double counts.
+func (db *DB) RestoreStat(stat StatRecord) error {
+	db.WaitForReady()
+
+	_, err := db.Exec(`
+		INSERT INTO statistics (event_date, event_type, count)
+		VALUES (?, ?, ?)
+		ON CONFLICT(event_date, event_type) DO UPDATE SET
+			count = MAX(count, excluded.count)
+	`, stat.EventDate, stat.EventType, stat.Count)
+	return err
+}
+
+// userDataTables are the tables ClearUserData empties, children before their parents
+var userDataTables = []string{
+	"chat_messages",
+	"chat_sessions",
+	"article_contents",
+	"article_fulltexts",
+	"feed_tags",
+	"feed_extraction_rules",
+	"retention_policies",
+	"article_archives",
+	"subscription_list_log",
+	"subscription_list_exclusions",
+	"subscription_list_feeds",
+	"subscription_lists",
+	"feed_fetch_history",
+	"freshrss_sync_queue",
+	"articles",
+	"feeds",
+	"tags",
+	"saved_filters",
+	"ai_profiles",
+	"statistics",
+}
+
+// ClearUserData deletes feeds, articles, tags, saved filters, AI profiles,
+// chat history, statistics, subscription lists and retention policies. It is used before a
+// restore that replaces the current data. Settings are left in place.
+func (db *DB) ClearUserData() error {
+	db.WaitForReady()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range userDataTables {
+		if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RestoreUserData puts back the user data and settings of a copy written by
+// VacuumInto, in a single transaction. It undoes a restore that failed after
+// ClearUserData.
+func (db *DB) RestoreUserData(path string) error {
+	db.WaitForReady()
+
+	ctx := context.Background()
+	// ATTACH is not allowed inside a transaction, so the write connection is
+	// held for the whole restore
+	conn, err := db.writePool.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `ATTACH DATABASE ? AS restore_src`, path); err != nil {
+		return fmt.Errorf("failed to attach %s: %w", path, err)
+	}
+	defer conn.ExecContext(ctx, `DETACH DATABASE restore_src`)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tables := append([]string{"settings"}, userDataTables...)
+	for _, table := range tables {
+		if _, err := tx.Exec("DELETE FROM main." + table); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	for i := len(tables) - 1; i >= 0; i-- {
+		table := tables[i]
+		if _, err := tx.Exec("INSERT INTO main." + table + " SELECT * FROM restore_src." + table); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", table, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// VacuumInto writes a compacted copy of the database to path, which must not
+// exist yet. The copy is consistent even while other connections write. It
+// only reads the database, so it runs on a read connection and does not
+// hold up writes.
+func (db *DB) VacuumInto(path string) error {
+	db.WaitForReady()
+
+	ctx := context.Background()
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, `VACUUM INTO ?`, path)
+	return err
+}
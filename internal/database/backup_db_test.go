@@ -0,0 +1,49 @@
+package database_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"MrRSS/internal/models"
+)
+
+func TestRestoreUserDataUndoesClear(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	feedID, err := db.AddFeed(&models.Feed{Title: "Kept", URL: "https://kept.example.com/feed"})
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+	if err := db.SetSetting("language", "de"); err != nil {
+		t.Fatalf("SetSetting() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "undo.db")
+	if err := db.VacuumInto(path); err != nil {
+		t.Fatalf("VacuumInto() error = %v", err)
+	}
+	if err := db.ClearUserData(); err != nil {
+		t.Fatalf("ClearUserData() error = %v", err)
+	}
+	if err := db.SetSetting("language", "en"); err != nil {
+		t.Fatalf("SetSetting() error = %v", err)
+	}
+	if _, err := db.AddFeed(&models.Feed{Title: "Partial", URL: "https://partial.example.com/feed"}); err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+
+	if err := db.RestoreUserData(path); err != nil {
+		t.Fatalf("RestoreUserData() error = %v", err)
+	}
+	feeds, err := db.GetFeeds()
+	if err != nil {
+		t.Fatalf("GetFeeds() error = %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].ID != feedID {
+		t.Errorf("feeds after restore = %+v, want only feed %d", feeds, feedID)
+	}
+	if got, _ := db.GetSetting("language"); got != "de" {
+		t.Errorf("language after restore = %q, want %q", got, "de")
+	}
+}
@@ -8,10 +8,12 @@ import (
 
 const defaultMaxArticlesPerFeed = 15000
 
-// CleanupOldArticles removes articles based on age and status.
-// - Articles older than configured days: delete except favorited or read later
+// CleanupOldArticles removes articles based on age and status, moving them to
+// cold storage when it is enabled.
+// - Articles older than configured days: delete except favorited, read later or archived
 // - Read article metadata beyond the per-feed retention limit
 // - Also checks database size against max_cache_size_mb setting
+// Feeds governed by a retention policy are left to ApplyRetentionPolicies.
 func (db *DB) CleanupOldArticles() (int64, error) {
 	db.WaitForReady()
 
@@ -28,18 +30,18 @@ func (db *DB) CleanupOldArticles() (int64, error) {
 
 	cutoffDate := time.Now().AddDate(0, 0, -maxAgeDays)
 
-	// Delete articles older than configured age that are not favorited or in read later
-	result, err := db.Exec(`
-		DELETE FROM articles
+	// Remove articles older than configured age that are not favorited or in read later
+	count, err := db.removeArticles(`
+		SELECT id FROM articles
 		WHERE published_at < ?
 		AND is_favorite = 0
 		AND is_read_later = 0
+		AND id NOT IN (SELECT article_id FROM article_archives)
+		AND feed_id NOT IN (SELECT feed_id FROM retention_policy_feeds)
 	`, cutoffDate)
 	if err != nil {
 		return 0, err
 	}
-
-	count, _ := result.RowsAffected()
 	totalDeleted += count
 
 	// Step 2: Apply per-feed article retention so high-volume feeds do not
@@ -71,9 +73,12 @@ func (db *DB) CleanupOldArticles() (int64, error) {
 	return totalDeleted, nil
 }
 
-// CleanupAllArticleContents removes all cached article contents
+// CleanupAllArticleContents removes all cached article contents and full texts
 func (db *DB) CleanupAllArticleContents() (int64, error) {
 	db.WaitForReady()
+	if _, err := db.Exec(`DELETE FROM article_fulltexts`); err != nil {
+		return 0, err
+	}
 	result, err := db.Exec(`DELETE FROM article_contents`)
 	if err != nil {
 		return 0, err
@@ -100,7 +105,7 @@ func (db *DB) DeleteAllArticles() (int64, error) {
 	return count, nil
 }
 
-// CleanupUnimportantArticles removes all articles except read, favorited, and read later ones.
+// CleanupUnimportantArticles removes all articles except read, favorited, read later and archived ones.
 func (db *DB) CleanupUnimportantArticles() (int64, error) {
 	db.WaitForReady()
 
@@ -109,6 +114,7 @@ func (db *DB) CleanupUnimportantArticles() (int64, error) {
 		WHERE is_read = 0
 		AND is_favorite = 0
 		AND is_read_later = 0
+		AND id NOT IN (SELECT article_id FROM article_archives)
 	`)
 	if err != nil {
 		return 0, err
@@ -129,8 +135,9 @@ func (db *DB) CleanupUnimportantArticles() (int64, error) {
 }
 
 // CleanupReadArticlesOverPerFeedLimit removes old read articles above the
-// per-feed retention limit while preserving favorites, read-later items, and
-// unread metadata. Protected articles may cause a feed to exceed the limit.
+// per-feed retention limit while preserving favorites, read-later items,
+// archived articles and unread metadata. Protected articles may cause a feed
+// to exceed the limit.
 func (db *DB) CleanupReadArticlesOverPerFeedLimit(maxArticlesPerFeed int) (int64, error) {
 	db.WaitForReady()
 
@@ -138,7 +145,7 @@ func (db *DB) CleanupReadArticlesOverPerFeedLimit(maxArticlesPerFeed int) (int64
 		return 0, nil
 	}
 
-	result, err := db.Exec(`
+	count, err := db.removeArticles(`
 		WITH ranked_articles AS (
 			SELECT
 				id,
@@ -148,22 +155,20 @@ func (db *DB) CleanupReadArticlesOverPerFeedLimit(maxArticlesPerFeed int) (int64
 				) AS feed_rank
 			FROM articles
 		)
-		DELETE FROM articles
-		WHERE id IN (
-			SELECT articles.id
-			FROM articles
-			JOIN ranked_articles ON ranked_articles.id = articles.id
-			WHERE ranked_articles.feed_rank > ?
-			AND articles.is_read = 1
-			AND articles.is_favorite = 0
-			AND articles.is_read_later = 0
-		)
+		SELECT articles.id
+		FROM articles
+		JOIN ranked_articles ON ranked_articles.id = articles.id
+		WHERE ranked_articles.feed_rank > ?
+		AND articles.is_read = 1
+		AND articles.is_favorite = 0
+		AND articles.is_read_later = 0
+		AND articles.id NOT IN (SELECT article_id FROM article_archives)
+		AND articles.feed_id NOT IN (SELECT feed_id FROM retention_policy_feeds)
 	`, maxArticlesPerFeed)
 	if err != nil {
 		return 0, err
 	}
 
-	count, _ := result.RowsAffected()
 	if count > 0 {
 		_, _ = db.IncrementalVacuum()
 	}
@@ -298,6 +303,7 @@ func (db *DB) ShouldCleanupBeforeSave() (bool, error) {
 }
 
 // CleanupBySize reduces cached content first to keep database under max_cache_size_mb.
+// Articles it removes go to cold storage when it is enabled.
 // Article metadata is preserved whenever possible so refreshed feeds do not
 // reinsert old read items as new articles after cleanup.
 func (db *DB) CleanupBySize() (int64, error) {
@@ -365,24 +371,22 @@ func (db *DB) CleanupBySize() (int64, error) {
 		}
 	}
 
-	// Step 3: If per-feed retention is not enough, delete oldest read article metadata.
+	// Step 3: If per-feed retention is not enough, remove oldest read article metadata.
 	for currentSizeMB > targetSizeMB {
-		result, err := db.Exec(`
-			DELETE FROM articles
-			WHERE id IN (
-				SELECT id FROM articles
-				WHERE is_read = 1
-				AND is_favorite = 0
-				AND is_read_later = 0
-				ORDER BY published_at ASC
-				LIMIT 100
-			)
+		count, err := db.removeArticles(`
+			SELECT id FROM articles
+			WHERE is_read = 1
+			AND is_favorite = 0
+			AND is_read_later = 0
+			AND id NOT IN (SELECT article_id FROM article_archives)
+			AND feed_id NOT IN (SELECT feed_id FROM retention_policy_feeds)
+			ORDER BY published_at ASC
+			LIMIT 100
 		`)
 		if err != nil {
 			break
 		}
 
-		count, _ := result.RowsAffected()
 		if count == 0 {
 			break // No more read articles to delete
 		}
@@ -407,10 +411,17 @@ func (db *DB) CleanupBySize() (int64, error) {
 	return totalDeleted, nil
 }
 
-// CleanupArticleContentsByAge removes article content cache entries older than maxAgeDays
+// CleanupArticleContentsByAge removes article content cache entries and full
+// texts older than maxAgeDays
 // This only deletes content, not article metadata
 func (db *DB) CleanupArticleContentsByAge(maxAgeDays int) (int64, error) {
 	db.WaitForReady()
+	if _, err := db.Exec(
+		`DELETE FROM article_fulltexts WHERE fetched_at < datetime('now', '-' || ? || ' days')`,
+		maxAgeDays,
+	); err != nil {
+		return 0, err
+	}
 	result, err := db.Exec(
 		`DELETE FROM article_contents WHERE fetched_at < datetime('now', '-' || ? || ' days')`,
 		maxAgeDays,
@@ -479,11 +490,13 @@ func (db *DB) CleanupArticleContentsBySize() (int64, error) {
 	return totalDeleted, nil
 }
 
-// CleanupOldArticlesLayered removes articles in layers:
+// CleanupOldArticlesLayered removes articles in layers, moving them to cold
+// storage when it is enabled:
 // Layer 1: Read articles older than 30 days (not favorited/read later)
 // Layer 2: Read articles older than 14 days (not favorited/read later)
 // Layer 3: Unread articles older than 90 days (not favorited/read later)
 // Layer 4: Unread articles older than 60 days (not favorited/read later)
+// Feeds governed by a retention policy are skipped.
 func (db *DB) CleanupOldArticlesLayered() (int64, error) {
 	db.WaitForReady()
 
@@ -500,15 +513,16 @@ func (db *DB) CleanupOldArticlesLayered() (int64, error) {
 
 	// Layer 1: Delete very old read articles (maxAgeDays)
 	cutoffDate := time.Now().AddDate(0, 0, -maxAgeDays)
-	result, err := db.Exec(`
-		DELETE FROM articles
+	count, err := db.removeArticles(`
+		SELECT id FROM articles
 		WHERE published_at < ?
 		AND is_read = 1
 		AND is_favorite = 0
 		AND is_read_later = 0
+		AND id NOT IN (SELECT article_id FROM article_archives)
+		AND feed_id NOT IN (SELECT feed_id FROM retention_policy_feeds)
 	`, cutoffDate)
 	if err == nil {
-		count, _ := result.RowsAffected()
 		totalDeleted += count
 		if count > 0 {
 			log.Printf("Layer 1: Deleted %d read articles older than %d days", count, maxAgeDays)
@@ -517,15 +531,16 @@ func (db *DB) CleanupOldArticlesLayered() (int64, error) {
 
 	// Layer 2: Delete old read articles (14 days)
 	cutoffDate = time.Now().AddDate(0, 0, -14)
-	result, err = db.Exec(`
-		DELETE FROM articles
+	count, err = db.removeArticles(`
+		SELECT id FROM articles
 		WHERE published_at < ?
 		AND is_read = 1
 		AND is_favorite = 0
 		AND is_read_later = 0
+		AND id NOT IN (SELECT article_id FROM article_archives)
+		AND feed_id NOT IN (SELECT feed_id FROM retention_policy_feeds)
 	`, cutoffDate)
 	if err == nil {
-		count, _ := result.RowsAffected()
 		totalDeleted += count
 		if count > 0 {
 			log.Printf("Layer 2: Deleted %d read articles older than 14 days", count)
@@ -534,15 +549,16 @@ func (db *DB) CleanupOldArticlesLayered() (int64, error) {
 
 	// Layer 3: Delete very old unread articles (90 days)
 	cutoffDate = time.Now().AddDate(0, 0, -90)
-	result, err = db.Exec(`
-		DELETE FROM articles
+	count, err = db.removeArticles(`
+		SELECT id FROM articles
 		WHERE published_at < ?
 		AND is_read = 0
 		AND is_favorite = 0
 		AND is_read_later = 0
+		AND id NOT IN (SELECT article_id FROM article_archives)
+		AND feed_id NOT IN (SELECT feed_id FROM retention_policy_feeds)
 	`, cutoffDate)
 	if err == nil {
-		count, _ := result.RowsAffected()
 		totalDeleted += count
 		if count > 0 {
 			log.Printf("Layer 3: Deleted %d unread articles older than 90 days", count)
@@ -551,15 +567,16 @@ func (db *DB) CleanupOldArticlesLayered() (int64, error) {
 
 	// Layer 4: Delete old unread articles (60 days)
 	cutoffDate = time.Now().AddDate(0, 0, -60)
-	result, err = db.Exec(`
-		DELETE FROM articles
+	count, err = db.removeArticles(`
+		SELECT id FROM articles
 		WHERE published_at < ?
 		AND is_read = 0
 		AND is_favorite = 0
 		AND is_read_later = 0
+		AND id NOT IN (SELECT article_id FROM article_archives)
+		AND feed_id NOT IN (SELECT feed_id FROM retention_policy_feeds)
 	`, cutoffDate)
 	if err == nil {
-		count, _ := result.RowsAffected()
 		totalDeleted += count
 		if count > 0 {
 			log.Printf("Layer 4: Deleted %d unread articles older than 60 days", count)
@@ -579,47 +596,49 @@ func (db *DB) CleanupOldArticlesLayered() (int64, error) {
 }
 
 // CleanupOldReadArticles removes read articles older than specified days
-// Protects favorited and read later articles
+// Protects favorited, read later and archived articles
 // With foreign_keys enabled, ON DELETE CASCADE automatically removes
 // associated article_contents, chat_sessions, and chat_messages rows.
+// Removed articles are moved to cold storage when it is enabled.
 func (db *DB) CleanupOldReadArticles(maxAgeDays int) (int64, error) {
 	db.WaitForReady()
 
 	cutoffDate := time.Now().AddDate(0, 0, -maxAgeDays)
-	result, err := db.Exec(`
-		DELETE FROM articles
+	count, err := db.removeArticles(`
+		SELECT id FROM articles
 		WHERE published_at < ?
 		AND is_read = 1
 		AND is_favorite = 0
 		AND is_read_later = 0
+		AND id NOT IN (SELECT article_id FROM article_archives)
+		AND feed_id NOT IN (SELECT feed_id FROM retention_policy_feeds)
 	`, cutoffDate)
 	if err != nil {
 		return 0, err
 	}
-
-	count, _ := result.RowsAffected()
 	return count, nil
 }
 
 // CleanupOldUnreadArticles removes unread articles older than specified days
-// Protects favorited and read later articles
+// Protects favorited, read later and archived articles
 // With foreign_keys enabled, ON DELETE CASCADE automatically removes
 // associated article_contents, chat_sessions, and chat_messages rows.
+// Removed articles are moved to cold storage when it is enabled.
 func (db *DB) CleanupOldUnreadArticles(maxAgeDays int) (int64, error) {
 	db.WaitForReady()
 
 	cutoffDate := time.Now().AddDate(0, 0, -maxAgeDays)
-	result, err := db.Exec(`
-		DELETE FROM articles
+	count, err := db.removeArticles(`
+		SELECT id FROM articles
 		WHERE published_at < ?
 		AND is_read = 0
 		AND is_favorite = 0
 		AND is_read_later = 0
+		AND id NOT IN (SELECT article_id FROM article_archives)
+		AND feed_id NOT IN (SELECT feed_id FROM retention_policy_feeds)
 	`, cutoffDate)
 	if err != nil {
 		return 0, err
 	}
-
-	count, _ := result.RowsAffected()
 	return count, nil
 }
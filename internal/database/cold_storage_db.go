@@ -0,0 +1,386 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"MrRSS/internal/models"
+
+	"modernc.org/sqlite"
+)
+
+// Cold storage keeps articles removed by automatic cleanup in a separate
+// database file, attached to every connection as "cold", instead of deleting
+// them. Their cached content is kept gzip-compressed.
+
+// ErrColdStorageDisabled is returned when no cold storage file is attached.
+var ErrColdStorageDisabled = errors.New("cold storage is not enabled")
+
+// coldStoragePaths maps the data source names of open databases to the cold
+// storage file attached to their connections
+var coldStoragePaths sync.Map
+
+func init() {
+	sqlite.RegisterConnectionHook(attachColdStorage)
+}
+
+func attachColdStorage(conn sqlite.ExecQuerierContext, dsn string) error {
+	path, ok := coldStoragePaths.Load(dsn)
+	if !ok {
+		return nil
+	}
+	_, err := conn.ExecContext(context.Background(), `ATTACH DATABASE ? AS cold`,
+		[]driver.NamedValue{{Ordinal: 1, Value: path}})
+	return err
+}
+
+// coldStorageSchema creates the cold storage tables. Articles keep their ID,
+// and the title and URL of their feed in case the feed is deleted later.
+var coldStorageSchema = []string{
+	`CREATE TABLE IF NOT EXISTS cold.articles (
+		id INTEGER PRIMARY KEY,
+		feed_id INTEGER,
+		title TEXT,
+		url TEXT,
+		image_url TEXT,
+		audio_url TEXT DEFAULT '',
+		video_url TEXT DEFAULT '',
+		translated_title TEXT,
+		published_at DATETIME,
+		is_read BOOLEAN DEFAULT 0,
+		is_favorite BOOLEAN DEFAULT 0,
+		is_hidden BOOLEAN DEFAULT 0,
+		is_read_later BOOLEAN DEFAULT 0,
+		summary TEXT DEFAULT '',
+		original_summary TEXT DEFAULT '',
+		unique_id TEXT,
+		author TEXT DEFAULT '',
+		freshrss_item_id TEXT DEFAULT '',
+		feed_title TEXT DEFAULT '',
+		feed_url TEXT DEFAULT '',
+		archived_at DATETIME
+	)`,
+	`CREATE INDEX IF NOT EXISTS cold.idx_articles_url ON articles(url)`,
+	`CREATE INDEX IF NOT EXISTS cold.idx_articles_unique_id ON articles(unique_id)`,
+	`CREATE INDEX IF NOT EXISTS cold.idx_articles_published_at ON articles(published_at DESC)`,
+	`CREATE TABLE IF NOT EXISTS cold.article_contents (
+		article_id INTEGER PRIMARY KEY,
+		encoding TEXT NOT NULL,
+		content BLOB NOT NULL
+	)`,
+}
+
+// coldArticleColumns are the article columns copied to and from cold storage
+var coldArticleColumns = []string{
+	"id", "feed_id", "title", "url", "image_url", "audio_url", "video_url", "translated_title",
+	"published_at", "is_read", "is_favorite", "is_hidden", "is_read_later", "summary",
+	"original_summary", "unique_id", "author", "freshrss_item_id",
+}
+
+// coldArticleListColumns are articleListColumns for articles in cold storage,
+// whose feed may no longer exist
+const coldArticleListColumns = `a.id, a.feed_id, a.title, a.url, a.image_url, a.audio_url, a.video_url, a.published_at, a.is_read, a.is_favorite, a.is_hidden, a.is_read_later, a.translated_title, a.summary, a.freshrss_item_id, COALESCE(f.title, a.feed_title), a.author`
+
+const coldArticleListFrom = `
+		FROM cold.articles a
+		LEFT JOIN feeds f ON a.feed_id = f.id`
+
+// EnableColdStorage attaches the cold storage file at path to every
+// connection of the database. It must be called before Init.
+func (db *DB) EnableColdStorage(path string) {
+	db.coldStoragePath = path
+	coldStoragePaths.Store(db.readDSN, path)
+	coldStoragePaths.Store(db.writeDSN, path)
+}
+
+// initColdStorage creates the cold storage tables when a file is attached
+func (db *DB) initColdStorage() error {
+	if db.coldStoragePath == "" {
+		return nil
+	}
+	for _, stmt := range coldStorageSchema {
+		if _, err := db.writePool.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// coldStorageActive reports whether cleanup moves articles to cold storage
+func (db *DB) coldStorageActive() bool {
+	if db.coldStoragePath == "" {
+		return false
+	}
+	enabled, _ := db.GetSetting("cold_storage_enabled")
+	return enabled != "false"
+}
+
+// removeArticles removes the articles whose IDs selectIDs returns, moving
+// them to cold storage when it is active and deleting them otherwise.
+func (db *DB) removeArticles(selectIDs string, args ...interface{}) (int64, error) {
+	if !db.coldStorageActive() {
+		result, err := db.Exec(`DELETE FROM articles WHERE id IN (`+selectIDs+`)`, args...)
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	}
+	keepContent, _ := db.GetSetting("cold_storage_keep_content")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	ids, err := queryTxIDs(tx, selectIDs, args)
+	if err != nil {
+		return 0, err
+	}
+
+	columns := strings.Join(coldArticleColumns, ", ")
+	selected := "a." + strings.Join(coldArticleColumns, ", a.")
+	archivedAt := time.Now()
+
+	// Keep the number of bound variables well below SQLite's limit
+	const batchSize = 500
+	var moved int64
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:min(start+batchSize, len(ids))]
+		in := "(" + strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",") + ")"
+		batchArgs := make([]interface{}, len(batch))
+		for i, id := range batch {
+			batchArgs[i] = id
+		}
+
+		// An article fetched again after it was archived replaces the old copy
+		if _, err := tx.Exec(`
+			DELETE FROM cold.article_contents WHERE article_id IN (
+				SELECT c.id FROM cold.articles c JOIN main.articles a ON a.unique_id = c.unique_id
+				WHERE a.id IN `+in+` AND c.id != a.id
+			)`, batchArgs...); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`
+			DELETE FROM cold.articles WHERE id IN (
+				SELECT c.id FROM cold.articles c JOIN main.articles a ON a.unique_id = c.unique_id
+				WHERE a.id IN `+in+` AND c.id != a.id
+			)`, batchArgs...); err != nil {
+			return 0, err
+		}
+
+		if _, err := tx.Exec(`
+			INSERT OR REPLACE INTO cold.articles (`+columns+`, feed_title, feed_url, archived_at)
+			SELECT `+selected+`, COALESCE(f.title, ''), COALESCE(f.url, ''), ?
+			FROM main.articles a LEFT JOIN main.feeds f ON f.id = a.feed_id
+			WHERE a.id IN `+in,
+			append([]interface{}{archivedAt}, batchArgs...)...); err != nil {
+			return 0, err
+		}
+		if keepContent != "false" {
+			if _, err := tx.Exec(`
+				INSERT OR REPLACE INTO cold.article_contents (article_id, encoding, content)
+				SELECT article_id, 'gzip', gzip(content) FROM main.article_contents
+				WHERE article_id IN `+in, batchArgs...); err != nil {
+				return 0, err
+			}
+		}
+
+		result, err := tx.Exec(`DELETE FROM main.articles WHERE id IN `+in, batchArgs...)
+		if err != nil {
+			return 0, err
+		}
+		count, _ := result.RowsAffected()
+		moved += count
+	}
+
+	return moved, tx.Commit()
+}
+
+func queryTxIDs(tx *sql.Tx, query string, args []interface{}) ([]int64, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CountColdArticles returns the number of articles in cold storage matching
+// conditions. Conditions on article content never match archived articles.
+func (db *DB) CountColdArticles(conditions []FilterCondition) (int, error) {
+	db.WaitForReady()
+	if db.coldStoragePath == "" {
+		return 0, nil
+	}
+
+	expr, args, err := db.compileFilter(conditions)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	err = db.QueryRow("SELECT COUNT(*)"+coldArticleListFrom+" WHERE "+expr, args...).Scan(&count)
+	return count, err
+}
+
+// SearchColdArticles returns the articles in cold storage matching
+// conditions, newest first.
+func (db *DB) SearchColdArticles(conditions []FilterCondition, limit, offset int) ([]models.Article, error) {
+	db.WaitForReady()
+	articles := []models.Article{}
+	if db.coldStoragePath == "" {
+		return articles, nil
+	}
+
+	expr, args, err := db.compileFilter(conditions)
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT " + coldArticleListColumns + coldArticleListFrom + " WHERE " + expr +
+		" ORDER BY a.published_at DESC, a.id DESC LIMIT ? OFFSET ?"
+	rows, err := db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a, err := scanArticleListRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+	}
+	return articles, rows.Err()
+}
+
+// FindArticleByURL returns the newest article with the given URL, looking in
+// cold storage too when includeColdStorage is set. It reports whether the
+// article was found in cold storage, and returns a nil article when there is
+// none.
+func (db *DB) FindArticleByURL(url string, includeColdStorage bool) (*models.Article, bool, error) {
+	db.WaitForReady()
+
+	article, err := db.queryArticleByURL("SELECT "+articleListColumns+articleListFrom, url)
+	if err != nil || article != nil || !includeColdStorage || db.coldStoragePath == "" {
+		return article, false, err
+	}
+	article, err = db.queryArticleByURL("SELECT "+coldArticleListColumns+coldArticleListFrom, url)
+	return article, article != nil, err
+}
+
+func (db *DB) queryArticleByURL(selectFrom, url string) (*models.Article, error) {
+	rows, err := db.Query(selectFrom+" WHERE a.url = ? ORDER BY a.published_at DESC, a.id DESC LIMIT 1", url)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	a, err := scanArticleListRow(rows)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// RestoreColdArticle moves an article from cold storage back into its feed,
+// or into fallbackFeed when that feed no longer exists, and returns its ID.
+// The restored article is marked read later so that the next cleanup does
+// not archive it again. When the article was fetched again in the meantime,
+// the archived copy is dropped and the existing article is returned.
+func (db *DB) RestoreColdArticle(id int64, fallbackFeed *models.Feed) (int64, error) {
+	db.WaitForReady()
+	if db.coldStoragePath == "" {
+		return 0, ErrColdStorageDisabled
+	}
+
+	var feedID int64
+	var uniqueID sql.NullString
+	var feedExists bool
+	err := db.QueryRow(`
+		SELECT COALESCE(a.feed_id, 0), a.unique_id, f.id IS NOT NULL
+		FROM cold.articles a LEFT JOIN feeds f ON f.id = a.feed_id
+		WHERE a.id = ?
+	`, id).Scan(&feedID, &uniqueID, &feedExists)
+	if err != nil {
+		return 0, err
+	}
+	if !feedExists {
+		if feedID, err = db.GetOrCreateBuiltinFeed(fallbackFeed); err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	restoredID := id
+	err = tx.QueryRow(`SELECT id FROM articles WHERE unique_id = ?`, uniqueID).Scan(&restoredID)
+	if err == sql.ErrNoRows {
+		// Keep the original ID unless another article has taken it
+		var taken bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM articles WHERE id = ?)`, id).Scan(&taken); err != nil {
+			return 0, err
+		}
+		columns := coldArticleColumns
+		if taken {
+			columns = columns[1:]
+		}
+		selected := make([]string, len(columns))
+		for i, column := range columns {
+			switch column {
+			case "feed_id":
+				selected[i] = "?"
+			case "is_read_later":
+				selected[i] = "1"
+			default:
+				selected[i] = column
+			}
+		}
+		result, err := tx.Exec(`INSERT INTO articles (`+strings.Join(columns, ", ")+`)
+			SELECT `+strings.Join(selected, ", ")+` FROM cold.articles WHERE id = ?`, feedID, id)
+		if err != nil {
+			return 0, err
+		}
+		if restoredID, err = result.LastInsertId(); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`
+			INSERT OR REPLACE INTO article_contents (article_id, content)
+			SELECT ?, gunzip(content) FROM cold.article_contents
+			WHERE article_id = ? AND encoding = 'gzip'
+		`, restoredID, id); err != nil {
+			return 0, err
+		}
+	} else if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM cold.article_contents WHERE article_id = ?`, id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM cold.articles WHERE id = ?`, id); err != nil {
+		return 0, err
+	}
+	return restoredID, tx.Commit()
+}
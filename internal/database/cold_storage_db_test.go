@@ -0,0 +1,119 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"MrRSS/internal/models"
+)
+
+func setupColdStorageDB(t *testing.T) (*DB, int64) {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := NewDB(filepath.Join(dir, "rss.db"))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.EnableColdStorage(filepath.Join(dir, "archive.db"))
+	if err := db.Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	res, err := db.Exec(`INSERT INTO feeds (title, url) VALUES ('Feed', 'https://example.com/feed')`)
+	if err != nil {
+		t.Fatalf("insert feed: %v", err)
+	}
+	feedID, _ := res.LastInsertId()
+	return db, feedID
+}
+
+func TestCleanupMovesArticlesToColdStorage(t *testing.T) {
+	db, feedID := setupColdStorageDB(t)
+
+	old := time.Now().AddDate(0, 0, -60)
+	res, err := db.Exec(`INSERT INTO articles (feed_id, title, url, published_at, is_read, unique_id) VALUES (?, 'Old story', 'https://example.com/old', ?, 1, 'old')`, feedID, old)
+	if err != nil {
+		t.Fatalf("insert article: %v", err)
+	}
+	oldID, _ := res.LastInsertId()
+	if _, err := db.Exec(`INSERT INTO articles (feed_id, title, url, published_at, is_read, unique_id) VALUES (?, 'New story', 'https://example.com/new', ?, 1, 'new')`, feedID, time.Now()); err != nil {
+		t.Fatalf("insert article: %v", err)
+	}
+	if err := db.SetArticleContent(oldID, "<p>archived body</p>"); err != nil {
+		t.Fatalf("SetArticleContent: %v", err)
+	}
+
+	removed, err := db.CleanupOldReadArticles(30)
+	if err != nil {
+		t.Fatalf("CleanupOldReadArticles: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("removed = %d, want 1", removed)
+	}
+	if a, _ := db.GetArticleByID(oldID); a != nil {
+		t.Fatalf("article %d still in main database", oldID)
+	}
+
+	conditions := []FilterCondition{{Field: "article_title", Operator: "contains", Value: "story"}}
+	total, err := db.CountColdArticles(conditions)
+	if err != nil || total != 1 {
+		t.Fatalf("CountColdArticles = %d, %v, want 1", total, err)
+	}
+	archived, err := db.SearchColdArticles(conditions, 10, 0)
+	if err != nil {
+		t.Fatalf("SearchColdArticles: %v", err)
+	}
+	if len(archived) != 1 || archived[0].ID != oldID || archived[0].FeedTitle != "Feed" {
+		t.Fatalf("SearchColdArticles = %+v", archived)
+	}
+
+	if a, inCold, err := db.FindArticleByURL("https://example.com/old", false); err != nil || a != nil || inCold {
+		t.Errorf("FindArticleByURL without archive = %v, %v, %v", a, inCold, err)
+	}
+	a, inCold, err := db.FindArticleByURL("https://example.com/old", true)
+	if err != nil || a == nil || !inCold || a.ID != oldID {
+		t.Fatalf("FindArticleByURL with archive = %v, %v, %v", a, inCold, err)
+	}
+
+	restoredID, err := db.RestoreColdArticle(oldID, &models.Feed{Title: "Imported", URL: "mrrss://imported", Type: "imported"})
+	if err != nil {
+		t.Fatalf("RestoreColdArticle: %v", err)
+	}
+	if restoredID != oldID {
+		t.Errorf("restored ID = %d, want %d", restoredID, oldID)
+	}
+	restored, err := db.GetArticleByID(restoredID)
+	if err != nil || restored == nil {
+		t.Fatalf("GetArticleByID: %v, %v", restored, err)
+	}
+	if restored.FeedID != feedID || !restored.IsReadLater {
+		t.Errorf("restored article = %+v", restored)
+	}
+	content, ok, err := db.GetArticleContent(restoredID)
+	if err != nil || !ok || content != "<p>archived body</p>" {
+		t.Errorf("restored content = %q, %v, %v", content, ok, err)
+	}
+	if total, _ := db.CountColdArticles(nil); total != 0 {
+		t.Errorf("cold articles after restore = %d, want 0", total)
+	}
+}
+
+func TestCleanupDeletesWhenColdStorageDisabled(t *testing.T) {
+	db, feedID := setupColdStorageDB(t)
+	if err := db.SetSetting("cold_storage_enabled", "false"); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+
+	old := time.Now().AddDate(0, 0, -60)
+	if _, err := db.Exec(`INSERT INTO articles (feed_id, title, url, published_at, unique_id) VALUES (?, 'Old', 'https://example.com/old', ?, 'old')`, feedID, old); err != nil {
+		t.Fatalf("insert article: %v", err)
+	}
+	if removed, err := db.CleanupOldUnreadArticles(30); err != nil || removed != 1 {
+		t.Fatalf("CleanupOldUnreadArticles = %d, %v, want 1", removed, err)
+	}
+	if total, err := db.CountColdArticles(nil); err != nil || total != 0 {
+		t.Errorf("CountColdArticles = %d, %v, want 0", total, err)
+	}
+}
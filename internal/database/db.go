@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"strings"
 	"sync"
@@ -10,10 +11,24 @@ import (
 )
 
 // DB wraps sql.DB with initialization state tracking.
+//
+// The embedded sql.DB is the read pool. Writes made through Exec, ExecContext,
+// Begin and BeginTx go to a separate pool with a single connection, so SQLite
+// never sees two writers at once. Plain Exec calls are queued to one goroutine
+// that coalesces them into batched transactions.
 type DB struct {
 	*sql.DB
 	ready chan struct{}
 	once  sync.Once
+
+	writePool *sql.DB
+	writer    *writer
+
+	// Data source names of the read and write pools, and the cold storage
+	// file attached to their connections, if any
+	readDSN         string
+	writeDSN        string
+	coldStoragePath string
 }
 
 // NewDB creates a new database connection with optimized settings.
@@ -22,10 +37,11 @@ func NewDB(dataSourceName string) (*DB, error) {
 	// Also enable WAL mode for better concurrency
 	// Add performance optimizations: increase cache size, set synchronous=NORMAL
 	// Enable foreign_keys to make ON DELETE CASCADE work (disabled by default in SQLite)
+	pragmas := "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=cache_size(-32000)&_pragma=synchronous(NORMAL)&_pragma=foreign_keys(1)"
 	if !strings.Contains(dataSourceName, "?") {
-		dataSourceName += "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=cache_size(-32000)&_pragma=synchronous(NORMAL)&_pragma=foreign_keys(1)"
+		dataSourceName += "?" + pragmas
 	} else {
-		dataSourceName += "&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=cache_size(-32000)&_pragma=synchronous(NORMAL)&_pragma=foreign_keys(1)"
+		dataSourceName += "&" + pragmas
 	}
 
 	db, err := sql.Open("sqlite", dataSourceName)
@@ -38,13 +54,81 @@ func NewDB(dataSourceName string) (*DB, error) {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
+	// Every connection to an in-memory database sees its own database, so
+	// reads and writes have to share a pool there
+	writePool, writeDSN := db, dataSourceName
+	if !isMemoryDSN(dataSourceName) {
+		// Take the write lock when a transaction starts rather than at its
+		// first write, so transactions never fail to upgrade their lock
+		writeDSN += "&_txlock=immediate"
+		writePool, err = sql.Open("sqlite", writeDSN)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		writePool.SetMaxOpenConns(1)
+		writePool.SetMaxIdleConns(1)
+		writePool.SetConnMaxLifetime(0)
+	}
+
 	return &DB{
-		DB:    db,
-		ready: make(chan struct{}),
+		DB:        db,
+		ready:     make(chan struct{}),
+		writePool: writePool,
+		writer:    newWriter(writePool),
+		readDSN:   dataSourceName,
+		writeDSN:  writeDSN,
 	}, nil
 }
 
+func isMemoryDSN(dataSourceName string) bool {
+	return strings.HasPrefix(dataSourceName, ":memory:") || strings.Contains(dataSourceName, "mode=memory")
+}
+
 // WaitForReady blocks until the database is initialized.
 func (db *DB) WaitForReady() {
 	<-db.ready
 }
+
+// Exec queues a write for the writer goroutine and waits for its result.
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.writer.exec(context.Background(), query, args)
+}
+
+// ExecContext is Exec with a context. A statement whose context is done
+// before it starts is not run.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.writer.exec(ctx, query, args)
+}
+
+// Begin starts a transaction on the write connection. Queued writes wait until
+// it ends, so the caller must not use db.Exec while it is open.
+func (db *DB) Begin() (*sql.Tx, error) {
+	return db.writePool.Begin()
+}
+
+// BeginTx is Begin with a context and options.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return db.writePool.BeginTx(ctx, opts)
+}
+
+// WriterStats returns queue and batching metrics of the writer.
+func (db *DB) WriterStats() WriterStats {
+	return db.writer.stats()
+}
+
+// Close waits for queued writes and closes both connection pools.
+func (db *DB) Close() error {
+	db.writer.close()
+	if db.coldStoragePath != "" {
+		coldStoragePaths.Delete(db.readDSN)
+		coldStoragePaths.Delete(db.writeDSN)
+	}
+	if db.writePool != db.DB {
+		if err := db.writePool.Close(); err != nil {
+			db.DB.Close()
+			return err
+		}
+	}
+	return db.DB.Close()
+}
@@ -0,0 +1,383 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"MrRSS/internal/crypto"
+)
+
+// MasterPassphraseEnv names the environment variable that unlocks the master
+// key on start, for headless setups where nobody can enter the passphrase
+const MasterPassphraseEnv = "MRRSS_MASTER_PASSPHRASE"
+
+// Internal settings that hold the master key. The wrapped key is the master
+// key encrypted with the machine key, so this machine loads it on start; on
+// another machine it cannot be decrypted and the passphrase is needed again.
+const (
+	masterKeySaltSetting    = "master_key_salt"
+	masterKeyIDSetting      = "master_key_id"
+	masterKeyWrappedSetting = "master_key_wrapped"
+)
+
+// keyringSettings are left out when secrets are re-encrypted
+var keyringSettings = map[string]bool{
+	masterKeySaltSetting:    true,
+	masterKeyIDSetting:      true,
+	masterKeyWrappedSetting: true,
+}
+
+// EncryptionStatus describes the key secrets are encrypted with
+type EncryptionStatus struct {
+	Mode     string `json:"mode"`             // "machine" or "passphrase"
+	KeyID    string `json:"key_id,omitempty"` // ID of the master key
+	Unlocked bool   `json:"unlocked"`         // Whether the master key is loaded
+	Secrets  int    `json:"secrets"`          // Encrypted values stored
+	Locked   int    `json:"locked"`           // Encrypted values that cannot be decrypted now
+}
+
+// SecretsRotation reports a re-encryption of the stored secrets
+type SecretsRotation struct {
+	Rotated int `json:"rotated"`
+	Failed  int `json:"failed"` // Values that could not be decrypted and were left as they are
+}
+
+// sealFeedSecret encrypts a feed secret for storage. Values that are already
+// encrypted are stored as they are.
+func sealFeedSecret(value string) (string, error) {
+	if value == "" || crypto.IsEncrypted(value) {
+		return value, nil
+	}
+	encrypted, err := crypto.Encrypt(value)
+	if err != nil {
+		return "", fmt.Errorf("encrypt feed secret: %w", err)
+	}
+	return encrypted, nil
+}
+
+// openFeedSecret decrypts a stored feed secret. Plain text from older versions
+// is returned as it is, and so is a value that cannot be decrypted, so saving
+// the feed again does not lose it.
+func openFeedSecret(value string) string {
+	if !crypto.IsEncrypted(value) {
+		return value
+	}
+	decrypted, err := crypto.Decrypt(value)
+	if err != nil {
+		log.Printf("Warning: failed to decrypt feed secret: %v", err)
+		return value
+	}
+	return decrypted
+}
+
+// loadMasterKey loads the master key, if one is set, from the passphrase in
+// MRRSS_MASTER_PASSPHRASE or from the key wrapped for this machine.
+//
+// It runs inside Init() before db.ready is closed and must not call methods
+// that invoke WaitForReady.
+func (db *DB) loadMasterKey() error {
+	var keyID string
+	err := db.DB.QueryRow(`SELECT value FROM settings WHERE key = ?`, masterKeyIDSetting).Scan(&keyID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && keyID == "") {
+		crypto.SetMasterKey(nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if passphrase := os.Getenv(MasterPassphraseEnv); passphrase != "" {
+		return db.unlockMasterKey(passphrase)
+	}
+	var wrapped string
+	_ = db.DB.QueryRow(`SELECT value FROM settings WHERE key = ?`, masterKeyWrappedSetting).Scan(&wrapped)
+	key, err := crypto.UnwrapMasterKey(wrapped)
+	if err != nil || key.ID != keyID {
+		crypto.SetMasterKey(nil)
+		return crypto.ErrLocked
+	}
+	crypto.SetMasterKey(key)
+	return nil
+}
+
+// UnlockMasterKey loads the master key from its passphrase, e.g. after the
+// database was moved to another machine, and wraps it for this machine so
+// the passphrase is not needed on the next start.
+func (db *DB) UnlockMasterKey(passphrase string) error {
+	db.WaitForReady()
+	return db.unlockMasterKey(passphrase)
+}
+
+func (db *DB) unlockMasterKey(passphrase string) error {
+	var encodedSalt, keyID string
+	if err := db.DB.QueryRow(`SELECT value FROM settings WHERE key = ?`, masterKeySaltSetting).Scan(&encodedSalt); err != nil {
+		return errors.New("no master passphrase is set")
+	}
+	_ = db.DB.QueryRow(`SELECT value FROM settings WHERE key = ?`, masterKeyIDSetting).Scan(&keyID)
+	salt, err := base64.StdEncoding.DecodeString(encodedSalt)
+	if err != nil {
+		return fmt.Errorf("invalid master key salt: %w", err)
+	}
+
+	key, err := crypto.DeriveMasterKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	if key.ID != keyID {
+		return crypto.ErrWrongPassphrase
+	}
+	crypto.SetMasterKey(key)
+
+	wrapped, err := crypto.WrapMasterKey(key)
+	if err != nil {
+		return err
+	}
+	_, err = db.writePool.Exec(`INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)`, masterKeyWrappedSetting, wrapped)
+	return err
+}
+
+// CheckMasterPassphrase reports whether passphrase derives the current master key
+func (db *DB) CheckMasterPassphrase(passphrase string) error {
+	db.WaitForReady()
+
+	var encodedSalt string
+	if err := db.QueryRow(`SELECT value FROM settings WHERE key = ?`, masterKeySaltSetting).Scan(&encodedSalt); err != nil {
+		return errors.New("no master passphrase is set")
+	}
+	salt, err := base64.StdEncoding.DecodeString(encodedSalt)
+	if err != nil {
+		return fmt.Errorf("invalid master key salt: %w", err)
+	}
+	key, err := crypto.DeriveMasterKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	if current := crypto.CurrentMasterKey(); current == nil || current.ID != key.ID {
+		return crypto.ErrWrongPassphrase
+	}
+	return nil
+}
+
+// SetMasterPassphrase derives a new master key from passphrase and
+// re-encrypts all secrets with it. The master key must be unlocked if one is
+// set; secrets that cannot be decrypted are left as they are.
+func (db *DB) SetMasterPassphrase(passphrase string) (*SecretsRotation, error) {
+	db.WaitForReady()
+	if err := db.requireUnlocked(); err != nil {
+		return nil, err
+	}
+
+	salt, err := crypto.NewSalt()
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypto.DeriveMasterKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := crypto.WrapMasterKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return db.rotateSecrets(key, map[string]string{
+		masterKeySaltSetting:    base64.StdEncoding.EncodeToString(salt),
+		masterKeyIDSetting:      key.ID,
+		masterKeyWrappedSetting: wrapped,
+	})
+}
+
+// RemoveMasterPassphrase re-encrypts all secrets with the machine key and
+// forgets the master key
+func (db *DB) RemoveMasterPassphrase() (*SecretsRotation, error) {
+	db.WaitForReady()
+	if err := db.requireUnlocked(); err != nil {
+		return nil, err
+	}
+	return db.rotateSecrets(nil, map[string]string{
+		masterKeySaltSetting:    "",
+		masterKeyIDSetting:      "",
+		masterKeyWrappedSetting: "",
+	})
+}
+
+// RotateSecrets re-encrypts all encrypted settings, AI profile API keys and
+// feed email passwords with the current key and fresh salts, and encrypts
+// email passwords stored in plain text by older versions.
+func (db *DB) RotateSecrets() (*SecretsRotation, error) {
+	db.WaitForReady()
+	if err := db.requireUnlocked(); err != nil {
+		return nil, err
+	}
+	return db.rotateSecrets(nil, nil)
+}
+
+// requireUnlocked returns crypto.ErrLocked when a master passphrase is set
+// but its key is not loaded, so secrets are never moved off the master key
+// by accident
+func (db *DB) requireUnlocked() error {
+	var keyID string
+	_ = db.QueryRow(`SELECT value FROM settings WHERE key = ?`, masterKeyIDSetting).Scan(&keyID)
+	return checkUnlocked(keyID, crypto.CurrentMasterKey())
+}
+
+// checkUnlocked returns crypto.ErrLocked when current is not the master key
+// with the stored keyID
+func checkUnlocked(keyID string, current *crypto.MasterKey) error {
+	if keyID != "" && (current == nil || current.ID != keyID) {
+		return crypto.ErrLocked
+	}
+	return nil
+}
+
+// secretColumn is a table column holding secrets. Plain text in it is a
+// secret too and gets encrypted; in settings only encrypted values are secrets.
+type secretColumn struct {
+	table, key, column string
+	plainIsSecret      bool
+}
+
+var secretColumns = []secretColumn{
+	{"settings", "key", "value", false},
+	{"ai_profiles", "id", "api_key", true},
+	{"feeds", "id", "email_password", true},
+}
+
+// rotateSecrets decrypts all secrets with the current key and encrypts them
+// with to (nil for the machine key), writing keyring settings in the same
+// transaction; an empty keyring value deletes the setting. Without a keyring
+// secrets are encrypted with the current key again. The secrets are read in
+// the transaction, and the process switches to the new key as it commits,
+// so no secret is written with the replaced key in between.
+func (db *DB) rotateSecrets(to *crypto.MasterKey, keyring map[string]string) (*SecretsRotation, error) {
+	// The transaction is taken first: writers encrypting secrets would
+	// otherwise wait for the key while holding it
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	change := crypto.BeginKeyChange()
+	defer change.Abort()
+
+	from := change.Current()
+	var keyID string
+	_ = tx.QueryRow(`SELECT value FROM settings WHERE key = ?`, masterKeyIDSetting).Scan(&keyID)
+	if err := checkUnlocked(keyID, from); err != nil {
+		return nil, err
+	}
+	if keyring == nil {
+		to = from
+	}
+
+	type update struct {
+		column secretColumn
+		key    interface{}
+		value  string
+	}
+	var updates []update
+	result := &SecretsRotation{}
+	for _, c := range secretColumns {
+		rows, err := tx.Query(fmt.Sprintf(`SELECT %s, %s FROM %s WHERE COALESCE(%s, '') != ''`, c.key, c.column, c.table, c.column))
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var key interface{}
+			var value string
+			if err := rows.Scan(&key, &value); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if c.table == "settings" && keyringSettings[fmt.Sprint(key)] {
+				continue
+			}
+			plain := value
+			if crypto.IsEncrypted(value) {
+				if plain, err = crypto.DecryptWithKey(value, from); err != nil {
+					result.Failed++
+					continue
+				}
+			} else if !c.plainIsSecret {
+				continue
+			}
+			encrypted, err := crypto.EncryptWithKey(plain, to)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			updates = append(updates, update{column: c, key: key, value: encrypted})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, u := range updates {
+		if _, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ?`, u.column.table, u.column.column, u.column.key), u.value, u.key); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range keyring {
+		if value == "" {
+			_, err = tx.Exec(`DELETE FROM settings WHERE key = ?`, key)
+		} else {
+			_, err = tx.Exec(`INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)`, key, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	change.Commit(to)
+
+	result.Rotated = len(updates)
+	return result, nil
+}
+
+// GetEncryptionStatus reports the key secrets are encrypted with and how
+// many stored secrets cannot be decrypted with the loaded keys
+func (db *DB) GetEncryptionStatus() (*EncryptionStatus, error) {
+	db.WaitForReady()
+
+	status := &EncryptionStatus{Mode: "machine", Unlocked: true}
+	_ = db.QueryRow(`SELECT value FROM settings WHERE key = ?`, masterKeyIDSetting).Scan(&status.KeyID)
+	current := crypto.CurrentMasterKey()
+	if status.KeyID != "" {
+		status.Mode = "passphrase"
+		status.Unlocked = current != nil && current.ID == status.KeyID
+	}
+
+	for _, c := range secretColumns {
+		rows, err := db.Query(fmt.Sprintf(`SELECT %s, %s FROM %s WHERE COALESCE(%s, '') != ''`, c.key, c.column, c.table, c.column))
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var key interface{}
+			var value string
+			if err := rows.Scan(&key, &value); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if !crypto.IsEncrypted(value) || (c.table == "settings" && keyringSettings[fmt.Sprint(key)]) {
+				continue
+			}
+			status.Secrets++
+			if _, err := crypto.DecryptWithKey(value, current); err != nil {
+				status.Locked++
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,83 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"MrRSS/internal/crypto"
+	"MrRSS/internal/models"
+)
+
+func TestMasterPassphraseLifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { crypto.SetMasterKey(nil) })
+
+	feedID, err := db.AddFeed(&models.Feed{Title: "Mail", URL: "email://me@example.com", Type: "email", EmailPassword: "imap-secret"})
+	if err != nil {
+		t.Fatalf("AddFeed: %v", err)
+	}
+	if err := db.SetEncryptedSetting("deepl_api_key", "deepl-secret"); err != nil {
+		t.Fatalf("SetEncryptedSetting: %v", err)
+	}
+	if _, err := db.CreateAIProfile(&models.AIProfile{Name: "Main", APIKey: "sk-secret", Endpoint: "http://localhost", Model: "m"}); err != nil {
+		t.Fatalf("CreateAIProfile: %v", err)
+	}
+
+	rawPassword := func() string {
+		t.Helper()
+		var value string
+		if err := db.QueryRow(`SELECT email_password FROM feeds WHERE id = ?`, feedID).Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+	if raw := rawPassword(); !crypto.IsEncrypted(raw) {
+		t.Fatalf("email password stored as %q, want it encrypted", raw)
+	}
+
+	result, err := db.SetMasterPassphrase("correct horse")
+	if err != nil {
+		t.Fatalf("SetMasterPassphrase: %v", err)
+	}
+	if result.Rotated != 3 || result.Failed != 0 {
+		t.Errorf("SetMasterPassphrase = %+v, want 3 rotated", result)
+	}
+	key := crypto.CurrentMasterKey()
+	if key == nil || crypto.KeyID(rawPassword()) != key.ID {
+		t.Fatalf("email password not encrypted with the master key: %q", rawPassword())
+	}
+
+	// On another machine the wrapped key cannot be loaded
+	crypto.SetMasterKey(nil)
+	if _, err := db.GetEncryptedSetting("deepl_api_key"); !errors.Is(err, crypto.ErrLocked) {
+		t.Errorf("GetEncryptedSetting while locked error = %v, want ErrLocked", err)
+	}
+	if _, err := db.RotateSecrets(); !errors.Is(err, crypto.ErrLocked) {
+		t.Errorf("RotateSecrets while locked error = %v, want ErrLocked", err)
+	}
+	if status, err := db.GetEncryptionStatus(); err != nil || status.Unlocked || status.Locked != 3 {
+		t.Errorf("GetEncryptionStatus while locked = %+v, %v", status, err)
+	}
+	if err := db.UnlockMasterKey("wrong"); !errors.Is(err, crypto.ErrWrongPassphrase) {
+		t.Errorf("UnlockMasterKey(wrong) error = %v, want ErrWrongPassphrase", err)
+	}
+	if err := db.UnlockMasterKey("correct horse"); err != nil {
+		t.Fatalf("UnlockMasterKey: %v", err)
+	}
+	if value, err := db.GetEncryptedSetting("deepl_api_key"); err != nil || value != "deepl-secret" {
+		t.Errorf("GetEncryptedSetting = %q, %v", value, err)
+	}
+	if feed, err := db.GetFeedByID(feedID); err != nil || feed.EmailPassword != "imap-secret" {
+		t.Errorf("email password = %q, %v", feed.EmailPassword, err)
+	}
+
+	if _, err := db.RemoveMasterPassphrase(); err != nil {
+		t.Fatalf("RemoveMasterPassphrase: %v", err)
+	}
+	if raw := rawPassword(); !crypto.IsEncrypted(raw) || crypto.KeyID(raw) != "" {
+		t.Errorf("email password not back on the machine key: %q", raw)
+	}
+	if status, err := db.GetEncryptionStatus(); err != nil || status.Mode != "machine" || status.Locked != 0 {
+		t.Errorf("GetEncryptionStatus = %+v, %v", status, err)
+	}
+}
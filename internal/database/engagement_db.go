@@ -0,0 +1,54 @@
+package database
+
+// EngagedArticle is an article the user read, favorited or saved for later,
+// together with the best HTML available for it
+type EngagedArticle struct {
+	ID          int64
+	URL         string
+	FeedURL     string
+	FeedLink    string
+	Content     string
+	IsRead      bool
+	IsFavorite  bool
+	IsReadLater bool
+}
+
+// GetEngagedArticles returns articles the user has engaged with, strongest
+// engagement first. Cached full content is preferred over the feed summary.
+func (db *DB) GetEngagedArticles(limit int) ([]EngagedArticle, error) {
+	db.WaitForReady()
+
+	if limit <= 0 {
+		limit = 500
+	}
+
+	rows, err := db.Query(`
+		SELECT a.id, COALESCE(a.url, ''), COALESCE(f.url, ''), COALESCE(f.link, ''),
+			COALESCE(ac.content, a.original_summary, ''),
+			a.is_read, a.is_favorite, a.is_read_later
+		FROM articles a
+		LEFT JOIN feeds f ON f.id = a.feed_id
+		LEFT JOIN article_contents ac ON ac.article_id = a.id
+		WHERE a.is_read = 1 OR a.is_favorite = 1 OR a.is_read_later = 1
+		ORDER BY a.is_favorite DESC, a.is_read_later DESC, a.published_at DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	articles := make([]EngagedArticle, 0)
+	for rows.Next() {
+		var a EngagedArticle
+		if err := rows.Scan(&a.ID, &a.URL, &a.FeedURL, &a.FeedLink, &a.Content, &a.IsRead, &a.IsFavorite, &a.IsReadLater); err != nil {
+			return nil, err
+		}
+		if a.Content == "" {
+			continue
+		}
+		articles = append(articles, a)
+	}
+
+	return articles, rows.Err()
+}
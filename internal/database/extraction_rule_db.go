@@ -0,0 +1,54 @@
+package database
+
+import "database/sql"
+
+// GetFeedExtractionRule returns the full-text extraction rule chosen for a
+// feed, or "" when the rule is picked by article host.
+func (db *DB) GetFeedExtractionRule(feedID int64) (string, error) {
+	db.WaitForReady()
+
+	var rule string
+	err := db.QueryRow(`SELECT rule FROM feed_extraction_rules WHERE feed_id = ?`, feedID).Scan(&rule)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return rule, err
+}
+
+// SetFeedExtractionRule chooses the full-text extraction rule for a feed.
+// An empty rule restores picking the rule by article host.
+func (db *DB) SetFeedExtractionRule(feedID int64, rule string) error {
+	db.WaitForReady()
+
+	if rule == "" {
+		_, err := db.Exec(`DELETE FROM feed_extraction_rules WHERE feed_id = ?`, feedID)
+		return err
+	}
+	_, err := db.Exec(`
+		INSERT INTO feed_extraction_rules (feed_id, rule) VALUES (?, ?)
+		ON CONFLICT(feed_id) DO UPDATE SET rule = excluded.rule
+	`, feedID, rule)
+	return err
+}
+
+// GetFeedExtractionRules returns the extraction rules chosen for feeds, keyed by feed ID
+func (db *DB) GetFeedExtractionRules() (map[int64]string, error) {
+	db.WaitForReady()
+
+	rows, err := db.Query(`SELECT feed_id, rule FROM feed_extraction_rules`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make(map[int64]string)
+	for rows.Next() {
+		var feedID int64
+		var rule string
+		if err := rows.Scan(&feedID, &rule); err != nil {
+			return nil, err
+		}
+		rules[feedID] = rule
+	}
+	return rules, rows.Err()
+}
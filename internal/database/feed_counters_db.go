@@ -0,0 +1,237 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// feedCounter is one column of feed_counters and the condition an article
+// must meet, written against a row alias ("NEW", "OLD" or the table), to be
+// counted in it. Hidden articles are never counted.
+type feedCounter struct {
+	column    string
+	predicate string
+}
+
+var feedCounters = []feedCounter{
+	{"unread", "%[1]s.is_read = 0 AND %[1]s.is_hidden = 0"},
+	{"favorites", "%[1]s.is_favorite = 1 AND %[1]s.is_hidden = 0"},
+	{"favorites_unread", "%[1]s.is_favorite = 1 AND %[1]s.is_read = 0 AND %[1]s.is_hidden = 0"},
+	{"read_later", "%[1]s.is_read_later = 1 AND %[1]s.is_hidden = 0"},
+	{"read_later_unread", "%[1]s.is_read_later = 1 AND %[1]s.is_read = 0 AND %[1]s.is_hidden = 0"},
+	{"images", "%[1]s.image_url IS NOT NULL AND %[1]s.image_url != '' AND %[1]s.is_hidden = 0"},
+	{"images_unread", "%[1]s.image_url IS NOT NULL AND %[1]s.image_url != '' AND %[1]s.is_read = 0 AND %[1]s.is_hidden = 0"},
+}
+
+// FeedCounts holds the maintained article counts of one feed.
+type FeedCounts struct {
+	Unread          int `json:"unread"`
+	Favorites       int `json:"favorites"`
+	FavoritesUnread int `json:"favorites_unread"`
+	ReadLater       int `json:"read_later"`
+	ReadLaterUnread int `json:"read_later_unread"`
+	Images          int `json:"images"`
+	ImagesUnread    int `json:"images_unread"`
+}
+
+// fields returns pointers to the counts in feedCounters order, for scanning
+func (c *FeedCounts) fields() []any {
+	return []any{&c.Unread, &c.Favorites, &c.FavoritesUnread, &c.ReadLater, &c.ReadLaterUnread, &c.Images, &c.ImagesUnread}
+}
+
+// feedCounterFlags returns one 0/1 expression per counter for the given row
+func feedCounterFlags(alias string) []string {
+	flags := make([]string, len(feedCounters))
+	for i, c := range feedCounters {
+		flags[i] = "CASE WHEN " + fmt.Sprintf(c.predicate, alias) + " THEN 1 ELSE 0 END"
+	}
+	return flags
+}
+
+func feedCounterColumns() string {
+	columns := make([]string, len(feedCounters))
+	for i, c := range feedCounters {
+		columns[i] = c.column
+	}
+	return strings.Join(columns, ", ")
+}
+
+// addFeedCountersSQL adds the counts of an article row to its feed's counters
+func addFeedCountersSQL(alias string) string {
+	sets := make([]string, len(feedCounters))
+	for i, c := range feedCounters {
+		sets[i] = fmt.Sprintf("%[1]s = %[1]s + excluded.%[1]s", c.column)
+	}
+	return fmt.Sprintf(
+		"INSERT INTO feed_counters (feed_id, %[1]s) SELECT %[2]s.feed_id, %[3]s WHERE %[2]s.feed_id IS NOT NULL ON CONFLICT(feed_id) DO UPDATE SET %[4]s;",
+		feedCounterColumns(), alias, strings.Join(feedCounterFlags(alias), ", "), strings.Join(sets, ", "),
+	)
+}
+
+// subtractFeedCountersSQL removes the counts of an article row from its
+// feed's counters. A missing row means the feed is being deleted.
+func subtractFeedCountersSQL(alias string) string {
+	flags := feedCounterFlags(alias)
+	sets := make([]string, len(feedCounters))
+	for i, c := range feedCounters {
+		sets[i] = fmt.Sprintf("%s = %s - (%s)", c.column, c.column, flags[i])
+	}
+	return fmt.Sprintf("UPDATE feed_counters SET %s WHERE feed_id = %s.feed_id;", strings.Join(sets, ", "), alias)
+}
+
+// feedCounterTriggers returns the statements creating the triggers that keep
+// feed_counters in step with every write to articles and feeds
+func feedCounterTriggers() []string {
+	watched := []string{"feed_id", "is_read", "is_favorite", "is_read_later", "is_hidden", "image_url"}
+	changed := make([]string, len(watched))
+	for i, column := range watched {
+		changed[i] = fmt.Sprintf("OLD.%[1]s IS NOT NEW.%[1]s", column)
+	}
+
+	return []string{
+		`CREATE TRIGGER IF NOT EXISTS feed_counters_article_insert AFTER INSERT ON articles BEGIN ` +
+			addFeedCountersSQL("NEW") + ` END`,
+		`CREATE TRIGGER IF NOT EXISTS feed_counters_article_delete AFTER DELETE ON articles BEGIN ` +
+			subtractFeedCountersSQL("OLD") + ` END`,
+		`CREATE TRIGGER IF NOT EXISTS feed_counters_article_update AFTER UPDATE OF ` + strings.Join(watched, ", ") +
+			` ON articles WHEN ` + strings.Join(changed, " OR ") + ` BEGIN ` +
+			subtractFeedCountersSQL("OLD") + " " + addFeedCountersSQL("NEW") + ` END`,
+		`CREATE TRIGGER IF NOT EXISTS feed_counters_feed_delete AFTER DELETE ON feeds BEGIN
+			DELETE FROM feed_counters WHERE feed_id = OLD.id;
+		END`,
+	}
+}
+
+// feedCountersAggregateSQL computes the counters from the articles table
+func feedCountersAggregateSQL() string {
+	sums := feedCounterFlags("articles")
+	for i, flag := range sums {
+		sums[i] = "SUM(" + flag + ") AS " + feedCounters[i].column
+	}
+	return `SELECT feed_id, ` + strings.Join(sums, ", ") + ` FROM articles WHERE feed_id IS NOT NULL GROUP BY feed_id`
+}
+
+// migrateFeedCounters creates feed_counters, its triggers, and fills it in
+func migrateFeedCounters(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+		CREATE TABLE feed_counters (
+			feed_id INTEGER PRIMARY KEY,
+			unread INTEGER NOT NULL DEFAULT 0,
+			favorites INTEGER NOT NULL DEFAULT 0,
+			favorites_unread INTEGER NOT NULL DEFAULT 0,
+			read_later INTEGER NOT NULL DEFAULT 0,
+			read_later_unread INTEGER NOT NULL DEFAULT 0,
+			images INTEGER NOT NULL DEFAULT 0,
+			images_unread INTEGER NOT NULL DEFAULT 0
+		)
+	`); err != nil {
+		return err
+	}
+	if err := createFeedCounterTriggers(tx); err != nil {
+		return err
+	}
+	return rebuildFeedCounters(tx)
+}
+
+func createFeedCounterTriggers(tx *sql.Tx) error {
+	for _, stmt := range feedCounterTriggers() {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuildFeedCounters recomputes every feed's counters from its articles
+func rebuildFeedCounters(tx *sql.Tx) error {
+	if _, err := tx.Exec(`DELETE FROM feed_counters`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT INTO feed_counters (feed_id, ` + feedCounterColumns() + `) ` + feedCountersAggregateSQL())
+	return err
+}
+
+// CheckFeedCounters verifies that the maintained counters match the articles
+// table and rebuilds them if not, also restoring any trigger that is missing
+// (rebuilding the articles table drops its triggers). It reports whether the
+// counters had to be rebuilt. Init runs it on every start.
+func (db *DB) CheckFeedCounters() (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := createFeedCounterTriggers(tx); err != nil {
+		return false, err
+	}
+
+	// Feeds whose stored counters differ from the computed ones, in either
+	// direction; rows with all-zero counts count as absent
+	columns := feedCounterColumns()
+	nonZero := strings.ReplaceAll(columns, ", ", " != 0 OR ") + " != 0"
+	stored := `SELECT feed_id, ` + columns + ` FROM feed_counters WHERE ` + nonZero
+	computed := `SELECT feed_id, ` + columns + ` FROM (` + feedCountersAggregateSQL() + `) WHERE ` + nonZero
+	var mismatched int
+	err = tx.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM (` + stored + ` EXCEPT ` + computed + `))
+			+ (SELECT COUNT(*) FROM (` + computed + ` EXCEPT ` + stored + `))
+	`).Scan(&mismatched)
+	if err != nil {
+		return false, err
+	}
+	if mismatched == 0 {
+		return false, tx.Commit()
+	}
+
+	log.Printf("Feed counters out of date for %d feed(s), rebuilding", mismatched)
+	if err := rebuildFeedCounters(tx); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetFeedCounters returns the maintained article counts of every feed that
+// has any articles, keyed by feed ID.
+func (db *DB) GetFeedCounters() (map[int64]FeedCounts, error) {
+	db.WaitForReady()
+	rows, err := db.Query(`SELECT feed_id, ` + feedCounterColumns() + ` FROM feed_counters`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]FeedCounts)
+	for rows.Next() {
+		var feedID int64
+		var c FeedCounts
+		if err := rows.Scan(append([]any{&feedID}, c.fields()...)...); err != nil {
+			return nil, err
+		}
+		counts[feedID] = c
+	}
+	return counts, rows.Err()
+}
+
+// getFeedCounter returns one counter for every feed where it is non-zero
+func (db *DB) getFeedCounter(column string) (map[int64]int, error) {
+	db.WaitForReady()
+	rows, err := db.Query(fmt.Sprintf(`SELECT feed_id, %[1]s FROM feed_counters WHERE %[1]s > 0`, column))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var feedID int64
+		var count int
+		if err := rows.Scan(&feedID, &count); err != nil {
+			return nil, err
+		}
+		counts[feedID] = count
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,138 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	dbpkg "MrRSS/internal/database"
+)
+
+func TestFeedCountersFollowArticleWrites(t *testing.T) {
+	db := setupDBWithFeed(t)
+
+	var feedID int64
+	if err := db.QueryRow(`SELECT id FROM feeds WHERE url = ?`, "https://example.com/feed").Scan(&feedID); err != nil {
+		t.Fatalf("scan feed id: %v", err)
+	}
+	res, err := db.Exec(`INSERT INTO feeds (title, url, category) VALUES ('Other', 'https://example.org/feed', 'news')`)
+	if err != nil {
+		t.Fatalf("insert feed: %v", err)
+	}
+	otherID, _ := res.LastInsertId()
+
+	insert := func(feedID int64, key, imageURL string) int64 {
+		t.Helper()
+		res, err := db.Exec(
+			`INSERT INTO articles (feed_id, title, url, image_url, published_at, unique_id) VALUES (?, ?, ?, ?, ?, ?)`,
+			feedID, key, "https://example.com/"+key, imageURL, time.Now(), key,
+		)
+		if err != nil {
+			t.Fatalf("insert article %s: %v", key, err)
+		}
+		id, _ := res.LastInsertId()
+		return id
+	}
+	expect := func(step string, feedID int64, want dbpkg.FeedCounts) {
+		t.Helper()
+		counters, err := db.GetFeedCounters()
+		if err != nil {
+			t.Fatalf("%s: GetFeedCounters: %v", step, err)
+		}
+		if got := counters[feedID]; got != want {
+			t.Errorf("%s: counters = %+v, want %+v", step, got, want)
+		}
+	}
+
+	a := insert(feedID, "a", "https://example.com/a.png")
+	b := insert(feedID, "b", "")
+	expect("insert", feedID, dbpkg.FeedCounts{Unread: 2, Images: 1, ImagesUnread: 1})
+
+	if err := db.MarkArticleRead(a, true); err != nil {
+		t.Fatalf("MarkArticleRead: %v", err)
+	}
+	if err := db.SetArticleFavorite(b, true); err != nil {
+		t.Fatalf("SetArticleFavorite: %v", err)
+	}
+	if err := db.SetArticleReadLater(b, true); err != nil {
+		t.Fatalf("SetArticleReadLater: %v", err)
+	}
+	expect("status changes", feedID, dbpkg.FeedCounts{Unread: 1, Favorites: 1, FavoritesUnread: 1, ReadLater: 1, ReadLaterUnread: 1, Images: 1})
+
+	if err := db.SetArticleHidden(b, true); err != nil {
+		t.Fatalf("SetArticleHidden: %v", err)
+	}
+	expect("hide", feedID, dbpkg.FeedCounts{Images: 1})
+
+	if _, err := db.Exec(`UPDATE articles SET feed_id = ? WHERE id = ?`, otherID, a); err != nil {
+		t.Fatalf("move article: %v", err)
+	}
+	expect("move out", feedID, dbpkg.FeedCounts{})
+	expect("move in", otherID, dbpkg.FeedCounts{Images: 1})
+
+	if err := db.SetArticleHidden(b, false); err != nil {
+		t.Fatalf("SetArticleHidden: %v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM articles WHERE id = ?`, b); err != nil {
+		t.Fatalf("delete article: %v", err)
+	}
+	expect("delete", feedID, dbpkg.FeedCounts{})
+
+	total, err := db.GetTotalUnreadCount()
+	if err != nil || total != 0 {
+		t.Errorf("GetTotalUnreadCount = %d, %v, want 0", total, err)
+	}
+
+	if err := db.DeleteFeed(otherID); err != nil {
+		t.Fatalf("DeleteFeed: %v", err)
+	}
+	var rows int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM feed_counters WHERE feed_id = ?`, otherID).Scan(&rows); err != nil || rows != 0 {
+		t.Errorf("counter rows of deleted feed = %d, %v", rows, err)
+	}
+}
+
+func TestCheckFeedCountersRebuilds(t *testing.T) {
+	db := setupDBWithFeed(t)
+
+	var feedID int64
+	if err := db.QueryRow(`SELECT id FROM feeds WHERE url = ?`, "https://example.com/feed").Scan(&feedID); err != nil {
+		t.Fatalf("scan feed id: %v", err)
+	}
+
+	rebuilt, err := db.CheckFeedCounters()
+	if err != nil || rebuilt {
+		t.Fatalf("CheckFeedCounters on consistent counters = %v, %v", rebuilt, err)
+	}
+
+	// Writes made while the triggers are missing leave the counters stale
+	if _, err := db.Exec(`DROP TRIGGER feed_counters_article_insert`); err != nil {
+		t.Fatalf("drop trigger: %v", err)
+	}
+	if _, err := db.Exec(
+		`INSERT INTO articles (feed_id, title, url, published_at, unique_id) VALUES (?, 'x', 'https://example.com/x', ?, 'x')`,
+		feedID, time.Now(),
+	); err != nil {
+		t.Fatalf("insert article: %v", err)
+	}
+
+	rebuilt, err = db.CheckFeedCounters()
+	if err != nil || !rebuilt {
+		t.Fatalf("CheckFeedCounters on stale counters = %v, %v", rebuilt, err)
+	}
+	counts, err := db.GetUnreadCountsForAllFeeds()
+	if err != nil || counts[feedID] != 1 {
+		t.Errorf("unread counts after rebuild = %v, %v", counts, err)
+	}
+
+	// The missing trigger is restored
+	if _, err := db.Exec(
+		`INSERT INTO articles (feed_id, title, url, published_at, unique_id) VALUES (?, 'y', 'https://example.com/y', ?, 'y')`,
+		feedID, time.Now(),
+	); err != nil {
+		t.Fatalf("insert article: %v", err)
+	}
+	counts, err = db.GetUnreadCountsForAllFeeds()
+	if err != nil || counts[feedID] != 2 {
+		t.Errorf("unread counts after restored trigger = %v, %v", counts, err)
+	}
+}
@@ -2,6 +2,8 @@ package database
 
 import (
 	"database/sql"
+	"net/url"
+	"strings"
 	"time"
 
 	"MrRSS/internal/models"
@@ -20,6 +22,7 @@ type FeedUpdateOptions struct {
 	ProxyEnabled        *bool
 	RefreshInterval     *int
 	IsImageMode         *bool
+	AllowPrivateNetwork *bool
 	Type                *string
 	XPathItem           *string
 	XPathItemTitle      *string
@@ -48,10 +51,15 @@ type FeedUpdateOptions struct {
 func (db *DB) AddFeed(feed *models.Feed) (int64, error) {
 	db.WaitForReady()
 
+	emailPassword, err := sealFeedSecret(feed.EmailPassword)
+	if err != nil {
+		return 0, err
+	}
+
 	// Check if feed already exists with same URL AND same source type
 	var existingID int64
 	var existingIsFreshRSS bool
-	err := db.QueryRow("SELECT id, is_freshrss_source FROM feeds WHERE url = ?", feed.URL).Scan(&existingID, &existingIsFreshRSS)
+	err = db.QueryRow("SELECT id, is_freshrss_source FROM feeds WHERE url = ?", feed.URL).Scan(&existingID, &existingIsFreshRSS)
 
 	if err == sql.ErrNoRows {
 		// Feed doesn't exist, insert new
@@ -64,7 +72,7 @@ func (db *DB) AddFeed(feed *models.Feed) (int64, error) {
 			}
 		}
 
-		// 36 columns to insert (added is_freshrss_source and freshrss_stream_id)
+		// 37 columns to insert (added is_freshrss_source and freshrss_stream_id)
 		query := `INSERT INTO feeds (
 			title, url, link, description, category, image_url, position,
 			script_path, hide_from_timeline, proxy_url, proxy_enabled, refresh_interval,
@@ -75,9 +83,9 @@ func (db *DB) AddFeed(feed *models.Feed) (int64, error) {
 			article_view_mode, auto_expand_content,
 			email_address, email_imap_server, email_imap_port,
 			email_username, email_password, email_folder, email_last_uid,
-			is_freshrss_source, freshrss_stream_id,
+			is_freshrss_source, freshrss_stream_id, allow_private_network,
 			last_updated
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 		result, err := db.Exec(query,
 			feed.Title, feed.URL, feed.Link, feed.Description, feed.Category, feed.ImageURL, position,
 			feed.ScriptPath, feed.HideFromTimeline, feed.ProxyURL, feed.ProxyEnabled, feed.RefreshInterval,
@@ -87,8 +95,8 @@ func (db *DB) AddFeed(feed *models.Feed) (int64, error) {
 			feed.XPathItemThumbnail, feed.XPathItemCategories, feed.XPathItemUid,
 			feed.ArticleViewMode, feed.AutoExpandContent,
 			feed.EmailAddress, feed.EmailIMAPServer, feed.EmailIMAPPort,
-			feed.EmailUsername, feed.EmailPassword, feed.EmailFolder, feed.EmailLastUID,
-			feed.IsFreshRSSSource, feed.FreshRSSStreamID,
+			feed.EmailUsername, emailPassword, feed.EmailFolder, feed.EmailLastUID,
+			feed.IsFreshRSSSource, feed.FreshRSSStreamID, feed.AllowPrivateNetwork,
 			time.Now())
 		if err != nil {
 			return 0, err
@@ -125,9 +133,9 @@ func (db *DB) AddFeed(feed *models.Feed) (int64, error) {
 			article_view_mode, auto_expand_content,
 			email_address, email_imap_server, email_imap_port,
 			email_username, email_password, email_folder, email_last_uid,
-			is_freshrss_source, freshrss_stream_id,
+			is_freshrss_source, freshrss_stream_id, allow_private_network,
 			last_updated
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 		result, err := db.Exec(query,
 			feed.Title, feed.URL, feed.Link, feed.Description, feed.Category, feed.ImageURL, position,
 			feed.ScriptPath, feed.HideFromTimeline, feed.ProxyURL, feed.ProxyEnabled, feed.RefreshInterval,
@@ -137,8 +145,8 @@ func (db *DB) AddFeed(feed *models.Feed) (int64, error) {
 			feed.XPathItemThumbnail, feed.XPathItemCategories, feed.XPathItemUid,
 			feed.ArticleViewMode, feed.AutoExpandContent,
 			feed.EmailAddress, feed.EmailIMAPServer, feed.EmailIMAPPort,
-			feed.EmailUsername, feed.EmailPassword, feed.EmailFolder, feed.EmailLastUID,
-			feed.IsFreshRSSSource, feed.FreshRSSStreamID,
+			feed.EmailUsername, emailPassword, feed.EmailFolder, feed.EmailLastUID,
+			feed.IsFreshRSSSource, feed.FreshRSSStreamID, feed.AllowPrivateNetwork,
 			time.Now())
 		if err != nil {
 			return 0, err
@@ -153,7 +161,7 @@ func (db *DB) AddFeed(feed *models.Feed) (int64, error) {
 	// Same URL and same source type - update existing feed
 	// (note: we don't update is_freshrss_source or freshrss_stream_id for existing feeds)
 	query := `UPDATE feeds SET title = ?, link = ?, description = ?, category = ?, image_url = ?, position = ?, script_path = ?, hide_from_timeline = ?, proxy_url = ?, proxy_enabled = ?, refresh_interval = ?, is_image_mode = ?, type = ?, xpath_item = ?, xpath_item_title = ?, xpath_item_content = ?, xpath_item_uri = ?, xpath_item_author = ?, xpath_item_timestamp = ?, xpath_item_time_format = ?, xpath_item_thumbnail = ?, xpath_item_categories = ?, xpath_item_uid = ?, article_view_mode = ?, auto_expand_content = ?, email_address = ?, email_imap_server = ?, email_imap_port = ?, email_username = ?, email_password = ?, email_folder = ?, email_last_uid = ?, last_updated = ? WHERE id = ?`
-	_, err = db.Exec(query, feed.Title, feed.Link, feed.Description, feed.Category, feed.ImageURL, feed.Position, feed.ScriptPath, feed.HideFromTimeline, feed.ProxyURL, feed.ProxyEnabled, feed.RefreshInterval, feed.IsImageMode, feed.Type, feed.XPathItem, feed.XPathItemTitle, feed.XPathItemContent, feed.XPathItemUri, feed.XPathItemAuthor, feed.XPathItemTimestamp, feed.XPathItemTimeFormat, feed.XPathItemThumbnail, feed.XPathItemCategories, feed.XPathItemUid, feed.ArticleViewMode, feed.AutoExpandContent, feed.EmailAddress, feed.EmailIMAPServer, feed.EmailIMAPPort, feed.EmailUsername, feed.EmailPassword, feed.EmailFolder, feed.EmailLastUID, time.Now(), existingID)
+	_, err = db.Exec(query, feed.Title, feed.Link, feed.Description, feed.Category, feed.ImageURL, feed.Position, feed.ScriptPath, feed.HideFromTimeline, feed.ProxyURL, feed.ProxyEnabled, feed.RefreshInterval, feed.IsImageMode, feed.Type, feed.XPathItem, feed.XPathItemTitle, feed.XPathItemContent, feed.XPathItemUri, feed.XPathItemAuthor, feed.XPathItemTimestamp, feed.XPathItemTimeFormat, feed.XPathItemThumbnail, feed.XPathItemCategories, feed.XPathItemUid, feed.ArticleViewMode, feed.AutoExpandContent, feed.EmailAddress, feed.EmailIMAPServer, feed.EmailIMAPPort, feed.EmailUsername, emailPassword, feed.EmailFolder, feed.EmailLastUID, time.Now(), existingID)
 	return existingID, err
 }
 
@@ -191,7 +199,7 @@ func (db *DB) GetFeeds() ([]models.Feed, error) {
 			COALESCE(f.email_imap_port, 993), COALESCE(f.email_username, ''),
 			COALESCE(f.email_password, ''), COALESCE(f.email_folder, 'INBOX'),
 			COALESCE(f.email_last_uid, 0), COALESCE(f.is_freshrss_source, 0),
-			COALESCE(f.freshrss_stream_id, ''),
+			COALESCE(f.freshrss_stream_id, ''), COALESCE(f.allow_private_network, 0),
 			(SELECT MAX(a.published_at) FROM articles a WHERE a.feed_id = f.id) as latest_article_time,
 			CAST(COALESCE((
 				SELECT
@@ -233,7 +241,7 @@ func (db *DB) GetFeeds() ([]models.Feed, error) {
 			&xpathItemThumbnail, &xpathItemCategories, &xpathItemUid, &articleViewMode,
 			&autoExpandContent, &emailAddress, &emailIMAPServer, &f.EmailIMAPPort,
 			&emailUsername, &emailPassword, &emailFolder, &f.EmailLastUID,
-			&f.IsFreshRSSSource, &freshRSSStreamID, &latestArticleTimeStr, &f.ArticlesPerMonth,
+			&f.IsFreshRSSSource, &freshRSSStreamID, &f.AllowPrivateNetwork, &latestArticleTimeStr, &f.ArticlesPerMonth,
 		); err != nil {
 			return nil, err
 		}
@@ -274,7 +282,7 @@ func (db *DB) GetFeeds() ([]models.Feed, error) {
 		f.EmailAddress = emailAddress.String
 		f.EmailIMAPServer = emailIMAPServer.String
 		f.EmailUsername = emailUsername.String
-		f.EmailPassword = emailPassword.String
+		f.EmailPassword = openFeedSecret(emailPassword.String)
 		f.EmailFolder = emailFolder.String
 		if f.EmailFolder == "" {
 			f.EmailFolder = "INBOX"
@@ -325,12 +333,12 @@ func (db *DB) GetFeeds() ([]models.Feed, error) {
 // GetFeedByID retrieves a specific feed by its ID.
 func (db *DB) GetFeedByID(id int64) (*models.Feed, error) {
 	db.WaitForReady()
-	row := db.QueryRow("SELECT id, title, url, link, description, category, image_url, COALESCE(position, 0), last_updated, last_error, COALESCE(discovery_completed, 0), COALESCE(script_path, ''), COALESCE(hide_from_timeline, 0), COALESCE(proxy_url, ''), COALESCE(proxy_enabled, 0), COALESCE(refresh_interval, 0), COALESCE(is_image_mode, 0), COALESCE(type, ''), COALESCE(xpath_item, ''), COALESCE(xpath_item_title, ''), COALESCE(xpath_item_content, ''), COALESCE(xpath_item_uri, ''), COALESCE(xpath_item_author, ''), COALESCE(xpath_item_timestamp, ''), COALESCE(xpath_item_time_format, ''), COALESCE(xpath_item_thumbnail, ''), COALESCE(xpath_item_categories, ''), COALESCE(xpath_item_uid, ''), COALESCE(article_view_mode, 'global'), COALESCE(auto_expand_content, 'global'), COALESCE(email_address, ''), COALESCE(email_imap_server, ''), COALESCE(email_imap_port, 993), COALESCE(email_username, ''), COALESCE(email_password, ''), COALESCE(email_folder, 'INBOX'), COALESCE(email_last_uid, 0), COALESCE(is_freshrss_source, 0), COALESCE(freshrss_stream_id, '') FROM feeds WHERE id = ?", id)
+	row := db.QueryRow("SELECT id, title, url, link, description, category, image_url, COALESCE(position, 0), last_updated, last_error, COALESCE(discovery_completed, 0), COALESCE(script_path, ''), COALESCE(hide_from_timeline, 0), COALESCE(proxy_url, ''), COALESCE(proxy_enabled, 0), COALESCE(refresh_interval, 0), COALESCE(is_image_mode, 0), COALESCE(type, ''), COALESCE(xpath_item, ''), COALESCE(xpath_item_title, ''), COALESCE(xpath_item_content, ''), COALESCE(xpath_item_uri, ''), COALESCE(xpath_item_author, ''), COALESCE(xpath_item_timestamp, ''), COALESCE(xpath_item_time_format, ''), COALESCE(xpath_item_thumbnail, ''), COALESCE(xpath_item_categories, ''), COALESCE(xpath_item_uid, ''), COALESCE(article_view_mode, 'global'), COALESCE(auto_expand_content, 'global'), COALESCE(email_address, ''), COALESCE(email_imap_server, ''), COALESCE(email_imap_port, 993), COALESCE(email_username, ''), COALESCE(email_password, ''), COALESCE(email_folder, 'INBOX'), COALESCE(email_last_uid, 0), COALESCE(is_freshrss_source, 0), COALESCE(freshrss_stream_id, ''), COALESCE(allow_private_network, 0) FROM feeds WHERE id = ?", id)
 
 	var f models.Feed
 	var link, category, imageURL, lastError, scriptPath, proxyURL, feedType, xpathItem, xpathItemTitle, xpathItemContent, xpathItemUri, xpathItemAuthor, xpathItemTimestamp, xpathItemTimeFormat, xpathItemThumbnail, xpathItemCategories, xpathItemUid, articleViewMode, autoExpandContent, emailAddress, emailIMAPServer, emailUsername, emailPassword, emailFolder, freshRSSStreamID sql.NullString
 	var lastUpdated sql.NullTime
-	if err := row.Scan(&f.ID, &f.Title, &f.URL, &link, &f.Description, &category, &imageURL, &f.Position, &lastUpdated, &lastError, &f.DiscoveryCompleted, &scriptPath, &f.HideFromTimeline, &proxyURL, &f.ProxyEnabled, &f.RefreshInterval, &f.IsImageMode, &feedType, &xpathItem, &xpathItemTitle, &xpathItemContent, &xpathItemUri, &xpathItemAuthor, &xpathItemTimestamp, &xpathItemTimeFormat, &xpathItemThumbnail, &xpathItemCategories, &xpathItemUid, &articleViewMode, &autoExpandContent, &emailAddress, &emailIMAPServer, &f.EmailIMAPPort, &emailUsername, &emailPassword, &emailFolder, &f.EmailLastUID, &f.IsFreshRSSSource, &freshRSSStreamID); err != nil {
+	if err := row.Scan(&f.ID, &f.Title, &f.URL, &link, &f.Description, &category, &imageURL, &f.Position, &lastUpdated, &lastError, &f.DiscoveryCompleted, &scriptPath, &f.HideFromTimeline, &proxyURL, &f.ProxyEnabled, &f.RefreshInterval, &f.IsImageMode, &feedType, &xpathItem, &xpathItemTitle, &xpathItemContent, &xpathItemUri, &xpathItemAuthor, &xpathItemTimestamp, &xpathItemTimeFormat, &xpathItemThumbnail, &xpathItemCategories, &xpathItemUid, &articleViewMode, &autoExpandContent, &emailAddress, &emailIMAPServer, &f.EmailIMAPPort, &emailUsername, &emailPassword, &emailFolder, &f.EmailLastUID, &f.IsFreshRSSSource, &freshRSSStreamID, &f.AllowPrivateNetwork); err != nil {
 		return nil, err
 	}
 	f.Link = link.String
@@ -366,7 +374,7 @@ func (db *DB) GetFeedByID(id int64) (*models.Feed, error) {
 	f.EmailAddress = emailAddress.String
 	f.EmailIMAPServer = emailIMAPServer.String
 	f.EmailUsername = emailUsername.String
-	f.EmailPassword = emailPassword.String
+	f.EmailPassword = openFeedSecret(emailPassword.String)
 	f.EmailFolder = emailFolder.String
 	if f.EmailFolder == "" {
 		f.EmailFolder = "INBOX"
@@ -399,6 +407,48 @@ func (db *DB) GetAllFeedURLs() (map[string]bool, error) {
 	return urls, rows.Err()
 }
 
+// GetPrivateNetworkHosts returns the lower-cased hostnames of the feed and
+// site URLs of feeds allowed to reach private network addresses.
+func (db *DB) GetPrivateNetworkHosts() (map[string]bool, error) {
+	db.WaitForReady()
+	rows, err := db.Query("SELECT url, COALESCE(link, '') FROM feeds WHERE allow_private_network = 1")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hosts := make(map[string]bool)
+	for rows.Next() {
+		var feedURL, link string
+		if err := rows.Scan(&feedURL, &link); err != nil {
+			return nil, err
+		}
+		for _, raw := range []string{feedURL, link} {
+			if u, err := url.Parse(raw); err == nil && u.Hostname() != "" {
+				hosts[strings.ToLower(u.Hostname())] = true
+			}
+		}
+	}
+	return hosts, rows.Err()
+}
+
+// GetOrCreateBuiltinFeed returns the ID of a built-in feed such as the
+// Imported or Saved pages feed, looked up by its URL, and creates it from
+// feed when it does not exist yet.
+func (db *DB) GetOrCreateBuiltinFeed(feed *models.Feed) (int64, error) {
+	db.WaitForReady()
+
+	var id int64
+	err := db.QueryRow("SELECT id FROM feeds WHERE url = ? AND COALESCE(is_freshrss_source, 0) = 0", feed.URL).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+	return db.AddFeed(feed)
+}
+
 // UpdateFeedWithOptions updates a feed using the provided options.
 // Only non-nil fields in opts will be updated.
 func (db *DB) UpdateFeedWithOptions(id int64, opts FeedUpdateOptions) error {
@@ -448,6 +498,10 @@ func (db *DB) UpdateFeedWithOptions(id int64, opts FeedUpdateOptions) error {
 		setParts = append(setParts, "is_image_mode = ?")
 		args = append(args, *opts.IsImageMode)
 	}
+	if opts.AllowPrivateNetwork != nil {
+		setParts = append(setParts, "allow_private_network = ?")
+		args = append(args, *opts.AllowPrivateNetwork)
+	}
 	if opts.Type != nil {
 		setParts = append(setParts, "type = ?")
 		args = append(args, *opts.Type)
@@ -513,8 +567,12 @@ func (db *DB) UpdateFeedWithOptions(id int64, opts FeedUpdateOptions) error {
 		args = append(args, *opts.EmailUsername)
 	}
 	if opts.EmailPassword != nil {
+		emailPassword, err := sealFeedSecret(*opts.EmailPassword)
+		if err != nil {
+			return err
+		}
 		setParts = append(setParts, "email_password = ?")
-		args = append(args, *opts.EmailPassword)
+		args = append(args, emailPassword)
 	}
 	if opts.EmailFolder != nil {
 		setParts = append(setParts, "email_folder = ?")
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"MrRSS/internal/models"
+)
+
+// maxFetchHistoryPerFeed is the number of fetch records kept for each feed
+const maxFetchHistoryPerFeed = 50
+
+// AddFeedFetchRecord stores a fetch attempt and trims the feed's history
+// to the most recent maxFetchHistoryPerFeed entries.
+func (db *DB) AddFeedFetchRecord(record *models.FeedFetchRecord) error {
+	db.WaitForReady()
+
+	result, err := db.Exec(`
+		INSERT INTO feed_fetch_history (feed_id, fetched_at, success, error, duration_ms, served_by)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, record.FeedID, record.FetchedAt, record.Success, record.Error, record.DurationMs, record.ServedBy)
+	if err != nil {
+		return err
+	}
+
+	if id, err := result.LastInsertId(); err == nil {
+		record.ID = id
+	}
+
+	_, err = db.Exec(`
+		DELETE FROM feed_fetch_history
+		WHERE feed_id = ? AND id NOT IN (
+			SELECT id FROM feed_fetch_history
+			WHERE feed_id = ?
+			ORDER BY fetched_at DESC, id DESC
+			LIMIT ?
+		)
+	`, record.FeedID, record.FeedID, maxFetchHistoryPerFeed)
+	return err
+}
+
+// GetFeedFetchHistory returns the most recent fetch attempts for a feed, newest first
+func (db *DB) GetFeedFetchHistory(feedID int64, limit int) ([]models.FeedFetchRecord, error) {
+	db.WaitForReady()
+
+	if limit <= 0 || limit > maxFetchHistoryPerFeed {
+		limit = maxFetchHistoryPerFeed
+	}
+
+	rows, err := db.Query(`
+		SELECT id, feed_id, fetched_at, success, COALESCE(error, ''), COALESCE(duration_ms, 0), COALESCE(served_by, '')
+		FROM feed_fetch_history
+		WHERE feed_id = ?
+		ORDER BY fetched_at DESC, id DESC
+		LIMIT ?
+	`, feedID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]models.FeedFetchRecord, 0)
+	for rows.Next() {
+		var r models.FeedFetchRecord
+		if err := rows.Scan(&r.ID, &r.FeedID, &r.FetchedAt, &r.Success, &r.Error, &r.DurationMs, &r.ServedBy); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
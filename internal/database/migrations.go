@@ -1,354 +1,512 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"os"
 	"strings"
 )
 
-// runMigrations applies database migrations for existing databases.
-// This ensures all columns and tables exist as the schema evolves.
-func runMigrations(db *sql.DB) error {
-	// Migration: Add content and is_hidden columns if they don't exist
-	// SQLite doesn't support IF NOT EXISTS for ALTER TABLE, so we ignore errors if columns already exist
-	_, _ = db.Exec(`ALTER TABLE articles ADD COLUMN content TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE articles ADD COLUMN is_hidden BOOLEAN DEFAULT 0`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN last_error TEXT DEFAULT ''`)
+// migration is one numbered step of the database schema. Each migration runs
+// once, inside its own transaction, and is recorded in schema_version.
+// Released migrations must never be edited or renumbered; schema changes are
+// made by appending a new migration to the list.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+	// present reports whether a database created before schema versioning
+	// already contains this migration's changes, in which case the migration
+	// is recorded as applied without running it
+	present func(tx *sql.Tx) (bool, error)
+}
 
-	// Migration: Add is_read_later column for read later feature
-	_, _ = db.Exec(`ALTER TABLE articles ADD COLUMN is_read_later BOOLEAN DEFAULT 0`)
+// migrations is the ordered schema history. The first migration is the
+// baseline, which also upgrades databases created before versioning; later
+// migrations that such databases may already contain detect it with present.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "baseline",
+		up:      migrateBaseline,
+	},
+	{
+		// Outcome of each fetch attempt, per feed
+		version: 2,
+		name:    "feed_fetch_history",
+		up: execMigration(`
+			CREATE TABLE feed_fetch_history (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				feed_id INTEGER NOT NULL,
+				fetched_at DATETIME NOT NULL,
+				success BOOLEAN DEFAULT 0,
+				error TEXT DEFAULT '',
+				duration_ms INTEGER DEFAULT 0,
+				served_by TEXT DEFAULT '',
+				FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+			);
+			CREATE INDEX idx_feed_fetch_history_feed ON feed_fetch_history(feed_id, fetched_at DESC);
+		`),
+		present: tablePresent("feed_fetch_history"),
+	},
+	{
+		// Remote OPML files kept in sync, their feeds and a change log
+		version: 3,
+		name:    "subscription_lists",
+		up: execMigration(`
+			CREATE TABLE subscription_lists (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				url TEXT NOT NULL UNIQUE,
+				title TEXT DEFAULT '',
+				category TEXT NOT NULL,
+				refresh_interval INTEGER DEFAULT 360,
+				last_synced DATETIME,
+				last_error TEXT DEFAULT '',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE subscription_list_feeds (
+				list_id INTEGER NOT NULL,
+				feed_id INTEGER NOT NULL UNIQUE,
+				FOREIGN KEY(list_id) REFERENCES subscription_lists(id) ON DELETE CASCADE,
+				FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+			);
+			CREATE INDEX idx_subscription_list_feeds_list ON subscription_list_feeds(list_id);
+			CREATE TABLE subscription_list_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				list_id INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				action TEXT NOT NULL,
+				feed_url TEXT DEFAULT '',
+				feed_title TEXT DEFAULT '',
+				message TEXT DEFAULT '',
+				FOREIGN KEY(list_id) REFERENCES subscription_lists(id) ON DELETE CASCADE
+			);
+			CREATE INDEX idx_subscription_list_log_list ON subscription_list_log(list_id, created_at DESC);
+		`),
+		present: tablePresent("subscription_list_log"),
+	},
+	{
+		// Per-feed choice of full-text extraction rule (site config)
+		version: 4,
+		name:    "feed_extraction_rules",
+		up: execMigration(`
+			CREATE TABLE feed_extraction_rules (
+				feed_id INTEGER PRIMARY KEY,
+				rule TEXT NOT NULL,
+				FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+			);
+		`),
+		present: tablePresent("feed_extraction_rules"),
+	},
+	{
+		// Offline archives of articles (self-contained HTML files in the data dir)
+		version: 5,
+		name:    "article_archives",
+		up: execMigration(`
+			CREATE TABLE article_archives (
+				article_id INTEGER PRIMARY KEY,
+				status TEXT NOT NULL DEFAULT 'pending',
+				size INTEGER NOT NULL DEFAULT 0,
+				error TEXT NOT NULL DEFAULT '',
+				attempts INTEGER NOT NULL DEFAULT 0,
+				queued_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				archived_at DATETIME,
+				FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE
+			);
+			CREATE INDEX idx_article_archives_status ON article_archives(status, queued_at);
+		`),
+		present: tablePresent("article_archives"),
+	},
+	{
+		// Full-text extractions fetched ahead of time for offline reading
+		version: 6,
+		name:    "article_fulltexts",
+		up: execMigration(`
+			CREATE TABLE article_fulltexts (
+				article_id INTEGER PRIMARY KEY,
+				content TEXT NOT NULL,
+				fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE
+			);
+		`),
+		present: tablePresent("article_fulltexts"),
+	},
+	{
+		// Per-feed opt-in to fetching from private network addresses
+		version: 7,
+		name:    "feeds_allow_private_network",
+		up:      execMigration(`ALTER TABLE feeds ADD COLUMN allow_private_network BOOLEAN DEFAULT 0`),
+		present: columnPresent("feeds", "allow_private_network"),
+	},
+	{
+		// Per-feed unread/favorite/read-later/image counts kept by triggers
+		version: 8,
+		name:    "feed_counters",
+		up:      migrateFeedCounters,
+		present: tablePresent("feed_counters"),
+	},
+	{
+		// Retention policies for feeds, categories and tags, and the feeds
+		// they govern, which global cleanup leaves alone
+		version: 9,
+		name:    "retention_policies",
+		up: execMigration(`
+			CREATE TABLE retention_policies (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				scope TEXT NOT NULL CHECK (scope IN ('feed', 'category', 'tag')),
+				feed_id INTEGER,
+				category TEXT,
+				tag_id INTEGER,
+				keep_newest INTEGER NOT NULL DEFAULT 0,
+				max_age_days INTEGER NOT NULL DEFAULT 0,
+				keep_unread BOOLEAN NOT NULL DEFAULT 0,
+				keep_favorites BOOLEAN NOT NULL DEFAULT 1,
+				keep_read_later BOOLEAN NOT NULL DEFAULT 1,
+				enabled BOOLEAN NOT NULL DEFAULT 1,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
+				FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE
+			);
+			CREATE UNIQUE INDEX idx_retention_policies_scope
+				ON retention_policies(scope, COALESCE(feed_id, 0), COALESCE(category, ''), COALESCE(tag_id, 0));
+			CREATE VIEW retention_policy_feeds AS
+				SELECT f.id AS feed_id FROM feeds f
+				WHERE EXISTS (
+					SELECT 1 FROM retention_policies p
+					WHERE p.enabled = 1 AND (
+						(p.scope = 'feed' AND p.feed_id = f.id)
+						OR (p.scope = 'category' AND p.category = COALESCE(f.category, ''))
+						OR (p.scope = 'tag' AND p.tag_id IN (SELECT tag_id FROM feed_tags WHERE feed_id = f.id))
+					)
+				);
+		`),
+		present: tablePresent("retention_policies"),
+	},
+	{
+		// Feeds the user unsubscribed from, which subscription lists must
+		// not add again
+		version: 10,
+		name:    "subscription_list_exclusions",
+		up: execMigration(`
+			CREATE TABLE subscription_list_exclusions (
+				list_id INTEGER NOT NULL,
+				url TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY(list_id, url),
+				FOREIGN KEY(list_id) REFERENCES subscription_lists(id) ON DELETE CASCADE
+			);
+		`),
+		present: tablePresent("subscription_list_exclusions"),
+	},
+	{
+		// Category policies also govern the feeds of subcategories. The view
+		// is rebuilt in place, so the migration can safely run again on
+		// databases from before versioning.
+		version: 11,
+		name:    "retention_policy_subcategories",
+		up: execMigration(`
+			DROP VIEW IF EXISTS retention_policy_feeds;
+			CREATE VIEW retention_policy_feeds AS
+				SELECT f.id AS feed_id FROM feeds f
+				WHERE EXISTS (
+					SELECT 1 FROM retention_policies p
+					WHERE p.enabled = 1 AND (
+						(p.scope = 'feed' AND p.feed_id = f.id)
+						OR (p.scope = 'category' AND (
+							p.category = COALESCE(f.category, '')
+							OR substr(COALESCE(f.category, ''), 1, length(p.category) + 1) = p.category || '/'))
+						OR (p.scope = 'tag' AND p.tag_id IN (SELECT tag_id FROM feed_tags WHERE feed_id = f.id))
+					)
+				);
+		`),
+	},
+}
 
-	// Migration: Add audio_url column for podcast support
-	_, _ = db.Exec(`ALTER TABLE articles ADD COLUMN audio_url TEXT DEFAULT ''`)
+// ErrSchemaTooNew is returned when the database was migrated by a newer
+// release than the running one. Opening it could corrupt data the newer
+// release relies on.
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of MrRSS supports")
 
-	// Migration: Add video_url column for YouTube video support
-	_, _ = db.Exec(`ALTER TABLE articles ADD COLUMN video_url TEXT DEFAULT ''`)
+// runMigrations brings the database schema up to date. Pending migrations run
+// in order on a single connection with foreign key enforcement off, so that
+// table rebuilds do not cascade, and each is checked for foreign key
+// violations before it commits. An existing database is backed up to a file
+// next to it first.
+func runMigrations(db *sql.DB, list []migration) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
 
-	// Migration: Add XPath support fields to feeds table
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN type TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN xpath_item TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN xpath_item_title TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN xpath_item_content TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN xpath_item_uri TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN xpath_item_author TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN xpath_item_timestamp TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN xpath_item_time_format TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN xpath_item_thumbnail TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN xpath_item_categories TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN xpath_item_uid TEXT DEFAULT ''`)
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("create schema_version table: %w", err)
+	}
 
-	// Migration: Add summary column for caching AI-generated summaries
-	_, _ = db.Exec(`ALTER TABLE articles ADD COLUMN summary TEXT DEFAULT ''`)
+	var current int
+	if err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&current); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	latest := list[len(list)-1].version
+	if current > latest {
+		return fmt.Errorf("%w: database is at schema version %d, this build knows up to %d", ErrSchemaTooNew, current, latest)
+	}
+	if current == latest {
+		return nil
+	}
 
-	// Migration: Add original_summary column for RSS-provided summaries/descriptions
-	_, _ = db.Exec(`ALTER TABLE articles ADD COLUMN original_summary TEXT DEFAULT ''`)
+	var legacy bool
+	if current == 0 {
+		if err := conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'feeds')`).Scan(&legacy); err != nil {
+			return fmt.Errorf("inspect database: %w", err)
+		}
+	}
 
-	// Migration: Add article_contents table for caching article content
-	// This uses a separate table to keep the articles table lightweight
-	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS article_contents (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		article_id INTEGER NOT NULL UNIQUE,
-		content TEXT NOT NULL,
-		fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE
-	)`)
-	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_article_contents_article_id ON article_contents(article_id)`)
+	backupPath := ""
+	if current > 0 || legacy {
+		if backupPath, err = backupBeforeMigration(ctx, conn, current); err != nil {
+			return fmt.Errorf("back up database before migrating: %w", err)
+		}
+		if backupPath != "" {
+			log.Printf("Backed up database to %s before migrating from schema version %d", backupPath, current)
+		}
+	}
 
-	// Migration: Add chat_sessions and chat_messages tables for AI chat feature
-	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS chat_sessions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		article_id INTEGER NOT NULL,
-		title TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE
-	)`)
-	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS chat_messages (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		session_id INTEGER NOT NULL,
-		role TEXT NOT NULL,
-		content TEXT NOT NULL,
-		thinking TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY(session_id) REFERENCES chat_sessions(id) ON DELETE CASCADE
-	)`)
-	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_chat_sessions_article_id ON chat_sessions(article_id)`)
-	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_chat_sessions_updated_at ON chat_sessions(updated_at DESC)`)
-	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_chat_messages_session_id ON chat_messages(session_id)`)
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer func() { _, _ = conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`) }()
 
-	// Migration: Add newsletter/email support fields to feeds table
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN email_address TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN email_imap_server TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN email_imap_port INTEGER DEFAULT 993`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN email_username TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN email_password TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN email_folder TEXT DEFAULT 'INBOX'`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN email_last_uid INTEGER DEFAULT 0`)
+	if legacy {
+		log.Printf("Database predates schema versioning, detecting applied migrations")
+	}
 
-	// Migration: Add FreshRSS integration fields
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN is_freshrss_source BOOLEAN DEFAULT 0`)
-	_, _ = db.Exec(`ALTER TABLE feeds ADD COLUMN freshrss_stream_id TEXT DEFAULT ''`)
-	_, _ = db.Exec(`ALTER TABLE articles ADD COLUMN freshrss_item_id TEXT DEFAULT ''`)
+	for _, m := range list {
+		if m.version <= current {
+			continue
+		}
+		err := inMigrationTx(ctx, conn, m, func(tx *sql.Tx) error {
+			// Databases from before versioning are upgraded by the baseline
+			// and already contain the changes of some later migrations
+			if legacy && m.present != nil {
+				found, err := m.present(tx)
+				if err != nil || found {
+					return err
+				}
+			}
+			log.Printf("Applying schema migration %d (%s)", m.version, m.name)
+			return m.up(tx)
+		})
+		if err != nil {
+			return migrationError(m, err, current, backupPath)
+		}
+		current = m.version
+	}
+	return nil
+}
 
-	// Migration: Add author field to articles table
-	_, _ = db.Exec(`ALTER TABLE articles ADD COLUMN author TEXT DEFAULT ''`)
+// inMigrationTx runs fn in a transaction on conn and records m as applied if
+// fn succeeds and the schema has no foreign key violations
+func inMigrationTx(ctx context.Context, conn *sql.Conn, m migration, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
 
-	// Migration: Add saved_filters table for custom filter persistence
-	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS saved_filters (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL UNIQUE,
-		conditions TEXT NOT NULL,
-		position INTEGER DEFAULT 0,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	)`)
-	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_saved_filters_position ON saved_filters(position)`)
+	if err := fn(tx); err != nil {
+		return err
+	}
 
-	// Migration: Add tags table for feed tagging feature
-	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS tags (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL UNIQUE,
-		color TEXT NOT NULL DEFAULT '#3B82F6',
-		position INTEGER DEFAULT 0
-	)`)
-	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_tags_position ON tags(position)`)
+	rows, err := tx.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return err
+	}
+	var violation struct {
+		table  string
+		rowid  sql.NullInt64
+		parent string
+		fkid   int
+	}
+	hasViolation := rows.Next()
+	if hasViolation {
+		err = rows.Scan(&violation.table, &violation.rowid, &violation.parent, &violation.fkid)
+	}
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	if hasViolation {
+		return fmt.Errorf("foreign key violation in %s (row %d) referencing %s", violation.table, violation.rowid.Int64, violation.parent)
+	}
 
-	// Migration: Add feed_tags junction table for many-to-many relationship
-	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS feed_tags (
-		feed_id INTEGER NOT NULL,
-		tag_id INTEGER NOT NULL,
-		PRIMARY KEY (feed_id, tag_id),
-		FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
-		FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
-	)`)
-	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_feed_tags_feed_id ON feed_tags(feed_id)`)
-	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_feed_tags_tag_id ON feed_tags(tag_id)`)
+	if _, err := tx.Exec(`INSERT INTO schema_version (version, name) VALUES (?, ?)`, m.version, m.name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	// Migration: Add ai_profiles table for multiple AI configuration support
-	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS ai_profiles (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		api_key TEXT DEFAULT '',
-		endpoint TEXT NOT NULL,
-		model TEXT NOT NULL,
-		custom_headers TEXT DEFAULT '',
-		is_default BOOLEAN DEFAULT 0,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	)`)
-	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_ai_profiles_is_default ON ai_profiles(is_default)`)
+// migrationError describes a failed migration and where to find the backup
+func migrationError(m migration, err error, current int, backupPath string) error {
+	msg := fmt.Sprintf("schema migration %d (%s) failed, database left at schema version %d", m.version, m.name, current)
+	if backupPath != "" {
+		msg += ", backup saved to " + backupPath
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
 
-	return nil
+// backupBeforeMigration copies the database to <db file>.v<version>.bak with
+// VACUUM INTO, replacing an earlier backup of the same version. It returns
+// an empty path for in-memory databases, which have no file to back up.
+func backupBeforeMigration(ctx context.Context, conn *sql.Conn, version int) (string, error) {
+	var path string
+	rows, err := conn.QueryContext(ctx, `PRAGMA database_list`)
+	if err != nil {
+		return "", err
+	}
+	for rows.Next() {
+		var seq int
+		var name, file string
+		if err := rows.Scan(&seq, &name, &file); err != nil {
+			rows.Close()
+			return "", err
+		}
+		if name == "main" {
+			path = file
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", nil
+	}
+
+	backupPath := fmt.Sprintf("%s.v%d.bak", path, version)
+	if err := os.Remove(backupPath); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if _, err := conn.ExecContext(ctx, `VACUUM INTO ?`, backupPath); err != nil {
+		return "", err
+	}
+	return backupPath, nil
 }
 
-// migrateUniqueIDOnArticles adds unique_id column and generates values for existing articles.
-// This replaces URL-based deduplication with title+feed_id+published_date based deduplication.
-func migrateUniqueIDOnArticles(db *sql.DB) error {
-	// Migration: Add unique_id column to articles table for better deduplication
-	_, _ = db.Exec(`ALTER TABLE articles ADD COLUMN unique_id TEXT UNIQUE`)
+// execMigration returns a migration step that executes the given statements
+func execMigration(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
 
-	// Migration: Migrate existing articles to generate unique_id
-	// For existing articles, generate unique_id from title+feed_id+published_date (date only, not full timestamp)
-	// If url was UNIQUE before, keep it but unique_id is now the primary deduplication key
-	_, err := db.Exec(`
-		UPDATE articles
-		SET unique_id = LOWER(HEX(MD5(title || '|' || feed_id || '|' || COALESCE(strftime('%Y-%m-%d', published_at), ''))))
-		WHERE unique_id IS NULL
-	`)
-	if err != nil {
-		log.Printf("Warning: Failed to migrate unique_id: %v", err)
+// tablePresent returns a check for whether a table exists
+func tablePresent(table string) func(tx *sql.Tx) (bool, error) {
+	return func(tx *sql.Tx) (bool, error) {
+		var found bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)`, table).Scan(&found)
+		return found, err
+	}
+}
+
+// columnPresent returns a check for whether a table has a column
+func columnPresent(table, column string) func(tx *sql.Tx) (bool, error) {
+	return func(tx *sql.Tx) (bool, error) {
+		return columnExists(tx, table, column)
 	}
+}
 
-	// Backfill published_at for articles that have NULL values
-	// Set to current time as fallback (article creation time is unknown)
-	result, err := db.Exec(`
-		UPDATE articles
-		SET published_at = datetime('now')
-		WHERE published_at IS NULL
-	`)
+// columnExists reports whether a table has a column
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	columns, err := tableColumns(tx, table)
 	if err != nil {
-		log.Printf("Warning: Failed to backfill published_at: %v", err)
-	} else {
-		rowsAffected, _ := result.RowsAffected()
-		if rowsAffected > 0 {
-			log.Printf("Backfilled published_at for %d articles", rowsAffected)
+		return false, err
+	}
+	for _, c := range columns {
+		if strings.EqualFold(c, column) {
+			return true, nil
 		}
 	}
-
-	return nil
+	return false, nil
 }
 
-// migrateDropUniqueConstraintOnArticles drops the UNIQUE constraint on url column from articles table.
-// This allows multiple articles with the same URL (e.g., from different feeds).
-func migrateDropUniqueConstraintOnArticles(db *sql.DB) error {
-	// Migration: Drop the UNIQUE constraint on url column if it exists
-	// SQLite doesn't support DROP CONSTRAINT directly, so we need to recreate the table
-	// Check if we need to migrate by checking if url is still UNIQUE
-	var tableInfo string
-	_ = db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='articles'").Scan(&tableInfo)
-	if strings.Contains(tableInfo, "url TEXT UNIQUE") {
-		// Need to recreate table without UNIQUE constraint on url
-		_, err := db.Exec(`
-			CREATE TABLE articles_new (
-				id INTEGER PRIMARY KEY AUTOINCREMENT,
-				feed_id INTEGER,
-				title TEXT,
-				url TEXT,
-				image_url TEXT,
-				audio_url TEXT DEFAULT '',
-				video_url TEXT DEFAULT '',
-				translated_title TEXT,
-				published_at DATETIME,
-				is_read BOOLEAN DEFAULT 0,
-				is_favorite BOOLEAN DEFAULT 0,
-				is_hidden BOOLEAN DEFAULT 0,
-				is_read_later BOOLEAN DEFAULT 0,
-				summary TEXT DEFAULT '',
-				original_summary TEXT DEFAULT '',
-				unique_id TEXT UNIQUE,
-				FOREIGN KEY(feed_id) REFERENCES feeds(id)
-			)
-		`)
-		if err == nil {
-			// Copy data from old table to new table
-			_, _ = db.Exec(`
-				INSERT INTO articles_new (id, feed_id, title, url, image_url, audio_url, video_url, translated_title, published_at, is_read, is_favorite, is_hidden, is_read_later, summary, original_summary, unique_id)
-				SELECT id, feed_id, title, url, image_url, audio_url, video_url, translated_title, published_at, is_read, is_favorite, is_hidden, is_read_later,
-					COALESCE(summary, '') as summary,
-					COALESCE(original_summary, '') as original_summary,
-					LOWER(HEX(MD5(title || '|' || feed_id || '|' || COALESCE(strftime('%Y-%m-%d', published_at), '')))) as unique_id
-				FROM articles
-			`)
-			// Drop old table and rename new table
-			_, _ = db.Exec(`DROP TABLE articles`)
-			_, _ = db.Exec(`ALTER TABLE articles_new RENAME TO articles`)
-			// Recreate indexes
-			_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_feed_id ON articles(feed_id)`)
-			_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_published_at ON articles(published_at DESC)`)
-			_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_is_read ON articles(is_read)`)
-			_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_is_favorite ON articles(is_favorite)`)
-			_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_is_hidden ON articles(is_hidden)`)
-			_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_is_read_later ON articles(is_read_later)`)
-			_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_feed_published ON articles(feed_id, published_at DESC)`)
-			_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_read_published ON articles(is_read, published_at DESC)`)
-			_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_fav_published ON articles(is_favorite, published_at DESC)`)
-			_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_readlater_published ON articles(is_read_later, published_at DESC)`)
-			_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_hidden_published ON articles(is_hidden, published_at DESC)`)
+// tableColumns returns the column names of a table in declaration order
+func tableColumns(tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, fmt.Errorf("inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
 		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+// addColumnIfMissing adds a column to a table unless it already exists
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	exists, err := columnExists(tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
 	}
 	return nil
 }
 
-// migrateDropUniqueConstraintOnFeeds drops the UNIQUE constraint on url column from feeds table.
-// This allows FreshRSS and local feeds with the same URL to coexist.
-func migrateDropUniqueConstraintOnFeeds(db *sql.DB) error {
-	// Migration: Drop the UNIQUE constraint on feeds.url column to allow FreshRSS and local feeds with same URL
-	var feedsTableInfo string
-	_ = db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='feeds'").Scan(&feedsTableInfo)
-	if strings.Contains(feedsTableInfo, "url TEXT UNIQUE") {
-		log.Printf("Migration: Dropping UNIQUE constraint on feeds.url to allow FreshRSS and local feeds to coexist")
-		_, err := db.Exec(`
-			CREATE TABLE feeds_new (
-				id INTEGER PRIMARY KEY AUTOINCREMENT,
-				title TEXT,
-				url TEXT,
-				link TEXT DEFAULT '',
-				description TEXT,
-				category TEXT DEFAULT '',
-				image_url TEXT DEFAULT '',
-				position INTEGER DEFAULT 0,
-				last_updated DATETIME,
-				last_error TEXT DEFAULT '',
-				discovery_completed BOOLEAN DEFAULT 0,
-				script_path TEXT DEFAULT '',
-				hide_from_timeline BOOLEAN DEFAULT 0,
-				proxy_url TEXT DEFAULT '',
-				proxy_enabled BOOLEAN DEFAULT 0,
-				refresh_interval INTEGER DEFAULT 0,
-				is_image_mode BOOLEAN DEFAULT 0,
-				type TEXT DEFAULT '',
-				xpath_item TEXT DEFAULT '',
-				xpath_item_title TEXT DEFAULT '',
-				xpath_item_content TEXT DEFAULT '',
-				xpath_item_uri TEXT DEFAULT '',
-				xpath_item_author TEXT DEFAULT '',
-				xpath_item_timestamp TEXT DEFAULT '',
-				xpath_item_time_format TEXT DEFAULT '',
-				xpath_item_thumbnail TEXT DEFAULT '',
-				xpath_item_categories TEXT DEFAULT '',
-				xpath_item_uid TEXT DEFAULT '',
-				article_view_mode TEXT DEFAULT '',
-				auto_expand_content TEXT DEFAULT '',
-				email_address TEXT DEFAULT '',
-				email_imap_server TEXT DEFAULT '',
-				email_imap_port INTEGER DEFAULT 993,
-				email_username TEXT DEFAULT '',
-				email_password TEXT DEFAULT '',
-				email_folder TEXT DEFAULT 'INBOX',
-				email_last_uid INTEGER DEFAULT 0,
-				is_freshrss_source BOOLEAN DEFAULT 0,
-				freshrss_stream_id TEXT DEFAULT ''
-			)
-		`)
-		if err == nil {
-			// Copy data from old table to new table
-			_, err = db.Exec(`
-				INSERT INTO feeds_new (
-					id, title, url, link, description, category, image_url, position, last_updated, last_error,
-					discovery_completed, script_path, hide_from_timeline, proxy_url, proxy_enabled, refresh_interval,
-					is_image_mode, type, xpath_item, xpath_item_title, xpath_item_content, xpath_item_uri,
-					xpath_item_author, xpath_item_timestamp, xpath_item_time_format, xpath_item_thumbnail,
-					xpath_item_categories, xpath_item_uid, article_view_mode, auto_expand_content,
-					email_address, email_imap_server, email_imap_port, email_username, email_password,
-					email_folder, email_last_uid, is_freshrss_source, freshrss_stream_id
-				)
-				SELECT
-					id, title, url, link, description, category, image_url,
-					COALESCE(position, 0) as position,
-					last_updated, COALESCE(last_error, '') as last_error,
-					COALESCE(discovery_completed, 0) as discovery_completed,
-					COALESCE(script_path, '') as script_path,
-					COALESCE(hide_from_timeline, 0) as hide_from_timeline,
-					COALESCE(proxy_url, '') as proxy_url,
-					COALESCE(proxy_enabled, 0) as proxy_enabled,
-					COALESCE(refresh_interval, 0) as refresh_interval,
-					COALESCE(is_image_mode, 0) as is_image_mode,
-					COALESCE(type, '') as type,
-					COALESCE(xpath_item, '') as xpath_item,
-					COALESCE(xpath_item_title, '') as xpath_item_title,
-					COALESCE(xpath_item_content, '') as xpath_item_content,
-					COALESCE(xpath_item_uri, '') as xpath_item_uri,
-					COALESCE(xpath_item_author, '') as xpath_item_author,
-					COALESCE(xpath_item_timestamp, '') as xpath_item_timestamp,
-					COALESCE(xpath_item_time_format, '') as xpath_item_time_format,
-					COALESCE(xpath_item_thumbnail, '') as xpath_item_thumbnail,
-					COALESCE(xpath_item_categories, '') as xpath_item_categories,
-					COALESCE(xpath_item_uid, '') as xpath_item_uid,
-					COALESCE(article_view_mode, '') as article_view_mode,
-					COALESCE(auto_expand_content, '') as auto_expand_content,
-					COALESCE(email_address, '') as email_address,
-					COALESCE(email_imap_server, '') as email_imap_server,
-					COALESCE(email_imap_port, 993) as email_imap_port,
-					COALESCE(email_username, '') as email_username,
-					COALESCE(email_password, '') as email_password,
-					COALESCE(email_folder, 'INBOX') as email_folder,
-					COALESCE(email_last_uid, 0) as email_last_uid,
-					COALESCE(is_freshrss_source, 0) as is_freshrss_source,
-					COALESCE(freshrss_stream_id, '') as freshrss_stream_id
-				FROM feeds
-			`)
-			if err != nil {
-				log.Printf("Error copying feeds data: %v", err)
+// rebuildTable recreates a table from create, a CREATE TABLE statement with
+// %s in place of the table name, copying the columns both versions share.
+// Indexes of the old table are dropped with it and must be recreated.
+func rebuildTable(tx *sql.Tx, table, create string) error {
+	log.Printf("Migration: rebuilding table %s", table)
+	newTable := table + "_new"
+	if _, err := tx.Exec(fmt.Sprintf(create, newTable)); err != nil {
+		return fmt.Errorf("create %s: %w", newTable, err)
+	}
+
+	oldColumns, err := tableColumns(tx, table)
+	if err != nil {
+		return err
+	}
+	newColumns, err := tableColumns(tx, newTable)
+	if err != nil {
+		return err
+	}
+	var shared []string
+	for _, c := range newColumns {
+		for _, o := range oldColumns {
+			if strings.EqualFold(c, o) {
+				shared = append(shared, c)
+				break
 			}
-			// Drop old table and rename new table
-			_, _ = db.Exec(`DROP TABLE feeds`)
-			_, _ = db.Exec(`ALTER TABLE feeds_new RENAME TO feeds`)
-			// Recreate indexes
-			_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_feeds_category ON feeds(category)`)
-			log.Printf("Migration completed: UNIQUE constraint dropped from feeds.url")
-		} else {
-			log.Printf("Error creating feeds_new table: %v", err)
 		}
 	}
+
+	columns := strings.Join(shared, ", ")
+	if _, err := tx.Exec(fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s`, newTable, columns, columns, table)); err != nil {
+		return fmt.Errorf("copy %s: %w", table, err)
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`DROP TABLE %s`, table)); err != nil {
+		return fmt.Errorf("drop %s: %w", table, err)
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, newTable, table)); err != nil {
+		return fmt.Errorf("rename %s: %w", newTable, err)
+	}
 	return nil
 }
@@ -0,0 +1,252 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func schemaVersionOf(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version); err != nil {
+		t.Fatalf("read schema version: %v", err)
+	}
+	return version
+}
+
+func latestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func TestRunMigrationsNewDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rss.db")
+	db, err := NewDB(path)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	defer db.Close()
+	if err := db.Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	if got := schemaVersionOf(t, db.DB); got != latestSchemaVersion() {
+		t.Errorf("schema version = %d, want %d", got, latestSchemaVersion())
+	}
+	var applied int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_version`).Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(migrations) {
+		t.Errorf("recorded %d migrations, want %d", applied, len(migrations))
+	}
+
+	// Feeds with the same URL may coexist (FreshRSS and local)
+	for range 2 {
+		if _, err := db.Exec(`INSERT INTO feeds (title, url) VALUES ('Feed', 'https://example.com/feed')`); err != nil {
+			t.Fatalf("insert feed: %v", err)
+		}
+	}
+
+	// Running again is a no-op and a new database is not backed up
+	if err := runMigrations(db.DB, migrations); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if matches, _ := filepath.Glob(path + ".v*.bak"); len(matches) != 0 {
+		t.Errorf("unexpected backups %v", matches)
+	}
+}
+
+func TestRunMigrationsUpgradesLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rss.db")
+	legacy, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Schema of an early release: url UNIQUE, no unique_id and few columns
+	_, err = legacy.Exec(`
+		CREATE TABLE feeds (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT,
+			url TEXT UNIQUE,
+			description TEXT,
+			category TEXT DEFAULT '',
+			image_url TEXT DEFAULT '',
+			last_updated DATETIME
+		);
+		CREATE TABLE articles (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			feed_id INTEGER,
+			title TEXT,
+			url TEXT UNIQUE,
+			image_url TEXT,
+			translated_title TEXT,
+			published_at DATETIME,
+			is_read BOOLEAN DEFAULT 0,
+			is_favorite BOOLEAN DEFAULT 0,
+			FOREIGN KEY(feed_id) REFERENCES feeds(id)
+		);
+		INSERT INTO feeds (id, title, url) VALUES (1, 'Old feed', 'https://example.com/feed');
+		INSERT INTO articles (feed_id, title, url, published_at, is_favorite) VALUES (1, 'First', 'https://example.com/1', '2024-01-02 10:00:00', 1);
+		INSERT INTO articles (feed_id, title, url, published_at) VALUES (1, 'Second', 'https://example.com/2', NULL);
+	`)
+	if err != nil {
+		t.Fatalf("create legacy schema: %v", err)
+	}
+	legacy.Close()
+
+	db, err := NewDB(path)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	defer db.Close()
+	if err := db.Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	if got := schemaVersionOf(t, db.DB); got != latestSchemaVersion() {
+		t.Errorf("schema version = %d, want %d", got, latestSchemaVersion())
+	}
+
+	var favorites, withoutID, undated int
+	if err := db.QueryRow(`SELECT
+		SUM(is_favorite),
+		SUM(CASE WHEN unique_id IS NULL THEN 1 ELSE 0 END),
+		SUM(CASE WHEN published_at IS NULL THEN 1 ELSE 0 END)
+		FROM articles`).Scan(&favorites, &withoutID, &undated); err != nil {
+		t.Fatalf("query articles: %v", err)
+	}
+	if favorites != 1 || withoutID != 0 || undated != 0 {
+		t.Errorf("favorites=%d withoutID=%d undated=%d, want 1, 0, 0", favorites, withoutID, undated)
+	}
+
+	var feedsSQL string
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'feeds'`).Scan(&feedsSQL); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(feedsSQL, "url TEXT UNIQUE") || !strings.Contains(feedsSQL, "allow_private_network") {
+		t.Errorf("feeds not upgraded: %s", feedsSQL)
+	}
+	if _, err := db.Exec(`UPDATE feeds SET allow_private_network = 1, email_folder = 'INBOX' WHERE id = 1`); err != nil {
+		t.Errorf("upgraded feeds columns missing: %v", err)
+	}
+
+	// The database was backed up as it was before the upgrade
+	backup, err := sql.Open("sqlite", path+".v0.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	var backupSQL string
+	if err := backup.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'feeds'`).Scan(&backupSQL); err != nil {
+		t.Fatalf("read backup: %v", err)
+	}
+	if !strings.Contains(backupSQL, "url TEXT UNIQUE") {
+		t.Errorf("backup does not hold the original schema: %s", backupSQL)
+	}
+}
+
+func TestRunMigrationsDetectsBaseline(t *testing.T) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := runMigrations(db.DB, migrations); err != nil {
+		t.Fatalf("runMigrations: %v", err)
+	}
+
+	// A database last opened by a release without schema versioning has
+	// every table but no record of them. Re-applying the later migrations
+	// would fail, e.g. on the existing allow_private_network column.
+	if _, err := db.Exec(`DROP TABLE schema_version`); err != nil {
+		t.Fatal(err)
+	}
+	if err := runMigrations(db.DB, migrations); err != nil {
+		t.Fatalf("runMigrations on unversioned database: %v", err)
+	}
+	if got := schemaVersionOf(t, db.DB); got != latestSchemaVersion() {
+		t.Errorf("schema version = %d, want %d", got, latestSchemaVersion())
+	}
+
+	// Migrations whose changes are missing run, the others are only recorded
+	if _, err := db.Exec(`DROP TABLE schema_version`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DROP TABLE article_archives`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`ALTER TABLE feeds DROP COLUMN allow_private_network`); err != nil {
+		t.Fatal(err)
+	}
+	if err := runMigrations(db.DB, migrations); err != nil {
+		t.Fatalf("runMigrations on partially upgraded database: %v", err)
+	}
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE name = 'article_archives')`).Scan(&exists); err != nil || !exists {
+		t.Errorf("article_archives not recreated: exists=%v err=%v", exists, err)
+	}
+}
+
+func TestRunMigrationsRefusesNewerDatabase(t *testing.T) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := runMigrations(db.DB, migrations); err != nil {
+		t.Fatalf("runMigrations: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_version (version, name) VALUES (?, 'from_the_future')`, latestSchemaVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+
+	err = runMigrations(db.DB, migrations)
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("err = %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestRunMigrationsFailureRollsBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rss.db")
+	db, err := NewDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := runMigrations(db.DB, migrations[:1]); err != nil {
+		t.Fatalf("runMigrations: %v", err)
+	}
+
+	list := append(migrations[:1:1], migration{
+		version: 2,
+		name:    "broken",
+		up: execMigration(`
+			CREATE TABLE half_done (id INTEGER PRIMARY KEY);
+			ALTER TABLE feeds ADD COLUMN title TEXT;
+		`),
+	})
+	err = runMigrations(db.DB, list)
+	if err == nil {
+		t.Fatal("expected the broken migration to fail")
+	}
+	for _, want := range []string{"schema migration 2 (broken) failed", "schema version 1", path + ".v1.bak", "duplicate column"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	if got := schemaVersionOf(t, db.DB); got != 1 {
+		t.Errorf("schema version = %d, want 1", got)
+	}
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE name = 'half_done')`).Scan(&exists); err != nil || exists {
+		t.Errorf("failed migration was not rolled back: exists=%v err=%v", exists, err)
+	}
+	if _, err := os.Stat(path + ".v1.bak"); err != nil {
+		t.Errorf("backup missing: %v", err)
+	}
+}
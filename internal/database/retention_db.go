@@ -0,0 +1,325 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"MrRSS/internal/models"
+)
+
+// articleSizeExpr estimates the bytes an article and its cached content take
+const articleSizeExpr = `
+	LENGTH(CAST(COALESCE(a.title, '') AS BLOB)) + LENGTH(CAST(COALESCE(a.url, '') AS BLOB))
+	+ LENGTH(CAST(COALESCE(a.summary, '') AS BLOB)) + LENGTH(CAST(COALESCE(a.original_summary, '') AS BLOB))
+	+ LENGTH(CAST(COALESCE(a.translated_title, '') AS BLOB)) + LENGTH(CAST(COALESCE(a.content, '') AS BLOB))
+	+ COALESCE((SELECT LENGTH(CAST(content AS BLOB)) FROM article_contents WHERE article_id = a.id), 0)
+	+ COALESCE((SELECT LENGTH(CAST(content AS BLOB)) FROM article_fulltexts WHERE article_id = a.id), 0)`
+
+const retentionPolicyColumns = `id, scope, COALESCE(feed_id, 0), COALESCE(category, ''), COALESCE(tag_id, 0),
+	keep_newest, max_age_days, keep_unread, keep_favorites, keep_read_later, enabled, created_at`
+
+func scanRetentionPolicy(scanner interface{ Scan(...interface{}) error }) (*models.RetentionPolicy, error) {
+	var p models.RetentionPolicy
+	var createdAt sql.NullTime
+	if err := scanner.Scan(&p.ID, &p.Scope, &p.FeedID, &p.Category, &p.TagID, &p.KeepNewest, &p.MaxAgeDays,
+		&p.KeepUnread, &p.KeepFavorites, &p.KeepReadLater, &p.Enabled, &createdAt); err != nil {
+		return nil, err
+	}
+	if createdAt.Valid {
+		p.CreatedAt = createdAt.Time
+	}
+	return &p, nil
+}
+
+// GetRetentionPolicies returns all retention policies
+func (db *DB) GetRetentionPolicies() ([]models.RetentionPolicy, error) {
+	db.WaitForReady()
+
+	rows, err := db.Query(`SELECT ` + retentionPolicyColumns + ` FROM retention_policies ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make([]models.RetentionPolicy, 0)
+	for rows.Next() {
+		p, err := scanRetentionPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, *p)
+	}
+	return policies, rows.Err()
+}
+
+// GetRetentionPolicy returns a retention policy by ID
+func (db *DB) GetRetentionPolicy(id int64) (*models.RetentionPolicy, error) {
+	db.WaitForReady()
+	return scanRetentionPolicy(db.QueryRow(`SELECT `+retentionPolicyColumns+` FROM retention_policies WHERE id = ?`, id))
+}
+
+// AddRetentionPolicy stores a new retention policy and returns its ID
+func (db *DB) AddRetentionPolicy(p *models.RetentionPolicy) (int64, error) {
+	db.WaitForReady()
+
+	feedID, category, tagID := retentionScopeArgs(p)
+	result, err := db.Exec(`
+		INSERT INTO retention_policies (scope, feed_id, category, tag_id, keep_newest, max_age_days,
+			keep_unread, keep_favorites, keep_read_later, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.Scope, feedID, category, tagID, p.KeepNewest, p.MaxAgeDays,
+		p.KeepUnread, p.KeepFavorites, p.KeepReadLater, p.Enabled)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// UpdateRetentionPolicy changes the rules of a retention policy. Its scope
+// is left as it is.
+func (db *DB) UpdateRetentionPolicy(p *models.RetentionPolicy) error {
+	db.WaitForReady()
+
+	result, err := db.Exec(`
+		UPDATE retention_policies
+		SET keep_newest = ?, max_age_days = ?, keep_unread = ?, keep_favorites = ?, keep_read_later = ?, enabled = ?
+		WHERE id = ?
+	`, p.KeepNewest, p.MaxAgeDays, p.KeepUnread, p.KeepFavorites, p.KeepReadLater, p.Enabled, p.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteRetentionPolicy removes a retention policy; its feeds fall back to
+// the global cleanup rules
+func (db *DB) DeleteRetentionPolicy(id int64) error {
+	db.WaitForReady()
+	_, err := db.Exec(`DELETE FROM retention_policies WHERE id = ?`, id)
+	return err
+}
+
+// retentionScopeArgs returns the feed_id, category and tag_id column values
+// of a policy's scope
+func retentionScopeArgs(p *models.RetentionPolicy) (feedID, category, tagID interface{}) {
+	switch p.Scope {
+	case models.RetentionScopeFeed:
+		return p.FeedID, nil, nil
+	case models.RetentionScopeCategory:
+		return nil, p.Category, nil
+	default:
+		return nil, nil, p.TagID
+	}
+}
+
+// retentionScopeRank orders scopes from the most to the least specific
+var retentionScopeRank = map[string]int{
+	models.RetentionScopeFeed:     0,
+	models.RetentionScopeTag:      1,
+	models.RetentionScopeCategory: 2,
+}
+
+// governedFeeds returns the IDs of the feeds each enabled policy governs.
+// A feed is governed by its most specific policy: its own, then that of its
+// first tag with a policy, then that of its deepest category or parent
+// category with a policy.
+func (db *DB) governedFeeds(policies []models.RetentionPolicy) (map[int64][]int64, error) {
+	enabled := make([]models.RetentionPolicy, 0, len(policies))
+	for _, p := range policies {
+		if p.Enabled {
+			enabled = append(enabled, p)
+		}
+	}
+	sort.SliceStable(enabled, func(i, j int) bool {
+		if enabled[i].Scope != enabled[j].Scope {
+			return retentionScopeRank[enabled[i].Scope] < retentionScopeRank[enabled[j].Scope]
+		}
+		return len(enabled[i].Category) > len(enabled[j].Category)
+	})
+
+	feedTags := make(map[int64]map[int64]bool)
+	rows, err := db.Query(`SELECT feed_id, tag_id FROM feed_tags`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var feedID, tagID int64
+		if err := rows.Scan(&feedID, &tagID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if feedTags[feedID] == nil {
+			feedTags[feedID] = make(map[int64]bool)
+		}
+		feedTags[feedID][tagID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`SELECT id, COALESCE(category, '') FROM feeds`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	governed := make(map[int64][]int64)
+	for rows.Next() {
+		var feedID int64
+		var category string
+		if err := rows.Scan(&feedID, &category); err != nil {
+			return nil, err
+		}
+		for _, p := range enabled {
+			if (p.Scope == models.RetentionScopeFeed && p.FeedID == feedID) ||
+				(p.Scope == models.RetentionScopeTag && feedTags[feedID][p.TagID]) ||
+				(p.Scope == models.RetentionScopeCategory && inRetentionCategory(category, p.Category)) {
+				governed[p.ID] = append(governed[p.ID], feedID)
+				break
+			}
+		}
+	}
+	return governed, rows.Err()
+}
+
+// inRetentionCategory reports whether a feed category is the policy category
+// or one of its subcategories
+func inRetentionCategory(category, policyCategory string) bool {
+	return category == policyCategory || strings.HasPrefix(category, policyCategory+"/")
+}
+
+// retentionCandidates returns a query selecting the IDs of the articles in
+// feedIDs that a policy removes. It returns "" when the policy removes nothing.
+// Offline archived articles are always kept.
+func retentionCandidates(p *models.RetentionPolicy, feedIDs []int64) (string, []interface{}) {
+	if len(feedIDs) == 0 {
+		return "", nil
+	}
+
+	placeholders := make([]string, len(feedIDs))
+	args := make([]interface{}, 0, len(feedIDs)+2)
+	for i, id := range feedIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+
+	var remove []string
+	if p.KeepNewest > 0 {
+		remove = append(remove, "feed_rank > ?")
+		args = append(args, p.KeepNewest)
+	}
+	if p.MaxAgeDays > 0 {
+		remove = append(remove, "published_at < ?")
+		args = append(args, time.Now().AddDate(0, 0, -p.MaxAgeDays))
+	}
+	if len(remove) == 0 {
+		return "", nil
+	}
+
+	keep := []string{"id NOT IN (SELECT article_id FROM article_archives)"}
+	if p.KeepUnread {
+		keep = append(keep, "is_read = 1")
+	}
+	if p.KeepFavorites {
+		keep = append(keep, "is_favorite = 0")
+	}
+	if p.KeepReadLater {
+		keep = append(keep, "is_read_later = 0")
+	}
+
+	return `
+		WITH ranked AS (
+			SELECT id, published_at, is_read, is_favorite, is_read_later,
+				ROW_NUMBER() OVER (PARTITION BY feed_id ORDER BY published_at DESC, id DESC) AS feed_rank
+			FROM articles
+			WHERE feed_id IN (` + strings.Join(placeholders, ",") + `)
+		)
+		SELECT id FROM ranked
+		WHERE (` + strings.Join(remove, " OR ") + `)
+		AND ` + strings.Join(keep, " AND "), args
+}
+
+// ApplyRetentionPolicies removes the articles that the enabled retention
+// policies do not keep, moving them to cold storage when it is enabled.
+// A failing policy does not stop the others. The global cleanup rules skip
+// the governed feeds, listed by the retention_policy_feeds view, so a policy
+// also protects articles it does not remove.
+func (db *DB) ApplyRetentionPolicies() (int64, error) {
+	db.WaitForReady()
+
+	policies, err := db.GetRetentionPolicies()
+	if err != nil || len(policies) == 0 {
+		return 0, err
+	}
+	governed, err := db.governedFeeds(policies)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	var errs []error
+	for i := range policies {
+		query, args := retentionCandidates(&policies[i], governed[policies[i].ID])
+		if query == "" {
+			continue
+		}
+		count, err := db.removeArticles(query, args...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("retention policy %d: %w", policies[i].ID, err))
+			continue
+		}
+		total += count
+	}
+	return total, errors.Join(errs...)
+}
+
+// PreviewRetentionPolicies reports how many articles, and how much data, each
+// retention policy would remove if applied now. Disabled policies are
+// previewed as if they were enabled.
+func (db *DB) PreviewRetentionPolicies() ([]models.RetentionPreview, error) {
+	db.WaitForReady()
+
+	policies, err := db.GetRetentionPolicies()
+	if err != nil {
+		return nil, err
+	}
+	governed, err := db.governedFeeds(policies)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make([]models.RetentionPreview, 0, len(policies))
+	for i := range policies {
+		feedIDs := governed[policies[i].ID]
+		if !policies[i].Enabled {
+			// Preview a disabled policy as if it was enabled
+			withPolicy := append([]models.RetentionPolicy(nil), policies...)
+			withPolicy[i].Enabled = true
+			g, err := db.governedFeeds(withPolicy)
+			if err != nil {
+				return nil, err
+			}
+			feedIDs = g[policies[i].ID]
+		}
+		preview := models.RetentionPreview{PolicyID: policies[i].ID, Feeds: len(feedIDs)}
+		if query, args := retentionCandidates(&policies[i], feedIDs); query != "" {
+			var sizeBytes int64
+			err := db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(`+articleSizeExpr+`), 0)
+				FROM articles a WHERE a.id IN (`+query+`)`, args...).Scan(&preview.Articles, &sizeBytes)
+			if err != nil {
+				return nil, err
+			}
+			preview.SizeMB = float64(sizeBytes) / (1024 * 1024)
+		}
+		previews = append(previews, preview)
+	}
+	return previews, nil
+}
@@ -0,0 +1,176 @@
+package database_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"MrRSS/internal/models"
+)
+
+func TestRetentionPoliciesPreviewAndApply(t *testing.T) {
+	db := setupTestDB(t)
+
+	addFeed := func(title, category string) int64 {
+		t.Helper()
+		res, err := db.Exec(`INSERT INTO feeds (title, url, category) VALUES (?, ?, ?)`, title, "https://example.com/"+title, category)
+		if err != nil {
+			t.Fatalf("insert feed %s: %v", title, err)
+		}
+		id, _ := res.LastInsertId()
+		return id
+	}
+	news := addFeed("news", "daily")
+	wire := addFeed("wire", "daily")
+	blog := addFeed("blog", "daily")
+	other := addFeed("other", "misc")
+
+	res, err := db.Exec(`INSERT INTO tags (name) VALUES ('precious')`)
+	if err != nil {
+		t.Fatalf("insert tag: %v", err)
+	}
+	tagID, _ := res.LastInsertId()
+	if _, err := db.Exec(`INSERT INTO feed_tags (feed_id, tag_id) VALUES (?, ?)`, blog, tagID); err != nil {
+		t.Fatalf("tag feed: %v", err)
+	}
+
+	// Five read articles per feed, one per day, and an old unread one
+	for _, feedID := range []int64{news, wire, blog, other} {
+		for i := 0; i < 5; i++ {
+			if _, err := db.Exec(`INSERT INTO articles (feed_id, title, url, published_at, is_read, unique_id) VALUES (?, 'x', 'u', ?, 1, ?)`,
+				feedID, time.Now().AddDate(0, 0, -i), fmt.Sprintf("%d-%d", feedID, i)); err != nil {
+				t.Fatalf("insert article: %v", err)
+			}
+		}
+		if _, err := db.Exec(`INSERT INTO articles (feed_id, title, url, published_at, is_read, unique_id) VALUES (?, 'x', 'u', ?, 0, ?)`,
+			feedID, time.Now().AddDate(0, 0, -400), fmt.Sprintf("%d-unread", feedID)); err != nil {
+			t.Fatalf("insert article: %v", err)
+		}
+	}
+
+	add := func(p models.RetentionPolicy) int64 {
+		t.Helper()
+		p.KeepFavorites, p.KeepReadLater, p.Enabled = true, true, true
+		id, err := db.AddRetentionPolicy(&p)
+		if err != nil {
+			t.Fatalf("AddRetentionPolicy: %v", err)
+		}
+		return id
+	}
+	// The category keeps 3 articles per feed, news keeps its 2 newest plus
+	// unread ones, and the tag keeps everything on blog
+	categoryPolicy := add(models.RetentionPolicy{Scope: models.RetentionScopeCategory, Category: "daily", KeepNewest: 3})
+	feedPolicy := add(models.RetentionPolicy{Scope: models.RetentionScopeFeed, FeedID: news, KeepNewest: 2, KeepUnread: true})
+	tagPolicy := add(models.RetentionPolicy{Scope: models.RetentionScopeTag, TagID: tagID})
+
+	previews, err := db.PreviewRetentionPolicies()
+	if err != nil {
+		t.Fatalf("PreviewRetentionPolicies: %v", err)
+	}
+	want := map[int64]models.RetentionPreview{
+		categoryPolicy: {Feeds: 1, Articles: 3},
+		feedPolicy:     {Feeds: 1, Articles: 3},
+		tagPolicy:      {Feeds: 1, Articles: 0},
+	}
+	for _, p := range previews {
+		w := want[p.PolicyID]
+		if p.Feeds != w.Feeds || p.Articles != w.Articles {
+			t.Errorf("preview of policy %d = %+v, want %d feeds and %d articles", p.PolicyID, p, w.Feeds, w.Articles)
+		}
+		if p.Articles > 0 && p.SizeMB <= 0 {
+			t.Errorf("preview of policy %d has no size", p.PolicyID)
+		}
+	}
+
+	removed, err := db.ApplyRetentionPolicies()
+	if err != nil {
+		t.Fatalf("ApplyRetentionPolicies: %v", err)
+	}
+	if removed != 6 {
+		t.Errorf("removed = %d, want 6", removed)
+	}
+
+	// Global cleanup leaves governed feeds alone
+	if _, err := db.CleanupOldUnreadArticles(30); err != nil {
+		t.Fatalf("CleanupOldUnreadArticles: %v", err)
+	}
+	for feedID, want := range map[int64]int{news: 3, wire: 3, blog: 6, other: 5} {
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM articles WHERE feed_id = ?`, feedID).Scan(&count); err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		if count != want {
+			t.Errorf("feed %d has %d articles, want %d", feedID, count, want)
+		}
+	}
+}
+
+func TestRetentionCategoryPoliciesCoverSubcategories(t *testing.T) {
+	db := setupTestDB(t)
+
+	feeds := make(map[string]int64)
+	for _, category := range []string{"news", "news/tech", "news/tech/go", "newsletters"} {
+		res, err := db.Exec(`INSERT INTO feeds (title, url, category) VALUES (?, ?, ?)`, category, "https://example.com/"+category, category)
+		if err != nil {
+			t.Fatalf("insert feed %s: %v", category, err)
+		}
+		feeds[category], _ = res.LastInsertId()
+		for i := 0; i < 5; i++ {
+			if _, err := db.Exec(`INSERT INTO articles (feed_id, title, url, published_at, is_read, unique_id) VALUES (?, 'x', 'u', ?, 1, ?)`,
+				feeds[category], time.Now().AddDate(0, 0, -i), fmt.Sprintf("%s-%d", category, i)); err != nil {
+				t.Fatalf("insert article: %v", err)
+			}
+		}
+	}
+
+	// news/tech overrides news for itself and news/tech/go; newsletters is
+	// not a subcategory of news
+	for _, p := range []models.RetentionPolicy{
+		{Scope: models.RetentionScopeCategory, Category: "news", KeepNewest: 4},
+		{Scope: models.RetentionScopeCategory, Category: "news/tech", KeepNewest: 2},
+	} {
+		p.Enabled = true
+		if _, err := db.AddRetentionPolicy(&p); err != nil {
+			t.Fatalf("AddRetentionPolicy: %v", err)
+		}
+	}
+
+	previews, err := db.PreviewRetentionPolicies()
+	if err != nil {
+		t.Fatalf("PreviewRetentionPolicies: %v", err)
+	}
+	var feedCount int
+	var articleCount int64
+	for _, p := range previews {
+		feedCount += p.Feeds
+		articleCount += p.Articles
+	}
+	if feedCount != 3 || articleCount != 7 {
+		t.Errorf("previews cover %d feeds and %d articles, want 3 and 7", feedCount, articleCount)
+	}
+
+	if removed, err := db.ApplyRetentionPolicies(); err != nil || removed != 7 {
+		t.Fatalf("ApplyRetentionPolicies = %d, %v, want 7", removed, err)
+	}
+
+	for category, want := range map[string]int{"news": 4, "news/tech": 2, "news/tech/go": 2, "newsletters": 5} {
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM articles WHERE feed_id = ?`, feeds[category]).Scan(&count); err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		if count != want {
+			t.Errorf("feed in %s has %d articles, want %d", category, count, want)
+		}
+	}
+
+	// Global cleanup skips the same feeds
+	for category, want := range map[string]bool{"news": true, "news/tech": true, "news/tech/go": true, "newsletters": false} {
+		var governed bool
+		if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM retention_policy_feeds WHERE feed_id = ?)`, feeds[category]).Scan(&governed); err != nil {
+			t.Fatalf("query view: %v", err)
+		}
+		if governed != want {
+			t.Errorf("feed in %s governed = %v, want %v", category, governed, want)
+		}
+	}
+}
@@ -2,26 +2,62 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"MrRSS/internal/utils/urlutil"
 )
 
-// initSchema initializes the database schema by creating all tables and indexes.
-// This is extracted from db.go for better code organization.
-func initSchema(db *sql.DB) error {
-	// First create tables
-	query := `
-	CREATE TABLE IF NOT EXISTS feeds (
+// baselineTables is the schema as it stood when schema versioning was
+// introduced. Each table is created with %s in place of its name so that
+// rebuildTable can create a replacement next to the original.
+var baselineTables = []struct {
+	name   string
+	create string
+}{
+	{"feeds", `CREATE TABLE IF NOT EXISTS %s (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		title TEXT,
-		url TEXT UNIQUE,
+		url TEXT,
 		link TEXT DEFAULT '',
 		description TEXT,
 		category TEXT DEFAULT '',
 		image_url TEXT DEFAULT '',
+		position INTEGER DEFAULT 0,
 		last_updated DATETIME,
-		last_error TEXT DEFAULT ''
-	);
-
-	CREATE TABLE IF NOT EXISTS articles (
+		last_error TEXT DEFAULT '',
+		discovery_completed BOOLEAN DEFAULT 0,
+		script_path TEXT DEFAULT '',
+		hide_from_timeline BOOLEAN DEFAULT 0,
+		proxy_url TEXT DEFAULT '',
+		proxy_enabled BOOLEAN DEFAULT 0,
+		refresh_interval INTEGER DEFAULT 0,
+		is_image_mode BOOLEAN DEFAULT 0,
+		type TEXT DEFAULT '',
+		xpath_item TEXT DEFAULT '',
+		xpath_item_title TEXT DEFAULT '',
+		xpath_item_content TEXT DEFAULT '',
+		xpath_item_uri TEXT DEFAULT '',
+		xpath_item_author TEXT DEFAULT '',
+		xpath_item_timestamp TEXT DEFAULT '',
+		xpath_item_time_format TEXT DEFAULT '',
+		xpath_item_thumbnail TEXT DEFAULT '',
+		xpath_item_categories TEXT DEFAULT '',
+		xpath_item_uid TEXT DEFAULT '',
+		article_view_mode TEXT DEFAULT '',
+		auto_expand_content TEXT DEFAULT '',
+		email_address TEXT DEFAULT '',
+		email_imap_server TEXT DEFAULT '',
+		email_imap_port INTEGER DEFAULT 993,
+		email_username TEXT DEFAULT '',
+		email_password TEXT DEFAULT '',
+		email_folder TEXT DEFAULT 'INBOX',
+		email_last_uid INTEGER DEFAULT 0,
+		is_freshrss_source BOOLEAN DEFAULT 0,
+		freshrss_stream_id TEXT DEFAULT ''
+	)`},
+	{"articles", `CREATE TABLE IF NOT EXISTS %s (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		feed_id INTEGER,
 		title TEXT,
@@ -38,11 +74,13 @@ func initSchema(db *sql.DB) error {
 		summary TEXT DEFAULT '',
 		original_summary TEXT DEFAULT '',
 		unique_id TEXT UNIQUE,
+		content TEXT DEFAULT '',
+		author TEXT DEFAULT '',
+		freshrss_item_id TEXT DEFAULT '',
 		FOREIGN KEY(feed_id) REFERENCES feeds(id)
-	);
-
-	-- Translation cache table to avoid redundant API calls
-	CREATE TABLE IF NOT EXISTS translation_cache (
+	)`},
+	// Translation cache table to avoid redundant API calls
+	{"translation_cache", `CREATE TABLE IF NOT EXISTS %s (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		source_text_hash TEXT NOT NULL,
 		source_text TEXT NOT NULL,
@@ -51,29 +89,25 @@ func initSchema(db *sql.DB) error {
 		provider TEXT NOT NULL,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		UNIQUE(source_text_hash, target_lang, provider)
-	);
-
-	-- Article content cache table to store full article content
-	CREATE TABLE IF NOT EXISTS article_contents (
+	)`},
+	// Article content cache, kept apart to keep the articles table lightweight
+	{"article_contents", `CREATE TABLE IF NOT EXISTS %s (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		article_id INTEGER NOT NULL UNIQUE,
 		content TEXT NOT NULL,
 		fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE
-	);
-
-	-- Chat sessions table to store AI chat conversations per article
-	CREATE TABLE IF NOT EXISTS chat_sessions (
+	)`},
+	// AI chat conversations per article and their messages
+	{"chat_sessions", `CREATE TABLE IF NOT EXISTS %s (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		article_id INTEGER NOT NULL,
 		title TEXT NOT NULL,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY(article_id) REFERENCES articles(id) ON DELETE CASCADE
-	);
-
-	-- Chat messages table to store individual messages in chat sessions
-	CREATE TABLE IF NOT EXISTS chat_messages (
+	)`},
+	{"chat_messages", `CREATE TABLE IF NOT EXISTS %s (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		session_id INTEGER NOT NULL,
 		role TEXT NOT NULL,
@@ -81,9 +115,97 @@ func initSchema(db *sql.DB) error {
 		thinking TEXT,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY(session_id) REFERENCES chat_sessions(id) ON DELETE CASCADE
-	);
+	)`},
+	// Custom filter persistence
+	{"saved_filters", `CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		conditions TEXT NOT NULL,
+		position INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`},
+	// Feed tags and the feed/tag junction table
+	{"tags", `CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		color TEXT NOT NULL DEFAULT '#3B82F6',
+		position INTEGER DEFAULT 0
+	)`},
+	{"feed_tags", `CREATE TABLE IF NOT EXISTS %s (
+		feed_id INTEGER NOT NULL,
+		tag_id INTEGER NOT NULL,
+		PRIMARY KEY (feed_id, tag_id),
+		FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
+		FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+	)`},
+	// Multiple AI configurations
+	{"ai_profiles", `CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		api_key TEXT DEFAULT '',
+		endpoint TEXT NOT NULL,
+		model TEXT NOT NULL,
+		custom_headers TEXT DEFAULT '',
+		is_default BOOLEAN DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`},
+}
+
+// legacyColumns were added to the baseline tables by unversioned migrations.
+// Databases created by older releases may lack any of them.
+var legacyColumns = []struct {
+	table, column, definition string
+}{
+	{"feeds", "link", "TEXT DEFAULT ''"},
+	{"feeds", "last_error", "TEXT DEFAULT ''"},
+	{"feeds", "position", "INTEGER DEFAULT 0"},
+	{"feeds", "discovery_completed", "BOOLEAN DEFAULT 0"},
+	{"feeds", "script_path", "TEXT DEFAULT ''"},
+	{"feeds", "hide_from_timeline", "BOOLEAN DEFAULT 0"},
+	{"feeds", "proxy_url", "TEXT DEFAULT ''"},
+	{"feeds", "proxy_enabled", "BOOLEAN DEFAULT 0"},
+	{"feeds", "refresh_interval", "INTEGER DEFAULT 0"},
+	{"feeds", "is_image_mode", "BOOLEAN DEFAULT 0"},
+	{"feeds", "type", "TEXT DEFAULT ''"},
+	{"feeds", "xpath_item", "TEXT DEFAULT ''"},
+	{"feeds", "xpath_item_title", "TEXT DEFAULT ''"},
+	{"feeds", "xpath_item_content", "TEXT DEFAULT ''"},
+	{"feeds", "xpath_item_uri", "TEXT DEFAULT ''"},
+	{"feeds", "xpath_item_author", "TEXT DEFAULT ''"},
+	{"feeds", "xpath_item_timestamp", "TEXT DEFAULT ''"},
+	{"feeds", "xpath_item_time_format", "TEXT DEFAULT ''"},
+	{"feeds", "xpath_item_thumbnail", "TEXT DEFAULT ''"},
+	{"feeds", "xpath_item_categories", "TEXT DEFAULT ''"},
+	{"feeds", "xpath_item_uid", "TEXT DEFAULT ''"},
+	{"feeds", "article_view_mode", "TEXT DEFAULT 'global'"},
+	{"feeds", "auto_expand_content", "TEXT DEFAULT 'global'"},
+	{"feeds", "email_address", "TEXT DEFAULT ''"},
+	{"feeds", "email_imap_server", "TEXT DEFAULT ''"},
+	{"feeds", "email_imap_port", "INTEGER DEFAULT 993"},
+	{"feeds", "email_username", "TEXT DEFAULT ''"},
+	{"feeds", "email_password", "TEXT DEFAULT ''"},
+	{"feeds", "email_folder", "TEXT DEFAULT 'INBOX'"},
+	{"feeds", "email_last_uid", "INTEGER DEFAULT 0"},
+	{"feeds", "is_freshrss_source", "BOOLEAN DEFAULT 0"},
+	{"feeds", "freshrss_stream_id", "TEXT DEFAULT ''"},
+	{"articles", "audio_url", "TEXT DEFAULT ''"},
+	{"articles", "video_url", "TEXT DEFAULT ''"},
+	{"articles", "is_hidden", "BOOLEAN DEFAULT 0"},
+	{"articles", "is_read_later", "BOOLEAN DEFAULT 0"},
+	{"articles", "summary", "TEXT DEFAULT ''"},
+	{"articles", "original_summary", "TEXT DEFAULT ''"},
+	// SQLite cannot add a UNIQUE column; the constraint comes from
+	// idx_articles_unique_id, created in migrateBaselineUniqueID
+	{"articles", "unique_id", "TEXT"},
+	{"articles", "content", "TEXT DEFAULT ''"},
+	{"articles", "author", "TEXT DEFAULT ''"},
+	{"articles", "freshrss_item_id", "TEXT DEFAULT ''"},
+}
 
-	-- Create indexes for better query performance
+// baselineIndexes are created once every baseline column exists
+const baselineIndexes = `
 	CREATE INDEX IF NOT EXISTS idx_articles_feed_id ON articles(feed_id);
 	CREATE INDEX IF NOT EXISTS idx_articles_published_at ON articles(published_at DESC);
 	CREATE INDEX IF NOT EXISTS idx_articles_is_read ON articles(is_read);
@@ -102,27 +224,107 @@ func initSchema(db *sql.DB) error {
 	-- Optimizes queries with: WHERE is_hidden = 0 ORDER BY published_at DESC
 	CREATE INDEX IF NOT EXISTS idx_articles_hidden_published ON articles(is_hidden, published_at DESC);
 
-	-- Translation cache index
 	CREATE INDEX IF NOT EXISTS idx_translation_cache_lookup ON translation_cache(source_text_hash, target_lang, provider);
-
-	-- Article content cache index
 	CREATE INDEX IF NOT EXISTS idx_article_contents_article_id ON article_contents(article_id);
-
-	-- Chat sessions and messages indexes
 	CREATE INDEX IF NOT EXISTS idx_chat_sessions_article_id ON chat_sessions(article_id);
 	CREATE INDEX IF NOT EXISTS idx_chat_sessions_updated_at ON chat_sessions(updated_at DESC);
 	CREATE INDEX IF NOT EXISTS idx_chat_messages_session_id ON chat_messages(session_id);
-	`
-	_, err := db.Exec(query)
+	CREATE INDEX IF NOT EXISTS idx_saved_filters_position ON saved_filters(position);
+	CREATE INDEX IF NOT EXISTS idx_tags_position ON tags(position);
+	CREATE INDEX IF NOT EXISTS idx_feed_tags_feed_id ON feed_tags(feed_id);
+	CREATE INDEX IF NOT EXISTS idx_feed_tags_tag_id ON feed_tags(tag_id);
+	CREATE INDEX IF NOT EXISTS idx_ai_profiles_is_default ON ai_profiles(is_default);
+`
+
+// migrateBaseline creates the baseline schema on a new database and brings a
+// database created before schema versioning up to it. Every step checks the
+// current schema first, so it applies to databases of any older release.
+func migrateBaseline(tx *sql.Tx) error {
+	for _, t := range baselineTables {
+		if _, err := tx.Exec(fmt.Sprintf(t.create, t.name)); err != nil {
+			return fmt.Errorf("create table %s: %w", t.name, err)
+		}
+	}
+
+	hadUniqueID, err := columnExists(tx, "articles", "unique_id")
 	if err != nil {
 		return err
 	}
+	for _, c := range legacyColumns {
+		if err := addColumnIfMissing(tx, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
 
-	// Then run migrations to ensure all columns exist
-	// This must happen AFTER creating tables
-	if err := runMigrations(db); err != nil {
+	if err := migrateBaselineUniqueID(tx, !hadUniqueID); err != nil {
 		return err
 	}
 
+	// Older releases declared url UNIQUE on feeds and articles, which keeps
+	// FreshRSS and local feeds with the same URL (and articles shared between
+	// feeds) apart. SQLite cannot drop a constraint, so rebuild the tables.
+	for _, t := range baselineTables[:2] {
+		var tableSQL string
+		if err := tx.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, t.name).Scan(&tableSQL); err != nil {
+			return fmt.Errorf("inspect table %s: %w", t.name, err)
+		}
+		if strings.Contains(tableSQL, "url TEXT UNIQUE") {
+			if err := rebuildTable(tx, t.name, t.create); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := tx.Exec(baselineIndexes); err != nil {
+		return fmt.Errorf("create indexes: %w", err)
+	}
+	return nil
+}
+
+// migrateBaselineUniqueID fills in unique_id, the deduplication key of
+// articles, for articles stored before it existed. When the column has just
+// been added it gets a unique index, and articles whose key collides with an
+// existing one keep a NULL key.
+func migrateBaselineUniqueID(tx *sql.Tx, added bool) error {
+	if added {
+		if _, err := tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_articles_unique_id ON articles(unique_id)`); err != nil {
+			return fmt.Errorf("create unique_id index: %w", err)
+		}
+	}
+
+	rows, err := tx.Query(`SELECT id, COALESCE(title, ''), COALESCE(feed_id, 0), published_at FROM articles WHERE unique_id IS NULL`)
+	if err != nil {
+		return fmt.Errorf("query articles without unique_id: %w", err)
+	}
+	type pending struct {
+		id       int64
+		uniqueID string
+	}
+	var updates []pending
+	for rows.Next() {
+		var id, feedID int64
+		var title string
+		var publishedAt sql.NullTime
+		if err := rows.Scan(&id, &title, &feedID, &publishedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan article: %w", err)
+		}
+		updates = append(updates, pending{id, urlutil.GenerateArticleUniqueID(title, feedID, publishedAt.Time, publishedAt.Valid)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query articles without unique_id: %w", err)
+	}
+
+	for _, u := range updates {
+		if _, err := tx.Exec(`UPDATE OR IGNORE articles SET unique_id = ? WHERE id = ?`, u.uniqueID, u.id); err != nil {
+			return fmt.Errorf("set unique_id of article %d: %w", u.id, err)
+		}
+	}
+
+	// The publish date of these articles is unknown; use the migration time
+	if _, err := tx.Exec(`UPDATE articles SET published_at = ? WHERE published_at IS NULL`, time.Now().UTC()); err != nil {
+		return fmt.Errorf("backfill published_at: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"database/sql/driver"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// SQL functions used by compiled article filters, available on every
+// connection opened by this package:
+//
+//	regexp(pattern, text)  backs the REGEXP operator with Go regular expressions
+//	casefold(text)         lowercases text like strings.ToLower, beyond ASCII
+//	parse_time(text)       returns the Unix time of a stored time value, or NULL
+//
+// and by cold storage:
+//
+//	gzip(text)             compresses text to a gzip blob
+//	gunzip(blob)           returns the text of a gzip blob
+func init() {
+	registerSQLFunction("regexp", 2, sqlRegexp)
+	registerSQLFunction("casefold", 1, sqlCasefold)
+	registerSQLFunction("parse_time", 1, sqlParseTime)
+	registerSQLFunction("gzip", 1, sqlGzip)
+	registerSQLFunction("gunzip", 1, sqlGunzip)
+}
+
+func registerSQLFunction(name string, nArgs int32, fn func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error)) {
+	if err := sqlite.RegisterDeterministicScalarFunction(name, nArgs, fn); err != nil {
+		panic(err)
+	}
+}
+
+// maxSQLRegexps bounds the patterns sqlRegexps keeps compiled
+const maxSQLRegexps = 64
+
+// sqlRegexps caches compiled patterns, as regexp is called for every row
+var sqlRegexps = newRegexpCache(maxSQLRegexps)
+
+// regexpCache keeps the most recently used compiled patterns
+type regexpCache struct {
+	mu      sync.Mutex
+	max     int
+	order   *list.List // Of *regexpEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type regexpEntry struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func newRegexpCache(max int) *regexpCache {
+	return &regexpCache{max: max, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns the compiled pattern, compiling it unless it is cached
+func (c *regexpCache) get(pattern string) (*regexp.Regexp, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(el)
+		c.mu.Unlock()
+		return el.Value.(*regexpEntry).re, nil
+	}
+	c.mu.Unlock()
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*regexpEntry).re, nil
+	}
+	c.entries[pattern] = c.order.PushFront(&regexpEntry{pattern: pattern, re: re})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*regexpEntry).pattern)
+	}
+	return re, nil
+}
+
+func sqlRegexp(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	pattern, ok := sqlText(args[0])
+	if !ok {
+		return nil, nil
+	}
+	text, ok := sqlText(args[1])
+	if !ok {
+		return nil, nil
+	}
+
+	re, err := sqlRegexps.get(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if re.MatchString(text) {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+func sqlCasefold(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	text, _ := sqlText(args[0])
+	return strings.ToLower(text), nil
+}
+
+func sqlGzip(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	text, ok := sqlText(args[0])
+	if !ok {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, text); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func sqlGunzip(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	data, ok := args[0].([]byte)
+	if !ok {
+		return nil, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	text, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
+
+// storedTimeFormats are the layouts time values are found in, after the
+// time.String form the driver writes
+var storedTimeFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+func sqlParseTime(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	if t, ok := args[0].(time.Time); ok {
+		return t.Unix(), nil
+	}
+	text, ok := sqlText(args[0])
+	if !ok {
+		return nil, nil
+	}
+	if t, ok := parseStoredTime(text); ok {
+		return t.Unix(), nil
+	}
+	return nil, nil
+}
+
+// parseStoredTime parses a time value as stored in the database
+func parseStoredTime(s string) (time.Time, bool) {
+	// time.String form, e.g. "2024-01-02 03:04:05 +0000 UTC m=+0.5"
+	if i := strings.Index(s, " m="); i > 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", s); err == nil {
+		return t, true
+	}
+	s = strings.TrimSuffix(s, "Z")
+	for _, layout := range storedTimeFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// sqlText returns a function argument as text, reporting false for NULL
+func sqlText(v driver.Value) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
@@ -0,0 +1,35 @@
+package database
+
+import "testing"
+
+func TestRegexpCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newRegexpCache(2)
+
+	a, err := c.get("a+")
+	if err != nil {
+		t.Fatalf("get(a+) error = %v", err)
+	}
+	if _, err := c.get("b+"); err != nil {
+		t.Fatalf("get(b+) error = %v", err)
+	}
+	// Using a+ again makes b+ the least recently used pattern
+	if again, _ := c.get("a+"); again != a {
+		t.Error("a+ was compiled again instead of taken from the cache")
+	}
+	if _, err := c.get("c+"); err != nil {
+		t.Fatalf("get(c+) error = %v", err)
+	}
+
+	if c.order.Len() != 2 {
+		t.Fatalf("cache holds %d patterns, want 2", c.order.Len())
+	}
+	if _, ok := c.entries["b+"]; ok {
+		t.Error("b+ was not evicted")
+	}
+	if _, ok := c.entries["a+"]; !ok {
+		t.Error("a+ was evicted")
+	}
+	if _, err := c.get("("); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
@@ -48,7 +48,9 @@ func TestDatabaseInitialization(t *testing.T) {
 		t.Errorf("Expected at least 8 indexes, got %d", indexCount)
 	}
 
-	// Schema version table removed in development - skip version check
+	if version := schemaVersionOf(t, db.DB); version != latestSchemaVersion() {
+		t.Errorf("Expected schema version %d, got %d", latestSchemaVersion(), version)
+	}
 }
 
 func TestDatabasePerformanceWithIndexes(t *testing.T) {
@@ -162,8 +164,7 @@ func TestMigrationIdempotency(t *testing.T) {
 		}
 	}
 
-	// Schema version table removed in development - migrations not used
-	// Just verify tables still exist after multiple inits
+	// Verify tables still exist after multiple inits
 	var tableCount int
 	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name IN ('feeds', 'articles', 'settings')").Scan(&tableCount)
 	if err != nil {
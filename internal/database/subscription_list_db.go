@@ -0,0 +1,245 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"MrRSS/internal/models"
+)
+
+// maxSubscriptionListLogPerList is the number of log entries kept for each subscription list
+const maxSubscriptionListLogPerList = 500
+
+// AddSubscriptionList stores a new subscription list and returns its ID
+func (db *DB) AddSubscriptionList(list *models.SubscriptionList) (int64, error) {
+	db.WaitForReady()
+
+	if list.RefreshInterval <= 0 {
+		list.RefreshInterval = 360
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO subscription_lists (url, title, category, refresh_interval)
+		VALUES (?, ?, ?, ?)
+	`, list.URL, list.Title, list.Category, list.RefreshInterval)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// subscriptionListColumns selects a subscription list together with its feed count
+const subscriptionListColumns = `
+	l.id, l.url, COALESCE(l.title, ''), l.category, COALESCE(l.refresh_interval, 360),
+	l.last_synced, COALESCE(l.last_error, ''), l.created_at,
+	(SELECT COUNT(*) FROM subscription_list_feeds lf WHERE lf.list_id = l.id)`
+
+// scanSubscriptionList scans a row selected with subscriptionListColumns
+func scanSubscriptionList(scanner interface{ Scan(...interface{}) error }) (*models.SubscriptionList, error) {
+	var list models.SubscriptionList
+	var lastSynced sql.NullTime
+	if err := scanner.Scan(&list.ID, &list.URL, &list.Title, &list.Category, &list.RefreshInterval,
+		&lastSynced, &list.LastError, &list.CreatedAt, &list.FeedCount); err != nil {
+		return nil, err
+	}
+	if lastSynced.Valid {
+		list.LastSynced = &lastSynced.Time
+	}
+	return &list, nil
+}
+
+// GetSubscriptionLists returns all subscription lists
+func (db *DB) GetSubscriptionLists() ([]models.SubscriptionList, error) {
+	db.WaitForReady()
+
+	rows, err := db.Query(`SELECT ` + subscriptionListColumns + ` FROM subscription_lists l ORDER BY l.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := make([]models.SubscriptionList, 0)
+	for rows.Next() {
+		list, err := scanSubscriptionList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, *list)
+	}
+	return lists, rows.Err()
+}
+
+// GetSubscriptionList returns a subscription list by ID
+func (db *DB) GetSubscriptionList(id int64) (*models.SubscriptionList, error) {
+	db.WaitForReady()
+	return scanSubscriptionList(db.QueryRow(`SELECT `+subscriptionListColumns+` FROM subscription_lists l WHERE l.id = ?`, id))
+}
+
+// DeleteSubscriptionList removes a subscription list, its provenance records and its log.
+// The feeds themselves are left in place; callers delete them if wanted.
+func (db *DB) DeleteSubscriptionList(id int64) error {
+	db.WaitForReady()
+	_, err := db.Exec(`DELETE FROM subscription_lists WHERE id = ?`, id)
+	return err
+}
+
+// UpdateSubscriptionListSyncResult records the outcome of a sync.
+// The title is only updated when a non-empty one is given.
+func (db *DB) UpdateSubscriptionListSyncResult(id int64, title string, syncErr string) error {
+	db.WaitForReady()
+	_, err := db.Exec(`
+		UPDATE subscription_lists
+		SET last_synced = ?, last_error = ?, title = CASE WHEN ? != '' THEN ? ELSE title END
+		WHERE id = ?
+	`, time.Now(), syncErr, title, title, id)
+	return err
+}
+
+// GetSubscriptionListFeeds returns the feeds owned by a list, keyed by feed URL
+func (db *DB) GetSubscriptionListFeeds(listID int64) (map[string]models.Feed, error) {
+	db.WaitForReady()
+
+	rows, err := db.Query(`
+		SELECT f.id, f.url, COALESCE(f.title, ''), COALESCE(f.category, '')
+		FROM subscription_list_feeds lf
+		JOIN feeds f ON f.id = lf.feed_id
+		WHERE lf.list_id = ?
+	`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feeds := make(map[string]models.Feed)
+	for rows.Next() {
+		var f models.Feed
+		if err := rows.Scan(&f.ID, &f.URL, &f.Title, &f.Category); err != nil {
+			return nil, err
+		}
+		feeds[f.URL] = f
+	}
+	return feeds, rows.Err()
+}
+
+// AddSubscriptionListFeed records that a feed was added by a subscription list
+func (db *DB) AddSubscriptionListFeed(listID, feedID int64) error {
+	db.WaitForReady()
+	_, err := db.Exec(`INSERT OR IGNORE INTO subscription_list_feeds (list_id, feed_id) VALUES (?, ?)`, listID, feedID)
+	return err
+}
+
+// DeleteFeedAndExclude deletes a feed the user unsubscribed from and, when a
+// subscription list added it, records that the list must not add it again.
+// Both happen in one transaction.
+func (db *DB) DeleteFeedAndExclude(feedID int64) error {
+	db.WaitForReady()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO subscription_list_exclusions (list_id, url)
+		SELECT lf.list_id, f.url
+		FROM subscription_list_feeds lf
+		JOIN feeds f ON f.id = lf.feed_id
+		WHERE lf.feed_id = ?
+	`, feedID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM articles WHERE feed_id = ?`, feedID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM feeds WHERE id = ?`, feedID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetSubscriptionListExclusions returns the URLs of the feeds the user
+// unsubscribed from, which the list must not add again
+func (db *DB) GetSubscriptionListExclusions(listID int64) (map[string]bool, error) {
+	db.WaitForReady()
+
+	rows, err := db.Query(`SELECT url FROM subscription_list_exclusions WHERE list_id = ?`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	excluded := make(map[string]bool)
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		excluded[url] = true
+	}
+	return excluded, rows.Err()
+}
+
+// AddSubscriptionListLog appends a log entry and trims the list's log
+// to the most recent maxSubscriptionListLogPerList entries.
+func (db *DB) AddSubscriptionListLog(entry *models.SubscriptionListLogEntry) error {
+	db.WaitForReady()
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO subscription_list_log (list_id, created_at, action, feed_url, feed_title, message)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, entry.ListID, entry.CreatedAt, entry.Action, entry.FeedURL, entry.FeedTitle, entry.Message)
+	if err != nil {
+		return err
+	}
+
+	if id, err := result.LastInsertId(); err == nil {
+		entry.ID = id
+	}
+
+	_, err = db.Exec(`
+		DELETE FROM subscription_list_log
+		WHERE list_id = ? AND id NOT IN (
+			SELECT id FROM subscription_list_log
+			WHERE list_id = ?
+			ORDER BY created_at DESC, id DESC
+			LIMIT ?
+		)
+	`, entry.ListID, entry.ListID, maxSubscriptionListLogPerList)
+	return err
+}
+
+// GetSubscriptionListLog returns the most recent log entries of a list, newest first
+func (db *DB) GetSubscriptionListLog(listID int64, limit int) ([]models.SubscriptionListLogEntry, error) {
+	db.WaitForReady()
+
+	if limit <= 0 || limit > maxSubscriptionListLogPerList {
+		limit = 100
+	}
+
+	rows, err := db.Query(`
+		SELECT id, list_id, created_at, action, COALESCE(feed_url, ''), COALESCE(feed_title, ''), COALESCE(message, '')
+		FROM subscription_list_log
+		WHERE list_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, listID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.SubscriptionListLogEntry, 0)
+	for rows.Next() {
+		var e models.SubscriptionListLogEntry
+		if err := rows.Scan(&e.ID, &e.ListID, &e.CreatedAt, &e.Action, &e.FeedURL, &e.FeedTitle, &e.Message); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
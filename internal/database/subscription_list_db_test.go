@@ -0,0 +1,45 @@
+package database_test
+
+import (
+	"testing"
+
+	"MrRSS/internal/models"
+)
+
+func TestDeleteFeedAndExclude(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	listID, err := db.AddSubscriptionList(&models.SubscriptionList{URL: "https://lists.example.com/opml"})
+	if err != nil {
+		t.Fatalf("AddSubscriptionList() error = %v", err)
+	}
+	listed, err := db.AddFeed(&models.Feed{Title: "Listed", URL: "https://listed.example.com/feed"})
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+	if err := db.AddSubscriptionListFeed(listID, listed); err != nil {
+		t.Fatalf("AddSubscriptionListFeed() error = %v", err)
+	}
+	own, err := db.AddFeed(&models.Feed{Title: "Own", URL: "https://own.example.com/feed"})
+	if err != nil {
+		t.Fatalf("AddFeed() error = %v", err)
+	}
+
+	for _, id := range []int64{listed, own} {
+		if err := db.DeleteFeedAndExclude(id); err != nil {
+			t.Fatalf("DeleteFeedAndExclude(%d) error = %v", id, err)
+		}
+		if _, err := db.GetFeedByID(id); err == nil {
+			t.Errorf("feed %d was not deleted", id)
+		}
+	}
+
+	excluded, err := db.GetSubscriptionListExclusions(listID)
+	if err != nil {
+		t.Fatalf("GetSubscriptionListExclusions() error = %v", err)
+	}
+	if len(excluded) != 1 || !excluded["https://listed.example.com/feed"] {
+		t.Errorf("exclusions = %v, want only the listed feed", excluded)
+	}
+}
@@ -0,0 +1,266 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxWriteBatch caps how many queued statements share one transaction, so a
+// long queue does not hold the write lock for too long at a time.
+const maxWriteBatch = 64
+
+// writeQueueSize is how many statements can wait before Exec blocks.
+const writeQueueSize = 256
+
+// WriterStats reports the activity of the serialized writer.
+type WriterStats struct {
+	Pending        int     `json:"pending"`
+	Statements     int64   `json:"statements"`
+	Batches        int64   `json:"batches"`
+	Failed         int64   `json:"failed"`
+	LargestBatch   int     `json:"largest_batch"`
+	AvgBatchSize   float64 `json:"avg_batch_size"`
+	AvgQueueWaitMs float64 `json:"avg_queue_wait_ms"`
+	MaxQueueWaitMs float64 `json:"max_queue_wait_ms"`
+}
+
+type writeRequest struct {
+	ctx      context.Context
+	query    string
+	args     []any
+	enqueued time.Time
+	done     chan writeResult
+}
+
+type writeResult struct {
+	res sql.Result
+	err error
+}
+
+// writer runs every Exec of a DB on one goroutine. Statements queued while a
+// batch runs are executed together in the next transaction, each inside its
+// own savepoint so that a failing statement does not undo the others.
+type writer struct {
+	pool  *sql.DB
+	queue chan *writeRequest
+
+	// mu guards closed; Exec holds it for reading while enqueueing so that
+	// Close cannot close the queue under a sender
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+
+	statsMu      sync.Mutex
+	statements   int64
+	batches      int64
+	failed       int64
+	largestBatch int
+	totalWait    time.Duration
+	maxWait      time.Duration
+}
+
+func newWriter(pool *sql.DB) *writer {
+	w := &writer{
+		pool:  pool,
+		queue: make(chan *writeRequest, writeQueueSize),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// exec queues a statement and waits for its result.
+func (w *writer) exec(ctx context.Context, query string, args []any) (sql.Result, error) {
+	req := &writeRequest{
+		ctx:      ctx,
+		query:    query,
+		args:     args,
+		enqueued: time.Now(),
+		done:     make(chan writeResult, 1),
+	}
+
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return nil, sql.ErrConnDone
+	}
+	select {
+	case w.queue <- req:
+	case <-ctx.Done():
+		w.mu.RUnlock()
+		return nil, ctx.Err()
+	}
+	w.mu.RUnlock()
+
+	r := <-req.done
+	return r.res, r.err
+}
+
+// close stops accepting statements, waits for the queued ones to finish and
+// stops the goroutine.
+func (w *writer) close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	<-w.done
+}
+
+func (w *writer) run() {
+	defer close(w.done)
+
+	var next *writeRequest
+	for {
+		req := next
+		next = nil
+		if req == nil {
+			var ok bool
+			if req, ok = <-w.queue; !ok {
+				return
+			}
+		}
+
+		// Statements that cannot run inside a transaction go alone
+		batch := []*writeRequest{req}
+		if !mustRunAlone(req.query) {
+		collect:
+			for len(batch) < maxWriteBatch {
+				select {
+				case more, ok := <-w.queue:
+					if !ok {
+						break collect
+					}
+					if mustRunAlone(more.query) {
+						next = more
+						break collect
+					}
+					batch = append(batch, more)
+				default:
+					break collect
+				}
+			}
+		}
+
+		w.record(batch)
+		if len(batch) == 1 {
+			w.execAlone(batch[0])
+		} else {
+			w.execBatch(batch)
+		}
+	}
+}
+
+func (w *writer) execAlone(req *writeRequest) {
+	res, err := w.pool.ExecContext(req.ctx, req.query, req.args...)
+	w.finish(req, res, err)
+}
+
+// execBatch runs the statements in one transaction. Statements are not bound
+// to their callers' contexts once started: interrupting a statement would roll
+// back the whole transaction, so a cancelled request is only skipped.
+func (w *writer) execBatch(batch []*writeRequest) {
+	tx, err := w.pool.Begin()
+	if err != nil {
+		for _, req := range batch {
+			w.execAlone(req)
+		}
+		return
+	}
+
+	results := make([]writeResult, len(batch))
+	for i, req := range batch {
+		if err := req.ctx.Err(); err != nil {
+			results[i].err = err
+			continue
+		}
+		if _, err := tx.Exec(`SAVEPOINT batched_write`); err != nil {
+			results[i].err = err
+			continue
+		}
+		res, err := tx.Exec(req.query, req.args...)
+		if err != nil {
+			_, _ = tx.Exec(`ROLLBACK TO batched_write`)
+		}
+		_, _ = tx.Exec(`RELEASE batched_write`)
+		results[i] = writeResult{res: res, err: err}
+	}
+
+	if err := tx.Commit(); err != nil {
+		for i := range results {
+			if results[i].err == nil {
+				results[i] = writeResult{err: err}
+			}
+		}
+	}
+	for i, req := range batch {
+		w.finish(req, results[i].res, results[i].err)
+	}
+}
+
+func (w *writer) finish(req *writeRequest, res sql.Result, err error) {
+	if err != nil {
+		w.statsMu.Lock()
+		w.failed++
+		w.statsMu.Unlock()
+	}
+	req.done <- writeResult{res: res, err: err}
+}
+
+func (w *writer) record(batch []*writeRequest) {
+	now := time.Now()
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+
+	w.batches++
+	w.statements += int64(len(batch))
+	if len(batch) > w.largestBatch {
+		w.largestBatch = len(batch)
+	}
+	for _, req := range batch {
+		wait := now.Sub(req.enqueued)
+		w.totalWait += wait
+		if wait > w.maxWait {
+			w.maxWait = wait
+		}
+	}
+}
+
+func (w *writer) stats() WriterStats {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+
+	stats := WriterStats{
+		Pending:        len(w.queue),
+		Statements:     w.statements,
+		Batches:        w.batches,
+		Failed:         w.failed,
+		LargestBatch:   w.largestBatch,
+		MaxQueueWaitMs: float64(w.maxWait) / float64(time.Millisecond),
+	}
+	if w.batches > 0 {
+		stats.AvgBatchSize = float64(w.statements) / float64(w.batches)
+	}
+	if w.statements > 0 {
+		stats.AvgQueueWaitMs = float64(w.totalWait) / float64(w.statements) / float64(time.Millisecond)
+	}
+	return stats
+}
+
+// mustRunAlone reports whether a statement has to run outside a transaction,
+// or changes transaction state itself.
+func mustRunAlone(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return true
+	}
+	switch strings.ToUpper(strings.TrimRight(fields[0], ";")) {
+	case "PRAGMA", "VACUUM", "BEGIN", "COMMIT", "END", "ROLLBACK", "SAVEPOINT", "RELEASE", "ATTACH", "DETACH":
+		return true
+	}
+	return false
+}
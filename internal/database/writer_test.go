@@ -0,0 +1,137 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriterSerializesConcurrentWrites(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "writer.db"))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	defer db.Close()
+	if err := db.Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	if _, err := db.Exec(`CREATE TABLE writes (id INTEGER PRIMARY KEY, name TEXT UNIQUE)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	const workers, perWorker = 16, 50
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				res, err := db.Exec(`INSERT INTO writes (name) VALUES (?)`, fmt.Sprintf("%d-%d", w, i))
+				if err == nil {
+					if id, idErr := res.LastInsertId(); idErr != nil || id == 0 {
+						err = fmt.Errorf("LastInsertId = %d, %v", id, idErr)
+					}
+				}
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent insert: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM writes`).Scan(&count); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != workers*perWorker {
+		t.Errorf("rows = %d, want %d", count, workers*perWorker)
+	}
+
+	stats := db.WriterStats()
+	if stats.Statements < workers*perWorker {
+		t.Errorf("stats.Statements = %d, want at least %d", stats.Statements, workers*perWorker)
+	}
+	if stats.Batches > stats.Statements || stats.Pending != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestWriterBatchIsolatesFailingStatement(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "writer.db"))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE writes (id INTEGER PRIMARY KEY, name TEXT UNIQUE)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	// Hold the write connection so that the statements queue up and run as
+	// one batch once it is released
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	names := []string{"a", "b", "a", "c"}
+	results := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			_, results[i] = db.Exec(`INSERT INTO writes (name) VALUES (?)`, name)
+		}(i, name)
+	}
+	// Wait until every statement is queued or picked up
+	for {
+		stats := db.WriterStats()
+		if stats.Pending+int(stats.Statements) >= len(names)+1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range results {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("failed statements = %d, want 1 (results %v)", failed, results)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM writes`).Scan(&count); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("rows = %d, want 3", count)
+	}
+}
+
+func TestWriterRunsPragmasAlone(t *testing.T) {
+	for _, query := range []string{"PRAGMA incremental_vacuum", "  vacuum", "BEGIN IMMEDIATE", ""} {
+		if !mustRunAlone(query) {
+			t.Errorf("mustRunAlone(%q) = false, want true", query)
+		}
+	}
+	for _, query := range []string{"INSERT INTO t VALUES (1)", "UPDATE t SET a = 1", "\n\t\tDELETE FROM t"} {
+		if mustRunAlone(query) {
+			t.Errorf("mustRunAlone(%q) = true, want false", query)
+		}
+	}
+}
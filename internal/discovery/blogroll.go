@@ -0,0 +1,167 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"MrRSS/internal/opml"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Blogroll discovery limits
+const (
+	// MaxBlogrollOPMLs limits how many OPML blogrolls are fetched per site
+	MaxBlogrollOPMLs = 3
+	// maxBlogrollSize caps the size of a fetched OPML blogroll
+	maxBlogrollSize = 5 << 20
+)
+
+// wellKnownBlogrollPaths are /.well-known/ locations where sites publish their blogroll
+var wellKnownBlogrollPaths = []string{
+	"/.well-known/recommendations.opml",
+}
+
+// discoverFromBlogrolls finds OPML blogrolls published by a site and returns their feeds.
+// Blogrolls are found via <link rel="blogroll">, OPML <link>/<a> references on the
+// homepage and /.well-known/ hints.
+func (s *Service) discoverFromBlogrolls(ctx context.Context, homepage string, doc *goquery.Document) []DiscoveredBlog {
+	var opmlURLs []string
+	if doc != nil {
+		opmlURLs = s.findBlogrollLinks(doc, homepage)
+	}
+	if u, err := url.Parse(homepage); err == nil && u.Host != "" {
+		for _, p := range wellKnownBlogrollPaths {
+			opmlURLs = append(opmlURLs, fmt.Sprintf("%s://%s%s", u.Scheme, u.Host, p))
+		}
+	}
+
+	seenOPML := make(map[string]bool)
+	seenFeed := make(map[string]bool)
+	var discovered []DiscoveredBlog
+	fetched := 0
+
+	for _, opmlURL := range opmlURLs {
+		if fetched >= MaxBlogrollOPMLs || ctx.Err() != nil {
+			break
+		}
+		if seenOPML[opmlURL] {
+			continue
+		}
+		seenOPML[opmlURL] = true
+
+		feeds, err := s.fetchBlogroll(ctx, opmlURL)
+		if err != nil {
+			continue
+		}
+		fetched++
+
+		for _, feed := range feeds {
+			if feed.URL == "" || seenFeed[feed.URL] {
+				continue
+			}
+			seenFeed[feed.URL] = true
+
+			blogHomepage := feed.Link
+			if blogHomepage == "" {
+				if u, err := url.Parse(feed.URL); err == nil {
+					blogHomepage = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+				}
+			}
+
+			discovered = append(discovered, DiscoveredBlog{
+				Name:     feed.Title,
+				Homepage: blogHomepage,
+				RSSFeed:  feed.URL,
+				IconURL:  s.getFavicon(blogHomepage),
+			})
+		}
+	}
+
+	return discovered
+}
+
+// findBlogrollLinks returns the OPML blogroll URLs referenced by a page
+func (s *Service) findBlogrollLinks(doc *goquery.Document, pageURL string) []string {
+	seen := make(map[string]bool)
+	var links []string
+	add := func(href string) {
+		if absURL := s.resolveURL(pageURL, strings.TrimSpace(href)); absURL != "" && !seen[absURL] {
+			seen[absURL] = true
+			links = append(links, absURL)
+		}
+	}
+
+	// <link rel="blogroll"> is the explicit hint; OPML-typed links are next best
+	doc.Find("link[href]").Each(func(i int, sel *goquery.Selection) {
+		rel := strings.ToLower(sel.AttrOr("rel", ""))
+		typ := strings.ToLower(sel.AttrOr("type", ""))
+		if containsToken(rel, "blogroll") || strings.Contains(typ, "opml") {
+			add(sel.AttrOr("href", ""))
+		}
+	})
+
+	doc.Find("a[href]").Each(func(i int, sel *goquery.Selection) {
+		href := sel.AttrOr("href", "")
+		if u, err := url.Parse(href); err == nil && strings.HasSuffix(strings.ToLower(u.Path), ".opml") {
+			add(href)
+		}
+	})
+
+	return links
+}
+
+// fetchBlogroll downloads an OPML blogroll and parses it with the OPML importer
+func (s *Service) fetchBlogroll(ctx context.Context, opmlURL string) ([]blogrollFeed, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", opmlURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "MrRSS (Blog Discovery Bot)")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	}
+
+	feeds, err := opml.Parse(io.LimitReader(resp.Body, maxBlogrollSize))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]blogrollFeed, 0, len(feeds))
+	for _, f := range feeds {
+		feedURL := s.resolveURL(opmlURL, f.URL)
+		if u, err := url.Parse(feedURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		result = append(result, blogrollFeed{Title: f.Title, URL: feedURL, Link: f.Link})
+	}
+	return result, nil
+}
+
+// blogrollFeed is a feed listed in an OPML blogroll
+type blogrollFeed struct {
+	Title string
+	URL   string
+	Link  string
+}
+
+// containsToken reports whether a space-separated attribute value contains token
+func containsToken(value, token string) bool {
+	for _, field := range strings.Fields(value) {
+		if field == token {
+			return true
+		}
+	}
+	return false
+}
@@ -286,3 +286,148 @@ func TestGetFaviconAndResolveURLAndExtractLinks(t *testing.T) {
 		t.Fatalf("resolveURL failed: %s", resolved)
 	}
 }
+
+func TestAggregateLinkedDomains(t *testing.T) {
+	s := NewService()
+
+	articles := []ArticleSignal{
+		{
+			URL:      "https://own.example.com/post/1",
+			Favorite: true,
+			Content: `<a href="https://www.loved.example.org/a">a</a>
+				<a href="https://loved.example.org/b">b</a>
+				<a href="/relative">own</a>
+				<a href="https://twitter.com/someone">social</a>`,
+		},
+		{
+			URL:     "https://own.example.com/post/2",
+			Read:    true,
+			Content: `<a href="https://loved.example.org/c">c</a><a href="https://other.example.net/">d</a>`,
+		},
+		{
+			URL:     "https://own.example.com/post/3",
+			Content: `<a href="https://ignored.example.com/">not engaged</a>`,
+		},
+	}
+
+	stats := s.aggregateLinkedDomains(articles)
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 domains, got %+v", stats)
+	}
+
+	top := stats[0]
+	if top.host != "loved.example.org" {
+		t.Fatalf("expected loved.example.org first, got %s", top.host)
+	}
+	if top.favoriteLinks != 1 || top.readLinks != 1 || top.score != favoriteWeight+readWeight {
+		t.Fatalf("unexpected stats for top domain: %+v", top)
+	}
+	if got := top.explanation(); got != "linked once from articles you favorited, linked once from articles you read" {
+		t.Fatalf("unexpected explanation: %q", got)
+	}
+	if stats[1].host != "other.example.net" {
+		t.Fatalf("expected other.example.net second, got %s", stats[1].host)
+	}
+}
+
+func TestRecommendFromReading(t *testing.T) {
+	newBlog := func(title string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/":
+				w.Header().Set("Content-Type", "text/html")
+				_, _ = w.Write([]byte(`<html><head><link rel="alternate" type="application/rss+xml" href="/feed.xml"></head></html>`))
+			case "/feed.xml":
+				w.Header().Set("Content-Type", "application/rss+xml")
+				_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>` + title + `</title><item><title>Hello</title></item></channel></rss>`))
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+	}
+
+	recommended := newBlog("Recommended Blog")
+	defer recommended.Close()
+	subscribed := newBlog("Subscribed Blog")
+	defer subscribed.Close()
+
+	var articles []ArticleSignal
+	for i := 0; i < 14; i++ {
+		articles = append(articles, ArticleSignal{
+			URL:      "https://own.example.com/post",
+			Favorite: true,
+			Content:  `<a href="` + recommended.URL + `/post">x</a><a href="` + subscribed.URL + `/post">y</a>`,
+		})
+	}
+
+	s := newServiceWithClient(recommended.Client())
+	s.feedParser.Client = recommended.Client()
+
+	recs, err := s.RecommendFromReading(context.Background(), articles, map[string]bool{subscribed.URL + "/feed.xml": true}, 5)
+	if err != nil {
+		t.Fatalf("RecommendFromReading error: %v", err)
+	}
+	if len(recs) != 1 {
+		t.Fatalf("expected 1 recommendation, got %+v", recs)
+	}
+
+	rec := recs[0]
+	if rec.Name != "Recommended Blog" || rec.RSSFeed != recommended.URL+"/feed.xml" {
+		t.Fatalf("unexpected recommendation: %+v", rec)
+	}
+	if rec.Explanation != "linked 14 times from articles you favorited" {
+		t.Fatalf("unexpected explanation: %q", rec.Explanation)
+	}
+}
+
+func TestDiscoverFromBlogrolls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><head><link rel="blogroll" type="text/xml" href="/blogroll.opml"></head>
+				<body><a href="/more/links.OPML">More</a></body></html>`))
+		case "/blogroll.opml":
+			_, _ = w.Write([]byte(`<?xml version="1.0"?><opml version="2.0"><body>
+				<outline text="Alice" type="rss" xmlUrl="https://alice.example.com/feed.xml" htmlUrl="https://alice.example.com/"/>
+				<outline text="Bob" type="rss" xmlUrl="/bob/rss.xml"/>
+			</body></opml>`))
+		case "/more/links.OPML":
+			_, _ = w.Write([]byte(`<?xml version="1.0"?><opml version="2.0"><body>
+				<outline text="Alice again" xmlUrl="https://alice.example.com/feed.xml"/>
+			</body></opml>`))
+		case "/.well-known/recommendations.opml":
+			_, _ = w.Write([]byte(`<?xml version="1.0"?><opml version="2.0"><body>
+				<outline text="Carol" xmlUrl="https://carol.example.org/atom.xml"/>
+			</body></opml>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	s := newServiceWithClient(srv.Client())
+	doc, err := s.fetchHTML(context.Background(), srv.URL+"/")
+	if err != nil {
+		t.Fatalf("fetchHTML error: %v", err)
+	}
+
+	links := s.findBlogrollLinks(doc, srv.URL+"/")
+	if len(links) != 2 || !strings.HasSuffix(links[0], "/blogroll.opml") || !strings.HasSuffix(links[1], "/more/links.OPML") {
+		t.Fatalf("unexpected blogroll links: %v", links)
+	}
+
+	blogs := s.discoverFromBlogrolls(context.Background(), srv.URL+"/", doc)
+	if len(blogs) != 3 {
+		t.Fatalf("expected 3 blogs, got %+v", blogs)
+	}
+	if blogs[0].Name != "Alice" || blogs[0].Homepage != "https://alice.example.com/" {
+		t.Fatalf("unexpected first blog: %+v", blogs[0])
+	}
+	if blogs[1].RSSFeed != srv.URL+"/bob/rss.xml" {
+		t.Fatalf("expected relative feed URL to be resolved, got %s", blogs[1].RSSFeed)
+	}
+	if blogs[2].RSSFeed != "https://carol.example.org/atom.xml" {
+		t.Fatalf("expected well-known blogroll feed, got %+v", blogs[2])
+	}
+}
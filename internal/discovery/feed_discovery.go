@@ -32,22 +32,42 @@ func (s *Service) DiscoverFromFeedWithProgress(ctx context.Context, feedURL stri
 		return nil, fmt.Errorf("failed to get homepage from feed: %w", err)
 	}
 
-	// Report progress: finding friend links
+	// Report progress: checking blogrolls
 	if progressCb != nil {
 		progressCb(Progress{
-			Stage:   "finding_friend_links",
-			Message: "Searching for friend links",
+			Stage:   "checking_blogroll",
+			Message: "Looking for OPML blogrolls",
 			Detail:  homepage,
 		})
 	}
 
+	// OPML blogrolls list feed URLs directly, so no RSS detection is needed
+	homeDoc, _ := s.fetchHTML(ctx, homepage)
+	blogrollBlogs := s.discoverFromBlogrolls(ctx, homepage, homeDoc)
+
+	// Report progress: finding friend links
+	if progressCb != nil {
+		progressCb(Progress{
+			Stage:      "finding_friend_links",
+			Message:    "Searching for friend links",
+			Detail:     homepage,
+			FoundCount: len(blogrollBlogs),
+		})
+	}
+
 	// Fetch the homepage HTML
 	friendLinks, err := s.findFriendLinksWithProgress(ctx, homepage, progressCb)
 	if err != nil {
+		if len(blogrollBlogs) > 0 {
+			return blogrollBlogs, nil
+		}
 		return nil, fmt.Errorf("failed to find friend links: %w", err)
 	}
 
 	if len(friendLinks) == 0 {
+		if len(blogrollBlogs) > 0 {
+			return blogrollBlogs, nil
+		}
 		return []DiscoveredBlog{}, nil
 	}
 
@@ -63,7 +83,23 @@ func (s *Service) DiscoverFromFeedWithProgress(ctx context.Context, feedURL stri
 	// Discover RSS feeds from friend links (concurrent)
 	discovered := s.discoverRSSFeedsWithProgress(ctx, friendLinks, progressCb)
 
-	return discovered, nil
+	return mergeDiscoveredBlogs(blogrollBlogs, discovered), nil
+}
+
+// mergeDiscoveredBlogs concatenates discovery results, dropping duplicate feeds
+func mergeDiscoveredBlogs(lists ...[]DiscoveredBlog) []DiscoveredBlog {
+	seen := make(map[string]bool)
+	merged := make([]DiscoveredBlog, 0)
+	for _, list := range lists {
+		for _, blog := range list {
+			if seen[blog.RSSFeed] {
+				continue
+			}
+			seen[blog.RSSFeed] = true
+			merged = append(merged, blog)
+		}
+	}
+	return merged
 }
 
 // getFeedHomepage extracts the homepage URL from a feed
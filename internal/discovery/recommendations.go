@@ -0,0 +1,251 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Recommendation tuning constants
+const (
+	// MaxRecommendationCandidates limits how many top domains are probed for feeds
+	MaxRecommendationCandidates = 20
+	// DefaultRecommendationLimit is the number of recommendations returned by default
+	DefaultRecommendationLimit = 10
+)
+
+// Engagement weights: a favorite says more about taste than a plain read
+const (
+	favoriteWeight  = 3.0
+	readLaterWeight = 2.0
+	readWeight      = 1.0
+)
+
+// ArticleSignal is an article the user engaged with and the HTML to mine for links
+type ArticleSignal struct {
+	URL       string
+	FeedLink  string // Homepage of the article's feed, excluded from recommendations
+	Content   string
+	Favorite  bool
+	ReadLater bool
+	Read      bool
+}
+
+// Recommendation is a blog suggested because articles the user engaged with link to it
+type Recommendation struct {
+	DiscoveredBlog
+	Domain         string  `json:"domain"`
+	Score          float64 `json:"score"`
+	FavoriteLinks  int     `json:"favorite_links"`
+	ReadLaterLinks int     `json:"read_later_links"`
+	ReadLinks      int     `json:"read_links"`
+	Explanation    string  `json:"explanation"`
+}
+
+// domainStat aggregates how often a domain is linked from engaged articles
+type domainStat struct {
+	host           string
+	homepage       string
+	score          float64
+	favoriteLinks  int
+	readLaterLinks int
+	readLinks      int
+}
+
+// RecommendFromReading suggests blogs that are frequently linked from articles the user
+// read, favorited or saved. Domains are ranked by engagement-weighted link counts and the
+// top ones are probed for feeds; feeds in subscribedURLs are excluded.
+func (s *Service) RecommendFromReading(ctx context.Context, articles []ArticleSignal, subscribedURLs map[string]bool, limit int) ([]Recommendation, error) {
+	if limit <= 0 {
+		limit = DefaultRecommendationLimit
+	}
+
+	subscribedHosts := make(map[string]bool, len(subscribedURLs))
+	for feedURL := range subscribedURLs {
+		if u, err := url.Parse(feedURL); err == nil && u.Host != "" {
+			subscribedHosts[normalizeHost(u.Host)] = true
+		}
+	}
+
+	var candidates []domainStat
+	for _, stat := range s.aggregateLinkedDomains(articles) {
+		if subscribedHosts[stat.host] {
+			continue
+		}
+		candidates = append(candidates, stat)
+		if len(candidates) >= MaxRecommendationCandidates {
+			break
+		}
+	}
+
+	results := make([]Recommendation, 0, len(candidates))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, MaxConcurrentRSSChecks)
+
+	for _, stat := range candidates {
+		wg.Add(1)
+		go func(stat domainStat) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+
+			blog, err := s.discoverBlogRSS(ctx, stat.homepage)
+			if err != nil || subscribedURLs[blog.RSSFeed] {
+				return
+			}
+
+			mu.Lock()
+			results = append(results, Recommendation{
+				DiscoveredBlog: blog,
+				Domain:         stat.host,
+				Score:          stat.score,
+				FavoriteLinks:  stat.favoriteLinks,
+				ReadLaterLinks: stat.readLaterLinks,
+				ReadLinks:      stat.readLinks,
+				Explanation:    stat.explanation(),
+			})
+			mu.Unlock()
+		}(stat)
+	}
+	wg.Wait()
+
+	if len(results) == 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Domain < results[j].Domain
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// aggregateLinkedDomains extracts outbound links from the articles and ranks the linked
+// domains by engagement weight. Each article counts at most once per domain.
+func (s *Service) aggregateLinkedDomains(articles []ArticleSignal) []domainStat {
+	stats := make(map[string]*domainStat)
+
+	for _, article := range articles {
+		weight := articleWeight(article)
+		if weight == 0 || article.Content == "" {
+			continue
+		}
+
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(article.Content))
+		if err != nil {
+			continue
+		}
+
+		ownHosts := map[string]bool{}
+		for _, raw := range []string{article.URL, article.FeedLink} {
+			if u, err := url.Parse(raw); err == nil && u.Host != "" {
+				ownHosts[normalizeHost(u.Host)] = true
+			}
+		}
+
+		linked := make(map[string]bool)
+		doc.Find("a[href]").Each(func(i int, sel *goquery.Selection) {
+			href, _ := sel.Attr("href")
+			u, err := url.Parse(s.resolveURL(article.URL, href))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return
+			}
+
+			host := normalizeHost(u.Host)
+			if ownHosts[host] || linked[host] || !s.isValidBlogDomain(host) {
+				return
+			}
+			linked[host] = true
+
+			stat, ok := stats[host]
+			if !ok {
+				stat = &domainStat{
+					host:     host,
+					homepage: fmt.Sprintf("%s://%s", u.Scheme, u.Host),
+				}
+				stats[host] = stat
+			}
+			stat.score += weight
+			switch {
+			case article.Favorite:
+				stat.favoriteLinks++
+			case article.ReadLater:
+				stat.readLaterLinks++
+			default:
+				stat.readLinks++
+			}
+		})
+	}
+
+	ranked := make([]domainStat, 0, len(stats))
+	for _, stat := range stats {
+		ranked = append(ranked, *stat)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].host < ranked[j].host
+	})
+	return ranked
+}
+
+// articleWeight returns the engagement weight of an article, using its strongest signal
+func articleWeight(article ArticleSignal) float64 {
+	switch {
+	case article.Favorite:
+		return favoriteWeight
+	case article.ReadLater:
+		return readLaterWeight
+	case article.Read:
+		return readWeight
+	}
+	return 0
+}
+
+// explanation describes why a domain is recommended, strongest engagement first
+func (d domainStat) explanation() string {
+	var parts []string
+	if d.favoriteLinks > 0 {
+		parts = append(parts, fmt.Sprintf("%s from articles you favorited", linkedTimes(d.favoriteLinks)))
+	}
+	if d.readLaterLinks > 0 {
+		parts = append(parts, fmt.Sprintf("%s from articles you saved for later", linkedTimes(d.readLaterLinks)))
+	}
+	if d.readLinks > 0 {
+		parts = append(parts, fmt.Sprintf("%s from articles you read", linkedTimes(d.readLinks)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// linkedTimes formats a link count, e.g. "linked 14 times"
+func linkedTimes(n int) string {
+	if n == 1 {
+		return "linked once"
+	}
+	return fmt.Sprintf("linked %d times", n)
+}
+
+// normalizeHost lowercases a host and strips the www. prefix so variants aggregate together
+func normalizeHost(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
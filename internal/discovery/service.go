@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"time"
 
+	"MrRSS/internal/utils/httputil"
+
 	"github.com/mmcdole/gofeed"
 )
 
@@ -16,6 +18,10 @@ const (
 	MaxConcurrentPathChecks = 5
 	// HTTPClientTimeout is the timeout for HTTP requests
 	HTTPClientTimeout = 15 * time.Second
+	// MaxRedirects is the number of redirects followed per request
+	MaxRedirects = 5
+	// MaxResponseBytes caps the size of pages and feeds fetched during discovery
+	MaxResponseBytes = 5 << 20
 )
 
 // ProgressCallback is called with progress updates during discovery
@@ -53,29 +59,22 @@ type Service struct {
 	feedParser *gofeed.Parser
 }
 
-// NewService creates a new discovery service
+// NewService creates a new discovery service. It refuses to fetch from
+// private network addresses until SetOutboundPolicy says otherwise.
 func NewService() *Service {
-	feedParser := gofeed.NewParser()
-	feedParser.Client = &http.Client{
-		Timeout: HTTPClientTimeout,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 5 {
-				return errTooManyRedirects
-			}
-			return nil
-		},
-	}
+	s := &Service{feedParser: gofeed.NewParser()}
+	s.SetOutboundPolicy(httputil.OutboundPolicy{})
+	return s
+}
 
-	return &Service{
-		client: &http.Client{
-			Timeout: HTTPClientTimeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 5 {
-					return errTooManyRedirects
-				}
-				return nil
-			},
-		},
-		feedParser: feedParser,
-	}
+// SetOutboundPolicy sets which addresses discovery may fetch from, such as
+// hosts of feeds allowed on the private network. Redirects and response sizes
+// are always limited.
+func (s *Service) SetOutboundPolicy(policy httputil.OutboundPolicy) {
+	policy.MaxRedirects = MaxRedirects
+	policy.MaxBodyBytes = MaxResponseBytes
+	// Creating a client only fails for an invalid proxy URL, and none is used
+	client, _ := httputil.NewOutboundClient("", HTTPClientTimeout, policy)
+	s.client = client
+	s.feedParser.Client = client
 }
@@ -162,7 +162,8 @@ func (cm *CleanupManager) getTargetSize() float64 {
 	return float64(maxSizeMB)
 }
 
-// layeredCleanup executes cleanup in layers until target size is reached
+// layeredCleanup applies the retention policies, then executes cleanup in
+// layers until target size is reached
 // Cleanup order:
 // 1. Old article contents
 // 2. Medium article contents
@@ -174,11 +175,21 @@ func (cm *CleanupManager) getTargetSize() float64 {
 func (cm *CleanupManager) layeredCleanup(targetSizeMB float64) int64 {
 	totalRemoved := int64(0)
 
+	// Retention policies apply whatever the size
+	count, err := cm.fetcher.db.ApplyRetentionPolicies()
+	if err != nil {
+		log.Printf("Retention policy error: %v", err)
+	}
+	if count > 0 {
+		log.Printf("Retention policies: Removed %d articles", count)
+		totalRemoved += count
+	}
+
 	// Get current size
 	currentSizeMB, _ := cm.fetcher.db.GetDatabaseSizeMB()
 
 	if currentSizeMB <= targetSizeMB {
-		return 0
+		return totalRemoved
 	}
 
 	log.Printf("Current size: %.2f MB, Target: %.2f MB", currentSizeMB, targetSizeMB)
@@ -37,6 +37,8 @@ type Fetcher struct {
 	refreshCalculator *IntelligentRefreshCalculator
 	taskManager       *TaskManager
 	cleanupManager    *CleanupManager
+	rsshubPool        *rsshub.Pool
+	onRefreshComplete func()
 }
 
 func NewFetcher(db *database.DB) *Fetcher {
@@ -75,6 +77,7 @@ func NewFetcher(db *database.DB) *Fetcher {
 		scriptExecutor:    executor,
 		emailFetcher:      NewEmailFetcher(db),
 		refreshCalculator: NewIntelligentRefreshCalculator(db),
+		rsshubPool:        rsshub.NewPool(),
 	}
 
 	// Initialize task manager with default capacity (increased from 5 to 10)
@@ -108,7 +111,26 @@ func (f *Fetcher) GetCleanupManager() *CleanupManager {
 	return f.cleanupManager
 }
 
-// transformRSSHubURL converts rsshub:// route to full URL
+// OnRefreshComplete sets a function called in the background each time all
+// queued refresh tasks have finished
+func (f *Fetcher) OnRefreshComplete(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onRefreshComplete = fn
+}
+
+// notifyRefreshComplete runs the refresh-complete hook, if any
+func (f *Fetcher) notifyRefreshComplete() {
+	f.mu.Lock()
+	fn := f.onRefreshComplete
+	f.mu.Unlock()
+	if fn != nil {
+		go fn()
+	}
+}
+
+// transformRSSHubURL converts rsshub:// route to full URL on the preferred healthy instance.
+// Fetches that need failover use parseRSSHubFeed, which walks all instances.
 func (f *Fetcher) transformRSSHubURL(url string) (string, error) {
 	if !rsshub.IsRSSHubURL(url) {
 		return url, nil
@@ -120,15 +142,8 @@ func (f *Fetcher) transformRSSHubURL(url string) (string, error) {
 		return "", fmt.Errorf("RSSHub integration is disabled. Please enable it in settings")
 	}
 
-	endpoint, _ := f.db.GetSetting("rsshub_endpoint")
-	if endpoint == "" {
-		endpoint = "https://rsshub.app"
-	}
-	apiKey, _ := f.db.GetEncryptedSetting("rsshub_api_key")
-
 	route := rsshub.ExtractRoute(url)
-	client := rsshub.NewClient(endpoint, apiKey)
-	return client.BuildURL(route), nil
+	return f.primaryRSSHubInstance().Client().BuildURL(route), nil
 }
 
 // getDataDir returns the data directory path
@@ -354,7 +369,14 @@ func (f *Fetcher) FetchFeed(ctx context.Context, feed models.Feed) {
 
 // fetchFeedWithContext is the internal fetch method used by TaskManager
 // Returns error instead of storing in progress.Errors
-func (f *Fetcher) fetchFeedWithContext(ctx context.Context, feed models.Feed) error {
+// Every attempt is recorded in the feed's fetch history.
+func (f *Fetcher) fetchFeedWithContext(ctx context.Context, feed models.Feed) (err error) {
+	ctx, trace := withFetchTrace(ctx)
+	startedAt := time.Now()
+	defer func() {
+		f.recordFetch(feed.ID, startedAt, trace, err)
+	}()
+
 	// Use ParseFeedWithFeed with normal priority for feed refresh
 	parsedFeed, err := f.ParseFeedWithFeed(ctx, &feed, false)
 	if err != nil {
@@ -438,6 +460,26 @@ func (f *Fetcher) fetchFeedWithContext(ctx context.Context, feed models.Feed) er
 	return nil
 }
 
+// recordFetch stores the outcome of a fetch attempt in the feed's fetch history
+func (f *Fetcher) recordFetch(feedID int64, startedAt time.Time, trace *fetchTrace, fetchErr error) {
+	record := &models.FeedFetchRecord{
+		FeedID:     feedID,
+		FetchedAt:  startedAt,
+		Success:    fetchErr == nil,
+		DurationMs: time.Since(startedAt).Milliseconds(),
+	}
+	if fetchErr != nil {
+		record.Error = fetchErr.Error()
+	}
+	if trace != nil {
+		record.ServedBy = trace.servedBy
+	}
+
+	if err := f.db.AddFeedFetchRecord(record); err != nil {
+		log.Printf("Error recording fetch history for feed %d: %v", feedID, err)
+	}
+}
+
 // FetchSingleFeed fetches a single feed with progress tracking.
 // This is used when adding a new feed, refreshing a single feed from the context menu,
 // or when the scheduler triggers individual feed refreshes.
@@ -0,0 +1,132 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"MrRSS/internal/rsshub"
+	"MrRSS/internal/utils"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// defaultRSSHubEndpoint is used when neither an instance list nor a single endpoint is configured
+const defaultRSSHubEndpoint = "https://rsshub.app"
+
+// fetchTraceKey is the context key for the per-fetch trace
+type fetchTraceKey struct{}
+
+// fetchTrace collects details about a single feed fetch for the fetch history
+type fetchTrace struct {
+	servedBy string // RSSHub instance endpoint that served the fetch
+}
+
+// withFetchTrace returns a context carrying a new fetch trace
+func withFetchTrace(ctx context.Context) (context.Context, *fetchTrace) {
+	trace := &fetchTrace{}
+	return context.WithValue(ctx, fetchTraceKey{}, trace), trace
+}
+
+// traceFromContext returns the fetch trace stored in ctx, or nil
+func traceFromContext(ctx context.Context) *fetchTrace {
+	trace, _ := ctx.Value(fetchTraceKey{}).(*fetchTrace)
+	return trace
+}
+
+// RSSHubPool returns the pool of configured RSSHub instances.
+// The pool is synced with the current settings before it is returned.
+func (f *Fetcher) RSSHubPool() *rsshub.Pool {
+	f.syncRSSHubInstances()
+	return f.rsshubPool
+}
+
+// syncRSSHubInstances loads the instance list from settings into the pool.
+// The rsshub_instances list takes precedence; the legacy single
+// rsshub_endpoint/rsshub_api_key pair is used when the list is empty.
+func (f *Fetcher) syncRSSHubInstances() {
+	raw, _ := f.db.GetEncryptedSetting("rsshub_instances")
+	instances, err := rsshub.ParseInstances(raw)
+	if err != nil {
+		log.Printf("Ignoring RSSHub instance list: %v", err)
+	}
+
+	if len(instances) == 0 {
+		endpoint, _ := f.db.GetSetting("rsshub_endpoint")
+		if strings.TrimSpace(endpoint) == "" {
+			endpoint = defaultRSSHubEndpoint
+		}
+		apiKey, _ := f.db.GetEncryptedSetting("rsshub_api_key")
+		instances = []rsshub.Instance{{Endpoint: endpoint, APIKey: apiKey}}
+	}
+
+	f.rsshubPool.SetInstances(instances)
+}
+
+// primaryRSSHubInstance returns the instance that should be tried first
+func (f *Fetcher) primaryRSSHubInstance() rsshub.Instance {
+	if instance, ok := f.RSSHubPool().Primary(); ok {
+		return instance
+	}
+	return rsshub.Instance{Endpoint: defaultRSSHubEndpoint}
+}
+
+// CheckRSSHubHealth probes every configured RSSHub instance and updates its health counters
+func (f *Fetcher) CheckRSSHubHealth() []rsshub.InstanceStatus {
+	pool := f.RSSHubPool()
+	pool.CheckHealth()
+	return pool.Status()
+}
+
+// parseRSSHubFeed fetches an rsshub:// route, trying each instance in turn.
+// Healthy instances are tried first in configured order; a failed fetch
+// counts against the instance and the route is retried on the next one.
+func (f *Fetcher) parseRSSHubFeed(ctx context.Context, feedURL string, priority bool, debugTimer *DebugTimer) (*gofeed.Feed, error) {
+	enabledStr, _ := f.db.GetSetting("rsshub_enabled")
+	if enabledStr != "true" {
+		return nil, fmt.Errorf("failed to transform RSSHub URL: RSSHub integration is disabled. Please enable it in settings")
+	}
+
+	route := rsshub.ExtractRoute(feedURL)
+	pool := f.RSSHubPool()
+	candidates := pool.Candidates()
+
+	var lastErr error
+	for i, instance := range candidates {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		actualURL := instance.Client().BuildURL(route)
+		utils.DebugLog("parseRSSHubFeed: Trying instance %d/%d (%s) for route %s", i+1, len(candidates), instance.Endpoint, route)
+
+		parsedFeed, err := f.parseFeedFromURL(ctx, actualURL, priority, debugTimer)
+		if err == nil {
+			pool.ReportSuccess(instance.Endpoint)
+			if trace := traceFromContext(ctx); trace != nil {
+				trace.servedBy = instance.Endpoint
+			}
+			return parsedFeed, nil
+		}
+
+		// Don't blame the instance when the caller gave up
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		pool.ReportFailure(instance.Endpoint, err)
+		lastErr = err
+		if i < len(candidates)-1 {
+			log.Printf("RSSHub instance %s failed for route %s: %v, trying next instance", instance.Endpoint, route, err)
+		}
+	}
+
+	if lastErr == nil {
+		return nil, fmt.Errorf("no RSSHub instance configured")
+	}
+	if len(candidates) > 1 {
+		return nil, fmt.Errorf("all %d RSSHub instances failed, last error: %w", len(candidates), lastErr)
+	}
+	return nil, lastErr
+}
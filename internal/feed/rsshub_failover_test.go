@@ -0,0 +1,122 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"MrRSS/internal/database"
+	"MrRSS/internal/models"
+	"MrRSS/internal/rsshub"
+)
+
+const failoverTestRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+  <title>Failover Feed</title>
+  <link>https://example.com</link>
+  <item>
+    <title>Served by backup</title>
+    <link>https://example.com/1</link>
+  </item>
+</channel>
+</rss>`
+
+func TestParseRSSHubFeedFailsOverToNextInstance(t *testing.T) {
+	db, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create db: %v", err)
+	}
+	if err := db.Init(); err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+
+	var backupKey string
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backupKey = r.URL.Query().Get("key")
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(failoverTestRSS))
+	}))
+	defer backup.Close()
+
+	instances, _ := json.Marshal([]rsshub.Instance{
+		{Endpoint: primary.URL},
+		{Endpoint: backup.URL, APIKey: "backup-key"},
+	})
+	if err := db.SetSetting("rsshub_enabled", "true"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	if err := db.SetEncryptedSetting("rsshub_instances", string(instances)); err != nil {
+		t.Fatalf("SetEncryptedSetting failed: %v", err)
+	}
+
+	fetcher := NewFetcher(db)
+	feedID, err := db.AddFeed(&models.Feed{Title: "RSSHub", URL: "rsshub://test/route"})
+	if err != nil {
+		t.Fatalf("AddFeed failed: %v", err)
+	}
+	feed, err := db.GetFeedByID(feedID)
+	if err != nil {
+		t.Fatalf("GetFeedByID failed: %v", err)
+	}
+
+	if err := fetcher.fetchFeedWithContext(context.Background(), *feed); err != nil {
+		t.Fatalf("fetchFeedWithContext failed: %v", err)
+	}
+
+	if backupKey != "backup-key" {
+		t.Errorf("backup instance received key %q, want backup-key", backupKey)
+	}
+
+	status := fetcher.RSSHubPool().Status()
+	if status[0].ConsecutiveFailures != 1 {
+		t.Errorf("primary failures = %d, want 1", status[0].ConsecutiveFailures)
+	}
+	if status[1].TotalSuccesses != 1 {
+		t.Errorf("backup successes = %d, want 1", status[1].TotalSuccesses)
+	}
+
+	history, err := db.GetFeedFetchHistory(feedID, 10)
+	if err != nil {
+		t.Fatalf("GetFeedFetchHistory failed: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("expected 1 fetch record, got %d", len(history))
+	}
+	if !history[0].Success || history[0].ServedBy != backup.URL {
+		t.Errorf("fetch record = %+v, want success served by %s", history[0], backup.URL)
+	}
+}
+
+func TestParseRSSHubFeedReportsAllInstancesFailed(t *testing.T) {
+	db, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create db: %v", err)
+	}
+	if err := db.Init(); err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	instances, _ := json.Marshal([]rsshub.Instance{{Endpoint: down.URL}, {Endpoint: down.URL + "/mirror"}})
+	_ = db.SetSetting("rsshub_enabled", "true")
+	_ = db.SetEncryptedSetting("rsshub_instances", string(instances))
+
+	fetcher := NewFetcher(db)
+	_, err = fetcher.ParseFeedWithFeed(context.Background(), &models.Feed{URL: "rsshub://test/route"}, false)
+	if err == nil || !strings.Contains(err.Error(), "all 2 RSSHub instances failed") {
+		t.Fatalf("expected all-instances-failed error, got %v", err)
+	}
+}
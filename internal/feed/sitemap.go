@@ -0,0 +1,325 @@
+package feed
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"MrRSS/internal/models"
+	"MrRSS/internal/utils/httputil"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Sitemap feed limits
+const (
+	// maxSitemapItems caps the number of items produced from a sitemap
+	maxSitemapItems = 100
+	// maxChildSitemaps caps how many child sitemaps of a sitemap index are fetched
+	maxChildSitemaps = 10
+	// maxSitemapSize caps the size of a single (decompressed) sitemap document
+	maxSitemapSize = 50 << 20
+)
+
+// sitemapPatternPrefix prefixes the path pattern stored in a sitemap feed URL's fragment
+const sitemapPatternPrefix = "path="
+
+// sitemapDocument matches both <urlset> sitemaps and <sitemapindex> documents
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// sitemapEntry is a single <url> or <sitemap> element
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// sitemapLastModLayouts are the W3C datetime variants allowed in <lastmod>
+var sitemapLastModLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// SitemapFeedURL builds the URL stored for a sitemap feed.
+// The path pattern is kept in the URL fragment, which is never sent to the
+// server, so the same sitemap can back several filtered feeds.
+func SitemapFeedURL(sitemapURL, pathPattern string) string {
+	sitemapURL, _ = splitSitemapFeedURL(sitemapURL)
+	pathPattern = strings.TrimSpace(pathPattern)
+	if pathPattern == "" {
+		return sitemapURL
+	}
+	return sitemapURL + "#" + sitemapPatternPrefix + pathPattern
+}
+
+// splitSitemapFeedURL splits a stored sitemap feed URL into the sitemap URL and path pattern
+func splitSitemapFeedURL(feedURL string) (string, string) {
+	idx := strings.Index(feedURL, "#")
+	if idx < 0 {
+		return feedURL, ""
+	}
+	fragment := feedURL[idx+1:]
+	if !strings.HasPrefix(fragment, sitemapPatternPrefix) {
+		return feedURL, ""
+	}
+	return feedURL[:idx], strings.TrimPrefix(fragment, sitemapPatternPrefix)
+}
+
+// compileSitemapPattern turns a path pattern into a matcher.
+// Patterns without wildcards match as path prefixes ("/blog/"); otherwise
+// "*" matches within a path segment, "**" across segments and "?" a single character.
+func compileSitemapPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	if !strings.ContainsAny(pattern, "*?") {
+		return regexp.Compile("^" + regexp.QuoteMeta(pattern))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				sb.WriteString(".*")
+				i++
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// AddSitemapSubscription adds a feed generated from a site's sitemap.xml and returns the feed ID.
+// Only sitemap URLs whose path matches pathPattern become items.
+func (f *Fetcher) AddSitemapSubscription(sitemapURL, pathPattern, category, customTitle string) (int64, error) {
+	if strings.TrimSpace(sitemapURL) == "" {
+		return 0, fmt.Errorf("sitemap URL cannot be empty")
+	}
+
+	feed := &models.Feed{
+		URL:      SitemapFeedURL(sitemapURL, pathPattern),
+		Category: category,
+		Type:     "sitemap",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	parsedFeed, err := f.parseFeedWithSitemap(ctx, feed)
+	if err != nil {
+		return 0, err
+	}
+	if len(parsedFeed.Items) == 0 {
+		return 0, fmt.Errorf("no pages in the sitemap match the path pattern %q", pathPattern)
+	}
+
+	feed.Title = customTitle
+	if feed.Title == "" {
+		feed.Title = parsedFeed.Title
+	}
+	feed.Link = parsedFeed.Link
+
+	return f.db.AddFeed(feed)
+}
+
+// parseFeedWithSitemap builds a feed from the <url> entries of a sitemap.
+// Sitemap indexes are followed one level deep. Items are the newest pages
+// by <lastmod>, filtered by the path pattern stored in the feed URL.
+func (f *Fetcher) parseFeedWithSitemap(ctx context.Context, feed *models.Feed) (*gofeed.Feed, error) {
+	sitemapURL, pathPattern := splitSitemapFeedURL(feed.URL)
+	matcher, err := compileSitemapPattern(pathPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sitemap path pattern %q: %w", pathPattern, err)
+	}
+
+	httpClient, err := httputil.CreateHTTPClient("", 30*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+
+	doc, err := fetchSitemap(ctx, httpClient, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := doc.URLs
+	for i, child := range doc.Sitemaps {
+		if i >= maxChildSitemaps {
+			break
+		}
+		childDoc, err := fetchSitemap(ctx, httpClient, strings.TrimSpace(child.Loc))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		entries = append(entries, childDoc.URLs...)
+	}
+
+	base, err := url.Parse(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedFeed := &gofeed.Feed{
+		Title:       feed.Title,
+		Link:        fmt.Sprintf("%s://%s", base.Scheme, base.Host),
+		Description: feed.Description,
+		Items:       sitemapItems(entries, matcher),
+	}
+	if parsedFeed.Title == "" {
+		parsedFeed.Title = base.Host
+	}
+
+	return parsedFeed, nil
+}
+
+// fetchSitemap downloads and decodes a sitemap, transparently handling gzip
+func fetchSitemap(ctx context.Context, client *http.Client, sitemapURL string) (*sitemapDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sitemap %s: %w", sitemapURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch sitemap %s: HTTP %d", sitemapURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSitemapSize))
+	if err != nil {
+		return nil, err
+	}
+
+	// Gzipped sitemaps (sitemap.xml.gz) are common; detect them by magic bytes
+	if len(body) > 2 && body[0] == 0x1f && body[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap %s: %w", sitemapURL, err)
+		}
+		body, err = io.ReadAll(io.LimitReader(zr, maxSitemapSize))
+		zr.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap %s: %w", sitemapURL, err)
+		}
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap %s: %w", sitemapURL, err)
+	}
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		return nil, fmt.Errorf("%s is not a sitemap (root element <%s>)", sitemapURL, doc.XMLName.Local)
+	}
+
+	return &doc, nil
+}
+
+// sitemapItems converts sitemap entries into feed items, newest first
+func sitemapItems(entries []sitemapEntry, matcher *regexp.Regexp) []*gofeed.Item {
+	seen := make(map[string]bool, len(entries))
+	items := make([]*gofeed.Item, 0, len(entries))
+
+	for _, entry := range entries {
+		loc := strings.TrimSpace(entry.Loc)
+		u, err := url.Parse(loc)
+		if err != nil || u.Host == "" || seen[loc] {
+			continue
+		}
+		if matcher != nil && !matcher.MatchString(u.Path) {
+			continue
+		}
+		seen[loc] = true
+
+		item := &gofeed.Item{
+			Title: titleFromSitemapPath(u),
+			Link:  loc,
+			GUID:  loc,
+		}
+		if published, ok := parseSitemapLastMod(entry.LastMod); ok {
+			item.Published = published.Format(time.RFC3339)
+			item.PublishedParsed = &published
+			item.UpdatedParsed = &published
+		}
+		items = append(items, item)
+	}
+
+	// Pages without <lastmod> keep their sitemap order after dated pages
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].PublishedParsed, items[j].PublishedParsed
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.After(*b)
+	})
+
+	if len(items) > maxSitemapItems {
+		items = items[:maxSitemapItems]
+	}
+	return items
+}
+
+// parseSitemapLastMod parses a W3C datetime <lastmod> value
+func parseSitemapLastMod(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range sitemapLastModLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// titleFromSitemapPath derives a readable title from a page URL's last path segment,
+// e.g. "/posts/hello-world.html" → "Hello world"
+func titleFromSitemapPath(u *url.URL) string {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	slug := segments[len(segments)-1]
+	if dot := strings.LastIndex(slug, "."); dot > 0 {
+		slug = slug[:dot]
+	}
+	if decoded, err := url.PathUnescape(slug); err == nil {
+		slug = decoded
+	}
+
+	title := strings.Join(strings.Fields(strings.NewReplacer("-", " ", "_", " ").Replace(slug)), " ")
+	if title == "" {
+		return u.Host
+	}
+	first, size := utf8.DecodeRuneInString(title)
+	return string(unicode.ToUpper(first)) + title[size:]
+}
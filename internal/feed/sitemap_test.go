@@ -0,0 +1,115 @@
+package feed
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"MrRSS/internal/models"
+)
+
+func TestSitemapFeedURL(t *testing.T) {
+	u := SitemapFeedURL("https://example.com/sitemap.xml", "/blog/*")
+	if u != "https://example.com/sitemap.xml#path=/blog/*" {
+		t.Fatalf("unexpected feed URL: %s", u)
+	}
+
+	sitemapURL, pattern := splitSitemapFeedURL(u)
+	if sitemapURL != "https://example.com/sitemap.xml" || pattern != "/blog/*" {
+		t.Fatalf("split returned %q, %q", sitemapURL, pattern)
+	}
+
+	if got := SitemapFeedURL(u, ""); got != "https://example.com/sitemap.xml" {
+		t.Fatalf("expected pattern to be cleared, got %s", got)
+	}
+}
+
+func TestCompileSitemapPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/blog/", "/blog/hello", true},
+		{"/blog/", "/about", false},
+		{"/blog/*", "/blog/hello", true},
+		{"/blog/*", "/blog/2024/hello", false},
+		{"/blog/**", "/blog/2024/hello", true},
+		{"/posts/????/*", "/posts/2024/hello", true},
+	}
+
+	for _, tt := range tests {
+		re, err := compileSitemapPattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compileSitemapPattern(%q) error: %v", tt.pattern, err)
+		}
+		if got := re.MatchString(tt.path); got != tt.want {
+			t.Errorf("pattern %q on %q = %v; want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseFeedWithSitemap(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, _ = zw.Write([]byte(`<?xml version="1.0"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>https://example.com/blog/newest-post</loc><lastmod>2026-03-01T10:00:00Z</lastmod></url>
+	<url><loc>https://example.com/about</loc><lastmod>2026-04-01</lastmod></url>
+</urlset>`))
+	_ = zw.Close()
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>` + srv.URL + `/posts.xml</loc></sitemap>
+	<sitemap><loc>` + srv.URL + `/more.xml.gz</loc></sitemap>
+</sitemapindex>`))
+		case "/posts.xml":
+			_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>https://example.com/blog/old_post.html</loc><lastmod>2025-01-15</lastmod></url>
+	<url><loc>https://example.com/blog/undated</loc></url>
+</urlset>`))
+		case "/more.xml.gz":
+			_, _ = w.Write(gz.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f := &Fetcher{}
+	feed := &models.Feed{
+		URL:  SitemapFeedURL(srv.URL+"/sitemap.xml", "/blog/"),
+		Type: "sitemap",
+	}
+
+	parsed, err := f.parseFeedWithSitemap(context.Background(), feed)
+	if err != nil {
+		t.Fatalf("parseFeedWithSitemap error: %v", err)
+	}
+
+	if len(parsed.Items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(parsed.Items))
+	}
+
+	wantTitles := []string{"Newest post", "Old post", "Undated"}
+	for i, want := range wantTitles {
+		if parsed.Items[i].Title != want {
+			t.Errorf("item %d title = %q; want %q", i, parsed.Items[i].Title, want)
+		}
+	}
+	if parsed.Items[0].PublishedParsed == nil || parsed.Items[2].PublishedParsed != nil {
+		t.Errorf("unexpected published dates: %v, %v", parsed.Items[0].PublishedParsed, parsed.Items[2].PublishedParsed)
+	}
+	if parsed.Link != srv.URL {
+		t.Errorf("feed link = %q; want %q", parsed.Link, srv.URL)
+	}
+}
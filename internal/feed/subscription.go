@@ -56,17 +56,12 @@ func (f *Fetcher) AddRSSHubSubscription(route string, category string, customTit
 		return 0, fmt.Errorf("RSSHub route cannot be empty")
 	}
 
-	// Validate route by testing it (skip if API key is empty)
-	endpoint, _ := f.db.GetSetting("rsshub_endpoint")
-	if endpoint == "" {
-		endpoint = "https://rsshub.app"
-	}
-	apiKey, _ := f.db.GetEncryptedSetting("rsshub_api_key")
-
-	client := rsshub.NewClient(endpoint, apiKey)
+	// Validate route by testing it against the preferred instance (skip if API key is empty)
+	instance := f.primaryRSSHubInstance()
+	client := instance.Client()
 
 	// Skip validation if API key is empty (public rsshub.app instance)
-	if apiKey != "" {
+	if instance.APIKey != "" {
 		if err := client.ValidateRoute(route); err != nil {
 			return 0, fmt.Errorf("RSSHub route validation failed: %w", err)
 		}
@@ -566,6 +561,14 @@ func (f *Fetcher) parseFeedWithFeedInternal(ctx context.Context, feed *models.Fe
 		return parsedFeed, nil
 	}
 
+	// The built-in Imported and Saved pages feeds hold stored items only and have nothing to fetch
+	if feed.Type == "imported" || feed.Type == "saved" {
+		return &gofeed.Feed{
+			Title:       feed.Title,
+			Description: feed.Description,
+		}, nil
+	}
+
 	if feed.ScriptPath != "" {
 		utils.DebugLog("parseFeedWithFeedInternal: Using script execution for %s", feed.ScriptPath)
 		// Execute the custom script to fetch feed
@@ -584,6 +587,13 @@ func (f *Fetcher) parseFeedWithFeedInternal(ctx context.Context, feed *models.Fe
 		return f.scriptExecutor.ExecuteScript(scriptCtx, feed.ScriptPath)
 	}
 
+	// Check if this is a sitemap-based feed
+	if feed.Type == "sitemap" {
+		debugTimer.Stage("Sitemap parsing path")
+		utils.DebugLog("parseFeedWithFeedInternal: Using sitemap parsing for %s", feed.URL)
+		return f.parseFeedWithSitemap(ctx, feed)
+	}
+
 	// Check if this is an XPath-based feed
 	if feed.Type == "HTML+XPath" || feed.Type == "XML+XPath" {
 		debugTimer.Stage("XPath parsing path")
@@ -603,17 +613,18 @@ func (f *Fetcher) parseFeedWithFeedInternal(ctx context.Context, feed *models.Fe
 	utils.DebugLog("parseFeedWithFeedInternal: Using traditional URL-based fetching for %s", feed.URL)
 	// Use traditional URL-based fetching
 
-	// Transform RSSHub URLs if needed
-	actualURL := feed.URL
+	// RSSHub routes are tried on each configured instance until one succeeds
 	if rsshub.IsRSSHubURL(feed.URL) {
-		transformedURL, err := f.transformRSSHubURL(feed.URL)
-		if err != nil {
-			return nil, fmt.Errorf("failed to transform RSSHub URL: %w", err)
-		}
-		actualURL = transformedURL
-		utils.DebugLog("parseFeedWithFeedInternal: Transformed RSSHub URL from %s to %s", feed.URL, actualURL)
+		return f.parseRSSHubFeed(ctx, feed.URL, priority, debugTimer)
 	}
 
+	return f.parseFeedFromURL(ctx, feed.URL, priority, debugTimer)
+}
+
+// parseFeedFromURL fetches and parses a feed from a plain HTTP(S) URL.
+// It tries the sanitizing fetch first, then standard parsing, and finally
+// JavaScript execution for pages that only render their feed in a browser.
+func (f *Fetcher) parseFeedFromURL(ctx context.Context, actualURL string, priority bool, debugTimer *DebugTimer) (*gofeed.Feed, error) {
 	// For high priority requests, use shorter timeout
 	fetchCtx := ctx
 	if priority {
@@ -0,0 +1,180 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"MrRSS/internal/models"
+	"MrRSS/internal/opml"
+	"MrRSS/internal/utils/httputil"
+)
+
+// maxSubscriptionListSize limits how much of a linked OPML document is read
+const maxSubscriptionListSize = 5 * 1024 * 1024
+
+// SubscriptionListSyncResult summarizes one sync of a subscription list
+type SubscriptionListSyncResult struct {
+	Added   []int64 `json:"added"` // IDs of feeds that were subscribed
+	Removed int     `json:"removed"`
+	Skipped int     `json:"skipped"`
+	Errors  int     `json:"errors"`
+}
+
+// SyncSubscriptionList fetches a linked OPML document and brings the list's
+// feeds in line with it. Feeds are added under the list's category, and only
+// feeds that the list itself added are ever removed, so feeds the user
+// subscribed to on their own are left untouched. Feeds the user unsubscribed
+// from are not added again. Every change is logged.
+func (f *Fetcher) SyncSubscriptionList(ctx context.Context, list *models.SubscriptionList) (*SubscriptionListSyncResult, error) {
+	result := &SubscriptionListSyncResult{}
+
+	entries, err := fetchSubscriptionList(ctx, list.URL)
+	if err == nil && len(entries) == 0 {
+		// An empty document is far more likely a broken publisher than an
+		// intentional unsubscribe from everything
+		err = errors.New("subscription list contains no feeds")
+	}
+	if err != nil {
+		f.logSubscriptionList(list.ID, "error", "", "", err.Error())
+		_ = f.db.UpdateSubscriptionListSyncResult(list.ID, "", err.Error())
+		return nil, err
+	}
+
+	owned, err := f.db.GetSubscriptionListFeeds(list.ID)
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := f.db.GetSubscriptionListExclusions(list.ID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := f.db.GetFeeds()
+	if err != nil {
+		return nil, err
+	}
+	existingByURL := make(map[string]models.Feed, len(existing))
+	for _, feed := range existing {
+		existingByURL[feed.URL] = feed
+	}
+
+	wanted := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		feed := entry.Feed
+		feed.URL = strings.TrimSpace(feed.URL)
+		if feed.URL == "" || wanted[feed.URL] {
+			continue
+		}
+		wanted[feed.URL] = true
+
+		if _, ok := owned[feed.URL]; ok || excluded[feed.URL] {
+			// Owned already, or the user unsubscribed from it
+			continue
+		}
+		if _, ok := existingByURL[feed.URL]; ok {
+			// Already subscribed by the user or another list
+			result.Skipped++
+			f.logSubscriptionList(list.ID, "skipped", feed.URL, feed.Title, "already subscribed")
+			continue
+		}
+
+		// The document is third-party: its mrrss:* settings (scripts,
+		// proxies, email or XPath sources) are never applied
+		newFeed := models.Feed{
+			Title:    feed.Title,
+			URL:      feed.URL,
+			Link:     feed.Link,
+			Category: subscriptionListCategory(list.Category, feed.Category),
+		}
+		feedID, err := f.db.AddFeed(&newFeed)
+		if err == nil {
+			err = f.db.AddSubscriptionListFeed(list.ID, feedID)
+		}
+		if err != nil {
+			result.Errors++
+			f.logSubscriptionList(list.ID, "error", feed.URL, feed.Title, err.Error())
+			continue
+		}
+		result.Added = append(result.Added, feedID)
+		f.logSubscriptionList(list.ID, "added", feed.URL, feed.Title, "")
+	}
+
+	for url, feed := range owned {
+		if wanted[url] {
+			continue
+		}
+		if err := f.db.DeleteFeed(feed.ID); err != nil {
+			result.Errors++
+			f.logSubscriptionList(list.ID, "error", url, feed.Title, err.Error())
+			continue
+		}
+		result.Removed++
+		f.logSubscriptionList(list.ID, "removed", url, feed.Title, "no longer in the list")
+	}
+
+	syncErr := ""
+	if result.Errors > 0 {
+		syncErr = fmt.Sprintf("%d feeds could not be synced", result.Errors)
+	}
+	if err := f.db.UpdateSubscriptionListSyncResult(list.ID, "", syncErr); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// logSubscriptionList records a sync change; logging failures are not fatal
+func (f *Fetcher) logSubscriptionList(listID int64, action, feedURL, feedTitle, message string) {
+	_ = f.db.AddSubscriptionListLog(&models.SubscriptionListLogEntry{
+		ListID:    listID,
+		Action:    action,
+		FeedURL:   feedURL,
+		FeedTitle: feedTitle,
+		Message:   message,
+	})
+}
+
+// subscriptionListCategory places a feed below the list's category,
+// keeping any folder structure of the OPML document
+func subscriptionListCategory(listCategory, feedCategory string) string {
+	switch {
+	case feedCategory == "":
+		return listCategory
+	case listCategory == "":
+		return feedCategory
+	default:
+		return listCategory + "/" + feedCategory
+	}
+}
+
+// fetchSubscriptionList downloads and parses a linked OPML document
+func fetchSubscriptionList(ctx context.Context, listURL string) ([]opml.Entry, error) {
+	client, err := httputil.CreateHTTPClient("", 30*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, listURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch subscription list: HTTP %d", resp.StatusCode)
+	}
+
+	entries, err := opml.ParseEntries(io.LimitReader(resp.Body, maxSubscriptionListSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse subscription list: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,136 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"MrRSS/internal/database"
+	"MrRSS/internal/models"
+)
+
+func TestSyncSubscriptionList(t *testing.T) {
+	db, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create db: %v", err)
+	}
+	if err := db.Init(); err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+
+	var mu sync.Mutex
+	body := `<?xml version="1.0"?>
+<opml version="2.0" xmlns:mrrss="https://github.com/WCY-dt/MrRSS/opml"><body>
+	<outline text="A" xmlUrl="https://a.example.com/feed" mrrss:scriptPath="steal.py" mrrss:proxyEnabled="true" mrrss:proxyUrl="http://evil.example.com:8080"/>
+	<outline text="Tech">
+		<outline text="B" xmlUrl="https://b.example.com/feed"/>
+	</outline>
+	<outline text="Mine" xmlUrl="https://mine.example.com/feed"/>
+</body></opml>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	// A feed the user subscribed to on their own
+	mineID, err := db.AddFeed(&models.Feed{Title: "Mine", URL: "https://mine.example.com/feed", Category: "Lists"})
+	if err != nil {
+		t.Fatalf("AddFeed error: %v", err)
+	}
+
+	listID, err := db.AddSubscriptionList(&models.SubscriptionList{URL: srv.URL, Category: "Lists"})
+	if err != nil {
+		t.Fatalf("AddSubscriptionList error: %v", err)
+	}
+	list, err := db.GetSubscriptionList(listID)
+	if err != nil {
+		t.Fatalf("GetSubscriptionList error: %v", err)
+	}
+
+	fetcher := NewFetcher(db)
+	result, err := fetcher.SyncSubscriptionList(context.Background(), list)
+	if err != nil {
+		t.Fatalf("SyncSubscriptionList error: %v", err)
+	}
+	if len(result.Added) != 2 || result.Skipped != 1 || result.Removed != 0 {
+		t.Fatalf("unexpected first sync result: %+v", result)
+	}
+
+	owned, _ := db.GetSubscriptionListFeeds(listID)
+	if owned["https://b.example.com/feed"].Category != "Lists/Tech" {
+		t.Errorf("expected nested category, got %q", owned["https://b.example.com/feed"].Category)
+	}
+	if _, ok := owned["https://mine.example.com/feed"]; ok {
+		t.Error("user feed must not be owned by the list")
+	}
+	// Settings of the third-party document are not applied
+	a, err := db.GetFeedByID(owned["https://a.example.com/feed"].ID)
+	if err != nil {
+		t.Fatalf("GetFeedByID error: %v", err)
+	}
+	if a.ScriptPath != "" || a.ProxyEnabled || a.ProxyURL != "" {
+		t.Errorf("list settings were applied: script %q, proxy %v %q", a.ScriptPath, a.ProxyEnabled, a.ProxyURL)
+	}
+
+	// The publisher drops feed A and the user's feed from the list
+	mu.Lock()
+	body = `<?xml version="1.0"?>
+<opml version="2.0"><body>
+	<outline text="B" xmlUrl="https://b.example.com/feed" category="Tech"/>
+</body></opml>`
+	mu.Unlock()
+
+	result, err = fetcher.SyncSubscriptionList(context.Background(), list)
+	if err != nil {
+		t.Fatalf("second SyncSubscriptionList error: %v", err)
+	}
+	if len(result.Added) != 0 || result.Removed != 1 {
+		t.Fatalf("unexpected second sync result: %+v", result)
+	}
+
+	if _, err := db.GetFeedByID(mineID); err != nil {
+		t.Errorf("user feed was removed: %v", err)
+	}
+	owned, _ = db.GetSubscriptionListFeeds(listID)
+	if len(owned) != 1 {
+		t.Errorf("expected 1 owned feed, got %d", len(owned))
+	}
+
+	// The user unsubscribes from B, which the list must not add again
+	bID := owned["https://b.example.com/feed"].ID
+	if err := db.DeleteFeedAndExclude(bID); err != nil {
+		t.Fatalf("DeleteFeedAndExclude error: %v", err)
+	}
+	result, err = fetcher.SyncSubscriptionList(context.Background(), list)
+	if err != nil {
+		t.Fatalf("third SyncSubscriptionList error: %v", err)
+	}
+	if len(result.Added) != 0 {
+		t.Errorf("unsubscribed feed was added again: %+v", result)
+	}
+
+	entries, err := db.GetSubscriptionListLog(listID, 0)
+	if err != nil {
+		t.Fatalf("GetSubscriptionListLog error: %v", err)
+	}
+	if len(entries) != 4 || entries[0].Action != "removed" {
+		t.Errorf("unexpected log: %+v", entries)
+	}
+
+	// An empty document is refused instead of unsubscribing from everything
+	mu.Lock()
+	body = `<?xml version="1.0"?><opml version="2.0"><body></body></opml>`
+	mu.Unlock()
+
+	if _, err := fetcher.SyncSubscriptionList(context.Background(), list); err == nil {
+		t.Error("expected an error for an empty list")
+	}
+	list, _ = db.GetSubscriptionList(listID)
+	if list.LastError == "" || list.LastSynced == nil || list.FeedCount != 0 {
+		t.Errorf("unexpected list state after failed sync: %+v", list)
+	}
+}
@@ -704,6 +704,7 @@ func (tm *TaskManager) checkCompletion() {
 
 		// Trigger cleanup through cleanup manager
 		tm.fetcher.cleanupManager.RequestCleanup()
+		tm.fetcher.notifyRefreshComplete()
 	}
 }
 
@@ -0,0 +1,513 @@
+// Package filterquery converts between a compact search syntax and article
+// filter conditions, for typing filters instead of building them field by
+// field:
+//
+//	feed:"Hacker News" cat:tech/ai tag:work is:unread is:starred author:foo
+//	after:2025-01-01 before:-7d title:/rust|go/ -word "exact phrase"
+//
+// Terms are joined by AND, or by OR when separated by the word OR, which
+// binds looser as in filter conditions. A leading - negates a term. Bare
+// words and quoted phrases search titles.
+package filterquery
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"MrRSS/internal/database"
+)
+
+// SyntaxError reports a query that cannot be parsed
+type SyntaxError struct {
+	Pos int    // 0-based character (rune) offset in the query
+	Msg string // what is wrong
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// Position returns the character offset the error points at
+func (e *SyntaxError) Position() int {
+	return e.Pos
+}
+
+// textKeys are the keys of text fields, which take a word or phrase to
+// search for, =value for an exact match or /pattern/ for a regular expression
+var textKeys = map[string]string{
+	"title":   "article_title",
+	"author":  "author",
+	"url":     "url",
+	"content": "article_content",
+}
+
+// listKeys are the keys of feed fields, which take a comma-separated list of
+// values of which any may match
+var listKeys = map[string]string{
+	"feed":     "feed_name",
+	"cat":      "feed_category",
+	"category": "feed_category",
+	"tag":      "feed_tags",
+	"type":     "feed_type",
+}
+
+// flag is a yes/no condition written as is:name or has:name
+type flag struct {
+	key, name, field string
+	value            bool
+}
+
+// flags lists each flag once; the first name of a field is used to render it
+var flags = []flag{
+	{"is", "unread", "is_read", false},
+	{"is", "read", "is_read", true},
+	{"is", "starred", "is_favorite", true},
+	{"is", "favorite", "is_favorite", true},
+	{"is", "hidden", "is_hidden", true},
+	{"is", "later", "is_read_later", true},
+	{"is", "freshrss", "is_freshrss_feed", true},
+	{"is", "imagefeed", "is_image_mode_feed", true},
+	{"has", "summary", "has_summary", true},
+	{"has", "translation", "has_translation", true},
+	{"has", "image", "has_image", true},
+	{"has", "audio", "has_audio", true},
+	{"has", "video", "has_video", true},
+}
+
+const dateLayout = "2006-01-02"
+
+// relativeTime matches relative times such as -7d or -12h
+var relativeTime = regexp.MustCompile(`^-(\d+)([dh])$`)
+
+// Parse parses a query into filter conditions. An empty query has no
+// conditions. Errors are *SyntaxError.
+func Parse(query string) ([]database.FilterCondition, error) {
+	p := &parser{src: []rune(query)}
+	var conditions []database.FilterCondition
+	orPos := -1
+	for {
+		p.skipSpace()
+		if p.eof() {
+			break
+		}
+		start := p.pos
+
+		if p.atWord("OR") {
+			if len(conditions) == 0 || orPos >= 0 {
+				return nil, &SyntaxError{start, "OR must stand between two terms"}
+			}
+			orPos = start
+			p.pos += 2
+			continue
+		}
+
+		c, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		if !p.eof() && !unicode.IsSpace(p.peek()) {
+			return nil, &SyntaxError{p.pos, fmt.Sprintf("unexpected %q", p.peek())}
+		}
+		switch {
+		case len(conditions) == 0:
+			c.Logic = ""
+		case orPos >= 0:
+			c.Logic = "or"
+		default:
+			c.Logic = "and"
+		}
+		orPos = -1
+		c.ID = int64(len(conditions) + 1)
+		conditions = append(conditions, c)
+	}
+	if orPos >= 0 {
+		return nil, &SyntaxError{orPos, "OR must stand between two terms"}
+	}
+	return conditions, nil
+}
+
+type parser struct {
+	src []rune
+	pos int
+}
+
+func (p *parser) eof() bool  { return p.pos >= len(p.src) }
+func (p *parser) peek() rune { return p.src[p.pos] }
+
+func (p *parser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+// atEnd reports whether a term ends at offset i
+func (p *parser) atEnd(i int) bool {
+	return i >= len(p.src) || unicode.IsSpace(p.src[i])
+}
+
+// atWord reports whether the next term is exactly word
+func (p *parser) atWord(word string) bool {
+	end := p.pos + len(word)
+	return end <= len(p.src) && string(p.src[p.pos:end]) == word && p.atEnd(end)
+}
+
+// term parses one, possibly negated, term
+func (p *parser) term() (database.FilterCondition, error) {
+	start := p.pos
+	negate := false
+	if p.peek() == '-' {
+		if p.atEnd(p.pos + 1) {
+			return database.FilterCondition{}, &SyntaxError{start, "nothing to negate after -"}
+		}
+		negate = true
+		p.pos++
+	}
+
+	var c database.FilterCondition
+	var err error
+	switch r := p.peek(); {
+	case r == '(' || r == ')':
+		return c, &SyntaxError{p.pos, "parentheses are not supported"}
+	case r == '"':
+		c.Field, c.Operator = "article_title", "contains"
+		c.Value, err = p.quoted()
+	case r == '/':
+		c.Field, c.Operator = "article_title", "regex"
+		c.Value, err = p.regex()
+	default:
+		if key, ok := p.key(); ok {
+			c, err = p.keyed(key)
+		} else {
+			c.Field, c.Operator = "article_title", "contains"
+			c.Value = p.word(false)
+		}
+	}
+	if err != nil {
+		return c, err
+	}
+	// A negated negation, e.g. -before:-7d, cancels out
+	c.Negate = c.Negate != negate
+	return c, nil
+}
+
+// key consumes a field key and its colon, if the next term starts with one.
+// Words such as http://example.com are not keyed terms.
+func (p *parser) key() (string, bool) {
+	i := p.pos
+	for i < len(p.src) && p.src[i] >= 'a' && p.src[i] <= 'z' {
+		i++
+	}
+	if i == p.pos || i >= len(p.src) || p.src[i] != ':' {
+		return "", false
+	}
+	if strings.HasPrefix(string(p.src[i+1:]), "//") {
+		return "", false
+	}
+	key := string(p.src[p.pos:i])
+	p.pos = i + 1
+	return key, true
+}
+
+// keyed parses the value of a key:value term
+func (p *parser) keyed(key string) (database.FilterCondition, error) {
+	keyPos := p.pos - len(key) - 1
+	c := database.FilterCondition{}
+	if p.atEnd(p.pos) {
+		return c, &SyntaxError{p.pos, fmt.Sprintf("missing value after %s:", key)}
+	}
+
+	if field, ok := textKeys[key]; ok {
+		c.Field = field
+		switch p.peek() {
+		case '/':
+			c.Operator = "regex"
+			v, err := p.regex()
+			c.Value = v
+			return c, err
+		case '=':
+			c.Operator = "exact"
+			p.pos++
+			if p.atEnd(p.pos) {
+				return c, &SyntaxError{p.pos, fmt.Sprintf("missing value after %s:=", key)}
+			}
+		default:
+			c.Operator = "contains"
+		}
+		v, err := p.value(false)
+		c.Value = v
+		return c, err
+	}
+
+	if field, ok := listKeys[key]; ok {
+		c.Field = field
+		for {
+			v, err := p.value(true)
+			if err != nil {
+				return c, err
+			}
+			c.Values = append(c.Values, v)
+			if p.eof() || p.peek() != ',' {
+				return c, nil
+			}
+			p.pos++
+			if p.atEnd(p.pos) {
+				return c, &SyntaxError{p.pos, "missing value after ,"}
+			}
+		}
+	}
+
+	valuePos := p.pos
+	value, err := p.value(false)
+	if err != nil {
+		return c, err
+	}
+	switch key {
+	case "is", "has":
+		for _, f := range flags {
+			if f.key == key && f.name == strings.ToLower(value) {
+				c.Field, c.Value = f.field, strconv.FormatBool(f.value)
+				return c, nil
+			}
+		}
+		return c, &SyntaxError{valuePos, fmt.Sprintf("unknown %s: value %q", key, value)}
+
+	case "after", "before":
+		if m := relativeTime.FindStringSubmatch(value); m != nil {
+			c.Field, c.Value = "published_after_days", m[1]
+			if m[2] == "h" {
+				c.Field = "published_after_hours"
+			}
+			// Before a relative time is not within it
+			c.Negate = key == "before"
+			return c, nil
+		}
+		if _, err := time.Parse(dateLayout, value); err != nil {
+			return c, &SyntaxError{valuePos, fmt.Sprintf("expected a date such as 2025-01-31 or a relative time such as -7d, got %q", value)}
+		}
+		c.Field, c.Value = "published_"+key, value
+		return c, nil
+
+	case "rate":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return c, &SyntaxError{valuePos, fmt.Sprintf("expected a number of articles per month, got %q", value)}
+		}
+		c.Field, c.Value = "feed_articles_per_month", value
+		return c, nil
+
+	case "status":
+		value = strings.ToLower(value)
+		if value != "success" && value != "failed" {
+			return c, &SyntaxError{valuePos, fmt.Sprintf("expected status:success or status:failed, got %q", value)}
+		}
+		c.Field, c.Value = "feed_last_update_status", value
+		return c, nil
+	}
+	return c, &SyntaxError{keyPos, fmt.Sprintf("unknown field %q", key)}
+}
+
+// value parses a quoted or bare value. In lists a bare value ends at a comma.
+func (p *parser) value(inList bool) (string, error) {
+	if !p.eof() && p.peek() == '"' {
+		return p.quoted()
+	}
+	return p.word(inList), nil
+}
+
+// word consumes a bare word
+func (p *parser) word(inList bool) string {
+	start := p.pos
+	for !p.atEnd(p.pos) && !(inList && p.peek() == ',') {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+// quoted consumes a double-quoted string, in which \" and \\ are escapes
+func (p *parser) quoted() (string, error) {
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for !p.eof() {
+		r := p.peek()
+		p.pos++
+		switch {
+		case r == '"':
+			return b.String(), nil
+		case r == '\\' && !p.eof() && (p.peek() == '"' || p.peek() == '\\'):
+			b.WriteRune(p.peek())
+			p.pos++
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", &SyntaxError{start, "unterminated quoted string"}
+}
+
+// regex consumes a /pattern/, in which \/ stands for a slash
+func (p *parser) regex() (string, error) {
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for !p.eof() {
+		r := p.peek()
+		p.pos++
+		switch {
+		case r == '/':
+			pattern := b.String()
+			if pattern == "" {
+				return "", &SyntaxError{start, "empty regular expression"}
+			}
+			if _, err := regexp.Compile(pattern); err != nil {
+				return "", &SyntaxError{start, "invalid regular expression: " + err.Error()}
+			}
+			return pattern, nil
+		case r == '\\' && !p.eof() && p.peek() == '/':
+			b.WriteRune('/')
+			p.pos++
+		case r == '\\' && !p.eof():
+			b.WriteRune(r)
+			b.WriteRune(p.peek())
+			p.pos++
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", &SyntaxError{start, "unterminated regular expression"}
+}
+
+// Format renders conditions as a query that parses back to equivalent
+// conditions. It fails for conditions a query cannot express, such as ones
+// without a value.
+func Format(conditions []database.FilterCondition) (string, error) {
+	var b strings.Builder
+	for i, c := range conditions {
+		term, err := formatCondition(c)
+		if err != nil {
+			return "", fmt.Errorf("condition %d: %w", i+1, err)
+		}
+		if i > 0 {
+			if c.Logic == "or" {
+				b.WriteString(" OR ")
+			} else {
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(term)
+	}
+	return b.String(), nil
+}
+
+func formatCondition(c database.FilterCondition) (string, error) {
+	negate := c.Negate
+	neg := func(term string) string {
+		if negate {
+			return "-" + term
+		}
+		return term
+	}
+	if c.Value == "" && len(c.Values) == 0 {
+		return "", fmt.Errorf("field %q has no value", c.Field)
+	}
+
+	for key, field := range textKeys {
+		if field != c.Field {
+			continue
+		}
+		switch c.Operator {
+		case "regex":
+			return neg(key + ":/" + strings.ReplaceAll(c.Value, "/", `\/`) + "/"), nil
+		case "exact":
+			return neg(key + ":=" + quoteIfNeeded(c.Value, false)), nil
+		}
+		if field == "article_title" {
+			if isPlainWord(c.Value) {
+				return neg(c.Value), nil
+			}
+			return neg(quote(c.Value)), nil
+		}
+		return neg(key + ":" + quoteIfNeeded(c.Value, false)), nil
+	}
+
+	for _, key := range []string{"feed", "cat", "tag", "type"} {
+		if listKeys[key] != c.Field {
+			continue
+		}
+		values := c.Values
+		if len(values) == 0 {
+			values = []string{c.Value}
+		}
+		quoted := make([]string, len(values))
+		for i, v := range values {
+			quoted[i] = quoteIfNeeded(v, true)
+		}
+		return neg(key + ":" + strings.Join(quoted, ",")), nil
+	}
+
+	for _, f := range flags {
+		if f.field != c.Field {
+			continue
+		}
+		if c.Value != "true" && c.Value != "false" {
+			return "", fmt.Errorf("field %q needs true or false, got %q", c.Field, c.Value)
+		}
+		// Render is_read=false as is:unread, is_favorite=false as -is:starred
+		for _, g := range flags {
+			if g.field == c.Field && strconv.FormatBool(g.value) == c.Value {
+				return neg(g.key + ":" + g.name), nil
+			}
+		}
+		negate = !negate
+		return neg(f.key + ":" + f.name), nil
+	}
+
+	switch c.Field {
+	case "published_after", "published_before":
+		if _, err := time.Parse(dateLayout, c.Value); err != nil {
+			return "", fmt.Errorf("invalid date %q", c.Value)
+		}
+		return neg(strings.TrimPrefix(c.Field, "published_") + ":" + c.Value), nil
+	case "published_after_days", "published_after_hours":
+		if _, err := strconv.Atoi(c.Value); err != nil {
+			return "", fmt.Errorf("invalid number %q", c.Value)
+		}
+		unit := "d"
+		if c.Field == "published_after_hours" {
+			unit = "h"
+		}
+		if negate {
+			return "before:-" + c.Value + unit, nil
+		}
+		return "after:-" + c.Value + unit, nil
+	case "feed_articles_per_month":
+		return neg("rate:" + quoteIfNeeded(c.Value, false)), nil
+	case "feed_last_update_status":
+		return neg("status:" + quoteIfNeeded(c.Value, false)), nil
+	}
+	return "", fmt.Errorf("field %q cannot be written as a query", c.Field)
+}
+
+// isPlainWord reports whether a title search can be written as a bare word
+func isPlainWord(s string) bool {
+	if s == "OR" || strings.ContainsAny(s, `":`) || strings.HasPrefix(s, "-") || strings.HasPrefix(s, "/") ||
+		strings.HasPrefix(s, "(") || strings.HasPrefix(s, ")") {
+		return false
+	}
+	return !strings.ContainsFunc(s, unicode.IsSpace)
+}
+
+// quoteIfNeeded returns a value as written after a key
+func quoteIfNeeded(s string, inList bool) string {
+	if s == "" || strings.ContainsFunc(s, unicode.IsSpace) || strings.HasPrefix(s, `"`) ||
+		strings.HasPrefix(s, "/") || strings.HasPrefix(s, "=") || (inList && strings.Contains(s, ",")) {
+		return quote(s)
+	}
+	return s
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,99 @@
+package filterquery
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"MrRSS/internal/database"
+)
+
+func TestParse(t *testing.T) {
+	got, err := Parse(`feed:"Hacker News" cat:tech/ai tag:work,"side project" is:unread is:starred author:foo after:2025-01-01 before:-7d title:/rust|go/ -word "exact phrase" OR url:=https://x.org/a`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []database.FilterCondition{
+		{ID: 1, Field: "feed_name", Values: []string{"Hacker News"}},
+		{ID: 2, Logic: "and", Field: "feed_category", Values: []string{"tech/ai"}},
+		{ID: 3, Logic: "and", Field: "feed_tags", Values: []string{"work", "side project"}},
+		{ID: 4, Logic: "and", Field: "is_read", Value: "false"},
+		{ID: 5, Logic: "and", Field: "is_favorite", Value: "true"},
+		{ID: 6, Logic: "and", Field: "author", Operator: "contains", Value: "foo"},
+		{ID: 7, Logic: "and", Field: "published_after", Value: "2025-01-01"},
+		{ID: 8, Logic: "and", Negate: true, Field: "published_after_days", Value: "7"},
+		{ID: 9, Logic: "and", Field: "article_title", Operator: "regex", Value: "rust|go"},
+		{ID: 10, Logic: "and", Negate: true, Field: "article_title", Operator: "contains", Value: "word"},
+		{ID: 11, Logic: "and", Field: "article_title", Operator: "contains", Value: "exact phrase"},
+		{ID: 12, Logic: "or", Field: "url", Operator: "exact", Value: "https://x.org/a"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{`title:"unterminated`, 6},
+		{`foo bar:baz`, 4},
+		{`is:maybe`, 3},
+		{`after:yesterday`, 6},
+		{`OR rust`, 0},
+		{`rust OR`, 5},
+		{`rust OR OR go`, 8},
+		{`/a(b/`, 0},
+		{`"a"b`, 3},
+		{`- rust`, 0},
+		{`(rust OR go)`, 0},
+		{`héllo feed:`, 11},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q) error = %v, want a SyntaxError", tt.query, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos {
+			t.Errorf("Parse(%q) error %q at %d, want position %d", tt.query, syntaxErr.Msg, syntaxErr.Pos, tt.pos)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	queries := []string{
+		`feed:"Hacker News",Lobsters cat:tech/ai is:unread -is:starred`,
+		`rust OR "two words" OR title:="Exact: Title" -content:/a\/b/`,
+		`after:-12h before:2025-02-01 has:image status:failed rate:10`,
+		`author:"Jane \"JD\" Doe" url:https://example.com/x`,
+		`before:-7d -after:2025-01-01 "OR" "-dash"`,
+	}
+	for _, q := range queries {
+		conditions, err := Parse(q)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", q, err)
+		}
+		formatted, err := Format(conditions)
+		if err != nil {
+			t.Fatalf("Format(%q): %v", q, err)
+		}
+		if formatted != q {
+			t.Errorf("Format(Parse(%q)) = %q", q, formatted)
+		}
+	}
+
+	// Conditions built in the filter editor render to equivalent queries
+	formatted, err := Format([]database.FilterCondition{
+		{Field: "is_favorite", Value: "false"},
+		{Logic: "and", Field: "feed_name", Value: "a,b"},
+	})
+	if err != nil || formatted != `-is:starred feed:"a,b"` {
+		t.Errorf("Format = %q, %v", formatted, err)
+	}
+	if _, err := Format([]database.FilterCondition{{Field: "is_read"}}); err == nil {
+		t.Error("Format accepted a condition without a value")
+	}
+}
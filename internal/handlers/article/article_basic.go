@@ -1,15 +1,19 @@
 package article
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
+	"MrRSS/internal/database"
 	"MrRSS/internal/handlers/core"
 	"MrRSS/internal/handlers/response"
 )
 
 // HandleArticles returns articles with filtering and pagination.
+// With a cursor parameter (empty for the first page) it returns a
+// database.ArticlePage paged by next_cursor/prev_cursor instead of an array.
 // @Summary      Get articles with filtering
 // @Description  Retrieve articles with optional filtering by feed, category, status, and pagination
 // @Tags         articles
@@ -20,8 +24,11 @@ import (
 // @Param        category  query     string  false  "Filter by category name"
 // @Param        only_unread query   bool    false  "Filter for only unread articles"
 // @Param        page      query     int     false  "Page number (default: 1)"  minimum(1)
+// @Param        cursor    query     string  false  "Opaque cursor from next_cursor or prev_cursor; switches to cursor paging"
 // @Param        limit     query     int     false  "Items per page (default: 50, max: 500)"  minimum(1)  maximum(500)
 // @Success      200  {array}   models.Article  "List of articles"
+// @Success      200  {object}  database.ArticlePage  "Page of articles when paging by cursor"
+// @Failure      400  {object}  map[string]string  "Invalid cursor"
 // @Failure      500  {object}  map[string]string  "Internal server error"
 // @Router       /articles [get]
 func HandleArticles(h *core.Handler, w http.ResponseWriter, r *http.Request) {
@@ -64,6 +71,12 @@ func HandleArticles(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 	showHiddenStr, _ := h.DB.GetSetting("show_hidden_articles")
 	showHidden := showHiddenStr == "true"
 
+	if cursor, ok := r.URL.Query()["cursor"]; ok {
+		page, err := h.DB.GetArticlesPage(filter, feedID, category, showHidden, onlyUnread, cursor[0], limit)
+		writeArticlePage(w, page, err)
+		return
+	}
+
 	articles, err := h.DB.GetArticlesWithUnreadFilter(filter, feedID, category, showHidden, onlyUnread, limit, offset)
 	if err != nil {
 		response.Error(w, err, http.StatusInternalServerError)
@@ -139,6 +152,7 @@ func HandleToggleReadLater(h *core.Handler, w http.ResponseWriter, r *http.Reque
 }
 
 // HandleImageGalleryArticles returns articles from image mode feeds with pagination.
+// Like HandleArticles it pages by cursor when a cursor parameter is given.
 // @Summary      Get image gallery articles
 // @Description  Retrieve articles from image-mode feeds (visual/rss-gallery feeds) with pagination
 // @Tags         articles
@@ -148,8 +162,11 @@ func HandleToggleReadLater(h *core.Handler, w http.ResponseWriter, r *http.Reque
 // @Param        category    query     string  false  "Filter by category name"
 // @Param        only_unread query     bool    false  "Filter for only unread articles"
 // @Param        page        query     int     false  "Page number (default: 1)"  minimum(1)
+// @Param        cursor      query     string  false  "Opaque cursor from next_cursor or prev_cursor; switches to cursor paging"
 // @Param        limit       query     int     false  "Items per page (default: 50)"  minimum(1)
 // @Success      200  {array}   models.Article  "List of image gallery articles"
+// @Success      200  {object}  database.ArticlePage  "Page of articles when paging by cursor"
+// @Failure      400  {object}  map[string]string  "Invalid cursor"
 // @Failure      500  {object}  map[string]string  "Internal server error"
 // @Router       /articles/image-gallery [get]
 func HandleImageGalleryArticles(h *core.Handler, w http.ResponseWriter, r *http.Request) {
@@ -194,6 +211,12 @@ func HandleImageGalleryArticles(h *core.Handler, w http.ResponseWriter, r *http.
 	// Parse only_unread parameter
 	onlyUnread := onlyUnreadStr == "true"
 
+	if cursor, ok := r.URL.Query()["cursor"]; ok {
+		page, err := h.DB.GetImageGalleryArticlesPage(feedID, category, showHidden, onlyUnread, cursor[0], limit)
+		writeArticlePage(w, page, err)
+		return
+	}
+
 	articles, err := h.DB.GetImageGalleryArticles(feedID, category, showHidden, onlyUnread, limit, offset)
 	if err != nil {
 		response.Error(w, err, http.StatusInternalServerError)
@@ -201,3 +224,16 @@ func HandleImageGalleryArticles(h *core.Handler, w http.ResponseWriter, r *http.
 	}
 	response.JSON(w, articles)
 }
+
+// writeArticlePage writes a page of articles read by cursor
+func writeArticlePage(w http.ResponseWriter, page *database.ArticlePage, err error) {
+	if errors.Is(err, database.ErrInvalidCursor) {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	response.JSON(w, page)
+}
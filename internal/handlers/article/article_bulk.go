@@ -2,6 +2,7 @@ package article
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
@@ -44,7 +45,8 @@ func HandleGetUnreadCounts(h *core.Handler, w http.ResponseWriter, r *http.Reque
 	response.JSON(w, resp)
 }
 
-// HandleGetFilterCounts returns article counts for different filters (unread, favorites, read_later, images).
+// HandleGetFilterCounts returns article counts for different filters (unread, favorites, read_later, images),
+// and the unread counts of saved filters keyed by filter ID.
 // @Summary      Get filter-specific feed counts
 // @Description  Get per-feed counts for different filter types (unread, favorites, read_later, images)
 // @Tags         articles
@@ -59,73 +61,122 @@ func HandleGetFilterCounts(h *core.Handler, w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Get unread counts per feed
-	unreadCounts, err := h.DB.GetUnreadCountsForAllFeeds()
+	counters, err := h.DB.GetFeedCounters()
 	if err != nil {
-		log.Printf("[HandleGetFilterCounts] ERROR getting unread counts: %v", err)
+		log.Printf("[HandleGetFilterCounts] ERROR getting feed counters: %v", err)
 		response.Error(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	// Get favorite counts per feed
-	favoriteCounts, err := h.DB.GetFavoriteCountsForAllFeeds()
+	// Get unread counts per saved filter
+	savedFilterCounts, err := savedFilterUnreadCounts(h)
 	if err != nil {
-		log.Printf("[HandleGetFilterCounts] ERROR getting favorite counts: %v", err)
+		log.Printf("[HandleGetFilterCounts] ERROR getting saved filter counts: %v", err)
 		response.Error(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	// Get favorite AND unread counts per feed
-	favoriteUnreadCounts, err := h.DB.GetFavoriteUnreadCountsForAllFeeds()
-	if err != nil {
-		log.Printf("[HandleGetFilterCounts] ERROR getting favorite unread counts: %v", err)
-		response.Error(w, err, http.StatusInternalServerError)
+	resp := filterCountMaps(counters)
+	resp["saved_filters"] = savedFilterCounts
+	response.JSON(w, resp)
+}
+
+// HandleGetCounts returns every count the sidebar shows in one call: the
+// total unread count, the per-feed counts of each filter, and the unread
+// counts of saved filters.
+// @Summary      Get all article counts
+// @Description  Get the total unread count, per-feed counts for each filter type, and saved filter unread counts
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Total unread count, filter counts and saved filter counts"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /articles/counts [get]
+func HandleGetCounts(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Get read_later counts per feed
-	readLaterCounts, err := h.DB.GetReadLaterCountsForAllFeeds()
+	counters, err := h.DB.GetFeedCounters()
 	if err != nil {
-		log.Printf("[HandleGetFilterCounts] ERROR getting read_later counts: %v", err)
 		response.Error(w, err, http.StatusInternalServerError)
 		return
 	}
-
-	// Get read_later AND unread counts per feed
-	readLaterUnreadCounts, err := h.DB.GetReadLaterUnreadCountsForAllFeeds()
+	savedFilterCounts, err := savedFilterUnreadCounts(h)
 	if err != nil {
-		log.Printf("[HandleGetFilterCounts] ERROR getting read_later unread counts: %v", err)
 		response.Error(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	// Get image mode counts per feed
-	imageCounts, err := h.DB.GetImageModeCountsForAllFeeds()
-	if err != nil {
-		log.Printf("[HandleGetFilterCounts] ERROR getting image counts: %v", err)
-		response.Error(w, err, http.StatusInternalServerError)
-		return
+	total := 0
+	for _, c := range counters {
+		total += c.Unread
 	}
+	resp := filterCountMaps(counters)
+	resp["total"] = total
+	resp["saved_filters"] = savedFilterCounts
+	response.JSON(w, resp)
+}
 
-	// Get image unread counts per feed
-	imageUnreadCounts, err := h.DB.GetImageUnreadCountsForAllFeeds()
-	if err != nil {
-		log.Printf("[HandleGetFilterCounts] ERROR getting image unread counts: %v", err)
-		response.Error(w, err, http.StatusInternalServerError)
-		return
+// filterCountMaps splits feed counters into one feed_id to count map per
+// filter type, leaving out zero counts
+func filterCountMaps(counters map[int64]database.FeedCounts) map[string]interface{} {
+	maps := map[string]map[int64]int{
+		"unread":            {},
+		"favorites":         {},
+		"favorites_unread":  {},
+		"read_later":        {},
+		"read_later_unread": {},
+		"images":            {},
+		"images_unread":     {},
+	}
+	add := func(key string, feedID int64, count int) {
+		if count > 0 {
+			maps[key][feedID] = count
+		}
+	}
+	for feedID, c := range counters {
+		add("unread", feedID, c.Unread)
+		add("favorites", feedID, c.Favorites)
+		add("favorites_unread", feedID, c.FavoritesUnread)
+		add("read_later", feedID, c.ReadLater)
+		add("read_later_unread", feedID, c.ReadLaterUnread)
+		add("images", feedID, c.Images)
+		add("images_unread", feedID, c.ImagesUnread)
 	}
 
-	resp := map[string]interface{}{
-		"unread":            unreadCounts,
-		"favorites":         favoriteCounts,
-		"favorites_unread":  favoriteUnreadCounts,
-		"read_later":        readLaterCounts,
-		"read_later_unread": readLaterUnreadCounts,
-		"images":            imageCounts,
-		"images_unread":     imageUnreadCounts,
+	resp := make(map[string]interface{}, len(maps)+2)
+	for key, m := range maps {
+		resp[key] = m
+	}
+	return resp
+}
+
+// savedFilterUnreadCounts returns the number of unread articles matching each
+// saved filter. Filters with unreadable conditions are skipped.
+func savedFilterUnreadCounts(h *core.Handler) (map[int64]int, error) {
+	filters, err := h.DB.GetSavedFilters()
+	if err != nil {
+		return nil, err
 	}
+	showHiddenStr, _ := h.DB.GetSetting("show_hidden_articles")
+	showHidden := showHiddenStr == "true"
 
-	response.JSON(w, resp)
+	counts := make(map[int64]int, len(filters))
+	for _, f := range filters {
+		var conditions []FilterCondition
+		if err := json.Unmarshal([]byte(f.Conditions), &conditions); err != nil {
+			log.Printf("[HandleGetFilterCounts] Skipping saved filter %q: %v", f.Name, err)
+			continue
+		}
+		count, err := h.DB.CountFilteredArticles(conditions, showHidden, true)
+		if err != nil {
+			return nil, err
+		}
+		counts[f.ID] = count
+	}
+	return counts, nil
 }
 
 // HandleMarkAllAsRead marks all articles as read.
@@ -208,6 +259,12 @@ func HandleClearReadLater(h *core.Handler, w http.ResponseWriter, r *http.Reques
 // @Success      200  {string}  string  "Refresh started successfully"
 // @Router       /articles/refresh [post]
 func HandleRefresh(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	// Nothing can be fetched in offline mode
+	if h.OfflineMode() {
+		response.JSON(w, map[string]string{"status": "offline"})
+		return
+	}
+
 	// Mark progress as running before starting goroutine
 	// This ensures the frontend immediately sees is_running=true
 	taskManager := h.Fetcher.GetTaskManager()
@@ -412,6 +469,16 @@ func performImmediateBulkSync(h *core.Handler, syncReqs []database.SyncRequest)
 		return
 	}
 
+	// Offline, the changes wait in the sync queue for the next global sync
+	if h.OfflineMode() {
+		for _, syncReq := range syncReqs {
+			if err := h.DB.EnqueueSyncChange(syncReq.ArticleID, syncReq.ArticleURL, syncReq.Action); err != nil {
+				log.Printf("[Bulk Sync] Failed to enqueue article %d while offline: %v", syncReq.ArticleID, err)
+			}
+		}
+		return
+	}
+
 	// Create sync service
 	syncService := freshrss.NewBidirectionalSyncService(serverURL, username, password, h.DB)
 
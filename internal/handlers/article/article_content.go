@@ -1,6 +1,7 @@
 package article
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 	"MrRSS/internal/feed"
 	"MrRSS/internal/handlers/core"
 	"MrRSS/internal/handlers/response"
+	"MrRSS/internal/utils/textutil"
 )
 
 // HandleGetArticleContent fetches the article content from RSS feed dynamically.
@@ -20,6 +22,7 @@ import (
 // @Success      200  {object}  map[string]string  "Article content (content, feed_url)"
 // @Failure      400  {object}  map[string]string  "Bad request (invalid article ID)"
 // @Failure      500  {object}  map[string]string  "Internal server error"
+// @Failure      503  {object}  map[string]string  "Offline mode is on and the content is not cached"
 // @Router       /articles/content [get]
 func HandleGetArticleContent(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -44,6 +47,16 @@ func HandleGetArticleContent(h *core.Handler, w http.ResponseWriter, r *http.Req
 
 	// Use the cached content fetching method
 	content, wasCached, err := h.GetArticleContent(articleID)
+	if errors.Is(err, core.ErrOffline) {
+		// Offline, the prefetched full text can stand in for the feed content
+		if fullText, found, _ := h.DB.GetArticleFullText(articleID); found {
+			content, wasCached, err = fullText, true, nil
+		}
+	}
+	if errors.Is(err, core.ErrOffline) {
+		response.Error(w, err, http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
 		log.Printf("Error getting article content: %v", err)
 		response.Error(w, err, http.StatusInternalServerError)
@@ -60,8 +73,10 @@ func HandleGetArticleContent(h *core.Handler, w http.ResponseWriter, r *http.Req
 		feedURL = feed.URL
 	}
 
+	// Content is sanitized at ingest, but older rows and synced or restored
+	// articles may predate that
 	response.JSON(w, map[string]interface{}{
-		"content":  content,
+		"content":  textutil.SanitizeHTML(content),
 		"feed_url": feedURL,
 		"cached":   wasCached,
 	})
@@ -116,6 +131,7 @@ func HandleReloadArticleContent(h *core.Handler, w http.ResponseWriter, r *http.
 // @Failure      400  {object}  map[string]string  "Bad request (invalid ID or missing URL)"
 // @Failure      403  {object}  map[string]string  "Full-text fetching disabled"
 // @Failure      500  {object}  map[string]string  "Internal server error"
+// @Failure      503  {object}  map[string]string  "Offline mode is on and the full text is not cached"
 // @Router       /articles/fetch-full [post]
 func HandleFetchFullArticle(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -158,16 +174,28 @@ func HandleFetchFullArticle(h *core.Handler, w http.ResponseWriter, r *http.Requ
 		feedURL = feed.URL
 	}
 
-	// Fetch full content
-	fullContent, err := h.FetchFullArticleContentWithFeed(article.URL, feed)
+	// Use the full text fetched ahead of time for offline reading, if any
+	fullContent, found, err := h.DB.GetArticleFullText(articleID)
 	if err != nil {
-		log.Printf("Error fetching full article content: %v", err)
-		response.Error(w, err, http.StatusInternalServerError)
-		return
+		log.Printf("Error getting cached full text: %v", err)
+	}
+	if !found {
+		if h.OfflineMode() {
+			response.Error(w, core.ErrOffline, http.StatusServiceUnavailable)
+			return
+		}
+
+		// Fetch full content
+		fullContent, err = h.FetchFullArticleContentWithFeed(article.URL, feed)
+		if err != nil {
+			log.Printf("Error fetching full article content: %v", err)
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
 	}
 
 	response.JSON(w, map[string]string{
-		"content":  fullContent,
+		"content":  textutil.SanitizeHTML(fullContent),
 		"feed_url": feedURL,
 	})
 }
@@ -213,6 +241,10 @@ func HandleExtractAllImages(h *core.Handler, w http.ResponseWriter, r *http.Requ
 
 	// Get article content
 	content, _, err := h.GetArticleContent(articleID)
+	if errors.Is(err, core.ErrOffline) {
+		response.Error(w, err, http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
 		log.Printf("Error getting article content: %v", err)
 		response.Error(w, err, http.StatusInternalServerError)
@@ -1,13 +1,17 @@
 package article
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"sort"
-	"strings"
 	"time"
 
+	"MrRSS/internal/database"
+	"MrRSS/internal/filterquery"
 	"MrRSS/internal/handlers/core"
 	"MrRSS/internal/handlers/response"
 	"MrRSS/internal/models"
@@ -133,6 +137,11 @@ func HandleTaskDetails(h *core.Handler, w http.ResponseWriter, r *http.Request)
 }
 
 // HandleFilteredArticles returns articles filtered by advanced conditions from the database.
+// The conditions may instead be given as a search query, or by a saved filter.
+// With a cursor (an empty string for the first page) the articles are paged by
+// next_cursor/prev_cursor instead of page, and total is not computed.
+// With include_archive, matching articles from cold storage are returned
+// separately in archived; it is ignored when paging by cursor.
 // @Summary      Get filtered articles
 // @Description  Retrieve articles with advanced filtering conditions
 // @Tags         articles
@@ -141,6 +150,7 @@ func HandleTaskDetails(h *core.Handler, w http.ResponseWriter, r *http.Request)
 // @Param        request  body      FilterRequest  true  "Filter criteria"
 // @Success      200  {object}  FilterResponse  "Filtered articles"
 // @Failure      400  {object}  map[string]string  "Bad request"
+// @Failure      404  {object}  map[string]string  "Saved filter not found"
 // @Failure      500  {object}  map[string]string  "Internal server error"
 // @Router       /articles/filter [post]
 func HandleFilteredArticles(h *core.Handler, w http.ResponseWriter, r *http.Request) {
@@ -155,6 +165,27 @@ func HandleFilteredArticles(h *core.Handler, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// A saved filter supplies its stored conditions
+	if req.SavedFilterID > 0 {
+		conditions, err := savedFilterConditions(h, req.SavedFilterID)
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, err, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		req.Conditions = conditions
+	} else if req.Query != "" {
+		conditions, err := filterquery.Parse(req.Query)
+		if err != nil {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		req.Conditions = conditions
+	}
+
 	// Set default pagination values
 	page := req.Page
 	if page < 1 {
@@ -169,147 +200,78 @@ func HandleFilteredArticles(h *core.Handler, w http.ResponseWriter, r *http.Requ
 	showHiddenStr, _ := h.DB.GetSetting("show_hidden_articles")
 	showHidden := showHiddenStr == "true"
 
-	// Get all articles from database
-	// Note: Using a high limit to fetch all articles for filtering
-	// For very large datasets, consider implementing database-level filtering
-	articles, err := h.DB.GetArticles("", 0, "", showHidden, 50000, 0)
-	if err != nil {
-		response.Error(w, err, http.StatusInternalServerError)
+	if req.Cursor != nil {
+		result, err := h.DB.GetFilteredArticlesPage(req.Conditions, showHidden, *req.Cursor, limit)
+		if errors.Is(err, database.ErrInvalidCursor) {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		response.JSON(w, FilterResponse{
+			Articles:   result.Articles,
+			Limit:      limit,
+			HasMore:    result.NextCursor != "",
+			NextCursor: result.NextCursor,
+			PrevCursor: result.PrevCursor,
+		})
 		return
 	}
 
-	// Get feeds for category lookup
-	feeds, err := h.DB.GetFeeds()
+	// The conditions are evaluated by the database
+	total, err := h.DB.CountFilteredArticles(req.Conditions, showHidden, false)
 	if err != nil {
 		response.Error(w, err, http.StatusInternalServerError)
 		return
 	}
-
-	// Collect feed IDs for batch tag loading
-	feedIDs := make([]int64, len(feeds))
-	for i, feed := range feeds {
-		feedIDs[i] = feed.ID
-	}
-
-	// Batch load all tags at once (fixes N+1 query problem)
-	tagsMap, err := h.DB.GetTagsForFeeds(feedIDs)
+	offset := (page - 1) * limit
+	articles, err := h.DB.GetFilteredArticles(req.Conditions, showHidden, limit, offset)
 	if err != nil {
 		response.Error(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	// Create maps of feed ID to feed data
-	feedCategories := make(map[int64]string)
-	feedTypes := make(map[int64]string)
-	feedIsImageMode := make(map[int64]bool)
-	feedTags := make(map[int64][]string)
-	feedArticlesPerMonth := make(map[int64]float64)
-	feedLastUpdateStatus := make(map[int64]string)
-
-	for _, feed := range feeds {
-		feedCategories[feed.ID] = feed.Category
-		feedTypes[feed.ID] = GetFeedType(&feed)
-		feedIsImageMode[feed.ID] = feed.IsImageMode
-		feedArticlesPerMonth[feed.ID] = feed.ArticlesPerMonth
-		feedLastUpdateStatus[feed.ID] = feed.LastUpdateStatus
-
-		// Build tag names list for this feed from pre-loaded tags
-		tags := tagsMap[feed.ID]
-		tagNames := make([]string, len(tags))
-		for i, tag := range tags {
-			tagNames[i] = tag.Name
-		}
-		feedTags[feed.ID] = tagNames
+	resp := FilterResponse{
+		Articles: articles,
+		Total:    total,
+		Page:     page,
+		Limit:    limit,
+		HasMore:  offset+len(articles) < total,
 	}
 
-	// Check if any filter condition requires article content
-	needsArticleContent := false
-	for _, condition := range req.Conditions {
-		if condition.Field == "article_content" {
-			needsArticleContent = true
-			break
+	if req.IncludeArchive {
+		resp.ArchivedTotal, err = h.DB.CountColdArticles(req.Conditions)
+		if err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
 		}
-	}
-
-	// Build article content map if needed
-	articleContents := make(map[int64]string)
-	if needsArticleContent {
-		// Collect article IDs
-		articleIDs := make([]int64, len(articles))
-		for i, article := range articles {
-			articleIDs[i] = article.ID
+		resp.Archived, err = h.DB.SearchColdArticles(req.Conditions, limit, offset)
+		if err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
 		}
+		resp.HasMore = resp.HasMore || offset+len(resp.Archived) < resp.ArchivedTotal
+	}
 
-		// Build placeholders for SQL query
-		placeholders := make([]string, len(articleIDs))
-		args := make([]interface{}, len(articleIDs))
-		for i, id := range articleIDs {
-			placeholders[i] = "?"
-			args[i] = id
-		}
+	response.JSON(w, resp)
+}
 
-		// Query all article contents at once
-		query := `SELECT article_id, content FROM article_contents WHERE article_id IN (` + strings.Join(placeholders, ",") + `)`
-		rows, err := h.DB.Query(query, args...)
-		if err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var articleID int64
-				var content string
-				if err := rows.Scan(&articleID, &content); err == nil {
-					articleContents[articleID] = content
-				}
-			}
-		}
+// savedFilterConditions returns the conditions of a saved filter
+func savedFilterConditions(h *core.Handler, id int64) ([]FilterCondition, error) {
+	filters, err := h.DB.GetSavedFilters()
+	if err != nil {
+		return nil, err
 	}
-
-	// Apply filter conditions
-	if len(req.Conditions) > 0 {
-		var filteredArticles []models.Article
-		for _, article := range articles {
-			if evaluateArticleConditions(
-				article,
-				req.Conditions,
-				feedCategories,
-				feedTypes,
-				feedIsImageMode,
-				feedTags,
-				feedArticlesPerMonth,
-				feedLastUpdateStatus,
-				articleContents,
-			) {
-				filteredArticles = append(filteredArticles, article)
+	for _, f := range filters {
+		if f.ID == id {
+			var conditions []FilterCondition
+			if err := json.Unmarshal([]byte(f.Conditions), &conditions); err != nil {
+				return nil, fmt.Errorf("saved filter %d has invalid conditions: %w", id, err)
 			}
+			return conditions, nil
 		}
-		articles = filteredArticles
-	}
-
-	// Apply pagination
-	total := len(articles)
-	offset := (page - 1) * limit
-	end := offset + limit
-
-	// Handle edge cases for pagination
-	var paginatedArticles []models.Article
-	if offset >= total {
-		// No more articles to show
-		paginatedArticles = []models.Article{}
-	} else {
-		if end > total {
-			end = total
-		}
-		paginatedArticles = articles[offset:end]
 	}
-
-	hasMore := end < total
-
-	resp := FilterResponse{
-		Articles: paginatedArticles,
-		Total:    total,
-		Page:     page,
-		Limit:    limit,
-		HasMore:  hasMore,
-	}
-
-	response.JSON(w, resp)
+	return nil, sql.ErrNoRows
 }
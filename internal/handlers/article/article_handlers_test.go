@@ -10,10 +10,12 @@ import (
 	"testing"
 	"time"
 
+	"MrRSS/internal/archive"
 	"MrRSS/internal/database"
 	ff "MrRSS/internal/feed"
 	"MrRSS/internal/handlers/article"
 	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/media"
 	"MrRSS/internal/models"
 )
 
@@ -86,6 +88,99 @@ func TestHandleArticles_ListAndImageGallery(t *testing.T) {
 	}
 }
 
+func TestHandleArticles_CursorPages(t *testing.T) {
+	h := setupHandler(t)
+
+	feedID, err := h.DB.AddFeed(&models.Feed{Title: "F", URL: "http://x"})
+	if err != nil {
+		t.Fatalf("AddFeed: %v", err)
+	}
+	now := time.Now()
+	var articles []*models.Article
+	for i := range 3 {
+		articles = append(articles, &models.Article{FeedID: feedID, Title: fmt.Sprint("a", i), URL: fmt.Sprint("u", i), PublishedAt: now.Add(-time.Duration(i) * time.Minute)})
+	}
+	if err := h.DB.SaveArticles(context.Background(), articles); err != nil {
+		t.Fatalf("SaveArticles: %v", err)
+	}
+
+	var titles []string
+	cursor := ""
+	for range 3 {
+		w := httptest.NewRecorder()
+		article.HandleArticles(h, w, httptest.NewRequest(http.MethodGet, "/api/articles?limit=2&cursor="+cursor, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		var page database.ArticlePage
+		if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		for _, a := range page.Articles {
+			titles = append(titles, a.Title)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if got := strings.Join(titles, ","); got != "a0,a1,a2" {
+		t.Fatalf("paged titles = %s, want a0,a1,a2", got)
+	}
+
+	w := httptest.NewRecorder()
+	article.HandleArticles(h, w, httptest.NewRequest(http.MethodGet, "/api/articles?cursor=bad", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid cursor, got %d", w.Code)
+	}
+}
+
+func TestHandleFilteredArticles_Query(t *testing.T) {
+	h := setupHandler(t)
+
+	feedID, err := h.DB.AddFeed(&models.Feed{Title: "Hacker News", URL: "http://x"})
+	if err != nil {
+		t.Fatalf("AddFeed: %v", err)
+	}
+	if err := h.DB.SaveArticles(context.Background(), []*models.Article{
+		{FeedID: feedID, Title: "Rust 2.0", URL: "u1", PublishedAt: time.Now()},
+		{FeedID: feedID, Title: "Go news", URL: "u2", PublishedAt: time.Now()},
+	}); err != nil {
+		t.Fatalf("SaveArticles: %v", err)
+	}
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		article.HandleFilteredArticles(h, w, httptest.NewRequest(http.MethodPost, "/api/articles/filter", strings.NewReader(body)))
+		return w
+	}
+
+	w := post(`{"query": "feed:\"hacker news\" -go"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp article.FilterResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 1 || resp.Articles[0].Title != "Rust 2.0" {
+		t.Fatalf("expected only the Rust article, got %+v", resp.Articles)
+	}
+
+	w = post(`{"query": "is:unread title:/(/"}`)
+	var errResp struct {
+		Error struct {
+			Position *int `json:"position"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || errResp.Error.Position == nil || *errResp.Error.Position != 16 {
+		t.Fatalf("expected 400 at position 16, got %d %+v", w.Code, errResp)
+	}
+}
+
 func TestArticleActions_MarkRead_Favorite_Hide_ReadLater(t *testing.T) {
 	h := setupHandler(t)
 	feedID, _ := h.DB.AddFeed(&models.Feed{Title: "F2", URL: "http://y"})
@@ -243,3 +338,301 @@ func TestHandleExportToObsidian(t *testing.T) {
 		t.Fatalf("Export not successful: %v", response)
 	}
 }
+
+func TestHandleSavePage(t *testing.T) {
+	h := setupHandler(t)
+
+	page := `<html><head>
+<title>Saved Story</title>
+<meta property="og:title" content="Saved Story">
+<meta property="og:image" content="https://example.com/lead.jpg">
+<meta name="author" content="Jane Writer">
+<meta property="article:published_time" content="2024-05-01T08:00:00Z">
+</head><body><article>
+<h1>Saved Story</h1>
+<p>` + strings.Repeat("This is the body of a saved page that readability should keep. ", 20) + `</p>
+</article></body></html>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, page)
+	}))
+	defer srv.Close()
+
+	// The test server is on loopback, which is refused until a feed on its
+	// host is allowed to reach the private network
+	blocked := httptest.NewRecorder()
+	article.HandleSavePage(h, blocked, httptest.NewRequest(http.MethodPost, "/api/saved/add?url="+srv.URL+"/story", nil))
+	if blocked.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a loopback page, got %d: %s", blocked.Code, blocked.Body.String())
+	}
+	if _, err := h.DB.AddFeed(&models.Feed{Title: "Intranet", URL: srv.URL + "/feed.xml", AllowPrivateNetwork: true}); err != nil {
+		t.Fatalf("AddFeed: %v", err)
+	}
+
+	save := func() models.Article {
+		req := httptest.NewRequest(http.MethodPost, "/api/saved/add?url="+srv.URL+"/story", nil)
+		w := httptest.NewRecorder()
+		article.HandleSavePage(h, w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var saved models.Article
+		if err := json.NewDecoder(w.Body).Decode(&saved); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return saved
+	}
+
+	saved := save()
+	if saved.Title != "Saved Story" || saved.Author != "Jane Writer" || !saved.IsReadLater ||
+		saved.ImageURL != "https://example.com/lead.jpg" || !saved.PublishedAt.Equal(time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected saved article: %+v", saved)
+	}
+
+	feed, err := h.DB.GetFeedByID(saved.FeedID)
+	if err != nil || feed.URL != article.SavedPagesFeedURL || feed.RefreshInterval != -2 {
+		t.Fatalf("article not in the Saved pages feed: %+v (%v)", feed, err)
+	}
+	content, ok, err := h.DB.GetArticleContent(saved.ID)
+	if err != nil || !ok || !strings.Contains(content, "saved page") {
+		t.Errorf("content not cached: %q (%v)", content, err)
+	}
+
+	// Saving the same page again reuses the article
+	if again := save(); again.ID != saved.ID {
+		t.Errorf("expected article %d to be reused, got %d", saved.ID, again.ID)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/saved/add?url=ftp://example.com/x", nil)
+	w := httptest.NewRecorder()
+	article.HandleSavePage(h, w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a non-http URL, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/saved/add?url="+srv.URL+"/story", nil)
+	w = httptest.NewRecorder()
+	article.HandleSavePage(h, w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", w.Code)
+	}
+}
+
+func TestHandleArticleArchive(t *testing.T) {
+	h := setupHandler(t)
+	h.Archives = archive.NewStore(t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/photo.png" {
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, "\x89PNGfake")
+			return
+		}
+		fmt.Fprint(w, `<html><head><title>Kept Story</title></head><body><article><h1>Kept Story</h1><p>`+
+			strings.Repeat("A story worth keeping offline for later. ", 20)+`</p><img src="/photo.png"></article></body></html>`)
+	}))
+	defer srv.Close()
+
+	feedID, err := h.DB.AddFeed(&models.Feed{Title: "F", URL: "http://x", AllowPrivateNetwork: true})
+	if err != nil {
+		t.Fatalf("AddFeed: %v", err)
+	}
+	if err := h.DB.SaveArticle(&models.Article{FeedID: feedID, Title: "Kept Story", URL: srv.URL + "/story", PublishedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveArticle: %v", err)
+	}
+	articles, _ := h.DB.GetArticles("", feedID, "", false, 10, 0)
+	id := articles[0].ID
+	idStr := fmt.Sprint(id)
+
+	call := func(method, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/articles/archive?"+query, nil)
+		w := httptest.NewRecorder()
+		article.HandleArticleArchive(h, w, req)
+		return w
+	}
+
+	if w := call(http.MethodGet, "id="+idStr); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 before archiving, got %d", w.Code)
+	}
+	if w := call(http.MethodPost, "id=999999"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown article, got %d", w.Code)
+	}
+	if w := call(http.MethodPost, "id="+idStr); w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	size, err := h.ArchiveArticle(context.Background(), id)
+	if err != nil {
+		t.Fatalf("ArchiveArticle: %v", err)
+	}
+	if err := h.DB.SetArticleArchived(id, size); err != nil {
+		t.Fatalf("SetArticleArchived: %v", err)
+	}
+
+	w := call(http.MethodGet, "id="+idStr)
+	var status struct {
+		Status  string `json:"status"`
+		OpenURL string `json:"open_url"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if status.Status != models.ArchiveStatusDone || status.OpenURL != "/api/webpage/proxy?archive="+idStr {
+		t.Fatalf("unexpected archive status: %+v", status)
+	}
+
+	// The archived copy is served through the webpage proxy
+	req := httptest.NewRequest(http.MethodGet, status.OpenURL, nil)
+	rec := httptest.NewRecorder()
+	media.HandleWebpageProxy(h, rec, req)
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "A story worth keeping") || !strings.Contains(body, "data:image/png;base64,") {
+		t.Fatalf("unexpected archived copy (%d): %s", rec.Code, body)
+	}
+
+	if w := call(http.MethodDelete, "id="+idStr); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d", w.Code)
+	}
+	rec = httptest.NewRecorder()
+	media.HandleWebpageProxy(h, rec, httptest.NewRequest(http.MethodGet, status.OpenURL, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after delete, got %d", rec.Code)
+	}
+}
+
+func TestOfflineModeServesOnlyCachedContent(t *testing.T) {
+	h := setupHandler(t)
+	feedID, err := h.DB.AddFeed(&models.Feed{Title: "Offline Feed", URL: "http://example.invalid/feed"})
+	if err != nil {
+		t.Fatalf("AddFeed: %v", err)
+	}
+
+	articleModels := []*models.Article{
+		{FeedID: feedID, Title: "Cached", URL: "http://example.invalid/cached", PublishedAt: time.Now()},
+		{FeedID: feedID, Title: "Full text", URL: "http://example.invalid/fulltext", PublishedAt: time.Now().Add(-time.Hour)},
+		{FeedID: feedID, Title: "Missing", URL: "http://example.invalid/missing", PublishedAt: time.Now().Add(-2 * time.Hour)},
+	}
+	if err := h.DB.SaveArticles(context.Background(), articleModels); err != nil {
+		t.Fatalf("SaveArticles: %v", err)
+	}
+	articles, err := h.DB.GetArticles("", feedID, "", true, 10, 0)
+	if err != nil || len(articles) != 3 {
+		t.Fatalf("GetArticles: %v (%d articles)", err, len(articles))
+	}
+	ids := make(map[string]int64)
+	for _, a := range articles {
+		ids[a.Title] = a.ID
+	}
+	if err := h.DB.SetArticleContent(ids["Cached"], "<p>cached</p>"); err != nil {
+		t.Fatalf("SetArticleContent: %v", err)
+	}
+	if err := h.DB.SetArticleFullText(ids["Full text"], "<p>full text</p>"); err != nil {
+		t.Fatalf("SetArticleFullText: %v", err)
+	}
+	if err := h.DB.SetSetting("offline_mode", "true"); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+
+	getContent := func(id int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/articles/content?id="+fmt.Sprint(id), nil)
+		w := httptest.NewRecorder()
+		article.HandleGetArticleContent(h, w, req)
+		return w
+	}
+
+	for title, want := range map[string]string{"Cached": "<p>cached</p>", "Full text": "<p>full text</p>"} {
+		w := getContent(ids[title])
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", title, w.Code, w.Body.String())
+		}
+		var resp map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp["content"] != want {
+			t.Fatalf("%s: expected content %q, got %v", title, want, resp["content"])
+		}
+	}
+
+	if w := getContent(ids["Missing"]); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 for uncached article, got %d", w.Code)
+	}
+
+	// Prefetching needs the network and is refused while offline
+	req := httptest.NewRequest(http.MethodPost, "/api/offline/prefetch", nil)
+	w := httptest.NewRecorder()
+	article.HandleOfflinePrefetch(h, w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for prefetch while offline, got %d", w.Code)
+	}
+}
+
+func TestHandleGetArticleContentSanitizesStoredContent(t *testing.T) {
+	h := setupHandler(t)
+	feedID, err := h.DB.AddFeed(&models.Feed{Title: "Feed", URL: "http://example.invalid/feed"})
+	if err != nil {
+		t.Fatalf("AddFeed: %v", err)
+	}
+	if err := h.DB.SaveArticles(context.Background(), []*models.Article{
+		{FeedID: feedID, Title: "Legacy", URL: "http://example.invalid/legacy", PublishedAt: time.Now()},
+	}); err != nil {
+		t.Fatalf("SaveArticles: %v", err)
+	}
+	articles, err := h.DB.GetArticles("", feedID, "", true, 10, 0)
+	if err != nil || len(articles) != 1 {
+		t.Fatalf("GetArticles: %v (%d articles)", err, len(articles))
+	}
+
+	// Rows stored before sanitization at ingest still reach the client safely
+	stored := `<p onclick="steal()">Hello<script>alert(1)</script></p><img src="javascript:alert(2)">`
+	if err := h.DB.SetArticleContent(articles[0].ID, stored); err != nil {
+		t.Fatalf("SetArticleContent: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/articles/content?id="+fmt.Sprint(articles[0].ID), nil)
+	w := httptest.NewRecorder()
+	article.HandleGetArticleContent(h, w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp["content"] != "<p>Hello</p><img/>" {
+		t.Fatalf("unexpected content %q", resp["content"])
+	}
+}
+
+func TestHandleGetCounts(t *testing.T) {
+	h := setupHandler(t)
+
+	feedID, err := h.DB.AddFeed(&models.Feed{Title: "Feed", URL: "http://x"})
+	if err != nil {
+		t.Fatalf("AddFeed: %v", err)
+	}
+	if err := h.DB.SaveArticles(context.Background(), []*models.Article{
+		{FeedID: feedID, Title: "a", URL: "u1", PublishedAt: time.Now(), IsFavorite: true},
+		{FeedID: feedID, Title: "b", URL: "u2", PublishedAt: time.Now(), IsRead: true},
+	}); err != nil {
+		t.Fatalf("SaveArticles: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	article.HandleGetCounts(h, w, httptest.NewRequest(http.MethodGet, "/api/articles/counts", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Total     int           `json:"total"`
+		Unread    map[int64]int `json:"unread"`
+		Favorites map[int64]int `json:"favorites"`
+		ReadLater map[int64]int `json:"read_later"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 1 || resp.Unread[feedID] != 1 || resp.Favorites[feedID] != 1 || len(resp.ReadLater) != 0 {
+		t.Fatalf("unexpected counts %+v", resp)
+	}
+}
@@ -71,6 +71,9 @@ func HandleToggleFavoriteWithImmediateSync(h *core.Handler, w http.ResponseWrite
 
 	w.WriteHeader(http.StatusOK)
 
+	// New favorites are archived when archive_favorites is on
+	h.RequestArchive()
+
 	// Immediately sync to FreshRSS if needed
 	if syncReq != nil {
 		go performImmediateSync(h, syncReq)
@@ -91,6 +94,14 @@ func performImmediateSync(h *core.Handler, syncReq *database.SyncRequest) {
 		return
 	}
 
+	// Offline, the change waits in the sync queue for the next global sync
+	if h.OfflineMode() {
+		if err := h.DB.EnqueueSyncChange(syncReq.ArticleID, syncReq.ArticleURL, syncReq.Action); err != nil {
+			log.Printf("[Immediate Sync] Failed to enqueue article %d while offline: %v", syncReq.ArticleID, err)
+		}
+		return
+	}
+
 	// Create sync service
 	syncService := freshrss.NewBidirectionalSyncService(serverURL, username, password, h.DB)
 
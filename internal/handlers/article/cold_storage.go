@@ -0,0 +1,98 @@
+package article
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"MrRSS/internal/database"
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+	"MrRSS/internal/models"
+	"MrRSS/internal/readerimport"
+)
+
+// ArticleLookupResponse is the result of looking up an article by URL
+type ArticleLookupResponse struct {
+	Article  *models.Article `json:"article"`
+	Archived bool            `json:"archived"` // The article is in cold storage
+}
+
+// HandleLookupArticle finds the newest article with a URL.
+// @Summary      Look up an article by URL
+// @Description  Find the newest article with the given URL, optionally searching the cold storage archive too
+// @Tags         articles
+// @Produce      json
+// @Param        url              query     string  true   "Article URL"
+// @Param        include_archive  query     bool    false  "Also search the cold storage archive"
+// @Success      200  {object}  ArticleLookupResponse  "The article"
+// @Failure      400  {object}  map[string]string  "Missing url"
+// @Failure      404  {object}  map[string]string  "No article with this URL"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /articles/lookup [get]
+func HandleLookupArticle(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		response.Error(w, errors.New("url is required"), http.StatusBadRequest)
+		return
+	}
+	includeArchive := r.URL.Query().Get("include_archive") == "true"
+
+	article, archived, err := h.DB.FindArticleByURL(url, includeArchive)
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	if article == nil {
+		response.Error(w, errors.New("article not found"), http.StatusNotFound)
+		return
+	}
+	response.JSON(w, ArticleLookupResponse{Article: article, Archived: archived})
+}
+
+// HandleRestoreColdArticle moves an article from cold storage back into its
+// feed. Articles whose feed was deleted are restored into the Imported feed.
+// @Summary      Restore an archived article
+// @Description  Move an article from the cold storage archive back into its feed and mark it read later
+// @Tags         articles
+// @Produce      json
+// @Param        id  query     int64  true  "Archived article ID"
+// @Success      200  {object}  map[string]int64  "ID of the restored article"
+// @Failure      400  {object}  map[string]string  "Invalid ID or cold storage disabled"
+// @Failure      404  {object}  map[string]string  "Article not in cold storage"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /articles/cold-storage/restore [post]
+func HandleRestoreColdArticle(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.Error(w, errors.New("invalid article id"), http.StatusBadRequest)
+		return
+	}
+
+	restoredID, err := h.DB.RestoreColdArticle(id, &models.Feed{
+		Title:           readerimport.ImportedFeedTitle,
+		URL:             readerimport.ImportedFeedURL,
+		Type:            readerimport.ImportedFeedType,
+		Description:     "Items imported from other readers and read-it-later services",
+		RefreshInterval: -2,
+	})
+	if errors.Is(err, database.ErrColdStorageDisabled) {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		response.Error(w, errors.New("article not in cold storage"), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	response.JSON(w, map[string]int64{"id": restoredID})
+}
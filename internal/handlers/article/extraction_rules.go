@@ -0,0 +1,151 @@
+package article
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+	"MrRSS/internal/models"
+	"MrRSS/internal/siteconfig"
+)
+
+// HandleExtractionRules lists the available full-text extraction rules.
+// @Summary      List full-text extraction rules
+// @Description  List the site config rules (ftr-site-config format) available for full-text extraction. User rules in the data dir's site_config directory override bundled rules of the same name.
+// @Tags         articles
+// @Produce      json
+// @Success      200  {array}  siteconfig.Rule  "Rules (name, source)"
+// @Router       /fulltext/rules [get]
+func HandleExtractionRules(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+	response.JSON(w, h.SiteConfigs.List())
+}
+
+// HandleTestExtractionRule shows which rule matches a URL and what it extracts.
+// @Summary      Test full-text extraction for a URL
+// @Description  Fetch a URL with the rule that would be used for it (or the named rule) and return the rule, its directives and the extracted result. An empty "rule" in the page means readability was used.
+// @Tags         articles
+// @Produce      json
+// @Param        url      query     string  true   "Page URL"
+// @Param        rule     query     string  false  "Rule name to test instead of the matching one, or \"none\" for readability"
+// @Param        feed_id  query     int64   false  "Feed whose proxy and pinned rule apply"
+// @Success      200  {object}  map[string]interface{}  "Test result (url, rule, page, error)"
+// @Failure      400  {object}  map[string]string  "Bad request"
+// @Failure      404  {object}  map[string]string  "Rule not found"
+// @Router       /fulltext/test [get]
+func HandleTestExtractionRule(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	pageURL := strings.TrimSpace(r.URL.Query().Get("url"))
+	parsed, err := url.ParseRequestURI(pageURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		response.Error(w, errors.New("a valid http or https URL is required"), http.StatusBadRequest)
+		return
+	}
+
+	var feedConfig *models.Feed
+	if feedIDStr := r.URL.Query().Get("feed_id"); feedIDStr != "" {
+		feedID, err := strconv.ParseInt(feedIDStr, 10, 64)
+		if err != nil {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		if feedConfig, err = h.DB.GetFeedByID(feedID); err != nil {
+			response.Error(w, err, http.StatusNotFound)
+			return
+		}
+	}
+
+	var rule *siteconfig.Rule
+	switch name := r.URL.Query().Get("rule"); name {
+	case "":
+		rule = h.SiteConfigFor(parsed.Host, feedConfig)
+	case siteconfig.RuleNone:
+	default:
+		if rule, err = h.SiteConfigs.Get(name); err != nil {
+			response.Error(w, err, http.StatusNotFound)
+			return
+		}
+	}
+
+	result := map[string]interface{}{
+		"url":  pageURL,
+		"rule": rule,
+	}
+	page, err := h.FetchPageWithRule(pageURL, feedConfig, rule)
+	if err != nil {
+		result["error"] = err.Error()
+	} else {
+		result["page"] = page
+	}
+	response.JSON(w, result)
+}
+
+// HandleFeedExtractionRule gets or sets the extraction rule pinned for a feed.
+// @Summary      Get or set a feed's extraction rule
+// @Description  GET returns the rule pinned for a feed. POST pins a rule by name, "none" to turn rules off, or "" to pick the rule by article host.
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param        feed_id  query     int64   false  "Feed ID (GET)"
+// @Param        request  body      object  false  "POST: feed_id and rule"
+// @Success      200  {object}  map[string]interface{}  "Feed rule (feed_id, rule)"
+// @Failure      400  {object}  map[string]string  "Bad request or unknown rule"
+// @Router       /fulltext/feed-rule [get]
+// @Router       /fulltext/feed-rule [post]
+func HandleFeedExtractionRule(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		feedID, err := strconv.ParseInt(r.URL.Query().Get("feed_id"), 10, 64)
+		if err != nil {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		rule, err := h.DB.GetFeedExtractionRule(feedID)
+		if err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		response.JSON(w, map[string]interface{}{"feed_id": feedID, "rule": rule})
+
+	case http.MethodPost:
+		var req struct {
+			FeedID int64  `json:"feed_id"`
+			Rule   string `json:"rule"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		if _, err := h.DB.GetFeedByID(req.FeedID); err != nil {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		req.Rule = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(req.Rule)), ".txt")
+		if req.Rule != "" && req.Rule != siteconfig.RuleNone {
+			if _, err := h.SiteConfigs.Get(req.Rule); err != nil {
+				response.Error(w, err, http.StatusBadRequest)
+				return
+			}
+		}
+		if err := h.DB.SetFeedExtractionRule(req.FeedID, req.Rule); err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		response.JSON(w, map[string]interface{}{"feed_id": req.FeedID, "rule": req.Rule})
+
+	default:
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,96 @@
+package article
+
+import (
+	"net/http"
+	"strconv"
+
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+	"MrRSS/internal/models"
+)
+
+// archiveResponse is an archive record with the URL its copy is opened at
+type archiveResponse struct {
+	*models.ArticleArchive
+	OpenURL string `json:"open_url,omitempty"`
+}
+
+// HandleArticleArchive gets, queues or deletes the offline archive of an article.
+// @Summary      Manage an article's offline archive
+// @Description  GET returns the archive status. POST queues the article for archiving (refresh=true archives it again). DELETE removes the archive. A finished archive is opened at open_url, served through the webpage proxy.
+// @Tags         articles
+// @Produce      json
+// @Param        id       query     int64  true   "Article ID"
+// @Param        refresh  query     bool   false  "POST: archive again even when an archive exists"
+// @Success      200  {object}  archiveResponse  "Archive status"
+// @Success      202  {object}  archiveResponse  "Archiving queued"
+// @Failure      400  {object}  map[string]string  "Bad request"
+// @Failure      404  {object}  map[string]string  "Article or archive not found"
+// @Router       /articles/archive [get]
+// @Router       /articles/archive [post]
+// @Router       /articles/archive [delete]
+func HandleArticleArchive(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		record, err := h.DB.GetArticleArchive(id)
+		if err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		if record == nil {
+			response.Error(w, nil, http.StatusNotFound)
+			return
+		}
+		response.JSON(w, newArchiveResponse(record))
+
+	case http.MethodPost:
+		if _, err := h.DB.GetArticleByID(id); err != nil {
+			response.Error(w, err, http.StatusNotFound)
+			return
+		}
+		refresh := r.URL.Query().Get("refresh") == "true"
+		if err := h.DB.QueueArticleArchive(id, refresh); err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		h.RequestArchive()
+
+		record, err := h.DB.GetArticleArchive(id)
+		if err != nil || record == nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		if record.Status != models.ArchiveStatusDone {
+			w.WriteHeader(http.StatusAccepted)
+		}
+		response.JSON(w, newArchiveResponse(record))
+
+	case http.MethodDelete:
+		if err := h.DB.DeleteArticleArchive(id); err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		if err := h.Archives.Remove(id); err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		response.JSON(w, map[string]bool{"success": true})
+
+	default:
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+	}
+}
+
+func newArchiveResponse(record *models.ArticleArchive) archiveResponse {
+	resp := archiveResponse{ArticleArchive: record}
+	if record.Status == models.ArchiveStatusDone {
+		resp.OpenURL = "/api/webpage/proxy?archive=" + strconv.FormatInt(record.ArticleID, 10)
+	}
+	return resp
+}
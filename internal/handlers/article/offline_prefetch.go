@@ -0,0 +1,37 @@
+package article
+
+import (
+	"context"
+	"net/http"
+
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+)
+
+// HandleOfflinePrefetch starts an offline prefetch run or reports its progress.
+// @Summary      Prefetch articles for offline reading
+// @Description  GET returns the state of the latest prefetch run (null when none has run). POST starts a run that caches content, full text and images of unread articles in the categories and saved filters chosen for offline reading, within offline_prefetch_max_mb.
+// @Tags         articles
+// @Produce      json
+// @Success      200  {object}  core.PrefetchState  "Prefetch state"
+// @Success      202  {object}  core.PrefetchState  "Prefetch started"
+// @Failure      409  {object}  map[string]string  "A run is in progress or offline mode is on"
+// @Router       /offline/prefetch [get]
+// @Router       /offline/prefetch [post]
+func HandleOfflinePrefetch(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		response.JSON(w, h.GetPrefetchState())
+
+	case http.MethodPost:
+		if !h.StartPrefetch(context.Background()) {
+			response.Error(w, nil, http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		response.JSON(w, h.GetPrefetchState())
+
+	default:
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+	}
+}
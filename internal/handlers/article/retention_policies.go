@@ -0,0 +1,227 @@
+package article
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+	"MrRSS/internal/models"
+)
+
+// retentionPolicyRequest carries the fields of a retention policy to create
+// or change; rules that are left out keep their current or default value
+type retentionPolicyRequest struct {
+	Scope         string `json:"scope"`
+	FeedID        int64  `json:"feed_id"`
+	Category      string `json:"category"`
+	TagID         int64  `json:"tag_id"`
+	KeepNewest    *int   `json:"keep_newest"`
+	MaxAgeDays    *int   `json:"max_age_days"`
+	KeepUnread    *bool  `json:"keep_unread"`
+	KeepFavorites *bool  `json:"keep_favorites"`
+	KeepReadLater *bool  `json:"keep_read_later"`
+	Enabled       *bool  `json:"enabled"`
+}
+
+// applyRules copies the rules given in the request onto a policy
+func (req *retentionPolicyRequest) applyRules(p *models.RetentionPolicy) error {
+	if req.KeepNewest != nil {
+		p.KeepNewest = *req.KeepNewest
+	}
+	if req.MaxAgeDays != nil {
+		p.MaxAgeDays = *req.MaxAgeDays
+	}
+	if req.KeepUnread != nil {
+		p.KeepUnread = *req.KeepUnread
+	}
+	if req.KeepFavorites != nil {
+		p.KeepFavorites = *req.KeepFavorites
+	}
+	if req.KeepReadLater != nil {
+		p.KeepReadLater = *req.KeepReadLater
+	}
+	if req.Enabled != nil {
+		p.Enabled = *req.Enabled
+	}
+	if p.KeepNewest < 0 || p.MaxAgeDays < 0 {
+		return errors.New("keep_newest and max_age_days must not be negative")
+	}
+	return nil
+}
+
+// HandleRetentionPolicies lists retention policies (GET) or adds one (POST).
+// @Summary      Manage retention policies
+// @Description  GET returns all retention policies. POST adds a policy for a feed (feed_id), a category (category, "" for uncategorized feeds) or the feeds with a tag (tag_id). A policy keeps the keep_newest newest articles of each feed and articles younger than max_age_days (0 for no limit on either), never removes unread articles with keep_unread, and keeps favorites and read-later articles unless keep_favorites or keep_read_later is false. A feed follows its own policy, then that of a tag, then that of its category, and is skipped by the global cleanup rules.
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  false  "Policy (scope, feed_id, category, tag_id, keep_newest, max_age_days, keep_unread, keep_favorites, keep_read_later, enabled) for POST"
+// @Success      200  {array}   models.RetentionPolicy  "Retention policies (GET)"
+// @Success      201  {object}  models.RetentionPolicy  "Created policy (POST)"
+// @Failure      400  {object}  map[string]string  "Bad request"
+// @Failure      409  {object}  map[string]string  "The feed, category or tag already has a policy"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /retention-policies [get]
+// @Router       /retention-policies [post]
+func HandleRetentionPolicies(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		policies, err := h.DB.GetRetentionPolicies()
+		if err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		response.JSON(w, policies)
+	case http.MethodPost:
+		addRetentionPolicy(h, w, r)
+	default:
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+	}
+}
+
+func addRetentionPolicy(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	var req retentionPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	policy := &models.RetentionPolicy{
+		Scope:         req.Scope,
+		KeepFavorites: true,
+		KeepReadLater: true,
+		Enabled:       true,
+	}
+	switch req.Scope {
+	case models.RetentionScopeFeed:
+		policy.FeedID = req.FeedID
+		if policy.FeedID <= 0 {
+			response.Error(w, errors.New("feed_id is required"), http.StatusBadRequest)
+			return
+		}
+		if _, err := h.DB.GetFeedByID(policy.FeedID); err != nil {
+			response.Error(w, errors.New("feed not found"), http.StatusBadRequest)
+			return
+		}
+	case models.RetentionScopeCategory:
+		policy.Category = strings.TrimSpace(req.Category)
+	case models.RetentionScopeTag:
+		policy.TagID = req.TagID
+		if policy.TagID <= 0 {
+			response.Error(w, errors.New("tag_id is required"), http.StatusBadRequest)
+			return
+		}
+	default:
+		response.Error(w, errors.New("scope must be feed, category or tag"), http.StatusBadRequest)
+		return
+	}
+	if err := req.applyRules(policy); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	existing, err := h.DB.GetRetentionPolicies()
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	for _, p := range existing {
+		if p.Scope == policy.Scope && p.FeedID == policy.FeedID && p.Category == policy.Category && p.TagID == policy.TagID {
+			response.Error(w, errors.New("a retention policy for this "+policy.Scope+" already exists"), http.StatusConflict)
+			return
+		}
+	}
+
+	id, err := h.DB.AddRetentionPolicy(policy)
+	if err != nil {
+		// A tag that does not exist fails the foreign key
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	saved, err := h.DB.GetRetentionPolicy(id)
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	response.JSON(w, saved)
+}
+
+// HandleRetentionPolicy changes (PUT) or deletes (DELETE) a retention policy.
+// @Summary      Update or delete a retention policy
+// @Description  PUT changes the rules of a policy; fields that are left out keep their value and the scope cannot be changed. DELETE removes the policy, returning its feeds to the global cleanup rules.
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param        id       query     int64   true   "Policy ID"
+// @Param        request  body      object  false  "Rules to change (keep_newest, max_age_days, keep_unread, keep_favorites, keep_read_later, enabled) for PUT"
+// @Success      200  {object}  models.RetentionPolicy  "Updated policy (PUT) or status (DELETE)"
+// @Failure      400  {object}  map[string]string  "Bad request"
+// @Failure      404  {object}  map[string]string  "Policy not found"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /retention-policies/policy [put]
+// @Router       /retention-policies/policy [delete]
+func HandleRetentionPolicy(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut, http.MethodPatch:
+		var req retentionPolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		policy, err := h.DB.GetRetentionPolicy(id)
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, err, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		if err := req.applyRules(policy); err != nil {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		if err := h.DB.UpdateRetentionPolicy(policy); err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		response.JSON(w, policy)
+	case http.MethodDelete:
+		if err := h.DB.DeleteRetentionPolicy(id); err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		response.JSON(w, map[string]string{"status": "ok"})
+	default:
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleRetentionPreview reports what each retention policy would remove.
+// @Summary      Preview retention policies
+// @Description  Report, for each retention policy, how many feeds it governs and how many articles and MB applying it now would remove. Disabled policies are previewed as if they were enabled.
+// @Tags         articles
+// @Produce      json
+// @Success      200  {array}   models.RetentionPreview  "Preview per policy"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /retention-policies/preview [get]
+func HandleRetentionPreview(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	previews, err := h.DB.PreviewRetentionPolicies()
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	response.JSON(w, previews)
+}
@@ -0,0 +1,127 @@
+package article
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+	"MrRSS/internal/models"
+	"MrRSS/internal/utils/httputil"
+	"MrRSS/internal/utils/textutil"
+	"MrRSS/internal/utils/urlutil"
+)
+
+// The built-in Saved pages feed holds web pages saved for later from outside any feed
+const (
+	SavedPagesFeedURL   = "mrrss://saved"
+	SavedPagesFeedType  = "saved"
+	SavedPagesFeedTitle = "Saved pages"
+)
+
+// HandleSavePage fetches a web page and saves it to read later.
+// @Summary      Save a web page to read later
+// @Description  Fetch any URL, extract its readable content, title, author, lead image and publish date, and store it in the built-in "Saved pages" feed marked read later. The content is cached so it survives the page disappearing. Saving the same URL again refreshes the cached content.
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param        url      query     string  false  "Page URL (or send {\"url\"} as the body)"
+// @Success      200  {object}  models.Article  "Saved article"
+// @Failure      400  {object}  map[string]string  "Bad request (missing or invalid URL)"
+// @Failure      502  {object}  map[string]string  "The page could not be fetched"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /saved/add [post]
+func HandleSavePage(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	// Saving changes data, so it is never done by a GET a page could trigger
+	if r.Method != http.MethodPost {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	pageURL := r.URL.Query().Get("url")
+	if pageURL == "" {
+		var req struct {
+			URL string `json:"url"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		pageURL = req.URL
+	}
+	pageURL = strings.TrimSpace(pageURL)
+	if parsed, err := url.ParseRequestURI(pageURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		response.Error(w, errors.New("a valid http or https URL is required"), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.FetchPage(pageURL, nil)
+	if err != nil {
+		log.Printf("Error saving page %s: %v", pageURL, err)
+		status := http.StatusBadGateway
+		if errors.Is(err, httputil.ErrBlockedAddress) {
+			status = http.StatusForbidden
+		}
+		response.Error(w, err, status)
+		return
+	}
+
+	feedID, err := h.DB.GetOrCreateBuiltinFeed(&models.Feed{
+		Title:           SavedPagesFeedTitle,
+		URL:             SavedPagesFeedURL,
+		Type:            SavedPagesFeedType,
+		Description:     "Web pages saved to read later",
+		RefreshInterval: -2,
+	})
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	title := strings.TrimSpace(page.Title)
+	if title == "" {
+		title = pageURL
+	}
+	hasValidTime := !page.PublishedAt.IsZero()
+	publishedAt := page.PublishedAt
+	if !hasValidTime {
+		publishedAt = time.Now()
+	}
+
+	article := &models.Article{
+		FeedID:          feedID,
+		Title:           title,
+		URL:             pageURL,
+		ImageURL:        page.ImageURL,
+		PublishedAt:     publishedAt,
+		IsReadLater:     true,
+		Author:          page.Author,
+		OriginalSummary: page.Excerpt,
+		UniqueID:        urlutil.GenerateArticleUniqueID(title, feedID, publishedAt, hasValidTime),
+	}
+	articleID, err := h.DB.RestoreArticle(article)
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	content := textutil.CleanHTML(page.Content)
+	if err := h.DB.SetArticleContent(articleID, content); err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	h.ContentCache.Set(articleID, content)
+
+	saved, err := h.DB.GetArticleByID(articleID)
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	response.JSON(w, saved)
+}
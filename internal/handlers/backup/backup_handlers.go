@@ -0,0 +1,161 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"MrRSS/internal/backup"
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+	"MrRSS/internal/handlers/settings"
+)
+
+// maxBackupUploadSize limits raw-body uploads, which are held in memory
+const maxBackupUploadSize = 512 << 20
+
+// encryptedSettings lists the setting keys stored encrypted
+func encryptedSettings() []string {
+	keys := make([]string, 0)
+	for _, def := range settings.AllSettings {
+		if def.Encrypted {
+			keys = append(keys, def.Key)
+		}
+	}
+	return keys
+}
+
+// HandleBackupExport downloads a backup archive of all user data.
+// @Summary      Export a backup archive
+// @Description  Download a zip archive with settings, feeds, tags, saved filters, rules, AI profiles, chat sessions, statistics and article state. Secrets are only included, encrypted, when a passphrase is posted.
+// @Tags         backup
+// @Accept       json
+// @Produce      application/zip
+// @Param        include_content  query     bool    false  "Include the article content cache"
+// @Param        request          body      object  false  "POST only: include_content and passphrase"
+// @Success      200  {file}    file  "Backup archive"
+// @Failure      400  {object}  map[string]string  "Bad request"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /backup/export [get]
+// @Router       /backup/export [post]
+func HandleBackupExport(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	opts := backup.ExportOptions{EncryptedSettings: encryptedSettings()}
+
+	switch r.Method {
+	case http.MethodGet:
+		v := r.URL.Query().Get("include_content")
+		opts.IncludeContent = v == "true" || v == "1"
+	case http.MethodPost:
+		// The passphrase is only accepted in a body so it never ends up in URLs or logs
+		var req struct {
+			IncludeContent bool   `json:"include_content"`
+			Passphrase     string `json:"passphrase"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		opts.IncludeContent = req.IncludeContent
+		opts.Passphrase = req.Passphrase
+	default:
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Build the archive in memory so a failure can still be reported as JSON
+	var buf bytes.Buffer
+	manifest, err := backup.Export(h.DB, &buf, opts)
+	if err != nil {
+		log.Printf("Error exporting backup: %v", err)
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("mrrss-backup-%s.zip", manifest.CreatedAt.Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Write(buf.Bytes())
+}
+
+// HandleBackupImport restores a backup archive.
+// @Summary      Import a backup archive
+// @Description  Restore a backup archive. In merge mode existing data is kept and only unset settings are restored; replace mode deletes feeds, articles and other user data first. IDs are remapped and secrets are re-encrypted for this machine.
+// @Tags         backup
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file        formData  file    false  "Backup archive (or send it as the raw request body)"
+// @Param        mode        formData  string  false  "merge (default) or replace"
+// @Param        passphrase  formData  string  false  "Passphrase used when the backup was created"
+// @Success      200  {object}  backup.ImportResult  "Restore summary"
+// @Failure      400  {object}  map[string]string  "Bad request or invalid archive"
+// @Failure      401  {object}  map[string]string  "Wrong passphrase"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /backup/import [post]
+func HandleBackupImport(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var archive io.ReaderAt
+	var size int64
+	opts := backup.ImportOptions{EncryptedSettings: encryptedSettings()}
+
+	if strings.Contains(r.Header.Get("Content-Type"), "multipart/form-data") {
+		f, header, err := r.FormFile("file")
+		if err != nil {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		archive, size = f, header.Size
+		opts.Mode = backup.Mode(r.FormValue("mode"))
+		opts.Passphrase = r.FormValue("passphrase")
+	} else {
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxBackupUploadSize+1))
+		if err != nil {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		if len(data) > maxBackupUploadSize {
+			response.Error(w, errors.New("backup archive is too large; upload it as multipart/form-data"), http.StatusRequestEntityTooLarge)
+			return
+		}
+		archive, size = bytes.NewReader(data), int64(len(data))
+		opts.Mode = backup.Mode(r.URL.Query().Get("mode"))
+		opts.Passphrase = r.Header.Get("X-Backup-Passphrase")
+	}
+
+	start := time.Now()
+	result, err := backup.Import(h.DB, archive, size, opts)
+	if err != nil {
+		log.Printf("Error importing backup: %v", err)
+		status := http.StatusBadRequest
+		if errors.Is(err, backup.ErrWrongPassphrase) {
+			status = http.StatusUnauthorized
+		} else if result != nil {
+			// The restore failed part way through
+			status = http.StatusInternalServerError
+		}
+		response.Error(w, err, status)
+		return
+	}
+	log.Printf("Restored backup from %s in %v (%d feeds, %d articles)",
+		result.Manifest.CreatedAt.Format(time.RFC3339), time.Since(start), result.Feeds, result.Articles)
+
+	// Fetch articles for restored feeds in the background
+	if len(result.FeedIDs) > 0 {
+		go func() {
+			h.Fetcher.FetchFeedsByIDs(context.Background(), result.FeedIDs)
+		}()
+	}
+
+	response.JSON(w, result)
+}
@@ -0,0 +1,243 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"MrRSS/internal/backup"
+	"MrRSS/internal/crypto"
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+)
+
+// minPassphraseLength is the shortest accepted master passphrase
+const minPassphraseLength = 8
+
+// passphraseRequest carries the passphrases of an encryption request. They
+// are only accepted in a body so they never end up in URLs or logs.
+type passphraseRequest struct {
+	Passphrase        string              `json:"passphrase"`
+	CurrentPassphrase string              `json:"current_passphrase"`
+	Secrets           *backup.SecretsFile `json:"secrets"`
+}
+
+// encryptionError writes the status for an error of the master key
+func encryptionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, crypto.ErrWrongPassphrase), errors.Is(err, backup.ErrWrongPassphrase):
+		response.Error(w, err, http.StatusUnauthorized)
+	case errors.Is(err, crypto.ErrLocked):
+		response.Error(w, err, http.StatusLocked)
+	default:
+		response.Error(w, err, http.StatusInternalServerError)
+	}
+}
+
+// HandleEncryptionStatus reports how secrets are encrypted.
+// @Summary      Get the encryption status
+// @Description  Report whether secrets are encrypted with the machine key or a master passphrase, whether the master key is unlocked, and how many stored secrets cannot be decrypted now.
+// @Tags         encryption
+// @Produce      json
+// @Success      200  {object}  database.EncryptionStatus  "Encryption status"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /encryption [get]
+func HandleEncryptionStatus(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	status, err := h.DB.GetEncryptionStatus()
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	response.JSON(w, status)
+}
+
+// HandleMasterPassphrase sets or changes (POST) or removes (DELETE) the master passphrase.
+// @Summary      Set or remove the master passphrase
+// @Description  POST derives a key from passphrase with Argon2id and re-encrypts all secrets with it, so they can be decrypted on any machine that knows the passphrase. DELETE re-encrypts them with the machine key. When a passphrase is set, current_passphrase must match it.
+// @Tags         encryption
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "passphrase (POST) and current_passphrase"
+// @Success      200  {object}  database.SecretsRotation  "Re-encrypted secrets"
+// @Failure      400  {object}  map[string]string  "Bad request"
+// @Failure      401  {object}  map[string]string  "Wrong current passphrase"
+// @Failure      423  {object}  map[string]string  "Master key locked"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /encryption/passphrase [post]
+// @Router       /encryption/passphrase [delete]
+func HandleMasterPassphrase(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+	var req passphraseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.DB.GetEncryptionStatus()
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	if status.Mode == "passphrase" {
+		if err := h.DB.CheckMasterPassphrase(req.CurrentPassphrase); err != nil {
+			encryptionError(w, err)
+			return
+		}
+	}
+
+	var result interface{}
+	if r.Method == http.MethodPost {
+		if len(req.Passphrase) < minPassphraseLength {
+			response.Error(w, fmt.Errorf("passphrase must be at least %d characters", minPassphraseLength), http.StatusBadRequest)
+			return
+		}
+		result, err = h.DB.SetMasterPassphrase(req.Passphrase)
+	} else {
+		if status.Mode != "passphrase" {
+			response.Error(w, errors.New("no master passphrase is set"), http.StatusBadRequest)
+			return
+		}
+		result, err = h.DB.RemoveMasterPassphrase()
+	}
+	if err != nil {
+		log.Printf("Error changing master passphrase: %v", err)
+		encryptionError(w, err)
+		return
+	}
+	response.JSON(w, result)
+}
+
+// HandleUnlockMasterKey unlocks the master key with its passphrase.
+// @Summary      Unlock the master key
+// @Description  Load the master key from its passphrase, e.g. after moving the database to another machine. The key is then kept for this machine, so the passphrase is not asked again on the next start.
+// @Tags         encryption
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "passphrase"
+// @Success      200  {object}  database.EncryptionStatus  "Encryption status"
+// @Failure      400  {object}  map[string]string  "Bad request"
+// @Failure      401  {object}  map[string]string  "Wrong passphrase"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /encryption/unlock [post]
+func HandleUnlockMasterKey(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+	var req passphraseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := h.DB.UnlockMasterKey(req.Passphrase); err != nil {
+		encryptionError(w, err)
+		return
+	}
+	HandleEncryptionStatus(h, w, r)
+}
+
+// HandleRotateSecrets re-encrypts all secrets with the current key.
+// @Summary      Rotate encrypted secrets
+// @Description  Re-encrypt all encrypted settings, AI profile API keys and feed email passwords with the current key and fresh salts, moving values of an older key version to the current one. Values that cannot be decrypted are left as they are and counted as failed.
+// @Tags         encryption
+// @Produce      json
+// @Success      200  {object}  database.SecretsRotation  "Re-encrypted secrets"
+// @Failure      423  {object}  map[string]string  "Master key locked"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /encryption/rotate [post]
+func HandleRotateSecrets(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+	result, err := h.DB.RotateSecrets()
+	if err != nil {
+		log.Printf("Error rotating secrets: %v", err)
+		encryptionError(w, err)
+		return
+	}
+	response.JSON(w, result)
+}
+
+// HandleSecretsExport downloads the secrets sealed with a passphrase.
+// @Summary      Export secrets
+// @Description  Download the encrypted settings, AI profile API keys and feed email passwords, sealed with passphrase, to import them on another machine.
+// @Tags         encryption
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "passphrase"
+// @Success      200  {object}  backup.SecretsFile  "Secrets export"
+// @Failure      400  {object}  map[string]string  "Bad request"
+// @Failure      423  {object}  map[string]string  "Master key locked"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /encryption/secrets/export [post]
+func HandleSecretsExport(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+	var req passphraseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	if req.Passphrase == "" {
+		response.Error(w, errors.New("passphrase is required"), http.StatusBadRequest)
+		return
+	}
+
+	file, err := backup.ExportSecrets(h.DB, req.Passphrase, encryptedSettings())
+	if err != nil {
+		log.Printf("Error exporting secrets: %v", err)
+		encryptionError(w, err)
+		return
+	}
+	filename := fmt.Sprintf("mrrss-secrets-%s.json", file.CreatedAt.Format("20060102-150405"))
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	response.JSON(w, file)
+}
+
+// HandleSecretsImport stores the secrets of a secrets export.
+// @Summary      Import secrets
+// @Description  Decrypt a secrets export with its passphrase and store the secrets with this machine's key. API keys and email passwords are matched to AI profiles by name and to feeds by URL.
+// @Tags         encryption
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "passphrase and secrets (the export)"
+// @Success      200  {object}  backup.SecretsImportResult  "Imported secrets"
+// @Failure      400  {object}  map[string]string  "Bad request"
+// @Failure      401  {object}  map[string]string  "Wrong passphrase"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /encryption/secrets/import [post]
+func HandleSecretsImport(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+	var req passphraseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	if req.Secrets == nil {
+		response.Error(w, errors.New("secrets are required"), http.StatusBadRequest)
+		return
+	}
+
+	result, err := backup.ImportSecrets(h.DB, req.Secrets, req.Passphrase, encryptedSettings())
+	if errors.Is(err, backup.ErrWrongPassphrase) {
+		response.Error(w, err, http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("Error importing secrets: %v", err)
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	response.JSON(w, result)
+}
@@ -0,0 +1,140 @@
+package backup
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+	"MrRSS/internal/snapshot"
+	"MrRSS/internal/utils/fileutil"
+)
+
+// snapshotError writes the status for a snapshot error
+func snapshotError(w http.ResponseWriter, err error) {
+	if errors.Is(err, snapshot.ErrNotFound) {
+		response.Error(w, err, http.StatusNotFound)
+		return
+	}
+	response.Error(w, err, http.StatusInternalServerError)
+}
+
+// HandleSnapshots lists database snapshots (GET) or takes one now (POST).
+// @Summary      Manage database snapshots
+// @Description  GET returns the database snapshots, newest first, and whether a restore is staged for the next start. POST takes a manual snapshot, which rotation never removes.
+// @Tags         backup
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Snapshots and restore_pending (GET)"
+// @Success      201  {object}  snapshot.Snapshot  "Created snapshot (POST)"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /backup/snapshots [get]
+// @Router       /backup/snapshots [post]
+func HandleSnapshots(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		snapshots, err := h.Snapshots.List()
+		if err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		dbPath, err := fileutil.GetDBPath()
+		if err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		response.JSON(w, map[string]interface{}{
+			"snapshots":       snapshots,
+			"restore_pending": snapshot.PendingRestore(dbPath),
+		})
+	case http.MethodPost:
+		compress, _ := h.DB.GetSetting("backup_compress")
+		snap, err := h.Snapshots.Create(h.DB, compress == "true", true)
+		if err != nil {
+			log.Printf("Error taking database snapshot: %v", err)
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		response.JSON(w, snap)
+	default:
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleSnapshot downloads (GET) or deletes (DELETE) a database snapshot.
+// @Summary      Download or delete a database snapshot
+// @Description  GET downloads the snapshot file, gzip-compressed when its name ends in .gz. DELETE removes it.
+// @Tags         backup
+// @Produce      application/octet-stream
+// @Param        name  query     string  true  "Snapshot name"
+// @Success      200  {file}    file  "Snapshot file (GET) or status (DELETE)"
+// @Failure      404  {object}  map[string]string  "Snapshot not found"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /backup/snapshots/snapshot [get]
+// @Router       /backup/snapshots/snapshot [delete]
+func HandleSnapshot(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+
+	switch r.Method {
+	case http.MethodGet:
+		path, err := h.Snapshots.Path(name)
+		if err != nil {
+			snapshotError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", "attachment; filename="+name)
+		http.ServeFile(w, r, path)
+	case http.MethodDelete:
+		if err := h.Snapshots.Delete(name); err != nil {
+			snapshotError(w, err)
+			return
+		}
+		response.JSON(w, map[string]string{"status": "ok"})
+	default:
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleSnapshotRestore stages (POST) or cancels (DELETE) a snapshot restore.
+// @Summary      Restore a database snapshot
+// @Description  POST verifies the snapshot and stages it to replace the database on the next start; the replaced database is kept next to it as a .before-restore file. DELETE cancels a staged restore.
+// @Tags         backup
+// @Produce      json
+// @Param        name  query     string  false  "Snapshot name (POST)"
+// @Success      200  {object}  map[string]interface{}  "Status and restore_pending"
+// @Failure      404  {object}  map[string]string  "Snapshot not found"
+// @Failure      500  {object}  map[string]string  "Internal server error or failed integrity check"
+// @Router       /backup/snapshots/restore [post]
+// @Router       /backup/snapshots/restore [delete]
+func HandleSnapshotRestore(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	dbPath, err := fileutil.GetDBPath()
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		name := r.URL.Query().Get("name")
+		if err := h.Snapshots.StageRestore(name, dbPath); err != nil {
+			log.Printf("Error staging snapshot restore: %v", err)
+			snapshotError(w, err)
+			return
+		}
+		log.Printf("Snapshot %s will be restored on the next start", name)
+	case http.MethodDelete:
+		if err := snapshot.CancelRestore(dbPath); err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+	default:
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+	response.JSON(w, map[string]interface{}{
+		"status":          "ok",
+		"restore_pending": snapshot.PendingRestore(dbPath),
+	})
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"MrRSS/internal/archive"
+)
+
+const (
+	// archiverInterval is how often queued archives are processed without a request
+	archiverInterval = 10 * time.Minute
+	// archiverBatchSize is how many articles are archived per run
+	archiverBatchSize = 20
+	// archiveTimeout limits archiving a single article with its resources
+	archiveTimeout = 5 * time.Minute
+)
+
+// RequestArchive wakes the archiver to process queued archives now
+func (h *Handler) RequestArchive() {
+	select {
+	case h.archiveWake <- struct{}{}:
+	default:
+	}
+}
+
+// ArchiveArticle saves a self-contained copy of an article with its images,
+// stylesheets and fonts embedded, and returns its size. The full content is
+// fetched from the article URL; when the site is unreachable the cached
+// content is archived instead.
+func (h *Handler) ArchiveArticle(ctx context.Context, articleID int64) (int64, error) {
+	article, err := h.DB.GetArticleByID(articleID)
+	if err != nil {
+		return 0, fmt.Errorf("get article: %w", err)
+	}
+	feedConfig, _ := h.DB.GetFeedByID(article.FeedID)
+
+	doc := archive.Document{
+		Title:       article.Title,
+		Author:      article.Author,
+		SourceURL:   article.URL,
+		PublishedAt: article.PublishedAt,
+	}
+	page, fetchErr := h.FetchPage(article.URL, feedConfig)
+	if fetchErr == nil && page.Content != "" {
+		doc.Content = page.Content
+	} else {
+		content, found, err := h.DB.GetArticleContent(articleID)
+		if err != nil || !found || content == "" {
+			if fetchErr != nil {
+				return 0, fetchErr
+			}
+			return 0, archive.ErrEmpty
+		}
+		doc.Content = content
+	}
+
+	client, err := h.createArticleHTTPClient(feedConfig)
+	if err != nil {
+		return 0, err
+	}
+	data, err := archive.Build(ctx, client, doc)
+	if err != nil {
+		return 0, err
+	}
+	if err := h.Archives.Save(articleID, data); err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+// startArchiver archives queued articles in the background. With
+// archive_favorites enabled every favorite is queued as well.
+func (h *Handler) startArchiver(ctx context.Context) {
+	ticker := time.NewTicker(archiverInterval)
+	defer ticker.Stop()
+
+	for {
+		h.runArchiver(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-h.archiveWake:
+		}
+	}
+}
+
+// runArchiver processes one batch of queued archives and removes archive
+// files whose articles are gone
+func (h *Handler) runArchiver(ctx context.Context) {
+	if enabled, _ := h.DB.GetSetting("archive_favorites"); enabled == "true" {
+		if _, err := h.DB.QueueFavoriteArchives(); err != nil {
+			log.Printf("Error queueing favorites for archiving: %v", err)
+		}
+	}
+
+	ids, err := h.DB.GetPendingArticleArchives(archiverBatchSize)
+	if err != nil {
+		log.Printf("Error loading queued archives: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		archiveCtx, cancel := context.WithTimeout(ctx, archiveTimeout)
+		size, err := h.ArchiveArticle(archiveCtx, id)
+		cancel()
+		if err != nil {
+			log.Printf("Error archiving article %d: %v", id, err)
+			_ = h.DB.SetArticleArchiveFailed(id, err.Error())
+			continue
+		}
+		_ = h.DB.SetArticleArchived(id, size)
+	}
+
+	// More work is queued; continue without waiting for the ticker
+	if len(ids) == archiverBatchSize {
+		h.RequestArchive()
+	}
+
+	if keep, err := h.DB.GetArchivedArticleIDs(); err == nil {
+		if removed, err := h.Archives.Prune(keep); err == nil && removed > 0 {
+			log.Printf("Removed %d archives of deleted articles", removed)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"MrRSS/internal/database"
 	"MrRSS/internal/feed"
 	"MrRSS/internal/models"
+	"MrRSS/internal/utils/httputil"
 )
 
 func TestNewHandler_ConstructsHandler(t *testing.T) {
@@ -84,10 +85,11 @@ func TestCreateArticleHTTPClientUsesGlobalProxyWhenFeedRequestsIt(t *testing.T)
 
 func proxyURLFromClient(t *testing.T, client *http.Client) string {
 	t.Helper()
-	transport, ok := client.Transport.(*http.Transport)
+	outbound, ok := client.Transport.(*httputil.OutboundTransport)
 	if !ok {
 		t.Fatalf("unexpected transport type %T", client.Transport)
 	}
+	transport := outbound.Base
 	if transport.Proxy == nil {
 		t.Fatalf("expected proxy to be configured")
 	}
@@ -14,12 +14,16 @@ import (
 	"time"
 
 	"MrRSS/internal/ai"
+	"MrRSS/internal/archive"
 	"MrRSS/internal/cache"
 	"MrRSS/internal/database"
 	"MrRSS/internal/discovery"
 	"MrRSS/internal/feed"
 	"MrRSS/internal/models"
+	"MrRSS/internal/readerimport"
 	svc "MrRSS/internal/service"
+	"MrRSS/internal/siteconfig"
+	"MrRSS/internal/snapshot"
 	"MrRSS/internal/statistics"
 	"MrRSS/internal/translation"
 	"MrRSS/internal/utils/httputil"
@@ -48,6 +52,15 @@ type DiscoveryState struct {
 	IsComplete bool                       `json:"is_complete"`
 }
 
+// ReaderImportState represents the current state of an import from another reader
+type ReaderImportState struct {
+	IsRunning  bool                  `json:"is_running"`
+	IsComplete bool                  `json:"is_complete"`
+	Format     string                `json:"format,omitempty"`
+	Progress   readerimport.Progress `json:"progress"`
+	Error      string                `json:"error,omitempty"`
+}
+
 // Handler holds all dependencies for HTTP handlers.
 // It now uses a service registry for better separation of concerns.
 type Handler struct {
@@ -64,11 +77,25 @@ type Handler struct {
 	App               interface{}         // Wails app instance for browser integration (interface{} to avoid import in server mode)
 	ContentCache      *cache.ContentCache // Cache for article content
 	Stats             *statistics.Service // Statistics tracking service
+	SiteConfigs       *siteconfig.Store   // Site-specific full-text extraction rules
+	Archives          *archive.Store      // Offline copies of articles
+	Snapshots         *snapshot.Store     // Database snapshots taken by the backup scheduler
 
 	// Discovery state tracking for polling-based progress
 	DiscoveryMu          sync.RWMutex
 	SingleDiscoveryState *DiscoveryState
 	BatchDiscoveryState  *DiscoveryState
+
+	// Reader import state tracking for polling-based progress
+	ReaderImportMu    sync.RWMutex
+	ReaderImportState *ReaderImportState
+
+	// Offline prefetch state tracking for polling-based progress
+	PrefetchMu    sync.RWMutex
+	PrefetchState *PrefetchState
+
+	// archiveWake wakes the background archiver
+	archiveWake chan struct{}
 }
 
 // NewHandler creates a new Handler with the given dependencies.
@@ -86,6 +113,13 @@ func NewHandler(db *database.DB, fetcher *feed.Fetcher, translator translation.T
 		DiscoveryService:  registry.DiscoveryService(),
 		ContentCache:      registry.ContentCache(),
 		Stats:             registry.Stats(),
+		SiteConfigs:       siteconfig.NewStore(""),
+		Archives:          archive.NewStore(""),
+		Snapshots:         snapshot.NewStore(""),
+		archiveWake:       make(chan struct{}, 1),
+	}
+	if h.DiscoveryService != nil {
+		h.DiscoveryService.SetOutboundPolicy(h.OutboundPolicy(nil))
 	}
 
 	return h
@@ -131,6 +165,11 @@ func (h *Handler) GetArticleContent(articleID int64) (string, bool, error) {
 		return content, true, nil
 	}
 
+	// In offline mode only cached content is served
+	if h.OfflineMode() {
+		return "", false, ErrOffline
+	}
+
 	// Get the article from database
 	article, err := h.DB.GetArticleByID(articleID)
 	if err != nil {
@@ -189,19 +228,94 @@ func (h *Handler) FetchFullArticleContent(articleURL string) (string, error) {
 
 // FetchFullArticleContentWithFeed fetches full content using the same proxy semantics as feed refresh.
 func (h *Handler) FetchFullArticleContentWithFeed(articleURL string, feedConfig *models.Feed) (string, error) {
-	parsedURL, err := url.ParseRequestURI(articleURL)
+	page, err := h.FetchPage(articleURL, feedConfig)
+	if err != nil {
+		return "", err
+	}
+	return page.Content, nil
+}
+
+// Page is a web page extracted with a site config rule or readability
+type Page struct {
+	Title       string    `json:"title"`
+	Author      string    `json:"author,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	Excerpt     string    `json:"excerpt,omitempty"`
+	Content     string    `json:"content"`                // Readable content as HTML
+	PublishedAt time.Time `json:"published_at,omitempty"` // Zero when the page has no publish date metadata
+	Rule        string    `json:"rule,omitempty"`         // Site config rule that extracted the page, empty for readability
+	Pages       []string  `json:"pages,omitempty"`        // Pages stitched together by the rule
+}
+
+// SiteConfigFor returns the site config rule used for full-text extraction
+// of a page on host. A feed can pin a rule by name or turn rules off with
+// "none"; otherwise the rule is picked by host. It returns nil when no rule applies.
+func (h *Handler) SiteConfigFor(host string, feedConfig *models.Feed) *siteconfig.Rule {
+	if feedConfig != nil && feedConfig.ID != 0 {
+		if name, err := h.DB.GetFeedExtractionRule(feedConfig.ID); err == nil && name != "" {
+			if name == siteconfig.RuleNone {
+				return nil
+			}
+			if rule, err := h.SiteConfigs.Get(name); err == nil {
+				return rule
+			}
+			log.Printf("Extraction rule %q of feed %d not found, picking by host", name, feedConfig.ID)
+		}
+	}
+	return h.SiteConfigs.Lookup(host)
+}
+
+// FetchPage fetches a web page and extracts its readable content and metadata.
+// A site config rule is used when one applies; readability is used otherwise,
+// or when the rule finds no content and allows falling back.
+// feedConfig selects the proxy like FetchFullArticleContentWithFeed and may pin a rule; it may be nil.
+func (h *Handler) FetchPage(pageURL string, feedConfig *models.Feed) (*Page, error) {
+	parsedURL, err := url.ParseRequestURI(pageURL)
 	if err != nil {
-		return "", fmt.Errorf("parse article URL: %w", err)
+		return nil, fmt.Errorf("parse article URL: %w", err)
 	}
+	return h.FetchPageWithRule(pageURL, feedConfig, h.SiteConfigFor(parsedURL.Host, feedConfig))
+}
 
-	client, err := h.createArticleHTTPClient(feedConfig)
+// FetchPageWithRule is FetchPage with an explicit site config rule; a nil rule means readability only
+func (h *Handler) FetchPageWithRule(pageURL string, feedConfig *models.Feed, rule *siteconfig.Rule) (*Page, error) {
+	parsedURL, err := url.ParseRequestURI(pageURL)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("parse article URL: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodGet, articleURL, nil)
+	client, err := h.createArticleHTTPClient(feedConfig, pageContentTypes...)
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return nil, err
+	}
+
+	if rule != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		result, err := siteconfig.Extract(ctx, client, pageURL, rule.Config)
+		if err == nil && result.Content != "" {
+			return &Page{
+				Title:       result.Title,
+				Author:      result.Author,
+				Content:     result.Content,
+				PublishedAt: result.PublishedAt,
+				Rule:        rule.Name,
+				Pages:       result.Pages,
+			}, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("site config %s matched no content", rule.Name)
+		}
+		if !rule.Config.AutodetectOnFailure {
+			return nil, err
+		}
+		log.Printf("Falling back to readability for %s: %v", pageURL, err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
 	}
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
@@ -209,30 +323,49 @@ func (h *Handler) FetchFullArticleContentWithFeed(articleURL string, feedConfig
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("fetch page: %w", err)
+		return nil, fmt.Errorf("fetch page: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("fetch page: HTTP %d", resp.StatusCode)
+		return nil, fmt.Errorf("fetch page: HTTP %d", resp.StatusCode)
 	}
 
 	article, err := readability.FromReader(resp.Body, parsedURL)
 	if err != nil {
-		return "", fmt.Errorf("readability parse: %w", err)
+		return nil, fmt.Errorf("readability parse: %w", err)
 	}
 
 	// Render the article content as HTML
 	var buf bytes.Buffer
 	err = article.RenderHTML(&buf)
 	if err != nil {
-		return "", fmt.Errorf("render HTML: %w", err)
+		return nil, fmt.Errorf("render HTML: %w", err)
 	}
 
-	return buf.String(), nil
+	page := &Page{
+		Title:    article.Title(),
+		Author:   article.Byline(),
+		ImageURL: article.ImageURL(),
+		Excerpt:  article.Excerpt(),
+		Content:  buf.String(),
+	}
+	if published, err := article.PublishedTime(); err == nil {
+		page.PublishedAt = published
+	}
+	return page, nil
 }
 
-func (h *Handler) createArticleHTTPClient(feedConfig *models.Feed) (*http.Client, error) {
+// maxArticleFetchBytes caps the size of pages and resources fetched for articles
+const maxArticleFetchBytes = 20 << 20
+
+// pageContentTypes are the content types accepted when fetching article pages
+var pageContentTypes = []string{"text/html", "application/xhtml+xml", "text/plain"}
+
+// createArticleHTTPClient creates a client for fetching article pages and their
+// resources, honoring the feed's proxy and private network settings.
+// contentTypes, when given, limits the accepted response types.
+func (h *Handler) createArticleHTTPClient(feedConfig *models.Feed, contentTypes ...string) (*http.Client, error) {
 	var proxyURL string
 	if feedConfig != nil && feedConfig.ProxyEnabled && feedConfig.ProxyURL != "" {
 		proxyURL = feedConfig.ProxyURL
@@ -248,7 +381,35 @@ func (h *Handler) createArticleHTTPClient(feedConfig *models.Feed) (*http.Client
 		}
 	}
 
-	return httputil.CreateHTTPClient(proxyURL, 30*time.Second)
+	policy := h.OutboundPolicy(feedConfig)
+	policy.MaxBodyBytes = maxArticleFetchBytes
+	policy.ContentTypes = contentTypes
+	return httputil.NewOutboundClient(proxyURL, 30*time.Second, policy)
+}
+
+// OutboundPolicy returns the policy for fetching URLs taken from feed content
+// or API requests. Internal addresses are refused unless feedConfig opted in
+// to private network access, or the host belongs to any feed that did.
+// feedConfig may be nil.
+func (h *Handler) OutboundPolicy(feedConfig *models.Feed) httputil.OutboundPolicy {
+	return httputil.OutboundPolicy{
+		AllowPrivate: feedConfig != nil && feedConfig.AllowPrivateNetwork,
+		AllowHost:    h.privateNetworkHostAllowed,
+	}
+}
+
+// privateNetworkHostAllowed reports whether host belongs to a feed allowed to
+// reach private network addresses
+func (h *Handler) privateNetworkHostAllowed(host string) bool {
+	if h.DB == nil {
+		return false
+	}
+	hosts, err := h.DB.GetPrivateNetworkHosts()
+	if err != nil {
+		log.Printf("Failed to load private network hosts: %v", err)
+		return false
+	}
+	return hosts[host]
 }
 
 func (h *Handler) globalProxyURL() string {
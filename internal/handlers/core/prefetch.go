@@ -0,0 +1,377 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"MrRSS/internal/cache"
+	"MrRSS/internal/feed"
+	"MrRSS/internal/models"
+	"MrRSS/internal/network"
+	"MrRSS/internal/rules"
+	"MrRSS/internal/utils/fileutil"
+)
+
+const (
+	// prefetchMaxArticles caps how many articles one prefetch run considers
+	prefetchMaxArticles = 200
+	// prefetchArticleTimeout limits prefetching a single article with its images
+	prefetchArticleTimeout = 2 * time.Minute
+	// defaultPrefetchMaxMB is the download budget of a run when none is set
+	defaultPrefetchMaxMB = 200
+)
+
+// ErrOffline is returned when offline mode is on and the requested content
+// is not cached
+var ErrOffline = errors.New("offline mode: content is not available offline")
+
+// PrefetchState represents the current state of an offline prefetch run
+type PrefetchState struct {
+	IsRunning     bool       `json:"is_running"`
+	Total         int        `json:"total"`          // Articles selected for prefetching
+	Done          int        `json:"done"`           // Articles processed so far
+	Images        int        `json:"images"`         // Images downloaded into the media cache
+	Bytes         int64      `json:"bytes"`          // Bytes downloaded in this run
+	BudgetReached bool       `json:"budget_reached"` // The run stopped at offline_prefetch_max_mb
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// OfflineMode reports whether offline mode is on. In offline mode content is
+// served only from caches and nothing is fetched from the network.
+func (h *Handler) OfflineMode() bool {
+	enabled, _ := h.DB.GetSetting("offline_mode")
+	return enabled == "true"
+}
+
+// GetPrefetchState returns a copy of the state of the latest prefetch run, or
+// nil when none has run
+func (h *Handler) GetPrefetchState() *PrefetchState {
+	h.PrefetchMu.RLock()
+	defer h.PrefetchMu.RUnlock()
+	if h.PrefetchState == nil {
+		return nil
+	}
+	state := *h.PrefetchState
+	return &state
+}
+
+// StartPrefetch starts an offline prefetch run in the background. It returns
+// false when a run is already in progress or offline mode is on.
+func (h *Handler) StartPrefetch(ctx context.Context) bool {
+	if h.OfflineMode() {
+		return false
+	}
+
+	h.PrefetchMu.Lock()
+	if h.PrefetchState != nil && h.PrefetchState.IsRunning {
+		h.PrefetchMu.Unlock()
+		return false
+	}
+	h.PrefetchState = &PrefetchState{IsRunning: true, StartedAt: time.Now()}
+	h.PrefetchMu.Unlock()
+
+	go h.runPrefetch(ctx)
+	return true
+}
+
+// updatePrefetchState applies fn to the current prefetch state under the lock
+func (h *Handler) updatePrefetchState(fn func(state *PrefetchState)) {
+	h.PrefetchMu.Lock()
+	defer h.PrefetchMu.Unlock()
+	if h.PrefetchState != nil {
+		fn(h.PrefetchState)
+	}
+}
+
+// runPrefetch caches the content, full text and images of unread articles in
+// the categories and saved filters chosen for offline reading, until the
+// offline_prefetch_max_mb download budget is used up
+func (h *Handler) runPrefetch(ctx context.Context) {
+	var runErr error
+	defer func() {
+		now := time.Now()
+		h.updatePrefetchState(func(state *PrefetchState) {
+			state.IsRunning = false
+			state.FinishedAt = &now
+			if runErr != nil {
+				state.Error = runErr.Error()
+			}
+		})
+	}()
+
+	articles, err := h.prefetchCandidates()
+	if err != nil {
+		log.Printf("Error selecting articles to prefetch: %v", err)
+		runErr = err
+		return
+	}
+	h.updatePrefetchState(func(state *PrefetchState) { state.Total = len(articles) })
+	if len(articles) == 0 {
+		return
+	}
+
+	budget := int64(defaultPrefetchMaxMB) * 1024 * 1024
+	if mb, err := strconv.Atoi(h.settingOrEmpty("offline_prefetch_max_mb")); err == nil && mb > 0 {
+		budget = int64(mb) * 1024 * 1024
+	}
+	fullText := h.settingOrEmpty("full_text_fetch_enabled") == "true"
+
+	// Images go to the media cache, which the media proxy only reads from
+	// (and the cleanup only bounds) when it is enabled
+	var mediaCache *cache.MediaCache
+	if h.settingOrEmpty("media_cache_enabled") == "true" {
+		if cacheDir, err := fileutil.GetMediaCacheDir(); err == nil {
+			if mediaCache, err = cache.NewMediaCache(cacheDir); err == nil {
+				mediaCache.SetOutboundPolicy(h.OutboundPolicy(nil))
+			}
+		}
+	}
+
+	p := &prefetcher{
+		h:          h,
+		budget:     budget,
+		fullText:   fullText,
+		mediaCache: mediaCache,
+	}
+
+	sem := make(chan struct{}, prefetchConcurrency(h.settingOrEmpty("network_speed")))
+	var wg sync.WaitGroup
+	for _, article := range articles {
+		if ctx.Err() != nil || p.budgetReached() || h.OfflineMode() {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(article models.Article) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			articleCtx, cancel := context.WithTimeout(ctx, prefetchArticleTimeout)
+			defer cancel()
+			p.prefetchArticle(articleCtx, article)
+
+			h.updatePrefetchState(func(state *PrefetchState) {
+				state.Done++
+				state.Images = int(p.images.Load())
+				state.Bytes = p.used.Load()
+			})
+		}(article)
+	}
+	wg.Wait()
+
+	h.updatePrefetchState(func(state *PrefetchState) {
+		state.Images = int(p.images.Load())
+		state.Bytes = p.used.Load()
+		state.BudgetReached = p.budgetReached()
+	})
+	log.Printf("Offline prefetch finished: %d articles, %d images, %d bytes", len(articles), p.images.Load(), p.used.Load())
+}
+
+// settingOrEmpty returns a setting, or an empty string when it cannot be read
+func (h *Handler) settingOrEmpty(key string) string {
+	value, _ := h.DB.GetSetting(key)
+	return value
+}
+
+// prefetchConcurrency returns how many articles are prefetched in parallel
+// for the detected network speed
+func prefetchConcurrency(speed string) int {
+	switch network.SpeedLevel(speed) {
+	case network.SpeedSlow:
+		return 1
+	case network.SpeedMedium:
+		return 3
+	case network.SpeedFast:
+		return 6
+	default:
+		return 2
+	}
+}
+
+// prefetchCandidates returns the unread articles to prefetch, newest first.
+// Articles come from the categories in offline_prefetch_categories and the
+// saved filters in offline_prefetch_filters; when neither is set all unread
+// articles are candidates.
+func (h *Handler) prefetchCandidates() ([]models.Article, error) {
+	var categories []string
+	if raw := h.settingOrEmpty("offline_prefetch_categories"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &categories); err != nil {
+			return nil, err
+		}
+	}
+	var filterIDs []int64
+	if raw := h.settingOrEmpty("offline_prefetch_filters"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &filterIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(categories) == 0 && len(filterIDs) == 0 {
+		return h.DB.GetArticlesWithUnreadFilter("unread", 0, "", false, false, prefetchMaxArticles, 0)
+	}
+
+	seen := make(map[int64]bool)
+	var candidates []models.Article
+	add := func(articles []models.Article) {
+		for _, article := range articles {
+			if !seen[article.ID] {
+				seen[article.ID] = true
+				candidates = append(candidates, article)
+			}
+		}
+	}
+
+	for _, category := range categories {
+		articles, err := h.DB.GetArticlesWithUnreadFilter("unread", 0, category, false, false, prefetchMaxArticles, 0)
+		if err != nil {
+			return nil, err
+		}
+		add(articles)
+	}
+
+	if len(filterIDs) > 0 {
+		filters, err := h.DB.GetSavedFilters()
+		if err != nil {
+			return nil, err
+		}
+		unread, err := h.DB.GetArticlesWithUnreadFilter("unread", 0, "", false, false, prefetchMaxArticles*5, 0)
+		if err != nil {
+			return nil, err
+		}
+		engine := rules.NewEngine(h.DB)
+		for _, filter := range filters {
+			if !containsID(filterIDs, filter.ID) {
+				continue
+			}
+			var conditions []rules.Condition
+			if err := json.Unmarshal([]byte(filter.Conditions), &conditions); err != nil {
+				log.Printf("Skipping saved filter %q for prefetch: %v", filter.Name, err)
+				continue
+			}
+			matched, err := engine.FilterArticles(unread, conditions)
+			if err != nil {
+				return nil, err
+			}
+			add(matched)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].PublishedAt.After(candidates[j].PublishedAt)
+	})
+	if len(candidates) > prefetchMaxArticles {
+		candidates = candidates[:prefetchMaxArticles]
+	}
+	return candidates, nil
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// prefetcher caches articles for one prefetch run within its byte budget
+type prefetcher struct {
+	h          *Handler
+	budget     int64
+	fullText   bool
+	mediaCache *cache.MediaCache
+
+	used   atomic.Int64
+	images atomic.Int64
+}
+
+func (p *prefetcher) budgetReached() bool {
+	return p.used.Load() >= p.budget
+}
+
+// prefetchArticle caches the feed content, the full text and the images of an article
+func (p *prefetcher) prefetchArticle(ctx context.Context, article models.Article) {
+	h := p.h
+
+	content, found, err := h.DB.GetArticleContent(article.ID)
+	if err != nil || !found {
+		content, _, err = h.GetArticleContent(article.ID)
+		if err != nil {
+			log.Printf("Prefetch: error getting content of article %d: %v", article.ID, err)
+		}
+		p.used.Add(int64(len(content)))
+	}
+
+	if p.fullText && article.URL != "" && !p.budgetReached() && ctx.Err() == nil {
+		if text, found, _ := h.DB.GetArticleFullText(article.ID); found {
+			content += text
+		} else {
+			feedConfig, _ := h.DB.GetFeedByID(article.FeedID)
+			text, err := h.FetchFullArticleContentWithFeed(article.URL, feedConfig)
+			if err != nil {
+				log.Printf("Prefetch: error fetching full text of article %d: %v", article.ID, err)
+			} else if text != "" {
+				if err := h.DB.SetArticleFullText(article.ID, text); err != nil {
+					log.Printf("Prefetch: error caching full text of article %d: %v", article.ID, err)
+				}
+				p.used.Add(int64(len(text)))
+				content += text
+			}
+		}
+	}
+
+	if p.mediaCache == nil {
+		return
+	}
+	imageURLs := feed.ExtractAllImageURLsFromHTML(content)
+	if article.ImageURL != "" {
+		imageURLs = append([]string{article.ImageURL}, imageURLs...)
+	}
+	base, _ := url.Parse(article.URL)
+	seen := make(map[string]bool)
+	for _, raw := range imageURLs {
+		if ctx.Err() != nil || p.budgetReached() {
+			return
+		}
+		imageURL := resolveImageURL(base, raw)
+		if imageURL == "" || seen[imageURL] || p.mediaCache.Exists(imageURL) {
+			continue
+		}
+		seen[imageURL] = true
+
+		data, _, err := p.mediaCache.Get(imageURL, article.URL)
+		if err != nil {
+			continue
+		}
+		p.used.Add(int64(len(data)))
+		p.images.Add(1)
+	}
+}
+
+// resolveImageURL makes an image URL absolute. It returns an empty string for
+// URLs that cannot be downloaded, such as data URIs.
+func resolveImageURL(base *url.URL, raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
@@ -35,6 +35,25 @@ func (h *Handler) StartBackgroundScheduler(ctx context.Context) {
 		}
 	}()
 
+	// Probe RSSHub instances periodically so failover skips dead instances
+	go h.startRSSHubHealthChecker(ctx)
+
+	// Keep linked OPML subscription lists in sync with their publishers
+	go h.startSubscriptionListSync(ctx)
+
+	// Archive favorites and articles queued for offline reading
+	go h.startArchiver(ctx)
+
+	// Take and rotate database snapshots
+	go h.startBackupScheduler(ctx)
+
+	// Prefetch articles for offline reading after each refresh
+	h.Fetcher.OnRefreshComplete(func() {
+		if enabled, _ := h.DB.GetSetting("offline_prefetch_enabled"); enabled == "true" {
+			h.StartPrefetch(ctx)
+		}
+	})
+
 	// Start the scheduler based on refresh mode
 	refreshMode, _ := h.DB.GetSetting("refresh_mode")
 
@@ -129,6 +148,11 @@ func (h *Handler) startScheduler(ctx context.Context, intelligentMode bool) {
 // In intelligent mode, this calculates intervals per feed
 // In fixed mode, all feeds refresh together at the global interval
 func (h *Handler) triggerGlobalRefresh(ctx context.Context, intelligentMode bool, lastGlobalRefresh *time.Time) {
+	// Nothing is fetched in offline mode; the refresh runs once it is turned off
+	if h.OfflineMode() {
+		return
+	}
+
 	feeds, err := h.DB.GetFeeds()
 	if err != nil {
 		log.Printf("Error getting feeds for global refresh: %v", err)
@@ -205,6 +229,10 @@ func (h *Handler) triggerGlobalRefresh(ctx context.Context, intelligentMode bool
 // scheduleIndividualFeeds schedules feeds with custom intervals (RefreshInterval != 0)
 // These feeds are refreshed independently of the global refresh cycle
 func (h *Handler) scheduleIndividualFeeds(ctx context.Context, intelligentMode bool) {
+	if h.OfflineMode() {
+		return
+	}
+
 	feeds, err := h.DB.GetFeeds()
 	if err != nil {
 		log.Printf("Error getting feeds for individual scheduling: %v", err)
@@ -268,6 +296,142 @@ func (h *Handler) scheduleIndividualFeeds(ctx context.Context, intelligentMode b
 	}
 }
 
+// startRSSHubHealthChecker probes the configured RSSHub instances at the
+// rsshub_health_check_interval (minutes). Probing is skipped while RSSHub is disabled.
+func (h *Handler) startRSSHubHealthChecker(ctx context.Context) {
+	getInterval := func() time.Duration {
+		intervalStr, _ := h.DB.GetSetting("rsshub_health_check_interval")
+		interval := 10
+		if i, err := strconv.Atoi(intervalStr); err == nil && i > 0 {
+			interval = i
+		}
+		return time.Duration(interval) * time.Minute
+	}
+
+	lastCheck := time.Time{}
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if time.Since(lastCheck) < getInterval() {
+				continue
+			}
+			enabled, _ := h.DB.GetSetting("rsshub_enabled")
+			if enabled != "true" {
+				continue
+			}
+
+			lastCheck = time.Now()
+			for _, status := range h.Fetcher.CheckRSSHubHealth() {
+				if !status.Healthy {
+					log.Printf("RSSHub instance %s is unhealthy (%d consecutive failures): %s",
+						status.Endpoint, status.ConsecutiveFailures, status.LastError)
+				}
+			}
+		}
+	}
+}
+
+// startSubscriptionListSync re-fetches linked OPML subscription lists once
+// their refresh interval (minutes) has passed and fetches newly added feeds.
+func (h *Handler) startSubscriptionListSync(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lists, err := h.DB.GetSubscriptionLists()
+			if err != nil {
+				log.Printf("Error loading subscription lists: %v", err)
+				continue
+			}
+			for i := range lists {
+				list := &lists[i]
+				interval := time.Duration(list.RefreshInterval) * time.Minute
+				if list.LastSynced != nil && time.Since(*list.LastSynced) < interval {
+					continue
+				}
+
+				result, err := h.Fetcher.SyncSubscriptionList(ctx, list)
+				if err != nil {
+					log.Printf("Error syncing subscription list %s: %v", list.URL, err)
+					continue
+				}
+				if len(result.Added) > 0 {
+					h.Fetcher.FetchFeedsByIDs(ctx, result.Added)
+				}
+			}
+		}
+	}
+}
+
+// startBackupScheduler takes a database snapshot once backup_interval_hours
+// (hours) have passed since the newest scheduled one, then rotates the
+// snapshots to backup_keep_daily daily and backup_keep_weekly weekly copies.
+func (h *Handler) startBackupScheduler(ctx context.Context) {
+	getInt := func(key string, fallback int) int {
+		value, _ := h.DB.GetSetting(key)
+		if i, err := strconv.Atoi(value); err == nil && i >= 0 {
+			return i
+		}
+		return fallback
+	}
+
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if enabled, _ := h.DB.GetSetting("backup_enabled"); enabled == "true" {
+			interval := time.Duration(getInt("backup_interval_hours", 24)) * time.Hour
+			if interval <= 0 {
+				interval = 24 * time.Hour
+			}
+			if h.lastScheduledSnapshot().Add(interval).Before(time.Now()) {
+				compress, _ := h.DB.GetSetting("backup_compress")
+				if snap, err := h.Snapshots.Create(h.DB, compress == "true", false); err != nil {
+					log.Printf("Error taking database snapshot: %v", err)
+				} else {
+					log.Printf("Took database snapshot %s (%d bytes)", snap.Name, snap.Size)
+				}
+				removed, err := h.Snapshots.Rotate(getInt("backup_keep_daily", 7), getInt("backup_keep_weekly", 4))
+				if err != nil {
+					log.Printf("Error rotating database snapshots: %v", err)
+				} else if len(removed) > 0 {
+					log.Printf("Removed %d old database snapshots", len(removed))
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lastScheduledSnapshot returns when the newest scheduled snapshot was taken,
+// or the zero time when there is none
+func (h *Handler) lastScheduledSnapshot() time.Time {
+	snapshots, err := h.Snapshots.List()
+	if err != nil {
+		return time.Time{}
+	}
+	for _, snap := range snapshots {
+		if !snap.Manual {
+			return snap.CreatedAt
+		}
+	}
+	return time.Time{}
+}
+
 // cleanupMediaCache performs media cache cleanup based on settings
 func (h *Handler) cleanupMediaCache() {
 	cacheDir, err := fileutil.GetMediaCacheDir()
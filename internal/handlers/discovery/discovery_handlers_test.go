@@ -79,8 +79,9 @@ func TestHandleDiscoverBlogs_Success(t *testing.T) {
 	}))
 	defer mainSrv.Close()
 
-	// Add feed pointing to mainSrv /feed
-	feed := &models.Feed{Title: "main", URL: mainSrv.URL + "/feed"}
+	// Add feed pointing to mainSrv /feed; the test servers are on loopback,
+	// which discovery only reaches for feeds allowed on the private network
+	feed := &models.Feed{Title: "main", URL: mainSrv.URL + "/feed", AllowPrivateNetwork: true}
 	feedID, err := h.DB.AddFeed(feed)
 	if err != nil {
 		t.Fatalf("AddFeed error: %v", err)
@@ -0,0 +1,71 @@
+package discovery
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"MrRSS/internal/discovery"
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+)
+
+// maxEngagedArticles limits how many engaged articles are mined for links
+const maxEngagedArticles = 500
+
+// HandleFeedRecommendations suggests feeds linked from articles the user engaged with.
+// @Summary      Recommend feeds from reading
+// @Description  Rank blogs linked from read, favorited and read-later articles by engagement and return those with a feed
+// @Tags         discovery
+// @Accept       json
+// @Produce      json
+// @Param        limit  query     int  false  "Maximum number of recommendations (default 10)"
+// @Success      200  {array}   discovery.Recommendation  "Ranked recommendations with explanations"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /feeds/recommendations [get]
+func HandleFeedRecommendations(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	engaged, err := h.DB.GetEngagedArticles(maxEngagedArticles)
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// Get all existing feed URLs for deduplication
+	subscribedURLs, err := h.DB.GetAllFeedURLs()
+	if err != nil {
+		log.Printf("Error getting subscribed URLs: %v", err)
+		subscribedURLs = make(map[string]bool) // Continue with empty set
+	}
+
+	signals := make([]discovery.ArticleSignal, 0, len(engaged))
+	for _, a := range engaged {
+		signals = append(signals, discovery.ArticleSignal{
+			URL:       a.URL,
+			FeedLink:  a.FeedLink,
+			Content:   a.Content,
+			Favorite:  a.IsFavorite,
+			ReadLater: a.IsReadLater,
+			Read:      a.IsRead,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), core.SingleFeedDiscoveryTimeout)
+	defer cancel()
+
+	recommendations, err := h.DiscoveryService.RecommendFromReading(ctx, signals, subscribedURLs, limit)
+	if err != nil {
+		log.Printf("Error building feed recommendations: %v", err)
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, recommendations)
+}
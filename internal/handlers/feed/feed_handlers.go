@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"time"
 
+	"MrRSS/internal/database"
+	ff "MrRSS/internal/feed"
 	"MrRSS/internal/handlers/core"
 	"MrRSS/internal/handlers/response"
 	"MrRSS/internal/rsshub"
@@ -63,6 +65,8 @@ func HandleAddFeed(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 		ProxyEnabled     bool   `json:"proxy_enabled"`
 		RefreshInterval  int    `json:"refresh_interval"`
 		IsImageMode      bool   `json:"is_image_mode"`
+		// Opt-in to fetching this feed's media and pages from private network addresses
+		AllowPrivateNetwork bool `json:"allow_private_network"`
 		// XPath fields
 		Type                string `json:"type"`
 		XPathItem           string `json:"xpath_item"`
@@ -84,6 +88,8 @@ func HandleAddFeed(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 		EmailUsername   string `json:"email_username"`
 		EmailPassword   string `json:"email_password"`
 		EmailFolder     string `json:"email_folder"`
+		// Sitemap fields
+		SitemapPathPattern string `json:"sitemap_path_pattern"`
 		// Tags
 		Tags []int64 `json:"tags"`
 	}
@@ -94,6 +100,9 @@ func HandleAddFeed(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 
 	// Normalize the URL to ensure it has a protocol
 	req.URL = urlutil.NormalizeFeedURL(req.URL)
+	if req.Type == "sitemap" {
+		req.URL = ff.SitemapFeedURL(req.URL, req.SitemapPathPattern)
+	}
 
 	// Determine the feed URL to check for duplicates
 	feedURL := req.URL
@@ -127,6 +136,9 @@ func HandleAddFeed(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 	} else if req.XPathItem != "" {
 		// Add feed using XPath
 		feedID, err = h.Fetcher.AddXPathSubscription(req.URL, req.Category, req.Title, req.Type, req.XPathItem, req.XPathItemTitle, req.XPathItemContent, req.XPathItemUri, req.XPathItemAuthor, req.XPathItemTimestamp, req.XPathItemTimeFormat, req.XPathItemThumbnail, req.XPathItemCategories, req.XPathItemUid)
+	} else if req.Type == "sitemap" {
+		// Add feed generated from the site's sitemap
+		feedID, err = h.Fetcher.AddSitemapSubscription(req.URL, req.SitemapPathPattern, req.Category, req.Title)
 	} else if req.Type == "email" {
 		// Add feed as email newsletter subscription
 		feedID, err = h.Fetcher.AddEmailSubscription(req.EmailAddress, req.EmailIMAPServer, req.EmailUsername, req.EmailPassword, req.Category, req.Title, req.EmailFolder, req.EmailIMAPPort)
@@ -156,6 +168,10 @@ func HandleAddFeed(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 		response.Error(w, err, http.StatusInternalServerError)
 		return
 	}
+	if err := h.DB.UpdateFeedWithOptions(feed.ID, database.FeedUpdateOptions{AllowPrivateNetwork: &req.AllowPrivateNetwork}); err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
 
 	// Set tags for the feed
 	if len(req.Tags) > 0 {
@@ -191,7 +207,8 @@ func HandleAddFeed(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 func HandleDeleteFeed(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	id, _ := strconv.ParseInt(idStr, 10, 64)
-	if err := h.DB.DeleteFeed(id); err != nil {
+	// Keep subscription lists from adding the feed again
+	if err := h.DB.DeleteFeedAndExclude(id); err != nil {
 		response.Error(w, err, http.StatusInternalServerError)
 		return
 	}
@@ -222,6 +239,8 @@ func HandleUpdateFeed(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 		ProxyEnabled     bool   `json:"proxy_enabled"`
 		RefreshInterval  int    `json:"refresh_interval"`
 		IsImageMode      bool   `json:"is_image_mode"`
+		// Opt-in to fetching this feed's media and pages from private network addresses; left unchanged when omitted
+		AllowPrivateNetwork *bool `json:"allow_private_network"`
 		// XPath fields
 		Type                string `json:"type"`
 		XPathItem           string `json:"xpath_item"`
@@ -357,6 +376,12 @@ func HandleUpdateFeed(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 		response.Error(w, err, http.StatusInternalServerError)
 		return
 	}
+	if req.AllowPrivateNetwork != nil {
+		if err := h.DB.UpdateFeedWithOptions(req.ID, database.FeedUpdateOptions{AllowPrivateNetwork: req.AllowPrivateNetwork}); err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
 
 	// Update tags for the feed
 	if req.Tags != nil {
@@ -450,3 +475,36 @@ func HandleReorderFeed(h *core.Handler, w http.ResponseWriter, r *http.Request)
 
 	response.JSON(w, map[string]string{"status": "ok"})
 }
+
+// HandleFeedFetchHistory returns the recent fetch attempts of a feed.
+// @Summary      Get feed fetch history
+// @Description  List recent fetch attempts for a feed, including duration, error and the RSSHub instance that served it
+// @Tags         feeds
+// @Produce      json
+// @Param        id     query     int64  true   "Feed ID"
+// @Param        limit  query     int    false  "Maximum number of records (default 50)"
+// @Success      200  {array}   models.FeedFetchRecord  "Fetch history, newest first"
+// @Failure      400  {object}  map[string]string  "Bad request (invalid feed ID)"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /feeds/fetch-history [get]
+func HandleFeedFetchHistory(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	history, err := h.DB.GetFeedFetchHistory(id, limit)
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, history)
+}
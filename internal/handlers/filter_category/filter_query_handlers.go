@@ -0,0 +1,92 @@
+package filter_category
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"MrRSS/internal/database"
+	"MrRSS/internal/filterquery"
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+)
+
+// HandleParseFilterQuery parses a search query into filter conditions.
+// @Summary      Parse a filter query
+// @Description  Convert search query text such as `feed:"Hacker News" is:unread -word` into filter conditions. Syntax errors include the character position.
+// @Tags         filters
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "Query (query)"
+// @Success      200  {object}  map[string]interface{}  "Conditions (conditions)"
+// @Failure      400  {object}  map[string]string  "Syntax error"
+// @Router       /filter-query/parse [post]
+func HandleParseFilterQuery(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	conditions, err := filterquery.Parse(req.Query)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	if conditions == nil {
+		conditions = []database.FilterCondition{}
+	}
+	response.JSON(w, map[string]interface{}{"conditions": conditions})
+}
+
+// HandleFormatFilterQuery renders filter conditions as search query text.
+// @Summary      Format a filter query
+// @Description  Convert filter conditions into search query text, the inverse of parsing
+// @Tags         filters
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "Conditions (conditions)"
+// @Success      200  {object}  map[string]string  "Query (query)"
+// @Failure      400  {object}  map[string]string  "Conditions cannot be written as a query"
+// @Router       /filter-query/format [post]
+func HandleFormatFilterQuery(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Conditions []database.FilterCondition `json:"conditions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	query, err := filterquery.Format(req.Conditions)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	response.JSON(w, map[string]string{"query": query})
+}
+
+// queryConditionsJSON parses a search query into the JSON conditions stored
+// by saved filters
+func queryConditionsJSON(query string) (string, error) {
+	conditions, err := filterquery.Parse(query)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(conditions)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
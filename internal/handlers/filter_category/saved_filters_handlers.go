@@ -19,7 +19,7 @@ import (
 // @Success      200  {array}   models.SavedFilter  "List of saved filters"
 // @Router       /saved-filters [get]
 // @Summary      Create a new saved filter
-// @Description  Create a new article filter with custom conditions
+// @Description  Create a new article filter with custom conditions, given as JSON or as a search query
 // @Tags         filters
 // @Accept       json
 // @Produce      json
@@ -45,11 +45,20 @@ func HandleSavedFilters(h *core.Handler, w http.ResponseWriter, r *http.Request)
 		var req struct {
 			Name       string `json:"name"`
 			Conditions string `json:"conditions"`
+			Query      string `json:"query"` // Search query saved as conditions instead
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			response.Error(w, err, http.StatusBadRequest)
 			return
 		}
+		if req.Conditions == "" && req.Query != "" {
+			conditions, err := queryConditionsJSON(req.Query)
+			if err != nil {
+				response.Error(w, err, http.StatusBadRequest)
+				return
+			}
+			req.Conditions = conditions
+		}
 
 		// Validate input
 		if req.Name == "" {
@@ -99,7 +108,7 @@ func HandleSavedFilters(h *core.Handler, w http.ResponseWriter, r *http.Request)
 
 // HandleSavedFilter handles operations on a specific saved filter
 // @Summary      Update a saved filter
-// @Description  Update an existing saved filter's name or conditions
+// @Description  Update an existing saved filter's name or conditions, given as JSON or as a search query
 // @Tags         filters
 // @Accept       json
 // @Produce      json
@@ -130,11 +139,20 @@ func HandleSavedFilter(h *core.Handler, w http.ResponseWriter, r *http.Request)
 		var req struct {
 			Name       string `json:"name"`
 			Conditions string `json:"conditions"`
+			Query      string `json:"query"` // Search query saved as conditions instead
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			response.Error(w, err, http.StatusBadRequest)
 			return
 		}
+		if req.Conditions == "" && req.Query != "" {
+			conditions, err := queryConditionsJSON(req.Query)
+			if err != nil {
+				response.Error(w, err, http.StatusBadRequest)
+				return
+			}
+			req.Conditions = conditions
+		}
 
 		filter := &models.SavedFilter{
 			ID:         id,
@@ -1,12 +1,17 @@
 package media
 
 import (
+	"bytes"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"MrRSS/internal/database"
 	corepkg "MrRSS/internal/handlers/core"
+	"MrRSS/internal/models"
 )
 
 func setupHandler(t *testing.T) *corepkg.Handler {
@@ -50,6 +55,83 @@ func TestHandleMediaProxy_CacheDisabled(t *testing.T) {
 	}
 }
 
+func TestHandleMediaProxy_RangeRequest(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("APPDATA", tmp)
+	t.Setenv("HOME", tmp)
+	t.Setenv("XDG_DATA_HOME", tmp)
+
+	data := bytes.Repeat([]byte("0123456789"), 300000)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		http.ServeContent(w, r, "", time.Unix(0, 0), bytes.NewReader(data))
+	}))
+	defer upstream.Close()
+
+	for _, mode := range []string{"cache", "fallback"} {
+		h := setupHandler(t)
+		_ = h.DB.SetSetting("media_cache_enabled", boolString(mode == "cache"))
+		_ = h.DB.SetSetting("media_proxy_fallback", boolString(mode == "fallback"))
+		// The upstream is on loopback, reachable only for allowed feeds
+		if _, err := h.DB.AddFeed(&models.Feed{Title: "Local", URL: upstream.URL + "/feed.xml", AllowPrivateNetwork: true}); err != nil {
+			t.Fatalf("AddFeed: %v", err)
+		}
+
+		mediaURL := upstream.URL + "/" + mode + ".mp3"
+		req := httptest.NewRequest(http.MethodGet, "/media/proxy?url="+url.QueryEscape(mediaURL), nil)
+		req.Header.Set("Range", "bytes=2000000-2000009")
+		rr := httptest.NewRecorder()
+
+		HandleMediaProxy(h, rr, req)
+
+		if rr.Code != http.StatusPartialContent {
+			t.Fatalf("%s: expected %d got %d: %s", mode, http.StatusPartialContent, rr.Code, rr.Body.String())
+		}
+		if got := rr.Header().Get("Content-Range"); got != "bytes 2000000-2000009/3000000" {
+			t.Fatalf("%s: unexpected Content-Range %q", mode, got)
+		}
+		if got := rr.Header().Get("Accept-Ranges"); got != "bytes" {
+			t.Fatalf("%s: unexpected Accept-Ranges %q", mode, got)
+		}
+		if !bytes.Equal(rr.Body.Bytes(), data[2000000:2000010]) {
+			t.Fatalf("%s: unexpected body %q", mode, rr.Body.String())
+		}
+	}
+}
+
+func TestHandleMediaProxy_BlocksPrivateAddresses(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png"))
+	}))
+	defer upstream.Close()
+
+	h := setupHandler(t)
+	_ = h.DB.SetSetting("media_cache_enabled", "false")
+	_ = h.DB.SetSetting("media_proxy_fallback", "true")
+
+	for _, target := range []string{upstream.URL + "/a.png", "http://169.254.169.254/latest/meta-data/", "http://localhost/admin"} {
+		req := httptest.NewRequest(http.MethodGet, "/media/proxy?url="+url.QueryEscape(target), nil)
+		rr := httptest.NewRecorder()
+		HandleMediaProxy(h, rr, req)
+		if rr.Code != http.StatusForbidden {
+			t.Fatalf("%s: expected %d got %d", target, http.StatusForbidden, rr.Code)
+		}
+	}
+	if hits.Load() != 0 {
+		t.Fatalf("expected no requests to reach the upstream, got %d", hits.Load())
+	}
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
 func TestHandleMediaProxy_MissingURL(t *testing.T) {
 	h := setupHandler(t)
 	// enable cache setting
@@ -0,0 +1,805 @@
+package media
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Content-Security-Policy values for proxied webpages. Rewritten pages load
+// every resource through the proxy, so both only allow the proxy's own
+// origin. Pages keep their scripts in normal mode, so they are sandboxed
+// into an opaque origin and cannot read the app's API. Reader-safe mode also
+// refuses scripts, frames and form submissions.
+const (
+	webpageCSP    = "default-src 'self'; script-src 'self' 'unsafe-inline' 'unsafe-eval'; style-src 'self' 'unsafe-inline'; img-src 'self' data: blob:; media-src 'self' data: blob:; font-src 'self' data:; connect-src 'self'; frame-src 'self'; worker-src 'self' blob:; object-src 'none'; base-uri 'none'; form-action 'self'; frame-ancestors 'self'; sandbox allow-scripts allow-popups allow-forms"
+	readerSafeCSP = "default-src 'none'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; media-src 'self' data:; font-src 'self' data:; base-uri 'none'; form-action 'none'; frame-ancestors 'self'"
+)
+
+// manifestOverride stops the browser from requesting the page's web app manifest
+const manifestOverride = `<meta name="manifest" content=""><link rel="manifest" href="about:blank">`
+
+// interceptorScript is injected at the top of proxied pages in normal mode.
+// It routes fetch and XHR calls through the resource proxy, stops the page
+// from rewriting the frame URL and opens links in the system browser. The
+// %s verb receives the page URL as a JavaScript string literal.
+const interceptorScript = `<script>
+	// Use immediately-invoked function with strict error suppression
+	(function() {
+		'use strict';
+		const ORIGINAL_BASE_URL = %s;
+		const PROXY_ORIGIN = window.location.origin;
+
+		// DEBUG: Log that interceptor is loaded
+		console.log('[Proxy] Interceptor loaded for:', ORIGINAL_BASE_URL);
+
+		// Override History API BEFORE anything else with try-catch to suppress ALL errors
+		try {
+			const originalPushState = History.prototype.pushState;
+			History.prototype.pushState = function(state, title, url) {
+				try {
+					if (url && typeof url === 'string' && (url.indexOf('http://') === 0 || url.indexOf('https://') === 0)) {
+						// Silently block - don't even log to avoid console spam
+						return undefined;
+					}
+				} catch(e) { /* Suppress all errors */ }
+				try {
+					return originalPushState.call(this, state, title, url);
+				} catch(e) { /* Suppress errors from original call */ }
+			};
+		} catch(e) { /* Suppress errors during override */ }
+
+		try {
+			const originalReplaceState = History.prototype.replaceState;
+			History.prototype.replaceState = function(state, title, url) {
+				try {
+					if (url && typeof url === 'string' && (url.indexOf('http://') === 0 || url.indexOf('https://') === 0)) {
+						// Silently block
+						return undefined;
+					}
+				} catch(e) { /* Suppress all errors */ }
+				try {
+					return originalReplaceState.call(this, state, title, url);
+				} catch(e) { /* Suppress errors from original call */ }
+			};
+		} catch(e) { /* Suppress errors during override */ }
+
+		// Also override on window.history for direct access
+		try {
+			if (window.history && window.history.pushState) {
+				const originalPushState = window.history.pushState;
+				window.history.pushState = function(state, title, url) {
+					try {
+						if (url && typeof url === 'string' && (url.indexOf('http://') === 0 || url.indexOf('https://') === 0)) {
+							return undefined;
+						}
+					} catch(e) { }
+					try {
+						return originalPushState.call(this, state, title, url);
+					} catch(e) { }
+				};
+			}
+		} catch(e) { }
+
+		try {
+			if (window.history && window.history.replaceState) {
+				const originalReplaceState = window.history.replaceState;
+				window.history.replaceState = function(state, title, url) {
+					try {
+						if (url && typeof url === 'string' && (url.indexOf('http://') === 0 || url.indexOf('https://') === 0)) {
+							return undefined;
+						}
+					} catch(e) { }
+					try {
+						return originalReplaceState.call(this, state, title, url);
+					} catch(e) { }
+				};
+			}
+		} catch(e) { }
+
+		// Helper function to resolve relative URLs
+		function resolveRelativeURL(url) {
+			try {
+				// If already absolute, return as-is
+				if (url.indexOf('http://') === 0 || url.indexOf('https://') === 0) {
+					return url;
+				}
+				// Protocol-relative URL
+				if (url.indexOf('//') === 0) {
+					return 'https:' + url;
+				}
+				// Relative URL - resolve against base URL
+				const base = new URL(ORIGINAL_BASE_URL);
+				return new URL(url, base).href;
+			} catch(e) {
+				return url;
+			}
+		}
+
+		// List of domains to skip proxying (analytics, ads, tracking)
+		const SKIP_PROXY_DOMAINS = [
+			'google-analytics.com',
+			'googletagmanager.com',
+			'googlesyndication.com',
+			'googleadservices.com',
+			'doubleclick.net',
+			'facebook.com/tr',
+			'connect.facebook.net',
+			'analytics.twitter.com',
+			't.co',
+			'adform.net',
+			'adnxs.com',
+			'rubiconproject.com',
+			'pubmatic.com',
+			'criteo.com',
+			'crwdcntrl.net',
+			'cookielaw.org',
+			'onetrust.com',
+			'clarity.ms',
+			'bing.com'
+		];
+
+		// Helper function to check if URL should be skipped
+		function shouldSkipProxy(url) {
+			try {
+				const urlObj = new URL(url);
+				const hostname = urlObj.hostname.toLowerCase();
+				return SKIP_PROXY_DOMAINS.some(domain =>
+					hostname === domain || hostname.endsWith('.' + domain)
+				);
+			} catch(e) {
+				return false;
+			}
+		}
+
+		// Intercept fetch() with error suppression
+		try {
+			const originalFetch = window.fetch;
+			window.fetch = function(input, ...args) {
+				let modifiedInput = input;
+				try {
+					let url = input;
+					// Handle Request objects
+					if (input && typeof input === 'object' && input.url) {
+						url = input.url;
+					}
+					if (typeof url === 'string') {
+						// Resolve relative URLs to absolute
+						const absoluteUrl = resolveRelativeURL(url);
+
+						// Only intercept external URLs (not our own proxy)
+						if (absoluteUrl.indexOf(PROXY_ORIGIN) !== 0) {
+							// Skip known analytics/ad/tracking domains
+							if (shouldSkipProxy(absoluteUrl)) {
+								// Don't intercept - let it fail naturally
+								return originalFetch.call(this, input, ...args);
+							}
+
+							// Reduce noise - only log important requests
+							if (!absoluteUrl.includes('/analytics') && !absoluteUrl.includes('/collect') && !absoluteUrl.includes('/rum')) {
+								console.log('[Proxy] Intercepting fetch:', url, '->', absoluteUrl);
+							}
+							try {
+								// Use base64 encoding to avoid URL encoding issues
+								const proxyUrl = PROXY_ORIGIN + '/api/webpage/resource?url_b64=' + btoa(absoluteUrl) + '&referer_b64=' + btoa(ORIGINAL_BASE_URL);
+								if (input && typeof input === 'object' && input.url) {
+									modifiedInput = new Request(proxyUrl, input);
+								} else {
+									modifiedInput = proxyUrl;
+								}
+							} catch(e) { }
+						}
+					}
+				} catch(e) { }
+				try {
+					return originalFetch.call(this, modifiedInput, ...args);
+				} catch(e) {
+					return Promise.reject(e);
+				}
+			};
+			console.log('[Proxy] Fetch interceptor installed');
+		} catch(e) { }
+
+		// Intercept XMLHttpRequest with error suppression
+		try {
+			const originalXHROpen = XMLHttpRequest.prototype.open;
+			XMLHttpRequest.prototype.open = function(method, url, ...args) {
+				let modifiedUrl = url;
+				try {
+					if (typeof url === 'string') {
+						// Resolve relative URLs to absolute
+						const absoluteUrl = resolveRelativeURL(url);
+
+						// Only intercept external URLs (not our own proxy)
+						if (absoluteUrl.indexOf(PROXY_ORIGIN) !== 0) {
+							// Skip known analytics/ad/tracking domains
+							if (shouldSkipProxy(absoluteUrl)) {
+								// Don't intercept - let it fail naturally
+								return originalXHROpen.call(this, method, url, ...args);
+							}
+
+							// Reduce noise - only log important requests
+							if (!absoluteUrl.includes('/analytics') && !absoluteUrl.includes('/collect') && !absoluteUrl.includes('/rum')) {
+								console.log('[Proxy] Intercepting XHR:', method, url, '->', absoluteUrl);
+							}
+							try {
+								// Use base64 encoding to avoid URL encoding issues
+								modifiedUrl = PROXY_ORIGIN + '/api/webpage/resource?url_b64=' + btoa(absoluteUrl) + '&referer_b64=' + btoa(ORIGINAL_BASE_URL);
+							} catch(e) { }
+						}
+					}
+				} catch(e) { }
+				try {
+					return originalXHROpen.call(this, method, modifiedUrl, ...args);
+				} catch(e) {
+					throw e;
+				}
+			};
+			console.log('[Proxy] XHR interceptor installed');
+		} catch(e) { }
+
+		// Intercept all link clicks to open in external browser
+		try {
+			document.addEventListener('click', function(e) {
+				try {
+					// Check if clicked element or its parents is a link with our marker
+					let target = e.target;
+					while (target && target !== document) {
+						if (target.tagName === 'A' && target.hasAttribute('data-proxy-link')) {
+							// This is our proxied link
+							const href = target.getAttribute('href');
+							if (href && href.startsWith('BROWSER-OPEN:')) {
+								e.preventDefault();
+								e.stopPropagation();
+								e.stopImmediatePropagation();
+
+								const urlToOpen = href.substring('BROWSER-OPEN:'.length);
+								console.log('[Proxy] Opening link in browser:', urlToOpen);
+
+								// Call our backend to open the URL
+								fetch(PROXY_ORIGIN + '/api/browser/open?url=' + encodeURIComponent(urlToOpen), {
+									method: 'GET',
+									mode: 'cors'
+								}).catch(err => {
+									console.error('[Proxy] Failed to open URL:', err);
+								});
+
+								return false;
+							}
+						}
+						target = target.parentElement;
+					}
+				} catch(err) {
+					console.error('[Proxy] Error handling click:', err);
+				}
+			}, true); // Use capture phase
+			console.log('[Proxy] Link click interceptor installed');
+		} catch(e) {
+			console.error('[Proxy] Failed to install link interceptor:', e);
+		}
+	})();
+	</script>`
+
+// urlAttrs lists the attributes that load a resource, by tag name
+var urlAttrs = map[string][]string{
+	"audio":  {"src"},
+	"body":   {"background"},
+	"button": {"formaction"},
+	"embed":  {"src"},
+	"form":   {"action"},
+	"frame":  {"src"},
+	"iframe": {"src"},
+	"image":  {"href", "xlink:href"},
+	"img":    {"src"},
+	"input":  {"src", "formaction"},
+	"link":   {"href"},
+	"object": {"data"},
+	"script": {"src"},
+	"source": {"src"},
+	"table":  {"background"},
+	"td":     {"background"},
+	"th":     {"background"},
+	"track":  {"src"},
+	"use":    {"href", "xlink:href"},
+	"video":  {"src", "poster"},
+}
+
+// scriptURLAttrs are the attributes where a javascript: URL runs script
+var scriptURLAttrs = []string{"href", "src", "action", "formaction", "xlink:href", "data"}
+
+// Attributes holding the URLs of lazy-loaded images, in order of preference
+var (
+	lazySrcAttrs    = []string{"data-src", "data-original", "data-lazy-src"}
+	lazySrcsetAttrs = []string{"data-srcset", "data-lazy-srcset"}
+)
+
+const asciiSpace = " \t\n\f\r"
+
+// pageRewriter rewrites a proxied webpage token by token. Tags it does not
+// change are copied byte for byte.
+type pageRewriter struct {
+	res        resourceProxy
+	pageURL    string
+	readerSafe bool
+
+	out        bytes.Buffer
+	raw        []byte    // copy of the current tag, reused between tags
+	skip       atom.Atom // element whose contents are being dropped
+	inStyle    bool
+	inNoscript bool
+	hasBase    bool
+	injected   bool
+}
+
+// rewriteWebpage rewrites an HTML page fetched from pageURL so its resources
+// load through the resource proxy and its links open in the system browser.
+// In reader-safe mode scripts, event handlers and javascript: URLs are
+// removed and noscript fallbacks are shown instead.
+func rewriteWebpage(body []byte, pageURL string, readerSafe bool) []byte {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		log.Printf("Failed to parse base URL: %v", err)
+		return body
+	}
+
+	r := &pageRewriter{
+		res:        newResourceProxy(base, pageURL),
+		pageURL:    pageURL,
+		readerSafe: readerSafe,
+	}
+	r.out.Grow(len(body) + len(body)/4)
+	r.rewrite(body)
+	return r.out.Bytes()
+}
+
+// rewrite tokenizes body and writes the rewritten markup to r.out. It is
+// called again for the contents of noscript elements in reader-safe mode.
+func (r *pageRewriter) rewrite(body []byte) {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return
+		case html.StartTagToken, html.SelfClosingTagToken:
+			// Token lower-cases and unescapes the tokenizer's buffer in
+			// place, so keep the original bytes for tags left unchanged
+			r.raw = append(r.raw[:0], z.Raw()...)
+			tok := z.Token()
+			r.startTag(&tok)
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch a := atom.Lookup(name); {
+			case r.skip != 0:
+				if a == r.skip {
+					r.skip = 0
+				}
+			case a == atom.Noscript && r.inNoscript:
+				r.inNoscript = false
+			default:
+				if a == atom.Style {
+					r.inStyle = false
+				}
+				r.out.Write(z.Raw())
+			}
+		case html.TextToken:
+			switch {
+			case r.skip != 0:
+			case r.inNoscript:
+				r.rewrite(z.Raw())
+			case r.inStyle:
+				r.out.WriteString(r.res.rewriteCSS(string(z.Raw())))
+			default:
+				r.out.Write(z.Raw())
+			}
+		default:
+			if r.skip == 0 {
+				r.out.Write(z.Raw())
+			}
+		}
+	}
+}
+
+// startTag writes a start tag, rewriting its URLs or dropping it
+func (r *pageRewriter) startTag(tok *html.Token) {
+	if r.skip != 0 {
+		return
+	}
+
+	switch tok.DataAtom {
+	case atom.Script:
+		if r.readerSafe {
+			// Script contents are raw text, so this also covers <script/>
+			r.skip = atom.Script
+			return
+		}
+	case atom.Noscript:
+		if r.readerSafe {
+			r.inNoscript = true
+			return
+		}
+	case atom.Base:
+		// Rewritten URLs are absolute or point at the proxy, so the tag
+		// itself is dropped once it has been used for resolving
+		if href, ok := getAttr(tok, "href"); ok && !r.hasBase {
+			if u, err := r.res.base.Parse(strings.TrimSpace(href)); err == nil {
+				r.res.base = u
+			}
+			r.hasBase = true
+		}
+		return
+	case atom.Meta:
+		// The page's own policy would refuse the proxy, and a refresh
+		// would navigate away from it
+		switch equiv, _ := getAttr(tok, "http-equiv"); strings.ToLower(strings.TrimSpace(equiv)) {
+		case "content-security-policy":
+			return
+		case "refresh":
+			if r.readerSafe {
+				return
+			}
+		}
+	case atom.Style:
+		r.inStyle = tok.Type == html.StartTagToken
+	}
+
+	// Inject ahead of the page's own tags, but after the charset declaration,
+	// which has to stay within the first kilobyte of the page
+	if !r.injected && tok.DataAtom != atom.Html && tok.DataAtom != atom.Head && !isCharsetMeta(tok) {
+		r.inject()
+	}
+
+	if r.rewriteAttrs(tok) {
+		r.out.WriteString(tok.String())
+	} else {
+		r.out.Write(r.raw)
+	}
+}
+
+func isCharsetMeta(tok *html.Token) bool {
+	if tok.DataAtom != atom.Meta {
+		return false
+	}
+	_, ok := getAttr(tok, "charset")
+	equiv, _ := getAttr(tok, "http-equiv")
+	return ok || strings.EqualFold(strings.TrimSpace(equiv), "content-type")
+}
+
+// inject writes the markup added at the top of every proxied page
+func (r *pageRewriter) inject() {
+	r.injected = true
+	if !r.readerSafe {
+		pageURL, _ := json.Marshal(r.pageURL)
+		fmt.Fprintf(&r.out, interceptorScript, pageURL)
+	}
+	r.out.WriteString(manifestOverride)
+}
+
+// rewriteAttrs rewrites the URL-bearing attributes of tok and reports
+// whether anything changed
+func (r *pageRewriter) rewriteAttrs(tok *html.Token) bool {
+	changed := false
+	if tok.DataAtom == atom.Img || tok.DataAtom == atom.Source {
+		changed = promoteLazyAttrs(tok)
+	}
+
+	isLink := tok.DataAtom == atom.A || tok.DataAtom == atom.Area
+	linked := false
+	kept := tok.Attr[:0]
+	for _, a := range tok.Attr {
+		val := a.Val
+		switch {
+		case r.readerSafe && (strings.HasPrefix(a.Key, "on") || a.Key == "srcdoc"):
+			changed = true
+			continue
+		case r.readerSafe && slices.Contains(scriptURLAttrs, a.Key) && isJavaScriptURL(val):
+			changed = true
+			continue
+		case a.Key == "style":
+			val = r.res.rewriteCSS(val)
+		case a.Key == "srcset" || a.Key == "imagesrcset":
+			val = r.res.rewriteSrcset(val)
+		case isLink && a.Key == "href":
+			if abs, ok := resolveReference(val, r.res.base); ok {
+				linked = true
+				val = abs
+				if !r.readerSafe {
+					val = "BROWSER-OPEN:" + abs
+				}
+			}
+		case slices.Contains(urlAttrs[tok.Data], a.Key):
+			val = r.res.url(val)
+		}
+		if val != a.Val {
+			a.Val = val
+			changed = true
+		}
+		kept = append(kept, a)
+	}
+	tok.Attr = kept
+
+	if linked {
+		// Normal mode links are opened by the injected script; without it
+		// they open in a new window
+		if r.readerSafe {
+			setAttr(tok, "target", "_blank")
+			setAttr(tok, "rel", "noopener noreferrer")
+		} else {
+			if _, ok := getAttr(tok, "target"); !ok {
+				setAttr(tok, "target", "_self")
+			}
+			setAttr(tok, "data-proxy-link", "true")
+		}
+	}
+	return changed
+}
+
+// promoteLazyAttrs moves lazy-loading URLs into src and srcset, so images
+// show without the page's lazy-loading scripts
+func promoteLazyAttrs(tok *html.Token) bool {
+	changed := false
+	for _, lazy := range []struct {
+		target string
+		from   []string
+	}{{"src", lazySrcAttrs}, {"srcset", lazySrcsetAttrs}} {
+		for _, key := range lazy.from {
+			if v, ok := getAttr(tok, key); ok && strings.TrimSpace(v) != "" {
+				setAttr(tok, lazy.target, v)
+				tok.Attr = slices.DeleteFunc(tok.Attr, func(a html.Attribute) bool { return a.Key == key })
+				changed = true
+				break
+			}
+		}
+	}
+
+	// Lazy-loading libraries hide images until their classes change
+	if class, ok := getAttr(tok, "class"); changed && ok {
+		classes := slices.DeleteFunc(strings.Fields(class), func(c string) bool {
+			return strings.HasPrefix(c, "lazy")
+		})
+		setAttr(tok, "class", strings.Join(classes, " "))
+	}
+	return changed
+}
+
+// resourceProxy points resource URLs at the webpage resource proxy
+type resourceProxy struct {
+	base    *url.URL // resolves relative references
+	referer string   // encoded referer parameter for the proxy
+}
+
+func newResourceProxy(base *url.URL, referer string) resourceProxy {
+	return resourceProxy{base: base, referer: encodeProxyParam(referer)}
+}
+
+// url returns the proxy URL that loads ref, or ref itself when it is not an
+// HTTP(S) resource
+func (p resourceProxy) url(ref string) string {
+	abs, ok := resolveReference(ref, p.base)
+	if !ok {
+		return ref
+	}
+	return "/api/webpage/resource?url_b64=" + encodeProxyParam(abs) + "&referer_b64=" + p.referer
+}
+
+// rewriteSrcset proxies each candidate URL of a srcset attribute
+func (p resourceProxy) rewriteSrcset(srcset string) string {
+	var b strings.Builder
+	for s := srcset; ; {
+		s = strings.TrimLeft(s, asciiSpace+",")
+		if s == "" {
+			break
+		}
+		end := strings.IndexAny(s, asciiSpace)
+		if end < 0 {
+			end = len(s)
+		}
+		ref, descriptor := s[:end], ""
+		s = s[end:]
+		// A comma right after the URL ends a candidate without descriptors
+		if trimmed := strings.TrimRight(ref, ","); trimmed != ref {
+			ref = trimmed
+		} else {
+			end = strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			descriptor = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+
+		if b.Len() > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(p.url(ref))
+		if descriptor != "" {
+			b.WriteByte(' ')
+			b.WriteString(descriptor)
+		}
+	}
+	return b.String()
+}
+
+// rewriteCSS proxies the URLs referenced by a stylesheet: url() values,
+// @import strings and the bare strings of image-set(). It scans the text
+// once, skipping comments and unrelated strings.
+func (p resourceProxy) rewriteCSS(css string) string {
+	var b strings.Builder
+	last := 0
+	replace := func(start, end int, ref, format string) {
+		if proxied := p.url(ref); proxied != ref {
+			b.WriteString(css[last:start])
+			fmt.Fprintf(&b, format, proxied)
+			last = end
+		}
+	}
+
+	depth, imageSetDepth := 0, 0
+	for i := 0; i < len(css); {
+		c := css[i]
+		switch {
+		case c == '/' && strings.HasPrefix(css[i:], "/*"):
+			if end := strings.Index(css[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(css)
+			}
+		case c == '"' || c == '\'':
+			end := cssStringEnd(css, i)
+			if imageSetDepth > 0 && depth == imageSetDepth && end-i >= 2 && css[end-1] == c {
+				replace(i, end, css[i+1:end-1], `"%s"`)
+			}
+			i = end
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			if depth == imageSetDepth {
+				imageSetDepth = 0
+			}
+			depth = max(depth-1, 0)
+			i++
+		case (c|0x20) == 'u' && hasPrefixFold(css[i:], "url(") && (i == 0 || !isCSSNameChar(css[i-1])):
+			ref, end, ok := parseCSSURL(css, i+4)
+			if !ok {
+				i += 4
+				continue
+			}
+			replace(i, end, ref, `url("%s")`)
+			i = end
+		case c == '@' && hasPrefixFold(css[i:], "@import"):
+			i = skipCSSSpace(css, i+len("@import"))
+			if i < len(css) && (css[i] == '"' || css[i] == '\'') {
+				end := cssStringEnd(css, i)
+				if end-i >= 2 && css[end-1] == css[i] {
+					replace(i, end, css[i+1:end-1], `url("%s")`)
+				}
+				i = end
+			}
+		case (c|0x20) == 'i' && hasPrefixFold(css[i:], "image-set(") && (i == 0 || css[i-1] == '-' || !isCSSNameChar(css[i-1])):
+			// The opening parenthesis is counted on the next iteration
+			imageSetDepth = depth + 1
+			i += len("image-set")
+		default:
+			i++
+		}
+	}
+
+	if last == 0 {
+		return css
+	}
+	b.WriteString(css[last:])
+	return b.String()
+}
+
+// parseCSSURL parses the argument of a url() token starting at i, just after
+// the opening parenthesis, and returns the index past the closing one
+func parseCSSURL(css string, i int) (ref string, end int, ok bool) {
+	i = skipCSSSpace(css, i)
+	if i < len(css) && (css[i] == '"' || css[i] == '\'') {
+		end = cssStringEnd(css, i)
+		if end-i < 2 || css[end-1] != css[i] {
+			return "", end, false
+		}
+		ref = css[i+1 : end-1]
+		i = skipCSSSpace(css, end)
+	} else {
+		closing := strings.IndexByte(css[i:], ')')
+		if closing < 0 {
+			return "", len(css), false
+		}
+		ref = strings.TrimSpace(css[i : i+closing])
+		i += closing
+	}
+	if i >= len(css) || css[i] != ')' {
+		return "", i, false
+	}
+	return ref, i + 1, true
+}
+
+// cssStringEnd returns the index just past the CSS string starting at start
+func cssStringEnd(css string, start int) int {
+	quote := css[start]
+	for i := start + 1; i < len(css); i++ {
+		switch css[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		case '\n':
+			// Unterminated string
+			return i
+		}
+	}
+	return len(css)
+}
+
+func skipCSSSpace(css string, i int) int {
+	for i < len(css) && strings.IndexByte(asciiSpace, css[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+func isCSSNameChar(c byte) bool {
+	return c == '-' || c == '_' || c >= 0x80 ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// resolveReference resolves ref against base and reports whether the result
+// is an HTTP(S) URL that should be proxied
+func resolveReference(ref string, base *url.URL) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || ref[0] == '#' ||
+		strings.HasPrefix(ref, "/api/webpage/") || strings.HasPrefix(ref, "/api/media/") {
+		return "", false
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// isJavaScriptURL reports whether an attribute value is a javascript: URL.
+// Browsers ignore leading whitespace and control characters.
+func isJavaScriptURL(val string) bool {
+	val = strings.TrimLeftFunc(val, func(r rune) bool { return r <= ' ' })
+	return hasPrefixFold(val, "javascript:")
+}
+
+// encodeProxyParam encodes a URL for the url_b64 and referer_b64 parameters
+func encodeProxyParam(s string) string {
+	return url.QueryEscape(base64.StdEncoding.EncodeToString([]byte(s)))
+}
+
+func getAttr(tok *html.Token, key string) (string, bool) {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func setAttr(tok *html.Token, key, val string) {
+	for i := range tok.Attr {
+		if tok.Attr[i].Key == key {
+			tok.Attr[i].Val = val
+			return
+		}
+	}
+	tok.Attr = append(tok.Attr, html.Attribute{Key: key, Val: val})
+}
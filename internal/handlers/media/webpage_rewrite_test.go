@@ -0,0 +1,238 @@
+package media
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"MrRSS/internal/models"
+)
+
+// proxiedURLs decodes the url_b64 parameters of the proxy URLs in s,
+// skipping the ones the injected script builds at runtime
+func proxiedURLs(t *testing.T, s string) []string {
+	t.Helper()
+	var urls []string
+	for _, part := range strings.Split(s, "url_b64=")[1:] {
+		end := strings.IndexAny(part, `&"' )`)
+		if end < 0 {
+			end = len(part)
+		}
+		if end == 0 {
+			continue
+		}
+		encoded, err := url.QueryUnescape(part[:end])
+		if err != nil {
+			t.Fatalf("bad escape in %q: %v", part, err)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			t.Fatalf("bad base64 in %q: %v", part, err)
+		}
+		urls = append(urls, string(decoded))
+	}
+	return urls
+}
+
+func TestRewriteWebpage_ResolvesURLs(t *testing.T) {
+	page := `<!DOCTYPE html><html><head><base href="/assets/"><link rel=stylesheet href="site.css"></head>
+<body><IMG SRC="a.png?x=1&amp;y=2" alt="A">
+<picture><source srcset="b-1x.webp 1x, b-2x.webp 2x"><img src="b.png" srcset="//cdn.example.com/b.png 640w,c.png"></picture>
+<video poster="poster.jpg"><track src="subs.vtt"></video>
+<svg><image xlink:href="icon.svg"/></svg>
+<img src="data:image/png;base64,AAAA"><img src="#frag">
+</body></html>`
+
+	out := string(rewriteWebpage([]byte(page), "https://example.com/blog/post", false))
+
+	want := []string{
+		"https://example.com/assets/site.css",
+		"https://example.com/assets/a.png?x=1&y=2",
+		"https://example.com/assets/b-1x.webp",
+		"https://example.com/assets/b-2x.webp",
+		"https://example.com/assets/b.png",
+		"https://cdn.example.com/b.png",
+		"https://example.com/assets/c.png",
+		"https://example.com/assets/poster.jpg",
+		"https://example.com/assets/subs.vtt",
+		"https://example.com/assets/icon.svg",
+	}
+	got := proxiedURLs(t, out)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("proxied URLs:\n got %v\nwant %v", got, want)
+	}
+
+	for _, s := range []string{`640w, /api/webpage/resource`, `data:image/png;base64,AAAA`, `src="#frag"`, `alt="A"`, `<!DOCTYPE html>`} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in output:\n%s", s, out)
+		}
+	}
+	if strings.Contains(out, "<base") {
+		t.Errorf("base tag should be removed:\n%s", out)
+	}
+	if !strings.Contains(out, `const ORIGINAL_BASE_URL = "https://example.com/blog/post";`) {
+		t.Errorf("interceptor script not injected:\n%s", out)
+	}
+}
+
+func TestRewriteWebpage_LinksAndLazyImages(t *testing.T) {
+	page := `<html><head></head><body>
+<a href="/next" target="_top">Next</a><a href="mailto:me@example.com">Mail</a>
+<img class="lazyload hero" src="placeholder.gif" data-src="real.jpg" data-srcset="real-2x.jpg 2x">
+</body></html>`
+
+	out := string(rewriteWebpage([]byte(page), "https://example.com/post", false))
+
+	if !strings.Contains(out, `<a href="BROWSER-OPEN:https://example.com/next" target="_top" data-proxy-link="true">`) {
+		t.Errorf("link not rewritten:\n%s", out)
+	}
+	if !strings.Contains(out, `<a href="mailto:me@example.com">`) {
+		t.Errorf("mailto link should be left alone:\n%s", out)
+	}
+	if strings.Contains(out, "data-src") || strings.Contains(out, "lazyload") || !strings.Contains(out, `class="hero"`) {
+		t.Errorf("lazy image not promoted:\n%s", out)
+	}
+	got := proxiedURLs(t, out)
+	if fmt.Sprint(got) != "[https://example.com/real.jpg https://example.com/real-2x.jpg]" {
+		t.Errorf("unexpected proxied URLs %v", got)
+	}
+}
+
+func TestRewriteWebpage_ReaderSafe(t *testing.T) {
+	page := `<html><head><script src="app.js"></script><script>alert(1)</script>
+<meta http-equiv="refresh" content="0; url=https://example.com/elsewhere"></head>
+<body onload="track()"><a href="javascript:void(0)" onclick="go()">Go</a><a href="/about">About</a>
+<iframe srcdoc="<script>alert(2)</script>"></iframe>
+<noscript><img src="fallback.png"></noscript><p>Text</p></body></html>`
+
+	out := string(rewriteWebpage([]byte(page), "https://example.com/post", true))
+
+	for _, s := range []string{"<script", "alert", "onload", "onclick", "javascript:", "refresh", "srcdoc", "noscript", "ORIGINAL_BASE_URL"} {
+		if strings.Contains(out, s) {
+			t.Errorf("reader-safe output contains %q:\n%s", s, out)
+		}
+	}
+	for _, s := range []string{`<a href="https://example.com/about" target="_blank" rel="noopener noreferrer">`, "<p>Text</p>", "<body>"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in output:\n%s", s, out)
+		}
+	}
+	if got := proxiedURLs(t, out); fmt.Sprint(got) != "[https://example.com/fallback.png]" {
+		t.Errorf("noscript fallback not rewritten: %v", got)
+	}
+}
+
+func TestResourceProxy_RewriteCSS(t *testing.T) {
+	base, _ := url.Parse("https://example.com/css/site.css")
+	p := newResourceProxy(base, "https://example.com/post")
+
+	css := `@import "reset.css";
+@import url('print.css') print;
+/* url(commented.png) */
+.a { background: URL( "../img/a.png" ) no-repeat; content: "url(not-a-url.png)"; }
+.b { background-image: -webkit-image-set("b.png" 1x, url(b2.png) 2x); }
+.c { background-image: image-set('c.avif' type("image/avif"), 'c.png'); }
+.d { background: url(data:image/png;base64,AAAA); }
+@font-face { src: url(/fonts/f.woff2) format("woff2"); }`
+
+	out := p.rewriteCSS(css)
+	want := []string{
+		"https://example.com/css/reset.css",
+		"https://example.com/css/print.css",
+		"https://example.com/img/a.png",
+		"https://example.com/css/b.png",
+		"https://example.com/css/b2.png",
+		"https://example.com/css/c.avif",
+		"https://example.com/css/c.png",
+		"https://example.com/fonts/f.woff2",
+	}
+	if got := proxiedURLs(t, out); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("proxied URLs:\n got %v\nwant %v\n%s", got, want, out)
+	}
+	for _, s := range []string{"url(commented.png)", `"url(not-a-url.png)"`, `type("image/avif")`, "url(data:image/png;base64,AAAA)"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q to be left alone:\n%s", s, out)
+		}
+	}
+}
+
+func TestHandleWebpageProxy_ContentSecurityPolicy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/articles/post", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'")
+		_, _ = w.Write([]byte(`<html><head><script>x()</script></head><body><img src="pic.png"></body></html>`))
+	}))
+	defer upstream.Close()
+
+	h := setupHandler(t)
+	if _, err := h.DB.AddFeed(&models.Feed{Title: "Local", URL: upstream.URL + "/feed.xml", AllowPrivateNetwork: true}); err != nil {
+		t.Fatalf("AddFeed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		query  string
+		csp    string
+		script bool
+	}{
+		{"", webpageCSP, true},
+		{"&reader_safe=true", readerSafeCSP, false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/webpage/proxy?url="+url.QueryEscape(upstream.URL+"/moved")+tc.query, nil)
+		rr := httptest.NewRecorder()
+		HandleWebpageProxy(h, rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected %d got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		if got := rr.Header().Get("Content-Security-Policy"); got != tc.csp {
+			t.Errorf("unexpected CSP %q", got)
+		}
+		// Pages that keep their scripts must not share the app's origin
+		if csp := rr.Header().Get("Content-Security-Policy"); tc.script &&
+			(!strings.Contains(csp, "sandbox allow-scripts") || strings.Contains(csp, "allow-same-origin")) {
+			t.Errorf("scripted page is not sandboxed: %q", csp)
+		}
+		if got := strings.Contains(rr.Body.String(), "x()"); got != tc.script {
+			t.Errorf("script present = %v, want %v", got, tc.script)
+		}
+		// Relative URLs resolve against the page reached after redirects
+		if got := proxiedURLs(t, rr.Body.String()); len(got) != 1 || got[0] != upstream.URL+"/articles/pic.png" {
+			t.Errorf("unexpected proxied URLs %v", got)
+		}
+	}
+}
+
+// benchmarkPage builds a large article page with the markup the rewriter
+// handles: stylesheets, scripts, lazy images, srcsets, links and inline styles
+func benchmarkPage() []byte {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html><html><head><title>Bench</title><link rel="stylesheet" href="/css/site.css">
+<script src="/js/app.js"></script><style>body{background:url(/img/bg.png)} @font-face{src:url(/f.woff2)}</style></head><body>`)
+	for i := range 400 {
+		fmt.Fprintf(&b, `<article class="post" style="background-image:url('/img/%d-bg.jpg')">
+<h2><a href="/posts/%d">Post %d</a></h2>
+<img class="lazy" src="/img/placeholder.gif" data-src="/img/%d.jpg" alt="Post %d">
+<picture><source srcset="/img/%d.webp 1x, /img/%d@2x.webp 2x"><img src="/img/%d.png"></picture>
+<p>Lorem ipsum dolor sit amet, <a href="https://other.example.com/ref/%d">consectetur</a> adipiscing elit, sed do eiusmod tempor.</p>
+</article>
+`, i, i, i, i, i, i, i, i, i)
+	}
+	b.WriteString(`</body></html>`)
+	return []byte(b.String())
+}
+
+func BenchmarkRewriteWebpage(b *testing.B) {
+	page := benchmarkPage()
+	b.SetBytes(int64(len(page)))
+	for b.Loop() {
+		rewriteWebpage(page, "https://example.com/blog/post", false)
+	}
+}
@@ -0,0 +1,254 @@
+package opml
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"MrRSS/internal/database"
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/jsonimport"
+	"MrRSS/internal/models"
+	"MrRSS/internal/opml"
+)
+
+// importResult summarizes an import that was applied
+type importResult struct {
+	Success  bool             `json:"success"`
+	Imported int              `json:"imported"` // Feeds added
+	Updated  int              `json:"updated"`
+	Total    int              `json:"total"`
+	Plan     *opml.ImportPlan `json:"plan"`
+}
+
+// isDryRun reports whether the request only asks for the import plan
+func isDryRun(r *http.Request) bool {
+	v := r.URL.Query().Get("dry_run")
+	return v == "true" || v == "1"
+}
+
+// readImportFile returns the uploaded file and its name. Both multipart
+// uploads and raw request bodies are accepted.
+func readImportFile(r *http.Request) (io.Reader, string, func(), error) {
+	if strings.Contains(r.Header.Get("Content-Type"), "multipart/form-data") {
+		f, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, "", nil, err
+		}
+		log.Printf("Received file %s, size: %d", header.Filename, header.Size)
+		return f, header.Filename, func() { f.Close() }, nil
+	}
+	return r.Body, "", func() { r.Body.Close() }, nil
+}
+
+// parseImportFile parses an OPML or JSON import, chosen by file extension.
+// JSON exports always carry the full per-feed configuration.
+func parseImportFile(file io.Reader, filename string) ([]opml.Entry, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != ".json" {
+		log.Printf("Using OPML format (extension: %s)", ext)
+		return opml.ParseEntries(file)
+	}
+
+	log.Printf("Detected JSON format from extension %s", ext)
+	feeds, err := jsonimport.Parse(file)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]opml.Entry, 0, len(feeds))
+	for _, f := range feeds {
+		entries = append(entries, opml.Entry{Feed: f, HasSettings: true})
+	}
+	return entries, nil
+}
+
+// planImport compares parsed entries with the current subscriptions
+func planImport(h *core.Handler, entries []opml.Entry) (opml.ImportPlan, error) {
+	existing, err := h.DB.GetFeeds()
+	if err != nil {
+		return opml.ImportPlan{}, err
+	}
+	// Tags are compared too
+	feedIDs := make([]int64, 0, len(existing))
+	for _, f := range existing {
+		feedIDs = append(feedIDs, f.ID)
+	}
+	tags, err := h.DB.GetTagsForFeeds(feedIDs)
+	if err != nil {
+		return opml.ImportPlan{}, err
+	}
+	for i := range existing {
+		existing[i].Tags = tags[existing[i].ID]
+	}
+	return opml.PlanImport(entries, existing), nil
+}
+
+// applyImportPlan adds and updates feeds as planned and returns the IDs of
+// feeds whose articles should be fetched. Conflicts and skipped entries are left alone.
+func applyImportPlan(h *core.Handler, plan *opml.ImportPlan) (result importResult, feedIDs []int64) {
+	result.Plan = plan
+	result.Total = len(plan.Entries)
+
+	for _, entry := range plan.Entries {
+		f := entry.Feed
+
+		var feedID int64
+		var err error
+		switch entry.Action {
+		case opml.ImportAdd:
+			// Imported feeds are always local subscriptions
+			f.ID = 0
+			f.IsFreshRSSSource = false
+			f.FreshRSSStreamID = ""
+			feedID, err = h.DB.AddFeed(&f)
+			if err == nil {
+				result.Imported++
+			}
+		case opml.ImportUpdate:
+			feedID = entry.ExistingID
+			err = h.DB.UpdateFeedWithOptions(feedID, updateOptionsFor(f, entry.Changes))
+			if err == nil {
+				result.Updated++
+			}
+		default:
+			continue
+		}
+
+		if err != nil {
+			log.Printf("Error importing feed %s: %v", f.Title, err)
+			continue
+		}
+
+		// Save tags for new feeds, and for existing feeds whose tags differ
+		if len(f.Tags) > 0 && (entry.Action == opml.ImportAdd || slices.Contains(entry.Changes, "tags")) {
+			if err := saveFeedTags(h, feedID, f.Tags); err != nil {
+				log.Printf("Error saving tags for feed %s: %v", f.Title, err)
+				// Continue even if tag saving fails
+			}
+		}
+
+		feedIDs = append(feedIDs, feedID)
+	}
+
+	result.Success = true
+	return result, feedIDs
+}
+
+// updateOptionsFor builds update options that only touch the changed settings.
+// Secrets are never part of an import, so existing passwords are kept.
+func updateOptionsFor(f models.Feed, changes []string) database.FeedUpdateOptions {
+	var opts database.FeedUpdateOptions
+	for _, change := range changes {
+		switch change {
+		case "title":
+			opts.Title = &f.Title
+		case "category":
+			opts.Category = &f.Category
+		case "type":
+			opts.Type = &f.Type
+		case "script_path":
+			opts.ScriptPath = &f.ScriptPath
+		case "refresh_interval":
+			opts.RefreshInterval = &f.RefreshInterval
+		case "hide_from_timeline":
+			opts.HideFromTimeline = &f.HideFromTimeline
+		case "is_image_mode":
+			opts.IsImageMode = &f.IsImageMode
+		case "proxy_enabled":
+			opts.ProxyEnabled = &f.ProxyEnabled
+		case "proxy_url":
+			opts.ProxyURL = &f.ProxyURL
+		case "article_view_mode":
+			opts.ArticleViewMode = &f.ArticleViewMode
+		case "auto_expand_content":
+			opts.AutoExpandContent = &f.AutoExpandContent
+		case "xpath":
+			opts.XPathItem = &f.XPathItem
+			opts.XPathItemTitle = &f.XPathItemTitle
+			opts.XPathItemContent = &f.XPathItemContent
+			opts.XPathItemUri = &f.XPathItemUri
+			opts.XPathItemAuthor = &f.XPathItemAuthor
+			opts.XPathItemTimestamp = &f.XPathItemTimestamp
+			opts.XPathItemTimeFormat = &f.XPathItemTimeFormat
+			opts.XPathItemThumbnail = &f.XPathItemThumbnail
+			opts.XPathItemCategories = &f.XPathItemCategories
+			opts.XPathItemUid = &f.XPathItemUid
+		case "email":
+			opts.EmailAddress = &f.EmailAddress
+			opts.EmailIMAPServer = &f.EmailIMAPServer
+			opts.EmailIMAPPort = &f.EmailIMAPPort
+			opts.EmailUsername = &f.EmailUsername
+			opts.EmailFolder = &f.EmailFolder
+		}
+	}
+	return opts
+}
+
+// exportableFeeds returns local feeds with their tags, ready for export
+func exportableFeeds(h *core.Handler) ([]models.Feed, int, error) {
+	feeds, err := h.DB.GetFeeds()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Filter out FreshRSS feeds - only export local feeds
+	localFeeds := make([]models.Feed, 0, len(feeds))
+	for _, feed := range feeds {
+		if !feed.IsFreshRSSSource {
+			feed.Tags, _ = h.DB.GetFeedTags(feed.ID)
+			localFeeds = append(localFeeds, feed)
+		}
+	}
+	return localFeeds, len(feeds) - len(localFeeds), nil
+}
+
+// saveFeedTags creates or finds tags by name and associates them with the feed
+func saveFeedTags(h *core.Handler, feedID int64, tags []models.Tag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	var tagIDs []int64
+	for _, tag := range tags {
+		// Check if tag already exists by name
+		existingTags, err := h.DB.GetTags()
+		if err != nil {
+			log.Printf("Error fetching tags: %v", err)
+			continue
+		}
+
+		var foundTagID int64
+		for _, existingTag := range existingTags {
+			if strings.EqualFold(existingTag.Name, tag.Name) {
+				foundTagID = existingTag.ID
+				break
+			}
+		}
+
+		// If tag doesn't exist, create it
+		if foundTagID == 0 {
+			newTag := &models.Tag{
+				Name:  tag.Name,
+				Color: tag.Color,
+			}
+			id, err := h.DB.AddTag(newTag)
+			if err != nil {
+				log.Printf("Error creating tag %s: %v", tag.Name, err)
+				continue
+			}
+			foundTagID = id
+		}
+
+		tagIDs = append(tagIDs, foundTagID)
+	}
+
+	// Associate tags with feed
+	if len(tagIDs) > 0 {
+		return h.DB.SetFeedTags(feedID, tagIDs)
+	}
+
+	return nil
+}
@@ -4,7 +4,6 @@ package opml
 
 import (
 	"context"
-	"io"
 	"log"
 	"net/http"
 	"os"
@@ -14,156 +13,57 @@ import (
 	"MrRSS/internal/handlers/core"
 	"MrRSS/internal/handlers/response"
 	"MrRSS/internal/jsonimport"
-	"MrRSS/internal/models"
 	"MrRSS/internal/opml"
 
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
-// saveFeedTags creates or finds tags by name and associates them with the feed
-func saveFeedTags(h *core.Handler, feedID int64, tags []models.Tag) error {
-	if len(tags) == 0 {
-		return nil
-	}
-
-	var tagIDs []int64
-	for _, tag := range tags {
-		// Check if tag already exists by name
-		existingTags, err := h.DB.GetTags()
-		if err != nil {
-			log.Printf("Error fetching tags: %v", err)
-			continue
-		}
-
-		var foundTagID int64
-		for _, existingTag := range existingTags {
-			if strings.EqualFold(existingTag.Name, tag.Name) {
-				foundTagID = existingTag.ID
-				break
-			}
-		}
-
-		// If tag doesn't exist, create it
-		if foundTagID == 0 {
-			newTag := &models.Tag{
-				Name:  tag.Name,
-				Color: tag.Color,
-			}
-			id, err := h.DB.AddTag(newTag)
-			if err != nil {
-				log.Printf("Error creating tag %s: %v", tag.Name, err)
-				continue
-			}
-			foundTagID = id
-		}
-
-		tagIDs = append(tagIDs, foundTagID)
-	}
-
-	// Associate tags with feed
-	if len(tagIDs) > 0 {
-		return h.DB.SetFeedTags(feedID, tagIDs)
-	}
-
-	return nil
-}
-
 // HandleOPMLImport handles OPML/JSON file import based on file extension.
 // @Summary      Import subscriptions from OPML/JSON
 // @Description  Import RSS feed subscriptions from an OPML or JSON file
 // @Tags         opml
 // @Accept       multipart/form-data
 // @Produce      json
-// @Param        file  formData  file  false  "OPML or JSON file to import"
-// @Success      200  {object}  map[string]string  "Import successful"
+// @Param        file     formData  file  false  "OPML or JSON file to import"
+// @Param        dry_run  query     bool  false  "Only report adds, updates, conflicts and skipped entries"
+// @Success      200  {object}  map[string]interface{}  "Import result, or the import plan for a dry run"
 // @Failure      400  {object}  map[string]string  "Bad request"
 // @Failure      500  {object}  map[string]string  "Internal server error"
 // @Router       /opml/import [post]
 func HandleOPMLImport(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 	log.Printf("HandleOPMLImport: ContentLength: %d", r.ContentLength)
-	contentType := r.Header.Get("Content-Type")
-	log.Printf("HandleOPMLImport: Content-Type: %s", contentType)
-
-	var file io.Reader
-	var filename string
-
-	if strings.Contains(contentType, "multipart/form-data") {
-		f, header, err := r.FormFile("file")
-		if err != nil {
-			log.Printf("Error getting form file: %v", err)
-			response.Error(w, err, http.StatusBadRequest)
-			return
-		}
-		defer f.Close()
-		filename = header.Filename
-		log.Printf("HandleOPMLImport: Received file %s, size: %d", filename, header.Size)
-
-		if header.Size == 0 {
-			response.Error(w, nil, http.StatusBadRequest)
-			return
-		}
-		file = f
-	} else {
-		// Handle raw body upload
-		file = r.Body
-		defer r.Body.Close()
-	}
-
-	// Determine format based on file extension
-	ext := strings.ToLower(filepath.Ext(filename))
-	isJSON := ext == ".json"
-
-	var feeds []models.Feed
-	var err error
+	log.Printf("HandleOPMLImport: Content-Type: %s", r.Header.Get("Content-Type"))
 
-	if isJSON {
-		log.Printf("HandleOPMLImport: Detected JSON format from extension %s", ext)
-		feeds, err = jsonimport.Parse(file)
-	} else {
-		log.Printf("HandleOPMLImport: Using OPML format (extension: %s)", ext)
-		feeds, err = opml.Parse(file)
+	file, filename, closeFile, err := readImportFile(r)
+	if err != nil {
+		log.Printf("Error getting form file: %v", err)
+		response.Error(w, err, http.StatusBadRequest)
+		return
 	}
+	defer closeFile()
 
+	entries, err := parseImportFile(file, filename)
 	if err != nil {
 		log.Printf("Error parsing file: %v", err)
 		response.Error(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	// Import feeds synchronously so they appear in the sidebar immediately
-	var feedIDs []int64
-	for _, f := range feeds {
-		var feedID int64
-		var err error
-
-		// Check if feed has XPath configuration
-		if f.Type == "HTML+XPath" || f.Type == "XML+XPath" {
-			feedID, err = h.Fetcher.AddXPathSubscription(
-				f.URL, f.Category, f.Title, f.Type,
-				f.XPathItem, f.XPathItemTitle, f.XPathItemContent, f.XPathItemUri,
-				f.XPathItemAuthor, f.XPathItemTimestamp, f.XPathItemTimeFormat,
-				f.XPathItemThumbnail, f.XPathItemCategories, f.XPathItemUid,
-			)
-		} else {
-			feedID, err = h.Fetcher.ImportSubscription(f.Title, f.URL, f.Category)
-		}
-
-		if err != nil {
-			log.Printf("Error importing feed %s: %v", f.Title, err)
-			continue
-		}
-
-		// Save tags for the feed
-		if len(f.Tags) > 0 {
-			if err := saveFeedTags(h, feedID, f.Tags); err != nil {
-				log.Printf("Error saving tags for feed %s: %v", f.Title, err)
-				// Continue even if tag saving fails
-			}
-		}
-
-		feedIDs = append(feedIDs, feedID)
+	plan, err := planImport(h, entries)
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// A dry run only reports what would happen
+	if isDryRun(r) {
+		response.JSON(w, plan)
+		return
 	}
 
+	// Import feeds synchronously so they appear in the sidebar immediately
+	result, feedIDs := applyImportPlan(h, &plan)
+
 	// Fetch articles for the newly imported feeds asynchronously with progress tracking
 	if len(feedIDs) > 0 {
 		go func() {
@@ -171,7 +71,7 @@ func HandleOPMLImport(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 		}()
 	}
 
-	w.WriteHeader(http.StatusOK)
+	response.JSON(w, result)
 }
 
 // HandleOPMLExport handles OPML file export.
@@ -184,22 +84,14 @@ func HandleOPMLImport(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 // @Failure      500  {object}  map[string]string  "Internal server error"
 // @Router       /opml/export [get]
 func HandleOPMLExport(h *core.Handler, w http.ResponseWriter, r *http.Request) {
-	feeds, err := h.DB.GetFeeds()
+	localFeeds, excluded, err := exportableFeeds(h)
 	if err != nil {
 		response.Error(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	// Filter out FreshRSS feeds - only export local feeds
-	localFeeds := make([]models.Feed, 0)
-	for _, feed := range feeds {
-		if !feed.IsFreshRSSSource {
-			localFeeds = append(localFeeds, feed)
-		}
-	}
-
 	log.Printf("[OPML Export] Exporting %d local feeds (excluded %d FreshRSS feeds)",
-		len(localFeeds), len(feeds)-len(localFeeds))
+		len(localFeeds), excluded)
 
 	data, err := opml.Generate(localFeeds)
 	if err != nil {
@@ -301,22 +193,19 @@ func HandleOPMLImportDialog(h *core.Handler, w http.ResponseWriter, r *http.Requ
 	}
 	defer file.Close()
 
-	// Determine format based on file extension
-	ext := strings.ToLower(filepath.Ext(filePath))
-	isJSON := ext == ".json"
-
-	var feeds []models.Feed
-
-	if isJSON {
-		log.Printf("HandleOPMLImportDialog: Detected JSON format from extension %s", ext)
-		feeds, err = jsonimport.Parse(file)
-	} else {
-		log.Printf("HandleOPMLImportDialog: Using OPML format (extension: %s)", ext)
-		feeds, err = opml.Parse(file)
+	entries, err := parseImportFile(file, filePath)
+	if err != nil {
+		log.Printf("Error parsing file: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		response.JSON(w, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
 	}
 
+	plan, err := planImport(h, entries)
 	if err != nil {
-		log.Printf("Error parsing file: %v", err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		response.JSON(w, map[string]interface{}{
@@ -326,38 +215,7 @@ func HandleOPMLImportDialog(h *core.Handler, w http.ResponseWriter, r *http.Requ
 	}
 
 	// Import feeds synchronously so they appear in the sidebar immediately
-	var feedIDs []int64
-	for _, f := range feeds {
-		var feedID int64
-		var err error
-
-		// Check if feed has XPath configuration
-		if f.Type == "HTML+XPath" || f.Type == "XML+XPath" {
-			feedID, err = h.Fetcher.AddXPathSubscription(
-				f.URL, f.Category, f.Title, f.Type,
-				f.XPathItem, f.XPathItemTitle, f.XPathItemContent, f.XPathItemUri,
-				f.XPathItemAuthor, f.XPathItemTimestamp, f.XPathItemTimeFormat,
-				f.XPathItemThumbnail, f.XPathItemCategories, f.XPathItemUid,
-			)
-		} else {
-			feedID, err = h.Fetcher.ImportSubscription(f.Title, f.URL, f.Category)
-		}
-
-		if err != nil {
-			log.Printf("Error importing feed %s: %v", f.Title, err)
-			continue
-		}
-
-		// Save tags for the feed
-		if len(f.Tags) > 0 {
-			if err := saveFeedTags(h, feedID, f.Tags); err != nil {
-				log.Printf("Error saving tags for feed %s: %v", f.Title, err)
-				// Continue even if tag saving fails
-			}
-		}
-
-		feedIDs = append(feedIDs, feedID)
-	}
+	result, feedIDs := applyImportPlan(h, &plan)
 
 	// Fetch articles for the newly imported feeds asynchronously with progress tracking
 	if len(feedIDs) > 0 {
@@ -369,7 +227,9 @@ func HandleOPMLImportDialog(h *core.Handler, w http.ResponseWriter, r *http.Requ
 	w.Header().Set("Content-Type", "application/json")
 	response.JSON(w, map[string]interface{}{
 		"status":    "success",
-		"feedCount": len(feeds),
+		"feedCount": len(entries),
+		"imported":  result.Imported,
+		"updated":   result.Updated,
 		"filePath":  filePath,
 	})
 }
@@ -396,7 +256,7 @@ func HandleOPMLExportDialog(h *core.Handler, w http.ResponseWriter, r *http.Requ
 	}
 
 	// Get feeds data
-	feeds, err := h.DB.GetFeeds()
+	localFeeds, excluded, err := exportableFeeds(h)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -406,16 +266,8 @@ func HandleOPMLExportDialog(h *core.Handler, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Filter out FreshRSS feeds - only export local feeds
-	localFeeds := make([]models.Feed, 0)
-	for _, feed := range feeds {
-		if !feed.IsFreshRSSSource {
-			localFeeds = append(localFeeds, feed)
-		}
-	}
-
 	log.Printf("[OPML Export Dialog] Exporting %d local feeds (excluded %d FreshRSS feeds)",
-		len(localFeeds), len(feeds)-len(localFeeds))
+		len(localFeeds), excluded)
 
 	// Type assert to *application.App to access Dialog
 	app, ok := h.App.(*application.App)
@@ -3,10 +3,9 @@
 package opml
 
 import (
-	"io"
+	"context"
 	"log"
 	"net/http"
-	"strings"
 
 	"MrRSS/internal/handlers/core"
 	"MrRSS/internal/handlers/response"
@@ -15,74 +14,61 @@ import (
 
 // HandleOPMLImport handles OPML file import for server mode.
 // @Summary      Import OPML file
-// @Description  Import feeds from an OPML file (server mode - requires file upload)
+// @Description  Import feeds from an OPML or JSON file (server mode - requires file upload)
 // @Tags         opml
 // @Accept       multipart/form-data
 // @Produce      json
-// @Param        file  formData  file  true  "OPML file"
-// @Success      200  {object}  map[string]interface{}  "Import result (success, imported, total)"
+// @Param        file     formData  file  true   "OPML or JSON file"
+// @Param        dry_run  query     bool  false  "Only report adds, updates, conflicts and skipped entries"
+// @Success      200  {object}  map[string]interface{}  "Import result (success, imported, updated, total, plan), or the import plan for a dry run"
 // @Failure      400  {object}  map[string]string  "Bad request (invalid file or format)"
 // @Failure      500  {object}  map[string]string  "Internal server error"
 // @Router       /opml/import [post]
 func HandleOPMLImport(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 	log.Printf("HandleOPMLImport: ContentLength: %d", r.ContentLength)
 
-	// Parse multipart form
-	err := r.ParseMultipartForm(32 << 20) // 32MB max
+	// Get the file
+	file, filename, closeFile, err := readImportFile(r)
 	if err != nil {
-		log.Printf("Error parsing multipart form: %v", err)
+		log.Printf("Error getting file: %v", err)
 		response.Error(w, err, http.StatusBadRequest)
 		return
 	}
+	defer closeFile()
 
-	// Get the file
-	file, header, err := r.FormFile("file")
+	// Parse OPML or JSON
+	entries, err := parseImportFile(file, filename)
 	if err != nil {
-		log.Printf("Error getting file: %v", err)
+		log.Printf("Error parsing OPML: %v", err)
 		response.Error(w, err, http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
 
-	log.Printf("Received file: %s, size: %d", header.Filename, header.Size)
+	log.Printf("Parsed %d feeds from OPML", len(entries))
 
-	// Read file content
-	content, err := io.ReadAll(file)
+	plan, err := planImport(h, entries)
 	if err != nil {
-		log.Printf("Error reading file: %v", err)
 		response.Error(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	// Parse OPML
-	feeds, err := opml.Parse(strings.NewReader(string(content)))
-	if err != nil {
-		log.Printf("Error parsing OPML: %v", err)
-		response.Error(w, err, http.StatusBadRequest)
+	// A dry run only reports what would happen
+	if isDryRun(r) {
+		response.JSON(w, plan)
 		return
 	}
 
-	log.Printf("Parsed %d feeds from OPML", len(feeds))
-
 	// Import feeds
-	imported := 0
-	for _, feed := range feeds {
-		_, err := h.DB.AddFeed(&feed)
-		if err != nil {
-			log.Printf("Error importing feed %s: %v", feed.URL, err)
-			continue
-		}
-		imported++
-	}
+	result, feedIDs := applyImportPlan(h, &plan)
 
-	log.Printf("Successfully imported %d feeds", imported)
+	log.Printf("Successfully imported %d feeds, updated %d", result.Imported, result.Updated)
+
+	if len(feedIDs) > 0 && h.Fetcher != nil {
+		go h.Fetcher.FetchFeedsByIDs(context.Background(), feedIDs)
+	}
 
 	// Return success response
-	response.JSON(w, map[string]interface{}{
-		"success":  true,
-		"imported": imported,
-		"total":    len(feeds),
-	})
+	response.JSON(w, result)
 }
 
 // HandleOPMLImportDialog is not available in server mode.
@@ -112,7 +98,7 @@ func HandleOPMLImportDialog(h *core.Handler, w http.ResponseWriter, r *http.Requ
 // @Router       /opml/export [get]
 func HandleOPMLExport(h *core.Handler, w http.ResponseWriter, r *http.Request) {
 	// Get feeds data
-	feeds, err := h.DB.GetFeeds()
+	feeds, _, err := exportableFeeds(h)
 	if err != nil {
 		response.Error(w, err, http.StatusInternalServerError)
 		return
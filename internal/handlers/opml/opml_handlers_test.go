@@ -9,6 +9,8 @@ import (
 	"MrRSS/internal/database"
 	"MrRSS/internal/feed"
 	corepkg "MrRSS/internal/handlers/core"
+	"MrRSS/internal/models"
+	"MrRSS/internal/opml"
 )
 
 func TestHandleOPMLImport_RawBody(t *testing.T) {
@@ -167,3 +169,54 @@ func TestHandleOPMLExport(t *testing.T) {
 		t.Fatalf("exported OPML missing feed URL: %s", body)
 	}
 }
+
+func TestHandleOPMLImport_UpdatesTags(t *testing.T) {
+	db, err := database.NewDB("file:TestHandleOPMLImport_UpdatesTags?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	if err := db.Init(); err != nil {
+		t.Fatalf("failed to init db: %v", err)
+	}
+	feedID, err := db.AddFeed(&models.Feed{Title: "Blog", URL: "https://blog.example.com/feed"})
+	if err != nil {
+		t.Fatalf("AddFeed failed: %v", err)
+	}
+	tagID, err := db.AddTag(&models.Tag{Name: "Go"})
+	if err != nil {
+		t.Fatalf("AddTag failed: %v", err)
+	}
+	if err := db.SetFeedTags(feedID, []int64{tagID}); err != nil {
+		t.Fatalf("SetFeedTags failed: %v", err)
+	}
+
+	// The feed matches in everything but its tags
+	xmlData := `<?xml version="1.0"?>
+<opml version="2.0" xmlns:mrrss="` + opml.Namespace + `">
+  <body>
+    <outline text="Blog" title="Blog" xmlUrl="https://blog.example.com/feed" mrrss:tags='[{"name":"go"},{"name":"Rust"}]'/>
+  </body>
+</opml>`
+
+	f := feed.NewFetcher(db)
+	h := &corepkg.Handler{DB: db, Fetcher: f}
+	defer f.GetTaskManager().Stop()
+	defer f.GetCleanupManager().Stop()
+
+	rr := httptest.NewRecorder()
+	HandleOPMLImport(h, rr, httptest.NewRequest(http.MethodPost, "/opml/import", strings.NewReader(xmlData)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	tags, err := db.GetFeedTags(feedID)
+	if err != nil {
+		t.Fatalf("GetFeedTags failed: %v", err)
+	}
+	if len(tags) != 2 {
+		t.Fatalf("expected the imported tags, got %+v", tags)
+	}
+	if tags[0].ID != tagID && tags[1].ID != tagID {
+		t.Errorf("existing tag Go was not reused: %+v", tags)
+	}
+}
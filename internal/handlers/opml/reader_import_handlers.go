@@ -0,0 +1,140 @@
+package opml
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+	"MrRSS/internal/readerimport"
+)
+
+// maxReaderExportSize limits uploaded exports, which are parsed in memory
+const maxReaderExportSize = 256 << 20
+
+// HandleReaderImport starts importing starred and saved items exported from another reader.
+// @Summary      Import starred and saved items
+// @Description  Import a Google Reader / FreshRSS / Inoreader starred-items JSON, Feedly saved-for-later JSON, Miniflux entries, Pocket HTML export or Wallabag JSON export. Items become articles in their original feed if subscribed, otherwise in the "Imported" feed. The import runs in the background; poll /import/items/progress.
+// @Tags         opml
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file    formData  file    false  "Export file (or send it as the raw request body)"
+// @Param        format  formData  string  false  "google-reader, feedly, miniflux, pocket or wallabag (detected when empty)"
+// @Success      202  {object}  map[string]interface{}  "Import started (status, format, total)"
+// @Failure      400  {object}  map[string]string  "Bad request or unrecognized export"
+// @Failure      409  {object}  map[string]string  "Import already in progress"
+// @Router       /import/items [post]
+func HandleReaderImport(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body io.Reader = r.Body
+	format := r.URL.Query().Get("format")
+	if strings.Contains(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			response.Error(w, err, http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+		if v := r.FormValue("format"); v != "" {
+			format = v
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxReaderExportSize+1))
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxReaderExportSize {
+		response.Error(w, errors.New("export file is too large"), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	items, detected, err := readerimport.Parse(data, readerimport.Format(format))
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	h.ReaderImportMu.Lock()
+	if h.ReaderImportState != nil && h.ReaderImportState.IsRunning {
+		h.ReaderImportMu.Unlock()
+		response.Error(w, nil, http.StatusConflict)
+		return
+	}
+	h.ReaderImportState = &core.ReaderImportState{
+		IsRunning: true,
+		Format:    string(detected),
+		Progress:  readerimport.Progress{Total: len(items)},
+	}
+	h.ReaderImportMu.Unlock()
+
+	go func() {
+		progressCb := func(progress readerimport.Progress) {
+			h.ReaderImportMu.Lock()
+			if h.ReaderImportState != nil {
+				h.ReaderImportState.Progress = progress
+			}
+			h.ReaderImportMu.Unlock()
+		}
+
+		log.Printf("Starting %s import of %d items", detected, len(items))
+		result, err := readerimport.Import(h.DB, items, progressCb)
+
+		h.ReaderImportMu.Lock()
+		defer h.ReaderImportMu.Unlock()
+
+		if h.ReaderImportState == nil {
+			return
+		}
+		h.ReaderImportState.IsRunning = false
+		h.ReaderImportState.IsComplete = true
+
+		if err != nil {
+			log.Printf("Error importing %s export: %v", detected, err)
+			h.ReaderImportState.Error = err.Error()
+			return
+		}
+		h.ReaderImportState.Progress = *result
+		log.Printf("Import complete: %d items in their feeds, %d in the Imported feed, %d failed",
+			result.InOriginalFeed, result.InImportedFeed, result.Failed)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+	response.JSON(w, map[string]interface{}{
+		"status": "started",
+		"format": detected,
+		"total":  len(items),
+	})
+}
+
+// HandleReaderImportProgress returns the progress of the current import.
+// @Summary      Get item import progress
+// @Description  Get the progress and result of the import started with /import/items
+// @Tags         opml
+// @Produce      json
+// @Success      200  {object}  core.ReaderImportState  "Import state (is_running, is_complete, format, progress, error)"
+// @Router       /import/items/progress [get]
+func HandleReaderImportProgress(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.ReaderImportMu.RLock()
+	defer h.ReaderImportMu.RUnlock()
+
+	if h.ReaderImportState == nil {
+		response.JSON(w, &core.ReaderImportState{})
+		return
+	}
+	response.JSON(w, h.ReaderImportState)
+}
@@ -0,0 +1,269 @@
+package opml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"MrRSS/internal/handlers/core"
+	"MrRSS/internal/handlers/response"
+	"MrRSS/internal/models"
+)
+
+// subscriptionListSyncTimeout bounds a sync triggered from the API
+const subscriptionListSyncTimeout = 60 * time.Second
+
+// HandleSubscriptionLists lists linked OPML subscription lists (GET) or adds one (POST).
+// @Summary      Manage linked OPML subscription lists
+// @Description  GET returns all subscription lists. POST subscribes to an OPML URL; its feeds are added under the given category and kept in sync with the publisher.
+// @Tags         opml
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  false  "List details (url, category, title, refresh_interval in minutes) for POST"
+// @Success      200  {object}  map[string]interface{}  "Subscription lists (GET) or the new list and its first sync (POST)"
+// @Failure      400  {object}  map[string]string  "Bad request or the OPML could not be synced"
+// @Failure      409  {object}  map[string]string  "Already subscribed to this list"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /opml/lists [get]
+// @Router       /opml/lists [post]
+func HandleSubscriptionLists(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		lists, err := h.DB.GetSubscriptionLists()
+		if err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		response.JSON(w, lists)
+	case http.MethodPost:
+		addSubscriptionList(h, w, r)
+	default:
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+	}
+}
+
+// addSubscriptionList stores a new list and runs its first sync. Lists
+// that cannot be synced are not kept, so a wrong URL is reported right away.
+func addSubscriptionList(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL             string `json:"url"`
+		Category        string `json:"category"`
+		Title           string `json:"title"`
+		RefreshInterval int    `json:"refresh_interval"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	req.URL = strings.TrimSpace(req.URL)
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		response.Error(w, errors.New("subscription list URL must be an http(s) URL"), http.StatusBadRequest)
+		return
+	}
+
+	lists, err := h.DB.GetSubscriptionLists()
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	for _, l := range lists {
+		if l.URL == req.URL {
+			response.Error(w, errors.New("already subscribed to this list"), http.StatusConflict)
+			return
+		}
+	}
+
+	list := &models.SubscriptionList{
+		URL:             req.URL,
+		Title:           strings.TrimSpace(req.Title),
+		Category:        strings.TrimSpace(req.Category),
+		RefreshInterval: req.RefreshInterval,
+	}
+	if list.Title == "" {
+		list.Title = u.Host
+	}
+	if list.Category == "" {
+		list.Category = list.Title
+	}
+
+	id, err := h.DB.AddSubscriptionList(list)
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	list.ID = id
+
+	ctx, cancel := context.WithTimeout(r.Context(), subscriptionListSyncTimeout)
+	defer cancel()
+
+	result, err := h.Fetcher.SyncSubscriptionList(ctx, list)
+	if err != nil {
+		log.Printf("Error syncing new subscription list %s: %v", list.URL, err)
+		_ = h.DB.DeleteSubscriptionList(id)
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	fetchAddedFeeds(h, result.Added)
+
+	saved, err := h.DB.GetSubscriptionList(id)
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	response.JSON(w, map[string]interface{}{
+		"list":   saved,
+		"result": result,
+	})
+}
+
+// HandleDeleteSubscriptionList unsubscribes from a linked OPML list.
+// @Summary      Delete a subscription list
+// @Description  Stop syncing a linked OPML list. With remove_feeds the feeds it added are unsubscribed too; otherwise they are kept as regular feeds.
+// @Tags         opml
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "Delete details (id, remove_feeds)"
+// @Success      200  {object}  map[string]interface{}  "Delete status and number of removed feeds"
+// @Failure      400  {object}  map[string]string  "Bad request"
+// @Failure      404  {object}  map[string]string  "Subscription list not found"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /opml/lists/delete [post]
+func HandleDeleteSubscriptionList(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID          int64 `json:"id"`
+		RemoveFeeds bool  `json:"remove_feeds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.DB.GetSubscriptionList(req.ID); err != nil {
+		response.Error(w, err, http.StatusNotFound)
+		return
+	}
+
+	removed := 0
+	if req.RemoveFeeds {
+		owned, err := h.DB.GetSubscriptionListFeeds(req.ID)
+		if err != nil {
+			response.Error(w, err, http.StatusInternalServerError)
+			return
+		}
+		for _, f := range owned {
+			if err := h.DB.DeleteFeed(f.ID); err != nil {
+				log.Printf("Error removing feed %s of subscription list %d: %v", f.URL, req.ID, err)
+				continue
+			}
+			removed++
+		}
+	}
+
+	if err := h.DB.DeleteSubscriptionList(req.ID); err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, map[string]interface{}{"status": "ok", "removed": removed})
+}
+
+// HandleSyncSubscriptionList syncs a linked OPML list right away.
+// @Summary      Sync a subscription list
+// @Description  Re-fetch a linked OPML list now, adding and removing its feeds
+// @Tags         opml
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "Sync details (id)"
+// @Success      200  {object}  feed.SubscriptionListSyncResult  "Sync result"
+// @Failure      400  {object}  map[string]string  "Bad request"
+// @Failure      404  {object}  map[string]string  "Subscription list not found"
+// @Failure      502  {object}  map[string]string  "The OPML could not be fetched or parsed"
+// @Router       /opml/lists/sync [post]
+func HandleSyncSubscriptionList(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.DB.GetSubscriptionList(req.ID)
+	if err != nil {
+		response.Error(w, err, http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), subscriptionListSyncTimeout)
+	defer cancel()
+
+	result, err := h.Fetcher.SyncSubscriptionList(ctx, list)
+	if err != nil {
+		response.Error(w, err, http.StatusBadGateway)
+		return
+	}
+
+	fetchAddedFeeds(h, result.Added)
+	response.JSON(w, result)
+}
+
+// HandleSubscriptionListLog returns the change log of a subscription list.
+// @Summary      Get subscription list log
+// @Description  List the feeds a linked OPML list added, removed or skipped, and sync errors, newest first
+// @Tags         opml
+// @Produce      json
+// @Param        id     query     int64  true   "Subscription list ID"
+// @Param        limit  query     int    false  "Maximum number of entries (default 100)"
+// @Success      200  {array}   models.SubscriptionListLogEntry  "Log entries"
+// @Failure      400  {object}  map[string]string  "Bad request (invalid list ID)"
+// @Failure      500  {object}  map[string]string  "Internal server error"
+// @Router       /opml/lists/log [get]
+func HandleSubscriptionListLog(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		response.Error(w, err, http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	entries, err := h.DB.GetSubscriptionListLog(id, limit)
+	if err != nil {
+		response.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	response.JSON(w, entries)
+}
+
+// fetchAddedFeeds fetches articles of feeds added by a sync in the background
+func fetchAddedFeeds(h *core.Handler, feedIDs []int64) {
+	if len(feedIDs) == 0 {
+		return
+	}
+	go func() {
+		h.Fetcher.FetchFeedsByIDs(context.Background(), feedIDs)
+	}()
+}
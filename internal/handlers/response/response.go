@@ -17,8 +17,16 @@ type APIResponse struct {
 
 // ErrorInfo represents error information in API responses
 type ErrorInfo struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Position *int   `json:"position,omitempty"` // Character offset in the input the error refers to
+}
+
+// positionError is an error about a character of the request input, such as
+// a syntax error in a query
+type positionError interface {
+	error
+	Position() int
 }
 
 // JSON writes a JSON response with success status
@@ -63,6 +71,11 @@ func Error(w http.ResponseWriter, err error, defaultStatus int) {
 	} else if err != nil {
 		errorInfo.Message = err.Error()
 	}
+	var posErr positionError
+	if errors.As(err, &posErr) {
+		pos := posErr.Position()
+		errorInfo.Position = &pos
+	}
 
 	resp := APIResponse{
 		Success: false,
@@ -109,7 +109,7 @@ func HandleTestConnection(h *core.Handler, w http.ResponseWriter, r *http.Reques
 // HandleValidateRoute validates a specific RSSHub route
 //
 //	@Summary		Validate RSSHub route
-//	@Description	Validates if a specific RSSHub route exists and is accessible using the configured endpoint and API key
+//	@Description	Validates if a specific RSSHub route exists and is accessible on the preferred healthy RSSHub instance
 //	@Tags			rsshub
 //	@Accept			json
 //	@Produce		json
@@ -137,15 +137,17 @@ func HandleValidateRoute(h *core.Handler, w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Get RSSHub settings
-	endpoint, _ := h.DB.GetSetting("rsshub_endpoint")
-	if endpoint == "" {
-		endpoint = "https://rss.spriple.org"
+	// Validate against the preferred healthy instance
+	instance, ok := h.Fetcher.RSSHubPool().Primary()
+	if !ok {
+		response.JSON(w, map[string]interface{}{
+			"valid": false,
+			"error": "No RSSHub instance configured",
+		})
+		return
 	}
-	apiKey, _ := h.DB.GetEncryptedSetting("rsshub_api_key")
 
-	client := rsshub.NewClient(endpoint, apiKey)
-	err := client.ValidateRoute(req.Route)
+	err := instance.Client().ValidateRoute(req.Route)
 
 	if err != nil {
 		response.JSON(w, map[string]interface{}{
@@ -164,7 +166,7 @@ func HandleValidateRoute(h *core.Handler, w http.ResponseWriter, r *http.Request
 // HandleTransformURL transforms a rsshub:// URL to full RSSHub URL
 //
 //	@Summary		Transform RSSHub URL
-//	@Description	Transforms a rsshub:// protocol URL to full RSSHub URL with endpoint and API key
+//	@Description	Transforms a rsshub:// protocol URL to full RSSHub URL on the preferred healthy instance
 //	@Tags			rsshub
 //	@Accept			json
 //	@Produce		json
@@ -207,19 +209,45 @@ func HandleTransformURL(h *core.Handler, w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Get RSSHub settings
-	endpoint, _ := h.DB.GetSetting("rsshub_endpoint")
-	if endpoint == "" {
-		endpoint = "https://rss.spriple.org"
+	// Build the URL on the preferred healthy instance
+	instance, ok := h.Fetcher.RSSHubPool().Primary()
+	if !ok {
+		response.Error(w, fmt.Errorf("no RSSHub instance configured"), http.StatusBadRequest)
+		return
 	}
-	apiKey, _ := h.DB.GetEncryptedSetting("rsshub_api_key")
 
 	// Extract route and build URL
 	route := rsshub.ExtractRoute(req.URL)
-	client := rsshub.NewClient(endpoint, apiKey)
-	transformedURL := client.BuildURL(route)
+	transformedURL := instance.Client().BuildURL(route)
 
 	response.JSON(w, map[string]interface{}{
 		"url": transformedURL,
 	})
 }
+
+// HandleInstanceStatus reports the health of all configured RSSHub instances.
+// A POST request probes every instance before reporting.
+//
+//	@Summary		RSSHub instance health
+//	@Description	Returns health and failure counters for each configured RSSHub instance in failover order. POST runs a health check first.
+//	@Tags			rsshub
+//	@Produce		json
+//	@Success		200	{object}	object{instances=[]rsshub.InstanceStatus}	"Instance health"
+//	@Router			/api/rsshub/instances [get]
+//	@Router			/api/rsshub/instances [post]
+func HandleInstanceStatus(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	var statuses []rsshub.InstanceStatus
+	switch r.Method {
+	case http.MethodGet:
+		statuses = h.Fetcher.RSSHubPool().Status()
+	case http.MethodPost:
+		statuses = h.Fetcher.CheckRSSHubHealth()
+	default:
+		response.Error(w, nil, http.StatusMethodNotAllowed)
+		return
+	}
+
+	response.JSON(w, map[string]interface{}{
+		"instances": statuses,
+	})
+}
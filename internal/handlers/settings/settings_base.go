@@ -31,11 +31,19 @@ var AllSettings = []SettingDef{
 	{Key: "ai_translation_prompt", Encrypted: false},
 	{Key: "ai_usage_limit", Encrypted: false},
 	{Key: "ai_usage_tokens", Encrypted: false},
+	{Key: "archive_favorites", Encrypted: false},
 	{Key: "auto_cleanup_enabled", Encrypted: false},
 	{Key: "auto_show_all_content", Encrypted: false},
+	{Key: "backup_compress", Encrypted: false},
+	{Key: "backup_enabled", Encrypted: false},
+	{Key: "backup_interval_hours", Encrypted: false},
+	{Key: "backup_keep_daily", Encrypted: false},
+	{Key: "backup_keep_weekly", Encrypted: false},
 	{Key: "baidu_app_id", Encrypted: false},
 	{Key: "baidu_secret_key", Encrypted: true},
 	{Key: "close_to_tray", Encrypted: false},
+	{Key: "cold_storage_enabled", Encrypted: false},
+	{Key: "cold_storage_keep_content", Encrypted: false},
 	{Key: "content_font_family", Encrypted: false},
 	{Key: "content_font_size", Encrypted: false},
 	{Key: "content_line_height", Encrypted: false},
@@ -88,6 +96,11 @@ var AllSettings = []SettingDef{
 	{Key: "obsidian_enabled", Encrypted: false},
 	{Key: "obsidian_vault", Encrypted: false},
 	{Key: "obsidian_vault_path", Encrypted: false},
+	{Key: "offline_mode", Encrypted: false},
+	{Key: "offline_prefetch_categories", Encrypted: false},
+	{Key: "offline_prefetch_enabled", Encrypted: false},
+	{Key: "offline_prefetch_filters", Encrypted: false},
+	{Key: "offline_prefetch_max_mb", Encrypted: false},
 	{Key: "proxy_enabled", Encrypted: false},
 	{Key: "proxy_host", Encrypted: false},
 	{Key: "proxy_password", Encrypted: true},
@@ -99,6 +112,8 @@ var AllSettings = []SettingDef{
 	{Key: "rsshub_api_key", Encrypted: true},
 	{Key: "rsshub_enabled", Encrypted: false},
 	{Key: "rsshub_endpoint", Encrypted: false},
+	{Key: "rsshub_health_check_interval", Encrypted: false},
+	{Key: "rsshub_instances", Encrypted: true},
 	{Key: "rules", Encrypted: false},
 	{Key: "shortcuts", Encrypted: false},
 	{Key: "shortcuts_enabled", Encrypted: false},
@@ -120,6 +135,7 @@ var AllSettings = []SettingDef{
 	{Key: "translation_provider", Encrypted: false},
 	{Key: "update_check_enabled", Encrypted: false},
 	{Key: "update_interval", Encrypted: false},
+	{Key: "webpage_reader_safe", Encrypted: false},
 	{Key: "window_height", Encrypted: false},
 	{Key: "window_maximized", Encrypted: false},
 	{Key: "window_width", Encrypted: false},
@@ -170,3 +170,18 @@ func HandleResetStatistics(h *core.Handler, w http.ResponseWriter, r *http.Reque
 		"message": "All statistics have been reset successfully",
 	})
 }
+
+// HandleGetDatabaseStats returns queue and batching metrics of the database writer
+// @Summary Get database writer statistics
+// @Tags statistics
+// @Produce json
+// @Success 200 {object} database.WriterStats
+// @Router /api/statistics/database [get]
+func HandleGetDatabaseStats(h *core.Handler, w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		response.Error(w, fmt.Errorf("database not available"), http.StatusInternalServerError)
+		return
+	}
+
+	response.JSON(w, h.DB.WriterStats())
+}
@@ -2,6 +2,7 @@ package jsonimport
 
 import (
 	"MrRSS/internal/models"
+	"MrRSS/internal/opml"
 	"encoding/json"
 	"errors"
 	"io"
@@ -52,11 +53,17 @@ func Parse(r io.Reader) ([]models.Feed, error) {
 	return export.Feeds, nil
 }
 
-// Generate generates JSON export data from feeds
+// Generate generates JSON export data from feeds.
+// Secrets (IMAP passwords, proxy credentials) are removed.
 func Generate(feeds []models.Feed) ([]byte, error) {
+	sanitized := make([]models.Feed, 0, len(feeds))
+	for _, f := range feeds {
+		sanitized = append(sanitized, opml.SanitizeForExport(f))
+	}
+
 	export := FeedExport{
 		Version: ExportVersion,
-		Feeds:   feeds,
+		Feeds:   sanitized,
 	}
 
 	data, err := json.MarshalIndent(export, "", "  ")
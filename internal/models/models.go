@@ -3,23 +3,24 @@ package models
 import "time"
 
 type Feed struct {
-	ID                 int64     `json:"id"`
-	Title              string    `json:"title"`
-	URL                string    `json:"url"`
-	Link               string    `json:"link"` // Website homepage link
-	Description        string    `json:"description"`
-	Category           string    `json:"category"`
-	ImageURL           string    `json:"image_url"` // New field
-	Position           int       `json:"position"`  // Position within category for custom ordering
-	LastUpdated        time.Time `json:"last_updated"`
-	LastError          string    `json:"last_error,omitempty"`  // Track last fetch error
-	DiscoveryCompleted bool      `json:"discovery_completed"`   // Track if discovery has been run
-	ScriptPath         string    `json:"script_path,omitempty"` // Path to custom script for fetching feed
-	HideFromTimeline   bool      `json:"hide_from_timeline"`    // Hide articles from timeline views
-	ProxyURL           string    `json:"proxy_url,omitempty"`   // Custom proxy URL for this feed (overrides global)
-	ProxyEnabled       bool      `json:"proxy_enabled"`         // Whether to use proxy for this feed
-	RefreshInterval    int       `json:"refresh_interval"`      // Custom refresh interval in minutes (0 = use global, -1 = intelligent, -2 = never, >0 = custom minutes)
-	IsImageMode        bool      `json:"is_image_mode"`         // Whether this feed is for image gallery mode
+	ID                  int64     `json:"id"`
+	Title               string    `json:"title"`
+	URL                 string    `json:"url"`
+	Link                string    `json:"link"` // Website homepage link
+	Description         string    `json:"description"`
+	Category            string    `json:"category"`
+	ImageURL            string    `json:"image_url"` // New field
+	Position            int       `json:"position"`  // Position within category for custom ordering
+	LastUpdated         time.Time `json:"last_updated"`
+	LastError           string    `json:"last_error,omitempty"`  // Track last fetch error
+	DiscoveryCompleted  bool      `json:"discovery_completed"`   // Track if discovery has been run
+	ScriptPath          string    `json:"script_path,omitempty"` // Path to custom script for fetching feed
+	HideFromTimeline    bool      `json:"hide_from_timeline"`    // Hide articles from timeline views
+	ProxyURL            string    `json:"proxy_url,omitempty"`   // Custom proxy URL for this feed (overrides global)
+	ProxyEnabled        bool      `json:"proxy_enabled"`         // Whether to use proxy for this feed
+	RefreshInterval     int       `json:"refresh_interval"`      // Custom refresh interval in minutes (0 = use global, -1 = intelligent, -2 = never, >0 = custom minutes)
+	IsImageMode         bool      `json:"is_image_mode"`         // Whether this feed is for image gallery mode
+	AllowPrivateNetwork bool      `json:"allow_private_network"` // Whether fetches for this feed may reach private/LAN addresses
 	// XPath support for HTML/XML scraping
 	Type                string `json:"type"`                   // "HTML+XPath" or "XML+XPath"
 	XPathItem           string `json:"xpath_item"`             // XPath to extract feed items
@@ -106,3 +107,88 @@ type AIProfile struct {
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
+
+// FeedFetchRecord represents a single fetch attempt in a feed's fetch history
+type FeedFetchRecord struct {
+	ID         int64     `json:"id"`
+	FeedID     int64     `json:"feed_id"`
+	FetchedAt  time.Time `json:"fetched_at"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	ServedBy   string    `json:"served_by,omitempty"` // RSSHub instance that served the fetch, if any
+}
+
+// SubscriptionList is a remote OPML file whose feeds are kept in sync in a dedicated category
+type SubscriptionList struct {
+	ID              int64      `json:"id"`
+	URL             string     `json:"url"`
+	Title           string     `json:"title"`
+	Category        string     `json:"category"`         // Category the list's feeds are placed in
+	RefreshInterval int        `json:"refresh_interval"` // Minutes between syncs
+	LastSynced      *time.Time `json:"last_synced,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	FeedCount       int        `json:"feed_count"` // Feeds currently owned by the list
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// Retention policy scopes
+const (
+	RetentionScopeFeed     = "feed"
+	RetentionScopeCategory = "category"
+	RetentionScopeTag      = "tag"
+)
+
+// RetentionPolicy decides how long the articles of a feed, a category or the
+// feeds with a tag are kept, in place of the global cleanup rules
+type RetentionPolicy struct {
+	ID            int64     `json:"id"`
+	Scope         string    `json:"scope"` // "feed", "category" or "tag"
+	FeedID        int64     `json:"feed_id,omitempty"`
+	Category      string    `json:"category,omitempty"`
+	TagID         int64     `json:"tag_id,omitempty"`
+	KeepNewest    int       `json:"keep_newest"`  // Articles kept per feed, newest first; 0 for no limit
+	MaxAgeDays    int       `json:"max_age_days"` // Articles older than this are removed; 0 for no limit
+	KeepUnread    bool      `json:"keep_unread"`  // Never remove unread articles
+	KeepFavorites bool      `json:"keep_favorites"`
+	KeepReadLater bool      `json:"keep_read_later"`
+	Enabled       bool      `json:"enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// RetentionPreview reports what applying a retention policy would remove
+type RetentionPreview struct {
+	PolicyID int64   `json:"policy_id"`
+	Feeds    int     `json:"feeds"` // Feeds the policy governs
+	Articles int64   `json:"articles"`
+	SizeMB   float64 `json:"size_mb"` // Estimated size of the removed articles and their cached content
+}
+
+// SubscriptionListLogEntry records a change made while syncing a subscription list
+type SubscriptionListLogEntry struct {
+	ID        int64     `json:"id"`
+	ListID    int64     `json:"list_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Action    string    `json:"action"` // "added", "removed", "skipped" or "error"
+	FeedURL   string    `json:"feed_url,omitempty"`
+	FeedTitle string    `json:"feed_title,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// Article archive statuses
+const (
+	ArchiveStatusPending = "pending"
+	ArchiveStatusDone    = "done"
+	ArchiveStatusFailed  = "failed"
+)
+
+// ArticleArchive tracks the self-contained offline copy of an article
+type ArticleArchive struct {
+	ArticleID  int64      `json:"article_id"`
+	Status     string     `json:"status"` // ArchiveStatusPending, ArchiveStatusDone or ArchiveStatusFailed
+	Size       int64      `json:"size"`   // Bytes of the archived HTML file
+	Error      string     `json:"error,omitempty"`
+	Attempts   int        `json:"attempts"`
+	QueuedAt   time.Time  `json:"queued_at"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
@@ -0,0 +1,182 @@
+package opml
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strconv"
+	"strings"
+
+	"MrRSS/internal/models"
+	"MrRSS/internal/utils/urlutil"
+)
+
+// Namespace is the XML namespace of the MrRSS OPML extension.
+// Outlines carry per-feed settings as mrrss:* attributes so an export can be
+// imported on another machine without losing configuration. Secrets such as
+// IMAP passwords and proxy credentials are never written.
+const Namespace = "https://github.com/WCY-dt/MrRSS/opml"
+
+// namespacePrefix is the prefix bound to Namespace in generated documents
+const namespacePrefix = "mrrss"
+
+// Extension attribute names (local part, without the prefix)
+const (
+	attrScriptPath        = "scriptPath"
+	attrRefreshInterval   = "refreshInterval"
+	attrHideFromTimeline  = "hideFromTimeline"
+	attrIsImageMode       = "isImageMode"
+	attrProxyEnabled      = "proxyEnabled"
+	attrProxyURL          = "proxyUrl"
+	attrArticleViewMode   = "articleViewMode"
+	attrAutoExpandContent = "autoExpandContent"
+	attrImageURL          = "imageUrl"
+	attrEmailAddress      = "emailAddress"
+	attrEmailIMAPServer   = "emailImapServer"
+	attrEmailIMAPPort     = "emailImapPort"
+	attrEmailUsername     = "emailUsername"
+	attrEmailFolder       = "emailFolder"
+	attrTags              = "tags"
+)
+
+// exportedTag is the JSON form of a tag in the mrrss:tags attribute
+type exportedTag struct {
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+// extensionAttrs returns the mrrss:* attributes for a feed.
+// Attributes holding default values are omitted.
+func extensionAttrs(f models.Feed) []xml.Attr {
+	var attrs []xml.Attr
+	add := func(name, value string) {
+		if value != "" {
+			attrs = append(attrs, xml.Attr{Name: xml.Name{Local: namespacePrefix + ":" + name}, Value: value})
+		}
+	}
+	addBool := func(name string, value bool) {
+		if value {
+			add(name, "true")
+		}
+	}
+	addMode := func(name, value string) {
+		if value != "global" {
+			add(name, value)
+		}
+	}
+
+	add(attrScriptPath, f.ScriptPath)
+	if f.RefreshInterval != 0 {
+		add(attrRefreshInterval, strconv.Itoa(f.RefreshInterval))
+	}
+	addBool(attrHideFromTimeline, f.HideFromTimeline)
+	addBool(attrIsImageMode, f.IsImageMode)
+	addBool(attrProxyEnabled, f.ProxyEnabled)
+	add(attrProxyURL, urlutil.StripCredentials(f.ProxyURL))
+	addMode(attrArticleViewMode, f.ArticleViewMode)
+	addMode(attrAutoExpandContent, f.AutoExpandContent)
+	add(attrImageURL, f.ImageURL)
+
+	if f.Type == "email" {
+		add(attrEmailAddress, f.EmailAddress)
+		add(attrEmailIMAPServer, f.EmailIMAPServer)
+		if f.EmailIMAPPort != 0 {
+			add(attrEmailIMAPPort, strconv.Itoa(f.EmailIMAPPort))
+		}
+		add(attrEmailUsername, f.EmailUsername)
+		add(attrEmailFolder, f.EmailFolder)
+	}
+
+	if len(f.Tags) > 0 {
+		tags := make([]exportedTag, 0, len(f.Tags))
+		for _, t := range f.Tags {
+			tags = append(tags, exportedTag{Name: t.Name, Color: t.Color})
+		}
+		if data, err := json.Marshal(tags); err == nil {
+			add(attrTags, string(data))
+		}
+	}
+
+	return attrs
+}
+
+// isExtensionAttr reports whether attr belongs to the MrRSS namespace.
+// Documents that use the prefix without declaring it are accepted too.
+func isExtensionAttr(attr xml.Attr) bool {
+	return attr.Name.Space == Namespace || attr.Name.Space == namespacePrefix
+}
+
+// declaresNamespace reports whether the document root binds the MrRSS namespace
+func declaresNamespace(attrs []xml.Attr) bool {
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" && attr.Value == Namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// applyExtensionAttrs copies mrrss:* attributes onto a feed.
+// It reports whether any extension attribute was present.
+func applyExtensionAttrs(f *models.Feed, attrs []xml.Attr) bool {
+	found := false
+	for _, attr := range attrs {
+		if !isExtensionAttr(attr) {
+			continue
+		}
+		found = true
+		value := strings.TrimSpace(attr.Value)
+
+		switch attr.Name.Local {
+		case attrScriptPath:
+			f.ScriptPath = value
+		case attrRefreshInterval:
+			f.RefreshInterval, _ = strconv.Atoi(value)
+		case attrHideFromTimeline:
+			f.HideFromTimeline = value == "true"
+		case attrIsImageMode:
+			f.IsImageMode = value == "true"
+		case attrProxyEnabled:
+			f.ProxyEnabled = value == "true"
+		case attrProxyURL:
+			f.ProxyURL = value
+		case attrArticleViewMode:
+			f.ArticleViewMode = value
+		case attrAutoExpandContent:
+			f.AutoExpandContent = value
+		case attrImageURL:
+			f.ImageURL = value
+		case attrEmailAddress:
+			f.EmailAddress = value
+		case attrEmailIMAPServer:
+			f.EmailIMAPServer = value
+		case attrEmailIMAPPort:
+			f.EmailIMAPPort, _ = strconv.Atoi(value)
+		case attrEmailUsername:
+			f.EmailUsername = value
+		case attrEmailFolder:
+			f.EmailFolder = value
+		case attrTags:
+			var tags []exportedTag
+			if err := json.Unmarshal([]byte(value), &tags); err == nil {
+				for _, t := range tags {
+					if t.Name == "" {
+						continue
+					}
+					if t.Color == "" {
+						t.Color = defaultTagColor
+					}
+					f.Tags = append(f.Tags, models.Tag{Name: t.Name, Color: t.Color})
+				}
+			}
+		}
+	}
+	return found
+}
+
+// SanitizeForExport returns a copy of a feed with secrets removed, for exports
+// that leave the machine (OPML, JSON, backups).
+func SanitizeForExport(f models.Feed) models.Feed {
+	f.EmailPassword = ""
+	f.ProxyURL = urlutil.StripCredentials(f.ProxyURL)
+	return f
+}
@@ -13,10 +13,12 @@ import (
 )
 
 type OPML struct {
-	XMLName xml.Name `xml:"opml"`
-	Version string   `xml:"version,attr"`
-	Head    Head     `xml:"head"`
-	Body    Body     `xml:"body"`
+	XMLName xml.Name   `xml:"opml"`
+	Version string     `xml:"version,attr"`
+	XMLNS   string     `xml:"xmlns:mrrss,attr,omitempty"` // Binds the MrRSS extension namespace on export
+	Attrs   []xml.Attr `xml:",any,attr"`                  // Namespace declarations seen on import
+	Head    Head       `xml:"head"`
+	Body    Body       `xml:"body"`
 }
 
 type Head struct {
@@ -52,6 +54,19 @@ type Outline struct {
 	XPathItemThumbnail  string `xml:"xPathItemThumbnail,attr"`
 	XPathItemCategories string `xml:"xPathItemCategories,attr"`
 	XPathItemUid        string `xml:"xPathItemUid,attr"`
+	// MrRSS extension attributes (mrrss:*) and any other unknown attributes
+	Extra []xml.Attr `xml:",any,attr"`
+}
+
+// defaultTagColor is used for imported tags without a color
+const defaultTagColor = "#3B82F6"
+
+// Entry is a feed read from an OPML document
+type Entry struct {
+	Feed models.Feed
+	// HasSettings is true when the entry comes from an MrRSS export, so
+	// missing settings mean defaults rather than "not specified"
+	HasSettings bool
 }
 
 // normalizeOPMLAttributes normalizes attribute names in OPML content to handle
@@ -77,7 +92,24 @@ func normalizeOPMLAttributes(content []byte) []byte {
 	return result
 }
 
+// Parse parses an OPML document and returns the feeds it lists.
+// Folders become "a/b/c" categories and mrrss:* settings are applied.
 func Parse(r io.Reader) ([]models.Feed, error) {
+	entries, err := ParseEntries(r)
+	if err != nil {
+		return nil, err
+	}
+
+	feeds := make([]models.Feed, 0, len(entries))
+	for _, e := range entries {
+		feeds = append(feeds, e.Feed)
+	}
+	return feeds, nil
+}
+
+// ParseEntries parses an OPML document like Parse, additionally reporting
+// for each feed whether it carries MrRSS per-feed settings.
+func ParseEntries(r io.Reader) ([]Entry, error) {
 	// Read all content to handle BOM
 	content, err := io.ReadAll(r)
 	if err != nil {
@@ -117,12 +149,18 @@ func Parse(r io.Reader) ([]models.Feed, error) {
 		feeds := fallbackParse(content)
 		if len(feeds) > 0 {
 			utils.DebugLog("OPML Parse: Fallback parsing found %d feeds", len(feeds))
-			return feeds, nil
+			entries := make([]Entry, 0, len(feeds))
+			for _, f := range feeds {
+				entries = append(entries, Entry{Feed: f})
+			}
+			return entries, nil
 		}
 		return nil, err
 	}
 
-	var feeds []models.Feed
+	fromMrRSS := declaresNamespace(doc.Attrs)
+
+	var entries []Entry
 	var extract func([]*Outline, string)
 	extract = func(outlines []*Outline, category string) {
 		for i := 0; i < len(outlines); i++ {
@@ -178,7 +216,7 @@ func Parse(r io.Reader) ([]models.Feed, error) {
 						if tagName != "" {
 							tags = append(tags, models.Tag{
 								Name:  tagName,
-								Color: "#3B82F6", // Default color for imported tags
+								Color: defaultTagColor,
 							})
 						}
 						i = j // Skip the tag outline in the main loop
@@ -187,11 +225,12 @@ func Parse(r io.Reader) ([]models.Feed, error) {
 					}
 				}
 
-				feeds = append(feeds, models.Feed{
-					Title:    title,
-					URL:      xmlURL,
-					Category: feedCategory,
-					Tags:     tags,
+				feed := models.Feed{
+					Title:       title,
+					URL:         xmlURL,
+					Link:        strings.TrimSpace(o.HTMLURL),
+					Description: strings.TrimSpace(o.Description),
+					Category:    feedCategory,
 					// XPath support
 					Type:                o.Type,
 					XPathItem:           o.XPathItem,
@@ -204,7 +243,14 @@ func Parse(r io.Reader) ([]models.Feed, error) {
 					XPathItemThumbnail:  o.XPathItemThumbnail,
 					XPathItemCategories: o.XPathItemCategories,
 					XPathItemUid:        o.XPathItemUid,
-				})
+				}
+
+				hasSettings := applyExtensionAttrs(&feed, o.Extra)
+				// Legacy exports list tags as sibling outlines instead of mrrss:tags
+				if len(feed.Tags) == 0 {
+					feed.Tags = tags
+				}
+				entries = append(entries, Entry{Feed: feed, HasSettings: fromMrRSS || hasSettings})
 			}
 
 			newCategory := category
@@ -233,8 +279,8 @@ func Parse(r io.Reader) ([]models.Feed, error) {
 	}
 	extract(doc.Body.Outlines, "")
 
-	utils.DebugLog("OPML Parse: Found %d feeds", len(feeds))
-	return feeds, nil
+	utils.DebugLog("OPML Parse: Found %d feeds", len(entries))
+	return entries, nil
 }
 
 // fixCommonXMLIssues attempts to fix common XML formatting issues
@@ -284,9 +330,12 @@ func fallbackParse(content []byte) []models.Feed {
 	return feeds
 }
 
+// Generate writes feeds as an OPML document. Categories become nested folders
+// and non-secret per-feed settings are written as mrrss:* attributes.
 func Generate(feeds []models.Feed) ([]byte, error) {
 	doc := OPML{
 		Version: "1.0",
+		XMLNS:   Namespace,
 		Head: Head{
 			Title: "MrRSS Subscriptions",
 		},
@@ -321,10 +370,12 @@ func Generate(feeds []models.Feed) ([]byte, error) {
 		}
 
 		*currentOutlines = append(*currentOutlines, &Outline{
-			Text:   f.Title,
-			Title:  f.Title,
-			Type:   f.Type,
-			XMLURL: f.URL,
+			Text:        f.Title,
+			Title:       f.Title,
+			Type:        f.Type,
+			XMLURL:      f.URL,
+			HTMLURL:     f.Link,
+			Description: f.Description,
 			// XPath support
 			XPathItem:           f.XPathItem,
 			XPathItemTitle:      f.XPathItemTitle,
@@ -336,17 +387,8 @@ func Generate(feeds []models.Feed) ([]byte, error) {
 			XPathItemThumbnail:  f.XPathItemThumbnail,
 			XPathItemCategories: f.XPathItemCategories,
 			XPathItemUid:        f.XPathItemUid,
+			Extra:               extensionAttrs(SanitizeForExport(f)),
 		})
-
-		// Add tags as additional outline elements with "tag:" prefix in category
-		for _, tag := range f.Tags {
-			*currentOutlines = append(*currentOutlines, &Outline{
-				Text:     tag.Name,
-				Title:    tag.Name,
-				Type:     "tag",
-				Category: "tag:" + tag.Name,
-			})
-		}
 	}
 
 	return xml.MarshalIndent(doc, "", "  ")
@@ -0,0 +1,207 @@
+package opml
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"MrRSS/internal/models"
+)
+
+// ImportAction is what an import will do with an entry
+type ImportAction string
+
+const (
+	// ImportAdd subscribes to a feed that does not exist yet
+	ImportAdd ImportAction = "add"
+	// ImportUpdate changes the settings of an existing subscription
+	ImportUpdate ImportAction = "update"
+	// ImportConflict marks an entry that cannot be applied safely
+	ImportConflict ImportAction = "conflict"
+	// ImportSkip marks an entry that is invalid or already up to date
+	ImportSkip ImportAction = "skip"
+)
+
+// ImportPlanEntry describes the planned outcome for one imported feed
+type ImportPlanEntry struct {
+	Action     ImportAction `json:"action"`
+	Title      string       `json:"title"`
+	URL        string       `json:"url"`
+	Category   string       `json:"category"`
+	ExistingID int64        `json:"existing_id,omitempty"`
+	Changes    []string     `json:"changes,omitempty"` // Settings that differ from the existing feed
+	Reason     string       `json:"reason,omitempty"`
+
+	Feed        models.Feed `json:"-"`
+	HasSettings bool        `json:"-"`
+}
+
+// ImportPlan is the result of comparing an import file with the current subscriptions
+type ImportPlan struct {
+	Adds      int               `json:"adds"`
+	Updates   int               `json:"updates"`
+	Conflicts int               `json:"conflicts"`
+	Skipped   int               `json:"skipped"`
+	Entries   []ImportPlanEntry `json:"entries"`
+}
+
+// PlanImport decides, without changing anything, how each entry would be imported.
+// Entries are matched to existing local feeds by URL. For entries without MrRSS
+// settings only the title and category are compared, so importing a plain OPML
+// never resets settings that the file does not know about.
+func PlanImport(entries []Entry, existing []models.Feed) ImportPlan {
+	local := make(map[string]models.Feed, len(existing))
+	freshRSS := make(map[string]bool)
+	for _, f := range existing {
+		if f.IsFreshRSSSource {
+			freshRSS[f.URL] = true
+		} else {
+			local[f.URL] = f
+		}
+	}
+
+	plan := ImportPlan{Entries: make([]ImportPlanEntry, 0, len(entries))}
+	seen := make(map[string]Entry, len(entries))
+
+	for _, e := range entries {
+		f := e.Feed
+		f.URL = strings.TrimSpace(f.URL)
+		entry := ImportPlanEntry{
+			Title:       f.Title,
+			URL:         f.URL,
+			Category:    f.Category,
+			Feed:        f,
+			HasSettings: e.HasSettings,
+		}
+
+		if prev, dup := seen[f.URL]; dup {
+			if len(diffFeedSettings(prev.Feed, f, true)) == 0 {
+				entry.Action = ImportSkip
+				entry.Reason = "duplicate entry in the import file"
+			} else {
+				entry.Action = ImportConflict
+				entry.Reason = "listed more than once with different settings"
+			}
+			plan.add(entry)
+			continue
+		}
+
+		switch existingFeed, ok := local[f.URL]; {
+		case f.URL == "":
+			entry.Action = ImportSkip
+			entry.Reason = "missing feed URL"
+		case ok:
+			entry.ExistingID = existingFeed.ID
+			entry.Changes = diffFeedSettings(existingFeed, f, e.HasSettings)
+			if len(entry.Changes) == 0 {
+				entry.Action = ImportSkip
+				entry.Reason = "already subscribed with the same settings"
+			} else {
+				entry.Action = ImportUpdate
+			}
+		case freshRSS[f.URL]:
+			entry.Action = ImportConflict
+			entry.Reason = "already subscribed through FreshRSS sync"
+		default:
+			entry.Action = ImportAdd
+		}
+
+		if f.URL != "" {
+			seen[f.URL] = e
+		}
+		plan.add(entry)
+	}
+
+	return plan
+}
+
+// add appends an entry and updates the counters
+func (p *ImportPlan) add(entry ImportPlanEntry) {
+	switch entry.Action {
+	case ImportAdd:
+		p.Adds++
+	case ImportUpdate:
+		p.Updates++
+	case ImportConflict:
+		p.Conflicts++
+	case ImportSkip:
+		p.Skipped++
+	}
+	p.Entries = append(p.Entries, entry)
+}
+
+// diffFeedSettings lists the importable settings that differ between two feeds.
+// When withSettings is false only the title and category are compared. Tags
+// are compared by name, ignoring case, when the incoming feed has any.
+func diffFeedSettings(current, incoming models.Feed, withSettings bool) []string {
+	var changes []string
+	diff := func(name, a, b string) {
+		if a != b {
+			changes = append(changes, name)
+		}
+	}
+	mode := func(v string) string {
+		if v == "" {
+			return "global"
+		}
+		return v
+	}
+
+	diff("title", current.Title, incoming.Title)
+	diff("category", current.Category, incoming.Category)
+	if !withSettings {
+		return changes
+	}
+
+	diff("type", current.Type, incoming.Type)
+	diff("script_path", current.ScriptPath, incoming.ScriptPath)
+	diff("refresh_interval", strconv.Itoa(current.RefreshInterval), strconv.Itoa(incoming.RefreshInterval))
+	diff("hide_from_timeline", strconv.FormatBool(current.HideFromTimeline), strconv.FormatBool(incoming.HideFromTimeline))
+	diff("is_image_mode", strconv.FormatBool(current.IsImageMode), strconv.FormatBool(incoming.IsImageMode))
+	diff("proxy_enabled", strconv.FormatBool(current.ProxyEnabled), strconv.FormatBool(incoming.ProxyEnabled))
+	if incoming.ProxyURL != SanitizeForExport(current).ProxyURL {
+		changes = append(changes, "proxy_url")
+	}
+	diff("article_view_mode", mode(current.ArticleViewMode), mode(incoming.ArticleViewMode))
+	diff("auto_expand_content", mode(current.AutoExpandContent), mode(incoming.AutoExpandContent))
+	diff("xpath", xpathSignature(current), xpathSignature(incoming))
+	if incoming.Type == "email" {
+		diff("email", emailSignature(current), emailSignature(incoming))
+	}
+	if len(incoming.Tags) > 0 {
+		diff("tags", tagSignature(current), tagSignature(incoming))
+	}
+
+	return changes
+}
+
+// xpathSignature joins a feed's XPath expressions for comparison
+func xpathSignature(f models.Feed) string {
+	return strings.Join([]string{
+		f.XPathItem, f.XPathItemTitle, f.XPathItemContent, f.XPathItemUri,
+		f.XPathItemAuthor, f.XPathItemTimestamp, f.XPathItemTimeFormat,
+		f.XPathItemThumbnail, f.XPathItemCategories, f.XPathItemUid,
+	}, "\x00")
+}
+
+// emailSignature joins a feed's non-secret IMAP settings for comparison
+func emailSignature(f models.Feed) string {
+	return strings.Join([]string{
+		f.EmailAddress, f.EmailIMAPServer, strconv.Itoa(f.EmailIMAPPort), f.EmailUsername, f.EmailFolder,
+	}, "\x00")
+}
+
+// tagSignature joins a feed's lowercased tag names, sorted, for comparison
+func tagSignature(f models.Feed) string {
+	names := make([]string, 0, len(f.Tags))
+	seen := make(map[string]bool, len(f.Tags))
+	for _, t := range f.Tags {
+		name := strings.ToLower(t.Name)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, "\x00")
+}
@@ -0,0 +1,360 @@
+package readerimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// flexInt decodes a number that may be written as a JSON string
+type flexInt int64
+
+func (n *flexInt) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*n = flexInt(v)
+	return nil
+}
+
+// flexBool decodes a flag written as a boolean, a number or a string
+type flexBool bool
+
+func (f *flexBool) UnmarshalJSON(b []byte) error {
+	switch strings.Trim(string(b), `"`) {
+	case "true", "1":
+		*f = true
+	default:
+		*f = false
+	}
+	return nil
+}
+
+// flexTime decodes a timestamp in any of the layouts used by the exports
+type flexTime time.Time
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+func (t *flexTime) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	for _, layout := range timeLayouts {
+		if v, err := time.Parse(layout, s); err == nil {
+			*t = flexTime(v)
+			return nil
+		}
+	}
+	// Unknown layouts are treated as a missing time rather than failing the import
+	*t = flexTime(time.Time{})
+	return nil
+}
+
+// unixTime converts a Unix timestamp in seconds or milliseconds
+func unixTime(v int64) time.Time {
+	switch {
+	case v <= 0:
+		return time.Time{}
+	case v > 1e11:
+		return time.UnixMilli(v).UTC()
+	default:
+		return time.Unix(v, 0).UTC()
+	}
+}
+
+// decodeList decodes a top-level array, or an array under key in a top-level object
+func decodeList(data []byte, key string, v interface{}) error {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) > 0 && data[0] == '{' {
+		var wrapper map[string]json.RawMessage
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return err
+		}
+		data = wrapper[key]
+		if data == nil {
+			return nil
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// greaderItem is an item of the Google Reader API, as exported by Google
+// Reader, FreshRSS and Inoreader. Feedly uses the same shape with
+// millisecond timestamps and a few extra fields.
+type greaderItem struct {
+	Title           string          `json:"title"`
+	Published       flexInt         `json:"published"`
+	CrawlTimeMsec   flexInt         `json:"crawlTimeMsec"`
+	Crawled         flexInt         `json:"crawled"`         // Feedly
+	ActionTimestamp flexInt         `json:"actionTimestamp"` // Feedly: when the item was saved
+	Canonical       []greaderLink   `json:"canonical"`
+	Alternate       []greaderLink   `json:"alternate"`
+	CanonicalURL    string          `json:"canonicalUrl"` // Feedly
+	Summary         *greaderText    `json:"summary"`
+	Content         *greaderText    `json:"content"`
+	Author          string          `json:"author"`
+	Categories      json.RawMessage `json:"categories"`
+	Unread          *bool           `json:"unread"` // Feedly
+	Visual          struct {
+		URL string `json:"url"`
+	} `json:"visual"` // Feedly
+	Origin struct {
+		StreamID string `json:"streamId"`
+		Title    string `json:"title"`
+		HTMLURL  string `json:"htmlUrl"`
+	} `json:"origin"`
+}
+
+type greaderLink struct {
+	Href string `json:"href"`
+}
+
+type greaderText struct {
+	Content string `json:"content"`
+}
+
+// parseGoogleReader parses Google Reader style items. Starred exports become
+// favorites; Feedly saved-for-later exports become read-later items.
+func parseGoogleReader(data []byte, starred bool) ([]Item, error) {
+	var raw []greaderItem
+	if err := decodeList(data, "items", &raw); err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(raw))
+	for _, r := range raw {
+		item := Item{
+			Title:     r.Title,
+			Author:    r.Author,
+			FeedTitle: r.Origin.Title,
+			SiteURL:   r.Origin.HTMLURL,
+			ImageURL:  r.Visual.URL,
+		}
+		if r.Visual.URL == "none" {
+			item.ImageURL = ""
+		}
+
+		// Stream IDs look like "feed/https://example.com/feed"
+		if feedURL := strings.TrimPrefix(r.Origin.StreamID, "feed/"); strings.Contains(feedURL, "://") {
+			item.FeedURL = feedURL
+		}
+
+		switch {
+		case r.CanonicalURL != "":
+			item.URL = r.CanonicalURL
+		case len(r.Canonical) > 0:
+			item.URL = r.Canonical[0].Href
+		case len(r.Alternate) > 0:
+			item.URL = r.Alternate[0].Href
+		}
+
+		if r.Content != nil {
+			item.Content = r.Content.Content
+		}
+		if r.Summary != nil {
+			item.Summary = r.Summary.Content
+		}
+
+		for _, ts := range []flexInt{r.Published, r.ActionTimestamp, r.Crawled, r.CrawlTimeMsec} {
+			if t := unixTime(int64(ts)); !t.IsZero() {
+				item.PublishedAt = t
+				break
+			}
+		}
+
+		if starred {
+			item.IsFavorite = true
+			var categories []string
+			if json.Unmarshal(r.Categories, &categories) == nil {
+				for _, c := range categories {
+					if strings.HasSuffix(c, "/state/com.google/read") {
+						item.IsRead = true
+					}
+				}
+			}
+		} else {
+			item.IsReadLater = true
+			item.IsRead = r.Unread != nil && !*r.Unread
+		}
+
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// minifluxEntry is an entry of the Miniflux API
+type minifluxEntry struct {
+	Title       string   `json:"title"`
+	URL         string   `json:"url"`
+	Content     string   `json:"content"`
+	Author      string   `json:"author"`
+	Status      string   `json:"status"`
+	Starred     bool     `json:"starred"`
+	PublishedAt flexTime `json:"published_at"`
+	CreatedAt   flexTime `json:"created_at"`
+	Feed        struct {
+		FeedURL string `json:"feed_url"`
+		SiteURL string `json:"site_url"`
+		Title   string `json:"title"`
+	} `json:"feed"`
+}
+
+// parseMiniflux parses Miniflux entries, either the API response object
+// with an "entries" list or a plain list of entries
+func parseMiniflux(data []byte) ([]Item, error) {
+	var raw []minifluxEntry
+	if err := decodeList(data, "entries", &raw); err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(raw))
+	for _, r := range raw {
+		published := time.Time(r.PublishedAt)
+		if published.IsZero() {
+			published = time.Time(r.CreatedAt)
+		}
+		items = append(items, Item{
+			FeedURL:     r.Feed.FeedURL,
+			FeedTitle:   r.Feed.Title,
+			SiteURL:     r.Feed.SiteURL,
+			Title:       r.Title,
+			URL:         r.URL,
+			Content:     r.Content,
+			Author:      r.Author,
+			PublishedAt: published,
+			IsRead:      r.Status == "read",
+			IsFavorite:  r.Starred,
+		})
+	}
+	return items, nil
+}
+
+// parsePocket parses Pocket's HTML export, a list of links under an
+// "Unread" and a "Read Archive" heading
+func parsePocket(data []byte) ([]Item, error) {
+	z := html.NewTokenizer(bytes.NewReader(data))
+	items := make([]Item, 0)
+	archived := false
+	var heading strings.Builder
+	var inHeading bool
+	var current *Item
+	var title strings.Builder
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return nil, err
+			}
+			return items, nil
+
+		case html.StartTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "h1":
+				inHeading = true
+				heading.Reset()
+			case "a":
+				item := Item{}
+				for _, attr := range tok.Attr {
+					switch attr.Key {
+					case "href":
+						item.URL = attr.Val
+					case "time_added":
+						if v, err := strconv.ParseInt(attr.Val, 10, 64); err == nil {
+							item.PublishedAt = unixTime(v)
+						}
+					}
+				}
+				current = &item
+				title.Reset()
+			}
+
+		case html.TextToken:
+			if inHeading {
+				heading.Write(z.Text())
+			} else if current != nil {
+				title.Write(z.Text())
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "h1":
+				inHeading = false
+				archived = strings.Contains(strings.ToLower(heading.String()), "archive")
+			case "a":
+				if current != nil && current.URL != "" {
+					current.Title = strings.TrimSpace(title.String())
+					if current.Title == "" {
+						current.Title = current.URL
+					}
+					current.IsRead = archived
+					current.IsReadLater = !archived
+					items = append(items, *current)
+				}
+				current = nil
+			}
+		}
+	}
+}
+
+// wallabagEntry is an entry of Wallabag's JSON export
+type wallabagEntry struct {
+	Title          string   `json:"title"`
+	URL            string   `json:"url"`
+	Content        string   `json:"content"`
+	IsArchived     flexBool `json:"is_archived"`
+	IsStarred      flexBool `json:"is_starred"`
+	CreatedAt      flexTime `json:"created_at"`
+	PublishedAt    flexTime `json:"published_at"`
+	PublishedBy    []string `json:"published_by"`
+	PreviewPicture string   `json:"preview_picture"`
+}
+
+// parseWallabag parses Wallabag's JSON export
+func parseWallabag(data []byte) ([]Item, error) {
+	var raw []wallabagEntry
+	if err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &raw); err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(raw))
+	for _, r := range raw {
+		published := time.Time(r.PublishedAt)
+		if published.IsZero() {
+			published = time.Time(r.CreatedAt)
+		}
+		title := r.Title
+		if title == "" {
+			title = r.URL
+		}
+		items = append(items, Item{
+			Title:       title,
+			URL:         r.URL,
+			Content:     r.Content,
+			Author:      strings.Join(r.PublishedBy, ", "),
+			ImageURL:    r.PreviewPicture,
+			PublishedAt: published,
+			IsRead:      bool(r.IsArchived),
+			IsFavorite:  bool(r.IsStarred),
+			IsReadLater: !bool(r.IsArchived),
+		})
+	}
+	return items, nil
+}
@@ -0,0 +1,169 @@
+package readerimport
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"MrRSS/internal/database"
+	"MrRSS/internal/models"
+	"MrRSS/internal/utils/textutil"
+	"MrRSS/internal/utils/urlutil"
+)
+
+// The "Imported" pseudo-feed holds items whose feed is not subscribed and
+// saved links that never had a feed. It is never refreshed.
+const (
+	ImportedFeedURL   = "mrrss://imported"
+	ImportedFeedType  = "imported"
+	ImportedFeedTitle = "Imported"
+)
+
+// maxErrors limits the errors kept in a Progress
+const maxErrors = 20
+
+// Progress reports how far an import has come. The final Progress is the
+// import result.
+type Progress struct {
+	Total          int      `json:"total"`
+	Processed      int      `json:"processed"`
+	InOriginalFeed int      `json:"in_original_feed"` // Items stored in a subscribed feed
+	InImportedFeed int      `json:"in_imported_feed"` // Items stored in the Imported pseudo-feed
+	Skipped        int      `json:"skipped"`          // Items without a link or title
+	Failed         int      `json:"failed"`
+	Errors         []string `json:"errors,omitempty"`
+}
+
+// Import stores items as articles, in their original feed when it is
+// subscribed and in the Imported pseudo-feed otherwise. Items already in
+// the database keep their state and gain the imported flags, so importing
+// the same export twice is harmless. onProgress, if set, is called after
+// every item.
+func Import(db *database.DB, items []Item, onProgress func(Progress)) (*Progress, error) {
+	progress := &Progress{Total: len(items)}
+
+	feeds, err := db.GetFeeds()
+	if err != nil {
+		return nil, fmt.Errorf("read feeds: %w", err)
+	}
+	byURL := make(map[string]int64)
+	bySite := make(map[string]int64)
+	var importedFeedID int64
+	for _, f := range feeds {
+		if f.IsFreshRSSSource {
+			continue
+		}
+		if f.URL == ImportedFeedURL {
+			importedFeedID = f.ID
+			continue
+		}
+		byURL[normalizeURL(f.URL)] = f.ID
+		if f.Link != "" {
+			bySite[normalizeURL(f.Link)] = f.ID
+		}
+	}
+
+	for _, item := range items {
+		if err := importItem(db, item, byURL, bySite, &importedFeedID, progress); err != nil {
+			progress.Failed++
+			if len(progress.Errors) < maxErrors {
+				progress.Errors = append(progress.Errors, fmt.Sprintf("%s: %v", item.URL, err))
+			}
+		}
+		progress.Processed++
+		if onProgress != nil {
+			onProgress(*progress)
+		}
+	}
+	return progress, nil
+}
+
+// importItem stores a single item and counts it in progress
+func importItem(db *database.DB, item Item, byURL, bySite map[string]int64, importedFeedID *int64, progress *Progress) error {
+	if item.URL == "" && item.Title == "" {
+		progress.Skipped++
+		return nil
+	}
+	if item.Title == "" {
+		item.Title = item.URL
+	}
+
+	var feedID int64
+	var ok bool
+	if item.FeedURL != "" {
+		feedID, ok = byURL[normalizeURL(item.FeedURL)]
+	}
+	if !ok && item.SiteURL != "" {
+		feedID, ok = bySite[normalizeURL(item.SiteURL)]
+	}
+	if !ok {
+		if *importedFeedID == 0 {
+			id, err := db.GetOrCreateBuiltinFeed(&models.Feed{
+				Title:           ImportedFeedTitle,
+				URL:             ImportedFeedURL,
+				Type:            ImportedFeedType,
+				Description:     "Items imported from other readers and read-it-later services",
+				RefreshInterval: -2,
+			})
+			if err != nil {
+				return fmt.Errorf("create imported feed: %w", err)
+			}
+			*importedFeedID = id
+		}
+		feedID = *importedFeedID
+	}
+
+	hasValidTime := !item.PublishedAt.IsZero()
+	publishedAt := item.PublishedAt
+	if !hasValidTime {
+		publishedAt = time.Now()
+	}
+
+	article := &models.Article{
+		FeedID:          feedID,
+		Title:           item.Title,
+		URL:             item.URL,
+		ImageURL:        item.ImageURL,
+		PublishedAt:     publishedAt,
+		IsRead:          item.IsRead,
+		IsFavorite:      item.IsFavorite,
+		IsReadLater:     item.IsReadLater,
+		Author:          item.Author,
+		OriginalSummary: item.Summary,
+		UniqueID:        urlutil.GenerateArticleUniqueID(item.Title, feedID, publishedAt, hasValidTime),
+	}
+	articleID, err := db.RestoreArticle(article)
+	if err != nil {
+		return err
+	}
+
+	// Cache the exported content unless the article already has some, since
+	// the Imported feed cannot be fetched for it later
+	content := item.Content
+	if content == "" {
+		content = item.Summary
+	}
+	if content != "" {
+		if _, cached, err := db.GetArticleContent(articleID); err == nil && !cached {
+			if err := db.SetArticleContent(articleID, textutil.CleanHTML(content)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if feedID == *importedFeedID {
+		progress.InImportedFeed++
+	} else {
+		progress.InOriginalFeed++
+	}
+	return nil
+}
+
+// normalizeURL makes feed URLs comparable across exports
+func normalizeURL(u string) string {
+	u = strings.TrimSpace(strings.ToLower(u))
+	u = strings.TrimPrefix(u, "https://")
+	u = strings.TrimPrefix(u, "http://")
+	u = strings.TrimPrefix(u, "www.")
+	return strings.TrimSuffix(u, "/")
+}
@@ -0,0 +1,138 @@
+// Package readerimport imports starred and saved items exported from other
+// feed readers and read-it-later services.
+//
+// Supported exports are the Google Reader starred-items JSON (also written
+// by FreshRSS and Inoreader), Feedly's saved-for-later JSON, Miniflux entries,
+// Pocket's HTML export and Wallabag's JSON export. Every format is parsed into
+// a list of Items, which Import stores as articles.
+package readerimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Format names an export format
+type Format string
+
+// Supported export formats
+const (
+	FormatGoogleReader Format = "google-reader" // Google Reader, FreshRSS and Inoreader starred items
+	FormatFeedly       Format = "feedly"        // Feedly saved for later
+	FormatMiniflux     Format = "miniflux"      // Miniflux entries
+	FormatPocket       Format = "pocket"        // Pocket HTML export
+	FormatWallabag     Format = "wallabag"      // Wallabag JSON export
+)
+
+// ErrUnknownFormat is returned when an export cannot be recognized
+var ErrUnknownFormat = errors.New("unrecognized export format")
+
+// Item is a starred or saved item read from an export
+type Item struct {
+	FeedURL     string    // URL of the feed the item came from, empty for saved links
+	FeedTitle   string    // Title of that feed
+	SiteURL     string    // Homepage of that feed
+	Title       string    // Item title
+	URL         string    // Link to the item
+	Content     string    // HTML content, if the export carries it
+	Summary     string    // Short description, if the export carries it
+	Author      string    // Item author
+	ImageURL    string    // Preview image
+	PublishedAt time.Time // Original publication (or save) time; zero when unknown
+	IsRead      bool
+	IsFavorite  bool
+	IsReadLater bool
+}
+
+// Parse reads an export. When format is empty it is detected from the data.
+// It returns the items and the format that was used.
+func Parse(data []byte, format Format) ([]Item, Format, error) {
+	if format == "" {
+		format = Detect(data)
+		if format == "" {
+			return nil, "", ErrUnknownFormat
+		}
+	}
+
+	var items []Item
+	var err error
+	switch format {
+	case FormatGoogleReader:
+		items, err = parseGoogleReader(data, true)
+	case FormatFeedly:
+		items, err = parseGoogleReader(data, false)
+	case FormatMiniflux:
+		items, err = parseMiniflux(data)
+	case FormatPocket:
+		items, err = parsePocket(data)
+	case FormatWallabag:
+		items, err = parseWallabag(data)
+	default:
+		return nil, format, fmt.Errorf("unsupported format: %s", format)
+	}
+	if err != nil {
+		return nil, format, fmt.Errorf("parse %s export: %w", format, err)
+	}
+	return items, format, nil
+}
+
+// Detect guesses the format of an export from its structure.
+// It returns an empty Format when the data matches none of them.
+func Detect(data []byte) Format {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return ""
+	}
+
+	switch data[0] {
+	case '<':
+		if bytes.Contains(bytes.ToLower(data), []byte("<a ")) {
+			return FormatPocket
+		}
+	case '{':
+		var probe struct {
+			Items   json.RawMessage `json:"items"`
+			Entries json.RawMessage `json:"entries"`
+		}
+		if json.Unmarshal(data, &probe) != nil {
+			return ""
+		}
+		switch {
+		case probe.Entries != nil:
+			return FormatMiniflux
+		case probe.Items != nil:
+			return FormatGoogleReader
+		}
+	case '[':
+		var probe []map[string]json.RawMessage
+		if json.Unmarshal(data, &probe) != nil {
+			return ""
+		}
+		if len(probe) == 0 {
+			return ""
+		}
+		first := probe[0]
+		switch {
+		case has(first, "is_starred", "is_archived"):
+			return FormatWallabag
+		case has(first, "feed", "starred", "status"):
+			return FormatMiniflux
+		case has(first, "origin", "alternate", "canonicalUrl"):
+			return FormatFeedly
+		}
+	}
+	return ""
+}
+
+// has reports whether the object has any of the keys
+func has(obj map[string]json.RawMessage, keys ...string) bool {
+	for _, k := range keys {
+		if _, ok := obj[k]; ok {
+			return true
+		}
+	}
+	return false
+}
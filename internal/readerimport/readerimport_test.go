@@ -0,0 +1,223 @@
+package readerimport
+
+import (
+	"testing"
+	"time"
+
+	"MrRSS/internal/database"
+	"MrRSS/internal/models"
+)
+
+const googleReaderExport = `{
+	"id": "user/-/state/com.google/starred",
+	"items": [{
+		"title": "Starred post",
+		"published": 1700000000,
+		"canonical": [{"href": "https://blog.example.com/starred"}],
+		"summary": {"content": "<p>Summary</p>"},
+		"author": "Ann",
+		"categories": ["user/-/state/com.google/starred", "user/-/state/com.google/read"],
+		"origin": {"streamId": "feed/https://blog.example.com/feed", "title": "Blog", "htmlUrl": "https://blog.example.com/"}
+	}, {
+		"title": "Unknown feed post",
+		"published": 1600000000,
+		"alternate": [{"href": "https://other.example.com/post", "type": "text/html"}],
+		"content": {"content": "<p>Full text</p>"},
+		"origin": {"streamId": "feed/https://other.example.com/rss", "title": "Other"}
+	}]
+}`
+
+const feedlyExport = `[{
+	"title": "Saved for later",
+	"published": 1700000000000,
+	"actionTimestamp": 1700000500000,
+	"canonicalUrl": "https://blog.example.com/later",
+	"unread": false,
+	"origin": {"streamId": "feed/http://blog.example.com/feed/", "title": "Blog"}
+}]`
+
+const minifluxExport = `{"total": 2, "entries": [{
+	"title": "Starred entry",
+	"url": "https://blog.example.com/entry",
+	"published_at": "2023-11-14T22:13:20Z",
+	"status": "unread",
+	"starred": true,
+	"content": "<p>Entry</p>",
+	"feed": {"feed_url": "https://blog.example.com/feed", "site_url": "https://blog.example.com", "title": "Blog"}
+}, {
+	"title": "Read entry",
+	"url": "https://blog.example.com/read",
+	"published_at": "2023-11-14T22:13:20Z",
+	"status": "read",
+	"starred": false,
+	"feed": {"feed_url": "https://blog.example.com/feed", "title": "Blog"}
+}]}`
+
+const pocketExport = `<!DOCTYPE html>
+<html><head><title>Pocket Export</title></head><body>
+<h1>Unread</h1>
+<ul>
+<li><a href="https://news.example.com/a" time_added="1700000000" tags="go">Article A</a></li>
+</ul>
+<h1>Read Archive</h1>
+<ul>
+<li><a href="https://news.example.com/b" time_added="1600000000" tags="">Article B</a></li>
+</ul>
+</body></html>`
+
+const wallabagExport = `[{
+	"is_archived": 0,
+	"is_starred": 1,
+	"title": "Wallabag entry",
+	"url": "https://news.example.com/w",
+	"content": "<p>Saved</p>",
+	"created_at": "2023-11-14T23:13:20+0100",
+	"published_at": null,
+	"published_by": ["Bob"],
+	"tags": ["reading"]
+}]`
+
+func TestDetectAndParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format Format
+		check  func(t *testing.T, items []Item)
+	}{
+		{"google reader", googleReaderExport, FormatGoogleReader, func(t *testing.T, items []Item) {
+			if len(items) != 2 {
+				t.Fatalf("got %d items", len(items))
+			}
+			it := items[0]
+			if it.FeedURL != "https://blog.example.com/feed" || it.URL != "https://blog.example.com/starred" ||
+				!it.IsFavorite || !it.IsRead || it.Summary != "<p>Summary</p>" || it.PublishedAt.Unix() != 1700000000 {
+				t.Errorf("unexpected item: %+v", it)
+			}
+			if items[1].URL != "https://other.example.com/post" || items[1].IsRead {
+				t.Errorf("unexpected item: %+v", items[1])
+			}
+		}},
+		{"feedly", feedlyExport, FormatFeedly, func(t *testing.T, items []Item) {
+			if len(items) != 1 || !items[0].IsReadLater || items[0].IsFavorite || !items[0].IsRead ||
+				items[0].PublishedAt.Unix() != 1700000000 || items[0].URL != "https://blog.example.com/later" {
+				t.Errorf("unexpected items: %+v", items)
+			}
+		}},
+		{"miniflux", minifluxExport, FormatMiniflux, func(t *testing.T, items []Item) {
+			if len(items) != 2 || !items[0].IsFavorite || items[0].IsRead || !items[1].IsRead ||
+				items[0].PublishedAt.Unix() != 1700000000 {
+				t.Errorf("unexpected items: %+v", items)
+			}
+		}},
+		{"pocket", pocketExport, FormatPocket, func(t *testing.T, items []Item) {
+			if len(items) != 2 {
+				t.Fatalf("got %d items", len(items))
+			}
+			if items[0].Title != "Article A" || !items[0].IsReadLater || items[0].IsRead || items[0].PublishedAt.Unix() != 1700000000 {
+				t.Errorf("unexpected unread item: %+v", items[0])
+			}
+			if !items[1].IsRead || items[1].IsReadLater {
+				t.Errorf("unexpected archived item: %+v", items[1])
+			}
+		}},
+		{"wallabag", wallabagExport, FormatWallabag, func(t *testing.T, items []Item) {
+			if len(items) != 1 || !items[0].IsFavorite || !items[0].IsReadLater || items[0].Author != "Bob" ||
+				items[0].PublishedAt.Unix() != 1700000000 {
+				t.Errorf("unexpected items: %+v", items)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect([]byte(tt.data)); got != tt.format {
+				t.Fatalf("Detect() = %q, want %q", got, tt.format)
+			}
+			items, format, err := Parse([]byte(tt.data), "")
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			if format != tt.format {
+				t.Errorf("format = %q, want %q", format, tt.format)
+			}
+			tt.check(t, items)
+		})
+	}
+
+	if _, _, err := Parse([]byte(`{"feeds": []}`), ""); err != ErrUnknownFormat {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	db, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create db: %v", err)
+	}
+	defer db.Close()
+	if err := db.Init(); err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+
+	blogID, err := db.AddFeed(&models.Feed{Title: "Blog", URL: "https://blog.example.com/feed", Link: "https://blog.example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, _, err := Parse([]byte(googleReaderExport), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var updates int
+	result, err := Import(db, items, func(Progress) { updates++ })
+	if err != nil {
+		t.Fatalf("Import error: %v", err)
+	}
+	if result.Processed != 2 || result.InOriginalFeed != 1 || result.InImportedFeed != 1 || result.Failed != 0 || updates != 2 {
+		t.Fatalf("unexpected result: %+v (updates %d)", result, updates)
+	}
+
+	articles, err := db.GetArticlesForBackup(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(articles) != 2 {
+		t.Fatalf("expected 2 articles, got %d", len(articles))
+	}
+	var importedFeedID int64
+	for _, a := range articles {
+		if !a.IsFavorite {
+			t.Errorf("article %q lost its favorite flag", a.Title)
+		}
+		switch a.URL {
+		case "https://blog.example.com/starred":
+			if a.FeedID != blogID || !a.PublishedAt.Equal(time.Unix(1700000000, 0)) {
+				t.Errorf("starred article not in its feed with its timestamp: %+v", a)
+			}
+		case "https://other.example.com/post":
+			importedFeedID = a.FeedID
+			if content, ok, _ := db.GetArticleContent(a.ID); !ok || content == "" {
+				t.Error("imported article content not cached")
+			}
+		}
+	}
+
+	imported, err := db.GetFeedByID(importedFeedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.URL != ImportedFeedURL || imported.RefreshInterval != -2 || imported.Type != ImportedFeedType {
+		t.Errorf("unexpected imported feed: %+v", imported)
+	}
+
+	// Importing again neither duplicates articles nor creates a second pseudo-feed
+	if _, err := Import(db, items, nil); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := db.GetArticlesForBackup(true); len(again) != 2 {
+		t.Errorf("expected 2 articles after a second import, got %d", len(again))
+	}
+	if feeds, _ := db.GetFeeds(); len(feeds) != 2 {
+		t.Errorf("expected 2 feeds, got %d", len(feeds))
+	}
+}
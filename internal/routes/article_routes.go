@@ -22,21 +22,33 @@ func registerArticleRoutes(mux *http.ServeMux, h *core.Handler) {
 	mux.HandleFunc("/api/articles/toggle-read-later", func(w http.ResponseWriter, r *http.Request) { article.HandleToggleReadLater(h, w, r) })
 	mux.HandleFunc("/api/articles/mark-all-read", func(w http.ResponseWriter, r *http.Request) { article.HandleMarkAllAsRead(h, w, r) })
 	mux.HandleFunc("/api/articles/clear-read-later", func(w http.ResponseWriter, r *http.Request) { article.HandleClearReadLater(h, w, r) })
+	mux.HandleFunc("/api/saved/add", func(w http.ResponseWriter, r *http.Request) { article.HandleSavePage(h, w, r) })
 
 	// Article content
 	mux.HandleFunc("/api/articles/content", func(w http.ResponseWriter, r *http.Request) { article.HandleGetArticleContent(h, w, r) })
 	mux.HandleFunc("/api/articles/reload-content", func(w http.ResponseWriter, r *http.Request) { article.HandleReloadArticleContent(h, w, r) })
 	mux.HandleFunc("/api/articles/fetch-full", func(w http.ResponseWriter, r *http.Request) { article.HandleFetchFullArticle(h, w, r) })
 	mux.HandleFunc("/api/articles/extract-images", func(w http.ResponseWriter, r *http.Request) { article.HandleExtractAllImages(h, w, r) })
+	mux.HandleFunc("/api/fulltext/rules", func(w http.ResponseWriter, r *http.Request) { article.HandleExtractionRules(h, w, r) })
+	mux.HandleFunc("/api/fulltext/test", func(w http.ResponseWriter, r *http.Request) { article.HandleTestExtractionRule(h, w, r) })
+	mux.HandleFunc("/api/fulltext/feed-rule", func(w http.ResponseWriter, r *http.Request) { article.HandleFeedExtractionRule(h, w, r) })
+	mux.HandleFunc("/api/articles/archive", func(w http.ResponseWriter, r *http.Request) { article.HandleArticleArchive(h, w, r) })
+	mux.HandleFunc("/api/articles/lookup", func(w http.ResponseWriter, r *http.Request) { article.HandleLookupArticle(h, w, r) })
+	mux.HandleFunc("/api/articles/cold-storage/restore", func(w http.ResponseWriter, r *http.Request) { article.HandleRestoreColdArticle(h, w, r) })
+	mux.HandleFunc("/api/offline/prefetch", func(w http.ResponseWriter, r *http.Request) { article.HandleOfflinePrefetch(h, w, r) })
 
 	// Article statistics
 	mux.HandleFunc("/api/articles/unread-counts", func(w http.ResponseWriter, r *http.Request) { article.HandleGetUnreadCounts(h, w, r) })
 	mux.HandleFunc("/api/articles/filter-counts", func(w http.ResponseWriter, r *http.Request) { article.HandleGetFilterCounts(h, w, r) })
+	mux.HandleFunc("/api/articles/counts", func(w http.ResponseWriter, r *http.Request) { article.HandleGetCounts(h, w, r) })
 
 	// Article cleanup
 	mux.HandleFunc("/api/articles/cleanup", func(w http.ResponseWriter, r *http.Request) { article.HandleCleanupArticles(h, w, r) })
 	mux.HandleFunc("/api/articles/cleanup-content", func(w http.ResponseWriter, r *http.Request) { article.HandleCleanupArticleContent(h, w, r) })
 	mux.HandleFunc("/api/articles/content-cache-info", func(w http.ResponseWriter, r *http.Request) { article.HandleGetArticleContentCacheInfo(h, w, r) })
+	mux.HandleFunc("/api/retention-policies", func(w http.ResponseWriter, r *http.Request) { article.HandleRetentionPolicies(h, w, r) })
+	mux.HandleFunc("/api/retention-policies/policy", func(w http.ResponseWriter, r *http.Request) { article.HandleRetentionPolicy(h, w, r) })
+	mux.HandleFunc("/api/retention-policies/preview", func(w http.ResponseWriter, r *http.Request) { article.HandleRetentionPreview(h, w, r) })
 
 	// Translation
 	mux.HandleFunc("/api/articles/translate", func(w http.ResponseWriter, r *http.Request) { translationhandlers.HandleTranslateArticle(h, w, r) })
@@ -21,6 +21,7 @@ func registerFeedRoutes(mux *http.ServeMux, h *core.Handler) {
 	mux.HandleFunc("/api/feeds/refresh", func(w http.ResponseWriter, r *http.Request) { feedhandlers.HandleRefreshFeed(h, w, r) })
 	mux.HandleFunc("/api/feeds/reorder", func(w http.ResponseWriter, r *http.Request) { feedhandlers.HandleReorderFeed(h, w, r) })
 	mux.HandleFunc("/api/feeds/test-imap", func(w http.ResponseWriter, r *http.Request) { feedhandlers.HandleTestIMAPConnection(h, w, r) })
+	mux.HandleFunc("/api/feeds/fetch-history", func(w http.ResponseWriter, r *http.Request) { feedhandlers.HandleFeedFetchHistory(h, w, r) })
 
 	// Discovery routes
 	mux.HandleFunc("/api/feeds/discover", func(w http.ResponseWriter, r *http.Request) { discovery.HandleDiscoverBlogs(h, w, r) })
@@ -31,6 +32,7 @@ func registerFeedRoutes(mux *http.ServeMux, h *core.Handler) {
 	mux.HandleFunc("/api/feeds/discover-all/start", func(w http.ResponseWriter, r *http.Request) { discovery.HandleStartBatchDiscovery(h, w, r) })
 	mux.HandleFunc("/api/feeds/discover-all/progress", func(w http.ResponseWriter, r *http.Request) { discovery.HandleGetBatchDiscoveryProgress(h, w, r) })
 	mux.HandleFunc("/api/feeds/discover-all/clear", func(w http.ResponseWriter, r *http.Request) { discovery.HandleClearBatchDiscovery(h, w, r) })
+	mux.HandleFunc("/api/feeds/recommendations", func(w http.ResponseWriter, r *http.Request) { discovery.HandleFeedRecommendations(h, w, r) })
 
 	// Tag routes
 	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) { taghandlers.HandleTags(h, w, r) })
@@ -48,10 +50,17 @@ func registerFeedRoutes(mux *http.ServeMux, h *core.Handler) {
 	mux.HandleFunc("/api/saved-filters/filter", func(w http.ResponseWriter, r *http.Request) {
 		filter_category.HandleSavedFilter(h, w, r)
 	})
+	mux.HandleFunc("/api/filter-query/parse", func(w http.ResponseWriter, r *http.Request) {
+		filter_category.HandleParseFilterQuery(h, w, r)
+	})
+	mux.HandleFunc("/api/filter-query/format", func(w http.ResponseWriter, r *http.Request) {
+		filter_category.HandleFormatFilterQuery(h, w, r)
+	})
 
 	// RSSHub routes
 	mux.HandleFunc("/api/rsshub/add", func(w http.ResponseWriter, r *http.Request) { rsshubHandler.HandleAddFeed(h, w, r) })
 	mux.HandleFunc("/api/rsshub/test-connection", func(w http.ResponseWriter, r *http.Request) { rsshubHandler.HandleTestConnection(h, w, r) })
 	mux.HandleFunc("/api/rsshub/validate-route", func(w http.ResponseWriter, r *http.Request) { rsshubHandler.HandleValidateRoute(h, w, r) })
 	mux.HandleFunc("/api/rsshub/transform-url", func(w http.ResponseWriter, r *http.Request) { rsshubHandler.HandleTransformURL(h, w, r) })
+	mux.HandleFunc("/api/rsshub/instances", func(w http.ResponseWriter, r *http.Request) { rsshubHandler.HandleInstanceStatus(h, w, r) })
 }
@@ -28,6 +28,12 @@ func registerOtherRoutes(mux *http.ServeMux, h *core.Handler) {
 	mux.HandleFunc("/api/opml/export", func(w http.ResponseWriter, r *http.Request) { opml.HandleOPMLExport(h, w, r) })
 	mux.HandleFunc("/api/opml/import-dialog", func(w http.ResponseWriter, r *http.Request) { opml.HandleOPMLImportDialog(h, w, r) })
 	mux.HandleFunc("/api/opml/export-dialog", func(w http.ResponseWriter, r *http.Request) { opml.HandleOPMLExportDialog(h, w, r) })
+	mux.HandleFunc("/api/opml/lists", func(w http.ResponseWriter, r *http.Request) { opml.HandleSubscriptionLists(h, w, r) })
+	mux.HandleFunc("/api/opml/lists/delete", func(w http.ResponseWriter, r *http.Request) { opml.HandleDeleteSubscriptionList(h, w, r) })
+	mux.HandleFunc("/api/opml/lists/sync", func(w http.ResponseWriter, r *http.Request) { opml.HandleSyncSubscriptionList(h, w, r) })
+	mux.HandleFunc("/api/opml/lists/log", func(w http.ResponseWriter, r *http.Request) { opml.HandleSubscriptionListLog(h, w, r) })
+	mux.HandleFunc("/api/import/items", func(w http.ResponseWriter, r *http.Request) { opml.HandleReaderImport(h, w, r) })
+	mux.HandleFunc("/api/import/items/progress", func(w http.ResponseWriter, r *http.Request) { opml.HandleReaderImportProgress(h, w, r) })
 
 	// Update
 	mux.HandleFunc("/api/check-updates", func(w http.ResponseWriter, r *http.Request) { update.HandleCheckUpdates(h, w, r) })
@@ -3,6 +3,7 @@ package routes
 import (
 	"net/http"
 
+	backuphandlers "MrRSS/internal/handlers/backup"
 	"MrRSS/internal/handlers/core"
 	settings "MrRSS/internal/handlers/settings"
 	stathandlers "MrRSS/internal/handlers/statistics"
@@ -23,4 +24,20 @@ func registerSettingsRoutes(mux *http.ServeMux, h *core.Handler) {
 	})
 	mux.HandleFunc("/api/statistics/all-time", func(w http.ResponseWriter, r *http.Request) { stathandlers.HandleGetAllTimeStatistics(h, w, r) })
 	mux.HandleFunc("/api/statistics/available-months", func(w http.ResponseWriter, r *http.Request) { stathandlers.HandleGetAvailableMonths(h, w, r) })
+	mux.HandleFunc("/api/statistics/database", func(w http.ResponseWriter, r *http.Request) { stathandlers.HandleGetDatabaseStats(h, w, r) })
+
+	// Backup and restore
+	mux.HandleFunc("/api/backup/export", func(w http.ResponseWriter, r *http.Request) { backuphandlers.HandleBackupExport(h, w, r) })
+	mux.HandleFunc("/api/backup/import", func(w http.ResponseWriter, r *http.Request) { backuphandlers.HandleBackupImport(h, w, r) })
+	mux.HandleFunc("/api/backup/snapshots", func(w http.ResponseWriter, r *http.Request) { backuphandlers.HandleSnapshots(h, w, r) })
+	mux.HandleFunc("/api/backup/snapshots/snapshot", func(w http.ResponseWriter, r *http.Request) { backuphandlers.HandleSnapshot(h, w, r) })
+	mux.HandleFunc("/api/backup/snapshots/restore", func(w http.ResponseWriter, r *http.Request) { backuphandlers.HandleSnapshotRestore(h, w, r) })
+
+	// Encryption keys and secrets
+	mux.HandleFunc("/api/encryption", func(w http.ResponseWriter, r *http.Request) { backuphandlers.HandleEncryptionStatus(h, w, r) })
+	mux.HandleFunc("/api/encryption/passphrase", func(w http.ResponseWriter, r *http.Request) { backuphandlers.HandleMasterPassphrase(h, w, r) })
+	mux.HandleFunc("/api/encryption/unlock", func(w http.ResponseWriter, r *http.Request) { backuphandlers.HandleUnlockMasterKey(h, w, r) })
+	mux.HandleFunc("/api/encryption/rotate", func(w http.ResponseWriter, r *http.Request) { backuphandlers.HandleRotateSecrets(h, w, r) })
+	mux.HandleFunc("/api/encryption/secrets/export", func(w http.ResponseWriter, r *http.Request) { backuphandlers.HandleSecretsExport(h, w, r) })
+	mux.HandleFunc("/api/encryption/secrets/import", func(w http.ResponseWriter, r *http.Request) { backuphandlers.HandleSecretsImport(h, w, r) })
 }
@@ -0,0 +1,248 @@
+package rsshub
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxFailures is the number of consecutive failures after which an
+// instance is considered unhealthy and moved behind healthy instances.
+const DefaultMaxFailures = 3
+
+// Instance is a single RSSHub deployment with its own optional access key
+type Instance struct {
+	Endpoint string `json:"endpoint"`
+	APIKey   string `json:"api_key,omitempty"`
+}
+
+// Client returns a client bound to this instance
+func (i Instance) Client() *Client {
+	return NewClient(i.Endpoint, i.APIKey)
+}
+
+// InstanceStatus reports the health of a configured instance.
+// The API key is intentionally not included.
+type InstanceStatus struct {
+	Endpoint            string    `json:"endpoint"`
+	Position            int       `json:"position"`
+	Healthy             bool      `json:"healthy"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	TotalFailures       int64     `json:"total_failures"`
+	TotalSuccesses      int64     `json:"total_successes"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastSuccess         time.Time `json:"last_success"`
+	LastFailure         time.Time `json:"last_failure"`
+	LastChecked         time.Time `json:"last_checked"`
+}
+
+// instanceState holds the mutable health counters of an instance
+type instanceState struct {
+	consecutiveFailures int
+	totalFailures       int64
+	totalSuccesses      int64
+	lastError           string
+	lastSuccess         time.Time
+	lastFailure         time.Time
+	lastChecked         time.Time
+}
+
+// Pool manages an ordered list of RSSHub instances and their health.
+// Instances keep their configured order; unhealthy instances are only
+// used after every healthy instance has been tried.
+type Pool struct {
+	mu          sync.RWMutex
+	instances   []Instance
+	states      map[string]*instanceState
+	maxFailures int
+}
+
+// NewPool creates an empty instance pool
+func NewPool() *Pool {
+	return &Pool{
+		states:      make(map[string]*instanceState),
+		maxFailures: DefaultMaxFailures,
+	}
+}
+
+// ParseInstances parses the JSON list stored in the rsshub_instances setting.
+// Entries without an endpoint and duplicate endpoints are dropped.
+func ParseInstances(raw string) ([]Instance, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var parsed []Instance
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid RSSHub instance list: %w", err)
+	}
+
+	seen := make(map[string]bool, len(parsed))
+	instances := make([]Instance, 0, len(parsed))
+	for _, inst := range parsed {
+		endpoint := normalizeEndpoint(inst.Endpoint)
+		if endpoint == "" || seen[endpoint] {
+			continue
+		}
+		seen[endpoint] = true
+		instances = append(instances, Instance{Endpoint: endpoint, APIKey: strings.TrimSpace(inst.APIKey)})
+	}
+	return instances, nil
+}
+
+// normalizeEndpoint trims whitespace and the trailing slash from an endpoint
+func normalizeEndpoint(endpoint string) string {
+	return strings.TrimSuffix(strings.TrimSpace(endpoint), "/")
+}
+
+// SetInstances replaces the configured instances.
+// Health counters are preserved for endpoints that are still configured.
+func (p *Pool) SetInstances(instances []Instance) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.instances = make([]Instance, 0, len(instances))
+	states := make(map[string]*instanceState, len(instances))
+	for _, inst := range instances {
+		inst.Endpoint = normalizeEndpoint(inst.Endpoint)
+		if inst.Endpoint == "" {
+			continue
+		}
+		if _, dup := states[inst.Endpoint]; dup {
+			continue
+		}
+		p.instances = append(p.instances, inst)
+		if state, ok := p.states[inst.Endpoint]; ok {
+			states[inst.Endpoint] = state
+		} else {
+			states[inst.Endpoint] = &instanceState{}
+		}
+	}
+	p.states = states
+}
+
+// Instances returns a copy of the configured instances in configured order
+func (p *Pool) Instances() []Instance {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return append([]Instance(nil), p.instances...)
+}
+
+// Candidates returns the instances in the order they should be tried:
+// healthy instances first (in configured order), then unhealthy ones.
+func (p *Pool) Candidates() []Instance {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	healthy := make([]Instance, 0, len(p.instances))
+	var unhealthy []Instance
+	for _, inst := range p.instances {
+		if p.isHealthyLocked(inst.Endpoint) {
+			healthy = append(healthy, inst)
+		} else {
+			unhealthy = append(unhealthy, inst)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// Primary returns the first instance that should be tried
+func (p *Pool) Primary() (Instance, bool) {
+	candidates := p.Candidates()
+	if len(candidates) == 0 {
+		return Instance{}, false
+	}
+	return candidates[0], true
+}
+
+// isHealthyLocked reports whether an instance is below the failure threshold.
+// Callers must hold p.mu.
+func (p *Pool) isHealthyLocked(endpoint string) bool {
+	state, ok := p.states[endpoint]
+	if !ok {
+		return true
+	}
+	return state.consecutiveFailures < p.maxFailures
+}
+
+// ReportSuccess records a successful request served by an instance
+func (p *Pool) ReportSuccess(endpoint string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.states[normalizeEndpoint(endpoint)]
+	if !ok {
+		return
+	}
+	state.consecutiveFailures = 0
+	state.totalSuccesses++
+	state.lastError = ""
+	state.lastSuccess = time.Now()
+}
+
+// ReportFailure records a failed request against an instance
+func (p *Pool) ReportFailure(endpoint string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.states[normalizeEndpoint(endpoint)]
+	if !ok {
+		return
+	}
+	state.consecutiveFailures++
+	state.totalFailures++
+	state.lastFailure = time.Now()
+	if err != nil {
+		state.lastError = err.Error()
+	}
+}
+
+// CheckHealth probes every instance and updates its counters.
+// A successful probe marks the instance healthy again immediately.
+func (p *Pool) CheckHealth() {
+	for _, inst := range p.Instances() {
+		err := inst.Client().ValidateRoute("healthz")
+
+		p.mu.Lock()
+		if state, ok := p.states[inst.Endpoint]; ok {
+			state.lastChecked = time.Now()
+		}
+		p.mu.Unlock()
+
+		if err != nil {
+			p.ReportFailure(inst.Endpoint, err)
+		} else {
+			p.ReportSuccess(inst.Endpoint)
+		}
+	}
+}
+
+// Status returns the health of every configured instance in configured order
+func (p *Pool) Status() []InstanceStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	statuses := make([]InstanceStatus, 0, len(p.instances))
+	for i, inst := range p.instances {
+		status := InstanceStatus{
+			Endpoint: inst.Endpoint,
+			Position: i,
+			Healthy:  p.isHealthyLocked(inst.Endpoint),
+		}
+		if state, ok := p.states[inst.Endpoint]; ok {
+			status.ConsecutiveFailures = state.consecutiveFailures
+			status.TotalFailures = state.totalFailures
+			status.TotalSuccesses = state.totalSuccesses
+			status.LastError = state.lastError
+			status.LastSuccess = state.lastSuccess
+			status.LastFailure = state.lastFailure
+			status.LastChecked = state.lastChecked
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
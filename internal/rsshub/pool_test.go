@@ -0,0 +1,108 @@
+package rsshub
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseInstances(t *testing.T) {
+	instances, err := ParseInstances(`[
+		{"endpoint": "https://a.example.com/", "api_key": "k1"},
+		{"endpoint": "  "},
+		{"endpoint": "https://a.example.com"},
+		{"endpoint": "https://b.example.com"}
+	]`)
+	if err != nil {
+		t.Fatalf("ParseInstances() error = %v", err)
+	}
+
+	if len(instances) != 2 {
+		t.Fatalf("ParseInstances() returned %d instances, want 2", len(instances))
+	}
+	if instances[0].Endpoint != "https://a.example.com" || instances[0].APIKey != "k1" {
+		t.Errorf("first instance = %+v", instances[0])
+	}
+	if instances[1].Endpoint != "https://b.example.com" {
+		t.Errorf("second instance = %+v", instances[1])
+	}
+
+	if _, err := ParseInstances("not json"); err == nil {
+		t.Error("ParseInstances() expected error for invalid JSON")
+	}
+	if instances, err := ParseInstances(""); err != nil || instances != nil {
+		t.Errorf("ParseInstances(\"\") = %v, %v; want nil, nil", instances, err)
+	}
+}
+
+func TestPoolCandidatesMoveUnhealthyInstancesLast(t *testing.T) {
+	pool := NewPool()
+	pool.SetInstances([]Instance{
+		{Endpoint: "https://a.example.com"},
+		{Endpoint: "https://b.example.com"},
+		{Endpoint: "https://c.example.com"},
+	})
+
+	for i := 0; i < DefaultMaxFailures; i++ {
+		pool.ReportFailure("https://a.example.com", errors.New("boom"))
+	}
+
+	candidates := pool.Candidates()
+	got := []string{candidates[0].Endpoint, candidates[1].Endpoint, candidates[2].Endpoint}
+	want := []string{"https://b.example.com", "https://c.example.com", "https://a.example.com"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Candidates() = %v, want %v", got, want)
+		}
+	}
+
+	// A single success restores the instance to its configured position
+	pool.ReportSuccess("https://a.example.com")
+	if primary, _ := pool.Primary(); primary.Endpoint != "https://a.example.com" {
+		t.Errorf("Primary() = %s, want https://a.example.com", primary.Endpoint)
+	}
+}
+
+func TestPoolSetInstancesPreservesState(t *testing.T) {
+	pool := NewPool()
+	pool.SetInstances([]Instance{{Endpoint: "https://a.example.com"}})
+	pool.ReportFailure("https://a.example.com", errors.New("boom"))
+
+	pool.SetInstances([]Instance{{Endpoint: "https://b.example.com"}, {Endpoint: "https://a.example.com/"}})
+
+	status := pool.Status()
+	if len(status) != 2 {
+		t.Fatalf("Status() returned %d entries, want 2", len(status))
+	}
+	if status[1].Endpoint != "https://a.example.com" || status[1].TotalFailures != 1 || status[1].LastError != "boom" {
+		t.Errorf("state for a.example.com was not preserved: %+v", status[1])
+	}
+	if status[0].TotalFailures != 0 {
+		t.Errorf("new instance should start clean: %+v", status[0])
+	}
+}
+
+func TestPoolCheckHealth(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer broken.Close()
+
+	pool := NewPool()
+	pool.SetInstances([]Instance{{Endpoint: broken.URL}, {Endpoint: healthy.URL}})
+	pool.CheckHealth()
+
+	status := pool.Status()
+	if status[0].ConsecutiveFailures != 1 || status[0].LastChecked.IsZero() {
+		t.Errorf("broken instance status = %+v", status[0])
+	}
+	if status[1].TotalSuccesses != 1 || status[1].LastChecked.IsZero() {
+		t.Errorf("healthy instance status = %+v", status[1])
+	}
+}
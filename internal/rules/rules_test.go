@@ -1,9 +1,11 @@
 package rules
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"testing"
+	"time"
 
 	"MrRSS/internal/database"
 	"MrRSS/internal/models"
@@ -49,16 +51,20 @@ func TestEngine_ApplyRulesToArticles(t *testing.T) {
 	rulesJSON, _ := json.Marshal(rules)
 	engine.db.SetSetting("rules", string(rulesJSON))
 
-	// Create test articles
-	articles := []models.Article{
-		{
-			ID:    1,
-			Title: "This is a test article",
-		},
-		{
-			ID:    2,
-			Title: "This is another article",
-		},
+	// Create test articles; rules are evaluated against the database
+	feedID, err := engine.db.AddFeed(&models.Feed{Title: "Feed", URL: "https://example.com/feed"})
+	if err != nil {
+		t.Fatalf("AddFeed failed: %v", err)
+	}
+	if err := engine.db.SaveArticles(context.Background(), []*models.Article{
+		{FeedID: feedID, Title: "This is a test article", URL: "https://example.com/1", PublishedAt: time.Now()},
+		{FeedID: feedID, Title: "This is another article", URL: "https://example.com/2", PublishedAt: time.Now()},
+	}); err != nil {
+		t.Fatalf("SaveArticles failed: %v", err)
+	}
+	articles, err := engine.db.GetArticles("", feedID, "", true, 10, 0)
+	if err != nil || len(articles) != 2 {
+		t.Fatalf("GetArticles: %d articles, %v", len(articles), err)
 	}
 
 	// Apply rules
@@ -70,6 +76,11 @@ func TestEngine_ApplyRulesToArticles(t *testing.T) {
 	if count != 1 {
 		t.Errorf("Expected 1 article to be processed, got %d", count)
 	}
+
+	favorites, err := engine.db.GetArticles("favorites", 0, "", true, 10, 0)
+	if err != nil || len(favorites) != 1 || favorites[0].Title != "This is a test article" || !favorites[0].IsRead {
+		t.Errorf("Expected the test article favorited and read, got %+v, %v", favorites, err)
+	}
 }
 
 func TestEngine_ApplyRule(t *testing.T) {
@@ -0,0 +1,140 @@
+// Package siteconfig implements site-specific full-text extraction rules in
+// the FiveFilters / Wallabag ftr-site-config format.
+//
+// A rule file is named after the host it applies to ("example.com.txt", or
+// ".example.com.txt" for every subdomain) and holds "directive: value" lines:
+//
+//	title: //h1[@class='headline']
+//	body: //div[@id='article-body']
+//	strip_id_or_class: share-buttons
+//	next_page_link: //a[@rel='next']
+//	http_header(user-agent): Mozilla/5.0
+//
+// Rules are bundled with the application and can be overridden or extended
+// by files in the site_config directory of the data dir.
+package siteconfig
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Config holds the directives of one rule file
+type Config struct {
+	Title               []string          `json:"title,omitempty"`             // XPaths for the title, first match wins
+	Body                []string          `json:"body,omitempty"`              // XPaths for the content, first match wins
+	Author              []string          `json:"author,omitempty"`            // XPaths for the author
+	Date                []string          `json:"date,omitempty"`              // XPaths for the publish date
+	Strip               []string          `json:"strip,omitempty"`             // XPaths of elements to remove
+	StripIDOrClass      []string          `json:"strip_id_or_class,omitempty"` // Remove elements whose id or class contains these
+	StripImageSrc       []string          `json:"strip_image_src,omitempty"`   // Remove images whose src contains these
+	SinglePageLink      []string          `json:"single_page_link,omitempty"`  // XPaths to a single-page version of the article
+	NextPageLink        []string          `json:"next_page_link,omitempty"`    // XPaths to the next page of a multi-page article
+	HTTPHeaders         map[string]string `json:"http_headers,omitempty"`      // Request headers, from http_header(name): value
+	FindString          []string          `json:"find_string,omitempty"`       // Strings replaced in the raw HTML before parsing...
+	ReplaceString       []string          `json:"replace_string,omitempty"`    // ...with the string at the same index
+	Prune               bool              `json:"prune"`                       // Remove non-content elements from the body
+	AutodetectOnFailure bool              `json:"autodetect_on_failure"`       // Fall back to generic extraction when no body matches
+	TestURLs            []string          `json:"test_url,omitempty"`
+}
+
+// Parse reads a rule file. Unknown directives, such as the login directives
+// of the format, are ignored.
+func Parse(r io.Reader) (*Config, error) {
+	cfg := &Config{
+		HTTPHeaders:         make(map[string]string),
+		Prune:               true,
+		AutodetectOnFailure: true,
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			continue
+		}
+		key, value := line[:colon], line[colon+1:]
+
+		// Directives with an argument: http_header(name) and replace_string(find).
+		// The argument may itself contain colons.
+		var arg string
+		if open := strings.Index(key, "("); open > 0 {
+			if end := strings.Index(line, "):"); end > open {
+				arg = line[open+1 : end]
+				key, value = line[:open], line[end+2:]
+			}
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "title":
+			cfg.Title = append(cfg.Title, value)
+		case "body":
+			cfg.Body = append(cfg.Body, value)
+		case "author":
+			cfg.Author = append(cfg.Author, value)
+		case "date":
+			cfg.Date = append(cfg.Date, value)
+		case "strip":
+			cfg.Strip = append(cfg.Strip, value)
+		case "strip_id_or_class":
+			cfg.StripIDOrClass = append(cfg.StripIDOrClass, strings.Trim(value, `"'`))
+		case "strip_image_src":
+			cfg.StripImageSrc = append(cfg.StripImageSrc, strings.Trim(value, `"'`))
+		case "single_page_link":
+			cfg.SinglePageLink = append(cfg.SinglePageLink, value)
+		case "next_page_link":
+			cfg.NextPageLink = append(cfg.NextPageLink, value)
+		case "http_header":
+			if arg != "" {
+				cfg.HTTPHeaders[strings.ToLower(arg)] = value
+			}
+		case "find_string":
+			cfg.FindString = append(cfg.FindString, value)
+		case "replace_string":
+			if arg != "" {
+				cfg.FindString = append(cfg.FindString, arg)
+			}
+			cfg.ReplaceString = append(cfg.ReplaceString, value)
+		case "prune":
+			cfg.Prune = parseBool(value)
+		case "autodetect_on_failure":
+			cfg.AutodetectOnFailure = parseBool(value)
+		case "test_url":
+			cfg.TestURLs = append(cfg.TestURLs, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseBool reads the yes/no values of the format
+func parseBool(v string) bool {
+	switch strings.ToLower(v) {
+	case "yes", "true", "1", "on":
+		return true
+	}
+	return false
+}
+
+// replacements returns the find/replace pairs of the config
+func (c *Config) replacements() []string {
+	n := len(c.FindString)
+	if len(c.ReplaceString) < n {
+		n = len(c.ReplaceString)
+	}
+	pairs := make([]string, 0, n*2)
+	for i := 0; i < n; i++ {
+		pairs = append(pairs, c.FindString[i], c.ReplaceString[i])
+	}
+	return pairs
+}
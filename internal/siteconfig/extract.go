@@ -0,0 +1,271 @@
+package siteconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/antchfx/htmlquery"
+	"golang.org/x/net/html"
+)
+
+const (
+	// maxPages limits how many pages of a multi-page article are stitched together
+	maxPages = 10
+	// maxPageSize limits the size of a fetched page
+	maxPageSize = 10 << 20
+)
+
+// Result is the content extracted with a rule
+type Result struct {
+	Title         string    `json:"title"`
+	Author        string    `json:"author,omitempty"`
+	Date          string    `json:"date,omitempty"` // As found on the page
+	PublishedAt   time.Time `json:"published_at"`   // Date parsed, zero when unknown
+	Content       string    `json:"content"`        // Empty when no body XPath matched
+	SinglePageURL string    `json:"single_page_url,omitempty"`
+	Pages         []string  `json:"pages"` // URLs the content was taken from, in order
+}
+
+// Extract fetches pageURL and extracts its content with cfg. It follows a
+// single_page_link to the single-page version of the article, and otherwise
+// follows next_page_link to stitch multi-page articles together. A Result
+// with empty Content means no body XPath matched.
+func Extract(ctx context.Context, client *http.Client, pageURL string, cfg *Config) (*Result, error) {
+	doc, base, err := fetchDocument(ctx, client, pageURL, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	if link := findLink(doc, base, cfg.SinglePageLink); link != "" && link != base.String() {
+		if singleDoc, singleBase, err := fetchDocument(ctx, client, link, cfg); err == nil {
+			doc, base = singleDoc, singleBase
+			result.SinglePageURL = link
+		}
+	}
+
+	result.Title = firstText(doc, cfg.Title)
+	result.Author = firstText(doc, cfg.Author)
+	result.Date = firstText(doc, cfg.Date)
+	result.PublishedAt = parseDate(result.Date)
+
+	// Find the next page before the strip directives can remove its link
+	var next string
+	if result.SinglePageURL == "" {
+		next = findLink(doc, base, cfg.NextPageLink)
+	}
+
+	body := extractBody(doc, base, cfg)
+	if body == "" {
+		return result, nil
+	}
+	result.Pages = append(result.Pages, base.String())
+
+	var content strings.Builder
+	content.WriteString(body)
+
+	// Stitch multi-page articles together
+	seen := map[string]bool{pageURL: true, base.String(): true}
+	for next != "" && !seen[next] && len(result.Pages) < maxPages {
+		seen[next] = true
+		nextDoc, nextBase, err := fetchDocument(ctx, client, next, cfg)
+		if err != nil {
+			break
+		}
+		next = findLink(nextDoc, nextBase, cfg.NextPageLink)
+		nextBody := extractBody(nextDoc, nextBase, cfg)
+		if nextBody == "" {
+			break
+		}
+		content.WriteString(nextBody)
+		result.Pages = append(result.Pages, nextBase.String())
+	}
+
+	result.Content = content.String()
+	return result, nil
+}
+
+// fetchDocument fetches and parses a page, applying the rule's headers and
+// string replacements. It returns the document and the final page URL.
+func fetchDocument(ctx context.Context, client *http.Client, pageURL string, cfg *Config) (*html.Node, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	for name, value := range cfg.HTTPHeaders {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, fmt.Errorf("fetch page: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("read page: %w", err)
+	}
+	if pairs := cfg.replacements(); len(pairs) > 0 {
+		data = []byte(strings.NewReplacer(pairs...).Replace(string(data)))
+	}
+
+	doc, err := htmlquery.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse page: %w", err)
+	}
+	return doc, resp.Request.URL, nil
+}
+
+// extractBody applies the strip directives and returns the HTML of the
+// first body XPath that matches, with links made absolute
+func extractBody(doc *html.Node, base *url.URL, cfg *Config) string {
+	for _, xp := range cfg.Strip {
+		removeAll(doc, xp)
+	}
+	for _, s := range cfg.StripIDOrClass {
+		removeAll(doc, fmt.Sprintf("//*[contains(@class, %s) or contains(@id, %s)]", xpathLiteral(s), xpathLiteral(s)))
+	}
+	for _, s := range cfg.StripImageSrc {
+		removeAll(doc, fmt.Sprintf("//img[contains(@src, %s)]", xpathLiteral(s)))
+	}
+
+	for _, xp := range cfg.Body {
+		nodes, err := htmlquery.QueryAll(doc, xp)
+		if err != nil || len(nodes) == 0 {
+			continue
+		}
+
+		var buf strings.Builder
+		for _, n := range nodes {
+			if cfg.Prune {
+				removeAll(n, ".//script | .//style | .//noscript | .//form | .//iframe[not(contains(@src, 'youtube') or contains(@src, 'vimeo'))]")
+			} else {
+				removeAll(n, ".//script | .//style")
+			}
+			absolutize(n, base)
+			buf.WriteString(htmlquery.OutputHTML(n, true))
+		}
+		if strings.TrimSpace(buf.String()) != "" {
+			return buf.String()
+		}
+	}
+	return ""
+}
+
+// firstText returns the text of the first XPath that yields a non-empty value
+func firstText(doc *html.Node, xpaths []string) string {
+	for _, xp := range xpaths {
+		nodes, err := htmlquery.QueryAll(doc, xp)
+		if err != nil {
+			continue
+		}
+		for _, n := range nodes {
+			if text := strings.Join(strings.Fields(htmlquery.InnerText(n)), " "); text != "" {
+				return text
+			}
+		}
+	}
+	return ""
+}
+
+// findLink returns the absolute URL of the first XPath that selects a link
+// or an href attribute
+func findLink(doc *html.Node, base *url.URL, xpaths []string) string {
+	for _, xp := range xpaths {
+		nodes, err := htmlquery.QueryAll(doc, xp)
+		if err != nil {
+			continue
+		}
+		for _, n := range nodes {
+			// Attribute nodes (".../@href") carry the value as their text
+			href := htmlquery.SelectAttr(n, "href")
+			if href == "" && n.Data != "a" {
+				href = htmlquery.InnerText(n)
+			}
+			href = strings.TrimSpace(href)
+			if ref, err := url.Parse(href); err == nil && href != "" {
+				return base.ResolveReference(ref).String()
+			}
+		}
+	}
+	return ""
+}
+
+// removeAll removes the nodes matched by an XPath
+func removeAll(root *html.Node, xp string) {
+	nodes, err := htmlquery.QueryAll(root, xp)
+	if err != nil {
+		return
+	}
+	for _, n := range nodes {
+		if n.Parent != nil && n != root {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// absolutize resolves relative src and href attributes against base
+func absolutize(n *html.Node, base *url.URL) {
+	if n.Type == html.ElementNode {
+		for i, attr := range n.Attr {
+			if attr.Key != "src" && attr.Key != "href" {
+				continue
+			}
+			if ref, err := url.Parse(strings.TrimSpace(attr.Val)); err == nil && !ref.IsAbs() && !strings.HasPrefix(attr.Val, "#") {
+				n.Attr[i].Val = base.ResolveReference(ref).String()
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		absolutize(c, base)
+	}
+}
+
+// dateLayouts are the date formats commonly found in article markup
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"02 Jan 2006",
+}
+
+// parseDate parses a date found with a date XPath, or returns the zero time
+func parseDate(s string) time.Time {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// xpathLiteral quotes a string for use in an XPath expression
+func xpathLiteral(s string) string {
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	parts := strings.Split(s, "'")
+	return "concat('" + strings.Join(parts, `', "'", '`) + "')"
+}
@@ -0,0 +1,193 @@
+package siteconfig
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse(strings.NewReader(`
+# Comment
+title: //h1
+body: //div[@class='post']
+body: //article
+strip_id_or_class: "share"
+http_header(User-Agent): TestAgent/1.0
+replace_string(<a href="http://old">): <a href="https://new">
+find_string: <br><br>
+replace_string: <p>
+prune: no
+autodetect_on_failure: no
+login_uri: https://example.com/login
+`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if len(cfg.Title) != 1 || len(cfg.Body) != 2 || cfg.Body[1] != "//article" {
+		t.Errorf("unexpected XPaths: %+v", cfg)
+	}
+	if len(cfg.StripIDOrClass) != 1 || cfg.StripIDOrClass[0] != "share" {
+		t.Errorf("strip_id_or_class = %v", cfg.StripIDOrClass)
+	}
+	if cfg.HTTPHeaders["user-agent"] != "TestAgent/1.0" {
+		t.Errorf("headers = %v", cfg.HTTPHeaders)
+	}
+	pairs := cfg.replacements()
+	if len(pairs) != 4 || pairs[0] != `<a href="http://old">` || pairs[1] != `<a href="https://new">` || pairs[2] != "<br><br>" {
+		t.Errorf("replacements = %q", pairs)
+	}
+	if cfg.Prune || cfg.AutodetectOnFailure {
+		t.Error("prune and autodetect_on_failure should be off")
+	}
+}
+
+func TestStoreLookup(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	if rule := store.Lookup("de.wikipedia.org"); rule == nil || rule.Name != ".wikipedia.org" || rule.Source != SourceBundled {
+		t.Fatalf("expected the bundled wildcard rule, got %+v", rule)
+	}
+	if rule := store.Lookup("example.com"); rule != nil {
+		t.Fatalf("expected no rule, got %+v", rule)
+	}
+
+	// User rules override bundled ones and match hosts without www.
+	if err := os.WriteFile(filepath.Join(dir, ".wikipedia.org.txt"), []byte("body: //main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "example.com.txt"), []byte("body: //article\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if rule := store.Lookup("en.wikipedia.org"); rule == nil || rule.Source != SourceUser || rule.Config.Body[0] != "//main" {
+		t.Errorf("expected the user rule, got %+v", rule)
+	}
+	if rule := store.Lookup("www.example.com:8080"); rule == nil || rule.Name != "example.com" {
+		t.Errorf("expected example.com rule, got %+v", rule)
+	}
+
+	// Rules are cached until their file changes
+	cached := store.Lookup("example.com")
+	if again := store.Lookup("example.com"); again != cached {
+		t.Errorf("expected the cached rule, got a new one")
+	}
+	path := filepath.Join(dir, "example.com.txt")
+	if err := os.WriteFile(path, []byte("body: //div[@id='content']\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if rule := store.Lookup("example.com"); rule == nil || rule.Config.Body[0] != "//div[@id='content']" {
+		t.Errorf("expected the edited rule, got %+v", rule)
+	}
+
+	if _, err := store.Get("../secret"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for a path, got %v", err)
+	}
+
+	sources := make(map[string]string)
+	for _, r := range store.List() {
+		sources[r.Name] = r.Source
+	}
+	if sources[".wikipedia.org"] != SourceUser || sources["github.com"] != SourceBundled || sources["example.com"] != SourceUser {
+		t.Errorf("unexpected rule list: %v", sources)
+	}
+}
+
+func TestExtractMultiPage(t *testing.T) {
+	var gotAgent string
+	mux := http.NewServeMux()
+	page := func(n int, next string) string {
+		link := ""
+		if next != "" {
+			link = `<a class="next" href="` + next + `">Next</a>`
+		}
+		return fmt.Sprintf(`<html><body>
+<h1 class="headline">Story</h1>
+<span class="byline">By Ann</span>
+<time>2024-05-01</time>
+<div class="post"><p>Page %d text</p><div class="share-box">Share!</div><img src="/img/%d.jpg"><script>x()</script></div>
+<nav>%s</nav>
+</body></html>`, n, n, link)
+	}
+	mux.HandleFunc("/story", func(w http.ResponseWriter, r *http.Request) {
+		gotAgent = r.Header.Get("User-Agent")
+		fmt.Fprint(w, page(1, "/story?page=2"))
+	})
+	mux.HandleFunc("/story2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, page(3, "/story2"))
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/story" && r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, page(2, "/story"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	cfg, err := Parse(strings.NewReader(`
+title: //h1[@class='headline']
+author: //span[@class='byline']
+date: //time
+body: //div[@class='post']
+strip_id_or_class: share
+next_page_link: //a[@class='next']/@href
+http_header(user-agent): RuleAgent
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Extract(context.Background(), srv.Client(), srv.URL+"/story", cfg)
+	if err != nil {
+		t.Fatalf("Extract error: %v", err)
+	}
+	if gotAgent != "RuleAgent" {
+		t.Errorf("User-Agent = %q", gotAgent)
+	}
+	if result.Title != "Story" || result.Author != "By Ann" || result.PublishedAt.Format("2006-01-02") != "2024-05-01" {
+		t.Errorf("unexpected metadata: %+v", result)
+	}
+	// Page 2 links back to page 1, which must not be fetched again
+	if len(result.Pages) != 2 {
+		t.Fatalf("expected 2 pages, got %v", result.Pages)
+	}
+	for _, want := range []string{"Page 1 text", "Page 2 text", srv.URL + "/img/1.jpg"} {
+		if !strings.Contains(result.Content, want) {
+			t.Errorf("content missing %q: %s", want, result.Content)
+		}
+	}
+	for _, unwanted := range []string{"Share!", "<script"} {
+		if strings.Contains(result.Content, unwanted) {
+			t.Errorf("content should not contain %q: %s", unwanted, result.Content)
+		}
+	}
+
+	// A single-page link replaces pagination
+	cfg.SinglePageLink = []string{"//a[@class='next']"}
+	cfg.NextPageLink = nil
+	result, err = Extract(context.Background(), srv.Client(), srv.URL+"/story2", cfg)
+	if err != nil {
+		t.Fatalf("Extract error: %v", err)
+	}
+	if result.SinglePageURL != "" || !strings.Contains(result.Content, "Page 3 text") {
+		t.Errorf("a self-referencing single-page link must be ignored: %+v", result)
+	}
+
+	// No matching body leaves the content empty
+	cfg.Body = []string{"//article"}
+	result, err = Extract(context.Background(), srv.Client(), srv.URL+"/story", cfg)
+	if err != nil || result.Content != "" {
+		t.Errorf("expected empty content, got %q (%v)", result.Content, err)
+	}
+}
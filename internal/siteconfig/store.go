@@ -0,0 +1,211 @@
+package siteconfig
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"MrRSS/internal/utils/fileutil"
+)
+
+//go:embed all:rules
+var bundled embed.FS
+
+// Rule sources
+const (
+	SourceBundled = "bundled"
+	SourceUser    = "user"
+)
+
+// RuleNone is the per-feed rule name that turns site config rules off
+const RuleNone = "none"
+
+// ErrNotFound is returned for a rule name without a rule file
+var ErrNotFound = errors.New("site config not found")
+
+// Rule is a parsed rule file
+type Rule struct {
+	Name   string  `json:"name"`   // File name without .txt, e.g. "example.com" or ".example.com"
+	Source string  `json:"source"` // SourceBundled or SourceUser
+	Config *Config `json:"config,omitempty"`
+}
+
+// Store looks up rule files, preferring the user's files over bundled ones.
+// Rules are parsed once; a user rule is parsed again when its file changes.
+type Store struct {
+	dir string
+
+	bundledOnce sync.Once
+	bundled     map[string]parsedRule // By name
+
+	mu   sync.Mutex
+	user map[string]userRule // By path
+}
+
+// parsedRule is a parsed rule file, or the error parsing it
+type parsedRule struct {
+	rule *Rule
+	err  error
+}
+
+// userRule is a parsed user rule file and the file version it was parsed from
+type userRule struct {
+	parsedRule
+	modTime time.Time
+	size    int64
+}
+
+// NewStore creates a store reading user rules from dir. An empty dir means
+// the site_config directory in the data dir.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// userDir returns the directory of user rules, or "" when there is none
+func (s *Store) userDir() string {
+	if s.dir != "" {
+		return s.dir
+	}
+	dataDir, err := fileutil.GetDataDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dataDir, "site_config")
+}
+
+// Get returns the rule with the given name
+func (s *Store) Get(name string) (*Rule, error) {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".txt")
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return nil, ErrNotFound
+	}
+
+	if dir := s.userDir(); dir != "" {
+		if parsed, ok := s.userRule(dir, name); ok {
+			return parsed.rule, parsed.err
+		}
+	}
+	if parsed, ok := s.bundledRules()[name]; ok {
+		return parsed.rule, parsed.err
+	}
+	return nil, ErrNotFound
+}
+
+// userRule returns the user rule with the given name, parsing its file
+// unless it is unchanged since it was last parsed
+func (s *Store) userRule(dir, name string) (parsedRule, bool) {
+	path := filepath.Join(dir, name+".txt")
+	info, err := os.Stat(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil || info.IsDir() {
+		delete(s.user, path)
+		return parsedRule{}, false
+	}
+	if cached, ok := s.user[path]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.parsedRule, true
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return parsedRule{}, false
+	}
+	var parsed parsedRule
+	parsed.rule, parsed.err = parseRule(name, SourceUser, data)
+	if s.user == nil {
+		s.user = make(map[string]userRule)
+	}
+	s.user[path] = userRule{parsedRule: parsed, modTime: info.ModTime(), size: info.Size()}
+	return parsed, true
+}
+
+// bundledRules returns the bundled rules by name, parsing them on first use
+func (s *Store) bundledRules() map[string]parsedRule {
+	s.bundledOnce.Do(func() {
+		s.bundled = make(map[string]parsedRule)
+		_ = fs.WalkDir(bundled, "rules", func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(path, ".txt") {
+				return nil
+			}
+			data, err := bundled.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			name := strings.TrimSuffix(d.Name(), ".txt")
+			var parsed parsedRule
+			parsed.rule, parsed.err = parseRule(name, SourceBundled, data)
+			s.bundled[name] = parsed
+			return nil
+		})
+	})
+	return s.bundled
+}
+
+// Lookup returns the rule for a host: the exact host, the host without
+// "www.", then wildcard rules for each parent domain. It returns nil when
+// no rule applies.
+func (s *Store) Lookup(host string) *Rule {
+	host = strings.ToLower(host)
+	if h, _, ok := strings.Cut(host, ":"); ok {
+		host = h
+	}
+	if host == "" {
+		return nil
+	}
+
+	candidates := []string{host}
+	if trimmed := strings.TrimPrefix(host, "www."); trimmed != host {
+		candidates = append(candidates, trimmed)
+	}
+	labels := strings.Split(host, ".")
+	for i := 0; i < len(labels)-1; i++ {
+		candidates = append(candidates, "."+strings.Join(labels[i:], "."))
+	}
+
+	for _, name := range candidates {
+		if rule, err := s.Get(name); err == nil {
+			return rule
+		}
+	}
+	return nil
+}
+
+// List returns all available rules, without their configs, sorted by name.
+// User rules hide bundled rules of the same name.
+func (s *Store) List() []Rule {
+	sources := make(map[string]string)
+	for name := range s.bundledRules() {
+		sources[name] = SourceBundled
+	}
+	if dir := s.userDir(); dir != "" {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), ".txt") {
+				sources[strings.ToLower(strings.TrimSuffix(e.Name(), ".txt"))] = SourceUser
+			}
+		}
+	}
+
+	rules := make([]Rule, 0, len(sources))
+	for name, source := range sources {
+		rules = append(rules, Rule{Name: name, Source: source})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules
+}
+
+func parseRule(name, source string, data []byte) (*Rule, error) {
+	cfg, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return &Rule{Name: name, Source: source, Config: cfg}, nil
+}
@@ -0,0 +1,442 @@
+// Package snapshot keeps point-in-time copies of the database, taken with
+// VACUUM INTO and checked with PRAGMA integrity_check, rotates them, and
+// restores one in place of the database on the next start.
+package snapshot
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"MrRSS/internal/database"
+	"MrRSS/internal/utils/fileutil"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	namePrefix   = "mrrss-"
+	nameTime     = "20060102-150405"
+	manualSuffix = "-manual"
+	dbExt        = ".db"
+	gzipExt      = ".gz"
+
+	// restoreSuffix marks a verified snapshot waiting to replace the database
+	restoreSuffix = ".restore"
+	// replacedSuffix marks the database file that a restore replaced
+	replacedSuffix = ".before-restore"
+)
+
+var (
+	// ErrNotFound is returned for a snapshot name that does not exist
+	ErrNotFound = errors.New("snapshot not found")
+	// ErrRollbackFailed is returned when a restore failed halfway and the
+	// database files could not be put back. The database must not be opened,
+	// as it would be created empty.
+	ErrRollbackFailed = errors.New("database files could not be put back")
+)
+
+// rename is os.Rename, replaced in tests to make a restore fail halfway
+var rename = os.Rename
+
+// Snapshot describes a snapshot file
+type Snapshot struct {
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"created_at"`
+	Size       int64     `json:"size"`
+	Compressed bool      `json:"compressed"`
+	Manual     bool      `json:"manual"` // Taken on request; never removed by rotation
+}
+
+// Store keeps snapshot files in a directory of the data dir
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store in dir. An empty dir means the backups directory
+// in the data dir.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory of the snapshot files
+func (s *Store) Dir() (string, error) {
+	if s.dir != "" {
+		return s.dir, nil
+	}
+	dataDir, err := fileutil.GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "backups"), nil
+}
+
+// Create takes a snapshot of db, verifies it and stores it, gzip-compressed
+// when compress is set. A snapshot that fails the integrity check is not kept.
+func (s *Store) Create(db *database.DB, compress, manual bool) (*Snapshot, error) {
+	dir, err := s.Dir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	name := namePrefix + time.Now().UTC().Format(nameTime)
+	if manual {
+		name += manualSuffix
+	}
+	name += dbExt
+	tmp := filepath.Join(dir, "."+name+".tmp")
+	_ = os.Remove(tmp)
+	defer os.Remove(tmp)
+
+	if err := db.VacuumInto(tmp); err != nil {
+		return nil, fmt.Errorf("vacuum into snapshot: %w", err)
+	}
+	if err := CheckIntegrity(tmp); err != nil {
+		return nil, err
+	}
+
+	if compress {
+		name += gzipExt
+		if err := gzipFile(tmp, tmp+gzipExt); err != nil {
+			_ = os.Remove(tmp + gzipExt)
+			return nil, err
+		}
+		tmp += gzipExt
+		defer os.Remove(tmp)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+	return s.stat(name)
+}
+
+// List returns the snapshots, newest first
+func (s *Store) List() ([]Snapshot, error) {
+	dir, err := s.Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]Snapshot, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if snap, err := s.stat(entry.Name()); err == nil {
+			snapshots = append(snapshots, *snap)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Path returns the file of a snapshot
+func (s *Store) Path(name string) (string, error) {
+	if _, err := parseName(name); err != nil {
+		return "", err
+	}
+	dir, err := s.Dir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// Delete removes a snapshot
+func (s *Store) Delete(name string) error {
+	path, err := s.Path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Rotate removes the scheduled snapshots that are neither among the newest
+// of the last keepDaily days nor among the newest of the last keepWeekly
+// weeks, and returns the names of the removed snapshots. Manual snapshots
+// are kept.
+func (s *Store) Rotate(keepDaily, keepWeekly int) ([]string, error) {
+	snapshots, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	removed := []string{}
+	for _, snap := range snapshots {
+		if snap.Manual {
+			continue
+		}
+		local := snap.CreatedAt.Local()
+		day := local.Format("2006-01-02")
+		year, week := local.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%02d", year, week)
+
+		keep := false
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep = true
+		}
+		if !weeks[weekKey] && len(weeks) < keepWeekly {
+			weeks[weekKey] = true
+			keep = true
+		}
+		if keep {
+			continue
+		}
+		if err := s.Delete(snap.Name); err != nil {
+			return removed, err
+		}
+		removed = append(removed, snap.Name)
+	}
+	return removed, nil
+}
+
+// StageRestore verifies a snapshot and places it next to the database file
+// at dbPath, to replace the database on the next start.
+func (s *Store) StageRestore(name, dbPath string) error {
+	path, err := s.Path(name)
+	if err != nil {
+		return err
+	}
+
+	staged := dbPath + restoreSuffix
+	tmp := staged + ".tmp"
+	defer os.Remove(tmp)
+	if strings.HasSuffix(name, gzipExt) {
+		err = gunzipFile(path, tmp)
+	} else {
+		err = copyFile(path, tmp)
+	}
+	if err != nil {
+		return err
+	}
+	if err := CheckIntegrity(tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, staged)
+}
+
+// PendingRestore reports whether a restore is staged for the database at dbPath
+func PendingRestore(dbPath string) bool {
+	_, err := os.Stat(dbPath + restoreSuffix)
+	return err == nil
+}
+
+// CancelRestore removes a staged restore
+func CancelRestore(dbPath string) error {
+	err := os.Remove(dbPath + restoreSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// ApplyPendingRestore replaces the database at dbPath with a staged snapshot,
+// keeping the replaced database as <dbPath>.before-restore. It must run
+// before the database is opened, and reports whether a restore was applied.
+// When the restore fails halfway the database files are put back; if that
+// fails too the error wraps ErrRollbackFailed.
+func ApplyPendingRestore(dbPath string) (bool, error) {
+	staged := dbPath + restoreSuffix
+	if _, err := os.Stat(staged); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	replaced := dbPath + replacedSuffix
+	suffixes := []string{"", "-wal", "-shm"}
+	for _, suffix := range suffixes {
+		if err := os.Remove(replaced + suffix); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+
+	// Files moved aside so far, to be moved back on failure
+	var moved []string
+	rollback := func(err error) (bool, error) {
+		for i := len(moved) - 1; i >= 0; i-- {
+			suffix := moved[i]
+			if rerr := rename(replaced+suffix, dbPath+suffix); rerr != nil {
+				return false, fmt.Errorf("%w: %v (restoring after: %v)", ErrRollbackFailed, rerr, err)
+			}
+		}
+		return false, err
+	}
+	for _, suffix := range suffixes {
+		if err := rename(dbPath+suffix, replaced+suffix); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return rollback(err)
+		}
+		moved = append(moved, suffix)
+	}
+	if err := rename(staged, dbPath); err != nil {
+		return rollback(err)
+	}
+	return true, nil
+}
+
+// CheckIntegrity opens the database file at path read-only and runs
+// PRAGMA integrity_check on it
+func CheckIntegrity(path string) error {
+	conn, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rows, err := conn.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return fmt.Errorf("integrity check: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return err
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("integrity check: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// stat describes the snapshot file name
+func (s *Store) stat(name string) (*Snapshot, error) {
+	createdAt, err := parseName(name)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := s.Dir()
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		Name:       name,
+		CreatedAt:  createdAt,
+		Size:       info.Size(),
+		Compressed: strings.HasSuffix(name, gzipExt),
+		Manual:     strings.Contains(name, manualSuffix+dbExt),
+	}, nil
+}
+
+// parseName returns the time a snapshot was taken from its file name, and
+// rejects names that are not snapshot names
+func parseName(name string) (time.Time, error) {
+	base := strings.TrimSuffix(name, gzipExt)
+	if !strings.HasPrefix(base, namePrefix) || !strings.HasSuffix(base, dbExt) {
+		return time.Time{}, ErrNotFound
+	}
+	stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(base, namePrefix), dbExt), manualSuffix)
+	t, err := time.Parse(nameTime, stamp)
+	if err != nil {
+		return time.Time{}, ErrNotFound
+	}
+	return t, nil
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func gunzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, zr); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
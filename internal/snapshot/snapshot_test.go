@@ -0,0 +1,169 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"MrRSS/internal/database"
+)
+
+func TestCreateAndRestoreSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "rss.db")
+	db, err := database.NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	if err := db.Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := db.SetSetting("language", "before"); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+
+	store := NewStore(filepath.Join(dir, "backups"))
+	for _, compress := range []bool{true, false} {
+		snap, err := store.Create(db, compress, !compress)
+		if err != nil {
+			t.Fatalf("Create(compress=%v): %v", compress, err)
+		}
+		if snap.Compressed != compress || snap.Manual == compress || snap.Size == 0 {
+			t.Errorf("Create(compress=%v) = %+v", compress, snap)
+		}
+		if !compress {
+			continue
+		}
+		// Both snapshots would get the same name within one second
+		time.Sleep(time.Second)
+	}
+	snapshots, err := store.List()
+	if err != nil || len(snapshots) != 2 {
+		t.Fatalf("List = %+v, %v, want 2 snapshots", snapshots, err)
+	}
+
+	if err := db.SetSetting("language", "after"); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+	compressed := snapshots[1]
+	if !compressed.Compressed {
+		t.Fatalf("oldest snapshot %+v is not the compressed one", compressed)
+	}
+	if err := store.StageRestore(compressed.Name, dbPath); err != nil {
+		t.Fatalf("StageRestore: %v", err)
+	}
+	if !PendingRestore(dbPath) {
+		t.Fatal("PendingRestore = false after staging")
+	}
+	db.Close()
+
+	applied, err := ApplyPendingRestore(dbPath)
+	if err != nil || !applied {
+		t.Fatalf("ApplyPendingRestore = %v, %v", applied, err)
+	}
+	if _, err := os.Stat(dbPath + replacedSuffix); err != nil {
+		t.Errorf("replaced database not kept: %v", err)
+	}
+
+	db, err = database.NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("NewDB after restore: %v", err)
+	}
+	defer db.Close()
+	if err := db.Init(); err != nil {
+		t.Fatalf("Init after restore: %v", err)
+	}
+	if value, _ := db.GetSetting("language"); value != "before" {
+		t.Errorf("language after restore = %q, want %q", value, "before")
+	}
+}
+
+func TestApplyPendingRestoreRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "rss.db")
+	for path, data := range map[string]string{dbPath: "current", dbPath + "-wal": "wal", dbPath + restoreSuffix: "staged"} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() { rename = os.Rename }()
+
+	// Moving the staged snapshot in fails, so both moved files go back
+	failed := errors.New("disk full")
+	rename = func(from, to string) error {
+		if from == dbPath+restoreSuffix {
+			return failed
+		}
+		return os.Rename(from, to)
+	}
+	if applied, err := ApplyPendingRestore(dbPath); applied || !errors.Is(err, failed) || errors.Is(err, ErrRollbackFailed) {
+		t.Fatalf("ApplyPendingRestore = %v, %v, want the rename error", applied, err)
+	}
+	for path, want := range map[string]string{dbPath: "current", dbPath + "-wal": "wal", dbPath + restoreSuffix: "staged"} {
+		if data, err := os.ReadFile(path); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v after rollback, want %q", filepath.Base(path), data, err, want)
+		}
+	}
+
+	// Moving the files back fails too
+	rename = func(from, to string) error {
+		if from == dbPath+restoreSuffix || to == dbPath {
+			return failed
+		}
+		return os.Rename(from, to)
+	}
+	if _, err := ApplyPendingRestore(dbPath); !errors.Is(err, ErrRollbackFailed) {
+		t.Fatalf("ApplyPendingRestore error = %v, want ErrRollbackFailed", err)
+	}
+}
+
+func TestRotateKeepsDailyAndWeeklySnapshots(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	// One scheduled snapshot every 12 hours for six weeks, and a manual one
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)
+	var names []string
+	for at := start; at.Before(start.AddDate(0, 0, 42)); at = at.Add(12 * time.Hour) {
+		names = append(names, namePrefix+at.UTC().Format(nameTime)+dbExt+gzipExt)
+	}
+	names = append(names, namePrefix+start.UTC().Format(nameTime)+manualSuffix+dbExt)
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := store.Rotate(3, 4); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	snapshots, err := store.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	// The newest of each of the last 3 days (the newest week's snapshot is
+	// among them), the newest of the 3 weeks before, and the manual one
+	if len(snapshots) != 7 {
+		t.Fatalf("kept %d snapshots, want 7: %+v", len(snapshots), snapshots)
+	}
+	if !snapshots[len(snapshots)-1].Manual {
+		t.Errorf("manual snapshot was rotated")
+	}
+	for i, snap := range snapshots[:3] {
+		want := start.AddDate(0, 0, 41-i).Add(12 * time.Hour)
+		if !snap.CreatedAt.Equal(want) {
+			t.Errorf("daily snapshot %d taken at %v, want %v", i, snap.CreatedAt.Local(), want)
+		}
+	}
+}
+
+func TestParseNameRejectsOtherFiles(t *testing.T) {
+	for _, name := range []string{"rss.db", "../mrrss-20260101-000000.db/x", "mrrss-2026.db", ".mrrss-20260101-000000.db.tmp"} {
+		if _, err := parseName(name); err == nil {
+			t.Errorf("parseName(%q) accepted", name)
+		}
+	}
+}
@@ -119,6 +119,15 @@ func GetDBPath() (string, error) {
 	return filepath.Join(dataDir, "rss.db"), nil
 }
 
+// GetColdStoragePath returns the full path to the cold storage archive file.
+func GetColdStoragePath() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "archive.db"), nil
+}
+
 // GetLogPath returns the full path to the debug log file.
 func GetLogPath() (string, error) {
 	dataDir, err := GetDataDir()
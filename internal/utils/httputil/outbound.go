@@ -0,0 +1,352 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxRedirects is the redirect limit used when a policy sets none
+const DefaultMaxRedirects = 5
+
+var (
+	// ErrBlockedAddress is returned when a URL points at, or resolves to, a
+	// private, loopback, link-local or otherwise internal address
+	ErrBlockedAddress = errors.New("destination address is not allowed")
+	// ErrTooManyRedirects is returned when a fetch exceeds the redirect limit
+	ErrTooManyRedirects = errors.New("too many redirects")
+	// ErrBodyTooLarge is returned when a response exceeds the body size limit
+	ErrBodyTooLarge = errors.New("response body too large")
+	// ErrUnexpectedContentType is returned when a successful response has a
+	// content type the caller does not accept
+	ErrUnexpectedContentType = errors.New("unexpected content type")
+)
+
+// blockedPrefixes are special-purpose ranges not covered by the netip helpers
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),  // documentation
+	netip.MustParsePrefix("fec0::/10"),      // deprecated site-local
+	netip.MustParsePrefix("100::/64"),       // discard-only
+	netip.MustParsePrefix("2002:a9fe::/32"), // 6to4 of 169.254.0.0/16
+	netip.MustParsePrefix("2002:7f00::/24"), // 6to4 of 127.0.0.0/8
+	netip.MustParsePrefix("2002:0a00::/24"), // 6to4 of 10.0.0.0/8
+	netip.MustParsePrefix("2002:c0a8::/32"), // 6to4 of 192.168.0.0/16
+	netip.MustParsePrefix("2002:ac10::/28"), // 6to4 of 172.16.0.0/12
+}
+
+// IsBlockedIP reports whether ip is an internal address that outbound fetches
+// must not reach: loopback, private, link-local (including cloud metadata
+// endpoints), multicast, unspecified and other special-purpose ranges.
+func IsBlockedIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// OutboundPolicy restricts where server-side fetches of user-supplied URLs may
+// connect and what responses they accept. The zero value blocks internal
+// addresses, allows DefaultMaxRedirects redirects and accepts any body.
+type OutboundPolicy struct {
+	// AllowPrivate disables address blocking, e.g. for a feed the user has
+	// explicitly marked as living on the local network
+	AllowPrivate bool
+	// AllowHost reports whether a host may resolve to an internal address.
+	// It backs the per-feed allowlist for intranet feeds.
+	AllowHost func(host string) bool
+	// MaxRedirects caps the redirects followed; 0 means DefaultMaxRedirects
+	MaxRedirects int
+	// MaxBodyBytes caps the response body size; 0 means unlimited
+	MaxBodyBytes int64
+	// ContentTypes lists accepted media types for successful responses. An
+	// entry ending in "/" matches a whole type, e.g. "image/". Responses
+	// without a Content-Type are accepted.
+	ContentTypes []string
+}
+
+// hostAllowed reports whether host may reach internal addresses
+func (p OutboundPolicy) hostAllowed(host string) bool {
+	if p.AllowPrivate {
+		return true
+	}
+	return p.AllowHost != nil && p.AllowHost(strings.ToLower(strings.TrimSuffix(host, ".")))
+}
+
+// checkAddr returns ErrBlockedAddress if host may not connect to ip
+func (p OutboundPolicy) checkAddr(host string, ip netip.Addr) error {
+	if !IsBlockedIP(ip) || p.hostAllowed(host) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, ip)
+}
+
+// checkHost resolves host and fails if any of its addresses is blocked
+func (p OutboundPolicy) checkHost(ctx context.Context, host string) error {
+	if p.hostAllowed(host) {
+		return nil
+	}
+	ips, err := lookupHost(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if err := p.checkAddr(host, ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateURL checks that rawURL is an absolute http(s) URL that does not
+// name an internal address directly. Hostnames are checked again after DNS
+// resolution when a client created by NewOutboundClient connects.
+func ValidateURL(rawURL string, policy OutboundPolicy) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.New("invalid URL format")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("URL must use HTTP or HTTPS")
+	}
+	host := u.Hostname()
+	if host == "" {
+		return errors.New("URL must include a host")
+	}
+	if policy.hostAllowed(host) {
+		return nil
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return policy.checkAddr(host, ip)
+	}
+	lower := strings.ToLower(strings.TrimSuffix(host, "."))
+	if lower == "localhost" || strings.HasSuffix(lower, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	return nil
+}
+
+// lookupHost resolves host, accepting IP literals without a DNS query
+func lookupHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{ip}, nil
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	return ips, nil
+}
+
+// outboundDialer resolves hosts itself and connects only to addresses that
+// passed the policy, so a DNS answer cannot change between check and connect
+type outboundDialer struct {
+	policy  OutboundPolicy
+	dialer  net.Dialer
+	proxies sync.Map // proxy addresses configured by the user, always reachable
+}
+
+func (d *outboundDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if _, ok := d.proxies.Load(addr); ok || d.policy.AllowPrivate {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := lookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		if err := d.policy.checkAddr(host, ip); err != nil {
+			lastErr = err
+			continue
+		}
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// OutboundTransport enforces an OutboundPolicy on top of a base transport
+type OutboundTransport struct {
+	Base   *http.Transport
+	Policy OutboundPolicy
+	dialer *outboundDialer
+}
+
+// NewOutboundTransport wraps base so its connections and responses follow
+// policy. base must not be shared, as its DialContext is replaced.
+func NewOutboundTransport(base *http.Transport, policy OutboundPolicy) *OutboundTransport {
+	d := &outboundDialer{
+		policy: policy,
+		dialer: net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+	}
+	base.DialContext = d.DialContext
+	return &OutboundTransport{Base: base, Policy: policy, dialer: d}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *OutboundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := ValidateURL(req.URL.String(), t.Policy); err != nil {
+		return nil, err
+	}
+
+	if t.Base.Proxy != nil {
+		proxyURL, err := t.Base.Proxy(req)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL != nil {
+			// The transport dials the proxy rather than the target, so the
+			// target is resolved and checked here instead
+			t.dialer.proxies.Store(canonicalAddr(proxyURL), struct{}{})
+			if err := t.Policy.checkHost(req.Context(), req.URL.Hostname()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.Policy.checkResponse(resp)
+}
+
+// checkResponse applies the content type and body size limits
+func (p OutboundPolicy) checkResponse(resp *http.Response) (*http.Response, error) {
+	if len(p.ContentTypes) > 0 && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if contentType := resp.Header.Get("Content-Type"); contentType != "" && !p.acceptsContentType(contentType) {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: %s", ErrUnexpectedContentType, contentType)
+		}
+	}
+
+	if p.MaxBodyBytes > 0 {
+		if resp.ContentLength > p.MaxBodyBytes {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: %d bytes", ErrBodyTooLarge, resp.ContentLength)
+		}
+		resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: p.MaxBodyBytes}
+	}
+	return resp, nil
+}
+
+// acceptsContentType reports whether contentType matches ContentTypes
+func (p OutboundPolicy) acceptsContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	}
+	for _, accepted := range p.ContentTypes {
+		if strings.HasSuffix(accepted, "/") && strings.HasPrefix(mediaType, accepted) {
+			return true
+		}
+		if mediaType == accepted {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckRedirect implements http.Client.CheckRedirect with the policy's limit
+func (p OutboundPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	limit := p.MaxRedirects
+	if limit <= 0 {
+		limit = DefaultMaxRedirects
+	}
+	if len(via) >= limit {
+		return ErrTooManyRedirects
+	}
+	return nil
+}
+
+// limitedBody fails reads past the body size limit instead of truncating
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	// Allow one extra byte so a body of exactly the limit is not rejected
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), ErrBodyTooLarge
+	}
+	return n, err
+}
+
+// canonicalAddr returns the host:port a transport dials for a proxy URL
+func canonicalAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// NewOutboundClient creates an HTTP client for fetching user-supplied URLs.
+// Connections to internal addresses are refused after DNS resolution unless
+// the policy allows them, and redirects, body size and content type are
+// limited as configured.
+func NewOutboundClient(proxyURL string, timeout time.Duration, policy OutboundPolicy) (*http.Client, error) {
+	client, err := CreateHTTPClient(proxyURL, timeout)
+	if err != nil {
+		return nil, err
+	}
+	client.Transport = NewOutboundTransport(client.Transport.(*http.Transport), policy)
+	client.CheckRedirect = policy.CheckRedirect
+	return client, nil
+}
@@ -0,0 +1,142 @@
+package httputil
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"93.184.216.34", false},
+		{"2606:4700::1111", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsBlockedIP(netip.MustParseAddr(tt.ip)); got != tt.blocked {
+			t.Errorf("IsBlockedIP(%s) = %v, want %v", tt.ip, got, tt.blocked)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://example.com/feed.xml", false},
+		{"ftp://example.com/file", true},
+		{"http://169.254.169.254/latest/meta-data/", true},
+		{"http://localhost:8080/admin", true},
+		{"http://[::1]/", true},
+		{"http://192.168.1.1/", true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateURL(tt.url, OutboundPolicy{}); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateURL(%s) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+
+	allowed := OutboundPolicy{AllowHost: func(host string) bool { return host == "192.168.1.1" }}
+	if err := ValidateURL("http://192.168.1.1/feed", allowed); err != nil {
+		t.Errorf("allowlisted host rejected: %v", err)
+	}
+}
+
+func TestOutboundClientBlocksInternalAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	client, err := NewOutboundClient("", 5*time.Second, OutboundPolicy{})
+	if err != nil {
+		t.Fatalf("NewOutboundClient returned error: %v", err)
+	}
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected ErrBlockedAddress, got %v", err)
+	}
+
+	// Hostnames are checked after resolution, when the dialer connects
+	d := &outboundDialer{}
+	if _, err := d.DialContext(t.Context(), "tcp", "localhost:80"); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected dial of localhost to be blocked, got %v", err)
+	}
+
+	allowed, err := NewOutboundClient("", 5*time.Second, OutboundPolicy{
+		AllowHost: func(host string) bool { return host == "127.0.0.1" },
+	})
+	if err != nil {
+		t.Fatalf("NewOutboundClient returned error: %v", err)
+	}
+	resp, err := allowed.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("allowlisted host rejected: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestOutboundClientLimits(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client, err := NewOutboundClient("", 5*time.Second, OutboundPolicy{
+		AllowPrivate: true,
+		MaxRedirects: 2,
+		MaxBodyBytes: 50,
+		ContentTypes: []string{"text/html"},
+	})
+	if err != nil {
+		t.Fatalf("NewOutboundClient returned error: %v", err)
+	}
+
+	if _, err := client.Get(srv.URL + "/loop"); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("expected ErrTooManyRedirects, got %v", err)
+	}
+
+	resp, err := client.Get(srv.URL + "/big")
+	if err == nil {
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("expected ErrBodyTooLarge, got %v", err)
+	}
+
+	if _, err := client.Get(srv.URL + "/image"); !errors.Is(err, ErrUnexpectedContentType) {
+		t.Errorf("expected ErrUnexpectedContentType, got %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+package textutil
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// sanitizeAttrs lists the attributes allowed on each tag. Tags missing from
+// the map are unwrapped, keeping their children, unless they are listed in
+// droppedTags.
+var sanitizeAttrs = map[atom.Atom][]string{
+	atom.A:          {"href", "target"},
+	atom.Abbr:       nil,
+	atom.Address:    nil,
+	atom.Article:    nil,
+	atom.Aside:      nil,
+	atom.Audio:      {"src", "controls", "loop", "muted", "preload"},
+	atom.B:          nil,
+	atom.Bdi:        nil,
+	atom.Bdo:        nil,
+	atom.Blockquote: {"cite"},
+	atom.Br:         nil,
+	atom.Caption:    nil,
+	atom.Cite:       nil,
+	atom.Code:       {"class"},
+	atom.Col:        {"span"},
+	atom.Colgroup:   {"span"},
+	atom.Dd:         nil,
+	atom.Del:        {"cite", "datetime"},
+	atom.Details:    {"open"},
+	atom.Dfn:        nil,
+	atom.Div:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.Footer:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Header:     nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Iframe:     {"src", "width", "height", "allowfullscreen"},
+	atom.Img:        {"src", "srcset", "sizes", "alt", "width", "height", "loading", "data-src", "data-original"},
+	atom.Ins:        {"cite", "datetime"},
+	atom.Kbd:        nil,
+	atom.Li:         {"value"},
+	atom.Mark:       nil,
+	atom.Ol:         {"start", "reversed", "type"},
+	atom.P:          nil,
+	atom.Picture:    nil,
+	atom.Pre:        {"class"},
+	atom.Q:          {"cite"},
+	atom.Rp:         nil,
+	atom.Rt:         nil,
+	atom.Ruby:       nil,
+	atom.S:          nil,
+	atom.Samp:       nil,
+	atom.Section:    nil,
+	atom.Small:      nil,
+	atom.Source:     {"src", "srcset", "sizes", "type", "media"},
+	atom.Span:       nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Summary:    nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan", "headers"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan", "headers", "scope"},
+	atom.Thead:      nil,
+	atom.Time:       {"datetime"},
+	atom.Tr:         nil,
+	atom.Track:      {"src", "kind", "srclang", "label", "default"},
+	atom.U:          nil,
+	atom.Ul:         nil,
+	atom.Var:        nil,
+	atom.Video:      {"src", "poster", "controls", "width", "height", "loop", "muted", "preload", "playsinline"},
+	atom.Wbr:        nil,
+}
+
+// globalAttrs are allowed on every kept tag
+var globalAttrs = []string{"title", "lang", "dir", "id"}
+
+// droppedTags are removed together with their contents
+var droppedTags = map[atom.Atom]bool{
+	atom.Applet: true, atom.Base: true, atom.Button: true, atom.Embed: true,
+	atom.Form: true, atom.Frame: true, atom.Frameset: true, atom.Head: true,
+	atom.Input: true, atom.Link: true, atom.Math: true, atom.Meta: true,
+	atom.Noembed: true, atom.Noframes: true, atom.Noscript: true, atom.Object: true,
+	atom.Option: true, atom.Script: true, atom.Select: true, atom.Style: true,
+	atom.Svg: true, atom.Template: true, atom.Textarea: true, atom.Title: true,
+}
+
+// urlAttrs hold URLs and are checked against the allowed schemes
+var urlAttrs = []string{"href", "src", "poster", "cite", "data-src", "data-original"}
+
+// embedSource is an iframe source allowed in article content
+type embedSource struct {
+	host       string
+	pathPrefix string
+}
+
+// allowedEmbeds are the video players whose iframes are kept, matching the
+// embed URLs the feed fetcher builds for YouTube and Bilibili videos
+var allowedEmbeds = []embedSource{
+	{"www.youtube.com", "/embed/"},
+	{"youtube.com", "/embed/"},
+	{"www.youtube-nocookie.com", "/embed/"},
+	{"youtube-nocookie.com", "/embed/"},
+	{"www.bilibili.com", "/blackboard/html5mobileplayer.html"},
+	{"player.bilibili.com", "/player.html"},
+}
+
+// embedSandbox is set on kept iframes; the players need their own scripts
+const embedSandbox = "allow-scripts allow-same-origin allow-popups allow-presentation"
+
+// SanitizeHTML reduces HTML to an allowlist of tags, attributes and URL
+// schemes so that publisher markup can be rendered safely. Scripts, styles,
+// forms and plugins are removed with their contents, unknown tags are
+// unwrapped, and iframes are kept only for YouTube and Bilibili players.
+func SanitizeHTML(htmlContent string) string {
+	if htmlContent == "" {
+		return ""
+	}
+
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragmentWithOptions(strings.NewReader(htmlContent), context, html.ParseOptionEnableScripting(false))
+	if err != nil {
+		return html.EscapeString(htmlContent)
+	}
+
+	var b strings.Builder
+	for _, n := range nodes {
+		for _, kept := range sanitizeNode(n) {
+			_ = html.Render(&b, kept)
+		}
+	}
+	return b.String()
+}
+
+// sanitizeNode returns the nodes that replace n in sanitized output: n
+// itself with its attributes and children filtered, n's sanitized children
+// if it is unwrapped, or nothing if it is dropped
+func sanitizeNode(n *html.Node) []*html.Node {
+	switch n.Type {
+	case html.TextNode:
+		return []*html.Node{n}
+	case html.ElementNode:
+	default:
+		// Comments, doctypes and stray documents
+		return nil
+	}
+
+	// Elements from SVG and MathML content are not in the allowlist
+	if n.Namespace != "" || droppedTags[n.DataAtom] {
+		return nil
+	}
+
+	var children []*html.Node
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		n.RemoveChild(c)
+		// Iframe contents are raw text that is never displayed
+		if n.DataAtom != atom.Iframe {
+			children = append(children, sanitizeNode(c)...)
+		}
+		c = next
+	}
+
+	allowed, ok := sanitizeAttrs[n.DataAtom]
+	if !ok {
+		return children
+	}
+
+	n.Attr = sanitizeAttributes(n, allowed)
+	if n.DataAtom == atom.Iframe {
+		src, _ := getAttr(n, "src")
+		if !isAllowedEmbed(src) {
+			return nil
+		}
+		n.Attr = append(n.Attr,
+			html.Attribute{Key: "sandbox", Val: embedSandbox},
+			html.Attribute{Key: "referrerpolicy", Val: "strict-origin-when-cross-origin"})
+	}
+	for _, c := range children {
+		n.AppendChild(c)
+	}
+	return []*html.Node{n}
+}
+
+// sanitizeAttributes returns the attributes of n that the policy allows
+func sanitizeAttributes(n *html.Node, allowed []string) []html.Attribute {
+	var kept []html.Attribute
+	for _, a := range n.Attr {
+		if a.Namespace != "" || (!slices.Contains(allowed, a.Key) && !slices.Contains(globalAttrs, a.Key)) {
+			continue
+		}
+
+		switch {
+		case slices.Contains(urlAttrs, a.Key):
+			if !isSafeURL(a.Val, a.Key == "href", n.DataAtom == atom.Img && a.Key == "src") {
+				continue
+			}
+		case a.Key == "srcset":
+			if !isSafeSrcset(a.Val) {
+				continue
+			}
+		case a.Key == "target":
+			if a.Val != "_blank" {
+				continue
+			}
+		case a.Key == "class":
+			// Only language hints for syntax highlighting of code blocks
+			classes := slices.DeleteFunc(strings.Fields(a.Val), func(c string) bool {
+				return !strings.HasPrefix(c, "language-") && !strings.HasPrefix(c, "lang-")
+			})
+			if len(classes) == 0 {
+				continue
+			}
+			a.Val = strings.Join(classes, " ")
+		}
+		kept = append(kept, a)
+	}
+
+	// Links open outside the reader without exposing it to the target page
+	if n.DataAtom == atom.A && slices.ContainsFunc(kept, func(a html.Attribute) bool { return a.Key == "href" }) {
+		kept = append(kept, html.Attribute{Key: "rel", Val: "noopener noreferrer nofollow"})
+	}
+	return kept
+}
+
+// isSafeURL reports whether a URL attribute value uses an allowed scheme.
+// Relative URLs are allowed; mailto: only for links and data: only for
+// raster images.
+func isSafeURL(raw string, isLink, isImage bool) bool {
+	raw = strings.TrimSpace(raw)
+	// Browsers ignore control characters and whitespace inside the scheme
+	if strings.IndexFunc(raw, func(r rune) bool { return r < ' ' || r == 0x7f }) >= 0 {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https":
+		return true
+	case "mailto":
+		return isLink
+	case "data":
+		lower := strings.ToLower(raw)
+		return isImage && slices.ContainsFunc([]string{"png", "gif", "jpeg", "jpg", "webp", "avif"}, func(t string) bool {
+			return strings.HasPrefix(lower, "data:image/"+t+";") || strings.HasPrefix(lower, "data:image/"+t+",")
+		})
+	}
+	return false
+}
+
+// isSafeSrcset reports whether every candidate of a srcset uses an allowed scheme
+func isSafeSrcset(srcset string) bool {
+	for candidate := range strings.SplitSeq(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 && !isSafeURL(fields[0], false, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// isAllowedEmbed reports whether src points at an allowed video player
+func isAllowedEmbed(src string) bool {
+	u, err := url.Parse(strings.TrimSpace(src))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http" && u.Scheme != "") || u.User != nil {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	return slices.ContainsFunc(allowedEmbeds, func(e embedSource) bool {
+		return host == e.host && strings.HasPrefix(u.Path, e.pathPrefix)
+	})
+}
+
+func getAttr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,174 @@
+package textutil
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// xssVectors is a corpus of well-known XSS payloads, adapted from the OWASP
+// filter evasion cheat sheet and html5sec.org
+var xssVectors = []string{
+	`<script>alert(1)</script>`,
+	`<SCRIPT SRC=https://xss.example/xss.js></SCRIPT>`,
+	`<scr<script>ipt>alert(1)</scr</script>ipt>`,
+	`<img src=x onerror=alert(1)>`,
+	`<IMG SRC="javascript:alert('XSS');">`,
+	`<IMG SRC=JaVaScRiPt:alert('XSS')>`,
+	`<IMG SRC=&#106;&#97;&#118;&#97;&#115;&#99;&#114;&#105;&#112;&#116;&#58;&#97;&#108;&#101;&#114;&#116;&#40;&#39;&#88;&#83;&#83;&#39;&#41;>`,
+	`<IMG SRC=&#x6A&#x61&#x76&#x61&#x73&#x63&#x72&#x69&#x70&#x74&#x3A&#x61&#x6C&#x65&#x72&#x74&#x28&#x27&#x58&#x53&#x53&#x27&#x29>`,
+	`<IMG SRC="jav	ascript:alert('XSS');">`,
+	`<IMG SRC="jav&#x0A;ascript:alert('XSS');">`,
+	`<IMG SRC=" &#14;  javascript:alert('XSS');">`,
+	`<a href="javascript&colon;alert(1)">x</a>`,
+	`<a href="  JAVASCRIPT:alert(1)">x</a>`,
+	`<a href="vbscript:msgbox(1)">x</a>`,
+	`<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`,
+	`<img src="data:image/svg+xml;base64,PHN2ZyBvbmxvYWQ9YWxlcnQoMSk+">`,
+	`<svg onload=alert(1)>`,
+	`<svg><script>alert(1)</script></svg>`,
+	`<svg><a xlink:href="javascript:alert(1)"><text>x</text></a></svg>`,
+	`<math><mtext><table><mglyph><style><img src=x onerror=alert(1)>`,
+	`<body onload=alert(1)>`,
+	`<iframe src="javascript:alert(1)"></iframe>`,
+	`<iframe src="https://evil.example/embed/"></iframe>`,
+	`<iframe srcdoc="<script>alert(1)</script>"></iframe>`,
+	`<object data="javascript:alert(1)"></object>`,
+	`<embed src="https://evil.example/x.swf">`,
+	`<form action="javascript:alert(1)"><button>x</button></form>`,
+	`<input onfocus=alert(1) autofocus>`,
+	`<details open ontoggle=alert(1)>`,
+	`<video><source onerror="alert(1)"></video>`,
+	`<audio src=x onerror=alert(1)>`,
+	`<div style="background:url(javascript:alert(1))">x</div>`,
+	`<div style="width: expression(alert(1))">x</div>`,
+	`<style>@import 'https://evil.example/x.css';</style>`,
+	`<link rel=stylesheet href="https://evil.example/x.css">`,
+	`<meta http-equiv="refresh" content="0;url=javascript:alert(1)">`,
+	`<base href="javascript:alert(1)//">`,
+	`<noscript><p title="</noscript><img src=x onerror=alert(1)>">`,
+	`<textarea><script>alert(1)</script></textarea>`,
+	`<xmp><img src=x onerror=alert(1)></xmp>`,
+	`<title><img src=x onerror=alert(1)></title>`,
+	`<template><script>alert(1)</script></template>`,
+	`<!--<img src="--><img src=x onerror=alert(1)//">`,
+	`<a href="#" onmouseover="alert(1)">x</a>`,
+	`<img srcset="https://ok.example/a.png 1x, javascript:alert(1) 2x">`,
+	`<p><a href=x id="location">x</a><a name=cookie>y</a></p>`,
+	`<blockquote cite="javascript:alert(1)">x</blockquote>`,
+	`<video poster=javascript:alert(1)//></video>`,
+	`<img src=x:alert(alt) onerror=eval(src) alt=0>`,
+	`<isindex type=image src=1 onerror=alert(1)>`,
+	`<table background="javascript:alert(1)"><tr><td>x</td></tr></table>`,
+	`<a href="https://example.com" target="_top" rel="opener">x</a>`,
+}
+
+// assertSafeMarkup tokenizes sanitized output and fails if any tag,
+// attribute or URL falls outside the policy
+func assertSafeMarkup(t *testing.T, vector, out string) {
+	t.Helper()
+	z := html.NewTokenizer(strings.NewReader(out))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		tok := z.Token()
+		allowed, ok := sanitizeAttrs[tok.DataAtom]
+		if !ok {
+			t.Errorf("SanitizeHTML(%q) = %q, kept tag <%s>", vector, out, tok.Data)
+		}
+		for _, a := range tok.Attr {
+			generated := (a.Key == "rel" && tok.DataAtom == atom.A) || ((a.Key == "sandbox" || a.Key == "referrerpolicy") && tok.DataAtom == atom.Iframe)
+			if !generated && !slices.Contains(allowed, a.Key) && !slices.Contains(globalAttrs, a.Key) {
+				t.Errorf("SanitizeHTML(%q) = %q, kept attribute %s on <%s>", vector, out, a.Key, tok.Data)
+			}
+			lower := strings.ToLower(a.Val)
+			for _, bad := range []string{"javascript:", "vbscript:", "data:text", "data:image/svg", "evil.example", "_top"} {
+				if strings.Contains(lower, bad) {
+					t.Errorf("SanitizeHTML(%q) = %q, kept %q in %s", vector, out, bad, a.Key)
+				}
+			}
+		}
+	}
+}
+
+func TestSanitizeHTMLNeutralizesXSSVectors(t *testing.T) {
+	for _, vector := range xssVectors {
+		out := SanitizeHTML(vector)
+		assertSafeMarkup(t, vector, out)
+		// Sanitized output must survive a second pass unchanged, otherwise
+		// a browser reparsing it could see different markup
+		if again := SanitizeHTML(out); again != out {
+			t.Errorf("SanitizeHTML is not stable for %q: %q then %q", vector, out, again)
+		}
+	}
+}
+
+func TestSanitizeHTMLKeepsArticleMarkup(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "figure with caption",
+			in:   `<figure class="wp-block-image"><img src="https://example.com/a.jpg" alt="A" width="600" style="x"><figcaption>Caption <em>here</em></figcaption></figure>`,
+			want: `<figure><img src="https://example.com/a.jpg" alt="A" width="600"/><figcaption>Caption <em>here</em></figcaption></figure>`,
+		},
+		{
+			name: "code block",
+			in:   "<pre class=\"highlight\"><code class=\"language-go hljs\">if a &lt; b &amp;&amp; c {\n\treturn &quot;x&quot;\n}</code></pre>",
+			want: "<pre><code class=\"language-go\">if a &lt; b &amp;&amp; c {\n\treturn &#34;x&#34;\n}</code></pre>",
+		},
+		{
+			name: "links",
+			in:   `<a href="/relative">a</a> <a href="mailto:me@example.com" target="_blank">b</a>`,
+			want: `<a href="/relative" rel="noopener noreferrer nofollow">a</a> <a href="mailto:me@example.com" target="_blank" rel="noopener noreferrer nofollow">b</a>`,
+		},
+		{
+			name: "lazy image and data image",
+			in:   `<img data-src="https://example.com/lazy.jpg" src="data:image/gif;base64,R0lGODlhAQABAAAAACw=">`,
+			want: `<img data-src="https://example.com/lazy.jpg" src="data:image/gif;base64,R0lGODlhAQABAAAAACw="/>`,
+		},
+		{
+			name: "unknown tags are unwrapped",
+			in:   `<center><font color="red">Hi</font></center><custom-tag>there</custom-tag>`,
+			want: `Hithere`,
+		},
+		{
+			name: "youtube embed",
+			in:   `<iframe width="560" height="315" src="https://www.youtube.com/embed/dQw4w9WgXcQ" frameborder="0" allow="autoplay" allowfullscreen></iframe>`,
+			want: `<iframe width="560" height="315" src="https://www.youtube.com/embed/dQw4w9WgXcQ" allowfullscreen="" sandbox="allow-scripts allow-same-origin allow-popups allow-presentation" referrerpolicy="strict-origin-when-cross-origin"></iframe>`,
+		},
+		{
+			name: "bilibili embed",
+			in:   `<iframe src="https://www.bilibili.com/blackboard/html5mobileplayer.html?aid=1&amp;bvid=BV1" allowfullscreen=""></iframe>`,
+			want: `<iframe src="https://www.bilibili.com/blackboard/html5mobileplayer.html?aid=1&amp;bvid=BV1" allowfullscreen="" sandbox="allow-scripts allow-same-origin allow-popups allow-presentation" referrerpolicy="strict-origin-when-cross-origin"></iframe>`,
+		},
+		{
+			name: "other embeds",
+			in:   `<iframe src="https://www.youtube.com/watch?v=x"></iframe><iframe src="https://youtube.com.evil.example/embed/x"></iframe>`,
+			want: ``,
+		},
+		{
+			name: "tables",
+			in:   `<table border="1"><thead><tr><th scope="col">A</th></tr></thead><tbody><tr><td colspan="2">1</td></tr></tbody></table>`,
+			want: `<table><thead><tr><th scope="col">A</th></tr></thead><tbody><tr><td colspan="2">1</td></tr></tbody></table>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.in); got != tt.want {
+				t.Errorf("SanitizeHTML()\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -24,28 +24,10 @@ var (
 
 	// Matches malformed self-closing tags without attributes like <br-->
 	malformedSelfClosingNoAttrs = regexp.MustCompile(`<(` + selfClosingTags + `)\s*--+>`)
-
-	// Matches style attributes in HTML tags
-	styleAttrRegex = regexp.MustCompile(`\s+style\s*=\s*"[^"]*"`)
-
-	// Alternative style attribute with single quotes
-	styleAttrSingleQuoteRegex = regexp.MustCompile(`\s+style\s*=\s*'[^']*'`)
-
-	// Matches class attributes in HTML tags
-	classAttrRegex = regexp.MustCompile(`\s+class\s*=\s*"[^"]*"`)
-
-	// Alternative class attribute with single quotes
-	classAttrSingleQuoteRegex = regexp.MustCompile(`\s+class\s*=\s*'[^']*'`)
-
-	// Matches <style> tags and their content
-	styleTagRegex = regexp.MustCompile(`(?i)<style[^>]*>.*?</style>`)
-
-	// Matches <script> tags and their content
-	scriptTagRegex = regexp.MustCompile(`(?i)<script[^>]*>.*?</script>`)
 )
 
-// CleanHTML sanitizes HTML content by fixing common malformed patterns
-// and removing unwanted inline styles, classes, and scripts.
+// CleanHTML fixes common malformed patterns in HTML content and then
+// sanitizes it with SanitizeHTML, which also drops inline styles and classes.
 func CleanHTML(htmlContent string) string {
 	if htmlContent == "" {
 		return htmlContent
@@ -58,21 +40,7 @@ func CleanHTML(htmlContent string) string {
 	htmlContent = malformedSelfClosingWithAttrs.ReplaceAllString(htmlContent, "<$1 $2>")
 	htmlContent = malformedSelfClosingNoAttrs.ReplaceAllString(htmlContent, "<$1>")
 
-	// Remove inline style attributes
-	htmlContent = styleAttrRegex.ReplaceAllString(htmlContent, "")
-	htmlContent = styleAttrSingleQuoteRegex.ReplaceAllString(htmlContent, "")
-
-	// Remove class attributes
-	htmlContent = classAttrRegex.ReplaceAllString(htmlContent, "")
-	htmlContent = classAttrSingleQuoteRegex.ReplaceAllString(htmlContent, "")
-
-	// Remove <style> tags and their content
-	htmlContent = styleTagRegex.ReplaceAllString(htmlContent, "")
-
-	// Remove <script> tags and their content
-	htmlContent = scriptTagRegex.ReplaceAllString(htmlContent, "")
-
-	return strings.TrimSpace(htmlContent)
+	return strings.TrimSpace(SanitizeHTML(htmlContent))
 }
 
 // RenderMarkdown converts markdown text to safe HTML.
@@ -115,35 +83,6 @@ func RenderMarkdownInline(markdownText string) string {
 	return result
 }
 
-// SanitizeHTML removes potentially dangerous HTML tags and attributes.
-func SanitizeHTML(htmlContent string) string {
-	if htmlContent == "" {
-		return ""
-	}
-
-	// Remove script tags
-	scriptRegex := regexp.MustCompile(`(?i)<script[^>]*>.*?</script>`)
-	htmlContent = scriptRegex.ReplaceAllString(htmlContent, "")
-
-	// Remove style tags
-	styleRegex := regexp.MustCompile(`(?i)<style[^>]*>.*?</style>`)
-	htmlContent = styleRegex.ReplaceAllString(htmlContent, "")
-
-	// Remove iframe tags
-	iframeRegex := regexp.MustCompile(`(?i)<iframe[^>]*>.*?</iframe>`)
-	htmlContent = iframeRegex.ReplaceAllString(htmlContent, "")
-
-	// Remove on* event handlers
-	eventRegex := regexp.MustCompile(`(?i)\s+on\w+\s*=\s*["'][^"']*["']`)
-	htmlContent = eventRegex.ReplaceAllString(htmlContent, "")
-
-	// Remove javascript: protocol
-	jsRegex := regexp.MustCompile(`(?i)javascript:`)
-	htmlContent = jsRegex.ReplaceAllString(htmlContent, "")
-
-	return htmlContent
-}
-
 // ConvertMarkdownToHTML converts markdown to safe HTML with sanitization.
 func ConvertMarkdownToHTML(markdownText string) string {
 	if markdownText == "" {
@@ -33,6 +33,21 @@ func NormalizeFeedURL(feedURL string) string {
 	return "https://" + feedURL
 }
 
+// StripCredentials removes the user:password part of a URL, e.g. from a proxy URL
+// that is about to be exported. Unparseable URLs may still carry credentials,
+// so "" is returned for them.
+func StripCredentials(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	if parsed.User == nil {
+		return rawURL
+	}
+	parsed.User = nil
+	return parsed.String()
+}
+
 // NormalizeURLForComparison returns a normalized URL for comparison purposes.
 func NormalizeURLForComparison(rawURL string) string {
 	if rawURL == "" {
@@ -5,6 +5,7 @@ package main
 import (
 	"context"
 	"embed"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -24,6 +25,7 @@ import (
 	handlers "MrRSS/internal/handlers/core"
 	"MrRSS/internal/network"
 	"MrRSS/internal/routes"
+	"MrRSS/internal/snapshot"
 	"MrRSS/internal/translation"
 	"MrRSS/internal/utils/fileutil"
 
@@ -85,6 +87,7 @@ func main() {
 	})
 	host := flag.String("host", "0.0.0.0", "Host to listen on in server mode")
 	port := flag.String("port", "1234", "Port to listen on in server mode")
+	rotateSecrets := flag.Bool("rotate-secrets", false, "Re-encrypt all stored secrets with the current key and exit (set "+database.MasterPassphraseEnv+" to unlock a master passphrase)")
 	flag.Parse()
 
 	// Force server mode for this build
@@ -126,6 +129,16 @@ func main() {
 	}
 	debugLog("Database path: %s", dbPath)
 
+	// Swap in a snapshot staged for restore before the database is opened
+	if restored, err := snapshot.ApplyPendingRestore(dbPath); errors.Is(err, snapshot.ErrRollbackFailed) {
+		// Opening the database now would create an empty one in its place
+		log.Fatalf("Error restoring database snapshot: %v", err)
+	} else if err != nil {
+		log.Printf("Error restoring database snapshot: %v", err)
+	} else if restored {
+		log.Println("Restored database snapshot; the replaced database was kept as a .before-restore file")
+	}
+
 	// Initialize database
 	log.Println("Initializing Database...")
 	db, err := database.NewDB(dbPath)
@@ -134,6 +147,14 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Attach the cold storage archive before the schema is created
+	coldStoragePath, err := fileutil.GetColdStoragePath()
+	if err != nil {
+		log.Printf("Error getting cold storage path: %v", err)
+		log.Fatal(err)
+	}
+	db.EnableColdStorage(coldStoragePath)
+
 	// Run database schema initialization synchronously to ensure it's ready
 	log.Println("Running DB migrations...")
 	if err := db.Init(); err != nil {
@@ -142,6 +163,16 @@ func main() {
 	}
 	log.Println("Database initialized successfully")
 
+	if *rotateSecrets {
+		result, err := db.RotateSecrets()
+		if err != nil {
+			log.Fatalf("Error rotating secrets: %v", err)
+		}
+		log.Printf("Re-encrypted %d secrets, %d could not be decrypted and were left as they are", result.Rotated, result.Failed)
+		db.Close()
+		return
+	}
+
 	// Initialize AI profile provider
 	profileProvider := ai.NewProfileProvider(db)
 	translator := translation.NewDynamicTranslatorWithCache(db, db)
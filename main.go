@@ -5,6 +5,7 @@ package main
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
@@ -27,6 +28,7 @@ import (
 	"MrRSS/internal/monitor"
 	"MrRSS/internal/network"
 	"MrRSS/internal/routes"
+	"MrRSS/internal/snapshot"
 	"MrRSS/internal/translation"
 	"MrRSS/internal/utils/fileutil"
 	"MrRSS/internal/utils/httputil"
@@ -137,6 +139,16 @@ func main() {
 	}
 	debugLog("Database path: %s", dbPath)
 
+	// Swap in a snapshot staged for restore before the database is opened
+	if restored, err := snapshot.ApplyPendingRestore(dbPath); errors.Is(err, snapshot.ErrRollbackFailed) {
+		// Opening the database now would create an empty one in its place
+		log.Fatalf("Error restoring database snapshot: %v", err)
+	} else if err != nil {
+		log.Printf("Error restoring database snapshot: %v", err)
+	} else if restored {
+		log.Println("Restored database snapshot; the replaced database was kept as a .before-restore file")
+	}
+
 	// Initialize database
 	log.Println("Initializing Database...")
 	db, err := database.NewDB(dbPath)
@@ -145,6 +157,14 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Attach the cold storage archive before the schema is created
+	coldStoragePath, err := fileutil.GetColdStoragePath()
+	if err != nil {
+		log.Printf("Error getting cold storage path: %v", err)
+		log.Fatal(err)
+	}
+	db.EnableColdStorage(coldStoragePath)
+
 	// Run database schema initialization synchronously to ensure it's ready
 	log.Println("Running DB migrations...")
 	if err := db.Init(); err != nil {